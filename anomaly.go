@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// anomalyMinRuns는 이상을 판단하기 전에 있어야 하는 같은 파이프라인의 이전 실행 수입니다.
+const anomalyMinRuns = 3
+
+// anomalyOptions는 성공한 실행의 결과를 같은 파이프라인의 이전 실행들과 비교하는 설정입니다.
+// 행 수와 출력 크기는 최근 실행들의 중앙값에서 threshold 비율보다, 컬럼의 null 비율은
+// nullDelta보다 벗어나면 이상으로 보고서에 남깁니다. 이전 실행이 anomalyMinRuns보다 적으면
+// 비교하지 않고 기록만 합니다.
+type anomalyOptions struct {
+	history   string
+	key       string
+	window    int
+	threshold float64
+	nullDelta float64
+	fail      bool
+}
+
+func (a *anomalyOptions) bind(fs *flag.FlagSet) {
+	fs.StringVar(&a.history, "anomaly-history", "", "compare each successful run with the previous runs of the same pipeline recorded in this JSON lines file (row count, output bytes and column null ratios), flag large deviations in the report and notifications, then append the run")
+	fs.StringVar(&a.key, "anomaly-key", "", "name the runs of --anomaly-history are grouped by (default: the command and its --index or --mapping; the pipeline name under daemon)")
+	fs.IntVar(&a.window, "anomaly-window", 10, "number of previous runs whose median is the baseline")
+	fs.Float64Var(&a.threshold, "anomaly-threshold", 0.5, "flag row counts and output sizes that differ from the baseline by more than this fraction of it")
+	fs.Float64Var(&a.nullDelta, "anomaly-null-delta", 0.2, "flag columns whose share of null values differs from the baseline by more than this")
+	fs.BoolVar(&a.fail, "fail-on-anomaly", false, "end a run with anomalies with exit code 7 instead of only warning")
+}
+
+func (a *anomalyOptions) validate() error {
+	switch {
+	case a.window < anomalyMinRuns:
+		return configErrorf("--anomaly-window must be at least %d", anomalyMinRuns)
+	case a.threshold <= 0:
+		return configErrorf("--anomaly-threshold must be positive")
+	case a.nullDelta <= 0 || a.nullDelta > 1:
+		return configErrorf("--anomaly-null-delta must be in (0, 1]")
+	}
+	return nil
+}
+
+// begin 함수는 --anomaly-key가 없으면 명령과 읽는 인덱스나 매핑으로 파이프라인 이름을 정합니다.
+func (a *anomalyOptions) begin(fs *flag.FlagSet, command string) {
+	if a.key != "" {
+		return
+	}
+	a.key = command
+	for _, name := range []string{"index", "mapping"} {
+		if f := fs.Lookup(name); f != nil && f.Value.String() != "" {
+			a.key += " " + f.Value.String()
+			return
+		}
+	}
+}
+
+// runAnomaly는 기준값에서 크게 벗어난 측정값 하나입니다. Metric은 rows, bytes 또는
+// null_ratio:<컬럼>입니다.
+type runAnomaly struct {
+	Metric   string  `json:"metric"`
+	Value    float64 `json:"value"`
+	Baseline float64 `json:"baseline"`
+}
+
+// historyEntry는 --anomaly-history 파일의 한 줄입니다.
+type historyEntry struct {
+	Key        string             `json:"key"`
+	FinishedAt time.Time          `json:"finished_at"`
+	Rows       int64              `json:"rows"`
+	Bytes      int64              `json:"bytes"`
+	NullRatios map[string]float64 `json:"null_ratios,omitempty"`
+}
+
+// check 함수는 성공한 실행의 보고서를 이력과 비교해 이상을 보고서에 남기고, 실행을 이력에
+// 덧붙입니다. --fail-on-anomaly이면 이상이 있을 때 오류를 반환합니다.
+func (a *anomalyOptions) check(report *runReport) error {
+	if a.history == "" {
+		return nil
+	}
+	entry := historyEntry{Key: a.key, FinishedAt: time.Now().UTC(), Rows: report.RowsExported}
+	var paths []string
+	for _, f := range report.Files {
+		entry.Bytes += f.Bytes
+		if strings.HasSuffix(f.Path, ".parquet") {
+			paths = append(paths, f.Path)
+		}
+	}
+	if len(paths) > 0 {
+		if profile, err := profileFiles(paths); err != nil {
+			report.warnf("anomaly check: reading null counts: %v", err)
+		} else {
+			entry.NullRatios = nullRatios(profile)
+		}
+	}
+	past, err := readHistory(a.history, a.key, a.window)
+	if err != nil {
+		return configErrorf("reading --anomaly-history: %w", err)
+	}
+	if len(past) >= anomalyMinRuns {
+		report.Anomalies = a.compare(entry, past)
+	}
+	for _, an := range report.Anomalies {
+		report.warnf("anomaly: %s is %s, the median of the last %d runs of %s is %s", an.Metric, formatMetric(an.Value), len(past), a.key, formatMetric(an.Baseline))
+	}
+	if err := appendJSONLine(a.history, entry); err != nil {
+		return configErrorf("writing --anomaly-history: %w", err)
+	}
+	if a.fail && len(report.Anomalies) > 0 {
+		return anomalyErrorf("%d anomalies against the last %d runs of %s", len(report.Anomalies), len(past), a.key)
+	}
+	return nil
+}
+
+// compare 함수는 entry를 past의 중앙값과 비교합니다.
+func (a *anomalyOptions) compare(entry historyEntry, past []historyEntry) []runAnomaly {
+	var out []runAnomaly
+	for _, m := range []struct {
+		name  string
+		value int64
+		get   func(historyEntry) int64
+	}{
+		{"rows", entry.Rows, func(e historyEntry) int64 { return e.Rows }},
+		{"bytes", entry.Bytes, func(e historyEntry) int64 { return e.Bytes }},
+	} {
+		values := make([]float64, len(past))
+		for i, e := range past {
+			values[i] = float64(m.get(e))
+		}
+		baseline, v := median(values), float64(m.value)
+		if baseline == v {
+			continue
+		}
+		if baseline == 0 || math.Abs(v-baseline)/baseline > a.threshold {
+			out = append(out, runAnomaly{Metric: m.name, Value: v, Baseline: baseline})
+		}
+	}
+	columns := make([]string, 0, len(entry.NullRatios))
+	for c := range entry.NullRatios {
+		columns = append(columns, c)
+	}
+	sort.Strings(columns)
+	for _, c := range columns {
+		var values []float64
+		for _, e := range past {
+			if r, ok := e.NullRatios[c]; ok {
+				values = append(values, r)
+			}
+		}
+		if len(values) < anomalyMinRuns {
+			continue
+		}
+		if baseline := median(values); math.Abs(entry.NullRatios[c]-baseline) > a.nullDelta {
+			out = append(out, runAnomaly{Metric: "null_ratio:" + c, Value: entry.NullRatios[c], Baseline: baseline})
+		}
+	}
+	return out
+}
+
+// nullRatios 함수는 profile의 컬럼마다 null인 값의 비율을 반환합니다. 통계가 없는 컬럼은
+// 뺍니다.
+func nullRatios(profile *datasetProfile) map[string]float64 {
+	ratios := make(map[string]float64)
+	for name, c := range profile.columns {
+		if c.hasStats && c.values > 0 {
+			ratios[name] = math.Round(float64(c.nulls)/float64(c.values)*1e4) / 1e4
+		}
+	}
+	return ratios
+}
+
+// readHistory 함수는 path에서 key의 최근 실행을 최대 window개까지 오래된 순서로 읽습니다.
+// 파일이 없으면 이력이 없는 것입니다.
+func readHistory(path, key string, window int) ([]historyEntry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []historyEntry
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		if len(strings.TrimSpace(sc.Text())) == 0 {
+			continue
+		}
+		var e historyEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if e.Key == key {
+			entries = append(entries, e)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(entries) > window {
+		entries = entries[len(entries)-window:]
+	}
+	return entries, nil
+}
+
+// appendJSONLine 함수는 v를 path에 JSON 한 줄로 덧붙입니다. 한 번의 write로 써서 같은 파일을
+// 쓰는 다른 파이프라인과 줄이 섞이지 않게 합니다.
+func appendJSONLine(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// formatMetric 함수는 정수인 값은 정수로, 비율은 소수로 씁니다.
+func formatMetric(v float64) string {
+	if v == math.Trunc(v) {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.4g", v)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAnomalyCheck(t *testing.T) {
+	history := filepath.Join(t.TempDir(), "history.jsonl")
+	a := anomalyOptions{history: history, key: "export logs-*", window: 10, threshold: 0.5, nullDelta: 0.2}
+	run := func(rows, bytes int64) *runReport {
+		report := newRunReport("export")
+		report.RowsExported = rows
+		report.Files = []fileReport{{Path: "logs.ndjson", Rows: rows, Bytes: bytes}}
+		if err := a.check(report); err != nil {
+			t.Fatal(err)
+		}
+		return report
+	}
+	for _, rows := range []int64{1000, 1100, 950} {
+		if report := run(rows, rows*10); len(report.Anomalies) != 0 {
+			t.Fatalf("%d rows: anomalies %v before the history is long enough", rows, report.Anomalies)
+		}
+	}
+	if report := run(1200, 12000); len(report.Anomalies) != 0 {
+		t.Errorf("1200 rows: anomalies %v", report.Anomalies)
+	}
+	report := run(10, 12000)
+	want := []runAnomaly{{Metric: "rows", Value: 10, Baseline: 1050}}
+	if !reflect.DeepEqual(report.Anomalies, want) {
+		t.Errorf("anomalies = %v, want %v", report.Anomalies, want)
+	}
+	if !(&notificationOptions{}).checkSuspicious(report) {
+		t.Error("a run with anomalies is not suspicious")
+	}
+
+	// 다른 파이프라인의 이력은 섞이지 않습니다.
+	other := anomalyOptions{history: history, key: "export metrics-*", window: 10, threshold: 0.5, nullDelta: 0.2, fail: true}
+	if err := other.check(newRunReport("export")); err != nil {
+		t.Errorf("first run of another key: %v", err)
+	}
+
+	a.fail = true
+	report = newRunReport("export")
+	if err := a.check(report); exitCodeFor(err) != exitAnomaly {
+		t.Errorf("--fail-on-anomaly with 0 rows = %v, want exit code %d", err, exitAnomaly)
+	}
+	entries, err := readHistory(history, "export logs-*", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[2].Rows != 0 {
+		t.Errorf("last entries = %+v", entries)
+	}
+}
+
+func TestAnomalyNullRatios(t *testing.T) {
+	a := anomalyOptions{threshold: 0.5, nullDelta: 0.2}
+	past := []historyEntry{
+		{Rows: 100, NullRatios: map[string]float64{"user.name": 0.01, "status": 0}},
+		{Rows: 100, NullRatios: map[string]float64{"user.name": 0.02, "status": 0}},
+		{Rows: 100, NullRatios: map[string]float64{"user.name": 0.01}},
+	}
+	got := a.compare(historyEntry{Rows: 100, NullRatios: map[string]float64{"user.name": 0.9, "status": 1}}, past)
+	// status는 이전 실행 두 번에만 있어 비교하지 않습니다.
+	want := []runAnomaly{{Metric: "null_ratio:user.name", Value: 0.9, Baseline: 0.01}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("anomalies = %v, want %v", got, want)
+	}
+}
+
+func TestAnomalyKey(t *testing.T) {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.String("mapping", "", "")
+	fs.String("index", "", "")
+	fs.Parse([]string{"--index", "logs-*"})
+	var a anomalyOptions
+	a.begin(fs, "export")
+	if a.key != "export logs-*" {
+		t.Errorf("key = %q", a.key)
+	}
+	if _, err := readHistory(filepath.Join(t.TempDir(), "missing.jsonl"), "x", 10); err != nil {
+		t.Errorf("missing history: %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/url"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+)
+
+// auditTimeout은 실행이 끝난 뒤 감사 기록을 Elasticsearch에 쓰는 요청의 제한 시간입니다.
+const auditTimeout = 30 * time.Second
+
+// auditOptions는 실행마다 한 줄씩 남기는 감사 기록의 설정입니다. 개인정보를 내보내는
+// 실행을 나중에 추적할 수 있도록, 파일에는 덧붙이기만 하고 인덱스에는 op_type=create로만
+// 씁니다. 어느 쪽에도 쓰지 못하면 실행이 성공했어도 설정 오류로 끝납니다.
+type auditOptions struct {
+	path  string
+	index string
+	es    esOptions
+
+	// flags는 begin이 모은, 명령행과 프로필로 정한 플래그 값입니다. 비밀 값은 가립니다.
+	flags  map[string]string
+	file   *os.File
+	client *esClient
+}
+
+func (a *auditOptions) bind(fs *flag.FlagSet) {
+	fs.StringVar(&a.path, "audit-log", os.Getenv("ES_SCHEMA_AUDIT_LOG"), "append a JSON line describing every run (who, when, flags, query, indices, rows and output files) to this file (default $ES_SCHEMA_AUDIT_LOG)")
+	fs.StringVar(&a.index, "audit-index", "", "also index the audit record of every run into this Elasticsearch index on --audit-url")
+	a.es.bind(fs, "audit-", "audit")
+}
+
+func (a *auditOptions) validate() error {
+	if a.index != "" && a.es.url == "" {
+		return configErrorf("--audit-index requires --audit-url")
+	}
+	return nil
+}
+
+// begin 함수는 실행 전에 플래그 값을 모으고 감사 기록을 쓸 곳을 엽니다. 기록할 수 없는
+// 실행이 데이터를 내보내지 않도록 명령을 실행하기 전에 부릅니다.
+func (a *auditOptions) begin(fs *flag.FlagSet) error {
+	a.flags = auditFlags(fs)
+	if a.path != "" {
+		f, err := os.OpenFile(a.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			return configErrorf("opening --audit-log: %w", err)
+		}
+		a.file = f
+	}
+	if a.index != "" {
+		client, err := a.es.client()
+		if err != nil {
+			return err
+		}
+		a.client = client
+	}
+	return nil
+}
+
+// auditRecord는 감사 기록 한 건입니다.
+type auditRecord struct {
+	Timestamp       time.Time         `json:"@timestamp"`
+	User            string            `json:"user"`
+	Host            string            `json:"host"`
+	Command         string            `json:"command"`
+	Status          string            `json:"status"`
+	StartedAt       time.Time         `json:"started_at"`
+	DurationSeconds float64           `json:"duration_seconds"`
+	Flags           map[string]string `json:"flags"`
+	Query           string            `json:"query,omitempty"`
+	Indices         []string          `json:"indices,omitempty"`
+	RowsRead        int64             `json:"rows_read,omitempty"`
+	RowsExported    int64             `json:"rows_exported"`
+	Outputs         []string          `json:"outputs"`
+	Error           string            `json:"error,omitempty"`
+}
+
+// newAuditRecord 함수는 끝난 실행의 보고서와 플래그로 감사 기록을 만듭니다. 인덱스는
+// export가 패턴을 풀어 찾은 이름을, 없으면 --index 값을 씁니다.
+func newAuditRecord(report *runReport, flags map[string]string) *auditRecord {
+	rec := &auditRecord{
+		Timestamp:       report.FinishedAt,
+		User:            currentUser(),
+		Command:         report.Command,
+		Status:          report.Status,
+		StartedAt:       report.StartedAt,
+		DurationSeconds: report.DurationSeconds,
+		Flags:           flags,
+		Query:           flags["query"],
+		RowsRead:        report.RowsRead,
+		RowsExported:    report.RowsExported,
+		Outputs:         []string{},
+	}
+	if rec.Flags == nil {
+		rec.Flags = map[string]string{}
+	}
+	rec.Host, _ = os.Hostname()
+	for _, r := range report.Indices {
+		rec.Indices = append(rec.Indices, r.Index)
+	}
+	if len(rec.Indices) == 0 && flags["index"] != "" {
+		rec.Indices = strings.Split(flags["index"], ",")
+	}
+	for _, f := range report.Files {
+		rec.Outputs = append(rec.Outputs, f.Path)
+	}
+	if report.Error != nil {
+		rec.Error = report.Error.Error
+	}
+	return rec
+}
+
+// record 함수는 끝난 실행의 감사 기록을 씁니다. begin 전에 실패한 실행(예: 잘못된 프로필)도
+// 남기도록, 아직 열지 않았으면 여기서 엽니다.
+func (a *auditOptions) record(report *runReport) error {
+	if a.path == "" && a.index == "" {
+		return nil
+	}
+	if a.file == nil && a.client == nil {
+		if err := a.begin(flag.NewFlagSet("", flag.ContinueOnError)); err != nil {
+			return err
+		}
+	}
+	rec := newAuditRecord(report, a.flags)
+	if a.file != nil {
+		data, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		// 한 번의 write로 한 줄을 써서, 같은 파일에 쓰는 다른 실행과 줄이 섞이지 않게 합니다.
+		_, err = a.file.Write(append(data, '\n'))
+		if closeErr := a.file.Close(); err == nil {
+			err = closeErr
+		}
+		a.file = nil
+		if err != nil {
+			return err
+		}
+	}
+	if a.client != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), auditTimeout)
+		defer cancel()
+		q := url.Values{"op_type": {"create"}, "refresh": {"false"}}
+		if err := a.client.sendJSON(ctx, http.MethodPost, "/"+url.PathEscape(a.index)+"/_doc", q, rec, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// auditFlags 함수는 명령행과 프로필로 정한 플래그 값을 모읍니다. 비밀 값은 가리고,
+// URL에 담긴 비밀번호도 가립니다.
+func auditFlags(fs *flag.FlagSet) map[string]string {
+	flags := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		v := f.Value.String()
+		if isSecretSetting(f.Name) {
+			v = "<redacted>"
+		} else if u, err := url.Parse(v); err == nil && u.User != nil {
+			v = u.Redacted()
+		}
+		flags[f.Name] = v
+	})
+	return flags
+}
+
+// currentUser 함수는 실행한 운영체제 사용자 이름을 반환합니다.
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return firstNonEmpty(os.Getenv("USER"), os.Getenv("USERNAME"))
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	var indexed []auditRecord
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/es-schema-audit/_doc" || r.URL.Query().Get("op_type") != "create" {
+			t.Errorf("audit request = %s %s", r.Method, r.URL)
+		}
+		var rec auditRecord
+		json.NewDecoder(r.Body).Decode(&rec)
+		indexed = append(indexed, rec)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	runOnce := func(args []string, runErr error) int {
+		var opts globalOptions
+		fs := flag.NewFlagSet("es-schema export", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		opts.bind(fs)
+		var o exportOptions
+		o.source.bind(fs, "", "source")
+		fs.Var(&o.indices, "index", "")
+		fs.StringVar(&o.query, "query", "", "")
+		if err := fs.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		if err := opts.audit.begin(fs); err != nil {
+			t.Fatal(err)
+		}
+		opts.audit.client.http = srv.Client()
+		report := newRunReport("export")
+		report.Indices = []indexReport{{Index: "users-2024"}, {Index: "users-2025"}}
+		report.addCopyFile("out/users-2024.parquet", 10)
+		report.RowsExported = 10
+		return finishRun(&opts, report, runErr)
+	}
+	common := []string{"--audit-log", path, "--audit-index", "es-schema-audit", "--audit-url", srv.URL,
+		"--url", "https://admin:hunter2@es:9200", "--password", "hunter2", "--index", "users-*", "--query", `{"term": {"country": "de"}}`}
+	if code := runOnce(common, nil); code != exitOK {
+		t.Fatalf("exit code %d", code)
+	}
+	runOnce(common, dataErrorf("boom"))
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var records []auditRecord
+	for sc := bufio.NewScanner(f); sc.Scan(); {
+		var rec auditRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			t.Fatalf("line %q: %v", sc.Text(), err)
+		}
+		records = append(records, rec)
+	}
+	if len(records) != 2 || len(indexed) != 2 {
+		t.Fatalf("records = %d in the file, %d indexed; want 2 each", len(records), len(indexed))
+	}
+	rec := records[0]
+	if rec.Command != "export" || rec.Status != statusSuccess || rec.RowsExported != 10 || rec.User == "" {
+		t.Errorf("record = %+v", rec)
+	}
+	if rec.Query != `{"term": {"country": "de"}}` || len(rec.Indices) != 2 || rec.Indices[1] != "users-2025" || rec.Outputs[0] != "out/users-2024.parquet" {
+		t.Errorf("record = %+v", rec)
+	}
+	if rec.Flags["password"] != "<redacted>" || rec.Flags["url"] != "https://admin:xxxxx@es:9200" {
+		t.Errorf("secrets in the audit record: %v", rec.Flags)
+	}
+	if records[1].Status != statusFailed || records[1].Error == "" || indexed[1].Error != records[1].Error {
+		t.Errorf("failed run = %+v, indexed %+v", records[1], indexed[1])
+	}
+}
+
+func TestAuditLogUnwritable(t *testing.T) {
+	opts := auditOptions{path: filepath.Join(t.TempDir(), "missing", "audit.log")}
+	if err := opts.begin(flag.NewFlagSet("", flag.ContinueOnError)); exitCodeFor(err) != exitConfigError {
+		t.Errorf("begin = %v, want a config error", err)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+// avroName은 Avro가 레코드, fixed와 필드 이름으로 받는 이름입니다.
+var avroName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// avroTimestamps는 Arrow timestamp 단위마다 Avro long의 logicalType입니다. Avro에는 초
+// 단위가 없으므로 초 컬럼은 밀리초로 읽습니다.
+var avroTimestamps = map[arrow.TimeUnit]string{
+	arrow.Second:      "timestamp-millis",
+	arrow.Millisecond: "timestamp-millis",
+	arrow.Microsecond: "timestamp-micros",
+	arrow.Nanosecond:  "timestamp-nanos",
+}
+
+// avroSchema 함수는 fields를 최상위 레코드 record의 Avro 스키마(.avsc) JSON으로 씁니다.
+// Elasticsearch 필드는 모두 값이 없을 수 있으므로 필드마다 null과의 union에 기본값 null을
+// 둡니다. 필드 이름은 Avro 이름이어야 하므로 --sanitize-names를 거친 필드를 받습니다.
+func avroSchema(record string, fields []arrow.Field) ([]byte, error) {
+	names := make(map[string]bool)
+	rec, err := avroRecord(record, fields, names)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// avroField는 Avro 레코드의 필드 하나입니다. Default는 null이어야 하므로 omitempty 없이
+// 씁니다.
+type avroField struct {
+	Name    string      `json:"name"`
+	Doc     string      `json:"doc,omitempty"`
+	Type    interface{} `json:"type"`
+	Default interface{} `json:"default"`
+}
+
+// avroRecordType은 Avro 레코드 타입입니다. 사람이 읽기 좋도록 type, name, fields 순서로
+// 씁니다.
+type avroRecordType struct {
+	Type   string      `json:"type"`
+	Name   string      `json:"name"`
+	Fields []avroField `json:"fields"`
+}
+
+// avroRecord 함수는 fields의 레코드를 만듭니다. Avro의 이름 있는 타입(record, fixed)은 한
+// 스키마에서 이름이 겹치면 안 되므로 안쪽 구조체는 경로로 이름을 짓고 names로 겹침을
+// 막습니다.
+func avroRecord(name string, fields []arrow.Field, names map[string]bool) (*avroRecordType, error) {
+	if !avroName.MatchString(name) {
+		return nil, fmt.Errorf("%q is not an Avro name", name)
+	}
+	if names[name] {
+		return nil, fmt.Errorf("Avro type name %s is used twice", name)
+	}
+	names[name] = true
+	out := make([]avroField, len(fields))
+	for i, f := range fields {
+		if !avroName.MatchString(f.Name) {
+			return nil, fmt.Errorf("field %s is not an Avro name; use --sanitize-names", f.Name)
+		}
+		t, err := avroType(name+"_"+f.Name, f.Type, names)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		out[i] = avroField{Name: f.Name, Type: []interface{}{"null", t}}
+		if original := originalName(f); original != f.Name {
+			out[i].Doc = "Elasticsearch field " + original
+		}
+	}
+	return &avroRecordType{Type: "record", Name: name, Fields: out}, nil
+}
+
+// avroType 함수는 Arrow 타입 t의 Avro 타입을 반환합니다. 배열 원소와 맵 값도 null일 수
+// 있습니다. path는 이름 있는 타입을 만들 때 쓰는 이름입니다.
+func avroType(path string, t arrow.DataType, names map[string]bool) (interface{}, error) {
+	switch t := t.(type) {
+	case *arrow.StringType:
+		return "string", nil
+	case *arrow.BinaryType:
+		return "bytes", nil
+	case *arrow.BooleanType:
+		return "boolean", nil
+	case *arrow.Int8Type, *arrow.Int16Type, *arrow.Int32Type:
+		return "int", nil
+	case *arrow.Int64Type:
+		return "long", nil
+	case *arrow.Uint64Type:
+		// unsigned_long은 long에 담기지 않으므로 소수 자릿수 없는 decimal로 씁니다.
+		return map[string]interface{}{"type": "bytes", "logicalType": "decimal", "precision": 20, "scale": 0}, nil
+	case *arrow.Float16Type, *arrow.Float32Type:
+		return "float", nil
+	case *arrow.Float64Type:
+		return "double", nil
+	case *arrow.Decimal128Type:
+		return map[string]interface{}{"type": "bytes", "logicalType": "decimal", "precision": t.Precision, "scale": t.Scale}, nil
+	case *arrow.TimestampType:
+		return map[string]interface{}{"type": "long", "logicalType": avroTimestamps[t.Unit]}, nil
+	case *arrow.FixedSizeBinaryType:
+		if names[path] {
+			return nil, fmt.Errorf("Avro type name %s is used twice", path)
+		}
+		names[path] = true
+		return map[string]interface{}{"type": "fixed", "name": path, "size": t.ByteWidth}, nil
+	case *arrow.DictionaryType:
+		return avroType(path, t.ValueType, names)
+	case *arrow.ListType:
+		items, err := avroType(path, t.Elem(), names)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "array", "items": []interface{}{"null", items}}, nil
+	case *arrow.FixedSizeListType:
+		items, err := avroType(path, t.Elem(), names)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "array", "items": []interface{}{"null", items}}, nil
+	case *arrow.MapType:
+		// Avro 맵의 키는 언제나 문자열입니다.
+		if t.KeyType().ID() != arrow.STRING {
+			return nil, fmt.Errorf("Avro maps need string keys, not %s", t.KeyType())
+		}
+		values, err := avroType(path, t.ItemType(), names)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "map", "values": []interface{}{"null", values}}, nil
+	case *arrow.StructType:
+		return avroRecord(path, t.Fields(), names)
+	}
+	return nil, fmt.Errorf("no Avro type for %s", t)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestAvroSchema(t *testing.T) {
+	schema, err := schemaFromMapping([]byte(`{"properties": {
+		"message":  {"type": "text"},
+		"at":       {"type": "date"},
+		"bytes":    {"type": "unsigned_long"},
+		"client":   {"properties": {"ip": {"type": "ip"}}},
+		"tags":     {"type": "keyword"},
+		"comments": {"type": "nested", "properties": {"likes": {"type": "integer"}}}
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := avroSchema("Document", schema.Fields())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rec struct {
+		Type   string
+		Name   string
+		Fields []struct {
+			Name    string
+			Type    []interface{}
+			Default interface{}
+		}
+	}
+	if err := json.Unmarshal(data, &rec); err != nil {
+		t.Fatalf("%v:\n%s", err, data)
+	}
+	if rec.Type != "record" || rec.Name != "Document" {
+		t.Errorf("record = %s %s", rec.Type, rec.Name)
+	}
+	types := make(map[string]string)
+	for _, f := range rec.Fields {
+		if len(f.Type) != 2 || f.Type[0] != "null" || f.Default != nil {
+			t.Errorf("field %s is not an optional union: %v default %v", f.Name, f.Type, f.Default)
+			continue
+		}
+		b, _ := json.Marshal(f.Type[1])
+		types[f.Name] = string(b)
+	}
+	for name, want := range map[string]string{
+		"message":  `"string"`,
+		"at":       `"logicalType":"timestamp-nanos"`,
+		"bytes":    `"logicalType":"decimal","precision":20`,
+		"client":   `"name":"Document_client"`,
+		"comments": `"type":"array"`,
+	} {
+		if !strings.Contains(types[name], want) {
+			t.Errorf("field %s = %s, want %s", name, types[name], want)
+		}
+	}
+	if !strings.Contains(types["client"], `"name":"Document_client_ip","size":16`) {
+		t.Errorf("client.ip is not a named fixed: %s", types["client"])
+	}
+}
+
+func TestAvroSchemaNames(t *testing.T) {
+	schema, err := schemaFromMapping([]byte(`{"properties": {"@timestamp": {"type": "date"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := avroSchema("Document", schema.Fields()); err == nil || !strings.Contains(err.Error(), "--sanitize-names") {
+		t.Errorf("unsanitized field name = %v", err)
+	}
+	if _, err := avroSchema("log-event", nil); err == nil {
+		t.Error("record name log-event was accepted")
+	}
+}
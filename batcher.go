@@ -0,0 +1,85 @@
+package main
+
+import "flag"
+
+const (
+	defaultBatchRows  = 5000
+	defaultBatchBytes = 64 << 20
+)
+
+// batchSizeHelp는 --batch-size 설명입니다.
+const batchSizeHelp = "documents converted into one Arrow record and written to Parquet at once; memory use grows with it, not with the input size"
+
+// batchOptions는 문서를 몇 개씩 모아 Arrow 레코드 하나로 바꿔 쓰는지 정하는 convert와
+// validate의 설정입니다. 첫 배치에서만 스키마를 넓힐 수 있으므로 validate도 convert와 같은
+// 크기로 읽어야 같은 경고를 냅니다.
+type batchOptions struct {
+	rows  int
+	bytes int
+}
+
+func (o *batchOptions) bind(fs *flag.FlagSet) {
+	fs.IntVar(&o.rows, "batch-size", defaultBatchRows, batchSizeHelp)
+	fs.IntVar(&o.bytes, "batch-bytes", defaultBatchBytes, "also convert and write a batch once its documents reach this many bytes of JSON, so a few huge documents cannot fill memory")
+}
+
+func (o *batchOptions) validate() error {
+	if o.rows <= 0 || o.bytes <= 0 {
+		return configErrorf("--batch-size and --batch-bytes must be positive")
+	}
+	return nil
+}
+
+// recordBatcher는 문서를 모아 문서 수나 JSON 크기가 한도에 이르면 flush로 넘깁니다. flush는
+// 배치를 레코드 하나로 바꿔 Parquet 파일에 쓰므로, 한 번에 메모리에 있는 문서와 레코드는
+// 입력 크기와 상관없이 배치 하나뿐입니다.
+type recordBatcher struct {
+	maxRows  int
+	maxBytes int
+	flush    func(hits []searchHit) error
+
+	pending []searchHit
+	size    int
+	// batches는 지금까지 넘긴 배치 수이고, firstRows는 첫 배치의 문서 수입니다.
+	batches   int
+	firstRows int
+}
+
+func newRecordBatcher(o batchOptions, flush func(hits []searchHit) error) *recordBatcher {
+	return &recordBatcher{maxRows: o.rows, maxBytes: o.bytes, flush: flush}
+}
+
+// add 함수는 문서 하나를 배치에 더합니다. 더하면 maxBytes를 넘는 경우 먼저 모아 둔 배치를
+// 넘기고, 문서 수나 크기가 한도에 이르면 바로 넘깁니다.
+func (b *recordBatcher) add(hit searchHit) error {
+	if len(b.pending) > 0 && b.size+len(hit.Source) > b.maxBytes {
+		if err := b.send(); err != nil {
+			return err
+		}
+	}
+	b.pending = append(b.pending, hit)
+	b.size += len(hit.Source)
+	if len(b.pending) >= b.maxRows || b.size >= b.maxBytes {
+		return b.send()
+	}
+	return nil
+}
+
+// close 함수는 남은 문서를 넘깁니다.
+func (b *recordBatcher) close() error {
+	return b.send()
+}
+
+func (b *recordBatcher) send() error {
+	if len(b.pending) == 0 {
+		return nil
+	}
+	hits := b.pending
+	// flush가 hits를 붙잡아 둘 수 있으므로 배열을 다시 쓰지 않습니다.
+	b.pending, b.size = nil, 0
+	if b.batches == 0 {
+		b.firstRows = len(hits)
+	}
+	b.batches++
+	return b.flush(hits)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRecordBatcher(t *testing.T) {
+	var sizes []int
+	b := newRecordBatcher(batchOptions{rows: 3, bytes: 25}, func(hits []searchHit) error {
+		sizes = append(sizes, len(hits))
+		return nil
+	})
+	// 7바이트 문서는 문서 수 한도로 세 개씩 넘기고, 13바이트 문서를 더하면 크기 한도를 넘기므로
+	// 먼저 모아 둔 배치를 넘깁니다.
+	for _, doc := range []string{`{"a":1}`, `{"a":2}`, `{"a":3}`, `{"a":4}`, `{"long":"123"}`, `{"long":"456"}`} {
+		if err := b.add(searchHit{Source: json.RawMessage(doc)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.close(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{3, 2, 1}; !reflect.DeepEqual(sizes, want) || b.firstRows != 3 || b.batches != 3 {
+		t.Errorf("batches = %v (first %d, %d batches), want %v", sizes, b.firstRows, b.batches, want)
+	}
+	if err := b.close(); err != nil || len(sizes) != 3 {
+		t.Errorf("second close sent %v, %v", sizes, err)
+	}
+}
+
+func TestNDJSONFeed(t *testing.T) {
+	src, err := openNDJSON(writeNDJSON(t, `{"a": 1}`, `{"a": 2}`, "", `{"a": 3}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.close()
+	var ids []string
+	b := newRecordBatcher(batchOptions{rows: 2, bytes: defaultBatchBytes}, func(hits []searchHit) error {
+		for _, h := range hits {
+			ids = append(ids, h.ID)
+		}
+		ids = append(ids, "|")
+		return nil
+	})
+	if err := src.feed(context.Background(), b); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(ids, ","); got != "1,2,|,4,|" {
+		t.Errorf("batches = %s", got)
+	}
+
+	for _, o := range []batchOptions{{rows: 0, bytes: 1}, {rows: 1, bytes: 0}} {
+		if err := o.validate(); exitCodeFor(err) != exitConfigError {
+			t.Errorf("validate(%+v) = %v", o, err)
+		}
+	}
+}
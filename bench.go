@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// benchSuite는 bench 명령이 읽는 쿼리 모음 파일입니다. 쿼리마다 같은 질문을 Elasticsearch
+// 검색 본문과, Parquet 파일을 docs 뷰로 읽는 DuckDB SQL로 한 번씩 적습니다.
+//
+//	{"queries": [{
+//	  "name": "errors per host",
+//	  "es": {"size": 0, "query": {"term": {"level": "error"}}, "aggs": {"h": {"terms": {"field": "host"}}}},
+//	  "sql": "SELECT host, count(*) FROM docs WHERE level = 'error' GROUP BY host"
+//	}]}
+type benchSuite struct {
+	Queries []benchQuery `json:"queries"`
+}
+
+type benchQuery struct {
+	Name string          `json:"name"`
+	ES   json.RawMessage `json:"es"`
+	SQL  string          `json:"sql"`
+}
+
+// readBenchSuite 함수는 쿼리 모음 파일을 읽고 검사합니다.
+func readBenchSuite(path string) (*benchSuite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, configErrorf("reading --suite: %w", err)
+	}
+	var s benchSuite
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, configErrorf("decoding --suite %s: %w", path, err)
+	}
+	if len(s.Queries) == 0 {
+		return nil, configErrorf("--suite %s has no queries", path)
+	}
+	seen := make(map[string]bool)
+	for i, q := range s.Queries {
+		switch {
+		case q.Name == "":
+			return nil, configErrorf("--suite %s: query %d has no name", path, i)
+		case seen[q.Name]:
+			return nil, configErrorf("--suite %s: duplicate query name %q", path, q.Name)
+		case len(q.ES) == 0 || strings.TrimSpace(q.SQL) == "":
+			return nil, configErrorf("--suite %s: query %q needs both es and sql", path, q.Name)
+		}
+		seen[q.Name] = true
+	}
+	return &s, nil
+}
+
+// benchOptions는 bench 명령의 설정입니다.
+type benchOptions struct {
+	source  esOptions
+	index   string
+	parquet string
+	suite   string
+	runs    int
+	warmup  int
+	duckdb  string
+	format  string
+}
+
+func setupBench(fs *flag.FlagSet) func(ctx context.Context, report *runReport, args []string) error {
+	var o benchOptions
+	o.source.bind(fs, "", "source")
+	fs.StringVar(&o.index, "index", "", "index to query (required)")
+	fs.StringVar(&o.parquet, "parquet", "", "exported Parquet file or glob, e.g. 'out/logs/*.parquet', read by the SQL as the docs view (required)")
+	fs.StringVar(&o.suite, "suite", "", "JSON file of equivalent Elasticsearch and SQL queries (required)")
+	fs.IntVar(&o.runs, "runs", 5, "timed runs of each query on each side")
+	fs.IntVar(&o.warmup, "warmup", 1, "untimed runs of each query before the timed ones")
+	fs.StringVar(&o.duckdb, "duckdb", "duckdb", "DuckDB CLI to run the SQL with")
+	fs.StringVar(&o.format, "format", "table", "output format: table or json")
+
+	return func(ctx context.Context, report *runReport, args []string) error {
+		if len(args) > 0 {
+			return configErrorf("bench: unexpected arguments %v", args)
+		}
+		if o.index == "" || o.parquet == "" || o.suite == "" {
+			return configErrorf("bench: --index, --parquet and --suite are required")
+		}
+		if o.runs < 1 || o.warmup < 0 {
+			return configErrorf("bench: --runs must be positive and --warmup must not be negative")
+		}
+		if o.format != "table" && o.format != "json" {
+			return configErrorf("bench: unknown --format %q (want table or json)", o.format)
+		}
+		suite, err := readBenchSuite(o.suite)
+		if err != nil {
+			return err
+		}
+		parquetBytes, err := globBytes(o.parquet)
+		if err != nil {
+			return err
+		}
+		bin, err := exec.LookPath(o.duckdb)
+		if err != nil {
+			return configErrorf("bench: DuckDB CLI %q not found; install it from duckdb.org or point --duckdb at it", o.duckdb)
+		}
+		client, err := o.source.client()
+		if err != nil {
+			return err
+		}
+		if err := o.source.checkReadOnly(ctx, client, report, []string{o.index}, "read", "monitor"); err != nil {
+			return err
+		}
+		indexBytes, err := client.indexStoreBytes(ctx, o.index)
+		if err != nil {
+			return err
+		}
+
+		out := benchReport{Index: o.index, IndexBytes: indexBytes, Parquet: o.parquet, ParquetBytes: parquetBytes, Runs: o.runs}
+		for _, q := range suite.Queries {
+			r, err := o.run(ctx, client, bin, q)
+			if err != nil {
+				return err
+			}
+			out.Queries = append(out.Queries, r)
+		}
+		if o.format == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(out)
+		}
+		writeBenchReport(os.Stdout, out)
+		return nil
+	}
+}
+
+// run 함수는 쿼리 하나를 양쪽에서 warmup+runs번 실행하고 측정한 runs번의 시간을 요약합니다.
+func (o *benchOptions) run(ctx context.Context, client *esClient, duckdb string, q benchQuery) (benchResult, error) {
+	var took, wall []time.Duration
+	for i := 0; i < o.warmup+o.runs; i++ {
+		start := time.Now()
+		t, err := client.timedSearch(ctx, o.index, q.ES)
+		if err != nil {
+			return benchResult{}, fmt.Errorf("query %q: %w", q.Name, err)
+		}
+		if i >= o.warmup {
+			took = append(took, t)
+			wall = append(wall, time.Since(start))
+		}
+	}
+	times, err := duckDBTimings(ctx, duckdb, o.parquet, q.SQL, o.warmup+o.runs)
+	if err != nil {
+		return benchResult{}, fmt.Errorf("query %q: %w", q.Name, err)
+	}
+	return benchResult{
+		Name:    q.Name,
+		ESTook:  summarizeLatency(took),
+		ESWall:  summarizeLatency(wall),
+		Parquet: summarizeLatency(times[o.warmup:]),
+	}, nil
+}
+
+// duckDBRunTime은 .timer on인 DuckDB CLI가 문장마다 출력하는 실행 시간 줄입니다.
+var duckDBRunTime = regexp.MustCompile(`Run Time \(s\): real ([0-9.]+)`)
+
+// duckDBTimings 함수는 DuckDB CLI 한 프로세스에서 sql을 n번 실행하고 문장마다의 실행
+// 시간을 반환합니다. 프로세스 시작과 Parquet 메타데이터를 읽는 뷰 생성은 재지 않고, 결과는
+// 버립니다.
+func duckDBTimings(ctx context.Context, bin, parquet, sql string, n int) ([]time.Duration, error) {
+	var script strings.Builder
+	fmt.Fprintf(&script, "CREATE VIEW docs AS SELECT * FROM read_parquet(%s, union_by_name = true);\n", sqlQuote(parquet))
+	fmt.Fprintf(&script, ".output %s\n.timer on\n", os.DevNull)
+	stmt := strings.TrimRight(strings.TrimSpace(sql), ";")
+	for i := 0; i < n; i++ {
+		script.WriteString(stmt + ";\n")
+	}
+
+	cmd := exec.CommandContext(ctx, bin, "-batch", "-bail", ":memory:")
+	cmd.Stdin = strings.NewReader(script.String())
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return nil, configErrorf("duckdb exited with status %d: %s", exit.ExitCode(), strings.TrimSpace(stderr.String()))
+	}
+	if err != nil {
+		return nil, configErrorf("running duckdb: %w", err)
+	}
+	times := parseDuckDBTimings(out.Bytes())
+	if len(times) != n {
+		return nil, dataErrorf("duckdb reported %d run times for %d runs", len(times), n)
+	}
+	return times, nil
+}
+
+// parseDuckDBTimings 함수는 DuckDB CLI 출력에서 실행 시간 줄을 모두 읽습니다.
+func parseDuckDBTimings(out []byte) []time.Duration {
+	var times []time.Duration
+	for _, m := range duckDBRunTime.FindAllSubmatch(out, -1) {
+		seconds, err := strconv.ParseFloat(string(m[1]), 64)
+		if err != nil {
+			continue
+		}
+		times = append(times, time.Duration(seconds*float64(time.Second)))
+	}
+	return times
+}
+
+// sqlQuote 함수는 s를 SQL 문자열 리터럴로 감쌉니다.
+func sqlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// globBytes 함수는 pattern에 맞는 파일의 크기를 모두 더합니다.
+func globBytes(pattern string) (int64, error) {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return 0, configErrorf("invalid --parquet pattern %q: %w", pattern, err)
+	}
+	if len(paths) == 0 {
+		return 0, configErrorf("--parquet %s matches no files", pattern)
+	}
+	var total int64
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return 0, configErrorf("reading --parquet: %w", err)
+		}
+		total += info.Size()
+	}
+	return total, nil
+}
+
+// latencySummary는 측정한 시간들의 요약으로, JSON에는 밀리초로 씁니다.
+type latencySummary struct {
+	Min    time.Duration
+	Median time.Duration
+	P95    time.Duration
+}
+
+func (l latencySummary) MarshalJSON() ([]byte, error) {
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	return json.Marshal(map[string]float64{"min_ms": ms(l.Min), "median_ms": ms(l.Median), "p95_ms": ms(l.P95)})
+}
+
+// summarizeLatency 함수는 times의 최솟값, 중앙값, 95번째 백분위수(가장 가까운 순위)를
+// 구합니다.
+func summarizeLatency(times []time.Duration) latencySummary {
+	if len(times) == 0 {
+		return latencySummary{}
+	}
+	sorted := append([]time.Duration(nil), times...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	median := sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		median = (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+	}
+	p95 := (len(sorted)*95 + 99) / 100
+	return latencySummary{Min: sorted[0], Median: median, P95: sorted[p95-1]}
+}
+
+// benchResult는 쿼리 하나의 측정 결과입니다. ESTook은 Elasticsearch가 보고한 처리 시간,
+// ESWall은 네트워크를 포함한 왕복 시간, Parquet은 DuckDB가 잰 실행 시간입니다.
+type benchResult struct {
+	Name    string         `json:"name"`
+	ESTook  latencySummary `json:"es_took"`
+	ESWall  latencySummary `json:"es_wall"`
+	Parquet latencySummary `json:"parquet"`
+}
+
+type benchReport struct {
+	Index        string        `json:"index"`
+	IndexBytes   int64         `json:"index_bytes"`
+	Parquet      string        `json:"parquet"`
+	ParquetBytes int64         `json:"parquet_bytes"`
+	Runs         int           `json:"runs"`
+	Queries      []benchResult `json:"queries"`
+}
+
+// writeBenchReport 함수는 저장 크기와 쿼리별 중앙값 시간을 표로 씁니다. 속도 비는
+// Elasticsearch took 중앙값을 DuckDB 중앙값으로 나눈 값으로, 1보다 크면 Parquet 쪽이
+// 빠릅니다.
+func writeBenchReport(w io.Writer, r benchReport) {
+	fmt.Fprintf(w, "storage: %s in Elasticsearch (primaries, %s), %s in Parquet", formatBytes(r.IndexBytes), r.Index, formatBytes(r.ParquetBytes))
+	if r.IndexBytes > 0 {
+		fmt.Fprintf(w, " (%.2fx)", float64(r.ParquetBytes)/float64(r.IndexBytes))
+	}
+	fmt.Fprintf(w, "\n%d timed runs per query, median (p95)\n", r.Runs)
+	fmt.Fprintf(w, "  %-30s %22s %22s %22s %8s\n", "query", "es took", "es wall", "parquet", "speedup")
+	for _, q := range r.Queries {
+		speedup := "-"
+		if q.Parquet.Median > 0 {
+			speedup = fmt.Sprintf("%.2fx", float64(q.ESTook.Median)/float64(q.Parquet.Median))
+		}
+		fmt.Fprintf(w, "  %-30s %22s %22s %22s %8s\n", q.Name, formatLatency(q.ESTook), formatLatency(q.ESWall), formatLatency(q.Parquet), speedup)
+	}
+}
+
+func formatLatency(l latencySummary) string {
+	return fmt.Sprintf("%s (%s)", l.Median.Round(time.Microsecond), l.P95.Round(time.Microsecond))
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadBenchSuite(t *testing.T) {
+	dir := t.TempDir()
+	write := func(src string) string {
+		path := filepath.Join(dir, "suite.json")
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	s, err := readBenchSuite(write(`{"queries": [{"name": "all", "es": {"size": 0}, "sql": "SELECT count(*) FROM docs"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Queries) != 1 || string(s.Queries[0].ES) != `{"size": 0}` {
+		t.Errorf("suite = %+v", s)
+	}
+	for _, bad := range []string{
+		`{"queries": []}`,
+		`{"queries": [{"es": {}, "sql": "SELECT 1"}]}`,
+		`{"queries": [{"name": "a", "sql": "SELECT 1"}]}`,
+		`{"queries": [{"name": "a", "es": {}, "sql": "SELECT 1"}, {"name": "a", "es": {}, "sql": "SELECT 1"}]}`,
+	} {
+		if _, err := readBenchSuite(write(bad)); kindOf(err) != kindConfig {
+			t.Errorf("readBenchSuite(%s) = %v, want a config error", bad, err)
+		}
+	}
+}
+
+func TestDuckDBTimings(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "script")
+	fake := filepath.Join(dir, "duckdb")
+	script := "#!/bin/sh\ncat > " + in + "\nprintf 'Run Time (s): real 0.250 user 0.1 sys 0.0\\nRun Time (s): real 0.125 user 0.1 sys 0.0\\n'\n"
+	if err := os.WriteFile(fake, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	times, err := duckDBTimings(context.Background(), fake, "out/it's/*.parquet", "SELECT 1;", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(times) != 2 || times[0] != 250*time.Millisecond || times[1] != 125*time.Millisecond {
+		t.Errorf("times = %v", times)
+	}
+	data, _ := os.ReadFile(in)
+	if !strings.Contains(string(data), "read_parquet('out/it''s/*.parquet'") || strings.Count(string(data), "SELECT 1;\n") != 2 {
+		t.Errorf("duckdb saw %q", data)
+	}
+
+	if _, err := duckDBTimings(context.Background(), fake, "x.parquet", "SELECT 1", 3); kindOf(err) != kindData {
+		t.Errorf("missing run times error = %v, want a data error", err)
+	}
+}
+
+func TestSummarizeLatency(t *testing.T) {
+	var times []time.Duration
+	for i := 20; i >= 1; i-- {
+		times = append(times, time.Duration(i)*time.Millisecond)
+	}
+	l := summarizeLatency(times)
+	if l.Min != time.Millisecond || l.Median != 10500*time.Microsecond || l.P95 != 19*time.Millisecond {
+		t.Errorf("summary = %+v", l)
+	}
+	if l := summarizeLatency([]time.Duration{3 * time.Millisecond}); l.Median != 3*time.Millisecond || l.P95 != 3*time.Millisecond {
+		t.Errorf("single-run summary = %+v", l)
+	}
+}
@@ -0,0 +1,527 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"es-schema/esschema"
+)
+
+// browseOptions는 browse 명령의 설정입니다. browse는 매핑의 필드를 트리로 보여 주고,
+// 내보낼 필드를 골라 export와 tune의 --fields 파일로 저장합니다.
+type browseOptions struct {
+	mappingPath string
+	fieldsPath  string
+}
+
+// fieldNode는 browse 트리의 필드 하나입니다.
+type fieldNode struct {
+	name      string
+	path      string
+	depth     int
+	esType    string
+	arrowType string
+	warnings  []string
+	children  []*fieldNode
+	excluded  bool
+	expanded  bool
+}
+
+// browser는 browse 세션 하나의 상태입니다. rows는 지금 화면에 보이는 행으로, 명령의
+// 행 번호는 여기의 순서(1부터)를 가리킵니다.
+type browser struct {
+	roots       []*fieldNode
+	rows        []*fieldNode
+	mappingPath string
+	savePath    string
+	out         io.Writer
+	dirty       bool
+	// quitting은 저장하지 않은 선택이 있을 때 q를 한 번 받았다는 표시입니다.
+	quitting bool
+}
+
+const browseHelp = `commands:
+  N [N...]     include or exclude the fields in rows N (a range such as 3-7 works too)
+  o N          expand or collapse the object in row N
+  e, c         expand or collapse every object
+  f TEXT       expand the tree to every field whose path contains TEXT
+  i N          show the mapping, Arrow type and warnings of row N
+  l            list the fields currently excluded
+  w [FILE]     save the selection (default: the --fields file)
+  q            quit
+`
+
+func setupBrowse(fs *flag.FlagSet) func(ctx context.Context, report *runReport, args []string) error {
+	var o browseOptions
+	for _, name := range []string{"mapping", "m"} {
+		fs.StringVar(&o.mappingPath, name, "", "mapping JSON file to browse (default: the mapping recorded in --fields)")
+	}
+	fs.StringVar(&o.fieldsPath, "fields", "fields.json", "field selection file to start from, if it exists, and to save to")
+
+	return func(ctx context.Context, report *runReport, args []string) error {
+		if len(args) > 0 {
+			return configErrorf("browse: unexpected arguments %v", args)
+		}
+		sel, err := readFieldSelection(o.fieldsPath)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			sel = &fieldSelection{}
+		case err != nil:
+			return err
+		}
+		if o.mappingPath == "" {
+			o.mappingPath = sel.Mapping
+		}
+		if o.mappingPath == "" {
+			return configErrorf("browse: --mapping is required")
+		}
+		data, err := os.ReadFile(o.mappingPath)
+		if err != nil {
+			return configErrorf("reading mapping: %w", err)
+		}
+		roots, err := buildFieldTree(data)
+		if err != nil {
+			return schemaErrorf("parsing mapping %s: %w", o.mappingPath, err)
+		}
+
+		b := &browser{roots: roots, mappingPath: o.mappingPath, savePath: o.fieldsPath, out: os.Stdout}
+		for _, path := range sel.Exclude {
+			if n := b.find(path); n != nil {
+				n.excluded = true
+			} else {
+				report.warnf("%s excludes %s, which the mapping does not have", o.fieldsPath, path)
+			}
+		}
+		if err := b.run(ctx, os.Stdin); err != nil {
+			return err
+		}
+		if b.dirty {
+			report.warnf("the field selection changed but was not saved")
+		}
+		return nil
+	}
+}
+
+// buildFieldTree 함수는 매핑 JSON의 properties를 필드 트리로 만듭니다. 필드는 이름 순입니다.
+func buildFieldTree(mapping []byte) ([]*fieldNode, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal(mapping, &m); err != nil {
+		return nil, err
+	}
+	props, ok := m["properties"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("mapping has no top-level \"properties\" object")
+	}
+	return fieldNodes(props, "", 0), nil
+}
+
+func fieldNodes(props map[string]interface{}, prefix string, depth int) []*fieldNode {
+	var nodes []*fieldNode
+	for _, name := range sortedParams(props) {
+		fieldProps, ok := props[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		esType, ok := fieldProps["type"].(string)
+		if !ok {
+			esType = "object"
+		}
+		n := &fieldNode{name: name, path: prefix + name, depth: depth, esType: esType}
+		if esType == "object" || esType == "nested" {
+			n.arrowType = "struct"
+			if sub, ok := fieldProps["properties"].(map[string]interface{}); ok {
+				n.children = fieldNodes(sub, n.path+".", depth+1)
+			}
+		} else if t, err := esschema.FieldType(esType, fieldProps); err == nil {
+			n.arrowType = fmt.Sprint(t)
+		}
+		n.warnings = fieldWarnings(n.path, esType, fieldProps)
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// arrowNativeTypes는 esschema.FieldType이 전용 Arrow 타입으로 옮기는 Elasticsearch 타입입니다.
+// 나머지는 문자열 컬럼이 됩니다.
+var arrowNativeTypes = map[string]bool{
+	"text": true, "keyword": true, "integer": true, "long": true, "float": true, "double": true,
+	"boolean": true, "date": true, "date_nanos": true, "dense_vector": true, "nested": true, "object": true,
+	"geo_point": true, "ip": true, "scaled_float": true, "half_float": true, "unsigned_long": true,
+	"binary": true, "flattened": true, "integer_range": true, "long_range": true, "float_range": true,
+	"double_range": true, "date_range": true, "ip_range": true, "geo_shape": true,
+}
+
+// objectValueTypes는 값이 보통 JSON 객체인 타입입니다. 문자열 컬럼에는 객체를 넣을 수 없어
+// null이 됩니다.
+var objectValueTypes = map[string]bool{
+	"shape": true, "point": true, "join": true,
+	"histogram": true, "aggregate_metric_double": true,
+}
+
+// fieldWarnings 함수는 필드를 Arrow로 옮길 때 값이 바뀌거나 사라질 수 있는 경우를 설명합니다.
+func fieldWarnings(path, esType string, props map[string]interface{}) []string {
+	var warnings []string
+	switch {
+	case nonDataTypes[esType] != "":
+		w := fmt.Sprintf("%s %s; export leaves it out", esType, nonDataTypes[esType])
+		if esType == "percolator" {
+			w += " unless --non-data-fields json"
+		}
+		warnings = append(warnings, w)
+	case objectValueTypes[esType]:
+		warnings = append(warnings, fmt.Sprintf("%s values are usually JSON objects, which a utf8 column cannot hold; they are written as null", esType))
+	case !arrowNativeTypes[esType]:
+		warnings = append(warnings, fmt.Sprintf("%s has no Arrow type of its own; values are written as strings", esType))
+	}
+	switch esType {
+	case "date", "date_nanos":
+		warnings = append(warnings, "stored as timestamp[ns]; dates before 1677 or after 2262 do not fit unless --timestamp-unit is coarser")
+		if format, ok := props["format"].(string); ok {
+			if _, err := esschema.ParseDateFormat(format); err != nil {
+				warnings = append(warnings, fmt.Sprintf("%v; values are read as RFC 3339 strings or epoch milliseconds instead", err))
+			}
+		}
+	case "dense_vector":
+		if _, ok := props["dims"].(float64); !ok {
+			warnings = append(warnings, "no dims in the mapping; schemas fail unless --vector-dims infer, which takes the length from the first vector")
+		}
+		if props["element_type"] == "bit" {
+			warnings = append(warnings, "bit vectors are stored as int8 bytes, dims/8 per vector; hex string values are written as null")
+		}
+	case "flattened":
+		warnings = append(warnings, "stored as map<utf8, utf8> keyed by dotted paths; numbers and booleans become text and arrays become JSON text")
+	case "geo_shape":
+		warnings = append(warnings, "stored as WKB binary, a GeoParquet geometry column when top-level; circles have no WKB form and are written as null unless --geo-shape json")
+	case "nested":
+		if props["include_in_parent"] == true || props["include_in_root"] == true {
+			warnings = append(warnings, "include_in_parent/include_in_root: the index also copies these values into the parent document; export writes them once, as nested objects or with --include-in-parent-as parent as the parent's value lists")
+		}
+	}
+	if esType == "object" || esType == "nested" {
+		if enabled, ok := props["enabled"].(bool); ok && !enabled {
+			warnings = append(warnings, "enabled: false; the object has no mapped sub-fields and is written as an empty struct")
+		} else if _, ok := props["properties"]; !ok {
+			warnings = append(warnings, "no mapped sub-fields; written as an empty struct")
+		}
+	}
+	if multi, ok := props["fields"].(map[string]interface{}); ok {
+		warnings = append(warnings, fmt.Sprintf("multi-fields %s exist only in the index and are not exported", strings.Join(sortedParams(multi), ", ")))
+	}
+	return warnings
+}
+
+// run 함수는 입력에서 명령을 한 줄씩 읽어 실행합니다. 입력이 끝나거나 q로 끝냅니다.
+// 입력을 기다리는 동안에도 ctx가 취소되면 바로 돌아갑니다.
+func (b *browser) run(ctx context.Context, in io.Reader) error {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	b.render()
+	fmt.Fprintln(b.out, "type ? for help")
+	for {
+		fmt.Fprint(b.out, "browse> ")
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(b.out)
+			return ctx.Err()
+		case line, ok := <-lines:
+			if !ok {
+				fmt.Fprintln(b.out)
+				return nil
+			}
+			if b.exec(strings.TrimSpace(line)) {
+				return nil
+			}
+		}
+	}
+}
+
+// exec 함수는 명령 한 줄을 실행하고, 세션을 끝내야 하면 true를 반환합니다.
+func (b *browser) exec(line string) bool {
+	if line == "" {
+		return false
+	}
+	cmd, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+	if cmd != "q" {
+		b.quitting = false
+	}
+	switch cmd {
+	case "?", "h", "help":
+		fmt.Fprint(b.out, browseHelp)
+	case "q":
+		if b.dirty && !b.quitting {
+			b.quitting = true
+			fmt.Fprintln(b.out, "the selection has unsaved changes; w to save them or q again to quit")
+			return false
+		}
+		return true
+	case "o":
+		if n := b.row(arg); n != nil {
+			if len(n.children) == 0 {
+				fmt.Fprintf(b.out, "%s has no sub-fields\n", n.path)
+				return false
+			}
+			n.expanded = !n.expanded
+			b.render()
+		}
+	case "e", "c":
+		walkFields(b.roots, func(n *fieldNode) { n.expanded = cmd == "e" && len(n.children) > 0 })
+		b.render()
+	case "f":
+		if arg == "" {
+			fmt.Fprintln(b.out, "f needs some text to look for")
+			return false
+		}
+		if found := b.reveal(b.roots, arg); found == 0 {
+			fmt.Fprintf(b.out, "no field path contains %q\n", arg)
+			return false
+		}
+		b.render()
+	case "i":
+		if n := b.row(arg); n != nil {
+			b.describe(n)
+		}
+	case "l":
+		paths := b.excludedPaths()
+		if len(paths) == 0 {
+			fmt.Fprintln(b.out, "no fields are excluded")
+		}
+		for _, p := range paths {
+			fmt.Fprintf(b.out, "  %s\n", p)
+		}
+	case "w":
+		path := arg
+		if path == "" {
+			path = b.savePath
+		}
+		if err := b.save(path); err != nil {
+			fmt.Fprintf(b.out, "saving %s: %v\n", path, err)
+		}
+	default:
+		b.toggle(line)
+	}
+	return false
+}
+
+// toggle 함수는 "3 5-7" 같은 행 번호 목록의 필드를 포함하거나 제외합니다.
+func (b *browser) toggle(spec string) {
+	var nodes []*fieldNode
+	for _, part := range strings.Fields(strings.ReplaceAll(spec, ",", " ")) {
+		from, to, isRange := strings.Cut(part, "-")
+		if !isRange {
+			to = from
+		}
+		first, err1 := strconv.Atoi(from)
+		last, err2 := strconv.Atoi(to)
+		if err1 != nil || err2 != nil || first < 1 || last < first || last > len(b.rows) {
+			fmt.Fprintf(b.out, "unknown command or row %q; type ? for help\n", part)
+			return
+		}
+		nodes = append(nodes, b.rows[first-1:last]...)
+	}
+	for _, n := range nodes {
+		if parent := b.excludedAncestor(n); parent != nil {
+			fmt.Fprintf(b.out, "%s is excluded with %s; include %s first\n", n.path, parent.path, parent.path)
+			continue
+		}
+		n.excluded = !n.excluded
+		b.dirty = true
+	}
+	b.render()
+}
+
+func (b *browser) row(arg string) *fieldNode {
+	i, err := strconv.Atoi(arg)
+	if err != nil || i < 1 || i > len(b.rows) {
+		fmt.Fprintf(b.out, "no row %q\n", arg)
+		return nil
+	}
+	return b.rows[i-1]
+}
+
+// find 함수는 점으로 이은 경로의 필드를 찾습니다.
+func (b *browser) find(path string) *fieldNode {
+	var found *fieldNode
+	walkFields(b.roots, func(n *fieldNode) {
+		if n.path == path {
+			found = n
+		}
+	})
+	return found
+}
+
+// excludedAncestor 함수는 n을 품고 있는 제외된 객체 필드를 찾습니다.
+func (b *browser) excludedAncestor(n *fieldNode) *fieldNode {
+	var parent *fieldNode
+	for _, p := range b.ancestors(n) {
+		if p.excluded {
+			parent = p
+		}
+	}
+	return parent
+}
+
+func (b *browser) ancestors(n *fieldNode) []*fieldNode {
+	var out []*fieldNode
+	for i, c := range n.path {
+		if c == '.' {
+			out = append(out, b.find(n.path[:i]))
+		}
+	}
+	return out
+}
+
+// reveal 함수는 경로에 text가 들어간 필드가 보이도록 그 위 객체를 펼치고, 찾은 수를 반환합니다.
+func (b *browser) reveal(nodes []*fieldNode, text string) int {
+	found := 0
+	for _, n := range nodes {
+		inside := b.reveal(n.children, text)
+		if inside > 0 {
+			n.expanded = true
+		}
+		found += inside
+		if strings.Contains(n.path, text) {
+			found++
+		}
+	}
+	return found
+}
+
+// excludedPaths 함수는 제외된 필드의 경로를 반환합니다. 제외된 객체 안의 필드는 객체와
+// 함께 빠지므로 따로 적지 않습니다.
+func (b *browser) excludedPaths() []string {
+	var paths []string
+	var walk func(nodes []*fieldNode)
+	walk = func(nodes []*fieldNode) {
+		for _, n := range nodes {
+			if n.excluded {
+				paths = append(paths, n.path)
+				continue
+			}
+			walk(n.children)
+		}
+	}
+	walk(b.roots)
+	return paths
+}
+
+// save 함수는 선택을 필드 선택 파일로 씁니다.
+func (b *browser) save(path string) error {
+	sel := fieldSelection{Mapping: b.mappingPath, Exclude: b.excludedPaths()}
+	if sel.Exclude == nil {
+		sel.Exclude = []string{}
+	}
+	if err := writeJSONFileAtomic(path, sel); err != nil {
+		return err
+	}
+	b.dirty = false
+	fmt.Fprintf(b.out, "saved %d excluded field(s) to %s; pass --fields %s to export or tune\n", len(sel.Exclude), path, path)
+	return nil
+}
+
+// render 함수는 펼쳐진 트리를 행 번호와 함께 표로 씁니다. [ ]는 제외된 필드, [~]는 일부
+// 하위 필드가 제외된 객체입니다. 경고가 있는 필드는 첫 경고를 보여 주고, 나머지는 i로 봅니다.
+func (b *browser) render() {
+	b.rows = b.rows[:0]
+	var collect func(nodes []*fieldNode)
+	collect = func(nodes []*fieldNode) {
+		for _, n := range nodes {
+			b.rows = append(b.rows, n)
+			if n.expanded {
+				collect(n.children)
+			}
+		}
+	}
+	collect(b.roots)
+
+	width := len("field")
+	for _, n := range b.rows {
+		if w := 2*n.depth + 2 + len(n.name); w > width {
+			width = w
+		}
+	}
+	fmt.Fprintf(b.out, "%4s %-3s %-*s %-14s %-22s %s\n", "#", "", width, "field", "es type", "arrow type", "warnings")
+	for i, n := range b.rows {
+		marker := "  "
+		if len(n.children) > 0 {
+			marker = "+ "
+			if n.expanded {
+				marker = "- "
+			}
+		}
+		warning := ""
+		if len(n.warnings) > 0 {
+			warning = "! " + n.warnings[0]
+			if len(n.warnings) > 1 {
+				warning += fmt.Sprintf(" (+%d more)", len(n.warnings)-1)
+			}
+		}
+		name := strings.Repeat("  ", n.depth) + marker + n.name
+		fmt.Fprintf(b.out, "%4d %-3s %-*s %-14s %-22s %s\n", i+1, b.mark(n), width, name, n.esType, n.arrowType, warning)
+	}
+	fields, excluded := 0, 0
+	walkFields(b.roots, func(n *fieldNode) {
+		fields++
+		if n.excluded || b.excludedAncestor(n) != nil {
+			excluded++
+		}
+	})
+	fmt.Fprintf(b.out, "%d fields, %d excluded\n", fields, excluded)
+}
+
+func (b *browser) mark(n *fieldNode) string {
+	if n.excluded || b.excludedAncestor(n) != nil {
+		return "[ ]"
+	}
+	partial := false
+	walkFields(n.children, func(c *fieldNode) { partial = partial || c.excluded })
+	if partial {
+		return "[~]"
+	}
+	return "[x]"
+}
+
+// describe 함수는 필드 하나의 자세한 정보를 씁니다.
+func (b *browser) describe(n *fieldNode) {
+	fmt.Fprintf(b.out, "%s\n  es type:    %s\n  arrow type: %s\n", n.path, n.esType, n.arrowType)
+	if len(n.children) > 0 {
+		fmt.Fprintf(b.out, "  sub-fields: %d\n", len(n.children))
+	}
+	state := "included"
+	if parent := b.excludedAncestor(n); parent != nil {
+		state = "excluded with " + parent.path
+	} else if n.excluded {
+		state = "excluded"
+	}
+	fmt.Fprintf(b.out, "  state:      %s\n", state)
+	for _, w := range n.warnings {
+		fmt.Fprintf(b.out, "  ! %s\n", w)
+	}
+}
+
+// walkFields 함수는 nodes와 그 아래의 모든 필드에 fn을 호출합니다.
+func walkFields(nodes []*fieldNode, fn func(*fieldNode)) {
+	for _, n := range nodes {
+		fn(n)
+		walkFields(n.children, fn)
+	}
+}
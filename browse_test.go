@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBrowseSession(t *testing.T) {
+	mapping := `{"properties": {
+		"age": {"type": "long"},
+		"location": {"type": "geo_shape"},
+		"user": {"properties": {"name": {"type": "text"}, "secret": {"type": "keyword"}}}
+	}}`
+	roots, err := buildFieldTree([]byte(mapping))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := roots[1].warnings; len(w) != 1 || !strings.Contains(w[0], "written as null") {
+		t.Errorf("location warnings = %q", w)
+	}
+
+	path := filepath.Join(t.TempDir(), "fields.json")
+	var out bytes.Buffer
+	b := &browser{roots: roots, mappingPath: "mapping.json", savePath: path, out: &out}
+	// 1 age, 2 location, 3 user; o 3 펼치면 4 user.name, 5 user.secret
+	input := "o 3\n2 5\nw\nq\n"
+	if err := b.run(context.Background(), strings.NewReader(input)); err != nil {
+		t.Fatal(err)
+	}
+	sel, err := readFieldSelection(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fieldSelection{Mapping: "mapping.json", Exclude: []string{"location", "user.secret"}}
+	if !reflect.DeepEqual(*sel, want) {
+		t.Errorf("saved %+v, want %+v\n%s", *sel, want, out.String())
+	}
+	if !strings.Contains(out.String(), "[~]") {
+		t.Errorf("user is not shown as partly excluded:\n%s", out.String())
+	}
+}
+
+func TestBrowseKeepsChildrenOfExcludedObject(t *testing.T) {
+	roots, err := buildFieldTree([]byte(`{"properties": {"user": {"properties": {"name": {"type": "text"}}}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	b := &browser{roots: roots, out: &out}
+	b.render()
+	for _, line := range []string{"o 1", "1", "2"} {
+		b.exec(line)
+	}
+	if got := b.excludedPaths(); !reflect.DeepEqual(got, []string{"user"}) {
+		t.Errorf("excluded = %v, want [user]", got)
+	}
+	if !strings.Contains(out.String(), "user.name is excluded with user") {
+		t.Errorf("toggling a field inside an excluded object was not refused:\n%s", out.String())
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// errBudgetExceeded는 --max-bytes-out이나 --max-duration 한도에 이르러 scroll을 멈출 때
+// writePage가 반환하는 오류입니다. 실패가 아니므로 인덱스는 그때까지 쓴 문서로 파일을 닫습니다.
+var errBudgetExceeded = errors.New("export budget exceeded")
+
+// budgetOptions는 export 실행 전체에 걸친 출력 크기와 시간 한도입니다. 한도는 scroll
+// 페이지를 쓸 때마다 확인하므로, 동시에 쓰는 scroll마다 한 페이지(buffered 모드에서는
+// 한 row group)만큼 넘칠 수 있습니다.
+type budgetOptions struct {
+	maxBytes    byteSizeFlag
+	maxDuration time.Duration
+
+	mu      sync.Mutex
+	started time.Time
+	used    int64
+	// reason은 처음 이른 한도로, 모든 인덱스가 같은 이유를 보고합니다.
+	reason string
+}
+
+func (o *budgetOptions) bind(fs *flag.FlagSet) {
+	fs.Var(&o.maxBytes, "max-bytes-out", "stop the export once the Parquet files of the run reach this size, such as 500gb (0 for no limit); every index keeps the documents written so far and gets an <index>.checkpoint.json")
+	fs.DurationVar(&o.maxDuration, "max-duration", 0, "stop the export after this long, such as 2h (0 for no limit); every index keeps the documents written so far and gets an <index>.checkpoint.json")
+}
+
+func (o *budgetOptions) validate() error {
+	if o.maxBytes < 0 || o.maxDuration < 0 {
+		return configErrorf("export: --max-bytes-out and --max-duration cannot be negative")
+	}
+	return nil
+}
+
+func (o *budgetOptions) enabled() bool {
+	return o.maxBytes > 0 || o.maxDuration > 0
+}
+
+// start 함수는 --max-duration을 재기 시작합니다.
+func (o *budgetOptions) start() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.started = time.Now()
+}
+
+// add 함수는 출력 파일에 새로 쓴 n 바이트를 셉니다.
+func (o *budgetOptions) add(n int64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.used += n
+}
+
+// exceeded 함수는 한도에 이르렀으면 그 이유를, 아니면 빈 문자열을 반환합니다.
+func (o *budgetOptions) exceeded() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.reason == "" {
+		switch {
+		case o.maxBytes > 0 && o.used >= int64(o.maxBytes):
+			o.reason = fmt.Sprintf("--max-bytes-out %s reached", o.maxBytes.String())
+		case o.maxDuration > 0 && !o.started.IsZero() && time.Since(o.started) >= o.maxDuration:
+			o.reason = fmt.Sprintf("--max-duration %s reached", o.maxDuration)
+		}
+	}
+	return o.reason
+}
+
+// check 함수는 한도에 이르렀으면 errBudgetExceeded를 반환합니다.
+func (o *budgetOptions) check() error {
+	if o.enabled() && o.exceeded() != "" {
+		return errBudgetExceeded
+	}
+	return nil
+}
+
+// exportCheckpoint는 한도에 걸려 멈춘 인덱스가 어디까지 썼는지 남기는 파일입니다. 데이터
+// 파일은 Rows개 문서로 온전히 닫혀 있어 그대로 읽을 수 있고, Total은 내보내려던 문서 수입니다.
+type exportCheckpoint struct {
+	Index     string    `json:"index"`
+	Reason    string    `json:"reason"`
+	File      string    `json:"file"`
+	Rows      int64     `json:"rows"`
+	Total     int64     `json:"total"`
+	Bytes     int64     `json:"bytes"`
+	StoppedAt time.Time `json:"stopped_at"`
+}
+
+func checkpointPath(outDir, index string) string {
+	return filepath.Join(outDir, index+".checkpoint.json")
+}
+
+// writeCheckpoint 함수는 c를 path에 JSON으로 씁니다.
+func writeCheckpoint(path string, c exportCheckpoint) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return configErrorf("writing checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestByteSizeFlag(t *testing.T) {
+	for in, want := range map[string]int64{
+		"0":     0,
+		"1500":  1500,
+		"512b":  512,
+		"10kb":  10 << 10,
+		"1.5MB": 3 << 19,
+		"2g":    2 << 30,
+		"3 GiB": 3 << 30,
+		"1tb":   1 << 40,
+	} {
+		var b byteSizeFlag
+		if err := b.Set(in); err != nil || int64(b) != want {
+			t.Errorf("Set(%q) = %d, %v; want %d", in, b, err, want)
+		}
+	}
+	for _, in := range []string{"", "gb", "10xb", "-1mb", "1e"} {
+		var b byteSizeFlag
+		if err := b.Set(in); err == nil {
+			t.Errorf("Set(%q) accepted %d", in, b)
+		}
+	}
+}
+
+func TestBudgetOptions(t *testing.T) {
+	var unlimited budgetOptions
+	unlimited.start()
+	unlimited.add(1 << 40)
+	if err := unlimited.check(); err != nil {
+		t.Errorf("budget without limits = %v", err)
+	}
+
+	o := budgetOptions{maxBytes: 100}
+	o.start()
+	o.add(60)
+	if err := o.check(); err != nil {
+		t.Fatalf("60 of 100 bytes = %v", err)
+	}
+	o.add(40)
+	if err := o.check(); !errors.Is(err, errBudgetExceeded) || o.exceeded() != "--max-bytes-out 100 B reached" {
+		t.Errorf("100 of 100 bytes = %v, %q", err, o.exceeded())
+	}
+
+	d := budgetOptions{maxDuration: time.Millisecond}
+	d.start()
+	time.Sleep(5 * time.Millisecond)
+	if err := d.check(); !errors.Is(err, errBudgetExceeded) || d.exceeded() != "--max-duration 1ms reached" {
+		t.Errorf("after --max-duration = %v, %q", err, d.exceeded())
+	}
+}
+
+func TestFinishCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	opts := &exportOptions{outDir: dir}
+	j := &exportJob{opts: opts, index: "logs", path: filepath.Join(dir, "logs.parquet"), rows: 20, total: 50, spent: 4096, stoppedBy: "--max-duration 1h0m0s reached"}
+	if err := j.finishCheckpoint(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "logs.checkpoint.json"))
+	if err != nil || j.checkpointFile == "" || len(j.warnings) != 1 {
+		t.Fatalf("checkpoint %q, warnings %v: %v", j.checkpointFile, j.warnings, err)
+	}
+	var c exportCheckpoint
+	json.Unmarshal(data, &c)
+	if c.Index != "logs" || c.Rows != 20 || c.Total != 50 || c.Bytes != 4096 || c.Reason != j.stoppedBy {
+		t.Errorf("checkpoint = %+v", c)
+	}
+
+	// 다음 실행이 끝까지 내보내면 남은 checkpoint를 지웁니다.
+	done := &exportJob{opts: opts, index: "logs"}
+	if err := done.finishCheckpoint(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "logs.checkpoint.json")); !os.IsNotExist(err) {
+		t.Errorf("stale checkpoint kept: %v", err)
+	}
+}
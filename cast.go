@@ -0,0 +1,444 @@
+package main
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+	"github.com/apache/arrow/go/v10/arrow/memory"
+
+	"es-schema/esschema"
+)
+
+// stagingType 함수는 Go 값 v를 손실 없이 담는 Arrow 타입을 반환합니다. 컬럼은 먼저 이
+// 타입으로 만든 뒤 castColumn으로 매핑의 타입에 맞춥니다. 담을 수 없는 값이면 nil입니다.
+func stagingType(v interface{}) arrow.DataType {
+	switch v.(type) {
+	case int, int32, int64:
+		return arrow.PrimitiveTypes.Int64
+	case float32:
+		return arrow.PrimitiveTypes.Float32
+	case float64:
+		return arrow.PrimitiveTypes.Float64
+	case string:
+		return arrow.BinaryTypes.String
+	case bool:
+		return arrow.FixedWidthTypes.Boolean
+	case time.Time:
+		return arrow.FixedWidthTypes.Timestamp_ns
+	}
+	return nil
+}
+
+// castable 함수는 dt가 castColumn으로 만들 수 있는 원시 타입인지 알려 줍니다. 중첩 타입은
+// 빌더로 직접 만듭니다.
+func castable(dt arrow.DataType) bool {
+	switch dt.ID() {
+	case arrow.INT32, arrow.INT64, arrow.FLOAT32, arrow.FLOAT64, arrow.STRING, arrow.BOOL, arrow.TIMESTAMP:
+		return true
+	}
+	return false
+}
+
+// columnStagingType 함수는 values 전체를 담을 staging 타입을 고릅니다. 정수와 실수가
+// 섞인 컬럼은 float64로 모으고, 그 밖에는 첫 번째 null이 아닌 값의 타입을 씁니다.
+func columnStagingType(values []interface{}) arrow.DataType {
+	var staging arrow.DataType
+	for _, v := range values {
+		t := stagingType(v)
+		switch {
+		case t == nil:
+		case staging == nil:
+			staging = t
+		case !arrow.TypeEqual(staging, t) && isNumericStaging(staging) && isNumericStaging(t):
+			staging = arrow.PrimitiveTypes.Float64
+		}
+	}
+	return staging
+}
+
+func isNumericStaging(dt arrow.DataType) bool {
+	switch dt.ID() {
+	case arrow.INT64, arrow.FLOAT32, arrow.FLOAT64:
+		return true
+	}
+	return false
+}
+
+// stagedColumn 함수는 values를 staging 타입 컬럼으로 만든 뒤 dt로 바꿉니다. 타입이 다른
+// 값과 dt로 바꿀 수 없는 값(넘침, 소수점이 있는 정수 필드 값, 숫자가 아닌 문자열 등)은
+// null이 되고, 그 수를 함께 반환합니다.
+func stagedColumn(ctx context.Context, dt arrow.DataType, values []interface{}) (arrow.Array, int, error) {
+	staging := columnStagingType(values)
+	if staging == nil {
+		return array.MakeArrayOfNull(memory.DefaultAllocator, dt, len(values)), countNonNil(values), nil
+	}
+	staged, mismatched := buildStaged(staging, values)
+	defer staged.Release()
+	out, nullified, err := castColumn(ctx, staged, dt)
+	return out, mismatched + nullified, err
+}
+
+// parseTimestamps 함수는 date 필드의 값을 필드의 형식 df(nil이면 기본 형식
+// strict_date_optional_time||epoch_millis)로 읽어 time.Time으로 바꾼 복사본을 반환합니다.
+// 시간대 오프셋과 epoch 숫자까지 읽으려고 cast 커널 대신 esschema가 읽습니다. 읽지 못한
+// 값은 그대로 두어 null이 됩니다.
+func parseTimestamps(values []interface{}, df *esschema.DateFormat) []interface{} {
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		out[i] = v
+		if v == nil {
+			continue
+		}
+		if t, ok := df.Time(v); ok {
+			out[i] = t
+		}
+	}
+	return out
+}
+
+func countNonNil(values []interface{}) int {
+	n := 0
+	for _, v := range values {
+		if v != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// buildStaged 함수는 values를 staging 타입 dt의 배열로 만듭니다. dt에 담을 수 없는 값은
+// null로 두고 그 수를 반환합니다.
+func buildStaged(dt arrow.DataType, values []interface{}) (arrow.Array, int) {
+	b := array.NewBuilder(memory.DefaultAllocator, dt)
+	defer b.Release()
+	mismatched := 0
+	for _, v := range values {
+		if v == nil {
+			b.AppendNull()
+			continue
+		}
+		if !appendStaged(b, v) {
+			b.AppendNull()
+			mismatched++
+		}
+	}
+	return b.NewArray(), mismatched
+}
+
+// appendStaged 함수는 v를 staging 빌더 b에 더하고, 담을 수 없는 값이면 false를 반환합니다.
+func appendStaged(b array.Builder, v interface{}) bool {
+	switch b := b.(type) {
+	case *array.Int64Builder:
+		switch x := v.(type) {
+		case int:
+			b.Append(int64(x))
+		case int32:
+			b.Append(int64(x))
+		case int64:
+			b.Append(x)
+		default:
+			return false
+		}
+	case *array.Float64Builder:
+		switch x := v.(type) {
+		case int:
+			b.Append(float64(x))
+		case int32:
+			b.Append(float64(x))
+		case int64:
+			b.Append(float64(x))
+		case float32:
+			b.Append(float64(x))
+		case float64:
+			b.Append(x)
+		default:
+			return false
+		}
+	case *array.Float32Builder:
+		x, ok := v.(float32)
+		if !ok {
+			return false
+		}
+		b.Append(x)
+	case *array.StringBuilder:
+		x, ok := v.(string)
+		if !ok {
+			return false
+		}
+		b.Append(x)
+	case *array.BooleanBuilder:
+		x, ok := v.(bool)
+		if !ok {
+			return false
+		}
+		b.Append(x)
+	case *array.TimestampBuilder:
+		x, ok := v.(time.Time)
+		if !ok {
+			return false
+		}
+		b.Append(arrow.Timestamp(x.UnixNano()))
+	default:
+		return false
+	}
+	return true
+}
+
+// castOptions는 castColumn의 변환 규칙입니다. 모두 false이면 값이 바뀌는 변환을 모두
+// 실패로 봅니다.
+type castOptions struct {
+	// allowIntOverflow이면 대상 정수 타입의 범위를 넘는 값을 잘라 씁니다.
+	allowIntOverflow bool
+	// allowFloatTruncate이면 실수를 정수로 바꿀 때 소수점 아래를 버리고, 실수 타입이
+	// 정확히 담지 못하는 큰 정수도 가장 가까운 값으로 씁니다.
+	allowFloatTruncate bool
+	// allowTimeTruncate이면 대상 단위보다 정밀한 시각을 그 단위로 자릅니다.
+	allowTimeTruncate bool
+}
+
+// defaultCastOptions 함수는 castColumn이 쓰는 변환 규칙입니다. 값이 바뀌는 넘침과 실수
+// 잘림은 막지만, 매핑보다 정밀한 시각은 매핑의 단위로 자릅니다.
+func defaultCastOptions() castOptions {
+	return castOptions{allowTimeTruncate: true}
+}
+
+// castColumn 함수는 staging 배열 arr을 dt 타입으로 바꿉니다. 바꿀 수 없는 값(opts가
+// 허용하지 않는 넘침이나 잘림, 숫자가 아닌 문자열 등)은 null이 되고, null로 만든 값의
+// 수를 함께 반환합니다.
+func castColumn(ctx context.Context, arr arrow.Array, dt arrow.DataType) (arrow.Array, int, error) {
+	if arrow.TypeEqual(arr.DataType(), dt) {
+		arr.Retain()
+		return arr, 0, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	opts := defaultCastOptions()
+	b := array.NewBuilder(memory.DefaultAllocator, dt)
+	defer b.Release()
+	nullified := 0
+	for i := 0; i < arr.Len(); i++ {
+		if arr.IsNull(i) {
+			b.AppendNull()
+			continue
+		}
+		if !castValue(b, stagedValue(arr, i), opts) {
+			b.AppendNull()
+			nullified++
+		}
+	}
+	return b.NewArray(), nullified, nil
+}
+
+// stagedValue 함수는 staging 배열 arr의 i번째 값을 Go 값으로 반환합니다. 시각은 나노초
+// 단위의 arrow.Timestamp입니다.
+func stagedValue(arr arrow.Array, i int) interface{} {
+	switch a := arr.(type) {
+	case *array.Int64:
+		return a.Value(i)
+	case *array.Float32:
+		return a.Value(i)
+	case *array.Float64:
+		return a.Value(i)
+	case *array.String:
+		return a.Value(i)
+	case *array.Boolean:
+		return a.Value(i)
+	case *array.Timestamp:
+		return a.Value(i)
+	}
+	return nil
+}
+
+// castValue 함수는 staging 값 v를 b의 타입으로 바꿔 더하고, 바꿀 수 없으면 false를
+// 반환합니다.
+func castValue(b array.Builder, v interface{}, opts castOptions) bool {
+	switch b := b.(type) {
+	case *array.Int32Builder:
+		x, ok := castInt(v, math.MinInt32, math.MaxInt32, opts)
+		if ok {
+			b.Append(int32(x))
+		}
+		return ok
+	case *array.Int64Builder:
+		x, ok := castInt(v, math.MinInt64, math.MaxInt64, opts)
+		if ok {
+			b.Append(x)
+		}
+		return ok
+	case *array.Float32Builder:
+		x, ok := castFloat(v, opts)
+		if ok {
+			b.Append(float32(x))
+		}
+		return ok
+	case *array.Float64Builder:
+		x, ok := castFloat(v, opts)
+		if ok {
+			b.Append(x)
+		}
+		return ok
+	case *array.StringBuilder:
+		x, ok := castString(v)
+		if ok {
+			b.Append(x)
+		}
+		return ok
+	case *array.BooleanBuilder:
+		x, ok := castBool(v)
+		if ok {
+			b.Append(x)
+		}
+		return ok
+	case *array.TimestampBuilder:
+		unit := b.Type().(*arrow.TimestampType).Unit
+		x, ok := castTimestamp(v, unit, opts)
+		if ok {
+			b.Append(x)
+		}
+		return ok
+	}
+	return false
+}
+
+// castInt 함수는 v를 [min, max] 범위의 정수로 바꿉니다.
+func castInt(v interface{}, min, max int64, opts castOptions) (int64, bool) {
+	var f float64
+	switch x := v.(type) {
+	case int64:
+		if x < min || x > max {
+			return clamp(x, min, max), opts.allowIntOverflow
+		}
+		return x, true
+	case bool:
+		if x {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		n, err := strconv.ParseInt(x, 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		return castInt(n, min, max, opts)
+	case float32:
+		f = float64(x)
+	case float64:
+		f = x
+	default:
+		return 0, false
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
+	}
+	if math.Trunc(f) != f && !opts.allowFloatTruncate {
+		return 0, false
+	}
+	// float64(math.MaxInt64)는 2^63으로 올림되므로 크거나 같은 값은 넘침입니다.
+	if f < float64(min) || f >= float64(max)+1 {
+		if !opts.allowIntOverflow {
+			return 0, false
+		}
+		if f < 0 {
+			return min, true
+		}
+		return max, true
+	}
+	return int64(f), true
+}
+
+func clamp(x, min, max int64) int64 {
+	if x < min {
+		return min
+	}
+	if x > max {
+		return max
+	}
+	return x
+}
+
+// castFloat 함수는 v를 실수로 바꿉니다. float64가 정확히 담지 못하는 큰 정수는
+// allowFloatTruncate일 때만 바꿉니다.
+func castFloat(v interface{}, opts castOptions) (float64, bool) {
+	switch x := v.(type) {
+	case int64:
+		f := float64(x)
+		if f >= math.MaxInt64 || int64(f) != x {
+			return f, opts.allowFloatTruncate
+		}
+		return f, true
+	case float32:
+		return float64(x), true
+	case float64:
+		return x, true
+	case bool:
+		if x {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		f, err := strconv.ParseFloat(x, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func castString(v interface{}) (string, bool) {
+	switch x := v.(type) {
+	case string:
+		return x, true
+	case int64:
+		return strconv.FormatInt(x, 10), true
+	case float32:
+		return strconv.FormatFloat(float64(x), 'g', -1, 32), true
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64), true
+	case bool:
+		return strconv.FormatBool(x), true
+	case arrow.Timestamp:
+		return time.Unix(0, int64(x)).UTC().Format(time.RFC3339Nano), true
+	}
+	return "", false
+}
+
+func castBool(v interface{}) (bool, bool) {
+	switch x := v.(type) {
+	case bool:
+		return x, true
+	case int64:
+		return x != 0, true
+	case float32:
+		return x != 0, true
+	case float64:
+		return x != 0, true
+	case string:
+		switch strings.ToLower(x) {
+		case "true", "1":
+			return true, true
+		case "false", "0":
+			return false, true
+		}
+	}
+	return false, false
+}
+
+// castTimestamp 함수는 나노초 단위의 시각이나 unit 단위의 정수 v를 unit 단위의 시각으로
+// 바꿉니다.
+func castTimestamp(v interface{}, unit arrow.TimeUnit, opts castOptions) (arrow.Timestamp, bool) {
+	switch x := v.(type) {
+	case arrow.Timestamp:
+		per := int64(unit.Multiplier())
+		if int64(x)%per != 0 && !opts.allowTimeTruncate {
+			return 0, false
+		}
+		return arrow.Timestamp(int64(x) / per), true
+	case int64:
+		return arrow.Timestamp(x), true
+	}
+	return 0, false
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+)
+
+func TestColumnStagingType(t *testing.T) {
+	cases := []struct {
+		values []interface{}
+		want   arrow.DataType
+	}{
+		{[]interface{}{nil, 10001, int64(2)}, arrow.PrimitiveTypes.Int64},
+		{[]interface{}{1, 2.5}, arrow.PrimitiveTypes.Float64},
+		{[]interface{}{float32(1), 2}, arrow.PrimitiveTypes.Float64},
+		{[]interface{}{"a", 1}, arrow.BinaryTypes.String},
+		{[]interface{}{time.Now(), "x"}, arrow.FixedWidthTypes.Timestamp_ns},
+		{[]interface{}{nil, []string{"a"}}, nil},
+	}
+	for _, c := range cases {
+		got := columnStagingType(c.values)
+		if (got == nil) != (c.want == nil) || got != nil && !arrow.TypeEqual(got, c.want) {
+			t.Errorf("columnStagingType(%v) = %v, want %v", c.values, got, c.want)
+		}
+	}
+}
+
+func TestParseTimestamps(t *testing.T) {
+	in := []interface{}{"2024-01-02T03:04:05+09:00", "yesterday", nil, 1704132245000.0}
+	got := parseTimestamps(in, nil)
+	want := time.Date(2024, 1, 1, 18, 4, 5, 0, time.UTC)
+	if ts, ok := got[0].(time.Time); !ok || !ts.Equal(want) {
+		t.Errorf("got[0] = %v", got[0])
+	}
+	if got[1] != "yesterday" || got[2] != nil {
+		t.Errorf("unparsed values changed: %v", got[1:3])
+	}
+	if ts, ok := got[3].(time.Time); !ok || !ts.Equal(want) {
+		t.Errorf("epoch milliseconds = %v", got[3])
+	}
+	if in[0] != "2024-01-02T03:04:05+09:00" {
+		t.Error("parseTimestamps modified its input")
+	}
+}
+
+func TestCastColumn(t *testing.T) {
+	ctx := context.Background()
+	cases := []struct {
+		values []interface{}
+		to     arrow.DataType
+		want   string
+		nulled int
+	}{
+		{[]interface{}{1, 3000000000, nil}, arrow.PrimitiveTypes.Int32, "[1 (null) (null)]", 1},
+		{[]interface{}{1.0, 2.5, -1e300}, arrow.PrimitiveTypes.Int64, "[1 (null) (null)]", 2},
+		{[]interface{}{int64(1) << 53, int64(1)<<53 + 1}, arrow.PrimitiveTypes.Float64, "[9.007199254740992e+15 (null)]", 1},
+		{[]interface{}{"12", "x", "-3"}, arrow.PrimitiveTypes.Int64, "[12 (null) -3]", 1},
+		{[]interface{}{"true", "0", "yes"}, arrow.FixedWidthTypes.Boolean, "[true false (null)]", 1},
+		{[]interface{}{int64(7), 0.5}, arrow.BinaryTypes.String, `["7" "0.5"]`, 0},
+	}
+	for _, c := range cases {
+		staged, mismatched := buildStaged(columnStagingType(c.values), c.values)
+		got, n, err := castColumn(ctx, staged, c.to)
+		staged.Release()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != c.want || n+mismatched != c.nulled {
+			t.Errorf("cast %v to %s = %s with %d nulled, want %s with %d", c.values, c.to, got, n+mismatched, c.want, c.nulled)
+		}
+		got.Release()
+	}
+
+	// 매핑보다 정밀한 시각은 매핑의 단위로 자릅니다.
+	at := time.Date(2024, 1, 2, 3, 4, 5, 678900000, time.UTC)
+	staged, _ := buildStaged(arrow.FixedWidthTypes.Timestamp_ns, []interface{}{at})
+	defer staged.Release()
+	got, n, err := castColumn(ctx, staged, arrow.FixedWidthTypes.Timestamp_ms)
+	if err != nil || n != 0 {
+		t.Fatalf("cast to ms: %v, %d nulled", err, n)
+	}
+	defer got.Release()
+	if ms := got.(*array.Timestamp).Value(0); int64(ms) != at.UnixMilli() {
+		t.Errorf("timestamp in ms = %d, want %d", ms, at.UnixMilli())
+	}
+}
@@ -0,0 +1,457 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	catalogNone         = "none"
+	catalogDataHub      = "datahub"
+	catalogOpenMetadata = "openmetadata"
+)
+
+// catalogOptions는 내보낸 데이터셋을 데이터 카탈로그에 등록하는 설정입니다. 등록은
+// 인덱스를 모두 쓴 뒤에 성공한 인덱스마다 한 번 하고, 실패해도 내보낸 파일은 그대로 두고
+// 경고만 남깁니다.
+type catalogOptions struct {
+	kind        string
+	url         string
+	token       string
+	description string
+	// platform과 env는 DataHub 데이터셋 URN의 플랫폼과 환경입니다.
+	platform string
+	env      string
+	// schema는 OpenMetadata에서 테이블을 넣을 데이터베이스 스키마의 정규화된 이름
+	// (service.database.schema)입니다.
+	schema string
+	http   *http.Client
+}
+
+func (o *catalogOptions) bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.kind, "catalog", catalogNone, "after the export, register each index's dataset (schema with column lineage, description and Parquet statistics) with a data catalog: none, datahub or openmetadata")
+	fs.StringVar(&o.url, "catalog-url", "", "catalog API base URL, e.g. http://datahub-gms:8080 or http://openmetadata:8585")
+	fs.StringVar(&o.token, "catalog-token", "", "catalog API bearer token (default $CATALOG_TOKEN)")
+	fs.StringVar(&o.description, "catalog-description", "", "dataset description; {index} is replaced with the index name (default: a note naming the source index)")
+	fs.StringVar(&o.platform, "catalog-platform", "file", "DataHub data platform of the exported files, e.g. file or s3")
+	fs.StringVar(&o.env, "catalog-env", "PROD", "DataHub environment (fabric) of the datasets")
+	fs.StringVar(&o.schema, "catalog-schema", "", "OpenMetadata database schema to create the tables in, as service.database.schema (required for openmetadata)")
+}
+
+func (o *catalogOptions) validate() error {
+	switch o.kind {
+	case catalogNone:
+		return nil
+	case catalogDataHub, catalogOpenMetadata:
+	default:
+		return configErrorf("unknown --catalog %q (want none, datahub or openmetadata)", o.kind)
+	}
+	if o.url == "" {
+		return configErrorf("--catalog %s needs --catalog-url", o.kind)
+	}
+	if _, err := url.Parse(o.url); err != nil {
+		return configErrorf("invalid --catalog-url: %w", err)
+	}
+	if o.kind == catalogOpenMetadata && strings.Count(o.schema, ".") != 2 {
+		return configErrorf("--catalog openmetadata needs --catalog-schema as service.database.schema")
+	}
+	return nil
+}
+
+func (o *catalogOptions) enabled() bool {
+	return o.kind != "" && o.kind != catalogNone
+}
+
+// catalogDataset은 카탈로그에 등록할 인덱스 하나의 내보내기 결과입니다.
+type catalogDataset struct {
+	index    string
+	location string
+	columns  []columnLineage
+	profile  *datasetProfile
+}
+
+// datasetProfile은 내보낸 Parquet 파일의 footer 통계를 모은 것입니다.
+type datasetProfile struct {
+	rows    int64
+	bytes   int64
+	columns map[string]*columnSummary
+}
+
+// profileFiles 함수는 paths의 footer만 읽어 행 수, 크기, 컬럼별 null 수와 최솟값·최댓값을
+// 모읍니다. 컬럼은 lineage와 같은 점 경로로 찾을 수 있게 리스트 단계를 뺀 경로로 둡니다.
+func profileFiles(paths []string) (*datasetProfile, error) {
+	p := &datasetProfile{columns: make(map[string]*columnSummary)}
+	for _, path := range paths {
+		in, err := inspectParquetFile(path)
+		if err != nil {
+			return nil, err
+		}
+		p.rows += in.rows
+		p.bytes += in.size
+		for _, c := range in.columns {
+			name := lineageColumnPath(c.path)
+			cur, ok := p.columns[name]
+			if !ok {
+				copied := *c
+				p.columns[name] = &copied
+				continue
+			}
+			cur.nulls += c.nulls
+			cur.values += c.values
+			if cur.hasStats && c.hasStats {
+				cur.min, cur.max = minStat(cur.min, c.min), maxStat(cur.max, c.max)
+			} else {
+				cur.hasStats, cur.min, cur.max = false, nil, nil
+			}
+		}
+	}
+	return p, nil
+}
+
+// lineageColumnPath 함수는 Parquet 컬럼 경로에서 리스트가 만드는 list.element 단계를 뺍니다.
+func lineageColumnPath(path string) string {
+	var kept []string
+	parts := strings.Split(path, ".")
+	for i := 0; i < len(parts); i++ {
+		if parts[i] == "list" && i+1 < len(parts) && (parts[i+1] == "element" || parts[i+1] == "item") {
+			i++
+			continue
+		}
+		kept = append(kept, parts[i])
+	}
+	return strings.Join(kept, ".")
+}
+
+// descriptionFor 함수는 index 데이터셋의 설명을 만듭니다.
+func (o *catalogOptions) descriptionFor(index string) string {
+	if o.description == "" {
+		return fmt.Sprintf("Parquet export of Elasticsearch index %s, written by es-schema.", index)
+	}
+	return strings.ReplaceAll(o.description, "{index}", index)
+}
+
+// push 함수는 데이터셋 하나를 설정한 카탈로그에 등록합니다.
+func (o *catalogOptions) push(ctx context.Context, d catalogDataset) error {
+	if o.kind == catalogDataHub {
+		return o.pushDataHub(ctx, d)
+	}
+	return o.pushOpenMetadata(ctx, d)
+}
+
+// fieldDescription 함수는 컬럼의 계보를 카탈로그 필드 설명으로 씁니다.
+func fieldDescription(c columnLineage) string {
+	var sb strings.Builder
+	if c.Source != "" {
+		fmt.Fprintf(&sb, "From Elasticsearch field %s", c.Source)
+		if c.ESType != "" {
+			fmt.Fprintf(&sb, " (%s)", c.ESType)
+		}
+	} else {
+		sb.WriteString("Not from a single Elasticsearch field")
+	}
+	fmt.Fprintf(&sb, "; rule: %s", c.Rule)
+	if len(c.Overrides) > 0 {
+		fmt.Fprintf(&sb, "; overrides: %s", strings.Join(c.Overrides, ", "))
+	}
+	return sb.String()
+}
+
+// datahubURN 함수는 데이터셋의 DataHub URN을 만듭니다. 이름은 내보낸 위치입니다.
+func (o *catalogOptions) datahubURN(d catalogDataset) string {
+	return fmt.Sprintf("urn:li:dataset:(urn:li:dataPlatform:%s,%s,%s)", o.platform, d.location, o.env)
+}
+
+// pushDataHub 함수는 DataHub GMS의 ingestProposal API로 datasetProperties,
+// schemaMetadata, datasetProfile aspect를 올립니다.
+func (o *catalogOptions) pushDataHub(ctx context.Context, d catalogDataset) error {
+	urn := o.datahubURN(d)
+	platform := "urn:li:dataPlatform:" + o.platform
+	fields := make([]map[string]interface{}, 0, len(d.columns))
+	for _, c := range d.columns {
+		fields = append(fields, map[string]interface{}{
+			"fieldPath":      c.Column,
+			"nativeDataType": c.ArrowType,
+			"type":           map[string]interface{}{"type": map[string]interface{}{datahubFieldType(c.ArrowType): map[string]interface{}{}}},
+			"nullable":       c.Rule != ruleMetadata,
+			"description":    fieldDescription(c),
+		})
+	}
+	now := time.Now().UnixMilli()
+	aspects := []struct {
+		name  string
+		value interface{}
+	}{
+		{"datasetProperties", map[string]interface{}{
+			"name":        d.index,
+			"description": o.descriptionFor(d.index),
+			"customProperties": map[string]string{
+				"source_index":  d.index,
+				"rows":          fmt.Sprint(d.profile.rows),
+				"bytes":         fmt.Sprint(d.profile.bytes),
+				"exported_with": "es-schema",
+			},
+		}},
+		{"schemaMetadata", map[string]interface{}{
+			"schemaName":     d.index,
+			"platform":       platform,
+			"version":        0,
+			"hash":           "",
+			"platformSchema": map[string]interface{}{"com.linkedin.schema.OtherSchema": map[string]string{"rawSchema": ""}},
+			"fields":         fields,
+		}},
+		{"datasetProfile", datahubProfile(d, now)},
+	}
+	for _, a := range aspects {
+		value, err := json.Marshal(a.value)
+		if err != nil {
+			return err
+		}
+		body := map[string]interface{}{"proposal": map[string]interface{}{
+			"entityType": "dataset",
+			"entityUrn":  urn,
+			"changeType": "UPSERT",
+			"aspectName": a.name,
+			"aspect":     map[string]string{"value": string(value), "contentType": "application/json"},
+		}}
+		if err := o.send(ctx, http.MethodPost, "/aspects?action=ingestProposal", body, nil); err != nil {
+			return fmt.Errorf("datahub %s of %s: %w", a.name, d.index, err)
+		}
+	}
+	return nil
+}
+
+func datahubProfile(d catalogDataset, now int64) map[string]interface{} {
+	var fields []map[string]interface{}
+	for _, c := range d.columns {
+		s, ok := d.profile.columns[c.Column]
+		if !ok {
+			continue
+		}
+		f := map[string]interface{}{"fieldPath": c.Column, "nullCount": s.nulls}
+		if d.profile.rows > 0 {
+			f["nullProportion"] = float64(s.nulls) / float64(d.profile.rows)
+		}
+		if s.hasStats && s.min != nil {
+			f["min"], f["max"] = formatStat(s.min), formatStat(s.max)
+		}
+		fields = append(fields, f)
+	}
+	return map[string]interface{}{
+		"timestampMillis": now,
+		"rowCount":        d.profile.rows,
+		"columnCount":     len(d.columns),
+		"sizeInBytes":     d.profile.bytes,
+		"fieldProfiles":   fields,
+	}
+}
+
+// datahubFieldType 함수는 Arrow 타입 이름을 DataHub 스키마 필드 타입으로 바꿉니다.
+func datahubFieldType(arrowType string) string {
+	switch {
+	case strings.HasPrefix(arrowType, "list<"), strings.HasPrefix(arrowType, "fixed_size_list<"):
+		return "com.linkedin.schema.ArrayType"
+	case strings.HasPrefix(arrowType, "struct<"):
+		return "com.linkedin.schema.RecordType"
+	case strings.HasPrefix(arrowType, "timestamp"):
+		return "com.linkedin.schema.TimeType"
+	case strings.HasPrefix(arrowType, "date"):
+		return "com.linkedin.schema.DateType"
+	case arrowType == "bool":
+		return "com.linkedin.schema.BooleanType"
+	case arrowType == "binary":
+		return "com.linkedin.schema.BytesType"
+	case strings.HasPrefix(arrowType, "int"), strings.HasPrefix(arrowType, "uint"), strings.HasPrefix(arrowType, "float"), strings.HasPrefix(arrowType, "decimal"):
+		return "com.linkedin.schema.NumberType"
+	}
+	return "com.linkedin.schema.StringType"
+}
+
+// pushOpenMetadata 함수는 OpenMetadata에 테이블을 만들거나 바꾸고(PUT /api/v1/tables),
+// 파일 통계를 테이블 프로파일로 올립니다.
+func (o *catalogOptions) pushOpenMetadata(ctx context.Context, d catalogDataset) error {
+	columns := make([]map[string]interface{}, 0, len(d.columns))
+	for _, c := range d.columns {
+		col := map[string]interface{}{
+			"name":            c.Column,
+			"dataType":        openMetadataType(c.ArrowType),
+			"dataTypeDisplay": c.ArrowType,
+			"description":     fieldDescription(c),
+		}
+		if col["dataType"] == "ARRAY" {
+			col["arrayDataType"] = openMetadataType(arrowElemType(c.ArrowType))
+		}
+		columns = append(columns, col)
+	}
+	table := map[string]interface{}{
+		"name":           d.index,
+		"description":    o.descriptionFor(d.index),
+		"databaseSchema": o.schema,
+		"tableType":      "External",
+		"sourceUrl":      d.location,
+		"columns":        columns,
+	}
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := o.send(ctx, http.MethodPut, "/api/v1/tables", table, &created); err != nil {
+		return fmt.Errorf("openmetadata table %s: %w", d.index, err)
+	}
+	if created.ID == "" {
+		return dataErrorf("openmetadata table %s: response has no id", d.index)
+	}
+
+	now := time.Now().UnixMilli()
+	var colProfiles []map[string]interface{}
+	for _, c := range d.columns {
+		s, ok := d.profile.columns[c.Column]
+		if !ok {
+			continue
+		}
+		p := map[string]interface{}{"name": c.Column, "timestamp": now, "nullCount": s.nulls}
+		if d.profile.rows > 0 {
+			p["nullProportion"] = float64(s.nulls) / float64(d.profile.rows)
+		}
+		colProfiles = append(colProfiles, p)
+	}
+	profile := map[string]interface{}{
+		"tableProfile": map[string]interface{}{
+			"timestamp":   now,
+			"rowCount":    d.profile.rows,
+			"columnCount": len(d.columns),
+			"sizeInByte":  d.profile.bytes,
+		},
+		"columnProfile": colProfiles,
+	}
+	if err := o.send(ctx, http.MethodPut, "/api/v1/tables/"+url.PathEscape(created.ID)+"/tableProfile", profile, nil); err != nil {
+		return fmt.Errorf("openmetadata profile of %s: %w", d.index, err)
+	}
+	return nil
+}
+
+// arrowElemType 함수는 "list<item: T, nullable>" 같은 리스트 타입 이름에서 원소 타입을 꺼냅니다.
+func arrowElemType(arrowType string) string {
+	inner := arrowType[strings.IndexByte(arrowType, '<')+1 : len(arrowType)-1]
+	if i := strings.Index(inner, ": "); i >= 0 {
+		inner = inner[i+2:]
+	}
+	return strings.TrimSuffix(inner, ", nullable")
+}
+
+// openMetadataType 함수는 Arrow 타입 이름을 OpenMetadata 컬럼 dataType으로 바꿉니다.
+func openMetadataType(arrowType string) string {
+	switch {
+	case strings.HasPrefix(arrowType, "list<"), strings.HasPrefix(arrowType, "fixed_size_list<"):
+		return "ARRAY"
+	case strings.HasPrefix(arrowType, "struct<"):
+		return "STRUCT"
+	case strings.HasPrefix(arrowType, "timestamp"):
+		return "TIMESTAMP"
+	case strings.HasPrefix(arrowType, "date"):
+		return "DATE"
+	}
+	switch arrowType {
+	case "bool":
+		return "BOOLEAN"
+	case "binary":
+		return "BINARY"
+	case "int8":
+		return "TINYINT"
+	case "int16":
+		return "SMALLINT"
+	case "int32":
+		return "INT"
+	case "int64", "uint64":
+		return "BIGINT"
+	case "float32", "float16":
+		return "FLOAT"
+	case "float64":
+		return "DOUBLE"
+	}
+	return "STRING"
+}
+
+// send 함수는 in을 JSON 본문으로 카탈로그 API에 보내고, out이 있으면 응답을 풉니다.
+func (o *catalogOptions) send(ctx context.Context, method, path string, in, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(o.url, "/")+path, bytes.NewReader(data))
+	if err != nil {
+		return configErrorf("building request %s %s: %w", method, path, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if o.kind == catalogDataHub {
+		req.Header.Set("X-RestLi-Protocol-Version", "2.0.0")
+	}
+	if token := firstNonEmpty(o.token, os.Getenv("CATALOG_TOKEN")); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if o.http == nil {
+		o.http = &http.Client{Timeout: time.Minute}
+	}
+	resp, err := o.http.Do(req)
+	if err != nil {
+		return connectionErrorf("%s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return connectionErrorf("%s %s: reading response: %w", method, path, err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return configErrorf("%s %s: catalog returned HTTP %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(body)))
+	case resp.StatusCode >= 300:
+		return connectionErrorf("%s %s: catalog returned HTTP %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if out != nil {
+		if err := json.Unmarshal(body, out); err != nil {
+			return dataErrorf("%s %s: decoding response: %w", method, path, err)
+		}
+	}
+	return nil
+}
+
+// pushCatalog 함수는 성공한 인덱스마다 데이터셋을 카탈로그에 등록합니다. 실패는 경고로
+// 남기고 나머지 인덱스는 계속 등록합니다.
+func pushCatalog(ctx context.Context, report *runReport, o *catalogOptions, jobs []*exportJob, results []indexReport) {
+	for i, r := range results {
+		if r.Status == statusFailed || jobs[i].lineage == nil {
+			continue
+		}
+		paths := []string{r.File}
+		if r.files != nil {
+			paths = paths[:0]
+			for _, f := range r.files {
+				paths = append(paths, f.path)
+			}
+		}
+		profile, err := profileFiles(paths)
+		if err != nil {
+			report.warnf("%s: not registered with %s: %v", r.label(), o.kind, err)
+			continue
+		}
+		location, err := filepath.Abs(r.File)
+		if err != nil {
+			location = r.File
+		}
+		d := catalogDataset{index: r.label(), location: location, columns: jobs[i].lineage, profile: profile}
+		if err := o.push(ctx, d); err != nil {
+			report.warnf("%s: not registered with %s: %v", r.label(), o.kind, err)
+			continue
+		}
+		fmt.Printf("  %s: registered with %s\n", r.label(), o.kind)
+	}
+}
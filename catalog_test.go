@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// catalogServer는 받은 요청의 메서드, 경로, 본문을 기록하는 가짜 카탈로그 API입니다.
+type catalogServer struct {
+	mu       sync.Mutex
+	requests []string
+	bodies   []map[string]interface{}
+}
+
+func (c *catalogServer) handler(reply string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]interface{}
+		json.Unmarshal(data, &body)
+		c.mu.Lock()
+		c.requests = append(c.requests, r.Method+" "+r.URL.RequestURI()+" "+r.Header.Get("Authorization"))
+		c.bodies = append(c.bodies, body)
+		c.mu.Unlock()
+		io.WriteString(w, reply)
+	}
+}
+
+func testCatalogDataset() catalogDataset {
+	return catalogDataset{
+		index:    "logs",
+		location: "/out/logs.parquet",
+		columns: []columnLineage{
+			{Column: "_id", Source: "_id", ArrowType: "utf8", Rule: ruleMetadata},
+			{Column: "tags", Source: "tags", ESType: "keyword", ArrowType: "list<item: utf8, nullable>", Rule: ruleCopy, Overrides: []string{"--list-fields"}},
+		},
+		profile: &datasetProfile{rows: 10, bytes: 2048, columns: map[string]*columnSummary{
+			"tags": {nulls: 4, hasStats: true, min: "a", max: "z"},
+		}},
+	}
+}
+
+func TestPushDataHub(t *testing.T) {
+	var srv catalogServer
+	ts := httptest.NewServer(srv.handler(`{}`))
+	defer ts.Close()
+	o := &catalogOptions{kind: catalogDataHub, url: ts.URL, token: "secret", platform: "file", env: "PROD"}
+	if err := o.push(context.Background(), testCatalogDataset()); err != nil {
+		t.Fatal(err)
+	}
+	if len(srv.requests) != 3 || srv.requests[0] != "POST /aspects?action=ingestProposal Bearer secret" {
+		t.Fatalf("requests = %q", srv.requests)
+	}
+	proposal := srv.bodies[1]["proposal"].(map[string]interface{})
+	if proposal["entityUrn"] != "urn:li:dataset:(urn:li:dataPlatform:file,/out/logs.parquet,PROD)" || proposal["aspectName"] != "schemaMetadata" {
+		t.Errorf("proposal = %v", proposal)
+	}
+	value := proposal["aspect"].(map[string]interface{})["value"].(string)
+	for _, want := range []string{`"fieldPath":"tags"`, `"com.linkedin.schema.ArrayType"`, `From Elasticsearch field tags (keyword); rule: copy; overrides: --list-fields`} {
+		if !strings.Contains(value, want) {
+			t.Errorf("schemaMetadata %s has no %s", value, want)
+		}
+	}
+	profile := srv.bodies[2]["proposal"].(map[string]interface{})["aspect"].(map[string]interface{})["value"].(string)
+	if !strings.Contains(profile, `"rowCount":10`) || !strings.Contains(profile, `"nullProportion":0.4`) {
+		t.Errorf("datasetProfile = %s", profile)
+	}
+}
+
+func TestPushOpenMetadata(t *testing.T) {
+	var srv catalogServer
+	ts := httptest.NewServer(srv.handler(`{"id": "t-1"}`))
+	defer ts.Close()
+	o := &catalogOptions{kind: catalogOpenMetadata, url: ts.URL, schema: "files.default.exports"}
+	if err := o.push(context.Background(), testCatalogDataset()); err != nil {
+		t.Fatal(err)
+	}
+	if len(srv.requests) != 2 || srv.requests[0] != "PUT /api/v1/tables " || srv.requests[1] != "PUT /api/v1/tables/t-1/tableProfile " {
+		t.Fatalf("requests = %q", srv.requests)
+	}
+	columns := srv.bodies[0]["columns"].([]interface{})
+	tags := columns[1].(map[string]interface{})
+	if tags["dataType"] != "ARRAY" || tags["arrayDataType"] != "STRING" || srv.bodies[0]["databaseSchema"] != "files.default.exports" {
+		t.Errorf("table = %v", srv.bodies[0])
+	}
+}
+
+func TestCatalogErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no", http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+	o := &catalogOptions{kind: catalogOpenMetadata, url: ts.URL, schema: "a.b.c"}
+	if err := o.push(context.Background(), testCatalogDataset()); kindOf(err) != kindConfig {
+		t.Errorf("unauthorized push = %v, want a config error", err)
+	}
+	for _, bad := range []catalogOptions{
+		{kind: "glue"},
+		{kind: catalogDataHub},
+		{kind: catalogOpenMetadata, url: ts.URL, schema: "only.two"},
+	} {
+		if err := bad.validate(); err == nil {
+			t.Errorf("validate accepted %+v", bad)
+		}
+	}
+}
+
+func TestLineageColumnPath(t *testing.T) {
+	for in, want := range map[string]string{
+		"tags.list.element":           "tags",
+		"users.list.element.name":     "users.name",
+		"user.address.city":           "user.address.city",
+		"a.list.element.list.element": "a",
+	} {
+		if got := lineageColumnPath(in); got != want {
+			t.Errorf("lineageColumnPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+// memoryLogColumns는 --memory-stats가 인덱스를 마칠 때 출력하는 컬럼 수입니다.
+const memoryLogColumns = 5
+
+// columnMemory는 최상위 컬럼 하나가 Arrow 레코드(scroll 페이지 하나)마다 잡은 버퍼
+// 크기입니다. 구조체와 리스트 컬럼은 자식 컬럼의 버퍼까지 더합니다.
+type columnMemory struct {
+	Column string `json:"column"`
+	// PeakBytes는 가장 큰 배치에서의 크기로, --scroll-size를 정할 때 볼 값입니다.
+	PeakBytes  int64 `json:"peak_bytes"`
+	TotalBytes int64 `json:"total_bytes"`
+}
+
+// columnMemoryStats는 인덱스 하나를 내보내는 동안 컬럼별 메모리를 모읍니다.
+type columnMemoryStats struct {
+	columns map[string]*columnMemory
+}
+
+// add 함수는 레코드 하나의 컬럼별 버퍼 크기를 더합니다.
+func (s *columnMemoryStats) add(rec arrow.Record) {
+	if s.columns == nil {
+		s.columns = make(map[string]*columnMemory)
+	}
+	for i, f := range rec.Schema().Fields() {
+		c := s.columns[f.Name]
+		if c == nil {
+			c = &columnMemory{Column: f.Name}
+			s.columns[f.Name] = c
+		}
+		n := arrayDataBytes(rec.Column(i).Data())
+		c.TotalBytes += n
+		if n > c.PeakBytes {
+			c.PeakBytes = n
+		}
+	}
+}
+
+// summary 함수는 모은 값을 PeakBytes가 큰 순서로 반환합니다.
+func (s *columnMemoryStats) summary() []columnMemory {
+	out := make([]columnMemory, 0, len(s.columns))
+	for _, c := range s.columns {
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].PeakBytes != out[j].PeakBytes {
+			return out[i].PeakBytes > out[j].PeakBytes
+		}
+		return out[i].Column < out[j].Column
+	})
+	return out
+}
+
+// arrayDataBytes 함수는 배열이 잡고 있는 버퍼의 용량을 자식과 사전까지 더해 반환합니다.
+func arrayDataBytes(d arrow.ArrayData) int64 {
+	var n int64
+	for _, buf := range d.Buffers() {
+		if buf != nil {
+			n += int64(buf.Cap())
+		}
+	}
+	for _, child := range d.Children() {
+		n += arrayDataBytes(child)
+	}
+	if d.DataType().ID() == arrow.DICTIONARY {
+		n += arrayDataBytes(d.Dictionary())
+	}
+	return n
+}
+
+// formatColumnMemory 함수는 처음 n개 컬럼을 한 줄로 씁니다.
+func formatColumnMemory(cols []columnMemory, n int) string {
+	if len(cols) > n {
+		cols = cols[:n]
+	}
+	parts := make([]string, len(cols))
+	for i, c := range cols {
+		parts[i] = fmt.Sprintf("%s %s", c.Column, formatBytes(c.PeakBytes))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestColumnMemorySummary(t *testing.T) {
+	s := columnMemoryStats{columns: map[string]*columnMemory{
+		"message": {Column: "message", PeakBytes: 4 << 20, TotalBytes: 9 << 20},
+		"level":   {Column: "level", PeakBytes: 512, TotalBytes: 1024},
+		"host":    {Column: "host", PeakBytes: 512, TotalBytes: 2048},
+	}}
+	got := s.summary()
+	var order []string
+	for _, c := range got {
+		order = append(order, c.Column)
+	}
+	if want := []string{"message", "host", "level"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("summary order = %v, want %v", order, want)
+	}
+	if line, want := formatColumnMemory(got, 2), "message 4.0 MiB, host 512 B"; line != want {
+		t.Errorf("formatColumnMemory = %q, want %q", line, want)
+	}
+}
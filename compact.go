@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+// compactOptions는 compact 명령의 설정입니다. 디렉터리 하나를 파티션 하나로 보고, 그 안의
+// 작은 Parquet 파일을 targetBytes 크기의 파일로 합칩니다.
+type compactOptions struct {
+	targetMB int64
+	dryRun   bool
+	parquet  parquetOptions
+}
+
+// compactFile은 합칠 후보인 Parquet 파일 하나입니다. key는 스키마(임베드된 매핑 포함)로,
+// key가 같은 파일만 합칩니다.
+type compactFile struct {
+	path string
+	size int64
+	rows int64
+	key  string
+}
+
+func setupCompact(fs *flag.FlagSet) func(ctx context.Context, report *runReport, args []string) error {
+	var o compactOptions
+	fs.Int64Var(&o.targetMB, "target-mb", 128, "merge small files into files of about this many MiB")
+	fs.BoolVar(&o.dryRun, "dry-run", false, "print which files would be merged without changing anything")
+	o.parquet.bind(fs)
+
+	return func(ctx context.Context, report *runReport, args []string) error {
+		if len(args) == 0 {
+			return configErrorf("compact: no directories given")
+		}
+		if o.targetMB <= 0 {
+			return configErrorf("compact: --target-mb must be positive")
+		}
+		if err := o.parquet.validate(); err != nil {
+			return err
+		}
+		for _, dir := range args {
+			if err := o.compactTree(ctx, dir, report); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// compactTree 함수는 dir과 그 아래 모든 디렉터리를 파티션마다 합칩니다.
+func (o *compactOptions) compactTree(ctx context.Context, dir string, report *runReport) error {
+	partitions := make(map[string][]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && isCompactCandidate(d.Name()) {
+			partitions[filepath.Dir(path)] = append(partitions[filepath.Dir(path)], path)
+		}
+		return nil
+	})
+	if err != nil {
+		return configErrorf("compact: %w", err)
+	}
+	dirs := make([]string, 0, len(partitions))
+	for d := range partitions {
+		dirs = append(dirs, d)
+	}
+	sort.Strings(dirs)
+	for _, d := range dirs {
+		if err := o.compactPartition(ctx, partitions[d], report); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isCompactCandidate 함수는 name이 합칠 수 있는 파일인지 알려 줍니다. export --tombstones
+// file의 삭제 파일은 데이터 파일 옆에 그대로 있어야 하므로 합치지 않습니다.
+func isCompactCandidate(name string) bool {
+	return strings.HasSuffix(name, ".parquet") && !strings.HasSuffix(name, ".deletes.parquet") && !strings.HasPrefix(name, ".")
+}
+
+func (o *compactOptions) compactPartition(ctx context.Context, paths []string, report *runReport) error {
+	sort.Strings(paths)
+	files := make([]compactFile, 0, len(paths))
+	for _, path := range paths {
+		f, err := describeCompactFile(path)
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+	}
+	for _, group := range planCompaction(files, o.targetMB<<20) {
+		if o.dryRun {
+			fmt.Printf("would merge %d files into %s:\n", len(group), group[0].path)
+			for _, f := range group {
+				fmt.Printf("  %s (%s, %d rows)\n", f.path, formatBytes(f.size), f.rows)
+			}
+			continue
+		}
+		rows, err := o.merge(ctx, group)
+		if err != nil {
+			return err
+		}
+		report.RowsRead += rows
+		report.addFile(group[0].path, rows)
+		fmt.Printf("merged %d files into %s (%d rows)\n", len(group), group[0].path, rows)
+	}
+	return nil
+}
+
+func describeCompactFile(path string) (compactFile, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return compactFile{}, configErrorf("compact: %w", err)
+	}
+	pf, err := openParquetFile(path)
+	if err != nil {
+		return compactFile{}, err
+	}
+	defer pf.Close()
+	sc, err := pf.reader.Schema()
+	if err != nil {
+		return compactFile{}, dataErrorf("reading Arrow schema of %s: %w", path, err)
+	}
+	return compactFile{path: path, size: info.Size(), rows: pf.NumRows(), key: sc.String()}, nil
+}
+
+// planCompaction 함수는 이름 순으로 놓인 files에서 합칠 묶음을 고릅니다. 정렬 순서를
+// 지키기 위해 이웃한 파일만 묶고, target 이상인 파일이나 스키마가 다른 파일을 만나면 묶음을
+// 끊습니다. 묶음은 크기 합이 target에 이르면 닫히며, 파일이 하나뿐인 묶음은 버립니다.
+func planCompaction(files []compactFile, target int64) [][]compactFile {
+	var groups [][]compactFile
+	var cur []compactFile
+	var size int64
+	flush := func() {
+		if len(cur) > 1 {
+			groups = append(groups, cur)
+		}
+		cur, size = nil, 0
+	}
+	for _, f := range files {
+		if f.size >= target {
+			flush()
+			continue
+		}
+		if len(cur) > 0 && (cur[0].key != f.key || size+f.size > target) {
+			flush()
+		}
+		cur = append(cur, f)
+		size += f.size
+	}
+	flush()
+	return groups
+}
+
+// merge 함수는 group의 파일을 순서대로 읽어 임시 파일 하나에 쓴 뒤 첫 파일을 그것으로
+// 바꾸고 나머지를 지웁니다. 바꾼 뒤 지우기 전에 멈추면 행이 중복될 뿐 잃지는 않습니다.
+func (o *compactOptions) merge(ctx context.Context, group []compactFile) (int64, error) {
+	first, err := openParquetFile(group[0].path)
+	if err != nil {
+		return 0, err
+	}
+	schema, err := first.reader.Schema()
+	first.Close()
+	if err != nil {
+		return 0, dataErrorf("reading Arrow schema of %s: %w", group[0].path, err)
+	}
+	tmp := filepath.Join(filepath.Dir(group[0].path), "."+filepath.Base(group[0].path)+".compacting")
+	w, err := createParquetFile(tmp, schema, &o.parquet)
+	if err != nil {
+		return 0, err
+	}
+	var rows, want int64
+	for _, f := range group {
+		want += f.rows
+		if err := copyParquetRecords(ctx, f.path, func(rec arrow.Record) error {
+			rows += rec.NumRows()
+			return w.write(rec)
+		}); err != nil {
+			w.abort()
+			return 0, err
+		}
+	}
+	if err := w.close(); err != nil {
+		os.Remove(tmp)
+		return 0, err
+	}
+	if rows != want {
+		os.Remove(tmp)
+		return 0, dataErrorf("compact: read %d rows from %d files whose metadata declares %d", rows, len(group), want)
+	}
+	if err := os.Rename(tmp, group[0].path); err != nil {
+		os.Remove(tmp)
+		return 0, configErrorf("compact: %w", err)
+	}
+	for _, f := range group[1:] {
+		if err := os.Remove(f.path); err != nil {
+			return rows, configErrorf("compact: %s was merged into %s but could not be removed: %w", f.path, group[0].path, err)
+		}
+	}
+	return rows, nil
+}
+
+func copyParquetRecords(ctx context.Context, path string, fn func(arrow.Record) error) error {
+	pf, err := openParquetFile(path)
+	if err != nil {
+		return err
+	}
+	defer pf.Close()
+	_, err = pf.records(ctx, nil, fn)
+	return err
+}
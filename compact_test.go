@@ -0,0 +1,45 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlanCompactionMergesNeighboursOfOneSchema(t *testing.T) {
+	files := []compactFile{
+		{path: "a", size: 10, key: "x"},
+		{path: "b", size: 20, key: "x"},
+		{path: "c", size: 100, key: "x"}, // 이미 충분히 크므로 묶음을 끊습니다.
+		{path: "d", size: 10, key: "x"},
+		{path: "e", size: 10, key: "y"},
+		{path: "f", size: 30, key: "y"},
+		{path: "g", size: 30, key: "y"},
+		{path: "h", size: 50, key: "y"},
+		{path: "i", size: 5, key: "y"},
+	}
+	var got [][]string
+	for _, g := range planCompaction(files, 64) {
+		var paths []string
+		for _, f := range g {
+			paths = append(paths, f.path)
+		}
+		got = append(got, paths)
+	}
+	want := [][]string{{"a", "b"}, {"e", "f"}, {"h", "i"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("planCompaction = %v, want %v", got, want)
+	}
+}
+
+func TestIsCompactCandidate(t *testing.T) {
+	for name, want := range map[string]bool{
+		"logs.parquet":             true,
+		"logs.deletes.parquet":     false,
+		".logs.parquet.compacting": false,
+		"logs.ids":                 false,
+	} {
+		if got := isCompactCandidate(name); got != want {
+			t.Errorf("isCompactCandidate(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
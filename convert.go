@@ -0,0 +1,549 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow/go/v10/arrow"
+
+	"es-schema/esschema"
+)
+
+// maxValidateProblems는 validate가 출력하는 문서별 문제의 최대 수입니다.
+const maxValidateProblems = 20
+
+// mappingInputOptions는 매핑과 NDJSON 문서를 읽는 convert, validate, schema의 설정입니다.
+// 매핑은 파일에서 읽거나 살아 있는 클러스터의 _mapping API로 가져옵니다.
+type mappingInputOptions struct {
+	mapping    string
+	es         esOptions
+	index      string
+	listFields stringListFlag
+	ipFormat   ipFormatOptions
+	scaled     scaledFloatOptions
+	tsUnit     timestampUnitOptions
+	flattened  flattenedFormatOptions
+	geoShape   geoShapeFormatOptions
+	vectorDims vectorDimsOptions
+	verbose    bool
+}
+
+func (o *mappingInputOptions) bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.mapping, "mapping", "", "mapping JSON file: a mapping with a top-level \"properties\" object, a create-index body or a saved _mapping response")
+	o.es.bind(fs, "es-", "source")
+	fs.StringVar(&o.index, "index", "", "read the mapping of this index (or alias) from the live _mapping API of --es-url instead of --mapping")
+	fs.Var(&o.listFields, "list-fields", "treat these fields as arrays from the start; dotted paths select nested fields (comma-separated or repeated)")
+	o.ipFormat.bind(fs)
+	o.scaled.bind(fs)
+	o.tsUnit.bind(fs)
+	o.flattened.bind(fs)
+	o.geoShape.bind(fs)
+	o.vectorDims.bind(fs)
+	fs.BoolVar(&o.verbose, "verbose", false, "print the schema and progress while converting")
+}
+
+// source 함수는 매핑을 어디서 읽는지 사람이 읽을 이름으로 반환합니다.
+func (o *mappingInputOptions) source() string {
+	if o.index != "" {
+		return o.index
+	}
+	return o.mapping
+}
+
+// readMapping 함수는 --mapping 파일이나 --es-url의 --index 매핑을 읽습니다.
+func (o *mappingInputOptions) readMapping(ctx context.Context) ([]byte, error) {
+	switch {
+	case o.mapping != "" && o.index != "":
+		return nil, configErrorf("give either --mapping or --index, not both")
+	case o.index != "":
+		client, err := o.es.client()
+		if err != nil {
+			return nil, err
+		}
+		return client.getMapping(ctx, o.index)
+	case o.mapping != "":
+		data, err := os.ReadFile(o.mapping)
+		if err != nil {
+			return nil, configErrorf("reading mapping: %w", err)
+		}
+		return data, nil
+	}
+	return nil, configErrorf("--mapping or --es-url with --index is required")
+}
+
+// schema 함수는 매핑을 읽어 schemaOf의 플래그를 적용한 스키마와 매핑 JSON을 반환합니다.
+func (o *mappingInputOptions) schema(ctx context.Context) (*arrow.Schema, []byte, error) {
+	for _, v := range []interface{ validate() error }{&o.ipFormat, &o.scaled, &o.tsUnit, &o.flattened, &o.geoShape, &o.vectorDims} {
+		if err := v.validate(); err != nil {
+			return nil, nil, err
+		}
+	}
+	data, err := o.readMapping(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	schema, err := o.schemaOf(data, o.source())
+	if err != nil {
+		return nil, nil, err
+	}
+	return schema, data, nil
+}
+
+// schemaOf 함수는 source에서 읽은 매핑 JSON data로 스키마를 만들고 --list-fields,
+// --ip-format, --scaled-float, --timestamp-unit, --flattened-format과 --geo-shape를
+// 적용합니다. --vector-dims infer가 아니면 dims가 없는 dense_vector 필드는 오류입니다.
+func (o *mappingInputOptions) schemaOf(data []byte, source string) (*arrow.Schema, error) {
+	schema, err := schemaFromMapping(data)
+	if err != nil {
+		return nil, schemaErrorf("parsing mapping of %s: %w", source, err)
+	}
+	fields := schema.Fields()
+	for _, path := range o.listFields {
+		var ok bool
+		if fields, ok = forceList(fields, strings.Split(path, ".")); !ok {
+			return nil, configErrorf("--list-fields: %s is not a field of %s", path, source)
+		}
+	}
+	fields = o.geoShape.apply(o.flattened.apply(o.tsUnit.apply(o.scaled.apply(o.ipFormat.apply(fields)))))
+	if err := o.vectorDims.check(fields, source); err != nil {
+		return nil, err
+	}
+	return arrow.NewSchema(fields, nil), nil
+}
+
+// ndjsonInputHelp는 convert와 validate의 --input 설명입니다.
+const ndjsonInputHelp = "NDJSON file with one _source document, or one search hit as written by elasticdump, per line; gzip-compressed input is detected; - for standard input"
+
+// gzipMagic은 gzip 스트림의 처음 두 바이트입니다.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// ndjsonInput은 한 줄에 문서 하나인 NDJSON 입력입니다. 문서는 searchHit으로 읽어 export와
+// 같은 기록 방식을 씁니다. 줄이 elasticdump가 쓰는 검색 결과의 hit이면 그 _id를, 아니면 줄
+// 번호를 _id로 합니다.
+type ndjsonInput struct {
+	name string
+	r    *bufio.Reader
+	c    []io.Closer
+	line int
+}
+
+// openNDJSON 함수는 path를 엽니다. -이면 표준 입력입니다. 입력이 gzip으로 시작하면
+// 압축을 풀면서 읽으므로 elasticdump --fsCompress의 출력도 그대로 읽습니다.
+func openNDJSON(path string) (*ndjsonInput, error) {
+	if path == "-" {
+		return readNDJSON("stdin", os.Stdin, nil)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, configErrorf("opening input: %w", err)
+	}
+	return readNDJSON(filepath.Base(path), f, f)
+}
+
+// readNDJSON 함수는 r을 name이라는 NDJSON 입력으로 읽습니다. c가 있으면 입력을 닫을 때 함께
+// 닫습니다.
+func readNDJSON(name string, r io.Reader, c io.Closer) (*ndjsonInput, error) {
+	in := &ndjsonInput{name: name}
+	if c != nil {
+		in.c = []io.Closer{c}
+	}
+	in.r = bufio.NewReaderSize(r, 1<<20)
+	if magic, _ := in.r.Peek(len(gzipMagic)); bytes.Equal(magic, gzipMagic) {
+		gz, err := gzip.NewReader(in.r)
+		if err != nil {
+			in.close()
+			return nil, configErrorf("reading %s: %w", in.name, err)
+		}
+		in.r = bufio.NewReaderSize(gz, 1<<20)
+		in.c = append(in.c, gz)
+	}
+	return in, nil
+}
+
+// next 함수는 빈 줄을 건너뛰고 문서를 n개까지 읽습니다. 더 읽을 문서가 없으면 io.EOF입니다.
+func (in *ndjsonInput) next(n int) ([]searchHit, error) {
+	var hits []searchHit
+	for len(hits) < n {
+		hit, err := in.read()
+		if err == io.EOF {
+			if len(hits) == 0 {
+				return nil, io.EOF
+			}
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, hit)
+	}
+	return hits, nil
+}
+
+// read 함수는 빈 줄을 건너뛰고 문서 하나를 읽습니다. 더 읽을 문서가 없으면 io.EOF입니다.
+func (in *ndjsonInput) read() (searchHit, error) {
+	for {
+		line, err := in.r.ReadBytes('\n')
+		if len(line) > 0 {
+			in.line++
+			if line = bytes.TrimSpace(line); len(line) > 0 {
+				return in.hit(line), nil
+			}
+		}
+		if err == io.EOF {
+			return searchHit{}, io.EOF
+		}
+		if err != nil {
+			return searchHit{}, configErrorf("reading %s: %w", in.name, err)
+		}
+	}
+}
+
+// hit 함수는 한 줄을 searchHit으로 바꿉니다. _source는 매핑의 필드 이름이 될 수 없으므로
+// 최상위에 _source가 있는 줄은 문서가 아니라 hit입니다. 줄을 읽는 데 실패하면 문서로 두어
+// 변환할 때 디코딩 오류로 기록합니다.
+func (in *ndjsonInput) hit(line []byte) searchHit {
+	id := strconv.Itoa(in.line)
+	if bytes.Contains(line, []byte(`"_source"`)) {
+		var hit searchHit
+		if err := json.Unmarshal(line, &hit); err == nil && len(hit.Source) > 0 {
+			hit.ID = firstNonEmpty(hit.ID, id)
+			hit.Index = firstNonEmpty(hit.Index, in.name)
+			return hit
+		}
+	}
+	return searchHit{Index: in.name, ID: id, Source: json.RawMessage(line)}
+}
+
+// feed 함수는 남은 문서를 모두 읽어 b에 더하고 마지막 배치까지 넘깁니다.
+func (in *ndjsonInput) feed(ctx context.Context, b *recordBatcher) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		hit, err := in.read()
+		if err == io.EOF {
+			return b.close()
+		}
+		if err != nil {
+			return err
+		}
+		if err := b.add(hit); err != nil {
+			return err
+		}
+	}
+}
+
+func (in *ndjsonInput) close() {
+	for i := len(in.c) - 1; i >= 0; i-- {
+		in.c[i].Close()
+	}
+	in.c = nil
+}
+
+func setupConvert(fs *flag.FlagSet) func(ctx context.Context, report *runReport, args []string) error {
+	var in mappingInputOptions
+	var names nameOptions
+	var pqOpts parquetOptions
+	var encryption encryptionOptions
+	var bad badDocumentOptions
+	var overflow overflowOptions
+	var sanitize sanitizeOptions
+	var batch batchOptions
+	var lock schemaLockOptions
+	var input, output string
+	in.bind(fs)
+	fs.StringVar(&input, "input", "-", ndjsonInputHelp)
+	fs.StringVar(&output, "output", "", "Parquet file to write (required)")
+	batch.bind(fs)
+	names.bind(fs)
+	pqOpts.bind(fs)
+	encryption.bind(fs)
+	bad.bind(fs)
+	overflow.bind(fs)
+	sanitize.bind(fs)
+	lock.bind(fs)
+
+	return func(ctx context.Context, report *runReport, args []string) error {
+		if len(args) > 0 {
+			return configErrorf("convert: unexpected arguments %v", args)
+		}
+		if output == "" {
+			return configErrorf("convert: --output is required")
+		}
+		for _, v := range []interface{ validate() error }{&batch, &names, &pqOpts, &bad, &overflow, &sanitize, &lock} {
+			if err := v.validate(); err != nil {
+				return err
+			}
+		}
+		if err := encryption.load(ctx, &pqOpts); err != nil {
+			return err
+		}
+		schema, mapping, err := in.schema(ctx)
+		if err != nil {
+			return err
+		}
+		src, err := openNDJSON(input)
+		if err != nil {
+			return err
+		}
+		defer src.close()
+		schema = arrow.NewSchema(lock.apply(schema.Fields()), nil)
+		return convertNDJSON(ctx, report, &in, src, batch, output, schema, mapping, &names, &pqOpts, bad.skip(), &overflow, &sanitize, &lock)
+	}
+}
+
+// convertNDJSON 함수는 src의 문서를 batch 크기의 레코드로 바꿔 output에 차례로 씁니다. 첫
+// 배치에서만 스키마를 넓힐 수 있으므로, 나중에 배열로 나오는 필드는 --list-fields로 미리
+// 알려야 합니다. lock이 conform이면 파일을 열기 전에 스키마를 --schema-lock과 비교하고,
+// write이면 다 쓴 뒤에 첫 배치가 내린 결정을 잠금 파일에 씁니다.
+func convertNDJSON(ctx context.Context, report *runReport, in *mappingInputOptions, src *ndjsonInput, batch batchOptions, output string, schema *arrow.Schema, mapping []byte, names *nameOptions, pqOpts *parquetOptions, skipBad bool, overflow *overflowOptions, sanitize *sanitizeOptions, lock *schemaLockOptions) error {
+	norm := newNormalizer(schema)
+	norm.rejectBad = skipBad
+	overflow.apply(norm)
+	sanitize.apply(norm)
+	var rejects docRejects
+	var sink *parquetSink
+	defer func() {
+		if sink != nil {
+			sink.abort()
+		}
+	}()
+	var rows int64
+	var batcher *recordBatcher
+	batcher = newRecordBatcher(batch, func(hits []searchHit) error {
+		report.RowsRead += int64(len(hits))
+		docs, hits, err := rejects.decodeHits(hits)
+		if err != nil {
+			return err
+		}
+		if changed := norm.widen(docs); changed != "" && sink != nil {
+			return schemaErrorf("field %s holds arrays only after the first %d documents, which changes the Parquet schema; rerun with --list-fields %s", changed, batcher.firstRows, changed)
+		}
+		rec, rejected := norm.record(docs)
+		defer rec.Release()
+		if hits, _, err = rejects.keep(hits, docs, rejected); err != nil {
+			return err
+		}
+		if sink == nil {
+			if in.verbose {
+				fmt.Printf("schema of %s:\n%s", output, formatSchema(rec.Schema(), "  "))
+			}
+			if err := lock.check(output, rec.Schema(), names); err != nil {
+				return err
+			}
+			if sink, err = newParquetSink(output, rec.Schema(), mapping, sinkColumns{}, names, pqOpts); err != nil {
+				return err
+			}
+			recordRenames(report, sink.renames)
+		}
+		if err := sink.writeHits(rec, hits); err != nil {
+			return err
+		}
+		rows += int64(len(hits))
+		if in.verbose {
+			fmt.Printf("converted %d documents (line %d)\n", rows, src.line)
+		}
+		return nil
+	})
+	if err := src.feed(ctx, batcher); err != nil {
+		return err
+	}
+	if sink == nil {
+		return dataErrorf("convert: %s has no documents", src.name)
+	}
+	if norm.dropped > 0 {
+		report.DocumentsDropped += norm.dropped
+		report.warnf("%d values could not be converted to their mapped type and were written as null", norm.dropped)
+	}
+	overflow.report(report, norm.overflowCounts())
+	sanitize.report(report, norm.sanitizedCounts())
+	renames := sink.renames
+	if err := sink.close(); err != nil {
+		return err
+	}
+	sink = nil
+	report.addFile(output, rows)
+	fmt.Printf("wrote %d rows to %s\n", rows, output)
+	lock.record(schema.Fields(), norm.schema, renames)
+	if err := lock.save(report); err != nil {
+		return err
+	}
+	return rejects.finish(report)
+}
+
+func setupValidate(fs *flag.FlagSet) func(ctx context.Context, report *runReport, args []string) error {
+	var in mappingInputOptions
+	var batch batchOptions
+	var overflow overflowOptions
+	var input string
+	in.bind(fs)
+	fs.StringVar(&input, "input", "-", ndjsonInputHelp)
+	batch.bind(fs)
+	overflow.bind(fs)
+
+	return func(ctx context.Context, report *runReport, args []string) error {
+		if len(args) > 0 {
+			return configErrorf("validate: unexpected arguments %v", args)
+		}
+		for _, v := range []interface{ validate() error }{&batch, &overflow} {
+			if err := v.validate(); err != nil {
+				return err
+			}
+		}
+		schema, _, err := in.schema(ctx)
+		if err != nil {
+			return err
+		}
+		src, err := openNDJSON(input)
+		if err != nil {
+			return err
+		}
+		defer src.close()
+		return validateNDJSON(ctx, report, &in, src, batch, schema, &overflow)
+	}
+}
+
+// validateNDJSON 함수는 src의 문서마다 매핑된 타입으로 바꿀 수 없는 값이 있는지 검사하고,
+// 그런 문서가 있으면 데이터 오류를 반환합니다. 같은 batch로 읽는 convert는 첫 배치 뒤에야
+// 배열로 나오는 필드에서 실패하므로 --list-fields를 권하는 경고를 남깁니다.
+func validateNDJSON(ctx context.Context, report *runReport, in *mappingInputOptions, src *ndjsonInput, batch batchOptions, schema *arrow.Schema, overflow *overflowOptions) error {
+	norm := newNormalizer(schema)
+	overflow.apply(norm)
+	conv := esschema.Converter{Overflow: norm.overflow}
+	pool := newStringPool()
+	var problems []string
+	var checked, invalid int64
+	lateArrays := map[string]bool{}
+	var batcher *recordBatcher
+	batcher = newRecordBatcher(batch, func(hits []searchHit) error {
+		docs := make([]map[string]interface{}, len(hits))
+		for i, hit := range hits {
+			var err error
+			if docs[i], err = pool.decodeDocument(hit.Source); err != nil {
+				invalid++
+				report.addFailures("decode", 1)
+				problems = append(problems, fmt.Sprintf("line %s: %v", hit.ID, err))
+			}
+		}
+		if changed := norm.widen(docs); changed != "" && batcher.batches > 1 && !lateArrays[changed] {
+			lateArrays[changed] = true
+			report.warnf("field %s holds arrays only after the first %d documents; convert it with --list-fields %s", changed, batcher.firstRows, changed)
+		}
+		fields := norm.schema.Fields()
+		values := make([]interface{}, len(fields))
+		for i, doc := range docs {
+			if doc == nil {
+				continue
+			}
+			checked++
+			bad, err := conv.Document(fields, doc, values)
+			if err == nil && len(bad) == 0 {
+				continue
+			}
+			invalid++
+			reason := "cannot convert " + strings.Join(bad, ", ") + " to the mapped type"
+			category := "conversion:error"
+			var rangeErr *esschema.RangeError
+			if errors.As(err, &rangeErr) {
+				reason, category = err.Error(), "overflow:"+rangeErr.Path
+			} else if err != nil {
+				reason = err.Error()
+			} else {
+				category = "conversion:" + bad[0]
+			}
+			report.addFailures(category, 1)
+			problems = append(problems, fmt.Sprintf("line %s: %s", hits[i].ID, reason))
+		}
+		report.RowsRead += int64(len(hits))
+		if in.verbose {
+			fmt.Printf("checked %d documents (line %d)\n", report.RowsRead, src.line)
+		}
+		return nil
+	})
+	if err := src.feed(ctx, batcher); err != nil {
+		return err
+	}
+	for i, p := range problems {
+		if i == maxValidateProblems {
+			fmt.Printf("  ... and %d more\n", len(problems)-i)
+			break
+		}
+		fmt.Println("  " + p)
+	}
+	fmt.Printf("checked %d documents of %s against %s: %d do not fit the mapping\n", report.RowsRead, src.name, in.source(), invalid)
+	overflow.report(report, conv.Overflows)
+	if invalid > 0 {
+		report.DocumentsFailed += invalid
+		return dataErrorf("validate: %d of %d documents do not fit the mapping", invalid, report.RowsRead)
+	}
+	return nil
+}
+
+func setupSchema(fs *flag.FlagSet) func(ctx context.Context, report *runReport, args []string) error {
+	var in mappingInputOptions
+	var names nameOptions
+	var batch schemaBatchOptions
+	var format string
+	in.bind(fs)
+	names.bind(fs)
+	batch.bind(fs)
+	fs.StringVar(&format, "format", "text", "output format: text (one line per top-level column) or json (the field tree of the preview API)")
+
+	return func(ctx context.Context, report *runReport, args []string) error {
+		// 매핑 파일 뒤의 플래그(schema ./mappings/*.json --out-dir schemas/)도 읽습니다.
+		args, err := parseInterspersed(fs, args)
+		if err != nil {
+			return configErrorf("schema: %w", err)
+		}
+		if format != "text" && format != "json" {
+			return configErrorf("schema: unknown --format %q (want text or json)", format)
+		}
+		if err := names.validate(); err != nil {
+			return err
+		}
+		if len(args) > 0 || batch.outDir != "" {
+			return batch.run(ctx, report, &in, &names, format, args)
+		}
+		schema, _, err := in.schema(ctx)
+		if err != nil {
+			return err
+		}
+		if names.enabled() {
+			var renames []fieldRename
+			if schema, renames, err = names.apply(schema); err != nil {
+				return err
+			}
+			recordRenames(report, renames)
+		}
+		out, err := renderSchema(schema, format)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+}
+
+// renderSchema 함수는 schema를 --format에 맞게 text(최상위 컬럼마다 한 줄)나 json(preview
+// API의 필드 트리)으로 씁니다.
+func renderSchema(schema *arrow.Schema, format string) ([]byte, error) {
+	if format == "text" {
+		return []byte(formatSchema(schema, "")), nil
+	}
+	data, err := json.MarshalIndent(describeSchema(schema.Fields()), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
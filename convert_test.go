@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/v10/arrow"
+
+	"es-schema/esschema"
+)
+
+func writeNDJSON(t *testing.T, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "docs.ndjson")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNDJSONInput(t *testing.T) {
+	src, err := openNDJSON(writeNDJSON(t, `{"a": 1}`, "", `  {"a": 2}  `, `{"a": 3}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.close()
+	hits, err := src.next(2)
+	if err != nil || len(hits) != 2 {
+		t.Fatalf("next = %v, %v", hits, err)
+	}
+	// 빈 줄도 줄 번호는 차지하므로 _id가 원래 파일의 줄을 가리킵니다.
+	if hits[0].ID != "1" || hits[1].ID != "3" || string(hits[1].Source) != `{"a": 2}` || hits[1].Index != "docs.ndjson" {
+		t.Errorf("hits = %+v", hits)
+	}
+	// 마지막 줄에 줄바꿈이 없어도 읽습니다.
+	if hits, err = src.next(2); err != nil || len(hits) != 1 || hits[0].ID != "4" {
+		t.Errorf("last batch = %+v, %v", hits, err)
+	}
+	if _, err = src.next(2); err != io.EOF {
+		t.Errorf("after the end = %v, want io.EOF", err)
+	}
+	if _, err := openNDJSON(filepath.Join(t.TempDir(), "missing.ndjson")); exitCodeFor(err) != exitConfigError {
+		t.Errorf("missing input = %v", err)
+	}
+}
+
+func TestNDJSONInputHitsAndGzip(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(`{"_index": "logs", "_type": "_doc", "_id": "a-1", "_score": 1, "_source": {"msg": "hi"}}
+{"msg": "has \"_source\" in a value"}
+{"_id": "a-3", "_source": {}}
+`))
+	gz.Close()
+	path := filepath.Join(t.TempDir(), "logs.json.gz")
+	os.WriteFile(path, buf.Bytes(), 0o644)
+
+	src, err := openNDJSON(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.close()
+	hits, err := src.next(10)
+	if err != nil || len(hits) != 3 {
+		t.Fatalf("next = %+v, %v", hits, err)
+	}
+	if hits[0].ID != "a-1" || hits[0].Index != "logs" || string(hits[0].Source) != `{"msg": "hi"}` {
+		t.Errorf("elasticdump hit = %+v", hits[0])
+	}
+	if hits[1].ID != "2" || hits[1].Index != "logs.json.gz" || !strings.HasPrefix(string(hits[1].Source), `{"msg"`) {
+		t.Errorf("plain document = %+v", hits[1])
+	}
+	if hits[2].ID != "a-3" || string(hits[2].Source) != "{}" {
+		t.Errorf("hit with an empty _source = %+v", hits[2])
+	}
+}
+
+func TestValidateNDJSON(t *testing.T) {
+	mapping := filepath.Join(t.TempDir(), "mapping.json")
+	os.WriteFile(mapping, []byte(`{"properties": {"n": {"type": "long"}, "tag": {"type": "keyword"}}}`), 0o644)
+	in := mappingInputOptions{mapping: mapping}
+	schema, _, err := in.schema(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func(lines ...string) (*runReport, error) {
+		src, err := openNDJSON(writeNDJSON(t, lines...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer src.close()
+		report := newRunReport("validate")
+		return report, validateNDJSON(context.Background(), report, &in, src, batchOptions{rows: defaultBatchRows, bytes: defaultBatchBytes}, schema, &overflowOptions{policy: "null"})
+	}
+
+	if report, err := run(`{"n": 1, "tag": "a"}`, `{"n": "2"}`); err != nil || report.RowsRead != 2 {
+		t.Errorf("valid documents: rows = %d, err = %v", report.RowsRead, err)
+	}
+	report, err := run(`{"n": 1}`, `{"n": "many"}`, `{not json`)
+	if exitCodeFor(err) != exitDataError || report.DocumentsFailed != 2 {
+		t.Fatalf("err = %v, failed = %d", err, report.DocumentsFailed)
+	}
+	if report.FailureReasons["conversion:n"] != 1 || report.FailureReasons["decode"] != 1 {
+		t.Errorf("reasons = %v", report.FailureReasons)
+	}
+}
+
+// convertForTest 함수는 mapping으로 NDJSON lines를 convert해 쓴 Parquet 파일의 경로를
+// 반환합니다.
+func convertForTest(t *testing.T, mapping string, lines ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	mappingPath := filepath.Join(dir, "mapping.json")
+	if err := os.WriteFile(mappingPath, []byte(mapping), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(dir, "docs.parquet")
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	run := setupConvert(fs)
+	if err := fs.Parse([]string{"--mapping", mappingPath, "--input", writeNDJSON(t, lines...), "--output", output}); err != nil {
+		t.Fatal(err)
+	}
+	if err := run(context.Background(), newRunReport("convert"), fs.Args()); err != nil {
+		t.Fatal(err)
+	}
+	return output
+}
+
+func TestConvertMissingFieldsAreNull(t *testing.T) {
+	output := convertForTest(t, `{"properties": {"k": {"type": "long"}, "d": {"type": "date"}, "v": {"type": "dense_vector", "dims": 3}}}`,
+		`{"k": 1, "d": "2024-01-02T00:00:00Z", "v": [1, 2, 3]}`, `{}`)
+
+	f, err := openParquetFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var rows int64
+	if _, err := f.records(context.Background(), nil, func(rec arrow.Record) error {
+		rows += rec.NumRows()
+		for i, col := range rec.Columns() {
+			name := rec.ColumnName(i)
+			if name == "_id" {
+				continue
+			}
+			if col.IsNull(0) || !col.IsNull(1) {
+				t.Errorf("%s: nulls = %t, %t, want false, true", name, col.IsNull(0), col.IsNull(1))
+			}
+			if !rec.Schema().Field(i).Nullable {
+				t.Errorf("%s is not nullable", name)
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if rows != 2 {
+		t.Errorf("rows = %d, want 2", rows)
+	}
+}
+
+func TestMappingInputFromCluster(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/logs/_mapping" {
+			t.Errorf("request = %s %s", r.Method, r.URL)
+		}
+		fmt.Fprint(w, `{"logs-2024.05": {"mappings": {"properties": {"message": {"type": "text"}}}}}`)
+	}))
+	defer srv.Close()
+	in := mappingInputOptions{es: esOptions{url: srv.URL}, index: "logs"}
+	data, err := in.readMapping(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, err := esschema.ParseMapping(data); err != nil || m.Properties["message"] == nil {
+		t.Errorf("mapping = %s: %v", data, err)
+	}
+
+	in.mapping = "mapping.json"
+	if _, err := in.readMapping(context.Background()); exitCodeFor(err) != exitConfigError {
+		t.Errorf("--mapping with --index = %v", err)
+	}
+	if _, err := (&mappingInputOptions{}).readMapping(context.Background()); exitCodeFor(err) != exitConfigError {
+		t.Errorf("no mapping = %v", err)
+	}
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// daemonConfig는 daemon 명령의 파이프라인 설정 파일입니다.
+//
+//	pipelines:
+//	  - name: logs-nightly
+//	    every: 24h
+//	    offset: 2h
+//	    flags:
+//	      url: https://es-prod:9200
+//	      api-key: ${file:/run/secrets/es-api-key}
+//	      index: [logs-*]
+//	      query: '{"range": {"@timestamp": {"gte": "now-1d/d"}}}'
+//	      out-dir: /data/logs
+type daemonConfig struct {
+	Pipelines []*pipelineSpec `json:"pipelines"`
+}
+
+// pipelineSpec은 정해진 간격마다 실행하는 명령 하나입니다. Command가 비어 있으면 export이고,
+// Flags는 프로필처럼 플래그 이름과 값입니다. 실행은 UTC 자정부터 Every 간격으로 맞춘 시각에
+// Offset을 더한 때 시작하므로, daemon을 다시 시작해도 실행 시각이 바뀌지 않습니다.
+type pipelineSpec struct {
+	Name    string                 `json:"name"`
+	Command string                 `json:"command,omitempty"`
+	Every   string                 `json:"every"`
+	Offset  string                 `json:"offset,omitempty"`
+	Flags   map[string]interface{} `json:"flags,omitempty"`
+
+	every  time.Duration
+	offset time.Duration
+}
+
+// loadDaemonConfig 함수는 path의 파이프라인을 읽어 검사한 뒤 이름별로 반환합니다.
+func loadDaemonConfig(path string) (map[string]*pipelineSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, configErrorf("reading daemon config: %w", err)
+	}
+	var cfg daemonConfig
+	if err := unmarshalYAML(data, &cfg); err != nil {
+		return nil, configErrorf("parsing %s: %w", path, err)
+	}
+	specs := make(map[string]*pipelineSpec, len(cfg.Pipelines))
+	for i, p := range cfg.Pipelines {
+		if p == nil || p.Name == "" {
+			return nil, configErrorf("%s: pipeline %d has no name", path, i+1)
+		}
+		if specs[p.Name] != nil {
+			return nil, configErrorf("%s: pipeline %q is defined twice", path, p.Name)
+		}
+		if err := p.validate(); err != nil {
+			return nil, configErrorf("%s: pipeline %q: %w", path, p.Name, err)
+		}
+		specs[p.Name] = p
+	}
+	return specs, nil
+}
+
+func (p *pipelineSpec) validate() error {
+	var err error
+	if p.every, err = time.ParseDuration(p.Every); err != nil || p.every <= 0 {
+		return fmt.Errorf("every must be a positive duration such as 1h, got %q", p.Every)
+	}
+	if p.Offset != "" {
+		if p.offset, err = time.ParseDuration(p.Offset); err != nil || p.offset < 0 || p.offset >= p.every {
+			return fmt.Errorf("offset must be a duration shorter than every, got %q", p.Offset)
+		}
+	}
+	return p.checkCommand()
+}
+
+// checkCommand 함수는 명령이 있고 Flags가 모두 그 명령의 플래그인지 검사합니다. 값은 실행할
+// 때 비밀 참조를 풀어 넣으므로 여기서는 검사하지 않습니다.
+func (p *pipelineSpec) checkCommand() error {
+	if p.Command == "" {
+		p.Command = "export"
+	}
+	cmd := lookupCommand(p.Command)
+	if cmd == nil || cmd.name == "daemon" {
+		return fmt.Errorf("unknown command %q", p.Command)
+	}
+	fs, _, _ := p.flagSet()
+	for key := range p.Flags {
+		if key == "profile" || fs.Lookup(key) == nil {
+			return fmt.Errorf("%s has no flag --%s", p.Command, key)
+		}
+	}
+	return nil
+}
+
+// flagSet 함수는 파이프라인 명령의 플래그와 전역 옵션, 실행 함수를 만듭니다.
+func (p *pipelineSpec) flagSet() (*flag.FlagSet, *globalOptions, func(ctx context.Context, report *runReport, args []string) error) {
+	fs := flag.NewFlagSet("es-schema "+p.Command, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	opts := &globalOptions{}
+	opts.bind(fs)
+	return fs, opts, lookupCommand(p.Command).setup(fs)
+}
+
+// nextRun 함수는 after 뒤의 첫 실행 시각을 반환합니다.
+func (p *pipelineSpec) nextRun(after time.Time) time.Time {
+	return after.Add(-p.offset).Truncate(p.every).Add(p.every + p.offset)
+}
+
+// sameSchedule 함수는 두 정의의 실행 시각이 같은지 알려 줍니다.
+func (p *pipelineSpec) sameSchedule(q *pipelineSpec) bool {
+	return p.every == q.every && p.offset == q.offset
+}
+
+// daemon은 설정 파일의 파이프라인을 일정에 따라 실행합니다. 설정 파일이 바뀌거나 SIGHUP을
+// 받으면 다시 읽어, 다음 실행부터 새 일정과 플래그를 씁니다. 실행 중인 파이프라인은 시작할
+// 때의 정의로 끝까지 돌고, 설정 파일이 잘못되면 이전 설정을 그대로 씁니다.
+type daemon struct {
+	path string
+	// run은 파이프라인 한 번을 실행해 report를 채웁니다. 테스트가 바꿔 끼웁니다.
+	run func(ctx context.Context, p *pipelineSpec, report *runReport)
+	// maxJobs는 동시에 실행하는 작업 수이고, history는 기억해 두는 끝난 작업 수입니다.
+	maxJobs int
+	history int
+	// locker가 있으면 파이프라인 작업을 owner 이름으로 잠그고 실행합니다.
+	locker  pipelineLocker
+	owner   string
+	lockTTL time.Duration
+
+	mu        sync.Mutex
+	pipelines map[string]*pipelineSpec
+	next      map[string]time.Time
+	jobs      []*daemonJob
+	seq       int
+	modTime   time.Time
+	size      int64
+	wg        sync.WaitGroup
+}
+
+func newDaemon(path string) *daemon {
+	return &daemon{
+		path:    path,
+		run:     runPipeline,
+		maxJobs: 1,
+		history: defaultJobHistory,
+		next:    make(map[string]time.Time),
+	}
+}
+
+func setupDaemon(fs *flag.FlagSet) func(ctx context.Context, report *runReport, args []string) error {
+	var config, listen, token string
+	var reloadEvery time.Duration
+	var maxJobs, history int
+	var lock lockOptions
+	fs.StringVar(&config, "config", "", "pipeline YAML file listing the commands to run, their schedules and flags (required)")
+	fs.DurationVar(&reloadEvery, "reload-interval", 10*time.Second, "check the config file for changes this often and apply them to later runs (0 to reload only on SIGHUP)")
+	fs.IntVar(&maxJobs, "max-jobs", 1, "run at most this many jobs at once; a pipeline never runs twice at the same time")
+	fs.IntVar(&history, "job-history", defaultJobHistory, "remember this many finished jobs for the API")
+	fs.StringVar(&listen, "listen", "", "serve the job API on this address, such as :8080, to submit, list and cancel jobs and fetch their reports")
+	fs.StringVar(&token, "api-token", "", "require this bearer token on every API request (default $ES_SCHEMA_API_TOKEN)")
+	lock.bind(fs)
+
+	return func(ctx context.Context, report *runReport, args []string) error {
+		if len(args) > 0 {
+			return configErrorf("daemon: unexpected arguments %v", args)
+		}
+		if config == "" {
+			return configErrorf("daemon: --config is required")
+		}
+		if reloadEvery < 0 {
+			return configErrorf("daemon: --reload-interval cannot be negative")
+		}
+		if maxJobs <= 0 || history < 0 {
+			return configErrorf("daemon: --max-jobs must be positive and --job-history not negative")
+		}
+		locker, err := lock.locker()
+		if err != nil {
+			return err
+		}
+		d := newDaemon(config)
+		d.maxJobs, d.history = maxJobs, history
+		d.locker, d.owner, d.lockTTL = locker, lock.owner, lock.ttl
+		return d.serve(ctx, reloadEvery, listen, firstNonEmpty(token, os.Getenv("ES_SCHEMA_API_TOKEN")))
+	}
+}
+
+// serve 함수는 ctx가 끝날 때까지 파이프라인을 실행하고, 끝나면 실행 중인 작업을
+// 기다립니다. listen이 있으면 작업 API도 엽니다. 처음 읽는 설정 파일이 잘못되거나 API
+// 주소를 열 수 없으면 바로 오류를 반환합니다.
+func (d *daemon) serve(ctx context.Context, reloadEvery time.Duration, listen, token string) error {
+	if err := d.reload(time.Now()); err != nil {
+		return err
+	}
+	defer d.wg.Wait()
+	if listen != "" {
+		ln, err := net.Listen("tcp", listen)
+		if err != nil {
+			return configErrorf("daemon: --listen: %w", err)
+		}
+		srv := &http.Server{Handler: d.handler(ctx, token), ReadHeaderTimeout: 10 * time.Second}
+		go srv.Serve(ln)
+		defer func() {
+			shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			srv.Shutdown(shutdown)
+		}()
+		fmt.Printf("daemon: job API listening on %s\n", ln.Addr())
+	}
+	hup := make(chan os.Signal, 1)
+	if len(reloadSignals) > 0 {
+		signal.Notify(hup, reloadSignals...)
+		defer signal.Stop(hup)
+	}
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+	var watch <-chan time.Time
+	if reloadEvery > 0 {
+		t := time.NewTicker(reloadEvery)
+		defer t.Stop()
+		watch = t.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-tick.C:
+			d.tick(ctx, now)
+		case <-hup:
+			d.reloadLogged("SIGHUP")
+		case <-watch:
+			if d.changed() {
+				d.reloadLogged("config change")
+			}
+		}
+	}
+}
+
+// reload 함수는 설정 파일을 다시 읽습니다. 일정이 바뀌었거나 새로 생긴 파이프라인만 다음
+// 실행 시각을 새로 정하고, 사라진 파이프라인은 더 실행하지 않습니다.
+func (d *daemon) reload(now time.Time) error {
+	info, err := os.Stat(d.path)
+	if err != nil {
+		return configErrorf("reading daemon config: %w", err)
+	}
+	specs, err := loadDaemonConfig(d.path)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.modTime, d.size = info.ModTime(), info.Size()
+	for name, p := range specs {
+		if old := d.pipelines[name]; old == nil || !old.sameSchedule(p) {
+			d.next[name] = p.nextRun(now)
+		}
+	}
+	for name := range d.pipelines {
+		if specs[name] == nil {
+			delete(d.next, name)
+		}
+	}
+	d.pipelines = specs
+	return nil
+}
+
+func (d *daemon) reloadLogged(why string) {
+	before := d.snapshot()
+	if err := d.reload(time.Now()); err != nil {
+		fmt.Printf("daemon: %s: keeping the previous configuration: %v\n", why, err)
+		return
+	}
+	fmt.Printf("daemon: %s: reloaded %s (%s)\n", why, d.path, describeReload(before, d.snapshot()))
+}
+
+// changed 함수는 마지막으로 읽은 뒤 설정 파일이 바뀌었는지 알려 줍니다.
+func (d *daemon) changed() bool {
+	info, err := os.Stat(d.path)
+	if err != nil {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return !info.ModTime().Equal(d.modTime) || info.Size() != d.size
+}
+
+func (d *daemon) snapshot() map[string]*pipelineSpec {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.pipelines
+}
+
+// tick 함수는 실행 시각이 된 파이프라인을 작업 큐에 넣습니다. 앞선 작업이 아직 큐에 있거나
+// 돌고 있는 파이프라인은 그 작업이 끝난 뒤의 tick에서 넣습니다.
+func (d *daemon) tick(ctx context.Context, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	names := make([]string, 0, len(d.pipelines))
+	for name := range d.pipelines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if d.active(name) || now.Before(d.next[name]) {
+			continue
+		}
+		p := d.pipelines[name]
+		slot := d.next[name]
+		d.next[name] = p.nextRun(now)
+		d.enqueue(ctx, p, triggerSchedule).slot = slot
+	}
+	d.dispatch()
+}
+
+// runPipeline 함수는 파이프라인의 명령을 한 번 실행해 report를 채우고 결과를 출력합니다.
+// --status-file, --notify-url 같은 전역 옵션도 파이프라인의 플래그로 줄 수 있습니다.
+func runPipeline(ctx context.Context, p *pipelineSpec, report *runReport) {
+	fs, opts, execute := p.flagSet()
+	fs.Parse(nil)
+	fmt.Printf("daemon: %s: starting %s\n", p.Name, p.Command)
+	err := applySettings(ctx, fs, fmt.Sprintf("pipeline %q", p.Name), p.Flags)
+	if err == nil {
+		err = opts.validate()
+	}
+	if err == nil {
+		err = opts.audit.begin(fs)
+	}
+	if err == nil {
+		if opts.anomaly.key == "" {
+			opts.anomaly.key = p.Name
+		}
+		err = execute(ctx, report, nil)
+	}
+	code := finishRun(opts, report, err)
+	fmt.Printf("daemon: %s: %s in %s, %d rows (exit code %d)\n", p.Name, report.Status, time.Duration(report.DurationSeconds*float64(time.Second)).Round(time.Second), report.RowsExported, code)
+}
+
+// describeReload 함수는 다시 읽은 설정에서 더하고 빼고 바꾼 파이프라인을 요약합니다.
+func describeReload(before, after map[string]*pipelineSpec) string {
+	var added, removed, changed int
+	for name, p := range after {
+		switch old := before[name]; {
+		case old == nil:
+			added++
+		case !reflect.DeepEqual(old, p):
+			changed++
+		}
+	}
+	for name := range before {
+		if after[name] == nil {
+			removed++
+		}
+	}
+	return fmt.Sprintf("%d pipelines: %d added, %d removed, %d changed", len(after), added, removed, changed)
+}
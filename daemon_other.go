@@ -0,0 +1,11 @@
+//go:build !(js && wasm)
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// reloadSignals는 daemon이 설정 파일을 다시 읽는 신호입니다.
+var reloadSignals = []os.Signal{syscall.SIGHUP}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadDaemonConfig(t *testing.T) {
+	dir := t.TempDir()
+	write := func(body string) string {
+		path := filepath.Join(dir, "pipeline.yaml")
+		os.WriteFile(path, []byte(body), 0o644)
+		return path
+	}
+	specs, err := loadDaemonConfig(write(`
+pipelines:
+  - name: logs
+    every: 24h
+    offset: 2h
+    flags:
+      url: http://localhost:9200
+      index: [logs-*]
+      out-dir: /data/logs
+  - name: compact-logs
+    command: compact
+    every: 1h
+    flags:
+      target-mb: 256
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := specs["logs"]; p == nil || p.Command != "export" || p.every != 24*time.Hour || p.offset != 2*time.Hour {
+		t.Errorf("logs = %+v", p)
+	}
+	if p := specs["compact-logs"]; p == nil || p.Command != "compact" {
+		t.Errorf("compact-logs = %+v", p)
+	}
+
+	for body, want := range map[string]string{
+		"pipelines:\n  - name: a\n    every: 1h\n  - name: a\n    every: 2h\n":                       `"a" is defined twice`,
+		"pipelines:\n  - name: a\n    command: daemon\n    every: 1h\n":                              `unknown command "daemon"`,
+		"pipelines:\n  - name: a\n    every: soon\n":                                                 "every must be a positive duration",
+		"pipelines:\n  - name: a\n    every: 1h\n    offset: 2h\n":                                   "offset must be a duration shorter than every",
+		"pipelines:\n  - name: a\n    command: compact\n    every: 1h\n    flags:\n      index: x\n": "compact has no flag --index",
+		"pipelines:\n  - every: 1h\n":                                                                "pipeline 1 has no name",
+	} {
+		if _, err := loadDaemonConfig(write(body)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("config\n%s= %v, want %q", body, err, want)
+		}
+	}
+}
+
+func TestPipelineNextRun(t *testing.T) {
+	p := &pipelineSpec{every: 24 * time.Hour, offset: 2 * time.Hour}
+	at := func(s string) time.Time {
+		v, _ := time.Parse(time.RFC3339, s)
+		return v
+	}
+	for after, want := range map[string]string{
+		"2024-05-01T01:30:00Z": "2024-05-01T02:00:00Z",
+		"2024-05-01T02:00:00Z": "2024-05-02T02:00:00Z",
+		"2024-05-01T13:00:00Z": "2024-05-02T02:00:00Z",
+	} {
+		if got := p.nextRun(at(after)); !got.Equal(at(want)) {
+			t.Errorf("nextRun(%s) = %s, want %s", after, got.Format(time.RFC3339), want)
+		}
+	}
+	hourly := &pipelineSpec{every: time.Hour}
+	if got := hourly.nextRun(at("2024-05-01T13:20:00Z")); !got.Equal(at("2024-05-01T14:00:00Z")) {
+		t.Errorf("hourly nextRun = %s", got)
+	}
+}
+
+func TestDaemonReloadKeepsRunningPipelines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pipeline.yaml")
+	config := func(every, outDir string) {
+		os.WriteFile(path, []byte("pipelines:\n  - name: logs\n    every: "+every+"\n    flags:\n      out-dir: "+outDir+"\n"), 0o644)
+	}
+	config("1h", "/data/v1")
+
+	started := make(chan string, 4)
+	release := make(chan struct{})
+	d := newDaemon(path)
+	d.run = func(ctx context.Context, p *pipelineSpec, report *runReport) {
+		started <- p.Flags["out-dir"].(string)
+		<-release
+	}
+	start := time.Date(2024, 5, 1, 13, 20, 0, 0, time.UTC)
+	if err := d.reload(start); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	d.tick(ctx, start.Add(30*time.Minute))
+	d.tick(ctx, start.Add(40*time.Minute))
+	if got := <-started; got != "/data/v1" {
+		t.Fatalf("first run used out-dir %s", got)
+	}
+
+	// 실행 중에 설정이 바뀌어도 그 실행은 그대로 두고, 다음 실행부터 새 설정을 씁니다.
+	config("30m", "/data/v2")
+	if !d.changed() {
+		t.Error("changed() did not notice the rewritten config")
+	}
+	if err := d.reload(start.Add(45 * time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	d.tick(ctx, start.Add(2*time.Hour))
+	select {
+	case got := <-started:
+		t.Fatalf("started %s while the previous run was still going", got)
+	default:
+	}
+	release <- struct{}{}
+	for {
+		d.mu.Lock()
+		idle := !d.active("logs")
+		d.mu.Unlock()
+		if idle {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	d.tick(ctx, start.Add(2*time.Hour))
+	if got := <-started; got != "/data/v2" {
+		t.Errorf("run after reload used out-dir %s", got)
+	}
+	close(release)
+	d.wg.Wait()
+
+	// 잘못된 설정은 이전 설정을 그대로 둡니다.
+	os.WriteFile(path, []byte("pipelines:\n  - name: logs\n    every: never\n"), 0o644)
+	d.reloadLogged("test")
+	if p := d.snapshot()["logs"]; p == nil || p.Flags["out-dir"] != "/data/v2" {
+		t.Errorf("invalid config replaced the pipelines: %+v", p)
+	}
+}
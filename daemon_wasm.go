@@ -0,0 +1,8 @@
+//go:build js && wasm
+
+package main
+
+import "os"
+
+// reloadSignals는 비어 있습니다. WebAssembly에는 SIGHUP이 없습니다.
+var reloadSignals []os.Signal
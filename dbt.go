@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// descriptionKey는 매핑의 _meta와 필드의 meta에서 설명을 담는 키입니다. _meta의 description은
+// 인덱스(테이블)의 설명이고, _meta의 descriptions는 필드 경로에서 설명으로의 객체입니다.
+//
+//	{"_meta": {"description": "web access logs", "descriptions": {"user.id": "account id"}},
+//	 "properties": {"status": {"type": "integer", "meta": {"description": "HTTP status"}}}}
+const descriptionKey = "description"
+
+// dbtOptions는 emit --builtin dbt의 설정입니다.
+type dbtOptions struct {
+	source   string
+	table    string
+	location string
+	dialect  string
+}
+
+func (o *dbtOptions) bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.source, "dbt-source", "elasticsearch", "--builtin dbt: name of the dbt source")
+	fs.StringVar(&o.table, "dbt-table", "", "--builtin dbt: table name (default: --index, or the --mapping file name without its extension)")
+	fs.StringVar(&o.location, "dbt-location", "", "--builtin dbt: location of the exported Parquet files (for example s3://bucket/exports/logs/), written as the external location read by dbt-external-tables")
+	fs.StringVar(&o.dialect, "dbt-dialect", "", "--builtin dbt: write column data_type for this warehouse: bigquery, snowflake or trino (default: the Arrow type)")
+}
+
+func (o *dbtOptions) validate() error {
+	if o.source == "" {
+		return configErrorf("--dbt-source must not be empty")
+	}
+	if _, ok := sqlDialects[o.dialect]; o.dialect != "" && !ok {
+		return configErrorf("unknown --dbt-dialect %q (want bigquery, snowflake or trino)", o.dialect)
+	}
+	return nil
+}
+
+// dbtSourcesFile은 dbt의 sources 정의 파일(models/sources.yml)입니다.
+type dbtSourcesFile struct {
+	Version int         `json:"version"`
+	Sources []dbtSource `json:"sources"`
+}
+
+type dbtSource struct {
+	Name   string     `json:"name"`
+	Tables []dbtTable `json:"tables"`
+}
+
+type dbtTable struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	External    *dbtExternal      `json:"external,omitempty"`
+	Meta        map[string]string `json:"meta,omitempty"`
+	Columns     []dbtColumn       `json:"columns"`
+}
+
+// dbtExternal은 dbt-external-tables 패키지가 외부 테이블을 만드는 설정입니다.
+type dbtExternal struct {
+	Location   string `json:"location"`
+	FileFormat string `json:"file_format"`
+}
+
+type dbtColumn struct {
+	Name        string `json:"name"`
+	DataType    string `json:"data_type,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// dbtSources 함수는 model의 최상위 컬럼마다 컬럼 하나인 테이블 하나의 dbt sources 정의를
+// YAML로 반환합니다. 구조체 컬럼은 웨어하우스에서도 컬럼 하나이므로 하위 필드로 나누지
+// 않습니다. mappingPath는 --dbt-table이 없을 때 테이블 이름을 정하는 데 씁니다.
+func dbtSources(model *emitModel, o *dbtOptions, mappingPath string) ([]byte, error) {
+	table := dbtTable{
+		Name:        o.table,
+		Description: model.Description,
+		Meta:        map[string]string{"es_schema_source": model.Source},
+	}
+	if table.Name == "" {
+		table.Name = datasetName(model, mappingPath)
+	}
+	if o.location != "" {
+		table.External = &dbtExternal{Location: o.location, FileFormat: "parquet"}
+	}
+	for _, f := range model.Fields {
+		c := dbtColumn{Name: f.Name, DataType: f.Type, Description: f.Description}
+		if o.dialect != "" {
+			d := sqlDialects[o.dialect]
+			t, err := d.columnType(d, f.DataType)
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %w", f.Name, err)
+			}
+			c.DataType = t
+		}
+		table.Columns = append(table.Columns, c)
+	}
+	var buf bytes.Buffer
+	err := writeYAML(&buf, dbtSourcesFile{Version: 2, Sources: []dbtSource{{Name: o.source, Tables: []dbtTable{table}}}})
+	return buf.Bytes(), err
+}
+
+// datasetName 함수는 내장 산출물의 데이터셋 이름으로 확장자를 뺀 --mapping 파일 이름을,
+// 파일이 없으면 model의 출처(--index)를 반환합니다.
+func datasetName(model *emitModel, mappingPath string) string {
+	if mappingPath == "" {
+		return model.Source
+	}
+	return strings.TrimSuffix(filepath.Base(mappingPath), filepath.Ext(mappingPath))
+}
+
+// mappingDescriptions 함수는 매핑에서 테이블 설명과 필드 경로별 설명을 모읍니다. 같은
+// 경로에는 필드의 meta가 _meta의 descriptions보다 우선합니다.
+func mappingDescriptions(mapping map[string]interface{}) (string, map[string]string) {
+	var table string
+	fields := make(map[string]string)
+	if meta, ok := mapping["_meta"].(map[string]interface{}); ok {
+		table, _ = meta[descriptionKey].(string)
+		if d, ok := meta[descriptionKey+"s"].(map[string]interface{}); ok {
+			for path, text := range d {
+				if text, ok := text.(string); ok && text != "" {
+					fields[path] = text
+				}
+			}
+		}
+	}
+	props, _ := mapping["properties"].(map[string]interface{})
+	fieldDescriptions(props, "", fields)
+	return table, fields
+}
+
+func fieldDescriptions(props map[string]interface{}, prefix string, out map[string]string) {
+	for name, v := range props {
+		field, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if meta, ok := field["meta"].(map[string]interface{}); ok {
+			if text, ok := meta[descriptionKey].(string); ok && text != "" {
+				out[prefix+name] = text
+			}
+		}
+		if sub, ok := field["properties"].(map[string]interface{}); ok {
+			fieldDescriptions(sub, prefix+name+".", out)
+		}
+	}
+}
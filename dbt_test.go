@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMappingDescriptions(t *testing.T) {
+	var mapping map[string]interface{}
+	json.Unmarshal([]byte(`{
+		"_meta": {"description": "web access logs", "descriptions": {"user.id": "account id", "status": "overridden"}},
+		"properties": {
+			"status": {"type": "integer", "meta": {"description": "HTTP status"}},
+			"user": {"properties": {"id": {"type": "keyword"}, "name": {"type": "keyword", "meta": {"description": "display name"}}}}
+		}}`), &mapping)
+	table, fields := mappingDescriptions(mapping)
+	if table != "web access logs" {
+		t.Errorf("table = %q", table)
+	}
+	want := map[string]string{"status": "HTTP status", "user.id": "account id", "user.name": "display name"}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("fields = %v, want %v", fields, want)
+	}
+}
+
+func TestDBTSources(t *testing.T) {
+	mapping := []byte(`{"logs": {"mappings": {
+		"_meta": {"description": "web access logs"},
+		"properties": {
+			"status": {"type": "integer", "meta": {"description": "HTTP status"}},
+			"user": {"properties": {"id": {"type": "keyword"}}}
+		}}}}`)
+	schema, err := schemaFromMapping(mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model, err := newEmitModel("mappings/logs.json", schema, mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := &dbtOptions{source: "es", location: "s3://lake/logs/", dialect: "bigquery"}
+	out, err := dbtSources(model, o, "mappings/logs.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `sources:
+  - name: es
+    tables:
+      - columns:
+          - data_type: INT64
+            description: "HTTP status"
+            name: status
+          - data_type: "STRUCT<id STRING>"
+            name: user
+        description: "web access logs"
+        external:
+          file_format: parquet
+          location: "s3://lake/logs/"
+        meta:
+          es_schema_source: mappings/logs.json
+        name: logs
+version: 2
+`
+	if string(out) != want {
+		t.Errorf("sources.yml =\n%s\nwant\n%s", out, want)
+	}
+
+	o.dialect = "oracle"
+	if err := o.validate(); exitCodeFor(err) != exitConfigError || !strings.Contains(err.Error(), "--dbt-dialect") {
+		t.Errorf("unknown dialect = %v", err)
+	}
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+const (
+	reservedQuote  = "quote"
+	reservedSuffix = "suffix"
+	reservedPrefix = "prefix"
+)
+
+// plainIdentifier는 따옴표 없이 쓸 수 있는 식별자입니다.
+var plainIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// sqlDialect는 DDL을 만들 대상 시스템입니다. 세 시스템 모두 따옴표 없는 식별자의
+// 대소문자를 구분하지 않으므로 대소문자만 다른 이름은 충돌로 봅니다.
+type sqlDialect struct {
+	name string
+	// reserved는 대문자로 적은 예약어 집합입니다.
+	reserved map[string]bool
+	quote    string
+	// namedStructs는 구조체 필드 이름이 DDL에 나타나는지 여부입니다(Snowflake OBJECT는 아닙니다).
+	namedStructs bool
+	columnType   func(d *sqlDialect, t arrow.DataType) (string, error)
+}
+
+var sqlDialects = map[string]*sqlDialect{
+	"bigquery": {
+		name:         "BigQuery",
+		reserved:     wordSet("ALL AND ANY ARRAY AS ASC ASSERT_ROWS_MODIFIED AT BETWEEN BY CASE CAST COLLATE CONTAINS CREATE CROSS CUBE CURRENT DEFAULT DEFINE DESC DISTINCT ELSE END ENUM ESCAPE EXCEPT EXCLUDE EXISTS EXTRACT FALSE FETCH FOLLOWING FOR FROM FULL GROUP GROUPING GROUPS HASH HAVING IF IGNORE IN INNER INTERSECT INTERVAL INTO IS JOIN LATERAL LEFT LIKE LIMIT LOOKUP MERGE NATURAL NEW NO NOT NULL NULLS OF ON OR ORDER OUTER OVER PARTITION PRECEDING PROTO QUALIFY RANGE RECURSIVE RESPECT RIGHT ROLLUP ROWS SELECT SET SOME STRUCT TABLESAMPLE THEN TO TREAT TRUE UNBOUNDED UNION UNNEST USING WHEN WHERE WINDOW WITH WITHIN"),
+		quote:        "`",
+		namedStructs: true,
+		columnType:   bigQueryType,
+	},
+	"snowflake": {
+		name:       "Snowflake",
+		reserved:   wordSet("ACCOUNT ALL ALTER AND ANY AS BETWEEN BY CASE CAST CHECK COLUMN CONNECT CONNECTION CONSTRAINT CREATE CROSS CURRENT CURRENT_DATE CURRENT_TIME CURRENT_TIMESTAMP CURRENT_USER DATABASE DELETE DISTINCT DROP ELSE EXISTS FALSE FOLLOWING FOR FROM FULL GRANT GROUP GSCLUSTER HAVING ILIKE IN INCREMENT INNER INSERT INTERSECT INTO IS ISSUE JOIN LATERAL LEFT LIKE LOCALTIME LOCALTIMESTAMP MINUS NATURAL NOT NULL OF ON OR ORDER ORGANIZATION QUALIFY REGEXP REVOKE RIGHT RLIKE ROW ROWS SAMPLE SCHEMA SELECT SET SOME START TABLE TABLESAMPLE THEN TO TRIGGER TRUE TRY_CAST UNION UNIQUE UPDATE USING VALUES VIEW WHEN WHENEVER WHERE WITH"),
+		quote:      `"`,
+		columnType: snowflakeType,
+	},
+	"trino": {
+		name:         "Trino",
+		reserved:     wordSet("ALTER AND AS BETWEEN BY CASE CAST CONSTRAINT CREATE CROSS CUBE CURRENT_CATALOG CURRENT_DATE CURRENT_PATH CURRENT_ROLE CURRENT_SCHEMA CURRENT_TIME CURRENT_TIMESTAMP CURRENT_USER DEALLOCATE DELETE DESCRIBE DISTINCT DROP ELSE END ESCAPE EXCEPT EXECUTE EXISTS EXTRACT FALSE FOR FROM FULL GROUP GROUPING HAVING IN INNER INSERT INTERSECT INTO IS JOIN JSON_ARRAY JSON_EXISTS JSON_OBJECT JSON_QUERY JSON_TABLE JSON_VALUE LEFT LIKE LISTAGG LOCALTIME LOCALTIMESTAMP NATURAL NORMALIZE NOT NULL ON OR ORDER OUTER PREPARE RECURSIVE RIGHT ROLLUP SELECT SKIP TABLE THEN TRIM TRUE UESCAPE UNION UNNEST USING VALUES WHEN WHERE WITH"),
+		quote:        `"`,
+		namedStructs: true,
+		columnType:   trinoType,
+	},
+}
+
+func wordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
+
+func (d *sqlDialect) ident(name string) string {
+	if plainIdentifier.MatchString(name) && !d.reserved[strings.ToUpper(name)] {
+		return name
+	}
+	return d.quote + strings.ReplaceAll(name, d.quote, d.quote+d.quote) + d.quote
+}
+
+type ddlOptions struct {
+	mappingPath   string
+	dialect       string
+	table         string
+	reserved      string
+	caseCollision string
+	names         nameOptions
+}
+
+func setupDDL(fs *flag.FlagSet) func(ctx context.Context, report *runReport, args []string) error {
+	var o ddlOptions
+	fs.StringVar(&o.mappingPath, "mapping", "", "mapping JSON file to generate the table from (required)")
+	fs.StringVar(&o.dialect, "dialect", "", "target system: bigquery, snowflake or trino (required)")
+	fs.StringVar(&o.table, "table", "es_documents", "table name in the CREATE TABLE statement")
+	fs.StringVar(&o.reserved, "reserved-words", reservedQuote, "fields named like reserved words: quote (keep the name), suffix (append _) or prefix (prepend _)")
+	fs.StringVar(&o.caseCollision, "case-collision", collisionSuffix, "fields whose names differ only in case: suffix (append _2, _3, ...) or error")
+	o.names.bind(fs)
+
+	return func(ctx context.Context, report *runReport, args []string) error {
+		d, err := o.validate(args)
+		if err != nil {
+			return err
+		}
+		schema, err := loadMappingFile(o.mappingPath)
+		if err != nil {
+			return err
+		}
+		if o.names.enabled() {
+			var renames []fieldRename
+			if schema, renames, err = o.names.apply(schema); err != nil {
+				return err
+			}
+			recordRenames(report, renames)
+		}
+		var renames []fieldRename
+		fields, err := o.resolveNames(d, schema.Fields(), "", "", &renames, report)
+		if err != nil {
+			return err
+		}
+		recordRenames(report, renames)
+
+		ddl, err := createTableDDL(d, o.table, fields)
+		if err != nil {
+			return err
+		}
+		fmt.Print(ddl)
+		return nil
+	}
+}
+
+func (o *ddlOptions) validate(args []string) (*sqlDialect, error) {
+	if len(args) > 0 {
+		return nil, configErrorf("ddl: unexpected arguments %v", args)
+	}
+	if o.mappingPath == "" {
+		return nil, configErrorf("ddl: --mapping is required")
+	}
+	d, ok := sqlDialects[o.dialect]
+	if !ok {
+		return nil, configErrorf("ddl: unknown --dialect %q (want bigquery, snowflake or trino)", o.dialect)
+	}
+	switch o.reserved {
+	case reservedQuote, reservedSuffix, reservedPrefix:
+	default:
+		return nil, configErrorf("ddl: unknown --reserved-words %q (want quote, suffix or prefix)", o.reserved)
+	}
+	switch o.caseCollision {
+	case collisionSuffix, collisionError:
+	default:
+		return nil, configErrorf("ddl: unknown --case-collision %q (want suffix or error)", o.caseCollision)
+	}
+	return d, o.names.validate()
+}
+
+// resolveNames 함수는 한 단계의 형제 필드에서 예약어와 대소문자 충돌을 찾아 이름을
+// 바꿉니다. 충돌하는 필드 중 앞의 것이 이름을 유지하고, 바꾼 이름은 renames에 모읍니다.
+func (o *ddlOptions) resolveNames(d *sqlDialect, fields []arrow.Field, prefix, newPrefix string, renames *[]fieldRename, report *runReport) ([]arrow.Field, error) {
+	candidates := make([]string, len(fields))
+	first := make(map[string]int, len(fields))
+	for i, f := range fields {
+		name := f.Name
+		if d.reserved[strings.ToUpper(name)] {
+			switch o.reserved {
+			case reservedSuffix:
+				name += "_"
+			case reservedPrefix:
+				name = "_" + name
+			default:
+				report.warnf("%s%s is a reserved word in %s; the column name is quoted", prefix, f.Name, d.name)
+			}
+		}
+		candidates[i] = name
+		if _, ok := first[strings.ToLower(name)]; !ok {
+			first[strings.ToLower(name)] = i
+		}
+	}
+
+	out := make([]arrow.Field, len(fields))
+	for i, f := range fields {
+		name := candidates[i]
+		if j := first[strings.ToLower(name)]; j != i {
+			if o.caseCollision == collisionError {
+				return nil, schemaErrorf("fields %s%s and %s%s differ only in case, which %s does not distinguish", prefix, fields[j].Name, prefix, f.Name, d.name)
+			}
+			base := name
+			for n := 2; ; n++ {
+				name = base + "_" + strconv.Itoa(n)
+				if _, ok := first[strings.ToLower(name)]; !ok {
+					break
+				}
+			}
+			first[strings.ToLower(name)] = i
+		}
+		if name != f.Name {
+			*renames = append(*renames, fieldRename{From: prefix + f.Name, To: newPrefix + name})
+		}
+
+		t := f.Type
+		if d.namedStructs {
+			var err error
+			if t, err = o.resolveTypeNames(d, t, prefix+f.Name+".", newPrefix+name+".", renames, report); err != nil {
+				return nil, err
+			}
+		}
+		out[i] = arrow.Field{Name: name, Type: t, Nullable: f.Nullable, Metadata: f.Metadata}
+	}
+	return out, nil
+}
+
+func (o *ddlOptions) resolveTypeNames(d *sqlDialect, t arrow.DataType, prefix, newPrefix string, renames *[]fieldRename, report *runReport) (arrow.DataType, error) {
+	switch t := t.(type) {
+	case *arrow.StructType:
+		fields, err := o.resolveNames(d, t.Fields(), prefix, newPrefix, renames, report)
+		if err != nil {
+			return nil, err
+		}
+		return arrow.StructOf(fields...), nil
+	case *arrow.ListType:
+		elem, err := o.resolveTypeNames(d, t.Elem(), prefix, newPrefix, renames, report)
+		if err != nil {
+			return nil, err
+		}
+		return arrow.ListOf(elem), nil
+	}
+	return t, nil
+}
+
+// createTableDDL 함수는 필드 목록으로 CREATE TABLE 문을 만듭니다.
+func createTableDDL(d *sqlDialect, table string, fields []arrow.Field) (string, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "CREATE TABLE %s (\n", d.ident(table))
+	for i, f := range fields {
+		t, err := d.columnType(d, f.Type)
+		if err != nil {
+			return "", schemaErrorf("column %s: %w", f.Name, err)
+		}
+		sep := ","
+		if i == len(fields)-1 {
+			sep = ""
+		}
+		fmt.Fprintf(&sb, "  %s %s%s\n", d.ident(f.Name), t, sep)
+	}
+	sb.WriteString(");\n")
+	return sb.String(), nil
+}
+
+func bigQueryType(d *sqlDialect, t arrow.DataType) (string, error) {
+	switch t := t.(type) {
+	case *arrow.StringType:
+		return "STRING", nil
+	case *arrow.BinaryType, *arrow.FixedSizeBinaryType:
+		return "BYTES", nil
+	case *arrow.Int8Type, *arrow.Int16Type, *arrow.Int32Type, *arrow.Int64Type:
+		return "INT64", nil
+	case *arrow.Uint64Type:
+		// INT64에 담기지 않는 unsigned_long은 소수 자릿수 없는 NUMERIC으로 씁니다.
+		return "NUMERIC(20, 0)", nil
+	case *arrow.Float16Type, *arrow.Float32Type, *arrow.Float64Type:
+		return "FLOAT64", nil
+	case *arrow.Decimal128Type:
+		// NUMERIC는 소수 자릿수 9까지만 받습니다.
+		if t.Scale > 9 {
+			return fmt.Sprintf("BIGNUMERIC(%d, %d)", t.Precision, t.Scale), nil
+		}
+		return fmt.Sprintf("NUMERIC(%d, %d)", t.Precision, t.Scale), nil
+	case *arrow.BooleanType:
+		return "BOOL", nil
+	case *arrow.TimestampType:
+		return "TIMESTAMP", nil
+	case *arrow.StructType:
+		if len(t.Fields()) == 0 {
+			return "JSON", nil
+		}
+		return structDDL(d, "STRUCT<", ">", t)
+	case *arrow.MapType:
+		// BigQuery에는 맵 타입이 없어 Parquet 맵을 키와 값 구조체의 배열로 읽습니다.
+		entry, err := structDDL(d, "STRUCT<", ">", t.ValueType())
+		if err != nil {
+			return "", err
+		}
+		return "ARRAY<" + entry + ">", nil
+	case *arrow.ListType:
+		return bigQueryArray(d, t.Elem())
+	case *arrow.FixedSizeListType:
+		return bigQueryArray(d, t.Elem())
+	}
+	return "", fmt.Errorf("no %s type for %s", d.name, t)
+}
+
+func trinoType(d *sqlDialect, t arrow.DataType) (string, error) {
+	switch t := t.(type) {
+	case *arrow.StringType:
+		return "VARCHAR", nil
+	case *arrow.BinaryType, *arrow.FixedSizeBinaryType:
+		return "VARBINARY", nil
+	case *arrow.Int8Type:
+		return "TINYINT", nil
+	case *arrow.Int16Type:
+		return "SMALLINT", nil
+	case *arrow.Int32Type:
+		return "INTEGER", nil
+	case *arrow.Int64Type:
+		return "BIGINT", nil
+	case *arrow.Uint64Type:
+		return "DECIMAL(20, 0)", nil
+	case *arrow.Float16Type, *arrow.Float32Type:
+		return "REAL", nil
+	case *arrow.Float64Type:
+		return "DOUBLE", nil
+	case *arrow.Decimal128Type:
+		return fmt.Sprintf("DECIMAL(%d, %d)", t.Precision, t.Scale), nil
+	case *arrow.BooleanType:
+		return "BOOLEAN", nil
+	case *arrow.TimestampType:
+		precision := map[arrow.TimeUnit]int{arrow.Second: 0, arrow.Millisecond: 3, arrow.Microsecond: 6, arrow.Nanosecond: 9}[t.Unit]
+		if t.TimeZone != "" {
+			return fmt.Sprintf("TIMESTAMP(%d) WITH TIME ZONE", precision), nil
+		}
+		return fmt.Sprintf("TIMESTAMP(%d)", precision), nil
+	case *arrow.StructType:
+		if len(t.Fields()) == 0 {
+			return "JSON", nil
+		}
+		return structDDL(d, "ROW(", ")", t)
+	case *arrow.MapType:
+		kt, err := d.columnType(d, t.KeyType())
+		if err != nil {
+			return "", err
+		}
+		it, err := d.columnType(d, t.ItemType())
+		if err != nil {
+			return "", err
+		}
+		return "MAP(" + kt + ", " + it + ")", nil
+	case *arrow.ListType:
+		return trinoArray(d, t.Elem())
+	case *arrow.FixedSizeListType:
+		return trinoArray(d, t.Elem())
+	}
+	return "", fmt.Errorf("no %s type for %s", d.name, t)
+}
+
+func snowflakeType(d *sqlDialect, t arrow.DataType) (string, error) {
+	switch t := t.(type) {
+	case *arrow.StringType:
+		return "VARCHAR", nil
+	case *arrow.BinaryType, *arrow.FixedSizeBinaryType:
+		return "BINARY", nil
+	case *arrow.Int8Type, *arrow.Int16Type, *arrow.Int32Type:
+		return "INTEGER", nil
+	case *arrow.Int64Type:
+		return "BIGINT", nil
+	case *arrow.Uint64Type:
+		return "NUMBER(20, 0)", nil
+	case *arrow.Float16Type, *arrow.Float32Type, *arrow.Float64Type:
+		return "FLOAT", nil
+	case *arrow.Decimal128Type:
+		return fmt.Sprintf("NUMBER(%d, %d)", t.Precision, t.Scale), nil
+	case *arrow.BooleanType:
+		return "BOOLEAN", nil
+	case *arrow.TimestampType:
+		if t.TimeZone != "" {
+			return "TIMESTAMP_TZ", nil
+		}
+		return "TIMESTAMP_NTZ", nil
+	case *arrow.StructType, *arrow.MapType:
+		return "OBJECT", nil
+	case *arrow.ListType, *arrow.FixedSizeListType:
+		return "ARRAY", nil
+	}
+	return "", fmt.Errorf("no %s type for %s", d.name, t)
+}
+
+func structDDL(d *sqlDialect, open, close string, t *arrow.StructType) (string, error) {
+	parts := make([]string, len(t.Fields()))
+	for i, f := range t.Fields() {
+		ft, err := d.columnType(d, f.Type)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = d.ident(f.Name) + " " + ft
+	}
+	return open + strings.Join(parts, ", ") + close, nil
+}
+
+// bigQueryArray 함수는 BigQuery 배열 타입을 만듭니다. BigQuery는 배열의 배열을 허용하지 않습니다.
+func bigQueryArray(d *sqlDialect, elem arrow.DataType) (string, error) {
+	if elem.ID() == arrow.LIST || elem.ID() == arrow.FIXED_SIZE_LIST {
+		return "", fmt.Errorf("%s does not support arrays of arrays", d.name)
+	}
+	et, err := d.columnType(d, elem)
+	if err != nil {
+		return "", err
+	}
+	return "ARRAY<" + et + ">", nil
+}
+
+func trinoArray(d *sqlDialect, elem arrow.DataType) (string, error) {
+	et, err := d.columnType(d, elem)
+	if err != nil {
+		return "", err
+	}
+	return "ARRAY(" + et + ")", nil
+}
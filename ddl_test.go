@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestDDLRenamesReservedWordsAndCaseCollisions(t *testing.T) {
+	schema, err := schemaFromMapping([]byte(`{"properties": {
+		"Name":   {"type": "keyword"},
+		"name":   {"type": "keyword"},
+		"select": {"type": "long"},
+		"user":   {"properties": {"order": {"type": "integer"}}}
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := ddlOptions{reserved: reservedSuffix, caseCollision: collisionSuffix}
+	d := sqlDialects["bigquery"]
+	var renames []fieldRename
+	fields, err := o.resolveNames(d, schema.Fields(), "", "", &renames, newRunReport("ddl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ddl, err := createTableDDL(d, "docs", fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "CREATE TABLE docs (\n" +
+		"  Name STRING,\n" +
+		"  name_2 STRING,\n" +
+		"  select_ INT64,\n" +
+		"  user STRUCT<order_ INT64>\n" +
+		");\n"
+	if ddl != want {
+		t.Errorf("ddl =\n%s\nwant\n%s", ddl, want)
+	}
+	if len(renames) != 3 {
+		t.Errorf("renames = %v, want name, select and user.order", renames)
+	}
+
+	o.caseCollision = collisionError
+	if _, err := o.resolveNames(d, schema.Fields(), "", "", &renames, newRunReport("ddl")); kindOf(err) != kindSchema {
+		t.Errorf("case collision with error strategy: err = %v, want schema error", err)
+	}
+}
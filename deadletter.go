@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+)
+
+// maxDeadLetterLine은 dead-letter 파일 한 줄(문서 하나)의 최대 크기입니다.
+const maxDeadLetterLine = 64 << 20
+
+// deadLetter는 Elasticsearch가 거부한 문서 한 건입니다. 원본 문서와 거부 사유를 함께
+// 남겨 두어, 원인을 고친 뒤 --retry-dead-letters로 다시 보낼 수 있습니다.
+type deadLetter struct {
+	Index    string          `json:"_index"`
+	ID       string          `json:"_id,omitempty"`
+	Status   int             `json:"status"`
+	Category string          `json:"category"`
+	Reason   string          `json:"reason"`
+	Error    *bulkItemError  `json:"error"`
+	Document json.RawMessage `json:"document"`
+	// Op는 거부된 bulk 동작(index, update, delete 등)입니다. delete는 문서가 null이므로
+	// --retry-dead-letters가 다시 지우기로 보냅니다.
+	Op string `json:"op,omitempty"`
+}
+
+// deadLetterWriter는 거부된 문서를 NDJSON 파일에 한 줄씩 기록합니다.
+type deadLetterWriter struct {
+	path  string
+	f     *os.File
+	w     *bufio.Writer
+	count int64
+}
+
+func createDeadLetterFile(path string) (*deadLetterWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, configErrorf("creating dead-letter file: %w", err)
+	}
+	return &deadLetterWriter{path: path, f: f, w: bufio.NewWriter(f)}, nil
+}
+
+func (d *deadLetterWriter) write(dl deadLetter) error {
+	data, err := json.Marshal(dl)
+	if err != nil {
+		return err
+	}
+	d.w.Write(data)
+	if err := d.w.WriteByte('\n'); err != nil {
+		return err
+	}
+	d.count++
+	return nil
+}
+
+func (d *deadLetterWriter) Close() error {
+	if err := d.w.Flush(); err != nil {
+		d.f.Close()
+		return err
+	}
+	return d.f.Close()
+}
+
+// readDeadLetters 함수는 dead-letter 파일을 읽어 항목마다 fn을 호출합니다.
+func readDeadLetters(path string, fn func(deadLetter) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return configErrorf("opening dead-letter file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 1<<20), maxDeadLetterLine)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var dl deadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &dl); err != nil {
+			return dataErrorf("%s:%d: %w", path, line, err)
+		}
+		if err := fn(dl); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return dataErrorf("reading %s: %w", path, err)
+	}
+	return nil
+}
+
+// decodeDocument 함수는 JSON 문서를 숫자 정밀도를 유지한 채 맵으로 디코딩합니다.
+func decodeDocument(data []byte) (map[string]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc map[string]interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// decodeJSONValue 함수는 임의의 JSON 값을 decodeDocument처럼 숫자 정밀도를 유지해 읽습니다.
+func decodeJSONValue(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
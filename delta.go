@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	deltaByHash  = "hash"
+	deltaBySeqNo = "seq-no"
+)
+
+// deltaOptions는 지난 실행의 manifest와 비교해 바뀐 문서만 내보내는 설정입니다. manifest는
+// 인덱스마다 <dir>/<index>.manifest에 문서마다 한 줄로 _source의 해시와 _seq_no,
+// _primary_term을 적은 파일입니다.
+type deltaOptions struct {
+	dir string
+	by  string
+}
+
+func (o *deltaOptions) bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.dir, "delta", "", "directory keeping each index's <index>.manifest of exported documents; only documents that are new or changed since the previous run's manifest are written, and the manifest is replaced when the index finishes")
+	fs.StringVar(&o.by, "delta-by", deltaByHash, "how --delta tells a changed document: hash (the SHA-256 of its _source changed) or seq-no (its _seq_no or _primary_term changed, also when an update left the content as it was)")
+}
+
+func (o *deltaOptions) validate() error {
+	switch o.by {
+	case deltaByHash, deltaBySeqNo:
+	default:
+		return configErrorf("unknown --delta-by %q (want hash or seq-no)", o.by)
+	}
+	if o.dir != "" {
+		if err := os.MkdirAll(o.dir, 0o755); err != nil {
+			return configErrorf("creating --delta: %w", err)
+		}
+	}
+	return nil
+}
+
+func (o *deltaOptions) enabled() bool {
+	return o.dir != ""
+}
+
+func (o *deltaOptions) manifestPath(index string) string {
+	return filepath.Join(o.dir, index+".manifest")
+}
+
+// manifestEntry는 manifest에서 문서 하나의 버전입니다. seqNo와 primaryTerm은 모르면 -1입니다.
+type manifestEntry struct {
+	hash        string
+	seqNo       int64
+	primaryTerm int64
+}
+
+// sourceHash 함수는 _source의 SHA-256 앞 16바이트를 16진수로 반환합니다. Elasticsearch는
+// 받은 _source를 그대로 저장하므로, 같은 내용을 다시 색인해도 클라이언트가 키 순서나 공백을
+// 다르게 보내면 바뀐 문서로 봅니다.
+func sourceHash(source []byte) string {
+	sum := sha256.Sum256(source)
+	return hex.EncodeToString(sum[:16])
+}
+
+// readManifest 함수는 manifest 파일을 읽습니다. 한 줄은 "해시\t_seq_no\t_primary_term\t_id"
+// 이고, _id를 마지막에 두어 탭이 든 _id도 읽습니다. 파일이 없으면(첫 실행) nil을 반환합니다.
+func readManifest(path string) (map[string]manifestEntry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, configErrorf("reading delta manifest: %w", err)
+	}
+	defer f.Close()
+	entries := make(map[string]manifestEntry)
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		parts := strings.SplitN(sc.Text(), "\t", 4)
+		if len(parts) != 4 {
+			return nil, configErrorf("delta manifest %s:%d: want hash, _seq_no, _primary_term and _id separated by tabs", path, line)
+		}
+		seqNo, err1 := strconv.ParseInt(parts[1], 10, 64)
+		term, err2 := strconv.ParseInt(parts[2], 10, 64)
+		if err1 != nil || err2 != nil {
+			return nil, configErrorf("delta manifest %s:%d: invalid _seq_no or _primary_term", path, line)
+		}
+		entries[parts[3]] = manifestEntry{hash: parts[0], seqNo: seqNo, primaryTerm: term}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, configErrorf("reading delta manifest %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// writeManifest 함수는 entries를 _id 순으로 path에 씁니다. 중간에 실패해도 이전 manifest가
+// 남도록 임시 파일에 쓴 뒤 이름을 바꿉니다.
+func writeManifest(path string, entries map[string]manifestEntry) error {
+	ids := make([]string, 0, len(entries))
+	for id := range entries {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return configErrorf("writing delta manifest: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	for _, id := range ids {
+		e := entries[id]
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", e.hash, e.seqNo, e.primaryTerm, id)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return configErrorf("writing delta manifest %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return configErrorf("writing delta manifest %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return configErrorf("writing delta manifest %s: %w", path, err)
+	}
+	return nil
+}
+
+// deltaFilter는 인덱스 하나의 export에서 바뀐 문서를 고르고 새 manifest를 모읍니다. 동시에
+// 도는 scroll이 같은 필터를 쓰므로 mu로 보호합니다. 새 manifest는 읽은 문서를 모두 담으므로
+// 문서 수에 비례하는 메모리를 씁니다.
+type deltaFilter struct {
+	by        string
+	previous  map[string]manifestEntry
+	mu        sync.Mutex
+	current   map[string]manifestEntry
+	unchanged int64
+}
+
+func newDeltaFilter(by string, previous map[string]manifestEntry) *deltaFilter {
+	return &deltaFilter{by: by, previous: previous, current: make(map[string]manifestEntry)}
+}
+
+// changed 함수는 hits를 모두 새 manifest에 넣고, 지난 manifest에 없거나 바뀐 문서만
+// 반환합니다.
+func (f *deltaFilter) changed(hits []searchHit) []searchHit {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []searchHit
+	for _, hit := range hits {
+		e := manifestEntry{hash: sourceHash(hit.Source), seqNo: -1, primaryTerm: -1}
+		if hit.SeqNo != nil && hit.PrimaryTerm != nil {
+			e.seqNo, e.primaryTerm = *hit.SeqNo, *hit.PrimaryTerm
+		}
+		f.current[hit.ID] = e
+		if old, ok := f.previous[hit.ID]; ok && f.same(old, e) {
+			f.unchanged++
+			continue
+		}
+		out = append(out, hit)
+	}
+	return out
+}
+
+// same 함수는 --delta-by에 따라 두 버전이 같은 문서인지 판단합니다. 어느 한쪽의 _seq_no를
+// 모르면 해시로 비교합니다.
+func (f *deltaFilter) same(old, cur manifestEntry) bool {
+	if f.by == deltaBySeqNo && old.seqNo >= 0 && cur.seqNo >= 0 {
+		return old.seqNo == cur.seqNo && old.primaryTerm == cur.primaryTerm
+	}
+	return old.hash == cur.hash
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestManifestRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.manifest")
+	if m, err := readManifest(path); err != nil || m != nil {
+		t.Fatalf("missing manifest = %v, %v; want nil for the first run", m, err)
+	}
+	entries := map[string]manifestEntry{
+		"a":         {hash: sourceHash([]byte(`{"n": 1}`)), seqNo: 7, primaryTerm: 1},
+		"with\ttab": {hash: sourceHash([]byte(`{}`)), seqNo: -1, primaryTerm: -1},
+	}
+	if err := writeManifest(path, entries); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readManifest(path)
+	if err != nil || !reflect.DeepEqual(got, entries) {
+		t.Errorf("round trip = %v, %v; want %v", got, err, entries)
+	}
+
+	os.WriteFile(path, []byte("abc\t1\n"), 0o644)
+	if _, err := readManifest(path); exitCodeFor(err) != exitConfigError || !strings.Contains(err.Error(), "logs.manifest:1") {
+		t.Errorf("broken manifest = %v", err)
+	}
+}
+
+func TestDeltaFilter(t *testing.T) {
+	hit := func(id, source string, seqNo int64) searchHit {
+		term := int64(1)
+		return searchHit{ID: id, Source: json.RawMessage(source), SeqNo: &seqNo, PrimaryTerm: &term}
+	}
+	first := newDeltaFilter(deltaByHash, nil)
+	page := []searchHit{hit("a", `{"n": 1}`, 1), hit("b", `{"n": 2}`, 2), hit("c", `{"n": 3}`, 3)}
+	if got := first.changed(page); len(got) != 3 {
+		t.Fatalf("first run kept %d of 3 documents", len(got))
+	}
+
+	// b는 같은 내용으로 다시 색인했고, c는 내용이 바뀌었고, d는 새 문서입니다.
+	page = []searchHit{hit("a", `{"n": 1}`, 1), hit("b", `{"n": 2}`, 9), hit("c", `{"n": 30}`, 10), hit("d", `{"n": 4}`, 11)}
+	ids := func(hits []searchHit) []string {
+		var out []string
+		for _, h := range hits {
+			out = append(out, h.ID)
+		}
+		return out
+	}
+	byHash := newDeltaFilter(deltaByHash, first.current)
+	if got := ids(byHash.changed(page)); !reflect.DeepEqual(got, []string{"c", "d"}) || byHash.unchanged != 2 {
+		t.Errorf("--delta-by hash kept %v, %d unchanged", got, byHash.unchanged)
+	}
+	bySeqNo := newDeltaFilter(deltaBySeqNo, first.current)
+	if got := ids(bySeqNo.changed(page)); !reflect.DeepEqual(got, []string{"b", "c", "d"}) || bySeqNo.unchanged != 1 {
+		t.Errorf("--delta-by seq-no kept %v, %d unchanged", got, bySeqNo.unchanged)
+	}
+	// 새 manifest는 건너뛴 문서까지 모든 문서의 새 버전을 담습니다.
+	if len(bySeqNo.current) != 4 || bySeqNo.current["b"].seqNo != 9 {
+		t.Errorf("new manifest = %v", bySeqNo.current)
+	}
+}
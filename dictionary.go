@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"sort"
+	"strings"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+// dictionaryStringType은 --dictionary-fields 컬럼의 Arrow 타입입니다. Arrow Go v10에는
+// run-end encoded 배열이 없으므로, 파일 안에서 거의 바뀌지 않는 값은 사전 인코딩으로
+// 담습니다. 배치마다 서로 다른 문자열 하나씩과 행마다 int32 인덱스만 남습니다.
+var dictionaryStringType = &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int32, ValueType: arrow.BinaryTypes.String}
+
+// dictionaryOptions는 문자열 컬럼을 사전 인코딩된 Arrow 컬럼으로 만드는 설정입니다.
+// 환경 태그나 constant_keyword처럼 값의 종류가 적은 필드에서 레코드를 만드는 동안의
+// 메모리를 줄입니다. Parquet 파일의 사전 인코딩(--dictionary)과는 별개입니다.
+type dictionaryOptions struct {
+	fields    stringListFlag
+	constants bool
+}
+
+func (o *dictionaryOptions) bind(fs *flag.FlagSet) {
+	fs.Var(&o.fields, "dictionary-fields", "build these string fields as dictionary-encoded Arrow columns to save memory on low-cardinality values; dotted paths select nested fields (comma-separated or repeated)")
+	fs.BoolVar(&o.constants, "dictionary-constants", false, "build every constant_keyword field as a dictionary-encoded Arrow column")
+}
+
+// paths 함수는 매핑에서 사전 인코딩할 필드의 경로를 정렬해 반환합니다.
+func (o *dictionaryOptions) paths(mapping []byte) ([]string, error) {
+	set := make(map[string]bool)
+	for _, f := range o.fields {
+		set[f] = true
+	}
+	if o.constants {
+		var m map[string]interface{}
+		if err := json.Unmarshal(mapping, &m); err != nil {
+			return nil, schemaErrorf("decoding mapping: %w", err)
+		}
+		props, _ := m["properties"].(map[string]interface{})
+		constantKeywords(props, "", set)
+	}
+	paths := make([]string, 0, len(set))
+	for p := range set {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// constantKeywords 함수는 properties 아래의 constant_keyword 필드 경로를 out에 모읍니다.
+func constantKeywords(props map[string]interface{}, prefix string, out map[string]bool) {
+	for name, v := range props {
+		field, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if sub, ok := field["properties"].(map[string]interface{}); ok {
+			constantKeywords(sub, prefix+name+".", out)
+			continue
+		}
+		if field["type"] == "constant_keyword" {
+			out[prefix+name] = true
+		}
+	}
+}
+
+// dictionaryEncode 함수는 점으로 구분된 경로의 문자열 필드(또는 문자열 리스트)를 사전
+// 인코딩 타입으로 바꾼 필드 목록을 반환합니다. 경로에 해당하는 문자열 필드가 없으면
+// false를 반환합니다.
+func dictionaryEncode(fields []arrow.Field, path []string) ([]arrow.Field, bool) {
+	out := append([]arrow.Field(nil), fields...)
+	for i, f := range out {
+		if f.Name != path[0] {
+			continue
+		}
+		t := f.Type
+		lt, isList := t.(*arrow.ListType)
+		if isList {
+			t = lt.Elem()
+		}
+		switch {
+		case len(path) == 1 && t.ID() == arrow.STRING:
+			t = dictionaryStringType
+		case len(path) == 1:
+			return nil, false
+		default:
+			st, ok := t.(*arrow.StructType)
+			if !ok {
+				return nil, false
+			}
+			children, ok := dictionaryEncode(st.Fields(), path[1:])
+			if !ok {
+				return nil, false
+			}
+			t = arrow.StructOf(children...)
+		}
+		if isList {
+			t = arrow.ListOf(t)
+		}
+		out[i].Type = t
+		return out, true
+	}
+	return nil, false
+}
+
+// dictionaryFields 함수는 paths의 필드를 사전 인코딩한 필드 목록을 반환합니다. 여러
+// 인덱스를 내보낼 때 모든 인덱스에 있지 않은 경로는 건너뜁니다.
+func dictionaryFields(fields []arrow.Field, paths []string) []arrow.Field {
+	for _, path := range paths {
+		if encoded, ok := dictionaryEncode(fields, strings.Split(path, ".")); ok {
+			fields = encoded
+		}
+	}
+	return fields
+}
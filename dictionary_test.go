@@ -0,0 +1,30 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDictionaryPaths(t *testing.T) {
+	mapping := []byte(`{"properties": {
+		"env": {"type": "constant_keyword", "value": "prod"},
+		"host": {"properties": {"region": {"type": "constant_keyword"}, "name": {"type": "keyword"}}},
+		"tag": {"type": "keyword"}
+	}}`)
+	o := dictionaryOptions{fields: stringListFlag{"tag", "env"}}
+	got, err := o.paths(mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"env", "tag"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("paths = %v, want %v", got, want)
+	}
+
+	o.constants = true
+	if got, err = o.paths(mapping); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"env", "host.region", "tag"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("paths with constants = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/apache/arrow/go/v10/arrow"
+
+	"es-schema/esschema"
+)
+
+// docHashColumn은 --doc-hash로 쓰는, 문서의 정규화된 내용의 해시 컬럼입니다.
+const docHashColumn = "_doc_hash"
+
+// documentHash 함수는 doc을 fields의 타입으로 변환한 값의 SHA-256 앞 16바이트를 16진수로
+// 반환합니다. 변환한 값을 이름 순으로 정렬한 JSON으로 해시하므로 _source의 키 순서와 공백,
+// "1"과 1처럼 같은 값으로 변환되는 표기 차이는 해시를 바꾸지 않습니다. null인 최상위 필드는
+// 빼므로 매핑에 필드가 더해져도 그 필드가 없는 문서의 해시는 그대로입니다. 변환이 실패하면
+// 빈 문자열입니다.
+func documentHash(fields []arrow.Field, doc map[string]interface{}, values []interface{}) string {
+	if _, err := esschema.ConvertDocument(fields, doc, values); err != nil {
+		return ""
+	}
+	content := make(map[string]interface{}, len(fields))
+	for i, f := range fields {
+		if values[i] != nil {
+			content[f.Name] = values[i]
+		}
+	}
+	data, err := json.Marshal(content)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// setDocHashes 함수는 hits마다 같은 자리의 docs로 documentHash를 구해 넣습니다. 디코딩하지
+// 못한 문서는 해시가 없습니다.
+func setDocHashes(fields []arrow.Field, hits []searchHit, docs []map[string]interface{}) {
+	values := make([]interface{}, len(fields))
+	for i, doc := range docs {
+		if doc != nil {
+			hits[i].docHash = documentHash(fields, doc, values)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+func TestDocumentHash(t *testing.T) {
+	fields := []arrow.Field{
+		{Name: "n", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "tags", Type: arrow.ListOf(arrow.BinaryTypes.String)},
+		{Name: "title", Type: arrow.BinaryTypes.String},
+	}
+	pool := newStringPool()
+	hash := func(source string) string {
+		doc, err := pool.decodeDocument([]byte(source))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return documentHash(fields, doc, make([]interface{}, len(fields)))
+	}
+	base := hash(`{"n": 1, "title": "a", "tags": ["x", "y"]}`)
+	if len(base) != 32 {
+		t.Fatalf("hash = %q, want 32 hex digits", base)
+	}
+	for _, same := range []string{
+		`{"tags":["x","y"],"title":"a","n":1}`,
+		`{"n": "1", "title": "a", "tags": ["x", "y"], "unmapped": true}`,
+		`{"n": 1.0, "title": "a", "tags": ["x", "y"], "missing": null}`,
+	} {
+		if got := hash(same); got != base {
+			t.Errorf("hash(%s) = %s, want %s", same, got, base)
+		}
+	}
+	for _, different := range []string{
+		`{"n": 2, "title": "a", "tags": ["x", "y"]}`,
+		`{"n": 1, "title": "a", "tags": ["y", "x"]}`,
+		`{"n": 1, "title": "a"}`,
+	} {
+		if got := hash(different); got == base {
+			t.Errorf("hash(%s) = %s, the same as the original", different, got)
+		}
+	}
+
+	hits := []searchHit{{ID: "1", Source: json.RawMessage(`{"n": 1}`)}, {ID: "2"}}
+	doc, _ := pool.decodeDocument(hits[0].Source)
+	setDocHashes(fields, hits, []map[string]interface{}{doc, nil})
+	if hits[0].docHash == "" || hits[1].docHash != "" {
+		t.Errorf("hashes = %q, %q", hits[0].docHash, hits[1].docHash)
+	}
+}
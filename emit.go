@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/apache/arrow/go/v10/arrow"
+
+	"es-schema/esschema"
+)
+
+// emitOptions는 emit 명령의 설정입니다.
+type emitOptions struct {
+	in           mappingInputOptions
+	names        nameOptions
+	templatePath string
+	builtin      string
+	dbt          dbtOptions
+	gx           gxOptions
+	out          string
+}
+
+// emitModel은 emit 템플릿이 받는 스키마 모델입니다. Fields는 Arrow 필드의 트리, Columns는
+// 잎 컬럼마다의 계보(--lineage와 같은 내용), Mapping은 감싼 것을 벗긴 매핑입니다.
+// Description은 매핑 _meta의 설명입니다(descriptionKey).
+type emitModel struct {
+	Source      string
+	Description string
+	Fields      []emitField
+	Columns     []columnLineage
+	Mapping     map[string]interface{}
+}
+
+// emitField는 emit 템플릿의 필드 하나입니다. Path는 점으로 이은 컬럼 경로이고, ESType은
+// 매핑의 원래 타입, Description은 매핑에 적힌 필드 설명입니다. Repeated는 리스트 컬럼이며,
+// 구조체(와 구조체 리스트)는 하위 필드를 Fields에 담습니다. DataType은 sqlType 함수에 넘깁니다.
+type emitField struct {
+	Name        string
+	Path        string
+	ESType      string
+	Type        string
+	Description string
+	Nullable    bool
+	Repeated    bool
+	Fields      []emitField
+	DataType    arrow.DataType
+}
+
+func setupEmit(fs *flag.FlagSet) func(ctx context.Context, report *runReport, args []string) error {
+	var o emitOptions
+	o.in.bind(fs)
+	o.names.bind(fs)
+	fs.StringVar(&o.templatePath, "template", "", "Go text/template file to render with the schema of the mapping; it gets .Source, .Description, .Fields (the field tree with .Name, .Path, .ESType, .Type, .Description, .Nullable, .Repeated and .Fields), .Columns (leaf columns with .Column, .Source, .ESType, .ArrowType and .Rule) and .Mapping, and the functions lower, upper, join, replace, quote, json, indent and sqlType")
+	fs.StringVar(&o.builtin, "builtin", "", "render a built-in artifact instead of --template: dbt (a dbt sources.yml with the columns, their types and the descriptions of the mapping's _meta and field meta) or gx (a Great Expectations suite of baseline checks from the mapping and --gx-report)")
+	o.dbt.bind(fs)
+	o.gx.bind(fs)
+	fs.StringVar(&o.out, "out", "-", "file to write, or - for standard output")
+
+	return func(ctx context.Context, report *runReport, args []string) error {
+		if len(args) > 0 {
+			return configErrorf("emit: unexpected arguments %v", args)
+		}
+		if err := o.validate(); err != nil {
+			return err
+		}
+		var tmpl *template.Template
+		if o.templatePath != "" {
+			var err error
+			if tmpl, err = parseEmitTemplate(o.templatePath); err != nil {
+				return err
+			}
+		}
+		schema, mapping, err := o.in.schema(ctx)
+		if err != nil {
+			return err
+		}
+		if o.names.enabled() {
+			var renames []fieldRename
+			if schema, renames, err = o.names.apply(schema); err != nil {
+				return err
+			}
+			recordRenames(report, renames)
+		}
+		model, err := newEmitModel(o.in.source(), schema, mapping)
+		if err != nil {
+			return err
+		}
+		// 템플릿이 중간에 실패하면 반쯤 쓴 파일을 남기지 않도록 다 만든 뒤에 씁니다.
+		var out []byte
+		if tmpl != nil {
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, model); err != nil {
+				return configErrorf("emit: %w", err)
+			}
+			out = buf.Bytes()
+		} else if o.builtin == "dbt" {
+			if out, err = dbtSources(model, &o.dbt, o.in.mapping); err != nil {
+				return err
+			}
+		} else if out, err = o.gx.suite(model, o.in.mapping); err != nil {
+			return err
+		}
+		if o.out == "-" {
+			_, err = os.Stdout.Write(out)
+			return err
+		}
+		if err := os.WriteFile(o.out, out, 0o644); err != nil {
+			return configErrorf("writing %s: %w", o.out, err)
+		}
+		return nil
+	}
+}
+
+func (o *emitOptions) validate() error {
+	switch {
+	case o.templatePath == "" && o.builtin == "":
+		return configErrorf("emit: --template or --builtin is required")
+	case o.templatePath != "" && o.builtin != "":
+		return configErrorf("emit: give either --template or --builtin, not both")
+	case o.builtin != "" && o.builtin != "dbt" && o.builtin != "gx":
+		return configErrorf("emit: unknown --builtin %q (want dbt or gx)", o.builtin)
+	}
+	if err := o.dbt.validate(); err != nil {
+		return err
+	}
+	if err := o.gx.validate(); err != nil {
+		return err
+	}
+	return o.names.validate()
+}
+
+// parseEmitTemplate 함수는 path의 템플릿을 emit 함수와 함께 읽습니다. 없는 맵 키는 빈 값
+// 대신 오류가 되어 템플릿의 오타가 드러납니다.
+func parseEmitTemplate(path string) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, configErrorf("reading template: %w", err)
+	}
+	tmpl, err := template.New(filepath.Base(path)).Funcs(emitFuncs).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, configErrorf("parsing template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// emitFuncs는 emit 템플릿에서 쓰는 함수입니다.
+var emitFuncs = template.FuncMap{
+	"lower":   strings.ToLower,
+	"upper":   strings.ToUpper,
+	"join":    func(sep string, elems []string) string { return strings.Join(elems, sep) },
+	"replace": func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"quote":   strconv.Quote,
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	// indent는 s의 줄마다 앞에 공백 n개를 붙입니다. YAML 안에 여러 줄 값을 넣을 때 씁니다.
+	"indent": func(n int, s string) string {
+		pad := strings.Repeat(" ", n)
+		return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+	},
+	// sqlType은 ddl 명령의 타입 대응으로 dialect(bigquery, snowflake 또는 trino)의 컬럼 타입을 반환합니다.
+	"sqlType": func(dialect string, dt arrow.DataType) (string, error) {
+		d, ok := sqlDialects[dialect]
+		if !ok {
+			return "", fmt.Errorf("sqlType: unknown dialect %q (want bigquery, snowflake or trino)", dialect)
+		}
+		return d.columnType(d, dt)
+	},
+}
+
+// newEmitModel 함수는 schema와 매핑 JSON으로 템플릿 모델을 만듭니다.
+func newEmitModel(source string, schema *arrow.Schema, mapping []byte) (*emitModel, error) {
+	m := &emitModel{Source: source}
+	var err error
+	if m.Mapping, err = esschema.MappingObject(mapping); err != nil {
+		return nil, schemaErrorf("decoding mapping: %w", err)
+	}
+	unwrapped, err := json.Marshal(m.Mapping)
+	if err != nil {
+		return nil, schemaErrorf("encoding mapping: %w", err)
+	}
+	if m.Columns, err = schemaLineage(schema, unwrapped, nil); err != nil {
+		return nil, err
+	}
+	props, _ := m.Mapping["properties"].(map[string]interface{})
+	types := make(map[string]string)
+	mappingTypes(props, "", types)
+	var descriptions map[string]string
+	m.Description, descriptions = mappingDescriptions(m.Mapping)
+	m.Fields = emitFields(schema.Fields(), "", "", types, descriptions)
+	return m, nil
+}
+
+func emitFields(fields []arrow.Field, path, source string, types, descriptions map[string]string) []emitField {
+	out := make([]emitField, len(fields))
+	for i, f := range fields {
+		e := emitField{
+			Name:        f.Name,
+			Path:        path + f.Name,
+			ESType:      types[source+originalName(f)],
+			Type:        fmt.Sprint(f.Type),
+			Description: descriptions[source+originalName(f)],
+			Nullable:    f.Nullable,
+			DataType:    f.Type,
+		}
+		_, e.Repeated = f.Type.(*arrow.ListType)
+		if st, ok := structElem(f.Type); ok {
+			e.Fields = emitFields(st.Fields(), e.Path+".", source+originalName(f)+".", types, descriptions)
+		}
+		out[i] = e
+	}
+	return out
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEmitTemplate(t *testing.T) {
+	mapping := []byte(`{"properties": {
+		"title":    {"type": "text"},
+		"price":    {"type": "scaled_float", "scaling_factor": 100},
+		"comments": {"type": "nested", "properties": {"author": {"type": "keyword"}}}
+	}}`)
+	schema, err := schemaFromMapping(mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model, err := newEmitModel("docs.json", schema, mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "sources.tmpl")
+	os.WriteFile(path, []byte(`# from {{.Source}}
+{{range .Fields}}- {{.Path}} {{.ESType}} {{sqlType "bigquery" .DataType}}{{if .Repeated}} repeated{{end}}
+{{range .Fields}}  - {{.Path}} {{.ESType}}
+{{end}}{{end}}{{range .Columns}}{{.Column}}={{.Rule}} {{end}}
+`), 0o644)
+	tmpl, err := parseEmitTemplate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, model); err != nil {
+		t.Fatal(err)
+	}
+	want := `# from docs.json
+- comments nested ARRAY<STRUCT<author STRING>> repeated
+  - comments.author keyword
+- price scaled_float NUMERIC(19, 2)
+- title text STRING
+comments.author=copy price=copy title=copy 
+`
+	if buf.String() != want {
+		t.Errorf("rendered\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestEmitFuncs(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "funcs.tmpl")
+	os.WriteFile(path, []byte(`{{indent 2 .Text}}|{{join ", " .List}}|{{replace "." "_" .Path | upper}}|{{json .List}}`), 0o644)
+	tmpl, err := parseEmitTemplate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	data := map[string]interface{}{"Text": "a\nb", "List": []string{"x", "y"}, "Path": "user.name"}
+	if err := tmpl.Execute(&buf, data); err != nil {
+		t.Fatal(err)
+	}
+	if want := "  a\n  b|x, y|USER_NAME|[\"x\",\"y\"]"; buf.String() != want {
+		t.Errorf("rendered %q, want %q", buf.String(), want)
+	}
+
+	os.WriteFile(path, []byte(`{{.Missing}}`), 0o644)
+	if tmpl, err = parseEmitTemplate(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := tmpl.Execute(&buf, data); err == nil || !strings.Contains(err.Error(), "Missing") {
+		t.Errorf("missing key = %v, want an error", err)
+	}
+	os.WriteFile(path, []byte(`{{if}}`), 0o644)
+	if _, err := parseEmitTemplate(path); exitCodeFor(err) != exitConfigError {
+		t.Errorf("bad template = %v, want a config error", err)
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/parquet"
+	"github.com/apache/arrow/go/v10/parquet/schema"
+)
+
+// sensitivityKey는 매핑의 _meta와 필드의 meta에서 민감도 등급을 담는 키입니다.
+const sensitivityKey = "sensitivity"
+
+// footerKeyID는 footer 키의 키 메타데이터입니다. 컬럼 키의 키 메타데이터는 등급 이름이므로
+// 읽는 쪽은 등급 이름으로 키를 찾습니다.
+const footerKeyID = "footer"
+
+// encryptionConfig는 설정 파일의 encryption 절입니다. Keys는 민감도 등급별 AES 키(16, 24
+// 또는 32바이트의 base64)이고, Sensitivity는 매핑에 더해 필드 경로에 붙이는 등급입니다. 키
+// 값에는 ${env:...}, ${file:...}, ${vault:...} 참조를 씁니다.
+//
+//	encryption:
+//	  footer-key: ${vault:secret/data/es-schema#footer_key}
+//	  keys:
+//	    pii: ${vault:secret/data/es-schema#pii_key}
+//	    finance: ${env:ES_SCHEMA_FINANCE_KEY}
+//	  sensitivity:
+//	    user.email: pii
+//	    order.total: finance
+type encryptionConfig struct {
+	FooterKey   string            `json:"footer-key"`
+	Keys        map[string]string `json:"keys"`
+	Sensitivity map[string]string `json:"sensitivity,omitempty"`
+}
+
+// encryptionOptions는 --encrypt-columns 플래그입니다.
+type encryptionOptions struct {
+	enabled bool
+}
+
+func (o *encryptionOptions) bind(fs *flag.FlagSet) {
+	fs.BoolVar(&o.enabled, "encrypt-columns", false, "encrypt every column whose field is tagged with a sensitivity level (\"sensitivity\" in the mapping's _meta or a field's meta, or the sensitivity of the encryption section of the config file) with that level's key from the encryption section, using Parquet modular encryption; the footer stays plain text so readers without a key still read the untagged columns")
+}
+
+// load 함수는 --encrypt-columns이면 설정 파일의 encryption 절을 읽어 키를 풀고, pq로 쓰는
+// 파일이 태그된 컬럼을 암호화하게 합니다.
+func (o *encryptionOptions) load(ctx context.Context, pq *parquetOptions) error {
+	if !o.enabled {
+		return nil
+	}
+	path, err := profilePath()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return configErrorf("--encrypt-columns: reading encryption keys: %w", err)
+	}
+	var cfg profileConfig
+	if err := unmarshalYAML(data, &cfg); err != nil {
+		return configErrorf("parsing %s: %w", path, err)
+	}
+	if cfg.Encryption == nil {
+		return configErrorf("--encrypt-columns: %s has no encryption section", path)
+	}
+	enc, err := cfg.Encryption.resolve(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: encryption: %w", path, err)
+	}
+	pq.encryption = enc
+	return nil
+}
+
+// resolve 함수는 키의 비밀 참조를 풀고 키 길이를 검사합니다.
+func (c *encryptionConfig) resolve(ctx context.Context) (*columnEncryption, error) {
+	var secrets secretResolver
+	decode := func(name, value string) (string, error) {
+		value, err := secrets.expand(ctx, value)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", name, err)
+		}
+		key, err := base64.StdEncoding.DecodeString(value)
+		if err != nil || (len(key) != 16 && len(key) != 24 && len(key) != 32) {
+			return "", configErrorf("%s must be 16, 24 or 32 random bytes in base64 (openssl rand -base64 32)", name)
+		}
+		return string(key), nil
+	}
+	if c.FooterKey == "" {
+		return nil, configErrorf("footer-key is required; it signs the plain text footer")
+	}
+	e := &columnEncryption{keys: make(map[string]string, len(c.Keys)), sensitivity: c.Sensitivity}
+	var err error
+	if e.footerKey, err = decode("footer-key", c.FooterKey); err != nil {
+		return nil, err
+	}
+	for level, value := range c.Keys {
+		if level == footerKeyID {
+			return nil, configErrorf("keys: %q is reserved for the footer key", level)
+		}
+		if e.keys[level], err = decode("keys."+level, value); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// columnEncryption은 --encrypt-columns의 풀린 키와 설정의 민감도 태그입니다. 파일마다 그 파일의
+// 매핑과 컬럼 계보로 어느 컬럼을 어느 키로 암호화할지 정합니다. 각 컬럼 키의 키 메타데이터는
+// 등급 이름이므로, 일부 등급의 키만 가진 사람은 그 등급과 태그 없는 컬럼만 읽습니다.
+type columnEncryption struct {
+	footerKey   string
+	keys        map[string]string
+	sensitivity map[string]string
+}
+
+// properties 함수는 schema를 pqSchema로 쓸 때의 암호화 설정을 반환합니다. 태그된 컬럼이
+// 없으면 nil입니다. Parquet는 암호화할 컬럼을 지정하지 않으면 모든 컬럼을 footer 키로
+// 암호화하기 때문입니다.
+func (e *columnEncryption) properties(sc *arrow.Schema, pqSchema *schema.Schema) (*parquet.FileEncryptionProperties, error) {
+	levels, err := e.columnLevels(sc)
+	if err != nil {
+		return nil, err
+	}
+	cols := make(parquet.ColumnPathToEncryptionPropsMap)
+	for i := 0; i < pqSchema.NumColumns(); i++ {
+		col := pqSchema.Column(i)
+		level, ok := levels[parquetLeafColumn(col.ColumnPath())]
+		if !ok {
+			continue
+		}
+		cols[col.Path()] = parquet.NewColumnEncryptionProperties(col.Path(), parquet.WithKey(e.keys[level]), parquet.WithKeyID(level))
+	}
+	if len(cols) == 0 {
+		return nil, nil
+	}
+	return parquet.NewFileEncryptionProperties(e.footerKey,
+		parquet.WithFooterKeyID(footerKeyID),
+		parquet.WithPlaintextFooter(),
+		parquet.WithEncryptedColumns(cols)), nil
+}
+
+// columnLevels 함수는 schema의 잎 컬럼별 민감도 등급을 찾습니다. 컬럼의 원래 필드는 schema에
+// 저장된 컬럼 계보로, 태그는 저장된 매핑과 설정에서 찾으며 같은 경로에는 설정이 이깁니다.
+// 객체 필드의 태그는 그 아래 필드 모두에 붙고, 더 깊은 경로의 태그가 우선합니다. 키가 없는
+// 등급이나, overflow 컬럼에 들어가는 태그된 필드는 평문으로 쓰지 않도록 오류입니다.
+func (e *columnEncryption) columnLevels(sc *arrow.Schema) (map[string]string, error) {
+	md := sc.Metadata()
+	var mapping map[string]interface{}
+	if idx := md.FindKey(mappingMetadataKey); idx >= 0 {
+		if err := json.Unmarshal([]byte(md.Values()[idx]), &mapping); err != nil {
+			return nil, schemaErrorf("decoding embedded mapping: %w", err)
+		}
+	}
+	var lineage []columnLineage
+	if idx := md.FindKey(lineageKey); idx >= 0 {
+		if err := json.Unmarshal([]byte(md.Values()[idx]), &lineage); err != nil {
+			return nil, schemaErrorf("decoding column lineage: %w", err)
+		}
+	}
+	return e.levels(mapping, lineage)
+}
+
+// levels 함수는 매핑과 컬럼 계보로 columnLevels를 계산합니다.
+func (e *columnEncryption) levels(mapping map[string]interface{}, lineage []columnLineage) (map[string]string, error) {
+	tags := mappingSensitivity(mapping)
+	for path, level := range e.sensitivity {
+		tags[path] = level
+	}
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	paths := make([]string, 0, len(tags))
+	for path, level := range tags {
+		if _, ok := e.keys[level]; !ok {
+			return nil, configErrorf("--encrypt-columns: field %s is tagged %q, but the encryption section has no key for it", path, level)
+		}
+		paths = append(paths, path)
+	}
+	// 긴 경로가 먼저 오므로 처음 맞는 태그가 가장 구체적인 태그입니다.
+	sort.Slice(paths, func(i, j int) bool { return len(paths[i]) > len(paths[j]) })
+
+	levels := make(map[string]string)
+	var sources []string
+	overflow := ""
+	for _, l := range lineage {
+		if l.Rule == ruleOverflow {
+			overflow = l.Column
+			continue
+		}
+		if l.Source != "" {
+			sources = append(sources, l.Source)
+		}
+		level := ""
+		for _, path := range paths {
+			if covers([]string{path}, l.Source) {
+				level = tags[path]
+				break
+			}
+		}
+		// JSON 컬럼처럼 객체 하나를 통째로 담는 컬럼은 그 아래의 태그된 필드도 담습니다.
+		for _, path := range paths {
+			if l.Source == "" || !strings.HasPrefix(path, l.Source+".") {
+				continue
+			}
+			if level == "" {
+				level = tags[path]
+			} else if level != tags[path] {
+				return nil, configErrorf("--encrypt-columns: column %s holds fields tagged %q and %q but can only be encrypted with one key", l.Column, level, tags[path])
+			}
+		}
+		if level != "" {
+			levels[l.Column] = level
+		}
+	}
+	if overflow != "" {
+		props, _ := mapping["properties"].(map[string]interface{})
+		for _, leaf := range mappingLeafPaths(props, "") {
+			if covers(sources, leaf) || !covers(paths, leaf) {
+				continue
+			}
+			return nil, configErrorf("--encrypt-columns: field %s is tagged but has no column of its own and would be written in plain text to %s; raise --max-fields or leave it out with --projection", leaf, overflow)
+		}
+	}
+	return levels, nil
+}
+
+// mappingSensitivity 함수는 매핑에서 민감도 태그를 모읍니다. 최상위 _meta의 sensitivity는
+// 필드 경로에서 등급으로의 객체이고, 필드의 meta(Elasticsearch 7.6+)에는 sensitivity 값
+// 하나를 둡니다.
+//
+//	{"_meta": {"sensitivity": {"user.email": "pii"}},
+//	 "properties": {"salary": {"type": "long", "meta": {"sensitivity": "finance"}}}}
+func mappingSensitivity(mapping map[string]interface{}) map[string]string {
+	tags := make(map[string]string)
+	if meta, ok := mapping["_meta"].(map[string]interface{}); ok {
+		if s, ok := meta[sensitivityKey].(map[string]interface{}); ok {
+			for path, level := range s {
+				if level, ok := level.(string); ok && level != "" {
+					tags[path] = level
+				}
+			}
+		}
+	}
+	props, _ := mapping["properties"].(map[string]interface{})
+	fieldSensitivity(props, "", tags)
+	return tags
+}
+
+func fieldSensitivity(props map[string]interface{}, prefix string, tags map[string]string) {
+	for name, v := range props {
+		field, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if meta, ok := field["meta"].(map[string]interface{}); ok {
+			if level, ok := meta[sensitivityKey].(string); ok && level != "" {
+				tags[prefix+name] = level
+			}
+		}
+		if sub, ok := field["properties"].(map[string]interface{}); ok {
+			fieldSensitivity(sub, prefix+name+".", tags)
+		}
+	}
+}
+
+// parquetLeafColumn 함수는 Parquet 컬럼 경로에서 pqarrow가 리스트에 넣는 list와 element
+// 단계를 빼고 점으로 이어, 컬럼 계보의 Column과 같은 경로로 만듭니다.
+func parquetLeafColumn(path parquet.ColumnPath) string {
+	parts := make([]string, 0, len(path))
+	for i := 0; i < len(path); i++ {
+		if path[i] == "list" && i+1 < len(path) && path[i+1] == "element" {
+			i++
+			continue
+		}
+		parts = append(parts, path[i])
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/v10/parquet"
+)
+
+func TestEncryptionLoad(t *testing.T) {
+	key := func(b byte, n int) string { return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, n)) }
+	path := filepath.Join(t.TempDir(), "config")
+	os.WriteFile(path, []byte(`
+encryption:
+  footer-key: `+key(1, 32)+`
+  keys:
+    pii: ${env:ES_TEST_PII_KEY}
+    finance: `+key(3, 16)+`
+  sensitivity:
+    order.total: finance
+`), 0o600)
+	t.Setenv("ES_SCHEMA_CONFIG", path)
+	t.Setenv("ES_TEST_PII_KEY", key(2, 24))
+
+	var pq parquetOptions
+	if err := (&encryptionOptions{}).load(context.Background(), &pq); err != nil || pq.encryption != nil {
+		t.Fatalf("without --encrypt-columns: %v, %+v", err, pq.encryption)
+	}
+	if err := (&encryptionOptions{enabled: true}).load(context.Background(), &pq); err != nil {
+		t.Fatal(err)
+	}
+	e := pq.encryption
+	if len(e.footerKey) != 32 || len(e.keys["pii"]) != 24 || len(e.keys["finance"]) != 16 || e.sensitivity["order.total"] != "finance" {
+		t.Errorf("encryption = %+v", e)
+	}
+
+	bad := []encryptionConfig{
+		{Keys: map[string]string{"pii": key(2, 32)}},
+		{FooterKey: key(1, 32), Keys: map[string]string{"pii": key(2, 20)}},
+		{FooterKey: key(1, 32), Keys: map[string]string{"footer": key(2, 32)}},
+	}
+	for _, c := range bad {
+		if _, err := c.resolve(context.Background()); exitCodeFor(err) != exitConfigError {
+			t.Errorf("resolve(%+v) = %v, want a config error", c, err)
+		}
+	}
+	os.WriteFile(path, []byte("profiles: {}\n"), 0o600)
+	if err := (&encryptionOptions{enabled: true}).load(context.Background(), &pq); err == nil || !strings.Contains(err.Error(), "no encryption section") {
+		t.Errorf("missing section = %v", err)
+	}
+}
+
+func TestColumnLevels(t *testing.T) {
+	var mapping map[string]interface{}
+	json.Unmarshal([]byte(`{
+		"_meta": {"sensitivity": {"user": "pii", "user.nickname": "public"}},
+		"properties": {
+			"user": {"properties": {"id": {"type": "keyword"}, "email": {"type": "keyword"}, "nickname": {"type": "keyword"}}},
+			"order": {"properties": {"total": {"type": "double"}, "card": {"type": "keyword", "meta": {"sensitivity": "pci"}}}},
+			"message": {"type": "text"}
+		}}`), &mapping)
+	e := &columnEncryption{
+		keys:        map[string]string{"pii": "k1", "public": "k2", "pci": "k3", "finance": "k4"},
+		sensitivity: map[string]string{"order.total": "finance"},
+	}
+	lineage := []columnLineage{
+		{Column: "_id", Source: "_id", Rule: ruleMetadata},
+		{Column: "user.id", Source: "user.id", Rule: ruleCopy},
+		{Column: "user.email", Source: "user.email", Rule: ruleCopy},
+		{Column: "user.nickname", Source: "user.nickname", Rule: ruleCopy},
+		{Column: "order", Source: "order", Rule: ruleJSON},
+		{Column: "message", Source: "message", Rule: ruleCopy},
+	}
+	_, err := e.levels(mapping, lineage)
+	if err == nil || !strings.Contains(err.Error(), "column order holds fields tagged") {
+		t.Errorf("JSON column with two levels = %v", err)
+	}
+
+	delete(e.sensitivity, "order.total")
+	levels, err := e.levels(mapping, lineage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"user.id": "pii", "user.email": "pii", "user.nickname": "public", "order": "pci"}
+	if !reflect.DeepEqual(levels, want) {
+		t.Errorf("levels = %v, want %v", levels, want)
+	}
+
+	overflow := append(lineage[:2:2], columnLineage{Column: "_overflow", Rule: ruleOverflow}, lineage[4], lineage[5])
+	if _, err := e.levels(mapping, overflow); exitCodeFor(err) != exitConfigError || !strings.Contains(err.Error(), "field user.email is tagged") {
+		t.Errorf("tagged field in the overflow column = %v", err)
+	}
+
+	delete(e.keys, "pci")
+	if _, err := e.levels(mapping, lineage); err == nil || !strings.Contains(err.Error(), `tagged "pci"`) {
+		t.Errorf("level without a key = %v", err)
+	}
+}
+
+func TestParquetLeafColumn(t *testing.T) {
+	cases := map[string]parquet.ColumnPath{
+		"message":        {"message"},
+		"tags":           {"tags", "list", "element"},
+		"orders.card":    {"orders", "list", "element", "card"},
+		"user.list.name": {"user", "list", "name"},
+	}
+	for want, path := range cases {
+		if got := parquetLeafColumn(path); got != want {
+			t.Errorf("parquetLeafColumn(%v) = %s, want %s", path, got, want)
+		}
+	}
+}
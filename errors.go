@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// 종료 코드는 Airflow, Argo 같은 오케스트레이터가 실패 유형에 따라 분기할 수 있도록
+// 실패 원인별로 고정된 값을 사용합니다. 한번 공개된 값은 바꾸지 않습니다.
+const (
+	exitOK              = 0
+	exitInternalError   = 1
+	exitConfigError     = 2
+	exitConnectionError = 3
+	exitSchemaError     = 4
+	exitDataError       = 5
+	exitPartialSuccess  = 6
+	exitAnomaly         = 7
+)
+
+// errorKind는 오류의 분류이며 JSON 오류 출력의 "kind" 값으로도 사용됩니다.
+type errorKind string
+
+const (
+	kindInternal   errorKind = "internal"
+	kindConfig     errorKind = "config"
+	kindConnection errorKind = "connection"
+	kindSchema     errorKind = "schema"
+	kindData       errorKind = "data"
+	kindPartial    errorKind = "partial_success"
+	kindAnomaly    errorKind = "anomaly"
+)
+
+// exitCodes는 오류 분류별 종료 코드입니다.
+var exitCodes = map[errorKind]int{
+	kindInternal:   exitInternalError,
+	kindConfig:     exitConfigError,
+	kindConnection: exitConnectionError,
+	kindSchema:     exitSchemaError,
+	kindData:       exitDataError,
+	kindPartial:    exitPartialSuccess,
+	kindAnomaly:    exitAnomaly,
+}
+
+// cliError는 원래 오류에 분류 정보를 덧붙인 오류입니다.
+type cliError struct {
+	kind errorKind
+	err  error
+}
+
+func (e *cliError) Error() string { return e.err.Error() }
+func (e *cliError) Unwrap() error { return e.err }
+
+// withKind 함수는 err에 분류를 부여합니다. err가 nil이면 nil을 반환합니다.
+func withKind(kind errorKind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &cliError{kind: kind, err: err}
+}
+
+func configErrorf(format string, args ...interface{}) error {
+	return withKind(kindConfig, fmt.Errorf(format, args...))
+}
+
+func connectionErrorf(format string, args ...interface{}) error {
+	return withKind(kindConnection, fmt.Errorf(format, args...))
+}
+
+func schemaErrorf(format string, args ...interface{}) error {
+	return withKind(kindSchema, fmt.Errorf(format, args...))
+}
+
+func dataErrorf(format string, args ...interface{}) error {
+	return withKind(kindData, fmt.Errorf(format, args...))
+}
+
+// anomalyErrorf 함수는 완료했지만 결과가 이전 실행들과 크게 다른 실행의 오류를 만듭니다.
+func anomalyErrorf(format string, args ...interface{}) error {
+	return withKind(kindAnomaly, fmt.Errorf(format, args...))
+}
+
+// kindOf 함수는 오류 체인에서 가장 바깥쪽의 분류를 찾습니다.
+// 분류가 없는 오류는 internal로 취급합니다.
+func kindOf(err error) errorKind {
+	var ce *cliError
+	if errors.As(err, &ce) {
+		return ce.kind
+	}
+	return kindInternal
+}
+
+// exitCodeFor 함수는 오류에 해당하는 프로세스 종료 코드를 반환합니다.
+func exitCodeFor(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	return exitCodes[kindOf(err)]
+}
+
+const (
+	errorFormatText = "text"
+	errorFormatJSON = "json"
+)
+
+// errorReport는 --error-format json일 때 stderr에 한 줄로 출력되는 오류 정보입니다.
+type errorReport struct {
+	Error    string    `json:"error"`
+	Kind     errorKind `json:"kind"`
+	ExitCode int       `json:"exit_code"`
+}
+
+// reportError 함수는 지정된 형식으로 오류를 출력합니다.
+func reportError(w io.Writer, err error, format string) {
+	if format == errorFormatJSON {
+		report := errorReport{Error: err.Error(), Kind: kindOf(err), ExitCode: exitCodeFor(err)}
+		if data, marshalErr := json.Marshal(report); marshalErr == nil {
+			fmt.Fprintln(w, string(data))
+			return
+		}
+	}
+	fmt.Fprintf(w, "es-schema: %s error: %v\n", kindOf(err), err)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestExitCodeFor(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, exitOK},
+		{errors.New("boom"), exitInternalError},
+		{configErrorf("bad flag"), exitConfigError},
+		{connectionErrorf("refused"), exitConnectionError},
+		{schemaErrorf("bad mapping"), exitSchemaError},
+		{dataErrorf("bad document"), exitDataError},
+		{withKind(kindPartial, errors.New("3 documents failed")), exitPartialSuccess},
+		{anomalyErrorf("rows far below the baseline"), exitAnomaly},
+		{fmt.Errorf("wrapped: %w", schemaErrorf("bad mapping")), exitSchemaError},
+	}
+	for _, tt := range tests {
+		if got := exitCodeFor(tt.err); got != tt.want {
+			t.Errorf("exitCodeFor(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestReportErrorJSON(t *testing.T) {
+	var buf bytes.Buffer
+	reportError(&buf, connectionErrorf("dial tcp: connection refused"), errorFormatJSON)
+
+	var got errorReport
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output is not JSON: %q", buf.String())
+	}
+	want := errorReport{Error: "dial tcp: connection refused", Kind: kindConnection, ExitCode: exitConnectionError}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
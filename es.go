@@ -0,0 +1,764 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// esOptions는 Elasticsearch 클러스터 접속 설정입니다. 같은 명령에서 여러 클러스터를
+// 다룰 수 있도록 플래그 이름 앞에 prefix를 붙여 등록합니다.
+type esOptions struct {
+	prefix   string
+	url      string
+	username string
+	password string
+	apiKey   string
+	caCert   string
+	insecure bool
+	timeout  time.Duration
+	// readOnly이면 클러스터를 바꿀 수 있는 요청을 보내지 않고, 실행 전에 읽기 권한을
+	// 확인합니다.
+	readOnly bool
+}
+
+func (o *esOptions) bind(fs *flag.FlagSet, prefix, role string) {
+	o.prefix = prefix
+	fs.StringVar(&o.url, prefix+"url", "", role+" Elasticsearch URL, e.g. https://localhost:9200")
+	fs.StringVar(&o.username, prefix+"username", "", "basic auth user for the "+role+" cluster (default $ES_USERNAME)")
+	fs.StringVar(&o.password, prefix+"password", "", "basic auth password for the "+role+" cluster (default $ES_PASSWORD)")
+	fs.StringVar(&o.apiKey, prefix+"api-key", "", "API key for the "+role+" cluster, base64 id:key (default $ES_API_KEY)")
+	fs.StringVar(&o.caCert, prefix+"ca-cert", "", "PEM CA certificate to trust for the "+role+" cluster")
+	fs.BoolVar(&o.insecure, prefix+"insecure", false, "skip TLS certificate verification for the "+role+" cluster")
+	fs.DurationVar(&o.timeout, prefix+"timeout", time.Minute, "timeout of a single request to the "+role+" cluster")
+	fs.BoolVar(&o.readOnly, prefix+"read-only", false, "refuse every request that can change the "+role+" cluster (indexing, mapping, settings) and check the read privileges of the credentials before starting")
+}
+
+// client 함수는 설정으로 클라이언트를 만듭니다. 자격 증명이 플래그로 주어지지 않으면
+// 명령행에 비밀 값이 남지 않도록 ES_USERNAME, ES_PASSWORD, ES_API_KEY 환경 변수를 사용합니다.
+func (o *esOptions) client() (*esClient, error) {
+	if o.url == "" {
+		return nil, configErrorf("--%surl is required", o.prefix)
+	}
+	if _, err := url.Parse(o.url); err != nil {
+		return nil, configErrorf("invalid --%surl: %w", o.prefix, err)
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: o.insecure}
+	if o.caCert != "" {
+		pem, err := os.ReadFile(o.caCert)
+		if err != nil {
+			return nil, configErrorf("reading --%sca-cert: %w", o.prefix, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, configErrorf("--%sca-cert %s contains no certificates", o.prefix, o.caCert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &esClient{
+		baseURL:  strings.TrimRight(o.url, "/"),
+		username: firstNonEmpty(o.username, os.Getenv("ES_USERNAME")),
+		password: firstNonEmpty(o.password, os.Getenv("ES_PASSWORD")),
+		apiKey:   firstNonEmpty(o.apiKey, os.Getenv("ES_API_KEY")),
+		http:     &http.Client{Transport: transport, Timeout: o.timeout},
+		readOnly: o.readOnly,
+		prefix:   o.prefix,
+	}, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// esClient는 이 도구가 사용하는 Elasticsearch REST API만 다루는 얇은 클라이언트입니다.
+type esClient struct {
+	baseURL  string
+	username string
+	password string
+	apiKey   string
+	http     *http.Client
+	// readOnly이면 readOnlyRequest가 아닌 요청을 보내기 전에 거부합니다. prefix는 그 오류에
+	// 쓸 플래그 접두사입니다.
+	readOnly bool
+	prefix   string
+}
+
+// esError는 Elasticsearch가 2xx가 아닌 상태로 응답한 경우의 오류입니다.
+type esError struct {
+	Status int
+	Type   string
+	Reason string
+}
+
+func (e *esError) Error() string {
+	if e.Type == "" {
+		return fmt.Sprintf("elasticsearch returned HTTP %d: %s", e.Status, e.Reason)
+	}
+	return fmt.Sprintf("elasticsearch returned HTTP %d: %s: %s", e.Status, e.Type, e.Reason)
+}
+
+// newESError 함수는 응답 본문에서 오류 정보를 꺼내고, 상태 코드로 오류 분류를 정합니다.
+// 인증/권한 문제와 없는 인덱스는 설정 오류, 과부하와 서버 오류는 연결 오류로 봅니다.
+func newESError(status int, body []byte) error {
+	e := &esError{Status: status}
+	var parsed struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(body, &parsed) == nil && len(parsed.Error) > 0 {
+		var detail struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		}
+		if json.Unmarshal(parsed.Error, &detail) == nil && detail.Type != "" {
+			e.Type, e.Reason = detail.Type, detail.Reason
+		} else {
+			e.Reason = strings.Trim(string(parsed.Error), `"`)
+		}
+	} else {
+		e.Reason = strings.TrimSpace(string(body))
+	}
+
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden || status == http.StatusNotFound:
+		return withKind(kindConfig, e)
+	case status == http.StatusTooManyRequests || status >= 500:
+		return withKind(kindConnection, e)
+	default:
+		return withKind(kindData, e)
+	}
+}
+
+// do 함수는 요청을 보내고 응답 본문을 반환합니다.
+func (c *esClient) do(ctx context.Context, method, path string, query url.Values, body io.Reader, contentType string) ([]byte, error) {
+	if c.readOnly && !readOnlyRequest(method, path) {
+		return nil, readOnlyError(c.prefix, method, path)
+	}
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, configErrorf("building request %s %s: %w", method, path, err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+	switch {
+	case c.apiKey != "":
+		req.Header.Set("Authorization", "ApiKey "+c.apiKey)
+	case c.username != "":
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, connectionErrorf("%s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, connectionErrorf("%s %s: reading response: %w", method, path, err)
+	}
+	if resp.StatusCode >= 300 {
+		return data, newESError(resp.StatusCode, data)
+	}
+	return data, nil
+}
+
+// sendJSON 함수는 in을 JSON 본문으로 보내고(nil이면 본문 없이), 응답을 out에 디코딩합니다.
+func (c *esClient) sendJSON(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	data, err := c.do(ctx, method, path, query, body, "application/json")
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return dataErrorf("%s %s: decoding response: %w", method, path, err)
+	}
+	return nil
+}
+
+// bulkItemResult는 _bulk 응답에서 문서 하나의 처리 결과입니다.
+type bulkItemResult struct {
+	Index  string         `json:"_index"`
+	ID     string         `json:"_id"`
+	Status int            `json:"status"`
+	Error  *bulkItemError `json:"error,omitempty"`
+}
+
+type bulkItemError struct {
+	Type     string                 `json:"type"`
+	Reason   string                 `json:"reason"`
+	CausedBy *bulkItemError         `json:"caused_by,omitempty"`
+	Header   map[string]interface{} `json:"header,omitempty"`
+}
+
+func (e *bulkItemError) String() string {
+	s := e.Type + ": " + e.Reason
+	if p := e.processorType(); p != "" {
+		s = "pipeline processor [" + p + "] " + s
+	}
+	if e.CausedBy != nil {
+		s += " (caused by " + e.CausedBy.String() + ")"
+	}
+	return s
+}
+
+// processorType 함수는 인제스트 파이프라인의 프로세서에서 난 오류이면 프로세서 종류를
+// 반환합니다. Elasticsearch는 이 정보를 header.processor_type에 담아 보냅니다.
+func (e *bulkItemError) processorType() string {
+	switch v := e.Header["processor_type"].(type) {
+	case string:
+		return v
+	case []interface{}:
+		if len(v) > 0 {
+			return fmt.Sprint(v[0])
+		}
+	}
+	return ""
+}
+
+// isPipelineFailure 함수는 문서가 색인 전에 인제스트 파이프라인에서 실패했는지 판단합니다.
+func (e *bulkItemError) isPipelineFailure() bool {
+	if e.processorType() != "" || e.Header["pipeline_origin"] != nil {
+		return true
+	}
+	if e.Type == "fail_processor_exception" || strings.HasPrefix(e.Reason, "pipeline with id [") {
+		return true
+	}
+	return e.CausedBy != nil && e.CausedBy.isPipelineFailure()
+}
+
+// category 함수는 실패 집계에 쓰는 "단계:오류 종류" 키를 만듭니다.
+func (e *bulkItemError) category() string {
+	if !e.isPipelineFailure() {
+		return "indexing:" + e.Type
+	}
+	if p := e.processorType(); p != "" {
+		return "pipeline:" + p + ":" + e.Type
+	}
+	return "pipeline:" + e.Type
+}
+
+type bulkResponse struct {
+	Took   int                         `json:"took"`
+	Errors bool                        `json:"errors"`
+	Items  []map[string]bulkItemResult `json:"items"`
+}
+
+// bulk 함수는 NDJSON 본문을 _bulk API로 보냅니다.
+func (c *esClient) bulk(ctx context.Context, index string, body []byte, query url.Values) (*bulkResponse, error) {
+	path := "/_bulk"
+	if index != "" {
+		path = "/" + url.PathEscape(index) + "/_bulk"
+	}
+	data, err := c.do(ctx, http.MethodPost, path, query, bytes.NewReader(body), "application/x-ndjson")
+	if err != nil {
+		return nil, err
+	}
+	var resp bulkResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, dataErrorf("decoding _bulk response: %w", err)
+	}
+	return &resp, nil
+}
+
+// indexExists 함수는 인덱스(또는 별칭)가 존재하는지 확인합니다.
+func (c *esClient) indexExists(ctx context.Context, index string) (bool, error) {
+	_, err := c.do(ctx, http.MethodHead, "/"+url.PathEscape(index), nil, nil, "")
+	var ee *esError
+	if errors.As(err, &ee) && ee.Status == http.StatusNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// createIndex 함수는 body({"mappings": ..., "settings": ...})로 인덱스를 만듭니다.
+func (c *esClient) createIndex(ctx context.Context, index string, body interface{}) error {
+	return c.sendJSON(ctx, http.MethodPut, "/"+url.PathEscape(index), nil, body, nil)
+}
+
+// serverVersion 함수는 클러스터의 주 버전을 반환합니다. OpenSearch는 타입 없는 매핑 등
+// Elasticsearch 7과 같은 API를 쓰므로 7로 봅니다.
+func (c *esClient) serverVersion(ctx context.Context) (int, error) {
+	var info struct {
+		Version struct {
+			Number       string `json:"number"`
+			Distribution string `json:"distribution"`
+		} `json:"version"`
+	}
+	if err := c.sendJSON(ctx, http.MethodGet, "/", nil, nil, &info); err != nil {
+		return 0, err
+	}
+	if info.Version.Distribution == "opensearch" {
+		return 7, nil
+	}
+	major, _, _ := strings.Cut(info.Version.Number, ".")
+	n, err := strconv.Atoi(major)
+	if err != nil {
+		return 0, dataErrorf("unexpected version number %q from %s", info.Version.Number, c.baseURL)
+	}
+	return n, nil
+}
+
+// getMapping 함수는 인덱스의 매핑을 가져옵니다. 별칭이나 패턴이 여러 인덱스를 가리키면
+// 어느 매핑을 쓸지 알 수 없으므로 설정 오류로 처리합니다.
+func (c *esClient) getMapping(ctx context.Context, index string) (json.RawMessage, error) {
+	mappings, err := c.getMappings(ctx, index)
+	if err != nil {
+		return nil, err
+	}
+	if len(mappings) != 1 {
+		return nil, configErrorf("%s matches %d indices; give a single index", index, len(mappings))
+	}
+	for _, m := range mappings {
+		return m, nil
+	}
+	return nil, nil
+}
+
+// getMappings 함수는 인덱스 이름, 별칭 또는 패턴(쉼표로 여러 개)이 가리키는 인덱스별
+// 매핑을 가져옵니다.
+func (c *esClient) getMappings(ctx context.Context, pattern string) (map[string]json.RawMessage, error) {
+	var resp map[string]struct {
+		Mappings json.RawMessage `json:"mappings"`
+	}
+	if err := c.sendJSON(ctx, http.MethodGet, "/"+url.PathEscape(pattern)+"/_mapping", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	mappings := make(map[string]json.RawMessage, len(resp))
+	for index, m := range resp {
+		mappings[index] = m.Mappings
+	}
+	return mappings, nil
+}
+
+// fieldCapability는 _field_caps 응답에서 필드 하나의 타입 하나입니다. 같은 필드가
+// 인덱스마다 다른 타입이면 Indices에 이 타입을 쓰는 인덱스가 담깁니다.
+type fieldCapability struct {
+	Type          string   `json:"type"`
+	MetadataField bool     `json:"metadata_field"`
+	Indices       []string `json:"indices"`
+}
+
+// fieldCaps 함수는 pattern이 가리키는 인덱스들의 필드별 타입을 Field Capabilities API로
+// 가져옵니다. 응답에 담긴 인덱스 이름과, 필드 경로에서 타입별 정보로의 맵을 반환합니다.
+func (c *esClient) fieldCaps(ctx context.Context, pattern string) ([]string, map[string]map[string]fieldCapability, error) {
+	var resp struct {
+		Indices []string                              `json:"indices"`
+		Fields  map[string]map[string]fieldCapability `json:"fields"`
+	}
+	query := url.Values{"fields": {"*"}}
+	if err := c.sendJSON(ctx, http.MethodGet, "/"+url.PathEscape(pattern)+"/_field_caps", query, nil, &resp); err != nil {
+		return nil, nil, err
+	}
+	return resp.Indices, resp.Fields, nil
+}
+
+// searchHit는 검색 결과의 문서 하나입니다.
+type searchHit struct {
+	Index  string          `json:"_index"`
+	ID     string          `json:"_id"`
+	Source json.RawMessage `json:"_source"`
+	// Sort는 정렬이 있는 검색(point in time 검색)에서 다음 페이지의 search_after로 씁니다.
+	Sort []json.RawMessage `json:"sort,omitempty"`
+	// SeqNo와 PrimaryTerm은 seq_no_primary_term=true로 검색했을 때만 옵니다.
+	SeqNo       *int64 `json:"_seq_no,omitempty"`
+	PrimaryTerm *int64 `json:"_primary_term,omitempty"`
+	// docHash는 --doc-hash일 때 export가 구한 정규화된 내용의 해시입니다.
+	docHash string
+}
+
+type scrollResponse struct {
+	ScrollID string `json:"_scroll_id"`
+	Hits     struct {
+		Hits []searchHit `json:"hits"`
+	} `json:"hits"`
+}
+
+// scroll 함수는 scroll API로 인덱스의 문서를 size개씩 읽어 fn에 넘깁니다. query가
+// 비어 있으면 모든 문서를 읽습니다. slice가 nil이 아니면 sliced scroll로 그 조각만
+// 읽습니다. params는 첫 검색 요청에 붙일 매개변수(예: preference)입니다. 끝나면(실패해도)
+// scroll 컨텍스트를 정리합니다.
+func (c *esClient) scroll(ctx context.Context, index string, query json.RawMessage, size int, keepAlive time.Duration, slice *searchSlice, params url.Values, fn func(hits []searchHit) error) error {
+	ka := fmt.Sprintf("%ds", int(keepAlive.Seconds()))
+	body := map[string]interface{}{"size": size, "sort": []string{"_doc"}}
+	if len(query) > 0 {
+		body["query"] = query
+	}
+	if slice != nil {
+		body["slice"] = slice
+	}
+	q := url.Values{"scroll": {ka}}
+	for k, v := range params {
+		q[k] = v
+	}
+	var resp scrollResponse
+	err := c.sendJSON(ctx, http.MethodPost, "/"+url.PathEscape(index)+"/_search", q, body, &resp)
+	scrollID := resp.ScrollID
+	defer func() {
+		if scrollID != "" {
+			c.clearScroll(scrollID)
+		}
+	}()
+	for err == nil && len(resp.Hits.Hits) > 0 {
+		if err = fn(resp.Hits.Hits); err != nil {
+			break
+		}
+		resp = scrollResponse{}
+		err = c.sendJSON(ctx, http.MethodPost, "/_search/scroll", nil, map[string]string{"scroll": ka, "scroll_id": scrollID}, &resp)
+		if resp.ScrollID != "" {
+			scrollID = resp.ScrollID
+		}
+	}
+	return err
+}
+
+// clearScroll 함수는 scroll 컨텍스트를 해제합니다. 실행이 취소된 뒤에도 호출되므로
+// 별도의 짧은 타임아웃을 쓰고, 실패는 무시합니다(컨텍스트는 keep-alive 후 만료됩니다).
+func (c *esClient) clearScroll(scrollID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c.sendJSON(ctx, http.MethodDelete, "/_search/scroll", nil, map[string][]string{"scroll_id": {scrollID}}, nil)
+}
+
+// pointInTime은 연 point in time의 현재 id입니다. Elasticsearch는 검색 응답마다 새 id를
+// 돌려줄 수 있으므로, 검색, 연장, 닫기가 모두 마지막으로 받은 id를 씁니다. 여러 인덱스와
+// 조각의 검색이 함께 쓰므로 mu로 보호합니다.
+type pointInTime struct {
+	mu sync.Mutex
+	id string
+}
+
+func (p *pointInTime) current() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.id
+}
+
+// update 함수는 응답의 pit_id가 비어 있지 않으면 현재 id로 삼습니다.
+func (p *pointInTime) update(id string) {
+	if id == "" {
+		return
+	}
+	p.mu.Lock()
+	p.id = id
+	p.mu.Unlock()
+}
+
+// openPIT 함수는 indices에 point in time을 엽니다. 이 point in time으로 하는 검색은
+// 모두 연 시점의 같은 스냅샷을 봅니다. Elasticsearch 7.10 이상이 필요합니다.
+func (c *esClient) openPIT(ctx context.Context, indices string, keepAlive time.Duration) (*pointInTime, error) {
+	var resp struct {
+		ID string `json:"id"`
+	}
+	q := url.Values{"keep_alive": {fmt.Sprintf("%ds", int(keepAlive.Seconds()))}}
+	if err := c.sendJSON(ctx, http.MethodPost, "/"+url.PathEscape(indices)+"/_pit", q, nil, &resp); err != nil {
+		return nil, err
+	}
+	if resp.ID == "" {
+		return nil, dataErrorf("opening point in time on %s: response has no id", indices)
+	}
+	return &pointInTime{id: resp.ID}, nil
+}
+
+// closePIT 함수는 point in time을 닫습니다. clearScroll처럼 취소 뒤에도 호출되므로 별도의
+// 짧은 타임아웃을 쓰고, 실패는 무시합니다.
+func (c *esClient) closePIT(pit *pointInTime) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c.sendJSON(ctx, http.MethodDelete, "/_pit", nil, map[string]string{"id": pit.current()}, nil)
+}
+
+// keepPITAlive 함수는 ctx가 끝날 때까지 keepAlive의 절반마다 빈 검색을 보내 point in
+// time을 연장합니다. 작업자를 기다리는 인덱스가 읽기 시작하기 전에 만료되지 않게 합니다.
+// 실패는 무시합니다(만료되면 그 인덱스의 검색이 오류로 끝납니다).
+func (c *esClient) keepPITAlive(ctx context.Context, pit *pointInTime, keepAlive time.Duration) {
+	ka := fmt.Sprintf("%ds", int(keepAlive.Seconds()))
+	t := time.NewTicker(keepAlive / 2)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			var resp struct {
+				PITID string `json:"pit_id"`
+			}
+			body := map[string]interface{}{"size": 0, "track_total_hits": false, "pit": map[string]string{"id": pit.current(), "keep_alive": ka}}
+			if c.sendJSON(ctx, http.MethodPost, "/_search", nil, body, &resp) == nil {
+				pit.update(resp.PITID)
+			}
+		}
+	}
+}
+
+// searchSlice는 point in time 검색이나 scroll을 나눠 읽을 때 그중 하나입니다.
+type searchSlice struct {
+	ID  int `json:"id"`
+	Max int `json:"max"`
+}
+
+// searchPIT 함수는 point in time pit의 문서 중 query에 맞는 것을 search_after로 size개씩
+// 읽어 fn에 넘깁니다. slice가 nil이 아니면 그 조각만 읽습니다. params는 scroll처럼 검색
+// 요청마다 붙일 매개변수입니다. 응답마다 point in time을 keepAlive만큼 연장하고, 새 id가
+// 오면 pit에 기록해 다음 요청과 연장, 닫기에 씁니다.
+func (c *esClient) searchPIT(ctx context.Context, pit *pointInTime, query json.RawMessage, size int, keepAlive time.Duration, slice *searchSlice, params url.Values, fn func(hits []searchHit) error) error {
+	ka := fmt.Sprintf("%ds", int(keepAlive.Seconds()))
+	var after []json.RawMessage
+	for {
+		body := map[string]interface{}{
+			"size": size,
+			"pit":  map[string]string{"id": pit.current(), "keep_alive": ka},
+			"sort": []map[string]string{{"_shard_doc": "asc"}},
+		}
+		if len(query) > 0 {
+			body["query"] = query
+		}
+		if slice != nil {
+			body["slice"] = slice
+		}
+		if after != nil {
+			body["search_after"] = after
+		}
+		var resp struct {
+			PITID string `json:"pit_id"`
+			Hits  struct {
+				Hits []searchHit `json:"hits"`
+			} `json:"hits"`
+		}
+		if err := c.sendJSON(ctx, http.MethodPost, "/_search", params, body, &resp); err != nil {
+			return err
+		}
+		hits := resp.Hits.Hits
+		if len(hits) == 0 {
+			return nil
+		}
+		pit.update(resp.PITID)
+		after = hits[len(hits)-1].Sort
+		if err := fn(hits); err != nil {
+			return err
+		}
+		if len(after) == 0 {
+			return dataErrorf("point in time search returned hits without sort values")
+		}
+	}
+}
+
+// indexStoreBytes 함수는 인덱스 주 샤드의 저장 크기(복제본 제외)를 반환합니다.
+func (c *esClient) indexStoreBytes(ctx context.Context, index string) (int64, error) {
+	var resp struct {
+		All struct {
+			Primaries struct {
+				Store struct {
+					SizeInBytes int64 `json:"size_in_bytes"`
+				} `json:"store"`
+			} `json:"primaries"`
+		} `json:"_all"`
+	}
+	if err := c.sendJSON(ctx, http.MethodGet, "/"+url.PathEscape(index)+"/_stats/store", nil, nil, &resp); err != nil {
+		return 0, err
+	}
+	return resp.All.Primaries.Store.SizeInBytes, nil
+}
+
+// count 함수는 query에 맞는 문서 수를 반환합니다. _stats의 docs.count는 nested 객체도
+// 따로 세므로 최상위 문서 수는 _count로 얻습니다.
+func (c *esClient) count(ctx context.Context, index string, query json.RawMessage) (int64, error) {
+	body := map[string]interface{}{}
+	if len(query) > 0 {
+		body["query"] = query
+	}
+	var resp struct {
+		Count int64 `json:"count"`
+	}
+	if err := c.sendJSON(ctx, http.MethodPost, "/"+url.PathEscape(index)+"/_count", nil, body, &resp); err != nil {
+		return 0, err
+	}
+	return resp.Count, nil
+}
+
+// geoBounds 함수는 query에 맞는 문서에서 field 값을 모두 담는 경계 상자를 geo_bounds
+// 집계로 구해 [xmin, ymin, xmax, ymax]로 반환합니다. 값이 없으면 nil입니다. 경도가
+// 날짜 변경선을 넘어 감싸지 않게 wrap_longitude를 끕니다.
+func (c *esClient) geoBounds(ctx context.Context, index string, query json.RawMessage, field string) ([]float64, error) {
+	body := map[string]interface{}{
+		"size": 0,
+		"aggs": map[string]interface{}{
+			"bounds": map[string]interface{}{"geo_bounds": map[string]interface{}{"field": field, "wrap_longitude": false}},
+		},
+	}
+	if len(query) > 0 {
+		body["query"] = query
+	}
+	type corner struct {
+		Lat float64 `json:"lat"`
+		Lon float64 `json:"lon"`
+	}
+	var resp struct {
+		Aggregations struct {
+			Bounds struct {
+				Bounds *struct {
+					TopLeft     corner `json:"top_left"`
+					BottomRight corner `json:"bottom_right"`
+				} `json:"bounds"`
+			} `json:"bounds"`
+		} `json:"aggregations"`
+	}
+	if err := c.sendJSON(ctx, http.MethodPost, "/"+url.PathEscape(index)+"/_search", nil, body, &resp); err != nil {
+		return nil, err
+	}
+	b := resp.Aggregations.Bounds.Bounds
+	if b == nil {
+		return nil, nil
+	}
+	return []float64{b.TopLeft.Lon, b.BottomRight.Lat, b.BottomRight.Lon, b.TopLeft.Lat}, nil
+}
+
+// timedSearch 함수는 body로 검색하고 Elasticsearch가 보고한 처리 시간(took)을 반환합니다.
+// 반복 측정이 캐시된 결과를 재지 않도록 샤드 요청 캐시를 끕니다.
+func (c *esClient) timedSearch(ctx context.Context, index string, body json.RawMessage) (time.Duration, error) {
+	var resp struct {
+		Took int64 `json:"took"`
+	}
+	params := url.Values{"request_cache": {"false"}}
+	if err := c.sendJSON(ctx, http.MethodPost, "/"+url.PathEscape(index)+"/_search", params, body, &resp); err != nil {
+		return 0, err
+	}
+	return time.Duration(resp.Took) * time.Millisecond, nil
+}
+
+// sample 함수는 query에 맞는 문서 중 size개를 무작위로 가져옵니다. scroll 순서의 앞부분만
+// 보면 오래된 문서에 치우치므로 random_score로 고릅니다.
+func (c *esClient) sample(ctx context.Context, index string, query json.RawMessage, size int) ([]searchHit, error) {
+	inner := query
+	if len(inner) == 0 {
+		inner = json.RawMessage(`{"match_all": {}}`)
+	}
+	body := map[string]interface{}{
+		"size": size,
+		"query": map[string]interface{}{
+			"function_score": map[string]interface{}{"query": inner, "random_score": map[string]interface{}{}},
+		},
+	}
+	var resp scrollResponse
+	if err := c.sendJSON(ctx, http.MethodPost, "/"+url.PathEscape(index)+"/_search", nil, body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Hits.Hits, nil
+}
+
+// analysisSettings는 인덱스 설정의 index.analysis 중 노멀라이저와 그것이 쓰는 필터
+// 정의입니다.
+type analysisSettings struct {
+	Normalizer map[string]normalizerDef `json:"normalizer"`
+	Filter     map[string]filterDef     `json:"filter"`
+}
+
+type normalizerDef struct {
+	Type       string   `json:"type"`
+	Filter     []string `json:"filter"`
+	CharFilter []string `json:"char_filter"`
+}
+
+type filterDef struct {
+	Type string `json:"type"`
+}
+
+// analysis 함수는 인덱스의 index.analysis 설정을 가져옵니다.
+func (c *esClient) analysis(ctx context.Context, index string) (*analysisSettings, error) {
+	var resp map[string]struct {
+		Settings struct {
+			Index struct {
+				Analysis analysisSettings `json:"analysis"`
+			} `json:"index"`
+		} `json:"settings"`
+	}
+	if err := c.sendJSON(ctx, http.MethodGet, "/"+url.PathEscape(index)+"/_settings/index.analysis.*", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	for _, s := range resp {
+		return &s.Settings.Index.Analysis, nil
+	}
+	return nil, configErrorf("index %s not found", index)
+}
+
+// analyze 함수는 _analyze API로 texts를 분석한 토큰을 반환합니다. analyzer가 비어
+// 있으면 index 매핑에서 field에 지정한 분석기를 씁니다.
+func (c *esClient) analyze(ctx context.Context, index, field, analyzer string, texts []string) ([]string, error) {
+	body := map[string]interface{}{"text": texts}
+	if analyzer != "" {
+		body["analyzer"] = analyzer
+	} else {
+		body["field"] = field
+	}
+	var resp struct {
+		Tokens []struct {
+			Token string `json:"token"`
+		} `json:"tokens"`
+	}
+	if err := c.sendJSON(ctx, http.MethodPost, "/"+url.PathEscape(index)+"/_analyze", nil, body, &resp); err != nil {
+		return nil, err
+	}
+	tokens := make([]string, len(resp.Tokens))
+	for i, t := range resp.Tokens {
+		tokens[i] = t.Token
+	}
+	return tokens, nil
+}
+
+// shardCount 함수는 인덱스의 주 샤드 수를 반환합니다.
+func (c *esClient) shardCount(ctx context.Context, index string) (int, error) {
+	var resp map[string]struct {
+		Settings struct {
+			Index struct {
+				NumberOfShards string `json:"number_of_shards"`
+			} `json:"index"`
+		} `json:"settings"`
+	}
+	if err := c.sendJSON(ctx, http.MethodGet, "/"+url.PathEscape(index)+"/_settings/index.number_of_shards", nil, nil, &resp); err != nil {
+		return 0, err
+	}
+	for _, s := range resp {
+		n, err := strconv.Atoi(s.Settings.Index.NumberOfShards)
+		if err != nil || n <= 0 {
+			return 0, dataErrorf("unexpected number_of_shards %q for %s", s.Settings.Index.NumberOfShards, index)
+		}
+		return n, nil
+	}
+	return 0, configErrorf("index %s not found", index)
+}
@@ -0,0 +1,456 @@
+package esschema
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+	"github.com/apache/arrow/go/v10/arrow/decimal128"
+	"github.com/apache/arrow/go/v10/arrow/float16"
+)
+
+// dateLayouts는 Elasticsearch 기본 날짜 형식(strict_date_optional_time)에 해당하는
+// 레이아웃입니다. 시간대가 없으면 UTC로 봅니다.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// ConvertDocument 함수는 doc의 최상위 필드를 values에 변환해 넣고, 변환할 수 없어 null로
+// 둔 필드의 경로를 반환합니다. 예상하지 못한 값으로 변환이 패닉하더라도 실행 전체를
+// 멈추지 않도록 오류로 바꿉니다. 범위를 넘는 숫자는 null로 둡니다.
+func ConvertDocument(fields []arrow.Field, doc map[string]interface{}, values []interface{}) (bad []string, err error) {
+	return new(Converter).Document(fields, doc, values)
+}
+
+// AppendValue 함수는 JSON에서 디코딩한 값 v를 builder에 추가합니다. 바꿀 수 없는 값은
+// null을 추가하고 false를 반환합니다.
+func AppendValue(builder array.Builder, dt arrow.DataType, v interface{}) bool {
+	var bad []string
+	AppendConverted(builder, ConvertValue(dt, v, "", &bad))
+	return len(bad) == 0
+}
+
+// ConvertValue 함수는 JSON에서 디코딩한 값 v를 dt 타입의 빌더에 넣을 값으로 바꿉니다.
+// Elasticsearch의 기본 coerce 규칙처럼 숫자 문자열은 숫자로, 원소 하나짜리 배열은 그
+// 원소로 받아들입니다. 리스트는 원소 값의 슬라이스, 구조체는 필드 순서대로의 값
+// 슬라이스, flattened 맵(IsFlattened)은 키 순서의 [키, 값] 쌍 슬라이스가 됩니다.
+// geo_point 구조체(IsGeoPoint)는 GeoPoint가 읽는 모든 형식을, range 구조체(IsRange)는 gt와
+// lt 경계와 CIDR 문자열을, ip(IsIP)는 IPv4와 IPv6 주소 문자열을 받습니다. 바꿀 수 없는
+// 값은 nil로 두고 path를 bad에 추가합니다.
+func ConvertValue(dt arrow.DataType, v interface{}, path string, bad *[]string) interface{} {
+	return new(Converter).Value(dt, v, path, bad)
+}
+
+// Value 함수는 ConvertValue와 같지만 범위를 넘는 숫자를 c.Overflow에 따라 다룹니다.
+func (c *Converter) Value(dt arrow.DataType, v interface{}, path string, bad *[]string) interface{} {
+	if v == nil {
+		return nil
+	}
+	switch t := dt.(type) {
+	case *arrow.ListType:
+		items, ok := v.([]interface{})
+		if !ok {
+			items = []interface{}{v}
+		} else if IsGeoPoint(t.Elem()) && isPoint(v) {
+			// [lon, lat] 배열은 점 여러 개가 아니라 점 하나입니다.
+			items = []interface{}{v}
+		}
+		out := make([]interface{}, len(items))
+		for i, item := range items {
+			out[i] = c.Value(t.Elem(), item, path, bad)
+		}
+		return out
+	case *arrow.FixedSizeListType:
+		items, ok := v.([]interface{})
+		if !ok || len(items) != int(t.Len()) {
+			*bad = append(*bad, path)
+			return nil
+		}
+		out := make([]interface{}, len(items))
+		for i, item := range items {
+			out[i] = c.Value(t.Elem(), item, path, bad)
+		}
+		return out
+	case *arrow.MapType:
+		if !IsFlattened(t) {
+			break
+		}
+		if items, ok := v.([]interface{}); ok && len(items) == 1 {
+			return c.Value(dt, items[0], path, bad)
+		}
+		entries, ok := flattenedEntries(v)
+		if !ok {
+			*bad = append(*bad, path)
+			return nil
+		}
+		for _, entry := range entries {
+			if c.Sanitize == 0 {
+				break
+			}
+			kv := entry.([]interface{})
+			kv[0], kv[1] = c.text(kv[0].(string), path), c.text(kv[1].(string), path)
+		}
+		return entries
+	case *arrow.StructType:
+		if IsGeoPoint(t) {
+			if lat, lon, ok := GeoPoint(v); ok {
+				return []interface{}{lat, lon}
+			}
+			if items, ok := v.([]interface{}); ok && len(items) == 1 {
+				return c.Value(dt, items[0], path, bad)
+			}
+			*bad = append(*bad, path)
+			return nil
+		}
+		if IsRange(t) {
+			if items, ok := v.([]interface{}); ok && len(items) == 1 {
+				return c.Value(dt, items[0], path, bad)
+			}
+			v = rangeBounds(t, v)
+		}
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			*bad = append(*bad, path)
+			return nil
+		}
+		out := make([]interface{}, len(t.Fields()))
+		for j, f := range t.Fields() {
+			out[j] = c.field(f, obj[f.Name], path+"."+f.Name, bad)
+		}
+		return out
+	}
+
+	if items, ok := v.([]interface{}); ok {
+		if len(items) == 1 {
+			return c.Value(dt, items[0], path, bad)
+		}
+		if len(items) > 1 {
+			*bad = append(*bad, path)
+		}
+		return nil
+	}
+	if cv, ok := c.scalar(dt, v, path); ok {
+		return cv
+	}
+	*bad = append(*bad, path)
+	return nil
+}
+
+// field 함수는 필드 f의 값 v를 바꿉니다. IPStrings로 문자열이 된 ip 필드는 주소로 검사하고,
+// FlattenedAsJSON과 GeoShapesAsJSON으로 문자열이 된 필드는 값을 JSON 텍스트로 쓰고, WKB
+// 컬럼(GeoTypeKey)은 geo 값을 WKB로 바꾸고, format이 있는 date 필드(DateFormatKey)는 그
+// 형식으로 읽고, scaled_float 필드(ScalingFactorKey)는 scaling_factor를 곱해 반올림합니다.
+func (c *Converter) field(f arrow.Field, v interface{}, path string, bad *[]string) interface{} {
+	if isIPString(f) {
+		return c.ipString(f.Type, v, path, bad)
+	}
+	if isFlattenedJSON(f) || isGeoShapeJSON(f) {
+		return jsonText(f.Type, v, path, bad)
+	}
+	if idx := f.Metadata.FindKey(GeoTypeKey); idx >= 0 {
+		return geoWKB(f.Type, f.Metadata.Values()[idx], v, path, bad)
+	}
+	if df, err := FieldDateFormat(f); df != nil && err == nil && timestampLeaf(f.Type) {
+		v = dateValue(df, v)
+	}
+	if factor, ok := FieldScalingFactor(f); ok {
+		v = scaledValue(f.Type, v, factor)
+	}
+	return c.Value(f.Type, v, path, bad)
+}
+
+func (c *Converter) scalar(dt arrow.DataType, v interface{}, path string) (interface{}, bool) {
+	if saturated, over := numberRange(dt, v); over {
+		return c.overflow(dt, v, path, saturated)
+	}
+	switch dt.ID() {
+	case arrow.DICTIONARY:
+		return c.scalar(dt.(*arrow.DictionaryType).ValueType, v, path)
+	case arrow.STRING:
+		switch x := v.(type) {
+		case string:
+			return c.text(x, path), true
+		case json.Number:
+			return x.String(), true
+		case bool:
+			return strconv.FormatBool(x), true
+		}
+	case arrow.BINARY:
+		// binary 필드의 base64 문자열과 변환(--geo-wkb 등)이 []byte로 바꿔 둔 값을 받습니다.
+		if b, ok := Bytes(v); ok {
+			return b, true
+		}
+	case arrow.INT8:
+		if i, ok := Int(v); ok {
+			return int8(i), true
+		}
+	case arrow.INT32:
+		if i, ok := Int(v); ok {
+			return int32(i), true
+		}
+	case arrow.INT64:
+		if i, ok := Int(v); ok {
+			return i, true
+		}
+	case arrow.UINT64:
+		if u, ok := Uint(v); ok {
+			return u, true
+		}
+	case arrow.FLOAT16:
+		if f, ok := Float(v); ok {
+			return float16.New(float32(f)), true
+		}
+	case arrow.FLOAT32:
+		if f, ok := Float(v); ok {
+			return float32(f), true
+		}
+	case arrow.FLOAT64:
+		if f, ok := Float(v); ok {
+			return f, true
+		}
+	case arrow.DECIMAL128:
+		// scaling_factor를 아는 필드의 값은 field가 미리 decimal로 바꿔 둡니다(scaledValue).
+		// 모르면 scaling_factor의 자릿수로 반올림합니다.
+		if n, ok := v.(decimal128.Num); ok {
+			return n, true
+		}
+		t := dt.(*arrow.Decimal128Type)
+		if f, ok := Float(v); ok {
+			if n, err := decimal128.FromFloat64(f, t.Precision, t.Scale); err == nil {
+				return n, true
+			}
+		}
+	case arrow.BOOL:
+		switch x := v.(type) {
+		case bool:
+			return x, true
+		case string:
+			if x == "true" || x == "false" {
+				return x == "true", true
+			}
+		}
+	case arrow.TIMESTAMP:
+		if t, ok := Time(v); ok {
+			return Timestamp(t, dt.(*arrow.TimestampType).Unit), true
+		}
+	case arrow.FIXED_SIZE_BINARY:
+		if !IsIP(dt) {
+			break
+		}
+		if addr, ok := ParseIP(v); ok {
+			b := addr.As16()
+			return b[:], true
+		}
+	}
+	return nil, false
+}
+
+// AppendConverted 함수는 ConvertValue가 만든 값을 builder에 추가합니다. 값은 이미
+// 빌더 타입에 맞게 바뀌어 있으므로 실패하지 않습니다.
+func AppendConverted(builder array.Builder, v interface{}) {
+	if v == nil {
+		appendNull(builder)
+		return
+	}
+	switch b := builder.(type) {
+	case *array.ListBuilder:
+		b.Append(true)
+		for _, item := range v.([]interface{}) {
+			AppendConverted(b.ValueBuilder(), item)
+		}
+	case *array.FixedSizeListBuilder:
+		b.Append(true)
+		for _, item := range v.([]interface{}) {
+			AppendConverted(b.ValueBuilder(), item)
+		}
+	case *array.StructBuilder:
+		b.Append(true)
+		for j, fv := range v.([]interface{}) {
+			AppendConverted(b.FieldBuilder(j), fv)
+		}
+	case *array.MapBuilder:
+		b.Append(true)
+		for _, entry := range v.([]interface{}) {
+			kv := entry.([]interface{})
+			AppendConverted(b.KeyBuilder(), kv[0])
+			AppendConverted(b.ItemBuilder(), kv[1])
+		}
+	case *array.StringBuilder:
+		b.Append(v.(string))
+	case *array.BinaryDictionaryBuilder:
+		b.AppendString(v.(string))
+	case *array.BinaryBuilder:
+		b.Append(v.([]byte))
+	case *array.FixedSizeBinaryBuilder:
+		b.Append(v.([]byte))
+	case *array.Int8Builder:
+		b.Append(v.(int8))
+	case *array.Int32Builder:
+		b.Append(v.(int32))
+	case *array.Int64Builder:
+		b.Append(v.(int64))
+	case *array.Uint64Builder:
+		b.Append(v.(uint64))
+	case *array.Float16Builder:
+		b.Append(v.(float16.Num))
+	case *array.Float32Builder:
+		b.Append(v.(float32))
+	case *array.Float64Builder:
+		b.Append(v.(float64))
+	case *array.Decimal128Builder:
+		b.Append(v.(decimal128.Num))
+	case *array.BooleanBuilder:
+		b.Append(v.(bool))
+	case *array.TimestampBuilder:
+		b.Append(v.(arrow.Timestamp))
+	default:
+		builder.AppendNull()
+	}
+}
+
+// appendNull 함수는 builder에 null을 추가합니다. fixed-size list는 null이어도 자식 배열에서
+// 길이만큼의 자리를 차지해야 하는데, 빌더의 AppendNull은 그 자리를 채우지 않아 자식 배열이
+// 짧아지므로 여기서 채웁니다.
+func appendNull(builder array.Builder) {
+	builder.AppendNull()
+	padNull(builder)
+}
+
+// padNull 함수는 방금 null을 추가한 builder와 그 구조체 필드 아래의 fixed-size list에 자식
+// 자리를 null로 채웁니다. 구조체의 AppendNull은 필드마다 AppendNull을 부르므로 필드도
+// 살펴봅니다.
+func padNull(builder array.Builder) {
+	switch b := builder.(type) {
+	case *array.FixedSizeListBuilder:
+		for i := int32(0); i < b.Type().(*arrow.FixedSizeListType).Len(); i++ {
+			appendNull(b.ValueBuilder())
+		}
+	case *array.StructBuilder:
+		for j := 0; j < b.NumField(); j++ {
+			padNull(b.FieldBuilder(j))
+		}
+	}
+}
+
+// Float 함수는 숫자나 숫자 문자열을 float64로 읽습니다.
+func Float(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case json.Number:
+		f, err := x.Float64()
+		return f, err == nil
+	case float64:
+		return x, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// Int 함수는 정수 값을 읽습니다. 소수는 Elasticsearch처럼 소수점 아래를 버립니다.
+func Int(v interface{}) (int64, bool) {
+	if n, ok := v.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return i, true
+		}
+	}
+	f, ok := Float(v)
+	if !ok || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, false
+	}
+	return int64(f), true
+}
+
+// Uint 함수는 unsigned_long 값을 읽습니다. math.MaxInt64보다 큰 값도 float64를 거치지 않고
+// 정확하게 읽으며, 소수는 Int처럼 소수점 아래를 버립니다. 음수와 2^64 이상은 false입니다.
+func Uint(v interface{}) (uint64, bool) {
+	var s string
+	switch x := v.(type) {
+	case json.Number:
+		s = x.String()
+	case string:
+		s = strings.TrimSpace(x)
+	case float64:
+		if x <= -1 || x >= math.MaxUint64 || math.IsNaN(x) {
+			return 0, false
+		}
+		return uint64(math.Max(x, 0)), true
+	default:
+		return 0, false
+	}
+	if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return u, true
+	}
+	// 1.5나 1e19처럼 정수로 바로 읽을 수 없는 값은 충분한 정밀도로 읽어 자릅니다.
+	f, _, err := big.ParseFloat(s, 10, 128, big.ToZero)
+	if err != nil || f.IsInf() {
+		return 0, false
+	}
+	i, _ := f.Int(nil)
+	if !i.IsUint64() {
+		return 0, false
+	}
+	return i.Uint64(), true
+}
+
+// Bytes 함수는 binary 필드의 값을 읽습니다. _source의 값은 base64 문자열이며,
+// Elasticsearch처럼 줄바꿈이 섞이거나 끝의 =가 없는 값도 받습니다. 이미 디코딩한 []byte는
+// 그대로 받습니다.
+func Bytes(v interface{}) ([]byte, bool) {
+	switch x := v.(type) {
+	case []byte:
+		return x, true
+	case string:
+		s := strings.NewReplacer("\n", "", "\r", "").Replace(x)
+		if b, err := base64.StdEncoding.DecodeString(s); err == nil {
+			return b, true
+		}
+		if b, err := base64.RawStdEncoding.DecodeString(s); err == nil {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+// Time 함수는 기본 형식 strict_date_optional_time||epoch_millis로 날짜 문자열이나 epoch
+// 밀리초 숫자를 시각으로 읽습니다. 이미 읽은 time.Time은 그대로 받습니다.
+func Time(v interface{}) (time.Time, bool) {
+	if t, ok := v.(time.Time); ok {
+		return t, true
+	}
+	if s, ok := v.(string); ok {
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, true
+			}
+		}
+	}
+	ms, ok := Float(v)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(0).Add(time.Duration(ms * float64(time.Millisecond))).UTC(), true
+}
+
+// Timestamp 함수는 시각을 단위에 맞는 Arrow timestamp 값으로 바꿉니다.
+func Timestamp(t time.Time, unit arrow.TimeUnit) arrow.Timestamp {
+	switch unit {
+	case arrow.Second:
+		return arrow.Timestamp(t.Unix())
+	case arrow.Millisecond:
+		return arrow.Timestamp(t.UnixMilli())
+	case arrow.Microsecond:
+		return arrow.Timestamp(t.UnixMicro())
+	default:
+		return arrow.Timestamp(t.UnixNano())
+	}
+}
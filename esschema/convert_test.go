@@ -0,0 +1,156 @@
+package esschema
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/float16"
+)
+
+func TestConvertDocumentReportsUnconvertibleFields(t *testing.T) {
+	fields := []arrow.Field{
+		{Name: "count", Type: arrow.PrimitiveTypes.Int32},
+		{Name: "user", Type: arrow.StructOf(arrow.Field{Name: "age", Type: arrow.PrimitiveTypes.Int64})},
+		{Name: "tags", Type: arrow.ListOf(arrow.BinaryTypes.String)},
+	}
+	doc := map[string]interface{}{
+		"count": json.Number("12"),
+		"user":  map[string]interface{}{"age": "old"},
+		"tags":  []interface{}{"a", map[string]interface{}{}, "b", []interface{}{}},
+	}
+	values := make([]interface{}, len(fields))
+	bad, err := ConvertDocument(fields, doc, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"user.age", "tags"}; !reflect.DeepEqual(bad, want) {
+		t.Errorf("bad = %v, want %v", bad, want)
+	}
+	want := []interface{}{int32(12), []interface{}{nil}, []interface{}{"a", nil, "b", nil}}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("values = %#v, want %#v", values, want)
+	}
+}
+
+func TestJSONScalars(t *testing.T) {
+	if i, ok := Int(json.Number("42")); !ok || i != 42 {
+		t.Errorf("Int(42) = %d, %v", i, ok)
+	}
+	if i, ok := Int("12.9"); !ok || i != 12 {
+		t.Errorf("Int(\"12.9\") = %d, %v; want 12 like Elasticsearch", i, ok)
+	}
+	if _, ok := Float("twelve"); ok {
+		t.Error("Float(\"twelve\") succeeded")
+	}
+	want := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	for _, v := range []interface{}{"2024-05-01", "2024-05-01T00:00:00Z", json.Number("1714521600000")} {
+		if got, ok := Time(v); !ok || !got.Equal(want) {
+			t.Errorf("Time(%v) = %v, %v", v, got, ok)
+		}
+	}
+	if ts := Timestamp(want, arrow.Millisecond); ts != 1714521600000 {
+		t.Errorf("Timestamp = %d", ts)
+	}
+}
+
+func TestConvertHalfFloat(t *testing.T) {
+	dt := arrow.FixedWidthTypes.Float16
+	var bad []string
+	if v, ok := ConvertValue(dt, json.Number("1.5"), "rating", &bad).(float16.Num); !ok || v.Float32() != 1.5 {
+		t.Errorf("1.5 = %v", v)
+	}
+	c := Converter{Overflow: OverflowSaturate}
+	if v, ok := c.Value(dt, 70000.0, "rating", &bad).(float16.Num); !ok || v.Float32() != 65504 || c.Overflows["rating"] != 1 {
+		t.Errorf("70000 = %v, %v", v, c.Overflows)
+	}
+	if len(bad) != 0 {
+		t.Errorf("bad = %v", bad)
+	}
+	if got := WidenNumber(dt, 70000.0); got.ID() != arrow.FLOAT32 {
+		t.Errorf("WidenNumber = %v, want float32", got)
+	}
+}
+
+func TestUint(t *testing.T) {
+	for _, tc := range []struct {
+		in   interface{}
+		want uint64
+		ok   bool
+	}{
+		{json.Number("18446744073709551615"), math.MaxUint64, true},
+		{json.Number("9223372036854775808"), 1 << 63, true},
+		{" 18446744073709551614 ", math.MaxUint64 - 1, true},
+		{json.Number("12.9"), 12, true},
+		{json.Number("1e3"), 1000, true},
+		{42.0, 42, true},
+		{json.Number("18446744073709551616"), 0, false},
+		{json.Number("-1"), 0, false},
+		{"abc", 0, false},
+		{true, 0, false},
+	} {
+		if got, ok := Uint(tc.in); got != tc.want || ok != tc.ok {
+			t.Errorf("Uint(%#v) = %d, %v, want %d, %v", tc.in, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestConvertUnsignedLong(t *testing.T) {
+	dt := arrow.PrimitiveTypes.Uint64
+	var bad []string
+	if v := ConvertValue(dt, json.Number("18446744073709551615"), "bytes_sent", &bad); v != uint64(math.MaxUint64) {
+		t.Errorf("max = %v", v)
+	}
+	c := Converter{Overflow: OverflowSaturate}
+	if v := c.Value(dt, json.Number("-3"), "bytes_sent", &bad); v != uint64(0) || c.Overflows["bytes_sent"] != 1 {
+		t.Errorf("-3 = %v, %v", v, c.Overflows)
+	}
+	if len(bad) != 0 {
+		t.Errorf("bad = %v", bad)
+	}
+	if got := WidenNumber(dt, json.Number("1e20")); got.ID() != arrow.FLOAT64 {
+		t.Errorf("WidenNumber = %v, want float64", got)
+	}
+}
+
+func TestBytes(t *testing.T) {
+	for _, tc := range []struct {
+		in   interface{}
+		want string
+		ok   bool
+	}{
+		{"U29tZSBiaW5hcnkgYmxvYg==", "Some binary blob", true},
+		{"U29tZSBiaW5h\ncnkgYmxvYg==", "Some binary blob", true},
+		{"U29tZSBiaW5hcnkgYmxvYg", "Some binary blob", true},
+		{[]byte{0, 1}, "\x00\x01", true},
+		{"", "", true},
+		{"not base64!", "", false},
+		{json.Number("12"), "", false},
+	} {
+		if got, ok := Bytes(tc.in); string(got) != tc.want || ok != tc.ok {
+			t.Errorf("Bytes(%#v) = %q, %v, want %q, %v", tc.in, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestConvertBinary(t *testing.T) {
+	fields := []arrow.Field{
+		{Name: "thumbnail", Type: arrow.BinaryTypes.Binary},
+		{Name: "location", Type: arrow.BinaryTypes.Binary, Metadata: arrow.NewMetadata([]string{GeoTypeKey}, []string{"geo_point"})},
+	}
+	values := make([]interface{}, len(fields))
+	bad, err := ConvertDocument(fields, map[string]interface{}{"thumbnail": "AAEC", "location": "AAEC"}, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, ok := values[0].([]byte); !ok || string(b) != "\x00\x01\x02" {
+		t.Errorf("thumbnail = %#v", values[0])
+	}
+	// WKB 컬럼의 문자열은 base64처럼 보여도 읽지 못한 geo 값입니다.
+	if values[1] != nil || len(bad) != 1 || bad[0] != "location" {
+		t.Errorf("location = %#v, bad = %v", values[1], bad)
+	}
+}
@@ -0,0 +1,222 @@
+package esschema
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+// DateFormatKey는 매핑에 format이 있는 date와 date_nanos 필드의 Arrow 필드 메타데이터
+// 키입니다. 값은 매핑의 format 그대로입니다.
+const DateFormatKey = "es_schema.date_format"
+
+// DateFormat은 date 필드의 format(||로 나눈 형식 목록)입니다. 값은 Elasticsearch처럼 앞의
+// 형식부터 차례로 맞춰 보고 처음 맞는 형식으로 읽습니다. nil은 기본 형식
+// strict_date_optional_time||epoch_millis입니다.
+type DateFormat struct {
+	parsers []func(v interface{}) (time.Time, bool)
+}
+
+// builtinDateFormats는 이름 있는 Elasticsearch 형식의 Java 패턴입니다. strict_ 접두사가
+// 붙은 이름도 같은 패턴입니다.
+var builtinDateFormats = map[string]string{
+	"date":                             "yyyy-MM-dd",
+	"year_month_day":                   "yyyy-MM-dd",
+	"year_month":                       "yyyy-MM",
+	"year":                             "yyyy",
+	"basic_date":                       "yyyyMMdd",
+	"date_hour":                        "yyyy-MM-dd'T'HH",
+	"date_hour_minute":                 "yyyy-MM-dd'T'HH:mm",
+	"date_hour_minute_second":          "yyyy-MM-dd'T'HH:mm:ss",
+	"date_hour_minute_second_millis":   "yyyy-MM-dd'T'HH:mm:ss.SSS",
+	"date_hour_minute_second_fraction": "yyyy-MM-dd'T'HH:mm:ss.SSSSSSSSS",
+	"date_time":                        "yyyy-MM-dd'T'HH:mm:ss.SSSXXX",
+	"date_time_no_millis":              "yyyy-MM-dd'T'HH:mm:ssXXX",
+	"basic_date_time":                  "yyyyMMdd'T'HHmmss.SSSXX",
+	"basic_date_time_no_millis":        "yyyyMMdd'T'HHmmssXX",
+}
+
+// dateFormats는 ParseDateFormat 결과를 format 문자열별로 기억합니다. 문서마다 같은 매핑의
+// 형식을 다시 읽지 않도록 합니다.
+var dateFormats sync.Map
+
+// ParseDateFormat 함수는 매핑의 format을 읽습니다. epoch_millis, epoch_second,
+// (strict_)date_optional_time, 이름 있는 형식과 yyyy-MM-dd HH:mm:ss 같은 Java 패턴을 받고,
+// 읽을 수 없는 패턴은 오류입니다.
+func ParseDateFormat(format string) (*DateFormat, error) {
+	if cached, ok := dateFormats.Load(format); ok {
+		return cached.(*DateFormat), nil
+	}
+	f := &DateFormat{}
+	for _, name := range strings.Split(format, "||") {
+		name = strings.TrimSpace(name)
+		switch base := strings.TrimPrefix(name, "strict_"); {
+		case name == "epoch_millis":
+			f.parsers = append(f.parsers, epochParser(time.Millisecond))
+		case name == "epoch_second":
+			f.parsers = append(f.parsers, epochParser(time.Second))
+		case base == "date_optional_time" || base == "date_optional_time_nanos":
+			f.parsers = append(f.parsers, layoutParser(dateLayouts...))
+		case builtinDateFormats[base] != "":
+			layout, _ := javaLayout(builtinDateFormats[base])
+			f.parsers = append(f.parsers, layoutParser(layout))
+		default:
+			layout, err := javaLayout(name)
+			if err != nil {
+				return nil, fmt.Errorf("date format %q: %w", name, err)
+			}
+			f.parsers = append(f.parsers, layoutParser(layout))
+		}
+	}
+	dateFormats.Store(format, f)
+	return f, nil
+}
+
+// dateValue 함수는 date 필드의 값 v(배열이면 원소마다)를 df로 읽은 time.Time으로 바꿉니다.
+// 읽지 못한 값은 Time이 받지 않는 unparsedDate가 되어 바꿀 수 없는 값으로 남습니다.
+func dateValue(df *DateFormat, v interface{}) interface{} {
+	switch x := v.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, item := range x {
+			out[i] = dateValue(df, item)
+		}
+		return out
+	}
+	if t, ok := df.Time(v); ok {
+		return t
+	}
+	return unparsedDate{v}
+}
+
+// unparsedDate는 필드의 형식으로 읽지 못한 date 값입니다.
+type unparsedDate struct{ value interface{} }
+
+// FieldDateFormat 함수는 필드 메타데이터의 DateFormatKey로 f의 형식을 반환합니다. 형식이
+// 없으면 nil(기본 형식)입니다.
+func FieldDateFormat(f arrow.Field) (*DateFormat, error) {
+	i := f.Metadata.FindKey(DateFormatKey)
+	if i < 0 {
+		return nil, nil
+	}
+	return ParseDateFormat(f.Metadata.Values()[i])
+}
+
+// Time 함수는 v를 f의 형식으로 읽습니다.
+func (f *DateFormat) Time(v interface{}) (time.Time, bool) {
+	if t, ok := v.(time.Time); ok {
+		return t, true
+	}
+	if f == nil {
+		return Time(v)
+	}
+	for _, parse := range f.parsers {
+		if t, ok := parse(v); ok {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// epochParser 함수는 숫자나 숫자 문자열을 1970년부터의 unit 수로 읽습니다. 소수도 받습니다.
+func epochParser(unit time.Duration) func(v interface{}) (time.Time, bool) {
+	return func(v interface{}) (time.Time, bool) {
+		n, ok := Float(v)
+		if !ok {
+			return time.Time{}, false
+		}
+		// 정수 부분과 소수 부분을 따로 더해 큰 epoch 값의 float64 반올림 오차를 피합니다.
+		whole, frac := math.Modf(n)
+		return time.Unix(0, 0).Add(time.Duration(whole)*unit + time.Duration(math.Round(frac*float64(unit)))).UTC(), true
+	}
+}
+
+// layoutParser 함수는 문자열을 layouts 중 하나로 읽습니다. 시간대가 없는 값은 UTC입니다.
+func layoutParser(layouts ...string) func(v interface{}) (time.Time, bool) {
+	return func(v interface{}) (time.Time, bool) {
+		s, ok := v.(string)
+		if !ok {
+			return time.Time{}, false
+		}
+		for _, layout := range layouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, true
+			}
+		}
+		return time.Time{}, false
+	}
+}
+
+// javaLayouts는 Java DateTimeFormatter 패턴 글자의 반복에 해당하는 Go 레이아웃입니다.
+var javaLayouts = map[string]string{
+	"yyyy": "2006", "uuuu": "2006", "yy": "06", "uu": "06",
+	"MMMM": "January", "MMM": "Jan", "MM": "01", "M": "1",
+	"dd": "02", "d": "2",
+	"EEEE": "Monday", "EEE": "Mon",
+	"HH": "15", "H": "15", "hh": "03", "h": "3",
+	"mm": "04", "m": "4", "ss": "05", "s": "5",
+	"a":   "PM",
+	"XXX": "Z07:00", "XX": "Z0700", "X": "Z07",
+	"xxx": "-07:00", "xx": "-0700", "ZZ": "-07:00", "Z": "-0700", "z": "MST",
+}
+
+// javaLayout 함수는 Elasticsearch의 format에 쓰는 Java 패턴을 time.Parse 레이아웃으로
+// 바꿉니다. 'T'처럼 따옴표로 감싼 글자는 그대로 쓰고, 소수 초(SSS)는 바로 앞에 . 이나 ,가
+// 있어야 합니다.
+func javaLayout(pattern string) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < len(pattern); {
+		c := pattern[i]
+		switch {
+		case c == '\'':
+			end := strings.IndexByte(pattern[i+1:], '\'')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated quote")
+			}
+			sb.WriteString(pattern[i+1 : i+1+end])
+			i += end + 2
+		case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i
+			for j < len(pattern) && pattern[j] == c {
+				j++
+			}
+			run := pattern[i:j]
+			i = j
+			if c == 'S' {
+				// 9는 Elasticsearch처럼 자릿수가 다르거나 없는 소수 초도 받습니다.
+				out := sb.String()
+				if !strings.HasSuffix(out, ".") && !strings.HasSuffix(out, ",") {
+					return "", fmt.Errorf("fraction of a second %s does not follow . or ,", run)
+				}
+				sb.WriteString(strings.Repeat("9", len(run)))
+				continue
+			}
+			layout, ok := javaLayouts[run]
+			if !ok {
+				return "", fmt.Errorf("unsupported pattern letters %s", run)
+			}
+			sb.WriteString(layout)
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+	return sb.String(), nil
+}
+
+// timestampLeaf 함수는 dt가 timestamp이거나 timestamp의 리스트인지 알려 줍니다.
+func timestampLeaf(dt arrow.DataType) bool {
+	switch t := dt.(type) {
+	case *arrow.ListType:
+		return timestampLeaf(t.Elem())
+	case *arrow.FixedSizeListType:
+		return timestampLeaf(t.Elem())
+	}
+	return dt.ID() == arrow.TIMESTAMP
+}
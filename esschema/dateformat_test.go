@@ -0,0 +1,74 @@
+package esschema
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+func TestParseDateFormat(t *testing.T) {
+	for _, tc := range []struct {
+		format string
+		in     interface{}
+		want   time.Time
+		ok     bool
+	}{
+		{"yyyy-MM-dd HH:mm:ss||epoch_second", "2024-05-01 12:30:45", time.Date(2024, 5, 1, 12, 30, 45, 0, time.UTC), true},
+		{"yyyy-MM-dd HH:mm:ss||epoch_second", json.Number("1714566645"), time.Date(2024, 5, 1, 12, 30, 45, 0, time.UTC), true},
+		{"yyyy-MM-dd HH:mm:ss||epoch_second", "2024-05-01T12:30:45Z", time.Time{}, false},
+		{"dd/MMM/yyyy:HH:mm:ss Z", "01/May/2024:21:30:45 +0900", time.Date(2024, 5, 1, 12, 30, 45, 0, time.UTC), true},
+		{"yyyy-MM-dd'T'HH:mm:ss.SSS", "2024-05-01T12:30:45.250", time.Date(2024, 5, 1, 12, 30, 45, 250e6, time.UTC), true},
+		{"strict_date_time", "2024-05-01T12:30:45.250+02:00", time.Date(2024, 5, 1, 10, 30, 45, 250e6, time.UTC), true},
+		{"basic_date", "20240501", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), true},
+		{"epoch_millis", 1714566645250.0, time.Date(2024, 5, 1, 12, 30, 45, 250e6, time.UTC), true},
+		{"strict_date_optional_time||epoch_millis", "2024-05-01", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), true},
+		{"date", 1714566645250.0, time.Time{}, false},
+	} {
+		df, err := ParseDateFormat(tc.format)
+		if err != nil {
+			t.Errorf("%s: %v", tc.format, err)
+			continue
+		}
+		got, ok := df.Time(tc.in)
+		if ok != tc.ok || !got.Equal(tc.want) {
+			t.Errorf("%s: Time(%v) = %v, %v, want %v, %v", tc.format, tc.in, got, ok, tc.want, tc.ok)
+		}
+	}
+	for _, format := range []string{"yyyy-ww", "HH:mm:ssSSS", "yyyy-MM-dd'T"} {
+		if _, err := ParseDateFormat(format); err == nil {
+			t.Errorf("%s: no error", format)
+		}
+	}
+}
+
+func TestConvertDateFormat(t *testing.T) {
+	fields, err := Fields(map[string]interface{}{
+		"logged": map[string]interface{}{"type": "date", "format": "yyyy/MM/dd HH:mm||epoch_second"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make([]interface{}, 1)
+	doc := map[string]interface{}{"logged": []interface{}{"2024/05/01 12:30", json.Number("0"), "2024-05-01"}}
+	bad, err := ConvertDocument(fields, doc, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 값이 여러 개인 배열은 timestamp 컬럼에 담을 수 없습니다.
+	if len(bad) != 1 || values[0] != nil {
+		t.Errorf("array: bad = %v, value = %v", bad, values[0])
+	}
+	want := arrow.Timestamp(time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC).UnixNano())
+	for _, v := range []interface{}{"2024/05/01 12:30", []interface{}{json.Number("1714566600")}} {
+		doc["logged"] = v
+		if bad, _ := ConvertDocument(fields, doc, values); len(bad) != 0 || values[0] != want {
+			t.Errorf("%v: bad = %v, value = %v, want %v", v, bad, values[0], want)
+		}
+	}
+	doc["logged"] = "2024-05-01T12:30:00Z"
+	if bad, _ := ConvertDocument(fields, doc, values); len(bad) != 1 {
+		t.Errorf("RFC 3339 value outside the format: bad = %v", bad)
+	}
+}
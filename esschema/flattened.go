@@ -0,0 +1,138 @@
+package esschema
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+// FlattenedJSONKey는 FlattenedAsJSON으로 문자열 컬럼이 된 flattened 필드에 붙는 필드
+// 메타데이터 키입니다. 이 필드의 값은 객체를 JSON 텍스트로 씁니다.
+const FlattenedJSONKey = "es_schema.flattened"
+
+// FlattenedType 함수는 flattened 필드의 Arrow 타입을 반환합니다. flattened 객체의 키는
+// 문서마다 다르므로 구조체가 아니라 키와 값이 모두 문자열인 맵입니다. Elasticsearch처럼
+// 안쪽 객체의 키는 점으로 이은 경로(labels.env)가 되고, 잎의 값은 keyword처럼 문자열이
+// 됩니다.
+func FlattenedType() arrow.DataType {
+	return arrow.MapOf(arrow.BinaryTypes.String, arrow.BinaryTypes.String)
+}
+
+// IsFlattened 함수는 dt가 FlattenedType인지 알려 줍니다.
+func IsFlattened(dt arrow.DataType) bool {
+	t, ok := dt.(*arrow.MapType)
+	return ok && t.KeyType().ID() == arrow.STRING && t.ItemType().ID() == arrow.STRING
+}
+
+// FlattenedAsJSON 함수는 fields 안(구조체와 리스트 안 포함)의 FlattenedType을
+// FlattenedJSONKey를 붙인 문자열 필드로 바꾼 필드 목록을 반환합니다. 맵 컬럼을 읽지 못하는
+// 소비자를 위한 것으로, 값은 객체 그대로의 JSON 텍스트가 됩니다.
+func FlattenedAsJSON(fields []arrow.Field) []arrow.Field {
+	out := make([]arrow.Field, len(fields))
+	for i, f := range fields {
+		out[i] = flattenedJSONField(f)
+	}
+	return out
+}
+
+func flattenedJSONField(f arrow.Field) arrow.Field {
+	switch t := f.Type.(type) {
+	case *arrow.MapType:
+		if IsFlattened(t) {
+			keys := append([]string{FlattenedJSONKey}, f.Metadata.Keys()...)
+			values := append([]string{"true"}, f.Metadata.Values()...)
+			f.Type = arrow.BinaryTypes.String
+			f.Metadata = arrow.NewMetadata(keys, values)
+		}
+	case *arrow.ListType:
+		elem := flattenedJSONField(arrow.Field{Name: f.Name, Type: t.Elem(), Metadata: f.Metadata})
+		f.Type, f.Metadata = arrow.ListOf(elem.Type), elem.Metadata
+	case *arrow.StructType:
+		f.Type = arrow.StructOf(FlattenedAsJSON(t.Fields())...)
+	}
+	return f
+}
+
+// isFlattenedJSON 함수는 f가 FlattenedAsJSON으로 만든 필드인지 알려 줍니다.
+func isFlattenedJSON(f arrow.Field) bool {
+	return f.Metadata.FindKey(FlattenedJSONKey) >= 0
+}
+
+// jsonText 함수는 FlattenedAsJSON이나 GeoShapesAsJSON으로 만든 필드의 값 v를 JSON
+// 텍스트로 바꿉니다. 리스트 필드는 원소마다 바꿉니다.
+func jsonText(dt arrow.DataType, v interface{}, path string, bad *[]string) interface{} {
+	if v == nil {
+		return nil
+	}
+	if lt, ok := dt.(*arrow.ListType); ok {
+		items, ok := v.([]interface{})
+		if !ok {
+			items = []interface{}{v}
+		}
+		out := make([]interface{}, len(items))
+		for i, item := range items {
+			out[i] = jsonText(lt.Elem(), item, path, bad)
+		}
+		return out
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		*bad = append(*bad, path)
+		return nil
+	}
+	return string(data)
+}
+
+// flattenedEntries 함수는 flattened 객체 v를 키 순서의 [키, 값] 쌍 목록으로 바꿉니다.
+// 안쪽 객체는 점으로 이은 키로 펼치고, null인 잎은 뺍니다. 배열은 키 하나에 값 하나가
+// 되도록 JSON 텍스트로 씁니다. 객체가 아니면 false입니다.
+func flattenedEntries(v interface{}) ([]interface{}, bool) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	leaves := make(map[string]string)
+	flattenInto(leaves, "", obj)
+	keys := make([]string, 0, len(leaves))
+	for k := range leaves {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]interface{}, len(keys))
+	for i, k := range keys {
+		out[i] = []interface{}{k, leaves[k]}
+	}
+	return out, true
+}
+
+func flattenInto(leaves map[string]string, prefix string, obj map[string]interface{}) {
+	for k, v := range obj {
+		key := prefix + k
+		switch x := v.(type) {
+		case nil:
+		case map[string]interface{}:
+			flattenInto(leaves, key+".", x)
+		default:
+			leaves[key] = flattenedLeaf(x)
+		}
+	}
+}
+
+// flattenedLeaf 함수는 flattened 객체의 잎 값을 문자열로 씁니다. 숫자와 boolean은
+// Elasticsearch가 keyword로 색인하는 텍스트와 같습니다.
+func flattenedLeaf(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case json.Number:
+		return x.String()
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -0,0 +1,63 @@
+package esschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+func TestFlattenedEntries(t *testing.T) {
+	got, ok := flattenedEntries(map[string]interface{}{
+		"env":     "prod",
+		"release": map[string]interface{}{"major": json.Number("2"), "canary": true, "note": nil},
+		"zones":   []interface{}{"a", "b"},
+		"ratio":   0.25,
+	})
+	want := []interface{}{
+		[]interface{}{"env", "prod"},
+		[]interface{}{"ratio", "0.25"},
+		[]interface{}{"release.canary", "true"},
+		[]interface{}{"release.major", "2"},
+		[]interface{}{"zones", `["a","b"]`},
+	}
+	if !ok || !reflect.DeepEqual(got, want) {
+		t.Errorf("entries = %v, %v; want %v", got, ok, want)
+	}
+	if _, ok := flattenedEntries("prod"); ok {
+		t.Error("a string is not a flattened object")
+	}
+}
+
+func TestConvertFlattened(t *testing.T) {
+	fields := []arrow.Field{
+		{Name: "labels", Type: FlattenedType()},
+		{Name: "bad", Type: FlattenedType()},
+	}
+	doc := map[string]interface{}{
+		"labels": []interface{}{map[string]interface{}{"team": map[string]interface{}{"name": "search"}}},
+		"bad":    "not an object",
+	}
+	values := make([]interface{}, len(fields))
+	bad, err := ConvertDocument(fields, doc, values)
+	if err != nil || !reflect.DeepEqual(bad, []string{"bad"}) {
+		t.Fatalf("bad = %v, %v", bad, err)
+	}
+	if want := []interface{}{[]interface{}{"team.name", "search"}}; !reflect.DeepEqual(values[0], want) {
+		t.Errorf("labels = %v, want %v", values[0], want)
+	}
+
+	js := FlattenedAsJSON(fields)
+	if js[0].Type.ID() != arrow.STRING || !isFlattenedJSON(js[0]) {
+		t.Fatalf("FlattenedAsJSON = %v", js)
+	}
+	doc["labels"] = map[string]interface{}{"team": map[string]interface{}{"name": "search"}, "tier": json.Number("1")}
+	if bad, _ = ConvertDocument(js, doc, values); len(bad) != 0 {
+		t.Errorf("json mode bad = %v", bad)
+	}
+	want := []interface{}{`{"team":{"name":"search"},"tier":1}`, `"not an object"`}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("json mode values = %v, want %v", values, want)
+	}
+}
@@ -0,0 +1,150 @@
+package esschema
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// GeoTypeKey는 geo 값을 WKB로 바꿔 담는 바이너리 컬럼에 원래 Elasticsearch 타입을 적는 필드
+// 메타데이터 키입니다. 이 컬럼의 문자열은 읽지 못한 geo 값이므로 base64로 읽지 않습니다.
+const GeoTypeKey = "es_schema.geo_type"
+
+// GeoPointType 함수는 geo_point 필드의 Arrow 타입 struct<lat: float64, lon: float64>를
+// 반환합니다.
+func GeoPointType() arrow.DataType {
+	return arrow.StructOf(
+		arrow.Field{Name: "lat", Type: arrow.PrimitiveTypes.Float64},
+		arrow.Field{Name: "lon", Type: arrow.PrimitiveTypes.Float64},
+	)
+}
+
+// IsGeoPoint 함수는 dt가 GeoPointType처럼 float64 lat과 lon 두 필드의 구조체인지 알려
+// 줍니다. 그런 구조체는 Elasticsearch가 geo_point로 받는 모든 형식의 값을 받습니다.
+func IsGeoPoint(dt arrow.DataType) bool {
+	st, ok := dt.(*arrow.StructType)
+	if !ok || len(st.Fields()) != 2 {
+		return false
+	}
+	for i, name := range []string{"lat", "lon"} {
+		if f := st.Field(i); f.Name != name || f.Type.ID() != arrow.FLOAT64 {
+			return false
+		}
+	}
+	return true
+}
+
+// GeoPoint 함수는 Elasticsearch가 받는 geo_point 값 하나(lat/lon 객체, GeoJSON Point,
+// [lon, lat] 배열, "lat,lon" 문자열, WKT POINT, geohash)의 위도와 경도를 읽습니다. 배열은
+// 숫자 둘이나 셋(z는 버립니다)이어야 하며, 점 여러 개의 배열은 받지 않습니다.
+func GeoPoint(v interface{}) (lat, lon float64, ok bool) {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		if t, ok := x["type"].(string); ok {
+			items, _ := x["coordinates"].([]interface{})
+			if !strings.EqualFold(t, "Point") {
+				return 0, 0, false
+			}
+			return GeoPoint(items)
+		}
+		lat, ok1 := Float(x["lat"])
+		lon, ok2 := Float(x["lon"])
+		return lat, lon, ok1 && ok2 && finite(lat, lon)
+	case []interface{}:
+		if len(x) != 2 && len(x) != 3 {
+			return 0, 0, false
+		}
+		var p [2]float64
+		for i := range p {
+			switch n := x[i].(type) {
+			case json.Number:
+				f, err := n.Float64()
+				if err != nil {
+					return 0, 0, false
+				}
+				p[i] = f
+			case float64:
+				p[i] = n
+			default:
+				return 0, 0, false
+			}
+		}
+		return p[1], p[0], finite(p[0], p[1])
+	case string:
+		s := strings.TrimSpace(x)
+		if strings.HasPrefix(strings.ToUpper(s), "POINT") {
+			return wktPoint(s[len("POINT"):])
+		}
+		if parts := strings.Split(s, ","); len(parts) == 2 || len(parts) == 3 {
+			lat, err1 := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+			lon, err2 := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+			return lat, lon, err1 == nil && err2 == nil && finite(lat, lon)
+		}
+		return geohash(s)
+	}
+	return 0, 0, false
+}
+
+// wktPoint 함수는 WKT POINT 뒤의 "(x y)" 또는 "(x y z)"를 읽습니다. POINT EMPTY는 점이
+// 아니므로 받지 않습니다.
+func wktPoint(s string) (lat, lon float64, ok bool) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "(") || !strings.HasSuffix(s, ")") {
+		return 0, 0, false
+	}
+	coords := strings.Fields(s[1 : len(s)-1])
+	if len(coords) != 2 && len(coords) != 3 {
+		return 0, 0, false
+	}
+	lon, err1 := strconv.ParseFloat(coords[0], 64)
+	lat, err2 := strconv.ParseFloat(coords[1], 64)
+	return lat, lon, err1 == nil && err2 == nil && finite(lat, lon)
+}
+
+// geohash 함수는 geohash 셀의 가운데 점을 반환합니다.
+func geohash(s string) (lat, lon float64, ok bool) {
+	if s == "" || len(s) > 12 {
+		return 0, 0, false
+	}
+	lons, lats := [2]float64{-180, 180}, [2]float64{-90, 90}
+	even := true
+	for _, c := range strings.ToLower(s) {
+		idx := strings.IndexRune(geohashAlphabet, c)
+		if idx < 0 {
+			return 0, 0, false
+		}
+		for bit := 4; bit >= 0; bit-- {
+			r := &lats
+			if even {
+				r = &lons
+			}
+			mid := (r[0] + r[1]) / 2
+			if idx&(1<<bit) != 0 {
+				r[0] = mid
+			} else {
+				r[1] = mid
+			}
+			even = !even
+		}
+	}
+	return (lats[0] + lats[1]) / 2, (lons[0] + lons[1]) / 2, true
+}
+
+func finite(fs ...float64) bool {
+	for _, f := range fs {
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func isPoint(v interface{}) bool {
+	_, _, ok := GeoPoint(v)
+	return ok
+}
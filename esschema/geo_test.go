@@ -0,0 +1,60 @@
+package esschema
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+func TestGeoPoint(t *testing.T) {
+	for _, v := range []interface{}{
+		map[string]interface{}{"lat": 41.12, "lon": -71.34},
+		map[string]interface{}{"lat": "41.12", "lon": "-71.34"},
+		map[string]interface{}{"type": "Point", "coordinates": []interface{}{-71.34, 41.12}},
+		[]interface{}{-71.34, 41.12},
+		[]interface{}{-71.34, 41.12, 7.0},
+		"41.12,-71.34",
+		"POINT (-71.34 41.12)",
+		"point(-71.34 41.12 7)",
+	} {
+		if lat, lon, ok := GeoPoint(v); !ok || lat != 41.12 || lon != -71.34 {
+			t.Errorf("GeoPoint(%v) = %v, %v, %v", v, lat, lon, ok)
+		}
+	}
+	if lat, lon, ok := GeoPoint("drm3btev3e86"); !ok || lat < 41.1199 || lat > 41.1201 || lon < -71.3401 || lon > -71.3399 {
+		t.Errorf("geohash = %v, %v, %v; want about 41.12, -71.34", lat, lon, ok)
+	}
+	for _, v := range []interface{}{
+		map[string]interface{}{"lat": 1.0},
+		map[string]interface{}{"type": "LineString", "coordinates": []interface{}{1.0, 2.0}},
+		[]interface{}{[]interface{}{1.0, 2.0}, []interface{}{3.0, 4.0}},
+		"not a point!", "POINT (1)", "POINT EMPTY", true,
+	} {
+		if _, _, ok := GeoPoint(v); ok {
+			t.Errorf("GeoPoint(%v) succeeded, want failure", v)
+		}
+	}
+}
+
+func TestConvertGeoPoint(t *testing.T) {
+	geo := GeoPointType()
+	if !IsGeoPoint(geo) || IsGeoPoint(arrow.StructOf(arrow.Field{Name: "lat", Type: arrow.PrimitiveTypes.Float64})) {
+		t.Fatal("IsGeoPoint does not recognise GeoPointType")
+	}
+	fields := []arrow.Field{{Name: "at", Type: geo}, {Name: "route", Type: arrow.ListOf(geo)}, {Name: "bad", Type: geo}}
+	doc := map[string]interface{}{
+		"at":    []interface{}{[]interface{}{2.0, 1.0}},
+		"route": []interface{}{2.0, 1.0},
+		"bad":   []interface{}{"1,2", "3,4"},
+	}
+	values := make([]interface{}, len(fields))
+	bad, err := ConvertDocument(fields, doc, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{[]interface{}{1.0, 2.0}, []interface{}{[]interface{}{1.0, 2.0}}, nil}
+	if !reflect.DeepEqual(values, want) || !reflect.DeepEqual(bad, []string{"bad"}) {
+		t.Errorf("values = %v, bad = %v; want %v, [bad]", values, bad, want)
+	}
+}
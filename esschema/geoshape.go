@@ -0,0 +1,658 @@
+package esschema
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+// GeoShapeJSONKey는 GeoShapesAsJSON으로 문자열 컬럼이 된 geo_shape 필드에 붙는 필드
+// 메타데이터 키입니다. 이 필드의 값은 GeoJSON 객체나 WKT 문자열을 그대로 JSON 텍스트로
+// 씁니다.
+const GeoShapeJSONKey = "es_schema.geo_shape_json"
+
+// maxGeometryDepth는 GeometryCollection을 몇 겹까지 읽을지 정합니다.
+const maxGeometryDepth = 32
+
+// WKB 지오메트리 타입 코드입니다. 2차원만 쓰고, z 좌표는 버립니다.
+const (
+	WKBPoint              = 1
+	WKBLineString         = 2
+	WKBPolygon            = 3
+	WKBMultiPoint         = 4
+	WKBMultiLineString    = 5
+	WKBMultiPolygon       = 6
+	WKBGeometryCollection = 7
+)
+
+var geometryNames = map[uint32]string{
+	WKBPoint:              "Point",
+	WKBLineString:         "LineString",
+	WKBPolygon:            "Polygon",
+	WKBMultiPoint:         "MultiPoint",
+	WKBMultiLineString:    "MultiLineString",
+	WKBMultiPolygon:       "MultiPolygon",
+	WKBGeometryCollection: "GeometryCollection",
+}
+
+// Geometry는 WKB로 쓰기 전의 지오메트리입니다. 좌표는 [경도, 위도] 순서입니다. Point는
+// Coords에 점 하나(비어 있으면 EMPTY), LineString은 Coords, Polygon은 Rings를 쓰고,
+// Multi 타입과 GeometryCollection은 Parts에 하위 지오메트리를 담습니다.
+type Geometry struct {
+	Kind   uint32
+	Coords [][2]float64
+	Rings  [][][2]float64
+	Parts  []Geometry
+}
+
+// GeoShapesAsJSON 함수는 fields 안(구조체와 리스트 안 포함)의 geo_shape WKB 컬럼을
+// GeoShapeJSONKey를 붙인 문자열 필드로 바꾼 필드 목록을 반환합니다. 값은 _source의
+// GeoJSON 객체나 WKT 문자열 그대로의 JSON 텍스트가 되므로, WKB로 나타낼 수 없는 circle도
+// 잃지 않습니다.
+func GeoShapesAsJSON(fields []arrow.Field) []arrow.Field {
+	out := make([]arrow.Field, len(fields))
+	for i, f := range fields {
+		out[i] = geoShapeJSONField(f)
+	}
+	return out
+}
+
+func geoShapeJSONField(f arrow.Field) arrow.Field {
+	if idx := f.Metadata.FindKey(GeoTypeKey); idx >= 0 && f.Metadata.Values()[idx] == "geo_shape" {
+		keys, values := []string{GeoShapeJSONKey}, []string{"true"}
+		for i, k := range f.Metadata.Keys() {
+			if i != idx {
+				keys, values = append(keys, k), append(values, f.Metadata.Values()[i])
+			}
+		}
+		f.Metadata = arrow.NewMetadata(keys, values)
+		if _, ok := f.Type.(*arrow.ListType); ok {
+			f.Type = arrow.ListOf(arrow.BinaryTypes.String)
+		} else {
+			f.Type = arrow.BinaryTypes.String
+		}
+		return f
+	}
+	switch t := f.Type.(type) {
+	case *arrow.ListType:
+		if st, ok := t.Elem().(*arrow.StructType); ok {
+			f.Type = arrow.ListOf(arrow.StructOf(GeoShapesAsJSON(st.Fields())...))
+		}
+	case *arrow.StructType:
+		f.Type = arrow.StructOf(GeoShapesAsJSON(t.Fields())...)
+	}
+	return f
+}
+
+// isGeoShapeJSON 함수는 f가 GeoShapesAsJSON으로 만든 필드인지 알려 줍니다.
+func isGeoShapeJSON(f arrow.Field) bool {
+	return f.Metadata.FindKey(GeoShapeJSONKey) >= 0
+}
+
+// geoWKB 함수는 WKB 컬럼(GeoTypeKey)의 값 v를 WKB로 바꿉니다. geoType이 geo_point이면
+// ParseGeoPoints가, 아니면 ParseGeoShape가 읽는 값을 받고, 여러 값은 하나의 Multi
+// 지오메트리나 GeometryCollection이 됩니다. 변환(--geo-wkb)이 이미 WKB로 바꿔 둔 []byte는
+// 그대로 씁니다. 리스트 필드는 원소마다 바꿉니다.
+func geoWKB(dt arrow.DataType, geoType string, v interface{}, path string, bad *[]string) interface{} {
+	if v == nil {
+		return nil
+	}
+	if lt, ok := dt.(*arrow.ListType); ok {
+		items, ok := v.([]interface{})
+		if !ok {
+			items = []interface{}{v}
+		}
+		out := make([]interface{}, len(items))
+		for i, item := range items {
+			out[i] = geoWKB(lt.Elem(), geoType, item, path, bad)
+		}
+		return out
+	}
+	if b, ok := v.([]byte); ok {
+		return b
+	}
+	if items, ok := v.([]interface{}); ok && len(items) == 0 {
+		return nil
+	}
+	parse := ParseGeoShape
+	if geoType == "geo_point" {
+		parse = ParseGeoPoints
+	}
+	if g, ok := parse(v); ok {
+		return g.AppendWKB(nil)
+	}
+	*bad = append(*bad, path)
+	return nil
+}
+
+func pointGeometry(p [2]float64) Geometry {
+	return Geometry{Kind: WKBPoint, Coords: [][2]float64{p}}
+}
+
+// ParseGeoPoints 함수는 Elasticsearch가 받는 geo_point 형식(lat/lon 객체, [lon, lat]
+// 배열, "lat,lon" 문자열, WKT POINT, geohash, GeoJSON Point)이나 그 배열을 읽습니다.
+func ParseGeoPoints(v interface{}) (Geometry, bool) {
+	items, ok := v.([]interface{})
+	if !ok {
+		p, ok := parsePoint(v)
+		return pointGeometry(p), ok
+	}
+	if p, ok := position(items); ok {
+		return pointGeometry(p), true
+	}
+	parts := make([]Geometry, len(items))
+	for i, item := range items {
+		p, ok := parsePoint(item)
+		if !ok {
+			return Geometry{}, false
+		}
+		parts[i] = pointGeometry(p)
+	}
+	if len(parts) == 1 {
+		return parts[0], true
+	}
+	return Geometry{Kind: WKBMultiPoint, Parts: parts}, true
+}
+
+func parsePoint(v interface{}) ([2]float64, bool) {
+	lat, lon, ok := GeoPoint(v)
+	return [2]float64{lon, lat}, ok
+}
+
+// position 함수는 [lon, lat] 또는 [lon, lat, z] 숫자 배열을 읽습니다. 문자열 배열은
+// 여러 geo_point 값이므로 받지 않습니다.
+func position(items []interface{}) ([2]float64, bool) {
+	return parsePoint(items)
+}
+
+// ParseGeoShape 함수는 geo_shape 값(GeoJSON 객체, WKT 문자열)이나 그 배열을 읽습니다.
+func ParseGeoShape(v interface{}) (Geometry, bool) {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		return geoJSONGeometry(x, 0)
+	case string:
+		return parseWKT(x)
+	case []interface{}:
+		parts := make([]Geometry, len(x))
+		for i, item := range x {
+			g, ok := ParseGeoShape(item)
+			if !ok {
+				return Geometry{}, false
+			}
+			parts[i] = g
+		}
+		if len(parts) == 1 {
+			return parts[0], true
+		}
+		return Geometry{Kind: WKBGeometryCollection, Parts: parts}, true
+	}
+	return Geometry{}, false
+}
+
+// geoJSONGeometry 함수는 GeoJSON 지오메트리 객체를 읽습니다. Elasticsearch의 envelope도
+// 네 모서리의 Polygon으로 받습니다. circle은 WKB로 나타낼 수 없어 받지 않습니다.
+func geoJSONGeometry(m map[string]interface{}, depth int) (Geometry, bool) {
+	t, _ := m["type"].(string)
+	coords, _ := m["coordinates"].([]interface{})
+	switch strings.ToLower(t) {
+	case "point":
+		p, ok := position(coords)
+		return pointGeometry(p), ok
+	case "linestring":
+		line, ok := positions(coords)
+		return Geometry{Kind: WKBLineString, Coords: line}, ok
+	case "polygon":
+		rings, ok := polygonRings(coords)
+		return Geometry{Kind: WKBPolygon, Rings: rings}, ok
+	case "multipoint":
+		points, ok := positions(coords)
+		g := Geometry{Kind: WKBMultiPoint}
+		for _, p := range points {
+			g.Parts = append(g.Parts, pointGeometry(p))
+		}
+		return g, ok
+	case "multilinestring":
+		g := Geometry{Kind: WKBMultiLineString}
+		for _, c := range coords {
+			items, _ := c.([]interface{})
+			line, ok := positions(items)
+			if !ok {
+				return g, false
+			}
+			g.Parts = append(g.Parts, Geometry{Kind: WKBLineString, Coords: line})
+		}
+		return g, true
+	case "multipolygon":
+		g := Geometry{Kind: WKBMultiPolygon}
+		for _, c := range coords {
+			items, _ := c.([]interface{})
+			rings, ok := polygonRings(items)
+			if !ok {
+				return g, false
+			}
+			g.Parts = append(g.Parts, Geometry{Kind: WKBPolygon, Rings: rings})
+		}
+		return g, true
+	case "geometrycollection":
+		if depth >= maxGeometryDepth {
+			return Geometry{}, false
+		}
+		items, _ := m["geometries"].([]interface{})
+		g := Geometry{Kind: WKBGeometryCollection}
+		for _, item := range items {
+			sub, _ := item.(map[string]interface{})
+			part, ok := geoJSONGeometry(sub, depth+1)
+			if !ok {
+				return g, false
+			}
+			g.Parts = append(g.Parts, part)
+		}
+		return g, true
+	case "envelope":
+		corners, ok := positions(coords)
+		if !ok || len(corners) != 2 {
+			return Geometry{}, false
+		}
+		// envelope은 [[minLon, maxLat], [maxLon, minLat]]입니다.
+		return envelopeGeometry(corners[0][0], corners[1][1], corners[1][0], corners[0][1]), true
+	}
+	return Geometry{}, false
+}
+
+func positions(items []interface{}) ([][2]float64, bool) {
+	out := make([][2]float64, len(items))
+	for i, item := range items {
+		c, _ := item.([]interface{})
+		p, ok := position(c)
+		if !ok {
+			return nil, false
+		}
+		out[i] = p
+	}
+	return out, true
+}
+
+func polygonRings(items []interface{}) ([][][2]float64, bool) {
+	rings := make([][][2]float64, len(items))
+	for i, item := range items {
+		c, _ := item.([]interface{})
+		ring, ok := positions(c)
+		if !ok {
+			return nil, false
+		}
+		rings[i] = ring
+	}
+	return rings, true
+}
+
+// envelopeGeometry 함수는 경계 상자를 반시계 방향으로 닫힌 Polygon으로 만듭니다.
+func envelopeGeometry(minX, minY, maxX, maxY float64) Geometry {
+	ring := [][2]float64{{minX, minY}, {maxX, minY}, {maxX, maxY}, {minX, maxY}, {minX, minY}}
+	return Geometry{Kind: WKBPolygon, Rings: [][][2]float64{ring}}
+}
+
+// wktParser는 Elasticsearch가 받는 WKT(와 BBOX)를 읽습니다.
+type wktParser struct {
+	s   string
+	pos int
+}
+
+// parseWKT 함수는 WKT 문자열 하나를 읽습니다. 뒤에 다른 내용이 남으면 실패합니다.
+func parseWKT(s string) (Geometry, bool) {
+	p := wktParser{s: s}
+	g, ok := p.geometry(0)
+	p.space()
+	return g, ok && p.pos == len(p.s)
+}
+
+func (p *wktParser) space() {
+	for p.pos < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+func (p *wktParser) word() string {
+	p.space()
+	start := p.pos
+	for p.pos < len(p.s) && (p.s[p.pos] >= 'A' && p.s[p.pos] <= 'Z' || p.s[p.pos] >= 'a' && p.s[p.pos] <= 'z') {
+		p.pos++
+	}
+	return strings.ToUpper(p.s[start:p.pos])
+}
+
+func (p *wktParser) consume(c byte) bool {
+	p.space()
+	if p.pos < len(p.s) && p.s[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *wktParser) peek(c byte) bool {
+	p.space()
+	return p.pos < len(p.s) && p.s[p.pos] == c
+}
+
+func (p *wktParser) number() (float64, bool) {
+	p.space()
+	start := p.pos
+	for p.pos < len(p.s) && strings.IndexByte("+-.0123456789eE", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+	f, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+	return f, err == nil && finite(f)
+}
+
+// coord 함수는 "x y" 또는 "x y z"를 읽습니다.
+func (p *wktParser) coord() ([2]float64, bool) {
+	x, ok1 := p.number()
+	y, ok2 := p.number()
+	if !ok1 || !ok2 {
+		return [2]float64{}, false
+	}
+	if !p.peek(',') && !p.peek(')') {
+		if _, ok := p.number(); !ok {
+			return [2]float64{}, false
+		}
+	}
+	return [2]float64{x, y}, true
+}
+
+// list 함수는 괄호 안의 쉼표로 구분된 항목을 item으로 읽습니다.
+func (p *wktParser) list(item func() bool) bool {
+	if !p.consume('(') {
+		return false
+	}
+	for {
+		if !item() {
+			return false
+		}
+		if !p.consume(',') {
+			return p.consume(')')
+		}
+	}
+}
+
+func (p *wktParser) coords() ([][2]float64, bool) {
+	var out [][2]float64
+	ok := p.list(func() bool {
+		c, ok := p.coord()
+		out = append(out, c)
+		return ok
+	})
+	return out, ok
+}
+
+func (p *wktParser) rings() ([][][2]float64, bool) {
+	var out [][][2]float64
+	ok := p.list(func() bool {
+		ring, ok := p.coords()
+		out = append(out, ring)
+		return ok
+	})
+	return out, ok
+}
+
+func (p *wktParser) geometry(depth int) (Geometry, bool) {
+	name := p.word()
+	if name == "BBOX" {
+		var v []float64
+		ok := p.list(func() bool {
+			f, ok := p.number()
+			v = append(v, f)
+			return ok
+		})
+		if !ok || len(v) != 4 {
+			return Geometry{}, false
+		}
+		// BBOX는 (minLon, maxLon, maxLat, minLat) 순서입니다.
+		return envelopeGeometry(v[0], v[3], v[1], v[2]), true
+	}
+	var g Geometry
+	for kind, n := range geometryNames {
+		if strings.ToUpper(n) == name {
+			g.Kind = kind
+		}
+	}
+	if g.Kind == 0 {
+		return g, false
+	}
+	if p.word() == "EMPTY" {
+		return g, true
+	}
+	var ok bool
+	switch g.Kind {
+	case WKBPoint:
+		ok = p.list(func() bool {
+			c, ok := p.coord()
+			g.Coords = append(g.Coords, c)
+			return ok && len(g.Coords) == 1
+		})
+	case WKBLineString:
+		g.Coords, ok = p.coords()
+	case WKBPolygon:
+		g.Rings, ok = p.rings()
+	case WKBMultiPoint:
+		// MULTIPOINT (1 2, 3 4)와 MULTIPOINT ((1 2), (3 4))를 모두 받습니다.
+		ok = p.list(func() bool {
+			paren := p.consume('(')
+			c, ok := p.coord()
+			g.Parts = append(g.Parts, pointGeometry(c))
+			return ok && (!paren || p.consume(')'))
+		})
+	case WKBMultiLineString:
+		ok = p.list(func() bool {
+			line, ok := p.coords()
+			g.Parts = append(g.Parts, Geometry{Kind: WKBLineString, Coords: line})
+			return ok
+		})
+	case WKBMultiPolygon:
+		ok = p.list(func() bool {
+			rings, ok := p.rings()
+			g.Parts = append(g.Parts, Geometry{Kind: WKBPolygon, Rings: rings})
+			return ok
+		})
+	case WKBGeometryCollection:
+		ok = depth < maxGeometryDepth && p.list(func() bool {
+			part, ok := p.geometry(depth + 1)
+			g.Parts = append(g.Parts, part)
+			return ok
+		})
+	}
+	return g, ok
+}
+
+// AppendWKB 함수는 g를 little-endian ISO WKB로 b에 덧붙입니다.
+func (g Geometry) AppendWKB(b []byte) []byte {
+	b = append(b, 1)
+	b = binary.LittleEndian.AppendUint32(b, g.Kind)
+	switch g.Kind {
+	case WKBPoint:
+		// 빈 Point는 좌표가 모두 NaN인 점으로 씁니다.
+		p := [2]float64{math.NaN(), math.NaN()}
+		if len(g.Coords) > 0 {
+			p = g.Coords[0]
+		}
+		b = appendCoord(b, p)
+	case WKBLineString:
+		b = appendCoords(b, g.Coords)
+	case WKBPolygon:
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(g.Rings)))
+		for _, ring := range g.Rings {
+			b = appendCoords(b, ring)
+		}
+	default:
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(g.Parts)))
+		for _, part := range g.Parts {
+			b = part.AppendWKB(b)
+		}
+	}
+	return b
+}
+
+func appendCoord(b []byte, p [2]float64) []byte {
+	b = binary.LittleEndian.AppendUint64(b, math.Float64bits(p[0]))
+	return binary.LittleEndian.AppendUint64(b, math.Float64bits(p[1]))
+}
+
+func appendCoords(b []byte, coords [][2]float64) []byte {
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(coords)))
+	for _, p := range coords {
+		b = appendCoord(b, p)
+	}
+	return b
+}
+
+// wkbReader는 WKB를 Geometry로 읽습니다. 처음 오류 뒤의 읽기는 모두 0을 반환합니다.
+type wkbReader struct {
+	data  []byte
+	pos   int
+	order binary.ByteOrder
+	err   error
+}
+
+var errShortWKB = errors.New("truncated WKB")
+
+// DecodeWKB 함수는 2차원 WKB 지오메트리 하나를 읽습니다.
+func DecodeWKB(data []byte) (Geometry, error) {
+	r := wkbReader{data: data}
+	g := r.geometry(0)
+	if r.err == nil && r.pos != len(data) {
+		r.err = fmt.Errorf("%d trailing bytes after WKB Geometry", len(data)-r.pos)
+	}
+	return g, r.err
+}
+
+func (r *wkbReader) uint32() uint32 {
+	if r.err != nil || len(r.data)-r.pos < 4 {
+		r.err = errShortWKB
+		return 0
+	}
+	v := r.order.Uint32(r.data[r.pos:])
+	r.pos += 4
+	return v
+}
+
+// count 함수는 원소 수를 읽고, 남은 바이트로 담을 수 없는 수이면 실패합니다.
+func (r *wkbReader) count(size int) int {
+	n := int(r.uint32())
+	if r.err == nil && n > (len(r.data)-r.pos)/size {
+		r.err = errShortWKB
+		return 0
+	}
+	return n
+}
+
+func (r *wkbReader) coord() [2]float64 {
+	if r.err != nil || len(r.data)-r.pos < 16 {
+		r.err = errShortWKB
+		return [2]float64{}
+	}
+	p := [2]float64{
+		math.Float64frombits(r.order.Uint64(r.data[r.pos:])),
+		math.Float64frombits(r.order.Uint64(r.data[r.pos+8:])),
+	}
+	r.pos += 16
+	return p
+}
+
+func (r *wkbReader) coords() [][2]float64 {
+	coords := make([][2]float64, r.count(16))
+	for i := range coords {
+		coords[i] = r.coord()
+	}
+	return coords
+}
+
+func (r *wkbReader) geometry(depth int) Geometry {
+	if r.err != nil || r.pos >= len(r.data) {
+		r.err = errShortWKB
+		return Geometry{}
+	}
+	switch r.data[r.pos] {
+	case 0:
+		r.order = binary.BigEndian
+	case 1:
+		r.order = binary.LittleEndian
+	default:
+		r.err = fmt.Errorf("invalid WKB byte order %d", r.data[r.pos])
+		return Geometry{}
+	}
+	r.pos++
+	g := Geometry{Kind: r.uint32()}
+	if _, ok := geometryNames[g.Kind]; !ok && r.err == nil {
+		r.err = fmt.Errorf("unsupported WKB Geometry type %d", g.Kind)
+	}
+	switch g.Kind {
+	case WKBPoint:
+		if p := r.coord(); !math.IsNaN(p[0]) || !math.IsNaN(p[1]) {
+			g.Coords = [][2]float64{p}
+		}
+	case WKBLineString:
+		g.Coords = r.coords()
+	case WKBPolygon:
+		g.Rings = make([][][2]float64, r.count(4))
+		for i := range g.Rings {
+			g.Rings[i] = r.coords()
+		}
+	default:
+		if depth >= maxGeometryDepth && r.err == nil {
+			r.err = errors.New("WKB Geometry nested too deeply")
+		}
+		// 가장 작은 하위 지오메트리(빈 컬렉션)도 5바이트입니다.
+		g.Parts = make([]Geometry, r.count(5))
+		for i := range g.Parts {
+			g.Parts[i] = r.geometry(depth + 1)
+		}
+	}
+	return g
+}
+
+// geoJSON 함수는 g를 Elasticsearch가 geo_shape 값으로 받는 GeoJSON 객체로 바꿉니다.
+func (g Geometry) GeoJSON() map[string]interface{} {
+	if g.Kind == WKBGeometryCollection {
+		geometries := make([]interface{}, len(g.Parts))
+		for i, part := range g.Parts {
+			geometries[i] = part.GeoJSON()
+		}
+		return map[string]interface{}{"type": geometryNames[g.Kind], "geometries": geometries}
+	}
+	return map[string]interface{}{"type": geometryNames[g.Kind], "coordinates": g.Coordinates()}
+}
+
+func (g Geometry) Coordinates() interface{} {
+	switch g.Kind {
+	case WKBPoint:
+		if len(g.Coords) == 0 {
+			return []interface{}{}
+		}
+		return []float64{g.Coords[0][0], g.Coords[0][1]}
+	case WKBLineString:
+		return coordList(g.Coords)
+	case WKBPolygon:
+		rings := make([]interface{}, len(g.Rings))
+		for i, ring := range g.Rings {
+			rings[i] = coordList(ring)
+		}
+		return rings
+	}
+	parts := make([]interface{}, len(g.Parts))
+	for i, part := range g.Parts {
+		parts[i] = part.Coordinates()
+	}
+	return parts
+}
+
+func coordList(coords [][2]float64) []interface{} {
+	out := make([]interface{}, len(coords))
+	for i, p := range coords {
+		out[i] = []float64{p[0], p[1]}
+	}
+	return out
+}
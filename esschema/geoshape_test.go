@@ -0,0 +1,181 @@
+package esschema
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+func TestGeoPointFormats(t *testing.T) {
+	// POINT (-71.34 41.12)를 little-endian WKB로 쓴 값입니다.
+	const want = "0101000000f6285c8fc2d551c08fc2f5285c8f4440"
+	for _, src := range []string{
+		`{"lat": 41.12, "lon": -71.34}`,
+		`{"lat": "41.12", "lon": "-71.34"}`,
+		`[-71.34, 41.12]`,
+		`"41.12,-71.34"`,
+		`"POINT (-71.34 41.12)"`,
+		`{"type": "Point", "coordinates": [-71.34, 41.12]}`,
+	} {
+		g, ok := ParseGeoPoints(geoValue(t, src))
+		if !ok {
+			t.Errorf("ParseGeoPoints(%s) failed", src)
+			continue
+		}
+		if got := hex.EncodeToString(g.AppendWKB(nil)); got != want {
+			t.Errorf("ParseGeoPoints(%s) = %s, want %s", src, got, want)
+		}
+	}
+
+	g, ok := ParseGeoPoints(geoValue(t, `"drm3btev3e86"`))
+	if !ok || g.Coords[0][0] < -71.3401 || g.Coords[0][0] > -71.3399 || g.Coords[0][1] < 41.1199 || g.Coords[0][1] > 41.1201 {
+		t.Errorf("geohash = %v, %v; want about [-71.34 41.12]", g.Coords, ok)
+	}
+
+	g, ok = ParseGeoPoints(geoValue(t, `[[-71.34, 41.12], "10,20"]`))
+	if !ok || g.Kind != WKBMultiPoint || len(g.Parts) != 2 || g.Parts[1].Coords[0] != [2]float64{20, 10} {
+		t.Errorf("multi-valued point = %+v, %v", g, ok)
+	}
+
+	for _, src := range []string{`{"lat": 1}`, `"not a point!"`, `[1, "x"]`, `"POINT (1)"`, `true`} {
+		if _, ok := ParseGeoPoints(geoValue(t, src)); ok {
+			t.Errorf("ParseGeoPoints(%s) succeeded, want failure", src)
+		}
+	}
+}
+
+func TestGeoShapeRoundTrip(t *testing.T) {
+	polygon := `{"type": "Polygon", "coordinates": [[[0, 0], [10, 0], [10, 10], [0, 0]], [[1, 1], [2, 1], [2, 2], [1, 1]]]}`
+	for _, tc := range []struct{ src, want string }{
+		{`"LINESTRING (0 0, 1 1, 2 0.5)"`, `{"type": "LineString", "coordinates": [[0, 0], [1, 1], [2, 0.5]]}`},
+		{`"POLYGON ((0 0, 10 0, 10 10, 0 0), (1 1, 2 1, 2 2, 1 1))"`, polygon},
+		{polygon, polygon},
+		{`"MULTIPOINT ((1 2), (3 4))"`, `{"type": "MultiPoint", "coordinates": [[1, 2], [3, 4]]}`},
+		{`"MULTIPOINT (1 2, 3 4)"`, `{"type": "MultiPoint", "coordinates": [[1, 2], [3, 4]]}`},
+		{`"multipolygon (((0 0, 1 0, 1 1, 0 0)))"`, `{"type": "MultiPolygon", "coordinates": [[[[0, 0], [1, 0], [1, 1], [0, 0]]]]}`},
+		{`"BBOX (-10, 10, 20, -20)"`, `{"type": "Polygon", "coordinates": [[[-10, -20], [10, -20], [10, 20], [-10, 20], [-10, -20]]]}`},
+		{`{"type": "envelope", "coordinates": [[-10, 20], [10, -20]]}`, `{"type": "Polygon", "coordinates": [[[-10, -20], [10, -20], [10, 20], [-10, 20], [-10, -20]]]}`},
+		{`"GEOMETRYCOLLECTION (POINT (1 2), LINESTRING EMPTY)"`, `{"type": "GeometryCollection", "geometries": [{"type": "Point", "coordinates": [1, 2]}, {"type": "LineString", "coordinates": []}]}`},
+		{`["POINT (1 2)", {"type": "Point", "coordinates": [3, 4, 5]}]`, `{"type": "GeometryCollection", "geometries": [{"type": "Point", "coordinates": [1, 2]}, {"type": "Point", "coordinates": [3, 4]}]}`},
+		{`"POINT EMPTY"`, `{"type": "Point", "coordinates": []}`},
+	} {
+		g, ok := ParseGeoShape(geoValue(t, tc.src))
+		if !ok {
+			t.Errorf("ParseGeoShape(%s) failed", tc.src)
+			continue
+		}
+		decoded, err := DecodeWKB(g.AppendWKB(nil))
+		if err != nil {
+			t.Errorf("DecodeWKB(%s): %v", tc.src, err)
+			continue
+		}
+		got, _ := json.Marshal(decoded.GeoJSON())
+		var gotV, wantV interface{}
+		json.Unmarshal(got, &gotV)
+		json.Unmarshal([]byte(tc.want), &wantV)
+		if !reflect.DeepEqual(gotV, wantV) {
+			t.Errorf("%s round-tripped to %s, want %s", tc.src, got, tc.want)
+		}
+	}
+
+	for _, src := range []string{`{"type": "circle", "coordinates": [1, 2], "radius": "1km"}`, `"POLYGON ((0 0, 1 1)"`, `"POINT (1 2) extra"`, `{"type": "LineString", "coordinates": [[0, "a"]]}`} {
+		if _, ok := ParseGeoShape(geoValue(t, src)); ok {
+			t.Errorf("ParseGeoShape(%s) succeeded, want failure", src)
+		}
+	}
+}
+
+func TestDecodeWKBErrors(t *testing.T) {
+	valid, _ := hex.DecodeString("0101000000000000000000f03f0000000000000040")
+	big, _ := hex.DecodeString("00000000013ff00000000000004000000000000000")
+	if g, err := DecodeWKB(big); err != nil || g.Coords[0] != [2]float64{1, 2} {
+		t.Errorf("big-endian WKB = %v, %v", g, err)
+	}
+	for name, data := range map[string][]byte{
+		"empty":      nil,
+		"truncated":  valid[:len(valid)-1],
+		"trailing":   append(append([]byte(nil), valid...), 0),
+		"byte order": append([]byte{2}, valid[1:]...),
+		"type":       {1, 99, 0, 0, 0},
+		"count":      {1, 2, 0, 0, 0, 0xff, 0xff, 0xff, 0x7f},
+	} {
+		if _, err := DecodeWKB(data); err == nil {
+			t.Errorf("%s: DecodeWKB succeeded, want error", name)
+		}
+	}
+}
+
+func TestGeoWKB(t *testing.T) {
+	var bad []string
+	line := geoWKB(arrow.BinaryTypes.Binary, "geo_shape", "LINESTRING (0 0, 1 1)", "area", &bad)
+	if g, err := DecodeWKB(line.([]byte)); err != nil || g.Kind != WKBLineString {
+		t.Errorf("LINESTRING = %v, %v", g, err)
+	}
+	point := geoWKB(arrow.BinaryTypes.Binary, "geo_point", "41.12,-71.34", "location", &bad)
+	if g, err := DecodeWKB(point.([]byte)); err != nil || g.Kind != WKBPoint {
+		t.Errorf("geo_point = %v, %v", g, err)
+	}
+	wkb := []byte{1, 2, 3}
+	if v := geoWKB(arrow.BinaryTypes.Binary, "geo_shape", wkb, "area", &bad); !reflect.DeepEqual(v, wkb) {
+		t.Errorf("WKB from a transform = %v", v)
+	}
+	if v := geoWKB(arrow.BinaryTypes.Binary, "geo_shape", []interface{}{}, "area", &bad); v != nil || len(bad) != 0 {
+		t.Errorf("empty array = %v, bad %v", v, bad)
+	}
+	circle := geoValue(t, `{"type": "circle", "coordinates": [1, 2], "radius": "1km"}`)
+	if v := geoWKB(arrow.BinaryTypes.Binary, "geo_shape", circle, "area", &bad); v != nil || !reflect.DeepEqual(bad, []string{"area"}) {
+		t.Errorf("circle = %v, bad %v", v, bad)
+	}
+}
+
+func TestConvertGeoShape(t *testing.T) {
+	fields, err := Fields(map[string]interface{}{
+		"area": map[string]interface{}{"type": "geo_shape"},
+		"site": map[string]interface{}{"properties": map[string]interface{}{
+			"boundary": map[string]interface{}{"type": "geo_shape"},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := geoValue(t, `{
+		"area": ["POINT (1 2)", {"type": "Point", "coordinates": [3, 4]}],
+		"site": {"boundary": {"type": "envelope", "coordinates": [[-10, 20], [10, -20]]}}
+	}`).(map[string]interface{})
+	values := make([]interface{}, len(fields))
+	if bad, err := ConvertDocument(fields, doc, values); err != nil || len(bad) != 0 {
+		t.Fatalf("bad = %v, %v", bad, err)
+	}
+	if g, err := DecodeWKB(values[0].([]byte)); err != nil || g.Kind != WKBGeometryCollection || len(g.Parts) != 2 {
+		t.Errorf("area = %+v, %v", g, err)
+	}
+	if g, err := DecodeWKB(values[1].([]interface{})[0].([]byte)); err != nil || g.Kind != WKBPolygon {
+		t.Errorf("site.boundary = %+v, %v", g, err)
+	}
+
+	js := GeoShapesAsJSON(fields)
+	if js[0].Type.ID() != arrow.STRING || !isGeoShapeJSON(js[0]) || js[0].Metadata.FindKey(GeoTypeKey) >= 0 {
+		t.Fatalf("GeoShapesAsJSON = %v", js)
+	}
+	if bad, err := ConvertDocument(js, doc, values); err != nil || len(bad) != 0 {
+		t.Fatalf("json mode bad = %v, %v", bad, err)
+	}
+	if want := `["POINT (1 2)",{"coordinates":[3,4],"type":"Point"}]`; values[0] != want {
+		t.Errorf("json mode area = %v, want %s", values[0], want)
+	}
+}
+
+func geoValue(t *testing.T, src string) interface{} {
+	t.Helper()
+	d := json.NewDecoder(strings.NewReader(src))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
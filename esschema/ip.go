@@ -0,0 +1,109 @@
+package esschema
+
+import (
+	"net/netip"
+	"strings"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+// IPStringKey는 IPStrings로 문자열 컬럼이 된 ip 필드에 붙는 필드 메타데이터 키입니다. 이
+// 필드의 값도 주소로 읽어 정규형 문자열로 씁니다.
+const IPStringKey = "es_schema.ip"
+
+// IPType 함수는 ip 필드의 Arrow 타입을 반환합니다. IPv4도 IPv6에 사상한 주소
+// (::ffff:a.b.c.d)로 16바이트에 담으므로 두 종류의 주소를 한 컬럼에서 정렬하고 비교할 수
+// 있습니다.
+func IPType() arrow.DataType {
+	return &arrow.FixedSizeBinaryType{ByteWidth: 16}
+}
+
+// IsIP 함수는 dt가 IPType인지 알려 줍니다.
+func IsIP(dt arrow.DataType) bool {
+	t, ok := dt.(*arrow.FixedSizeBinaryType)
+	return ok && t.ByteWidth == 16
+}
+
+// ParseIP 함수는 Elasticsearch가 ip 필드에 받는 IPv4나 IPv6 주소 문자열을 읽습니다.
+// IPv6 주소의 영역(%eth0)은 받지 않습니다.
+func ParseIP(v interface{}) (netip.Addr, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return netip.Addr{}, false
+	}
+	addr, err := netip.ParseAddr(strings.TrimSpace(s))
+	if err != nil || addr.Zone() != "" {
+		return netip.Addr{}, false
+	}
+	return addr, true
+}
+
+// IPText 함수는 IPType 값 16바이트를 주소 문자열로 바꿉니다. IPv4는 점으로 구분한 형식이 됩니다.
+func IPText(b []byte) string {
+	if len(b) != 16 {
+		return ""
+	}
+	return netip.AddrFrom16([16]byte(b)).Unmap().String()
+}
+
+// IPStrings 함수는 fields 안(구조체와 리스트 안 포함)의 IPType을 IPStringKey를 붙인 문자열
+// 필드로 바꾼 필드 목록을 반환합니다. 주소를 사람이 읽을 문자열로 두어야 하는 소비자를
+// 위한 것으로, 값은 여전히 주소로 검사하고 정규형(IPv4는 a.b.c.d, IPv6는 RFC 5952)으로 씁니다.
+func IPStrings(fields []arrow.Field) []arrow.Field {
+	out := make([]arrow.Field, len(fields))
+	for i, f := range fields {
+		out[i] = ipStringField(f)
+	}
+	return out
+}
+
+func ipStringField(f arrow.Field) arrow.Field {
+	switch t := f.Type.(type) {
+	case *arrow.FixedSizeBinaryType:
+		if IsIP(t) {
+			keys := append([]string{IPStringKey}, f.Metadata.Keys()...)
+			values := append([]string{"true"}, f.Metadata.Values()...)
+			f.Type = arrow.BinaryTypes.String
+			f.Metadata = arrow.NewMetadata(keys, values)
+		}
+	case *arrow.ListType:
+		elem := ipStringField(arrow.Field{Name: f.Name, Type: t.Elem(), Metadata: f.Metadata})
+		f.Type, f.Metadata = arrow.ListOf(elem.Type), elem.Metadata
+	case *arrow.StructType:
+		f.Type = arrow.StructOf(IPStrings(t.Fields())...)
+	}
+	return f
+}
+
+// isIPString 함수는 f가 IPStrings로 만든 필드인지 알려 줍니다.
+func isIPString(f arrow.Field) bool {
+	return f.Metadata.FindKey(IPStringKey) >= 0
+}
+
+// ipString 함수는 IPStrings로 만든 필드(와 그 리스트)의 값 v를 정규형 주소 문자열로
+// 바꿉니다. 주소가 아닌 값은 nil로 두고 path를 bad에 추가합니다.
+func (c *Converter) ipString(dt arrow.DataType, v interface{}, path string, bad *[]string) interface{} {
+	if v == nil {
+		return nil
+	}
+	if lt, ok := dt.(*arrow.ListType); ok {
+		items, ok := v.([]interface{})
+		if !ok {
+			items = []interface{}{v}
+		}
+		out := make([]interface{}, len(items))
+		for i, item := range items {
+			out[i] = c.ipString(lt.Elem(), item, path, bad)
+		}
+		return out
+	}
+	if items, ok := v.([]interface{}); ok && len(items) == 1 {
+		v = items[0]
+	}
+	addr, ok := ParseIP(v)
+	if !ok {
+		*bad = append(*bad, path)
+		return nil
+	}
+	return addr.String()
+}
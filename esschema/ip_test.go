@@ -0,0 +1,71 @@
+package esschema
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+func TestParseIP(t *testing.T) {
+	cases := map[string]string{
+		"192.168.0.10":        "192.168.0.10",
+		" 10.0.0.1 ":          "10.0.0.1",
+		"2001:DB8:0:0::1":     "2001:db8::1",
+		"::ffff:192.168.0.10": "192.168.0.10",
+		"::":                  "::",
+	}
+	for in, want := range cases {
+		addr, ok := ParseIP(in)
+		if !ok {
+			t.Errorf("ParseIP(%q) failed", in)
+			continue
+		}
+		b := addr.As16()
+		if got := IPText(b[:]); got != want {
+			t.Errorf("IPText(ParseIP(%q)) = %s, want %s", in, got, want)
+		}
+	}
+	for _, v := range []interface{}{"", "10.0.0", "256.0.0.1", "fe80::1%eth0", "10.0.0.0/8", 42.0, nil} {
+		if _, ok := ParseIP(v); ok {
+			t.Errorf("ParseIP(%v) succeeded, want failure", v)
+		}
+	}
+}
+
+func TestConvertIP(t *testing.T) {
+	fields := []arrow.Field{
+		{Name: "client", Type: IPType()},
+		{Name: "hops", Type: arrow.ListOf(IPType())},
+		{Name: "bad", Type: IPType()},
+	}
+	doc := map[string]interface{}{
+		"client": "10.0.0.1",
+		"hops":   []interface{}{"::1", "10.0.0.2"},
+		"bad":    "localhost",
+	}
+	values := make([]interface{}, len(fields))
+	bad, err := ConvertDocument(fields, doc, values)
+	if err != nil || !reflect.DeepEqual(bad, []string{"bad"}) {
+		t.Fatalf("bad = %v, %v", bad, err)
+	}
+	if got := IPText(values[0].([]byte)); got != "10.0.0.1" {
+		t.Errorf("client = %s", got)
+	}
+	if hops := values[1].([]interface{}); len(hops) != 2 || IPText(hops[0].([]byte)) != "::1" {
+		t.Errorf("hops = %v", hops)
+	}
+
+	strs := IPStrings(fields)
+	if strs[0].Type.ID() != arrow.STRING || !isIPString(strs[0]) || !isIPString(strs[1]) {
+		t.Fatalf("IPStrings = %v", strs)
+	}
+	doc["client"] = "2001:DB8::0:1"
+	if bad, _ = ConvertDocument(strs, doc, values); !reflect.DeepEqual(bad, []string{"bad"}) {
+		t.Errorf("string mode bad = %v", bad)
+	}
+	want := []interface{}{"2001:db8::1", []interface{}{"::1", "10.0.0.2"}, nil}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("string mode values = %v, want %v", values, want)
+	}
+}
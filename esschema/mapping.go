@@ -0,0 +1,211 @@
+// Package esschema는 Elasticsearch 매핑을 Arrow 스키마로, _source JSON 문서를 그 스키마의
+// Arrow 레코드로 바꿉니다. es-schema 명령이 쓰는 것과 같은 변환 규칙이며, 다른 Go 서비스가
+// 바이너리를 실행하지 않고 쓸 수 있도록 모든 실패를 오류로 반환합니다.
+//
+//	mapping, err := esschema.ParseMapping(data)
+//	schema, err := esschema.ToArrowSchema(mapping)
+//	b := esschema.NewRecordBuilder(memory.DefaultAllocator, schema)
+//	defer b.Release()
+//	for _, doc := range docs {
+//		if _, err := b.Append(doc); err != nil { ... }
+//	}
+//	rec := b.NewRecord()
+package esschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+// Mapping은 인덱스 매핑의 최상위 properties입니다.
+type Mapping struct {
+	Properties map[string]interface{}
+}
+
+// ParseMapping 함수는 매핑 JSON을 읽습니다. {"properties": ...} 형식의 매핑 본문 외에
+// 인덱스 생성 본문({"mappings": ...}), 인덱스 하나의 _mapping API 응답({"인덱스":
+// {"mappings": ...}})과 6.x의 타입 이름 감싸기({"_doc": {"properties": ...}})도 받습니다.
+// 응답에 인덱스가 여럿이면 어느 매핑인지 알 수 없으므로 오류입니다.
+func ParseMapping(data []byte) (*Mapping, error) {
+	m, err := MappingObject(data)
+	if err != nil {
+		return nil, err
+	}
+	return &Mapping{Properties: m["properties"].(map[string]interface{})}, nil
+}
+
+// MappingObject 함수는 ParseMapping처럼 감싼 것을 벗긴 매핑 객체를 반환합니다. properties
+// 옆의 _meta, dynamic 같은 매개변수도 그대로 들어 있습니다.
+func MappingObject(data []byte) (map[string]interface{}, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := m["properties"].(map[string]interface{}); ok {
+			return m, nil
+		}
+		if inner, ok := m["mappings"].(map[string]interface{}); ok {
+			m = inner
+			continue
+		}
+		inner, err := unwrapMapping(m)
+		if err != nil {
+			return nil, err
+		}
+		if inner == nil {
+			return nil, fmt.Errorf("mapping has no top-level \"properties\" object")
+		}
+		m = inner
+	}
+}
+
+// unwrapMapping 함수는 인덱스나 타입 이름 하나로 감싼 매핑을 벗깁니다. 감싼 것이 아니면
+// nil을 반환합니다.
+func unwrapMapping(m map[string]interface{}) (map[string]interface{}, error) {
+	var indices []string
+	for name, v := range m {
+		if inner, ok := v.(map[string]interface{}); ok && (inner["mappings"] != nil || inner["properties"] != nil) {
+			indices = append(indices, name)
+		}
+	}
+	switch {
+	case len(indices) == 0 || len(indices) != len(m):
+		return nil, nil
+	case len(indices) > 1:
+		sort.Strings(indices)
+		return nil, fmt.Errorf("_mapping response holds %d indices (%s); give the mapping of one index", len(indices), strings.Join(indices, ", "))
+	}
+	return m[indices[0]].(map[string]interface{}), nil
+}
+
+// ToArrowSchema 함수는 매핑을 Arrow 스키마로 바꿉니다.
+func ToArrowSchema(m *Mapping) (*arrow.Schema, error) {
+	fields, err := Fields(m.Properties)
+	if err != nil {
+		return nil, err
+	}
+	return arrow.NewSchema(fields, nil), nil
+}
+
+// Fields 함수는 properties 맵을 Arrow 필드 목록으로 바꿉니다. 맵 순회 순서는 일정하지
+// 않으므로 필드는 이름 순으로 정렬합니다.
+func Fields(properties map[string]interface{}) ([]arrow.Field, error) {
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fields := []arrow.Field{}
+	for _, name := range names {
+		props, ok := properties[name].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("field %s: want an object with a \"type\", got %T", name, properties[name])
+		}
+		esType, ok := props["type"].(string)
+		if !ok {
+			// "type"이 없는 경우 "object"로 가정
+			esType = "object"
+		}
+		t, err := FieldType(esType, props)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", name, err)
+		}
+		// 문서에 없는 필드는 null이어야 하므로 모든 필드가 nullable입니다. 그렇지 않으면
+		// Parquet에 REQUIRED 컬럼으로 쓰여 빠진 값이 0으로 저장됩니다.
+		f := arrow.Field{Name: name, Type: t, Nullable: true}
+		if format, ok := props["format"].(string); ok && (esType == "date" || esType == "date_nanos") {
+			// 값은 Converter가 이 형식으로 읽습니다(FieldDateFormat).
+			f.Metadata = arrow.NewMetadata([]string{DateFormatKey}, []string{format})
+		}
+		if factor, ok := Float(props["scaling_factor"]); ok && esType == "scaled_float" {
+			// 값은 Converter가 Elasticsearch처럼 이 수를 곱해 반올림합니다(FieldScalingFactor).
+			f.Metadata = arrow.NewMetadata([]string{ScalingFactorKey}, []string{strconv.FormatFloat(factor, 'g', -1, 64)})
+		}
+		if esType == "geo_shape" {
+			// GeoParquet 메타데이터를 쓰는 WKB 지오메트리 컬럼입니다(GeoShapesAsJSON 참고).
+			f.Metadata = arrow.NewMetadata([]string{GeoTypeKey}, []string{esType})
+		}
+		if _, ok := props["dims"]; esType == "dense_vector" && !ok {
+			// 차원은 문서를 보고 정합니다(SizeVector 참고).
+			f.Metadata = arrow.NewMetadata([]string{UnsizedVectorKey}, []string{"true"})
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// FieldType 함수는 Elasticsearch 타입을 Arrow 타입으로 매핑합니다. 전용 Arrow 타입이
+// 없는 타입은 문자열이 됩니다.
+func FieldType(esType string, props map[string]interface{}) (arrow.DataType, error) {
+	switch esType {
+	case "text", "keyword":
+		return arrow.BinaryTypes.String, nil
+	case "integer":
+		return arrow.PrimitiveTypes.Int32, nil
+	case "long":
+		return arrow.PrimitiveTypes.Int64, nil
+	case "unsigned_long":
+		return arrow.PrimitiveTypes.Uint64, nil
+	case "float":
+		return arrow.PrimitiveTypes.Float32, nil
+	case "half_float":
+		return arrow.FixedWidthTypes.Float16, nil
+	case "double":
+		return arrow.PrimitiveTypes.Float64, nil
+	case "scaled_float":
+		factor, _ := Float(props["scaling_factor"])
+		return ScaledFloatType(factor), nil
+	case "boolean":
+		return arrow.FixedWidthTypes.Boolean, nil
+	case "date", "date_nanos":
+		// Date 타입은 Arrow의 timestamp 타입으로 매핑합니다. date_nanos의 나노초를 잃지 않도록
+		// 둘 다 나노초 단위이고, 다른 단위는 TimestampsIn으로 바꿉니다.
+		return arrow.FixedWidthTypes.Timestamp_ns, nil
+	case "binary":
+		// _source의 base64 문자열은 Converter가 디코딩해 원래 바이트로 씁니다.
+		return arrow.BinaryTypes.Binary, nil
+	case "flattened":
+		return FlattenedType(), nil
+	case "integer_range", "long_range", "float_range", "double_range", "date_range", "ip_range":
+		var md arrow.Metadata
+		if format, ok := props["format"].(string); ok && esType == "date_range" {
+			md = arrow.NewMetadata([]string{DateFormatKey}, []string{format})
+		}
+		return RangeType(rangeElemTypes[esType](), md), nil
+	case "geo_point":
+		return GeoPointType(), nil
+	case "geo_shape":
+		// GeoJSON 객체와 WKT 문자열은 Converter가 WKB로 바꿔 씁니다.
+		return arrow.BinaryTypes.Binary, nil
+	case "ip":
+		return IPType(), nil
+	case "dense_vector":
+		// Dense vector 타입은 Arrow의 fixed-size list 타입으로 매핑합니다. dims가 없으면
+		// 길이 없는 리스트이고 Fields가 UnsizedVectorKey를 붙입니다.
+		return vectorType(props)
+	case "nested", "object":
+		// Nested 또는 Object 타입은 재귀적으로 처리합니다. nested 필드는 객체의 배열이므로
+		// 값이 하나뿐인 문서도 원소 하나짜리 구조체 리스트로 씁니다.
+		var fields []arrow.Field
+		if properties, ok := props["properties"].(map[string]interface{}); ok {
+			var err error
+			if fields, err = Fields(properties); err != nil {
+				return nil, err
+			}
+		}
+		st := arrow.StructOf(fields...)
+		if esType == "nested" {
+			return arrow.ListOf(st), nil
+		}
+		return st, nil
+	default:
+		return arrow.BinaryTypes.String, nil
+	}
+}
@@ -0,0 +1,72 @@
+package esschema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+func TestParseMapping(t *testing.T) {
+	for _, c := range []struct{ mapping, err string }{
+		{`{"mappings": {}}`, `no top-level "properties"`},
+		{`{"properties": `, "unexpected end"},
+	} {
+		if _, err := ParseMapping([]byte(c.mapping)); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("ParseMapping(%s) = %v, want %q", c.mapping, err, c.err)
+		}
+	}
+	// 인덱스 생성 본문, _mapping 응답과 타입 이름으로 감싼 매핑도 읽습니다.
+	for _, mapping := range []string{
+		`{"mappings": {"properties": {"title": {"type": "text"}}}}`,
+		`{"logs-2024.05": {"mappings": {"dynamic": "strict", "properties": {"title": {"type": "text"}}}}}`,
+		`{"logs-2024.05": {"mappings": {"_doc": {"properties": {"title": {"type": "text"}}}}}}`,
+	} {
+		m, err := ParseMapping([]byte(mapping))
+		if err != nil || m.Properties["title"] == nil {
+			t.Errorf("ParseMapping(%s) = %v, %v", mapping, m, err)
+		}
+	}
+	_, err := ParseMapping([]byte(`{"logs-b": {"mappings": {}}, "logs-a": {"mappings": {}}}`))
+	if err == nil || !strings.Contains(err.Error(), "holds 2 indices (logs-a, logs-b)") {
+		t.Errorf("ParseMapping of two indices = %v", err)
+	}
+
+	// 잘못된 필드 정의는 패닉하지 않고 필드 경로와 함께 오류가 됩니다.
+	m, err := ParseMapping([]byte(`{"properties": {"user": {"properties": {"name": "text"}}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ToArrowSchema(m); err == nil || !strings.Contains(err.Error(), "field user: field name") {
+		t.Errorf("ToArrowSchema = %v", err)
+	}
+}
+
+func TestToArrowSchema(t *testing.T) {
+	m, err := ParseMapping([]byte(`{"properties": {
+		"title": {"type": "text"},
+		"views": {"type": "long"},
+		"embedding": {"type": "dense_vector", "dims": 3},
+		"user": {"properties": {"age": {"type": "integer"}}},
+		"comments": {"type": "nested", "properties": {"likes": {"type": "integer"}}},
+		"ip": {"type": "ip"}
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := ToArrowSchema(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := arrow.NewSchema([]arrow.Field{
+		{Name: "comments", Type: arrow.ListOf(arrow.StructOf(arrow.Field{Name: "likes", Type: arrow.PrimitiveTypes.Int32, Nullable: true})), Nullable: true},
+		{Name: "embedding", Type: arrow.FixedSizeListOf(3, arrow.PrimitiveTypes.Float32), Nullable: true},
+		{Name: "ip", Type: IPType(), Nullable: true},
+		{Name: "title", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "user", Type: arrow.StructOf(arrow.Field{Name: "age", Type: arrow.PrimitiveTypes.Int32, Nullable: true}), Nullable: true},
+		{Name: "views", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+	}, nil)
+	if !schema.Equal(want) {
+		t.Errorf("schema =\n%s\nwant\n%s", schema, want)
+	}
+}
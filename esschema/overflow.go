@@ -0,0 +1,180 @@
+package esschema
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/float16"
+)
+
+// maxFloat16은 half_float의 가장 큰 유한한 값입니다.
+const maxFloat16 = 65504
+
+// OverflowPolicy는 숫자 값이 매핑된 숫자 타입(integer, long, unsigned_long, half_float,
+// float, scaled_float)의 범위를 넘을 때의 처리입니다.
+type OverflowPolicy string
+
+const (
+	// OverflowNull은 값을 null로 두고 바꿀 수 없는 값처럼 다룹니다. 기본값입니다.
+	OverflowNull OverflowPolicy = "null"
+	// OverflowError는 문서 변환을 *RangeError로 실패시킵니다.
+	OverflowError OverflowPolicy = "error"
+	// OverflowSaturate는 값을 타입의 가장 큰 값이나 가장 작은 값으로 바꿉니다.
+	OverflowSaturate OverflowPolicy = "saturate"
+	// OverflowWiden은 WidenNumber로 스키마를 미리 넓힌다는 뜻입니다. 넓힌 뒤에도 넘는 값은
+	// OverflowNull처럼 다룹니다.
+	OverflowWiden OverflowPolicy = "widen"
+)
+
+// ParseOverflowPolicy 함수는 정책 이름을 읽습니다. 빈 문자열은 OverflowNull입니다.
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	switch p := OverflowPolicy(s); p {
+	case "":
+		return OverflowNull, nil
+	case OverflowNull, OverflowError, OverflowSaturate, OverflowWiden:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown numeric overflow policy %q (want null, error, saturate or widen)", s)
+}
+
+// RangeError는 OverflowError인 Converter가 만난 첫 번째 범위를 넘는 값입니다.
+type RangeError struct {
+	Path  string
+	Value interface{}
+	Type  arrow.DataType
+}
+
+func (e *RangeError) Error() string {
+	return fmt.Sprintf("%s: %v overflows %s", e.Path, e.Value, e.Type)
+}
+
+// Converter는 ConvertValue와 ConvertDocument를 Overflow 정책으로 부르고, 범위를 넘은 값의
+// 수를 경로별로 셉니다. 고루틴 하나에서만 씁니다.
+type Converter struct {
+	Overflow OverflowPolicy
+	// Overflows는 지금까지 범위를 넘은 값의 수입니다. 구조체 안의 필드는 점으로 이은
+	// 경로입니다.
+	Overflows map[string]int64
+	// Sanitize는 문자열 값을 고치는 방법이고, Sanitized는 그래서 바뀐 값의 수를 Overflows처럼
+	// 경로별로 셉니다.
+	Sanitize  Sanitize
+	Sanitized map[string]int64
+
+	err error
+}
+
+// Document 함수는 ConvertDocument와 같습니다. OverflowError일 때 범위를 넘는 값이 있으면
+// *RangeError를 반환합니다.
+func (c *Converter) Document(fields []arrow.Field, doc map[string]interface{}, values []interface{}) (bad []string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("converting document: %v", r)
+		}
+	}()
+	c.err = nil
+	for j, f := range fields {
+		before := len(bad)
+		values[j] = c.field(f, doc[f.Name], f.Name, &bad)
+		// 한 필드 안에서 여러 값이 실패해도 경로는 한 번만 남깁니다.
+		if len(bad) > before+1 {
+			bad = bad[:before+1]
+		}
+	}
+	return bad, c.err
+}
+
+// overflow 함수는 path의 값 v가 dt의 범위를 넘었음을 기록하고, 정책에 따라 대신 넣을 값을
+// 반환합니다. 값이 없으면 false입니다.
+func (c *Converter) overflow(dt arrow.DataType, v interface{}, path string, saturated interface{}) (interface{}, bool) {
+	if c.Overflows == nil {
+		c.Overflows = make(map[string]int64)
+	}
+	c.Overflows[path]++
+	switch c.Overflow {
+	case OverflowSaturate:
+		return saturated, true
+	case OverflowError:
+		if c.err == nil {
+			c.err = &RangeError{Path: path, Value: v, Type: dt}
+		}
+	}
+	return nil, false
+}
+
+// numberRange 함수는 v가 숫자이면서 dt의 범위를 넘으면 dt에서 가장 가까운 값과 true를
+// 반환합니다.
+func numberRange(dt arrow.DataType, v interface{}) (interface{}, bool) {
+	f, ok := Float(v)
+	if !ok || math.IsNaN(f) {
+		return nil, false
+	}
+	switch dt.ID() {
+	case arrow.INT8:
+		// byte 원소의 dense_vector입니다.
+		if i, ok := Int(v); ok && i >= math.MinInt8 && i <= math.MaxInt8 {
+			return nil, false
+		}
+		if f < 0 {
+			return int8(math.MinInt8), true
+		}
+		return int8(math.MaxInt8), true
+	case arrow.INT32:
+		if i, ok := Int(v); ok && i >= math.MinInt32 && i <= math.MaxInt32 {
+			return nil, false
+		}
+		if f < 0 {
+			return int32(math.MinInt32), true
+		}
+		return int32(math.MaxInt32), true
+	case arrow.INT64:
+		if _, ok := Int(v); ok {
+			return nil, false
+		}
+		if f < 0 {
+			return int64(math.MinInt64), true
+		}
+		return int64(math.MaxInt64), true
+	case arrow.UINT64:
+		if _, ok := Uint(v); ok {
+			return nil, false
+		}
+		if f < 0 {
+			return uint64(0), true
+		}
+		return uint64(math.MaxUint64), true
+	case arrow.FLOAT16:
+		if math.Abs(f) <= maxFloat16 || math.IsInf(f, 0) {
+			return nil, false
+		}
+		return float16.New(float32(math.Copysign(maxFloat16, f))), true
+	case arrow.FLOAT32:
+		if math.Abs(f) <= math.MaxFloat32 || math.IsInf(f, 0) {
+			return nil, false
+		}
+		return float32(math.Copysign(math.MaxFloat32, f)), true
+	case arrow.DECIMAL128:
+		return decimalRange(dt.(*arrow.Decimal128Type), f)
+	}
+	return nil, false
+}
+
+// WidenNumber 함수는 v가 dt의 범위를 넘는 숫자이면 v를 담는 더 넓은 타입을, 아니면 dt를
+// 반환합니다. integer는 long으로, half_float는 float로, long이나 float나 scaled_float를
+// 넘으면 double이 됩니다. unsigned_long은 음수일 때도 double이 됩니다.
+func WidenNumber(dt arrow.DataType, v interface{}) arrow.DataType {
+	if _, over := numberRange(dt, v); !over {
+		return dt
+	}
+	switch dt.ID() {
+	case arrow.INT32:
+		if _, over := numberRange(arrow.PrimitiveTypes.Int64, v); !over {
+			return arrow.PrimitiveTypes.Int64
+		}
+	case arrow.FLOAT16:
+		if _, over := numberRange(arrow.PrimitiveTypes.Float32, v); !over {
+			return arrow.PrimitiveTypes.Float32
+		}
+	}
+	return arrow.PrimitiveTypes.Float64
+}
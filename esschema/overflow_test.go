@@ -0,0 +1,71 @@
+package esschema
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+func TestConverterOverflow(t *testing.T) {
+	fields := []arrow.Field{
+		{Name: "count", Type: arrow.PrimitiveTypes.Int32},
+		{Name: "bytes", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "ratio", Type: arrow.PrimitiveTypes.Float32},
+		{Name: "ok", Type: arrow.PrimitiveTypes.Int32},
+	}
+	doc := map[string]interface{}{"count": 3e9, "bytes": -1e19, "ratio": 1e39, "ok": 7.9}
+	values := make([]interface{}, len(fields))
+
+	c := Converter{Overflow: OverflowNull}
+	bad, err := c.Document(fields, doc, values)
+	if err != nil || !reflect.DeepEqual(bad, []string{"count", "bytes", "ratio"}) || values[0] != nil || values[3] != int32(7) {
+		t.Errorf("null: bad = %v, values = %v, err = %v", bad, values, err)
+	}
+
+	c = Converter{Overflow: OverflowSaturate}
+	bad, err = c.Document(fields, doc, values)
+	want := []interface{}{int32(math.MaxInt32), int64(math.MinInt64), float32(math.MaxFloat32), int32(7)}
+	if err != nil || len(bad) > 0 || !reflect.DeepEqual(values, want) {
+		t.Errorf("saturate: bad = %v, values = %v, err = %v", bad, values, err)
+	}
+	if want := map[string]int64{"count": 1, "bytes": 1, "ratio": 1}; !reflect.DeepEqual(c.Overflows, want) {
+		t.Errorf("overflows = %v, want %v", c.Overflows, want)
+	}
+
+	c = Converter{Overflow: OverflowError}
+	_, err = c.Document(fields, doc, values)
+	var rangeErr *RangeError
+	if !errors.As(err, &rangeErr) || rangeErr.Path != "count" {
+		t.Errorf("error: err = %v", err)
+	}
+	if _, err = c.Document(fields, map[string]interface{}{"count": 1.0}, values); err != nil {
+		t.Errorf("error policy kept the previous document's error: %v", err)
+	}
+}
+
+func TestWidenNumber(t *testing.T) {
+	cases := []struct {
+		dt   arrow.DataType
+		v    interface{}
+		want arrow.DataType
+	}{
+		{arrow.PrimitiveTypes.Int32, 12.0, arrow.PrimitiveTypes.Int32},
+		{arrow.PrimitiveTypes.Int32, "abc", arrow.PrimitiveTypes.Int32},
+		{arrow.PrimitiveTypes.Int32, 3e9, arrow.PrimitiveTypes.Int64},
+		{arrow.PrimitiveTypes.Int32, 1e19, arrow.PrimitiveTypes.Float64},
+		{arrow.PrimitiveTypes.Int64, 1e19, arrow.PrimitiveTypes.Float64},
+		{arrow.PrimitiveTypes.Float32, -1e39, arrow.PrimitiveTypes.Float64},
+		{arrow.BinaryTypes.String, 1e39, arrow.BinaryTypes.String},
+	}
+	for _, c := range cases {
+		if got := WidenNumber(c.dt, c.v); !arrow.TypeEqual(got, c.want) {
+			t.Errorf("WidenNumber(%s, %v) = %s, want %s", c.dt, c.v, got, c.want)
+		}
+	}
+	if _, err := ParseOverflowPolicy("wrap"); err == nil {
+		t.Error("ParseOverflowPolicy(wrap) succeeded")
+	}
+}
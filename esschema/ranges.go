@@ -0,0 +1,127 @@
+package esschema
+
+import (
+	"encoding/json"
+	"math"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+// rangeElemTypes는 range 타입마다 경계 값의 Arrow 타입입니다.
+var rangeElemTypes = map[string]func() arrow.DataType{
+	"integer_range": func() arrow.DataType { return arrow.PrimitiveTypes.Int32 },
+	"long_range":    func() arrow.DataType { return arrow.PrimitiveTypes.Int64 },
+	"float_range":   func() arrow.DataType { return arrow.PrimitiveTypes.Float32 },
+	"double_range":  func() arrow.DataType { return arrow.PrimitiveTypes.Float64 },
+	"date_range":    func() arrow.DataType { return arrow.FixedWidthTypes.Timestamp_ns },
+	"ip_range":      IPType,
+}
+
+// RangeType 함수는 경계 값이 elem 타입인 range 필드의 Arrow 타입을 반환합니다. 두 경계를
+// 포함하는 gte와 lte의 구조체이고, 한쪽이 열린 범위는 그 경계가 null입니다. format이 있는
+// date_range는 두 경계 필드에 DateFormatKey를 붙입니다.
+func RangeType(elem arrow.DataType, md arrow.Metadata) arrow.DataType {
+	return arrow.StructOf(
+		arrow.Field{Name: "gte", Type: elem, Nullable: true, Metadata: md},
+		arrow.Field{Name: "lte", Type: elem, Nullable: true, Metadata: md},
+	)
+}
+
+// IsRange 함수는 dt가 RangeType처럼 같은 타입의 gte와 lte 두 필드의 구조체인지 알려 줍니다.
+// 그런 구조체는 Elasticsearch가 range 필드로 받는 gt와 lt 경계도 받습니다.
+func IsRange(dt arrow.DataType) bool {
+	st, ok := dt.(*arrow.StructType)
+	if !ok || len(st.Fields()) != 2 {
+		return false
+	}
+	gte, lte := st.Field(0), st.Field(1)
+	return gte.Name == "gte" && lte.Name == "lte" && arrow.TypeEqual(gte.Type, lte.Type)
+}
+
+// rangeBounds 함수는 range 값 v를 gte와 lte만 있는 객체로 바꿉니다. Elasticsearch처럼 gt와
+// lt는 바로 다음과 바로 앞의 값(정수는 1, 날짜는 1밀리초, 실수와 주소는 다음 값)을 포함하는
+// 경계가 되고, ip_range의 CIDR 문자열(10.0.0.0/8)은 그 주소 블록의 처음과 끝이 됩니다. 읽을
+// 수 없는 값은 그대로 두어 바꿀 수 없는 값으로 남깁니다.
+func rangeBounds(t *arrow.StructType, v interface{}) interface{} {
+	bound := t.Field(0)
+	if s, ok := v.(string); ok && (IsIP(bound.Type) || isIPString(bound)) {
+		if p, err := netip.ParsePrefix(strings.TrimSpace(s)); err == nil {
+			first := p.Masked().Addr()
+			return map[string]interface{}{"gte": first.String(), "lte": lastAddr(first, p.Bits()).String()}
+		}
+		return v
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	out := map[string]interface{}{"gte": obj["gte"], "lte": obj["lte"]}
+	for _, b := range []struct {
+		exclusive, inclusive string
+		up                   bool
+	}{{"gt", "gte", true}, {"lt", "lte", false}} {
+		if x, ok := obj[b.exclusive]; ok && x != nil && out[b.inclusive] == nil {
+			out[b.inclusive] = adjacentValue(bound, x, b.up)
+		}
+	}
+	return out
+}
+
+// adjacentValue 함수는 경계 필드 f의 타입에서 v 바로 다음(up)이나 바로 앞의 값을 반환합니다.
+func adjacentValue(f arrow.Field, v interface{}, up bool) interface{} {
+	step := int64(-1)
+	if up {
+		step = 1
+	}
+	switch f.Type.ID() {
+	case arrow.INT32, arrow.INT64:
+		if i, ok := Int(v); ok {
+			return json.Number(strconv.FormatInt(i+step, 10))
+		}
+	case arrow.FLOAT32:
+		if x, ok := Float(v); ok {
+			return float64(math.Nextafter32(float32(x), float32(math.Inf(int(step)))))
+		}
+	case arrow.FLOAT64:
+		if x, ok := Float(v); ok {
+			return math.Nextafter(x, math.Inf(int(step)))
+		}
+	case arrow.TIMESTAMP:
+		df, _ := FieldDateFormat(f)
+		if t, ok := df.Time(v); ok {
+			return t.Add(time.Duration(step) * time.Millisecond)
+		}
+	case arrow.FIXED_SIZE_BINARY, arrow.STRING:
+		if addr, ok := ParseIP(v); ok {
+			if up {
+				addr = addr.Next()
+			} else {
+				addr = addr.Prev()
+			}
+			if addr.IsValid() {
+				return addr.String()
+			}
+		}
+	}
+	return v
+}
+
+// lastAddr 함수는 first에서 시작하는 bits 길이 주소 블록의 마지막 주소를 반환합니다.
+func lastAddr(first netip.Addr, bits int) netip.Addr {
+	b := first.AsSlice()
+	for i := range b {
+		// i번째 바이트에서 접두사가 차지하지 않는 비트를 모두 1로 채웁니다.
+		if keep := bits - i*8; keep < 8 {
+			if keep < 0 {
+				keep = 0
+			}
+			b[i] |= byte(0xff >> keep)
+		}
+	}
+	last, _ := netip.AddrFromSlice(b)
+	return last
+}
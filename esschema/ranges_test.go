@@ -0,0 +1,66 @@
+package esschema
+
+import (
+	"encoding/json"
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+func TestLastAddr(t *testing.T) {
+	for _, tc := range []struct{ prefix, want string }{
+		{"10.0.0.0/8", "10.255.255.255"},
+		{"192.168.1.128/25", "192.168.1.255"},
+		{"2001:db8::/32", "2001:db8:ffff:ffff:ffff:ffff:ffff:ffff"},
+		{"10.1.2.3/32", "10.1.2.3"},
+	} {
+		p := netip.MustParsePrefix(tc.prefix)
+		if got := lastAddr(p.Masked().Addr(), p.Bits()).String(); got != tc.want {
+			t.Errorf("lastAddr(%s) = %s, want %s", tc.prefix, got, tc.want)
+		}
+	}
+}
+
+func TestConvertRange(t *testing.T) {
+	var md arrow.Metadata
+	fields := []arrow.Field{
+		{Name: "age", Type: RangeType(arrow.PrimitiveTypes.Int32, md)},
+		{Name: "period", Type: RangeType(arrow.FixedWidthTypes.Timestamp_ms, arrow.NewMetadata([]string{DateFormatKey}, []string{"yyyy-MM-dd"}))},
+		{Name: "net", Type: RangeType(IPType(), md)},
+		{Name: "score", Type: RangeType(arrow.PrimitiveTypes.Float64, md)},
+	}
+	doc := map[string]interface{}{
+		"age":    map[string]interface{}{"gt": json.Number("17"), "lt": "65"},
+		"period": map[string]interface{}{"gte": "2024-01-01", "lt": "2024-02-01"},
+		"net":    "10.0.0.0/8",
+		"score":  []interface{}{map[string]interface{}{"gte": 0.5}},
+	}
+	values := make([]interface{}, len(fields))
+	bad, err := ConvertDocument(fields, doc, values)
+	if err != nil || len(bad) != 0 {
+		t.Fatalf("bad = %v, %v", bad, err)
+	}
+	jan, feb := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	want := []interface{}{
+		[]interface{}{int32(18), int32(64)},
+		[]interface{}{arrow.Timestamp(jan.UnixMilli()), arrow.Timestamp(feb.Add(-time.Millisecond).UnixMilli())},
+		nil,
+		[]interface{}{0.5, nil},
+	}
+	for i, w := range want {
+		if i != 2 && !reflect.DeepEqual(values[i], w) {
+			t.Errorf("%s = %v, want %v", fields[i].Name, values[i], w)
+		}
+	}
+	if net := values[2].([]interface{}); IPText(net[0].([]byte)) != "10.0.0.0" || IPText(net[1].([]byte)) != "10.255.255.255" {
+		t.Errorf("net = %v", net)
+	}
+
+	doc["age"] = map[string]interface{}{"gte": "young"}
+	if bad, _ := ConvertDocument(fields, doc, values); !reflect.DeepEqual(bad, []string{"age.gte"}) {
+		t.Errorf("bad = %v", bad)
+	}
+}
@@ -0,0 +1,90 @@
+package esschema
+
+import (
+	"strings"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+	"github.com/apache/arrow/go/v10/arrow/memory"
+)
+
+// RecordBuilder는 _source 문서를 한 건씩 받아 스키마의 Arrow 레코드를 만듭니다. 값은
+// ConvertValue의 규칙으로 바꾸고, 바꿀 수 없는 값은 null로 둡니다. 문서에서 배열로 나오는
+// 필드는 스키마에서 미리 리스트 타입이어야 합니다.
+type RecordBuilder struct {
+	schema *arrow.Schema
+	b      *array.RecordBuilder
+	values []interface{}
+	// Reject이면 바꿀 수 없는 값이 있는 문서를 null로 채우지 않고 통째로 뺍니다.
+	Reject bool
+	// Overflow는 숫자 타입의 범위를 넘는 값의 처리입니다. 비어 있으면 OverflowNull입니다.
+	Overflow OverflowPolicy
+	// Sanitize는 문자열 값을 추가하기 전에 고치는 방법입니다.
+	Sanitize Sanitize
+	conv     Converter
+}
+
+// NewRecordBuilder 함수는 schema의 레코드를 만드는 빌더를 만듭니다. 다 쓰면 Release를
+// 불러야 합니다.
+func NewRecordBuilder(mem memory.Allocator, schema *arrow.Schema) *RecordBuilder {
+	return &RecordBuilder{
+		schema: schema,
+		b:      array.NewRecordBuilder(mem, schema),
+		values: make([]interface{}, len(schema.Fields())),
+	}
+}
+
+// Append 함수는 doc을 한 행으로 추가하고, null로 둔 값의 필드 경로를 반환합니다. Reject일
+// 때 그런 값이 있거나 문서를 바꾸다 실패하면 행을 추가하지 않고 *RejectError를 반환합니다.
+// OverflowError일 때 범위를 넘는 숫자가 있으면 Reject와 관계없이 *RangeError를 반환합니다.
+func (r *RecordBuilder) Append(doc map[string]interface{}) ([]string, error) {
+	r.conv.Overflow = r.Overflow
+	r.conv.Sanitize = r.Sanitize
+	bad, err := r.conv.Document(r.schema.Fields(), doc, r.values)
+	if err == nil && r.Reject && len(bad) > 0 {
+		err = &RejectError{Fields: bad}
+	}
+	if err != nil {
+		return bad, err
+	}
+	for j, v := range r.values {
+		AppendConverted(r.b.Field(j), v)
+	}
+	return bad, nil
+}
+
+// Overflows 함수는 지금까지 추가한 문서에서 범위를 넘은 숫자 값의 수를 경로별로 반환합니다.
+// 빠진 문서의 값도 셉니다.
+func (r *RecordBuilder) Overflows() map[string]int64 {
+	return r.conv.Overflows
+}
+
+// Sanitized 함수는 지금까지 추가한 문서에서 Sanitize로 고친 문자열 값의 수를 경로별로
+// 반환합니다.
+func (r *RecordBuilder) Sanitized() map[string]int64 {
+	return r.conv.Sanitized
+}
+
+// NewRecord 함수는 지금까지 추가한 행의 레코드를 만들고 빌더를 비웁니다.
+func (r *RecordBuilder) NewRecord() arrow.Record {
+	return r.b.NewRecord()
+}
+
+// Schema 함수는 빌더의 스키마를 반환합니다.
+func (r *RecordBuilder) Schema() *arrow.Schema {
+	return r.schema
+}
+
+// Release 함수는 빌더의 메모리를 놓습니다.
+func (r *RecordBuilder) Release() {
+	r.b.Release()
+}
+
+// RejectError는 Reject인 RecordBuilder가 뺀 문서의 사유입니다.
+type RejectError struct {
+	Fields []string
+}
+
+func (e *RejectError) Error() string {
+	return "cannot convert " + strings.Join(e.Fields, ", ") + " to the mapped type"
+}
@@ -0,0 +1,123 @@
+package esschema
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+	"github.com/apache/arrow/go/v10/arrow/memory"
+	"github.com/apache/arrow/go/v10/parquet/pqarrow"
+)
+
+func TestRecordBuilder(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "count", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+		{Name: "tags", Type: arrow.ListOf(arrow.BinaryTypes.String), Nullable: true},
+	}, nil)
+	b := NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer b.Release()
+	docs := []map[string]interface{}{
+		{"count": 3.0, "tags": []interface{}{"a", "b"}},
+		{"count": "many", "tags": "c"},
+	}
+	for _, doc := range docs {
+		if _, err := b.Append(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b.Reject = true
+	bad, err := b.Append(map[string]interface{}{"count": "many"})
+	var reject *RejectError
+	if !errors.As(err, &reject) || len(bad) != 1 || bad[0] != "count" {
+		t.Errorf("Append with Reject = %v, %v", bad, err)
+	}
+
+	rec := b.NewRecord()
+	defer rec.Release()
+	if rec.NumRows() != 2 {
+		t.Fatalf("rows = %d, want 2", rec.NumRows())
+	}
+	counts := rec.Column(0).(*array.Int64)
+	if counts.Value(0) != 3 || !counts.IsNull(1) {
+		t.Errorf("count column = %v", counts)
+	}
+	if tags := rec.Column(1).(*array.List); tags.Len() != 2 || tags.IsNull(1) {
+		t.Errorf("tags column = %v", tags)
+	}
+}
+
+func TestRecordBuilderNullVectors(t *testing.T) {
+	vec := arrow.FixedSizeListOf(3, arrow.PrimitiveTypes.Float32)
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "v", Type: vec, Nullable: true},
+		{Name: "doc", Type: arrow.StructOf(arrow.Field{Name: "v", Type: vec, Nullable: true}), Nullable: true},
+	}, nil)
+	b := NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer b.Release()
+	for _, doc := range []map[string]interface{}{
+		{"v": []interface{}{1.0, 2.0, 3.0}, "doc": map[string]interface{}{"v": []interface{}{4.0, 5.0, 6.0}}},
+		{},
+	} {
+		if _, err := b.Append(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rec := b.NewRecord()
+	defer rec.Release()
+
+	// null인 fixed-size list도 자식 배열에서 3칸을 차지해야 합니다.
+	top := rec.Column(0).(*array.FixedSizeList)
+	nested := rec.Column(1).(*array.Struct).Field(0).(*array.FixedSizeList)
+	for name, l := range map[string]*array.FixedSizeList{"v": top, "doc.v": nested} {
+		if !l.IsNull(1) || l.ListValues().Len() != 6 {
+			t.Errorf("%s: null = %t, values = %d, want true, 6", name, l.IsNull(1), l.ListValues().Len())
+		}
+	}
+}
+
+func TestRecordMissingFieldsRoundTrip(t *testing.T) {
+	m, err := ParseMapping([]byte(`{"properties": {"count": {"type": "long"}, "v": {"type": "dense_vector", "dims": 2}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := ToArrowSchema(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer b.Release()
+	for _, doc := range []map[string]interface{}{{"count": 7.0, "v": []interface{}{1.0, 2.0}}, {}} {
+		if _, err := b.Append(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rec := b.NewRecord()
+	defer rec.Release()
+
+	// Parquet을 거쳐도 문서에 없던 필드는 0이 아니라 null입니다.
+	var buf bytes.Buffer
+	w, err := pqarrow.NewFileWriter(schema, &buf, nil, pqarrow.DefaultWriterProps())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(rec); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	table, err := pqarrow.ReadTable(context.Background(), bytes.NewReader(buf.Bytes()), nil, pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer table.Release()
+	for i := 0; i < int(table.NumCols()); i++ {
+		col := table.Column(i)
+		if chunk := col.Data().Chunk(0); !chunk.IsNull(1) {
+			t.Errorf("missing %s read back as %v, want null", col.Name(), chunk)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package esschema
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Sanitize는 문자열 값을 쓰기 전에 고치는 방법의 집합입니다. 로그에서 온 값에는 잘못된
+// UTF-8이나 제어 문자가 섞여 있기 쉬운데, 그런 값이 든 Parquet 파일은 일부 리더가 읽지
+// 않습니다.
+type Sanitize uint8
+
+const (
+	// SanitizeUTF8은 UTF-8이 아닌 바이트를 지웁니다. _source JSON을 읽을 때 잘못된 바이트는
+	// 이미 U+FFFD로 바뀌므로 변환(--transform 등)이 만든 문자열에 해당합니다.
+	SanitizeUTF8 Sanitize = 1 << iota
+	// SanitizeControl은 탭, 줄바꿈, 캐리지 리턴을 뺀 C0 제어 문자와 DEL, C1 제어 문자를
+	// 지웁니다.
+	SanitizeControl
+	// SanitizeNFC는 문자열을 유니코드 정규화 형식 NFC로 바꿉니다.
+	SanitizeNFC
+
+	// SanitizeAll은 모든 방법입니다.
+	SanitizeAll = SanitizeUTF8 | SanitizeControl | SanitizeNFC
+)
+
+// sanitizeNames는 ParseSanitize가 받는 이름입니다.
+var sanitizeNames = map[string]Sanitize{
+	"utf8":    SanitizeUTF8,
+	"control": SanitizeControl,
+	"nfc":     SanitizeNFC,
+	"all":     SanitizeAll,
+}
+
+// ParseSanitize 함수는 방법 이름(utf8, control, nfc, all)들을 읽습니다.
+func ParseSanitize(names []string) (Sanitize, error) {
+	var s Sanitize
+	for _, name := range names {
+		m, ok := sanitizeNames[strings.TrimSpace(name)]
+		if !ok {
+			return 0, fmt.Errorf("unknown string sanitization %q (want utf8, control, nfc or all)", name)
+		}
+		s |= m
+	}
+	return s, nil
+}
+
+// Apply 함수는 v를 고친 문자열과 바뀌었는지를 반환합니다. 고칠 것이 없으면 v를 그대로
+// 반환하므로 깨끗한 값에는 메모리를 더 쓰지 않습니다.
+func (s Sanitize) Apply(v string) (string, bool) {
+	if s == 0 {
+		return v, false
+	}
+	out := v
+	if s&SanitizeUTF8 != 0 && !utf8.ValidString(out) {
+		out = strings.ToValidUTF8(out, "")
+	}
+	if s&SanitizeControl != 0 && strings.IndexFunc(out, isStrippedControl) >= 0 {
+		out = strings.Map(func(r rune) rune {
+			if isStrippedControl(r) {
+				return -1
+			}
+			return r
+		}, out)
+	}
+	if s&SanitizeNFC != 0 {
+		if !norm.NFC.IsNormalString(out) {
+			out = norm.NFC.String(out)
+		}
+	}
+	return out, out != v
+}
+
+// isStrippedControl 함수는 SanitizeControl이 지우는 문자인지 알려 줍니다.
+func isStrippedControl(r rune) bool {
+	switch {
+	case r == '\t' || r == '\n' || r == '\r':
+		return false
+	case r < 0x20 || r == 0x7f:
+		return true
+	}
+	return r >= 0x80 && r <= 0x9f
+}
+
+// text 함수는 Sanitize대로 고친 문자열 값 v를 반환하고, 바뀌었으면 path에 셉니다.
+func (c *Converter) text(v, path string) string {
+	out, changed := c.Sanitize.Apply(v)
+	if changed {
+		if c.Sanitized == nil {
+			c.Sanitized = make(map[string]int64)
+		}
+		c.Sanitized[path]++
+	}
+	return out
+}
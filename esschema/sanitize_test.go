@@ -0,0 +1,67 @@
+package esschema
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+func TestSanitizeApply(t *testing.T) {
+	cases := []struct {
+		s       Sanitize
+		in, out string
+	}{
+		{SanitizeAll, "plain text", "plain text"},
+		{SanitizeControl, "a\x00b\x1b[0mc\u0085d\x7f", "ab[0mcd"},
+		{SanitizeControl, "line 1\r\n\tline 2", "line 1\r\n\tline 2"},
+		{SanitizeUTF8, "ok\xff\xfe!", "ok!"},
+		{SanitizeNFC, "cafe\u0301 Zu\u0308rich", "caf\u00e9 Z\u00fcrich"},
+		{SanitizeNFC, "\u1112\u1161\u11ab\u1100\u1173\u11af", "\ud55c\uae00"},
+		{SanitizeNFC, "\u0301e", "\u0301e"},
+		{SanitizeNFC, "\u0438\u0306", "\u0439"},
+		{SanitizeNFC, "Vie\u0323\u0302t", "Vi\u1ec7t"},
+		{SanitizeNFC, "Vie\u0302\u0323t", "Vi\u1ec7t"},
+		{SanitizeNFC, "\u03b1\u0301", "\u03ac"},
+		{SanitizeUTF8 | SanitizeNFC, "e\u0301\x00\xff", "\u00e9\x00"},
+		{0, "e\u0301\x00\xff", "e\u0301\x00\xff"},
+	}
+	for _, c := range cases {
+		out, changed := c.s.Apply(c.in)
+		if out != c.out || changed != (c.in != c.out) {
+			t.Errorf("Sanitize(%d).Apply(%q) = %q, %v, want %q", c.s, c.in, out, changed, c.out)
+		}
+	}
+}
+
+func TestParseSanitize(t *testing.T) {
+	if s, err := ParseSanitize([]string{"utf8", " control"}); err != nil || s != SanitizeUTF8|SanitizeControl {
+		t.Errorf("ParseSanitize(utf8, control) = %d, %v", s, err)
+	}
+	if s, err := ParseSanitize([]string{"all"}); err != nil || s != SanitizeAll {
+		t.Errorf("ParseSanitize(all) = %d, %v", s, err)
+	}
+	if _, err := ParseSanitize([]string{"nfkc"}); err == nil {
+		t.Error("ParseSanitize(nfkc) succeeded")
+	}
+}
+
+func TestConverterSanitize(t *testing.T) {
+	fields := []arrow.Field{
+		{Name: "message", Type: arrow.BinaryTypes.String},
+		{Name: "user", Type: arrow.StructOf(arrow.Field{Name: "name", Type: arrow.BinaryTypes.String})},
+	}
+	doc := map[string]interface{}{"message": "boot\x00ed", "user": map[string]interface{}{"name": "Jose\u0301"}}
+	values := make([]interface{}, len(fields))
+
+	c := Converter{Sanitize: SanitizeAll}
+	if bad, err := c.Document(fields, doc, values); err != nil || len(bad) > 0 {
+		t.Fatalf("bad = %v, err = %v", bad, err)
+	}
+	if values[0] != "booted" || !reflect.DeepEqual(values[1], []interface{}{"Jos\u00e9"}) {
+		t.Errorf("values = %q", values)
+	}
+	if want := map[string]int64{"message": 1, "user.name": 1}; !reflect.DeepEqual(c.Sanitized, want) {
+		t.Errorf("sanitized = %v, want %v", c.Sanitized, want)
+	}
+}
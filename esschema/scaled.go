@@ -0,0 +1,135 @@
+package esschema
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/decimal128"
+)
+
+// ScalingFactorKey는 scaled_float 필드의 Arrow 필드 메타데이터 키입니다. 값은 매핑의
+// scaling_factor이고, Converter는 이 수로 Elasticsearch가 저장하는 값을 만듭니다.
+const ScalingFactorKey = "es_schema.scaling_factor"
+
+// scaledFloatPrecision는 scaled_float 컬럼의 자릿수입니다. Elasticsearch는 값에
+// scaling_factor를 곱해 long으로 저장하므로 19자리를 넘지 않습니다.
+const scaledFloatPrecision = 19
+
+// ScaledFloatType 함수는 scaling_factor가 factor인 scaled_float 필드의 Arrow 타입을
+// 반환합니다. 소수 자릿수는 1/factor를 나타내는 데 필요한 자릿수(100이면 2)입니다.
+// factor가 없거나 양수가 아니면 float64입니다.
+func ScaledFloatType(factor float64) arrow.DataType {
+	if !(factor > 0) || math.IsInf(factor, 0) {
+		return arrow.PrimitiveTypes.Float64
+	}
+	// 10의 거듭제곱인 factor의 로그가 정수보다 아주 조금 크게 계산되어도 자릿수가 늘지 않게 합니다.
+	scale := int32(math.Ceil(math.Log10(factor) - 1e-9))
+	if scale < 0 {
+		scale = 0
+	}
+	precision := int32(scaledFloatPrecision)
+	if scale >= precision {
+		if scale >= 38 {
+			return arrow.PrimitiveTypes.Float64
+		}
+		precision = scale + 1
+	}
+	return &arrow.Decimal128Type{Precision: precision, Scale: scale}
+}
+
+// ScaledFloatsAsFloat64 함수는 fields 안(구조체와 리스트 안 포함)의 decimal 필드를 float64로
+// 바꾼 필드 목록을 반환합니다. decimal을 읽지 못하는 소비자를 위한 것으로, 매핑에서 decimal이
+// 되는 타입은 scaled_float뿐입니다.
+func ScaledFloatsAsFloat64(fields []arrow.Field) []arrow.Field {
+	out := make([]arrow.Field, len(fields))
+	for i, f := range fields {
+		f.Type = decimalAsFloat64(f.Type)
+		out[i] = f
+	}
+	return out
+}
+
+func decimalAsFloat64(dt arrow.DataType) arrow.DataType {
+	switch t := dt.(type) {
+	case *arrow.Decimal128Type:
+		return arrow.PrimitiveTypes.Float64
+	case *arrow.ListType:
+		return arrow.ListOf(decimalAsFloat64(t.Elem()))
+	case *arrow.FixedSizeListType:
+		return arrow.FixedSizeListOf(t.Len(), decimalAsFloat64(t.Elem()))
+	case *arrow.StructType:
+		return arrow.StructOf(ScaledFloatsAsFloat64(t.Fields())...)
+	}
+	return dt
+}
+
+// decimalRange 함수는 f가 t의 범위를 넘으면 t에서 가장 가까운 값과 true를 반환합니다.
+func decimalRange(t *arrow.Decimal128Type, f float64) (interface{}, bool) {
+	limit := math.Pow10(int(t.Precision - t.Scale))
+	if math.Abs(f) < limit {
+		return nil, false
+	}
+	// 가장 큰 값(자릿수만큼의 9)은 float64로 정확히 나타낼 수 없으므로 정수로 만듭니다.
+	largest := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(t.Precision)), nil)
+	largest.Sub(largest, big.NewInt(1))
+	if f < 0 {
+		largest.Neg(largest)
+	}
+	return decimal128.FromBigInt(largest), true
+}
+
+// FieldScalingFactor 함수는 필드 메타데이터의 ScalingFactorKey로 f의 scaling_factor를
+// 반환합니다. 없거나 양수가 아니면 false입니다.
+func FieldScalingFactor(f arrow.Field) (float64, bool) {
+	i := f.Metadata.FindKey(ScalingFactorKey)
+	if i < 0 {
+		return 0, false
+	}
+	factor, err := strconv.ParseFloat(f.Metadata.Values()[i], 64)
+	return factor, err == nil && factor > 0 && !math.IsInf(factor, 0)
+}
+
+// scaledValue 함수는 v(리스트면 각 원소)의 수를 Elasticsearch가 저장하는 값으로 바꿉니다.
+// dt의 범위를 넘는 수와 수가 아닌 값은 Converter가 넘침과 잘못된 값으로 다루도록 그대로
+// 둡니다.
+func scaledValue(dt arrow.DataType, v interface{}, factor float64) interface{} {
+	switch t := dt.(type) {
+	case *arrow.Decimal128Type:
+		f, ok := Float(v)
+		if !ok || math.IsNaN(f) {
+			return v
+		}
+		if _, over := decimalRange(t, f); over {
+			return v
+		}
+		if n, ok := scaledDecimal(t, f, factor); ok {
+			return n
+		}
+	case *arrow.ListType:
+		items, ok := v.([]interface{})
+		if !ok {
+			return scaledValue(t.Elem(), v, factor)
+		}
+		out := make([]interface{}, len(items))
+		for i, item := range items {
+			out[i] = scaledValue(t.Elem(), item, factor)
+		}
+		return out
+	}
+	return v
+}
+
+// scaledDecimal 함수는 Elasticsearch처럼 f에 factor를 곱해 반올림한 정수를 만들고, 그
+// 정수로 t의 decimal을 만듭니다. factor가 10의 거듭제곱이면 그 정수가 곧 decimal의 값이고,
+// 그렇지 않으면(3, 7 등) 정수를 factor로 나눈 값을 t의 자릿수로 반올림합니다.
+func scaledDecimal(t *arrow.Decimal128Type, f, factor float64) (decimal128.Num, bool) {
+	stored := math.Round(f * factor)
+	if factor == math.Pow10(int(t.Scale)) {
+		n, _ := new(big.Float).SetFloat64(stored).Int(nil)
+		return decimal128.FromBigInt(n), true
+	}
+	n, err := decimal128.FromFloat64(stored/factor, t.Precision, t.Scale)
+	return n, err == nil
+}
@@ -0,0 +1,83 @@
+package esschema
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/decimal128"
+)
+
+func TestScaledFloatType(t *testing.T) {
+	cases := map[float64][2]int32{
+		100:  {19, 2},
+		1000: {19, 3},
+		10:   {19, 1},
+		50:   {19, 2},
+		1:    {19, 0},
+		0.1:  {19, 0},
+		1e20: {21, 20},
+	}
+	for factor, want := range cases {
+		dt, ok := ScaledFloatType(factor).(*arrow.Decimal128Type)
+		if !ok || dt.Precision != want[0] || dt.Scale != want[1] {
+			t.Errorf("ScaledFloatType(%v) = %v, want decimal(%d, %d)", factor, ScaledFloatType(factor), want[0], want[1])
+		}
+	}
+	for _, factor := range []float64{0, -10, 1e40} {
+		if _, ok := ScaledFloatType(factor).(*arrow.Decimal128Type); ok {
+			t.Errorf("ScaledFloatType(%v) = %v, want float64", factor, ScaledFloatType(factor))
+		}
+	}
+}
+
+func TestConvertScaledFloat(t *testing.T) {
+	dt := ScaledFloatType(100)
+	var bad []string
+	if n, ok := ConvertValue(dt, "1.239", "price", &bad).(decimal128.Num); !ok || n.ToFloat64(2) != 1.24 {
+		t.Errorf("1.239 = %v, want 1.24", n)
+	}
+	c := Converter{Overflow: OverflowSaturate}
+	n, ok := c.Value(dt, 1e18, "price", &bad).(decimal128.Num)
+	if !ok || n.BigInt().String() != "9999999999999999999" || c.Overflows["price"] != 1 {
+		t.Errorf("saturated = %v, %v", n, c.Overflows)
+	}
+	if len(bad) != 0 {
+		t.Errorf("bad = %v", bad)
+	}
+	if got := WidenNumber(dt, 1e18); got.ID() != arrow.FLOAT64 {
+		t.Errorf("WidenNumber = %v, want float64", got)
+	}
+	fields := ScaledFloatsAsFloat64([]arrow.Field{{Name: "prices", Type: arrow.ListOf(dt)}})
+	if !arrow.TypeEqual(fields[0].Type, arrow.ListOf(arrow.PrimitiveTypes.Float64)) {
+		t.Errorf("ScaledFloatsAsFloat64 = %v", fields)
+	}
+}
+
+func TestConvertScaledFloatRoundsByFactor(t *testing.T) {
+	fields, err := Fields(map[string]interface{}{
+		"thirds":   map[string]interface{}{"type": "scaled_float", "scaling_factor": 3.0},
+		"sevenths": map[string]interface{}{"type": "scaled_float", "scaling_factor": 7.0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c Converter
+	values := make([]interface{}, len(fields))
+	// Elasticsearch는 1.2*3=3.6을 4로, 0.5*7=3.5를 4로 저장하므로 4/3과 4/7입니다.
+	doc := map[string]interface{}{"thirds": 1.2, "sevenths": 0.5}
+	if _, err := c.Document(fields, doc, values); err != nil {
+		t.Fatal(err)
+	}
+	// 두 factor 모두 소수 자릿수가 1이므로 0.6과 1.3은 6과 13입니다.
+	want := map[string]int64{"sevenths": 6, "thirds": 13}
+	for i, f := range fields {
+		if n, ok := values[i].(decimal128.Num); !ok || n.BigInt().Int64() != want[f.Name] {
+			t.Errorf("%s = %v, want %d", f.Name, values[i], want[f.Name])
+		}
+	}
+	// 값이 배열이면 원소마다 반올림합니다.
+	if got := scaledValue(arrow.ListOf(fields[1].Type), []interface{}{1.2, 0.5}, 3); fmt.Sprint(got) != "[{13 0} {7 0}]" {
+		t.Errorf("scaledValue of a list = %v", got)
+	}
+}
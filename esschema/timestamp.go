@@ -0,0 +1,49 @@
+package esschema
+
+import (
+	"fmt"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+// timeUnits는 ParseTimeUnit이 받는 단위 이름입니다.
+var timeUnits = map[string]arrow.TimeUnit{
+	"s":  arrow.Second,
+	"ms": arrow.Millisecond,
+	"us": arrow.Microsecond,
+	"ns": arrow.Nanosecond,
+}
+
+// ParseTimeUnit 함수는 s, ms, us, ns를 arrow.TimeUnit으로 읽습니다.
+func ParseTimeUnit(s string) (arrow.TimeUnit, error) {
+	if u, ok := timeUnits[s]; ok {
+		return u, nil
+	}
+	return 0, fmt.Errorf("unknown timestamp unit %q (want s, ms, us or ns)", s)
+}
+
+// TimestampsIn 함수는 fields의 timestamp 컬럼(date와 date_nanos 필드)을 시간대는 그대로 두고
+// unit 단위로 바꿉니다. 더 거친 단위로는 값의 나머지를 버리지만, 나노초로 담을 수 없는
+// 1677년 이전과 2262년 이후의 날짜도 담을 수 있습니다.
+func TimestampsIn(fields []arrow.Field, unit arrow.TimeUnit) []arrow.Field {
+	out := make([]arrow.Field, len(fields))
+	for i, f := range fields {
+		f.Type = timestampIn(f.Type, unit)
+		out[i] = f
+	}
+	return out
+}
+
+func timestampIn(dt arrow.DataType, unit arrow.TimeUnit) arrow.DataType {
+	switch t := dt.(type) {
+	case *arrow.TimestampType:
+		return &arrow.TimestampType{Unit: unit, TimeZone: t.TimeZone}
+	case *arrow.ListType:
+		return arrow.ListOf(timestampIn(t.Elem(), unit))
+	case *arrow.FixedSizeListType:
+		return arrow.FixedSizeListOf(t.Len(), timestampIn(t.Elem(), unit))
+	case *arrow.StructType:
+		return arrow.StructOf(TimestampsIn(t.Fields(), unit)...)
+	}
+	return dt
+}
@@ -0,0 +1,41 @@
+package esschema
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+func TestTimestampsIn(t *testing.T) {
+	fields, err := Fields(map[string]interface{}{
+		"seen":   map[string]interface{}{"type": "date_nanos"},
+		"events": map[string]interface{}{"type": "nested", "properties": map[string]interface{}{"at": map[string]interface{}{"type": "date"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields = TimestampsIn(fields, arrow.Microsecond)
+	want := []string{"list<item: struct<at: timestamp[us, tz=UTC]>, nullable>", "timestamp[us, tz=UTC]"}
+	for i, f := range fields {
+		if got := fmt.Sprint(f.Type); got != want[i] {
+			t.Errorf("%s = %s, want %s", f.Name, got, want[i])
+		}
+	}
+
+	var bad []string
+	at := time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.UTC)
+	if v := ConvertValue(fields[1].Type, at.Format(time.RFC3339Nano), "seen", &bad); v != arrow.Timestamp(at.UnixMicro()) {
+		t.Errorf("seen = %v, want %d", v, at.UnixMicro())
+	}
+}
+
+func TestParseTimeUnit(t *testing.T) {
+	if u, err := ParseTimeUnit("ms"); err != nil || u != arrow.Millisecond {
+		t.Errorf("ms = %v, %v", u, err)
+	}
+	if _, err := ParseTimeUnit("minutes"); err == nil {
+		t.Error("minutes: no error")
+	}
+}
@@ -0,0 +1,86 @@
+package esschema
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+// UnsizedVectorKey는 매핑에 dims가 없는 dense_vector 필드에 붙는 필드 메타데이터 키입니다.
+// Elasticsearch는 그런 필드의 차원을 처음 색인한 벡터로 정하므로, 필드는 길이 없는 리스트로
+// 두고 문서를 보고 SizeVector로 길이를 정하거나 UnsizedVectors로 찾아 알립니다.
+const UnsizedVectorKey = "es_schema.unsized_vector"
+
+// vectorType 함수는 dense_vector 매핑 props의 Arrow 타입을 반환합니다. element_type이
+// float(기본)이면 float32, byte이면 int8의 fixed-size list이고, bit는 8차원을 한 바이트에
+// 담는 int8 dims/8개입니다. dims가 없으면 원소 타입의 리스트입니다.
+func vectorType(props map[string]interface{}) (arrow.DataType, error) {
+	elem := arrow.DataType(arrow.PrimitiveTypes.Float32)
+	perElem := 1
+	switch et := props["element_type"]; et {
+	case nil, "float":
+	case "byte":
+		elem = arrow.PrimitiveTypes.Int8
+	case "bit":
+		elem, perElem = arrow.PrimitiveTypes.Int8, 8
+	default:
+		return nil, fmt.Errorf("dense_vector element_type %v is not supported (want float, byte or bit)", et)
+	}
+	dims, ok := props["dims"].(float64)
+	if !ok {
+		return arrow.ListOf(elem), nil
+	}
+	if dims < 1 || dims != math.Trunc(dims) || int(dims)%perElem != 0 {
+		if perElem > 1 {
+			return nil, fmt.Errorf("dense_vector dims %v is not a positive multiple of %d", dims, perElem)
+		}
+		return nil, fmt.Errorf("dense_vector dims %v is not a positive integer", dims)
+	}
+	return arrow.FixedSizeListOf(int32(dims)/int32(perElem), elem), nil
+}
+
+// IsUnsizedVector 함수는 f가 dims가 없는 dense_vector 필드인지 알려 줍니다.
+func IsUnsizedVector(f arrow.Field) bool {
+	return f.Metadata.FindKey(UnsizedVectorKey) >= 0
+}
+
+// UnsizedVectors 함수는 fields에서 아직 길이가 정해지지 않은 dense_vector 필드의 경로를
+// 점으로 구분해 반환합니다. 구조체와 구조체 리스트 안으로 들어갑니다.
+func UnsizedVectors(fields []arrow.Field) []string {
+	var paths []string
+	for _, f := range fields {
+		if IsUnsizedVector(f) {
+			if _, sized := f.Type.(*arrow.FixedSizeListType); !sized {
+				paths = append(paths, f.Name)
+			}
+			continue
+		}
+		t := f.Type
+		if lt, ok := t.(*arrow.ListType); ok {
+			t = lt.Elem()
+		}
+		if st, ok := t.(*arrow.StructType); ok {
+			for _, p := range UnsizedVectors(st.Fields()) {
+				paths = append(paths, f.Name+"."+p)
+			}
+		}
+	}
+	return paths
+}
+
+// SizeVector 함수는 dims가 없는 dense_vector 필드의 리스트 타입 t를 벡터 v의 길이의
+// fixed-size list로 바꿉니다. v가 비어 있지 않은 숫자 배열이 아니면 t를 그대로 반환합니다.
+func SizeVector(t arrow.DataType, v interface{}) arrow.DataType {
+	lt, ok := t.(*arrow.ListType)
+	items, isArray := v.([]interface{})
+	if !ok || !isArray || len(items) == 0 {
+		return t
+	}
+	for _, item := range items {
+		if _, ok := Float(item); !ok {
+			return t
+		}
+	}
+	return arrow.FixedSizeListOf(int32(len(items)), lt.Elem())
+}
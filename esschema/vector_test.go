@@ -0,0 +1,93 @@
+package esschema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+func TestVectorType(t *testing.T) {
+	for _, tc := range []struct {
+		props map[string]interface{}
+		want  arrow.DataType
+	}{
+		{map[string]interface{}{"dims": 3.0}, arrow.FixedSizeListOf(3, arrow.PrimitiveTypes.Float32)},
+		{map[string]interface{}{"dims": 4.0, "element_type": "byte"}, arrow.FixedSizeListOf(4, arrow.PrimitiveTypes.Int8)},
+		{map[string]interface{}{"dims": 16.0, "element_type": "bit"}, arrow.FixedSizeListOf(2, arrow.PrimitiveTypes.Int8)},
+		{map[string]interface{}{"element_type": "byte"}, arrow.ListOf(arrow.PrimitiveTypes.Int8)},
+		{map[string]interface{}{}, arrow.ListOf(arrow.PrimitiveTypes.Float32)},
+	} {
+		got, err := vectorType(tc.props)
+		if err != nil || !arrow.TypeEqual(got, tc.want) {
+			t.Errorf("vectorType(%v) = %v, %v, want %v", tc.props, got, err, tc.want)
+		}
+	}
+}
+
+func TestVectorTypeErrors(t *testing.T) {
+	for _, tc := range []struct {
+		props map[string]interface{}
+		want  string
+	}{
+		{map[string]interface{}{"dims": 3.0, "element_type": "half"}, "element_type half"},
+		{map[string]interface{}{"dims": 0.0}, "not a positive integer"},
+		{map[string]interface{}{"dims": 2.5}, "not a positive integer"},
+		{map[string]interface{}{"dims": 12.0, "element_type": "bit"}, "multiple of 8"},
+	} {
+		if _, err := vectorType(tc.props); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("vectorType(%v) = %v, want an error with %q", tc.props, err, tc.want)
+		}
+	}
+}
+
+func TestUnsizedVectors(t *testing.T) {
+	fields, err := Fields(map[string]interface{}{
+		"sized":     map[string]interface{}{"type": "dense_vector", "dims": 2.0},
+		"embedding": map[string]interface{}{"type": "dense_vector"},
+		"chunks": map[string]interface{}{"type": "nested", "properties": map[string]interface{}{
+			"vector": map[string]interface{}{"type": "dense_vector", "element_type": "byte"},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := UnsizedVectors(fields), []string{"chunks.vector", "embedding"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("UnsizedVectors = %v, want %v", got, want)
+	}
+
+	// 길이를 정한 필드는 더 찾지 않습니다.
+	embedding := fields[1]
+	embedding.Type = SizeVector(embedding.Type, []interface{}{0.5, 1.5, -2.0})
+	if want := arrow.FixedSizeListOf(3, arrow.PrimitiveTypes.Float32); !arrow.TypeEqual(embedding.Type, want) {
+		t.Fatalf("SizeVector = %v, want %v", embedding.Type, want)
+	}
+	if got := UnsizedVectors([]arrow.Field{embedding}); len(got) != 0 {
+		t.Errorf("UnsizedVectors after SizeVector = %v", got)
+	}
+	for _, v := range []interface{}{nil, []interface{}{}, []interface{}{"a"}, 1.0} {
+		if got := SizeVector(fields[1].Type, v); !arrow.TypeEqual(got, fields[1].Type) {
+			t.Errorf("SizeVector(%v) = %v, want the list unchanged", v, got)
+		}
+	}
+}
+
+func TestConvertByteVector(t *testing.T) {
+	fields, err := Fields(map[string]interface{}{
+		"embedding": map[string]interface{}{"type": "dense_vector", "dims": 3.0, "element_type": "byte"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make([]interface{}, 1)
+	if bad, err := ConvertDocument(fields, map[string]interface{}{"embedding": []interface{}{-128.0, 0.0, 127.0}}, values); err != nil || len(bad) != 0 {
+		t.Fatalf("bad = %v, %v", bad, err)
+	}
+	if want := []interface{}{int8(-128), int8(0), int8(127)}; !reflect.DeepEqual(values[0], want) {
+		t.Errorf("embedding = %v, want %v", values[0], want)
+	}
+	if bad, _ := ConvertDocument(fields, map[string]interface{}{"embedding": []interface{}{1.0, 2.0, 300.0}}, values); !reflect.DeepEqual(bad, []string{"embedding"}) {
+		t.Errorf("out-of-range byte: bad = %v", bad)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// maxEstimateSample은 표본 검색 한 번으로 가져올 수 있는 최대 문서 수입니다
+// (Elasticsearch 기본 index.max_result_window).
+const maxEstimateSample = 10000
+
+// outputEstimate는 표본 변환 결과로 추정한 전체 Parquet 출력 크기와 소요 시간입니다.
+type outputEstimate struct {
+	Documents          int64   `json:"documents"`
+	StoreBytes         int64   `json:"store_bytes"`
+	SampleDocuments    int64   `json:"sample_documents"`
+	SampleSourceBytes  int64   `json:"sample_source_bytes"`
+	SampleParquetBytes int64   `json:"sample_parquet_bytes"`
+	SampleSeconds      float64 `json:"sample_seconds"`
+	ParquetBytes       int64   `json:"parquet_bytes"`
+	Seconds            float64 `json:"seconds"`
+}
+
+// extrapolate 함수는 표본 문서당 크기와 시간을 전체 문서 수에 곱해 추정치를 채웁니다.
+func (e *outputEstimate) extrapolate() {
+	if e.SampleDocuments == 0 {
+		return
+	}
+	scale := float64(e.Documents) / float64(e.SampleDocuments)
+	e.ParquetBytes = int64(float64(e.SampleParquetBytes) * scale)
+	e.Seconds = e.SampleSeconds * scale
+}
+
+// estimateOutput 함수는 원본 인덱스의 문서 수와 저장 크기를 읽고, 무작위 표본을 실제
+// 설정(--parquet 관련 플래그와 이름 규칙)대로 임시 Parquet 파일에 써서 전체 출력을
+// 추정합니다. 시간에는 표본 검색, 정규화, 쓰기가 모두 들어갑니다.
+func (o *migrateOptions) estimateOutput(ctx context.Context, src *esClient, mapping []byte) (*outputEstimate, error) {
+	e := &outputEstimate{}
+	var err error
+	if e.Documents, err = src.count(ctx, o.index, json.RawMessage(o.query)); err != nil {
+		return nil, err
+	}
+	if e.StoreBytes, err = src.indexStoreBytes(ctx, o.index); err != nil {
+		return nil, err
+	}
+	if e.Documents == 0 {
+		return e, nil
+	}
+	schema, err := o.normalizationSchema(mapping)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "es-schema-estimate-")
+	if err != nil {
+		return nil, configErrorf("creating estimate directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "sample.parquet")
+
+	start := time.Now()
+	hits, err := src.sample(ctx, o.index, json.RawMessage(o.query), o.estimateSample)
+	if err != nil || len(hits) == 0 {
+		return e, err
+	}
+	for _, hit := range hits {
+		e.SampleSourceBytes += int64(len(hit.Source))
+	}
+	// 추정에서는 빠지는 문서를 따로 보고하지 않고, 변환된 문서만 셉니다.
+	var rejects docRejects
+	docs, hits, err := rejects.decodeHits(hits)
+	if err != nil {
+		return nil, err
+	}
+	norm := newNormalizer(schema)
+	norm.rejectBad = o.badDocuments.skip()
+	norm.widen(docs)
+	rec, rejected := norm.record(docs)
+	defer rec.Release()
+	if hits, _, err = rejects.keep(hits, docs, rejected); err != nil {
+		return nil, err
+	}
+	sink, err := newParquetSink(path, rec.Schema(), mapping, sinkColumns{}, &o.names, &o.parquet)
+	if err != nil {
+		return nil, err
+	}
+	if err := sink.writeHits(rec, hits); err != nil {
+		sink.abort()
+		return nil, err
+	}
+	if err := sink.close(); err != nil {
+		return nil, err
+	}
+	e.SampleSeconds = time.Since(start).Seconds()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, dataErrorf("checking sample file: %w", err)
+	}
+	e.SampleDocuments = int64(len(hits))
+	e.SampleParquetBytes = info.Size()
+	e.extrapolate()
+	return e, nil
+}
+
+// writeEstimate 함수는 dry-run 보고서의 출력 추정 부분을 씁니다.
+func writeEstimate(w io.Writer, e *outputEstimate) {
+	fmt.Fprintf(w, "output estimate: %d documents, %s primary store\n", e.Documents, formatBytes(e.StoreBytes))
+	if e.SampleDocuments == 0 {
+		return
+	}
+	fmt.Fprintf(w, "  sample: %d documents, %s _source -> %s Parquet in %.2fs\n",
+		e.SampleDocuments, formatBytes(e.SampleSourceBytes), formatBytes(e.SampleParquetBytes), e.SampleSeconds)
+	fmt.Fprintf(w, "  estimated Parquet size: %s", formatBytes(e.ParquetBytes))
+	if e.StoreBytes > 0 {
+		fmt.Fprintf(w, " (%.0f%% of the primary store)", 100*float64(e.ParquetBytes)/float64(e.StoreBytes))
+	}
+	fmt.Fprintf(w, "\n  estimated duration: %s\n", time.Duration(e.Seconds*float64(time.Second)).Round(time.Second))
+}
+
+// formatBytes 함수는 바이트 수를 사람이 읽기 쉬운 단위로 씁니다.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
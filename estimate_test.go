@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestOutputEstimateExtrapolatesSample(t *testing.T) {
+	e := &outputEstimate{
+		Documents:          2097152,
+		StoreBytes:         4 << 30,
+		SampleDocuments:    1024,
+		SampleSourceBytes:  2 << 20,
+		SampleParquetBytes: 512 << 10,
+		SampleSeconds:      0.5,
+	}
+	e.extrapolate()
+	if e.ParquetBytes != 1<<30 {
+		t.Errorf("ParquetBytes = %d, want %d", e.ParquetBytes, 1<<30)
+	}
+	if e.Seconds != 1024 {
+		t.Errorf("Seconds = %v, want 1024", e.Seconds)
+	}
+
+	var buf bytes.Buffer
+	writeEstimate(&buf, e)
+	for _, want := range []string{"1.0 GiB (25% of the primary store)", "estimated duration: 17m4s"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("estimate report missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for n, want := range map[int64]string{512: "512 B", 1536: "1.5 KiB", 3 << 20: "3.0 MiB"} {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+// explosionThreshold는 매핑 폭발로 보고 경고하는 필드 수입니다. Elasticsearch의
+// index.mapping.total_fields.limit 기본값과 같습니다.
+const explosionThreshold = 1000
+
+// overflowKey는 컬럼으로 만들지 않은 필드를 JSON으로 모은 컬럼에 붙이는 필드 메타데이터
+// 키입니다. 가져오기 시 이 컬럼의 JSON을 문서에 다시 합칩니다.
+const overflowKey = "es_schema.overflow"
+
+// fieldCapOptions는 동적 필드가 지나치게 많은 인덱스를 다루는 설정입니다. maxFields를
+// 넘는 매핑은 표본 문서에서 값이 많이 채워진 필드만 컬럼으로 남기고, 나머지는
+// overflowColumn 한 컬럼에 JSON으로 모읍니다.
+type fieldCapOptions struct {
+	maxFields      int
+	sample         int
+	overflowColumn string
+}
+
+func (o *fieldCapOptions) bind(fs *flag.FlagSet) {
+	fs.IntVar(&o.maxFields, "max-fields", 0, "when a mapping has more leaf fields than this, keep only the most populated ones as columns and put the rest in --overflow-column (0: no limit)")
+	fs.IntVar(&o.sample, "field-sample", 1000, "random documents sampled per index to rank fields by how often they hold a value for --max-fields")
+	fs.StringVar(&o.overflowColumn, "overflow-column", "_overflow", "column that holds the fields left out by --max-fields as a JSON object")
+}
+
+func (o *fieldCapOptions) validate() error {
+	if o.maxFields < 0 {
+		return configErrorf("--max-fields must not be negative")
+	}
+	if o.maxFields > 0 && (o.sample <= 0 || o.sample > maxEstimateSample) {
+		return configErrorf("--field-sample must be between 1 and %d", maxEstimateSample)
+	}
+	if o.overflowColumn == "" {
+		return configErrorf("--overflow-column must not be empty")
+	}
+	return nil
+}
+
+// fieldCap은 한 인덱스에 적용한 필드 상한의 결과입니다.
+type fieldCap struct {
+	mapping []byte
+	// kept는 컬럼으로 남긴 잎 필드의 경로이고, parents는 그 필드들을 품은 객체 필드의
+	// 경로입니다.
+	kept     map[string]bool
+	parents  map[string]bool
+	overflow arrow.Field
+}
+
+// capFields 함수는 매핑의 잎 필드 수를 세고, maxFields를 넘으면 index에서 표본 문서를
+// 가져와 값이 채워진 문서 수가 많은 순으로 maxFields개를 남긴 매핑을 만듭니다. 상한을
+// 넘지 않으면 nil을 반환합니다. chain은 표본에도 export와 같은 변환을 적용하기 위해 받습니다.
+func (o *fieldCapOptions) capFields(ctx context.Context, client *esClient, index string, query json.RawMessage, mapping []byte, chain transformChain) (*fieldCap, int, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal(mapping, &m); err != nil {
+		return nil, 0, schemaErrorf("decoding mapping of %s: %w", index, err)
+	}
+	props, _ := m["properties"].(map[string]interface{})
+	leaves := mappingLeafPaths(props, "")
+	if o.maxFields == 0 || len(leaves) <= o.maxFields {
+		return nil, len(leaves), nil
+	}
+	if _, ok := props[o.overflowColumn]; ok {
+		return nil, 0, configErrorf("%s already has a field %s; choose another --overflow-column", index, o.overflowColumn)
+	}
+
+	hits, err := client.sample(ctx, index, query, o.sample)
+	if err != nil {
+		return nil, 0, err
+	}
+	counts := make(map[string]int64)
+	for _, hit := range hits {
+		doc, err := decodeDocument(hit.Source)
+		if err != nil {
+			continue
+		}
+		if doc, err = chain.apply(doc); err != nil || doc == nil {
+			continue
+		}
+		countPopulated(doc, "", counts)
+	}
+	kept := topFields(leaves, counts, o.maxFields)
+	pruneMapping(props, "", kept)
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, 0, schemaErrorf("encoding capped mapping of %s: %w", index, err)
+	}
+	return &fieldCap{
+		mapping: data,
+		kept:    kept,
+		parents: fieldParents(kept),
+		overflow: arrow.Field{
+			Name:     o.overflowColumn,
+			Type:     arrow.BinaryTypes.String,
+			Nullable: true,
+			Metadata: arrow.NewMetadata([]string{overflowKey}, []string{"json"}),
+		},
+	}, len(leaves), nil
+}
+
+// mappingLeafPaths 함수는 매핑에서 하위 필드가 없는 필드의 경로를 모두 반환합니다.
+func mappingLeafPaths(props map[string]interface{}, prefix string) []string {
+	var paths []string
+	for _, name := range sortedParams(props) {
+		field, ok := props[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if sub, ok := field["properties"].(map[string]interface{}); ok {
+			paths = append(paths, mappingLeafPaths(sub, prefix+name+".")...)
+			continue
+		}
+		paths = append(paths, prefix+name)
+	}
+	return paths
+}
+
+// countPopulated 함수는 doc에서 null이 아닌 값이 있는 경로마다 counts를 하나씩 늘립니다.
+// 객체 배열(nested) 안의 필드는 문서 하나에 여러 번 나와도 한 번만 셉니다.
+func countPopulated(doc map[string]interface{}, prefix string, counts map[string]int64) {
+	seen := make(map[string]bool)
+	var walk func(v interface{}, path string)
+	walk = func(v interface{}, path string) {
+		switch v := v.(type) {
+		case nil:
+		case map[string]interface{}:
+			for k, child := range v {
+				walk(child, path+"."+k)
+			}
+		case []interface{}:
+			for _, item := range v {
+				walk(item, path)
+			}
+		default:
+			seen[path] = true
+		}
+	}
+	for k, v := range doc {
+		walk(v, prefix+k)
+	}
+	for path := range seen {
+		counts[path]++
+	}
+}
+
+// topFields 함수는 counts가 큰 순(같으면 경로 순)으로 n개의 잎 필드를 고릅니다.
+func topFields(leaves []string, counts map[string]int64, n int) map[string]bool {
+	ranked := append([]string(nil), leaves...)
+	sort.SliceStable(ranked, func(i, j int) bool { return counts[ranked[i]] > counts[ranked[j]] })
+	kept := make(map[string]bool, n)
+	for _, path := range ranked[:n] {
+		kept[path] = true
+	}
+	return kept
+}
+
+// pruneMapping 함수는 kept에 없는 잎 필드를 properties에서 지웁니다. 남은 하위 필드가
+// 없어진 객체 필드도 지웁니다.
+func pruneMapping(props map[string]interface{}, prefix string, kept map[string]bool) {
+	for name, v := range props {
+		field, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		sub, isObject := field["properties"].(map[string]interface{})
+		switch {
+		case isObject:
+			pruneMapping(sub, prefix+name+".", kept)
+			if len(sub) == 0 {
+				delete(props, name)
+			}
+		case !kept[prefix+name]:
+			delete(props, name)
+		}
+	}
+}
+
+// overflowTransform 함수는 컬럼으로 남기지 않은 값을 문서에서 떼어 column에 JSON 문자열로
+// 넣는 변환을 만듭니다. 객체 배열은 나눌 수 없으므로 배열을 품은 필드가 컬럼으로 남아
+// 있으면 배열째 그 자리에 둡니다.
+func (c *fieldCap) overflowTransform(column string) docTransform {
+	return func(doc map[string]interface{}) (map[string]interface{}, error) {
+		rest := c.split(doc, "")
+		if len(rest) == 0 {
+			return doc, nil
+		}
+		data, err := json.Marshal(rest)
+		if err != nil {
+			return nil, fmt.Errorf("encoding %s: %w", column, err)
+		}
+		doc[column] = string(data)
+		return doc, nil
+	}
+}
+
+// split 함수는 obj에서 컬럼이 없는 필드를 지우고, 지운 값을 같은 구조의 객체로 반환합니다.
+func (c *fieldCap) split(obj map[string]interface{}, prefix string) map[string]interface{} {
+	var rest map[string]interface{}
+	for k, v := range obj {
+		path := prefix + k
+		if c.kept[path] {
+			continue
+		}
+		if child, ok := v.(map[string]interface{}); ok && c.parents[path] {
+			if sub := c.split(child, path+"."); len(sub) > 0 {
+				if rest == nil {
+					rest = make(map[string]interface{})
+				}
+				rest[k] = sub
+			}
+			continue
+		}
+		if _, ok := v.([]interface{}); ok && c.parents[path] {
+			continue
+		}
+		if rest == nil {
+			rest = make(map[string]interface{})
+		}
+		rest[k] = v
+		delete(obj, k)
+	}
+	return rest
+}
+
+// fieldParents 함수는 paths의 필드를 품은 객체 필드의 경로를 모두 반환합니다.
+func fieldParents(paths map[string]bool) map[string]bool {
+	parents := make(map[string]bool)
+	for p := range paths {
+		for i, c := range p {
+			if c == '.' {
+				parents[p[:i]] = true
+			}
+		}
+	}
+	return parents
+}
+
+// isOverflowColumn 함수는 field가 --overflow-column으로 만든 컬럼인지 알려 줍니다.
+func isOverflowColumn(f arrow.Field) bool {
+	return f.Metadata.FindKey(overflowKey) >= 0
+}
+
+// mergeOverflow 함수는 overflow 컬럼의 JSON 객체를 doc에 합칩니다. 같은 객체 필드는 안으로
+// 들어가 합칩니다.
+func mergeOverflow(doc map[string]interface{}, raw string) {
+	v, err := decodeJSONValue([]byte(raw))
+	if rest, ok := v.(map[string]interface{}); ok && err == nil {
+		mergeObjects(doc, rest)
+	}
+}
+
+func mergeObjects(dst, src map[string]interface{}) {
+	for k, v := range src {
+		if srcObj, ok := v.(map[string]interface{}); ok {
+			if dstObj, ok := dst[k].(map[string]interface{}); ok {
+				mergeObjects(dstObj, srcObj)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestCapFieldsKeepsMostPopulated(t *testing.T) {
+	var m map[string]interface{}
+	json.Unmarshal([]byte(`{"properties": {
+		"a": {"type": "keyword"},
+		"b": {"type": "keyword"},
+		"attrs": {"properties": {"x": {"type": "long"}, "y": {"type": "long"}}}
+	}}`), &m)
+	props := m["properties"].(map[string]interface{})
+	leaves := mappingLeafPaths(props, "")
+	if want := []string{"a", "attrs.x", "attrs.y", "b"}; !reflect.DeepEqual(leaves, want) {
+		t.Fatalf("leaves = %v, want %v", leaves, want)
+	}
+
+	counts := make(map[string]int64)
+	for _, doc := range []string{
+		`{"a": "1", "attrs": {"x": 1}}`,
+		`{"a": "2", "attrs": {"x": 2, "y": null}, "b": "z"}`,
+	} {
+		d, err := decodeDocument([]byte(doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		countPopulated(d, "", counts)
+	}
+	kept := topFields(leaves, counts, 2)
+	if want := map[string]bool{"a": true, "attrs.x": true}; !reflect.DeepEqual(kept, want) {
+		t.Fatalf("kept = %v, want %v", kept, want)
+	}
+	pruneMapping(props, "", kept)
+	if got := mappingLeafPaths(props, ""); !reflect.DeepEqual(got, []string{"a", "attrs.x"}) {
+		t.Errorf("pruned mapping leaves = %v", got)
+	}
+
+	fc := &fieldCap{kept: kept, parents: fieldParents(kept)}
+	doc, _ := decodeDocument([]byte(`{"a": "1", "b": "z", "attrs": {"x": 1, "y": 2}}`))
+	out, err := fc.overflowTransform("_overflow")(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := out["_overflow"].(string)
+	var rest map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &rest); err != nil {
+		t.Fatalf("_overflow = %q: %v", raw, err)
+	}
+	if want := map[string]interface{}{"b": "z", "attrs": map[string]interface{}{"y": 2.0}}; !reflect.DeepEqual(rest, want) {
+		t.Errorf("_overflow = %s", raw)
+	}
+	if _, ok := out["b"]; ok {
+		t.Error("b was left in the document")
+	}
+
+	delete(out, "_overflow")
+	mergeOverflow(out, raw)
+	want, _ := decodeDocument([]byte(`{"a": "1", "b": "z", "attrs": {"x": 1, "y": 2}}`))
+	if !reflect.DeepEqual(out, want) {
+		t.Errorf("merged document = %v, want %v", out, want)
+	}
+}
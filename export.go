@@ -0,0 +1,1425 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+const (
+	splitNone   = "none"
+	splitShards = "shards"
+)
+
+// exportOptions는 export 명령의 설정입니다. export는 하나 이상의 인덱스를 인덱스마다
+// Parquet 파일 하나로 내보냅니다.
+type exportOptions struct {
+	source     esOptions
+	indices    stringListFlag
+	query      string
+	scrollSize int
+	keepAlive  time.Duration
+	outDir     string
+	listFields stringListFlag
+	fields     fieldSelectionOptions
+	fieldCap   fieldCapOptions
+	// unify이면 모든 인덱스를 _field_caps로 맞춘 하나의 매핑으로 내보내, 파일마다 스키마가
+	// 같아집니다.
+	unify     bool
+	conflicts typeConflictOptions
+	// applyNormalizers이면 normalizer가 있는 keyword 값을 검색에서 보이는 형태로 바꿔 씁니다.
+	applyNormalizers bool
+	analyze          analyzeOptions
+	dictionary       dictionaryOptions
+	nonData          nonDataOptions
+	nestedCopies     nestedCopyOptions
+	catalog          catalogOptions
+	budget           budgetOptions
+	// workers는 동시에 scroll할 수 있는 최대 수로, 모든 인덱스가 함께 나눠 씁니다.
+	workers int
+	// progressEvery는 인덱스별 진행 상황을 출력하는 최소 간격입니다.
+	progressEvery time.Duration
+	// split이 shards이면 인덱스를 샤드마다 따로 scroll해, 한 인덱스 안에서도 샤드 수만큼
+	// 작업자를 씁니다.
+	split string
+	// pit이면 실행 전체에 point in time 하나를 열어, 모든 인덱스와 샤드 조각의 검색이 같은
+	// 스냅샷을 보게 합니다.
+	pit bool
+	// seqNo이면 문서마다 _seq_no와 _primary_term 컬럼을 씁니다.
+	seqNo bool
+	// docHash이면 문서마다 정규화된 내용의 해시를 _doc_hash 컬럼에 씁니다.
+	docHash bool
+	// geoWKB이면 top-level geo_point 필드도 WKB 컬럼으로 쓰고 GeoParquet 메타데이터를
+	// 남깁니다. geo_shape 필드는 --geo-shape json이 아니면 언제나 WKB 컬럼입니다.
+	geoWKB bool
+	// geoLatLon이면 geo_point 필드마다 위도와 경도 float64 컬럼을 더합니다.
+	geoLatLon bool
+	// memoryStats이면 컬럼별 Arrow 버퍼 크기를 모아 출력하고 보고서에 남깁니다.
+	memoryStats bool
+	// lineage이면 인덱스마다 컬럼 계보 파일을 씁니다.
+	lineage bool
+	// tombstones는 삭제된 문서를 찾아 tombstone으로 쓰는 설정이고, dataQuery는 query에서
+	// 소프트 삭제된 문서를 뺀, 실제로 내보낼 문서의 검색 조건입니다.
+	tombstones tombstoneOptions
+	dataQuery  string
+	partition  partitionOptions
+	delta      deltaOptions
+	slices     sliceOptions
+	// projections는 인덱스마다 따로 쓰는 출력의 컬럼 집합과 가림 규칙입니다.
+	projections projectionOptions
+
+	names        nameOptions
+	parquet      parquetOptions
+	encryption   encryptionOptions
+	badDocuments badDocumentOptions
+	overflow     overflowOptions
+	sanitize     sanitizeOptions
+	ipFormat     ipFormatOptions
+	scaled       scaledFloatOptions
+	tsUnit       timestampUnitOptions
+	flattened    flattenedFormatOptions
+	geoShape     geoShapeFormatOptions
+	vectorDims   vectorDimsOptions
+	schemaLock   schemaLockOptions
+	transforms   transformOptions
+	chain        transformChain
+	recordHooks  recordHookOptions
+	hooks        recordHookChain
+}
+
+// indexReport는 export에서 인덱스 하나의 결과입니다.
+type indexReport struct {
+	Index string `json:"index"`
+	// Projection은 --projection일 때 이 결과의 프로젝션 이름입니다.
+	Projection string `json:"projection,omitempty"`
+	Status     string `json:"status"`
+	Rows       int64  `json:"rows"`
+	File       string `json:"file,omitempty"`
+	// Unchanged는 --delta에서 지난 manifest와 같아 건너뛴 문서 수입니다.
+	Unchanged int64 `json:"unchanged,omitempty"`
+	// Deleted는 tombstone으로 쓴 문서 수이고, DeletesFile은 --tombstones file의 파일입니다.
+	Deleted     int64  `json:"deleted,omitempty"`
+	DeletesFile string `json:"deletes_file,omitempty"`
+	// ColumnMemory는 --memory-stats의 컬럼별 메모리로, 큰 순서입니다.
+	ColumnMemory []columnMemory `json:"column_memory,omitempty"`
+	// LineageFile은 --lineage로 쓴 컬럼 계보 파일입니다.
+	LineageFile string `json:"lineage_file,omitempty"`
+	// StoppedBy는 --max-bytes-out이나 --max-duration으로 멈췄을 때 그 이유이고,
+	// CheckpointFile은 그때 쓴 checkpoint 파일입니다.
+	StoppedBy      string `json:"stopped_by,omitempty"`
+	CheckpointFile string `json:"checkpoint_file,omitempty"`
+	Error          string `json:"error,omitempty"`
+	err            error
+	// files는 --partition-by로 쓴 파티션 파일이나 --slice-output files로 쓴 조각 파일입니다.
+	// File은 그 위의 디렉터리입니다.
+	files []partitionFile
+}
+
+// label 함수는 메시지에 쓰는 결과의 이름으로, --projection이면 index@projection입니다.
+func (r indexReport) label() string {
+	return projectionLabel(r.Index, r.Projection)
+}
+
+func projectionLabel(index, projection string) string {
+	if projection == "" {
+		return index
+	}
+	return index + "@" + projection
+}
+
+func setupExport(fs *flag.FlagSet) func(ctx context.Context, report *runReport, args []string) error {
+	var o exportOptions
+	o.source.bind(fs, "", "source")
+	fs.Var(&o.indices, "index", "indices, aliases or patterns such as logs-* to export (comma-separated or repeated; required)")
+	fs.StringVar(&o.query, "query", "", "only export documents matching this query DSL JSON")
+	fs.IntVar(&o.scrollSize, "scroll-size", 1000, "documents per scroll page")
+	fs.DurationVar(&o.keepAlive, "scroll-keep-alive", 5*time.Minute, "how long the cluster keeps the scroll context between pages")
+	fs.StringVar(&o.outDir, "out-dir", ".", "directory to write <index>.parquet files to")
+	fs.Var(&o.listFields, "list-fields", "treat these fields as arrays from the start; dotted paths select nested fields (comma-separated or repeated)")
+	o.fields.bind(fs)
+	o.fieldCap.bind(fs)
+	fs.BoolVar(&o.unify, "unify", false, "write every index with one schema reconciled from _field_caps across --index, so the files can be read as one table")
+	o.conflicts.bind(fs)
+	o.analyze.bind(fs)
+	o.dictionary.bind(fs)
+	o.nonData.bind(fs)
+	o.nestedCopies.bind(fs)
+	o.catalog.bind(fs)
+	o.budget.bind(fs)
+	o.ipFormat.bind(fs)
+	o.scaled.bind(fs)
+	o.tsUnit.bind(fs)
+	o.flattened.bind(fs)
+	o.geoShape.bind(fs)
+	o.vectorDims.bind(fs)
+	fs.BoolVar(&o.geoWKB, "geo-wkb", false, "also write top-level geo_point fields as WKB geometry columns with GeoParquet 1.0 metadata, so GDAL and GeoPandas read the files as geospatial data (geo_shape fields are WKB unless --geo-shape json)")
+	fs.BoolVar(&o.geoLatLon, "geo-lat-lon", false, "also write <field>_lat and <field>_lon float64 columns for every top-level geo_point field (the first point of multi-valued fields), so engines without geometry support can filter by location with column statistics")
+	fs.BoolVar(&o.applyNormalizers, "apply-normalizers", false, "apply the normalizer of keyword fields (lowercase, uppercase, asciifolding and trim filters) to exported values, so they match what queries see")
+	fs.IntVar(&o.workers, "workers", 4, "maximum number of concurrent scrolls shared by all indices")
+	fs.StringVar(&o.split, "split", splitNone, "per-index parallelism: none (one scroll per index) or shards (one scroll per shard with preference=_shards:n, sharing the --workers budget)")
+	o.slices.bind(fs)
+	o.projections.bind(fs)
+	fs.BoolVar(&o.pit, "pit", false, "read every index, and every --split shards slice, through one point in time opened for the whole run, so documents indexed or deleted meanwhile are neither duplicated nor missed (Elasticsearch 7.12+)")
+	fs.BoolVar(&o.lineage, "lineage", false, "also write <index>.lineage.json with the source field, conversion rule and overriding flags of every column (the same lineage is always stored in the file's es_schema.lineage metadata)")
+	fs.BoolVar(&o.docHash, "doc-hash", false, "also write a "+docHashColumn+" column with a SHA-256 of each document's converted values, which ignores key order, whitespace and coerced spellings such as \"1\" for 1, for deduplication and change detection downstream")
+	fs.BoolVar(&o.seqNo, "seq-no", false, "also write _seq_no and _primary_term columns, so a later run can tell which documents changed since this export")
+	o.tombstones.bind(fs)
+	o.delta.bind(fs)
+	o.partition.bind(fs)
+	fs.BoolVar(&o.memoryStats, "memory-stats", false, "measure the Arrow buffer bytes of every column per scroll page, print the largest columns when each file is written and add all of them to the report")
+	fs.DurationVar(&o.progressEvery, "progress-interval", 10*time.Second, "print per-index progress at most this often")
+	o.names.bind(fs)
+	o.parquet.bind(fs)
+	o.encryption.bind(fs)
+	o.badDocuments.bind(fs)
+	o.overflow.bind(fs)
+	o.sanitize.bind(fs)
+	o.schemaLock.bind(fs)
+	o.transforms.bind(fs)
+	o.recordHooks.bind(fs)
+
+	return func(ctx context.Context, report *runReport, args []string) error {
+		if err := o.validate(args); err != nil {
+			return err
+		}
+		return o.export(ctx, report)
+	}
+}
+
+func (o *exportOptions) validate(args []string) error {
+	if len(args) > 0 {
+		return configErrorf("export: unexpected arguments %v", args)
+	}
+	if len(o.indices) == 0 {
+		return configErrorf("export: --index is required")
+	}
+	if o.query != "" && !json.Valid([]byte(o.query)) {
+		return configErrorf("export: --query is not valid JSON")
+	}
+	if o.scrollSize <= 0 || o.keepAlive < time.Second {
+		return configErrorf("export: --scroll-size must be positive and --scroll-keep-alive at least 1s")
+	}
+	if o.workers <= 0 {
+		return configErrorf("export: --workers must be positive")
+	}
+	switch o.split {
+	case splitNone, splitShards:
+	default:
+		return configErrorf("export: unknown --split %q (want none or shards)", o.split)
+	}
+	if err := o.parquet.validate(); err != nil {
+		return err
+	}
+	if err := o.badDocuments.validate(); err != nil {
+		return err
+	}
+	if err := o.overflow.validate(); err != nil {
+		return err
+	}
+	if err := o.sanitize.validate(); err != nil {
+		return err
+	}
+	if err := o.ipFormat.validate(); err != nil {
+		return err
+	}
+	if err := o.scaled.validate(); err != nil {
+		return err
+	}
+	if err := o.tsUnit.validate(); err != nil {
+		return err
+	}
+	if err := o.flattened.validate(); err != nil {
+		return err
+	}
+	if err := o.geoShape.validate(); err != nil {
+		return err
+	}
+	if err := o.vectorDims.validate(); err != nil {
+		return err
+	}
+	if err := o.schemaLock.validate(); err != nil {
+		return err
+	}
+	if err := o.fields.load(); err != nil {
+		return err
+	}
+	if err := o.fieldCap.validate(); err != nil {
+		return err
+	}
+	if err := o.conflicts.validate(); err != nil {
+		return err
+	}
+	if err := o.analyze.validate(); err != nil {
+		return err
+	}
+	if err := o.nonData.validate(); err != nil {
+		return err
+	}
+	if err := o.catalog.validate(); err != nil {
+		return err
+	}
+	if err := o.budget.validate(); err != nil {
+		return err
+	}
+	if err := o.nestedCopies.validate(); err != nil {
+		return err
+	}
+	if err := o.tombstones.validate(); err != nil {
+		return err
+	}
+	if err := o.delta.validate(); err != nil {
+		return err
+	}
+	if err := o.partition.validate(); err != nil {
+		return err
+	}
+	if o.partition.enabled() && o.tombstones.enabled() {
+		return configErrorf("export: --partition-by cannot be combined with --deleted-query or --id-snapshot")
+	}
+	if err := o.slices.validate(); err != nil {
+		return err
+	}
+	if o.slices.enabled() && o.split == splitShards {
+		return configErrorf("export: --slices cannot be combined with --split shards")
+	}
+	if o.slices.files() && (o.partition.enabled() || o.tombstones.enabled()) {
+		return configErrorf("export: --slice-output files cannot be combined with --partition-by, --deleted-query or --id-snapshot")
+	}
+	o.dataQuery = o.tombstones.dataQuery(o.query)
+	var err error
+	if o.chain, err = o.transforms.build(); err != nil {
+		return err
+	}
+	if o.hooks, err = o.recordHooks.build(); err != nil {
+		return err
+	}
+	return o.names.validate()
+}
+
+// export 함수는 --index가 가리키는 인덱스를 모두 찾아 작업자 풀에서 내보냅니다. 한 인덱스의
+// 실패는 다른 인덱스에 영향을 주지 않고, 끝난 뒤 인덱스별 결과로 보고됩니다.
+func (o *exportOptions) export(ctx context.Context, report *runReport) (err error) {
+	if err := o.encryption.load(ctx, &o.parquet); err != nil {
+		return err
+	}
+	if err := o.projections.load(ctx); err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := o.projections.close(); closeErr != nil && err == nil {
+			err = closeErr
+		} else if closeErr != nil {
+			report.warnf("%v", closeErr)
+		}
+	}()
+	o.budget.start()
+	client, err := o.source.client()
+	if err != nil {
+		return err
+	}
+	if err := o.source.checkReadOnly(ctx, client, report, o.indices, "read", "view_index_metadata"); err != nil {
+		return err
+	}
+	mappings, err := client.getMappings(ctx, strings.Join(o.indices, ","))
+	if err != nil {
+		return err
+	}
+	if len(mappings) == 0 {
+		return configErrorf("export: %s matches no indices", strings.Join(o.indices, ","))
+	}
+	if o.unify {
+		unified, conflicts, n, err := o.conflicts.reconcileMapping(ctx, client, strings.Join(o.indices, ","))
+		report.TypeConflicts = conflicts
+		if conflicts != nil {
+			writeTypeConflicts(os.Stdout, n, conflicts)
+		}
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(unified)
+		if err != nil {
+			return schemaErrorf("encoding unified mapping: %w", err)
+		}
+		for index := range mappings {
+			mappings[index] = data
+		}
+	}
+	if err := os.MkdirAll(o.outDir, 0o755); err != nil {
+		return configErrorf("creating --out-dir: %w", err)
+	}
+	projections := []*projectionSpec{nil}
+	if len(o.projections.specs) > 0 {
+		projections = o.projections.specs
+	}
+	for _, p := range o.projections.specs {
+		if err := os.MkdirAll(filepath.Join(o.outDir, p.name), 0o755); err != nil {
+			return configErrorf("creating the directory of --projection %s: %w", p.name, err)
+		}
+	}
+	indices := make([]string, 0, len(mappings))
+	for index := range mappings {
+		indices = append(indices, index)
+	}
+	sort.Strings(indices)
+
+	var pit *pointInTime
+	if o.pit {
+		if pit, err = client.openPIT(ctx, strings.Join(indices, ","), o.keepAlive); err != nil {
+			return err
+		}
+		defer client.closePIT(pit)
+		keepCtx, stop := context.WithCancel(ctx)
+		defer stop()
+		go client.keepPITAlive(keepCtx, pit, o.keepAlive)
+	}
+
+	pool := newWorkerPool(o.workers)
+	progress := &progressPrinter{w: os.Stdout, every: o.progressEvery, observe: report.progress}
+	var jobs []*exportJob
+	for _, index := range indices {
+		for _, p := range projections {
+			jobs = append(jobs, &exportJob{opts: o, client: client, index: index, projection: p, mapping: mappings[index], pit: pit, pool: pool, progress: progress})
+		}
+	}
+	results := make([]indexReport, len(jobs))
+	var wg sync.WaitGroup
+	for i := range jobs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = jobs[i].run(ctx)
+		}(i)
+	}
+	wg.Wait()
+
+	var failed, stopped []string
+	var firstErr error
+	rejects := &docRejects{}
+	overflows, sanitized := make(map[string]int64), make(map[string]int64)
+	for i, r := range results {
+		report.Warnings = append(report.Warnings, jobs[i].warnings...)
+		recordRenames(report, jobs[i].renames)
+		if jobs[i].norm != nil && r.Status != statusFailed {
+			o.schemaLock.record(jobs[i].lockBase, jobs[i].norm.schema, jobs[i].renames)
+		}
+		rejects.merge(&jobs[i].rejects)
+		report.DocumentsDropped += jobs[i].dropped
+		if jobs[i].norm != nil {
+			for path, n := range jobs[i].norm.overflowCounts() {
+				overflows[path] += n
+			}
+			for path, n := range jobs[i].norm.sanitizedCounts() {
+				sanitized[path] += n
+			}
+		}
+		report.Indices = append(report.Indices, r)
+		if r.Status == statusFailed {
+			failed = append(failed, r.label())
+			report.warnf("%s: %s", r.label(), r.Error)
+			continue
+		}
+		report.RowsRead += r.Rows
+		if r.StoppedBy != "" {
+			stopped = append(stopped, r.label())
+		}
+		for _, f := range r.files {
+			report.addFile(f.path, f.rows)
+		}
+		if r.files == nil {
+			report.addFile(r.File, r.Rows)
+		}
+		if r.DeletesFile != "" {
+			report.addFile(r.DeletesFile, r.Deleted)
+		}
+	}
+	if len(o.projections.specs) > 0 {
+		fmt.Printf("exported %d of %d indices in %d projections\n", len(jobs)-len(failed), len(jobs), len(projections))
+	} else {
+		fmt.Printf("exported %d of %d indices\n", len(jobs)-len(failed), len(jobs))
+	}
+	for i, r := range results {
+		if r.Status == statusFailed {
+			fmt.Printf("  %s: failed: %s\n", r.label(), r.Error)
+			if firstErr == nil {
+				firstErr = r.err
+			}
+		} else if r.StoppedBy != "" {
+			fmt.Printf("  %s: stopped after %d of %d documents (%s) -> %s\n", r.label(), r.Rows, jobs[i].total, r.StoppedBy, r.File)
+		} else if o.partition.enabled() {
+			fmt.Printf("  %s: %d documents in %d partition files -> %s\n", r.label(), r.Rows, len(r.files), r.File)
+		} else if o.slices.files() {
+			fmt.Printf("  %s: %d documents in %d slice files -> %s\n", r.label(), r.Rows, len(r.files), r.File)
+		} else if j := jobs[i]; j.opts.tombstones.enabled() {
+			fmt.Printf("  %s: %d documents, %d deleted -> %s\n", r.label(), r.Rows, r.Deleted, r.File)
+		} else {
+			fmt.Printf("  %s: %d documents -> %s\n", r.label(), r.Rows, r.File)
+		}
+	}
+
+	if len(failed) == 0 {
+		if err := o.schemaLock.save(report); err != nil {
+			return err
+		}
+	} else if o.schemaLock.mode == schemaLockWrite && o.schemaLock.path != "" {
+		// 실패한 인덱스의 컬럼이 빠진 잠금은 다음 실행을 실패하게 하므로 쓰지 않습니다.
+		report.warnf("--schema-lock %s was not written because %d indices failed", o.schemaLock.path, len(failed))
+	}
+	if o.catalog.enabled() && len(failed) < len(jobs) {
+		pushCatalog(ctx, report, &o.catalog, jobs, results)
+	}
+	if report.DocumentsDropped > 0 {
+		fmt.Printf("dropped %d documents by --transform\n", report.DocumentsDropped)
+	}
+	o.overflow.report(report, overflows)
+	o.sanitize.report(report, sanitized)
+	rejectErr := rejects.finish(report)
+	switch {
+	case len(failed) == 0 && (rejectErr != nil || len(stopped) == 0):
+		return rejectErr
+	case len(failed) == 0:
+		return withKind(kindPartial, fmt.Errorf("%s; %d of %d indices are incomplete: %s", o.budget.exceeded(), len(stopped), len(jobs), strings.Join(stopped, ", ")))
+	case len(failed) == len(jobs):
+		return firstErr
+	default:
+		return withKind(kindPartial, fmt.Errorf("%d of %d indices failed: %s", len(failed), len(jobs), strings.Join(failed, ", ")))
+	}
+}
+
+// workerPool은 여러 인덱스(와 --split shards의 샤드별 scroll)가 함께 쓰는 동시 scroll
+// 수의 한도입니다.
+type workerPool struct {
+	tokens chan struct{}
+}
+
+func newWorkerPool(n int) *workerPool {
+	return &workerPool{tokens: make(chan struct{}, n)}
+}
+
+// acquire 함수는 작업자 하나를 얻을 때까지 기다립니다. ctx가 취소되면 오류를 반환합니다.
+func (p *workerPool) acquire(ctx context.Context) error {
+	select {
+	case p.tokens <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *workerPool) release() {
+	<-p.tokens
+}
+
+// progressPrinter는 여러 작업자의 진행 상황을 줄이 섞이지 않게, 인덱스마다 every 간격
+// 이상으로 출력합니다.
+type progressPrinter struct {
+	w     io.Writer
+	every time.Duration
+	// observe는 있으면 출력 간격과 상관없이 모든 진행 상황을 받습니다.
+	observe func(index string, done, total int64)
+	mu      sync.Mutex
+	last    map[string]time.Time
+}
+
+func (p *progressPrinter) report(index string, done, total int64, final bool) {
+	if p.observe != nil {
+		p.observe(index, done, total)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	if p.last == nil {
+		p.last = make(map[string]time.Time)
+	}
+	if !final && now.Sub(p.last[index]) < p.every {
+		return
+	}
+	p.last[index] = now
+	if total > 0 {
+		fmt.Fprintf(p.w, "%s: %d/%d documents (%.0f%%)\n", index, done, total, 100*float64(done)/float64(total))
+	} else {
+		fmt.Fprintf(p.w, "%s: %d documents\n", index, done)
+	}
+}
+
+// exportJob은 인덱스 하나를 내보내는 작업입니다. 보고서는 작업이 모두 끝난 뒤 한
+// 고루틴에서만 고치므로, 작업 중의 경고는 warnings에 모아 둡니다.
+type exportJob struct {
+	opts   *exportOptions
+	client *esClient
+	index  string
+	// projection은 --projection일 때 이 작업이 쓰는 프로젝션입니다. 같은 인덱스를
+	// 프로젝션마다 다른 작업이 따로 읽습니다.
+	projection *projectionSpec
+	mapping    json.RawMessage
+	pool       *workerPool
+	progress   *progressPrinter
+	warnings   []string
+	renames    []fieldRename
+	// chain은 --transform에 이 인덱스의 노멀라이저와 --max-fields overflow 변환을 더한
+	// 것입니다.
+	chain transformChain
+	// pit은 --pit으로 연 point in time입니다. nil이면 scroll로 읽습니다.
+	pit *pointInTime
+
+	// 아래는 export 중의 상태로, 동시에 도는 scroll들이 writePage에서 mu를 잡고 씁니다.
+	mu          sync.Mutex
+	path        string
+	mappingJSON []byte
+	norm        *normalizer
+	rejects     docRejects
+	sink        *parquetSink
+	rows        int64
+	total       int64
+	dropped     int64
+	// ids는 --id-snapshot이 있을 때 이번에 읽은 _id입니다. 변환이 버리거나 거부된 문서도
+	// 원본에는 남아 있으므로 넣습니다.
+	ids map[string]bool
+	// delta는 --delta일 때 바뀐 문서를 고르는 필터입니다.
+	delta       *deltaFilter
+	deleted     int64
+	deletesFile string
+	memory      columnMemoryStats
+	// geoBounds는 --geo-wkb와 --geo-lat-lon 필드의 경계 상자입니다.
+	geoBounds map[string][]float64
+	// parts는 --partition-by일 때 sink 대신 쓰는 파티션 파일들입니다.
+	parts *partitionWriters
+	// slices는 --slice-output files일 때 sink 대신 쓰는 조각별 파일이고, sliceSchema는 그
+	// 파일들이 함께 쓰는 레코드 스키마입니다. sliceBytes는 조각마다 마지막으로 쓴 뒤의 파일
+	// 크기이고, sliceFiles는 다 닫은 파일들입니다.
+	slices      []*parquetSink
+	sliceSchema *arrow.Schema
+	sliceBytes  []int64
+	sliceFiles  []partitionFile
+	// overrides는 원래 필드 경로별로 기본 변환을 바꾼 플래그이고, lineage는 마지막으로 연
+	// 파일의 컬럼 계보입니다.
+	overrides   map[string][]string
+	lineage     []columnLineage
+	lineageFile string
+	// spent는 예산에 센 이 인덱스의 출력 바이트이고, stoppedBy는 한도에 걸려 멈췄을 때 그
+	// 이유입니다.
+	spent          int64
+	stoppedBy      string
+	checkpointFile string
+	// lockBase는 문서를 보기 전의 스키마 필드로, --schema-lock write가 넓힌 스키마와 비교합니다.
+	lockBase []arrow.Field
+}
+
+func (j *exportJob) run(ctx context.Context) indexReport {
+	r := indexReport{Index: j.index, Status: statusSuccess}
+	if j.projection != nil {
+		r.Projection = j.projection.name
+	}
+	rows, path, err := j.export(ctx)
+	r.Rows = rows
+	if err != nil {
+		r.Status, r.Error, r.err = statusFailed, err.Error(), err
+		return r
+	}
+	r.File = path
+	r.Deleted, r.DeletesFile = j.deleted, j.deletesFile
+	if j.delta != nil {
+		r.Unchanged = j.delta.unchanged
+	}
+	r.LineageFile = j.lineageFile
+	r.StoppedBy, r.CheckpointFile = j.stoppedBy, j.checkpointFile
+	if j.parts != nil {
+		r.files = j.parts.files
+	}
+	if j.sliceFiles != nil {
+		r.files = j.sliceFiles
+	}
+	if j.opts.memoryStats {
+		r.ColumnMemory = j.memory.summary()
+	}
+	return r
+}
+
+// label 함수는 진행 상황과 상태 파일에 쓰는 작업의 이름입니다.
+func (j *exportJob) label() string {
+	if j.projection == nil {
+		return j.index
+	}
+	return projectionLabel(j.index, j.projection.name)
+}
+
+// dir 함수는 이 작업의 파일을 쓰는 디렉터리로, --projection이면 프로젝션의 하위
+// 디렉터리입니다.
+func (j *exportJob) dir() string {
+	if j.projection == nil {
+		return j.opts.outDir
+	}
+	return filepath.Join(j.opts.outDir, j.projection.name)
+}
+
+func (j *exportJob) export(ctx context.Context) (int64, string, error) {
+	mapping, err := typelessMapping(j.mapping)
+	if err != nil {
+		return 0, "", err
+	}
+	// 여러 인덱스를 내보낼 때 --fields의 필드가 모든 인덱스에 있지는 않습니다.
+	if mapping, _, err = j.opts.fields.apply(mapping); err != nil {
+		return 0, "", schemaErrorf("mapping of %s: %w", j.index, err)
+	}
+	if j.projection != nil {
+		var missing []string
+		if mapping, missing, err = j.projection.apply(mapping); err != nil {
+			return 0, "", schemaErrorf("mapping of %s: %w", j.index, err)
+		}
+		for _, path := range missing {
+			j.warnings = append(j.warnings, fmt.Sprintf("%s: projection %s names %s, which the mapping does not have", j.index, j.projection.name, path))
+		}
+	}
+	fc, leaves, err := j.opts.fieldCap.capFields(ctx, j.client, j.index, json.RawMessage(j.opts.dataQuery), mapping, j.opts.chain)
+	if err != nil {
+		return 0, "", err
+	}
+	// 상한을 적용해도 파일에 남기는 매핑은 원래 매핑입니다. 가져올 때 overflow 컬럼의
+	// 필드까지 되살린 문서를 원래 매핑의 인덱스에 넣습니다.
+	schemaMapping := mapping
+	j.chain = append(transformChain(nil), j.opts.chain...)
+	if j.opts.applyNormalizers {
+		if err := j.addNormalizers(ctx, mapping); err != nil {
+			return 0, "", err
+		}
+	}
+	if j.projection != nil && len(j.projection.Mask) > 0 {
+		// 토큰 컬럼과 overflow 컬럼에도 가린 값만 들어가도록 그 변환보다 먼저 가립니다.
+		j.chain = append(j.chain, j.projection.transform())
+	}
+	tokenFields, err := j.addAnalysis(ctx, mapping)
+	if err != nil {
+		return 0, "", err
+	}
+	switch {
+	case fc != nil:
+		schemaMapping = fc.mapping
+		for _, f := range tokenFields {
+			fc.kept[f.Name] = true
+		}
+		j.chain = append(j.chain, fc.overflowTransform(j.opts.fieldCap.overflowColumn))
+		j.warnings = append(j.warnings, fmt.Sprintf("%s: mapping has %d fields; kept the %d most populated in a sample as columns and put the rest in %s", j.index, leaves, j.opts.fieldCap.maxFields, j.opts.fieldCap.overflowColumn))
+	case leaves > explosionThreshold:
+		j.warnings = append(j.warnings, fmt.Sprintf("%s: mapping has %d fields, more than Elasticsearch allows by default; consider --max-fields", j.index, leaves))
+	}
+	skip, asJSON, nonData, err := j.opts.nonData.split(schemaMapping)
+	if err != nil {
+		return 0, "", schemaErrorf("mapping of %s: %w", j.index, err)
+	}
+	if len(skip) > 0 {
+		if schemaMapping, _, err = excludeFields(schemaMapping, skip); err != nil {
+			return 0, "", schemaErrorf("mapping of %s: %w", j.index, err)
+		}
+		for _, path := range skip {
+			j.warnings = append(j.warnings, fmt.Sprintf("%s: %s", j.index, j.opts.nonData.skipWarning(path, nonData[path])))
+		}
+	}
+	schema, err := schemaFromMapping(schemaMapping)
+	if err != nil {
+		return 0, "", schemaErrorf("mapping of %s: %w", j.index, err)
+	}
+	fields := schema.Fields()
+	for _, path := range asJSON {
+		if marked, ok := jsonColumn(fields, strings.Split(path, ".")); ok {
+			fields = marked
+			j.override(path, "--non-data-fields json")
+		}
+	}
+	if len(asJSON) > 0 {
+		j.chain = append(j.chain, jsonTransform(asJSON))
+	}
+	if j.projection != nil {
+		fields = j.projection.maskFields(fields)
+		for _, path := range j.projection.maskPaths() {
+			j.override(path, "--projection "+j.projection.name+" ("+j.projection.Mask[path]+")")
+		}
+	}
+	if j.opts.nestedCopies.as == nestedAsParent {
+		if fields, err = j.addParentViews(mapping, fields); err != nil {
+			return 0, "", err
+		}
+	}
+	fields = append(fields, tokenFields...)
+	if fc != nil {
+		fields = append(fields, fc.overflow)
+	}
+	for _, path := range j.opts.listFields {
+		// 여러 인덱스를 내보낼 때 --list-fields의 필드가 모든 인덱스에 있지는 않습니다.
+		if forced, ok := forceList(fields, strings.Split(path, ".")); ok {
+			fields = forced
+			j.override(path, "--list-fields")
+		}
+	}
+	fields = j.opts.geoShape.apply(j.opts.flattened.apply(j.opts.tsUnit.apply(j.opts.scaled.apply(j.opts.ipFormat.apply(fields)))))
+	if err := j.opts.vectorDims.check(fields, j.index); err != nil {
+		return 0, "", err
+	}
+	dictPaths, err := j.opts.dictionary.paths(mapping)
+	if err != nil {
+		return 0, "", err
+	}
+	fields = dictionaryFields(fields, dictPaths)
+	if j.opts.geoWKB || j.opts.geoLatLon {
+		if fields, err = j.addGeo(ctx, mapping, fields); err != nil {
+			return 0, "", err
+		}
+	}
+	if j.total, err = j.client.count(ctx, j.index, json.RawMessage(j.opts.dataQuery)); err != nil {
+		return 0, "", err
+	}
+	preferences := []string{""}
+	if j.opts.split == splitShards {
+		shards, err := j.client.shardCount(ctx, j.index)
+		if err != nil {
+			return 0, "", err
+		}
+		preferences = make([]string, shards)
+		for i := range preferences {
+			preferences[i] = "_shards:" + strconv.Itoa(i)
+		}
+	} else if j.opts.slices.enabled() {
+		preferences = make([]string, j.opts.slices.n)
+	}
+
+	fields = j.opts.schemaLock.apply(fields)
+	j.lockBase = fields
+
+	j.path = filepath.Join(j.dir(), j.index+".parquet")
+	j.mappingJSON = mapping
+	j.norm = newNormalizer(arrow.NewSchema(fields, nil))
+	j.norm.rejectBad = j.opts.badDocuments.skip()
+	j.opts.overflow.apply(j.norm)
+	j.opts.sanitize.apply(j.norm)
+	if j.opts.tombstones.idSnapshot != "" {
+		j.ids = make(map[string]bool)
+	}
+	if j.opts.delta.enabled() {
+		previous, err := readManifest(j.opts.delta.manifestPath(j.label()))
+		if err != nil {
+			return 0, "", err
+		}
+		j.delta = newDeltaFilter(j.opts.delta.by, previous)
+	}
+	if j.opts.partition.enabled() {
+		j.path = filepath.Join(j.dir(), j.index)
+		j.parts = newPartitionWriters(j.path, &j.opts.partition, func(path string, schema *arrow.Schema) (*parquetSink, error) {
+			sink, err := newParquetSink(path, schema, mapping, j.sinkColumns(), &j.opts.names, &j.opts.parquet)
+			if err == nil {
+				j.lineage = sink.lineage
+			}
+			return sink, err
+		})
+	}
+	if j.opts.slices.files() {
+		j.path = filepath.Join(j.dir(), j.index)
+		j.slices = make([]*parquetSink, j.opts.slices.n)
+		j.sliceBytes = make([]int64, j.opts.slices.n)
+	}
+	defer func() {
+		if j.sink != nil {
+			j.sink.abort()
+		}
+		if j.parts != nil {
+			j.parts.abort()
+		}
+		for _, s := range j.slices {
+			if s != nil {
+				s.abort()
+			}
+		}
+	}()
+	if err := j.scrollAll(ctx, preferences); errors.Is(err, errBudgetExceeded) {
+		// 멈춘 뒤에도 이미 읽은 문서는 아래에서 모두 써서 파일을 온전히 닫습니다.
+		j.stoppedBy = j.opts.budget.exceeded()
+	} else if err != nil {
+		return j.rows, "", err
+	}
+	if j.parts != nil {
+		if err := j.finishPartitions(); err != nil {
+			return j.rows, "", err
+		}
+	} else if j.slices != nil {
+		if err := j.finishSlices(); err != nil {
+			return j.rows, "", err
+		}
+	} else if err := j.finishFile(ctx, mapping); err != nil {
+		return j.rows, "", err
+	}
+	if j.opts.lineage && j.lineage != nil {
+		path := filepath.Join(j.dir(), j.index+".lineage.json")
+		if err := writeLineageFile(path, j.index, j.lineage); err != nil {
+			return j.rows, "", err
+		}
+		j.lineageFile = path
+	}
+	if err := j.finishCheckpoint(); err != nil {
+		return j.rows, "", err
+	}
+	if err := j.finishDelta(); err != nil {
+		return j.rows, "", err
+	}
+	if j.opts.memoryStats && j.rows > 0 {
+		fmt.Printf("%s: largest columns per page: %s\n", j.index, formatColumnMemory(j.memory.summary(), memoryLogColumns))
+	}
+	if j.norm.dropped > 0 {
+		j.warnings = append(j.warnings, fmt.Sprintf("%s: %d values did not match their mapped type and were written as null", j.index, j.norm.dropped))
+	}
+	j.progress.report(j.label(), j.rows, j.total, true)
+	return j.rows, j.path, nil
+}
+
+// finishFile 함수는 --partition-by가 아닐 때 tombstone을 더하고 인덱스의 파일을 닫습니다.
+func (j *exportJob) finishFile(ctx context.Context, mapping []byte) error {
+	if j.sink == nil {
+		// 문서가 없는 인덱스도 스키마만 있는 파일을 만들어 둡니다.
+		if err := j.opts.schemaLock.check(j.label(), j.norm.schema, &j.opts.names); err != nil {
+			return err
+		}
+		schema, err := j.emptySchema()
+		if err != nil {
+			return err
+		}
+		if j.sink, err = newParquetSink(j.path, schema, mapping, j.sinkColumns(), &j.opts.names, &j.opts.parquet); err != nil {
+			return err
+		}
+		j.renames = j.sink.renames
+		j.lineage = j.sink.lineage
+	}
+	var tombstones []string
+	if j.opts.tombstones.enabled() && j.stoppedBy != "" {
+		// 읽지 못한 문서를 삭제된 것으로 보지 않도록 tombstone과 스냅샷을 모두 건너뜁니다.
+		j.warnings = append(j.warnings, fmt.Sprintf("%s: the export stopped early, so no tombstones were written and the --id-snapshot was left unchanged", j.index))
+	} else if j.opts.tombstones.enabled() {
+		var err error
+		if tombstones, err = j.collectTombstones(ctx); err != nil {
+			return err
+		}
+		if err := j.writeTombstones(tombstones); err != nil {
+			return err
+		}
+	}
+	s := j.sink
+	j.sink = nil
+	if err := s.close(); err != nil {
+		return err
+	}
+	j.account(s.bytesWritten())
+	if j.ids != nil && j.stoppedBy == "" {
+		// 파일을 모두 쓴 뒤에 스냅샷을 바꿔, 실패한 실행이 다음 실행의 비교 기준이 되지
+		// 않게 합니다.
+		if err := writeIDSnapshot(j.opts.tombstones.snapshotPath(j.label()), j.ids); err != nil {
+			return err
+		}
+	}
+	j.deleted = int64(len(tombstones))
+	return nil
+}
+
+// emptySchema 함수는 문서가 없는 파일의 스키마를 반환합니다. 레코드 훅이 컬럼을 더할 수
+// 있으므로 빈 레코드에 훅을 적용해 얻습니다.
+func (j *exportJob) emptySchema() (*arrow.Schema, error) {
+	empty, _ := j.norm.record(nil)
+	hooked, err := j.opts.hooks.apply(empty)
+	empty.Release()
+	if err != nil {
+		return nil, err
+	}
+	defer hooked.Release()
+	return hooked.Schema(), nil
+}
+
+// finishDelta 함수는 --delta의 새 manifest를 씁니다. 파일을 모두 쓴 뒤에 바꿔, 실패하거나
+// 중간에 멈춘 실행이 다음 실행의 비교 기준이 되지 않게 합니다.
+func (j *exportJob) finishDelta() error {
+	if j.delta == nil {
+		return nil
+	}
+	if j.stoppedBy != "" {
+		j.warnings = append(j.warnings, fmt.Sprintf("%s: the export stopped early, so the --delta manifest was left unchanged", j.index))
+		return nil
+	}
+	if err := writeManifest(j.opts.delta.manifestPath(j.label()), j.delta.current); err != nil {
+		return err
+	}
+	fmt.Printf("%s: %d new or changed documents, %d unchanged since the previous manifest\n", j.index, j.rows, j.delta.unchanged)
+	return nil
+}
+
+// finishCheckpoint 함수는 한도에 걸려 멈춘 인덱스의 checkpoint 파일을 씁니다. 끝까지
+// 내보낸 인덱스는 지난 실행이 남긴 checkpoint 파일을 지웁니다.
+func (j *exportJob) finishCheckpoint() error {
+	path := checkpointPath(j.dir(), j.index)
+	if j.stoppedBy == "" {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return configErrorf("removing stale checkpoint: %w", err)
+		}
+		return nil
+	}
+	c := exportCheckpoint{Index: j.index, Reason: j.stoppedBy, File: j.path, Rows: j.rows, Total: j.total, Bytes: j.spent, StoppedAt: time.Now().UTC()}
+	if err := writeCheckpoint(path, c); err != nil {
+		return err
+	}
+	j.checkpointFile = path
+	j.warnings = append(j.warnings, fmt.Sprintf("%s: %s; wrote %d of %d documents", j.index, j.stoppedBy, j.rows, j.total))
+	return nil
+}
+
+// spend 함수는 이 인덱스의 파일에 새로 쓴 바이트를 예산에 더하고, 한도에 이르렀으면
+// errBudgetExceeded를 반환해 scroll을 멈춥니다.
+func (j *exportJob) spend() error {
+	switch {
+	case j.parts != nil:
+		j.account(j.parts.bytesWritten())
+	case j.slices != nil:
+		j.account(j.sliceBytesWritten())
+	case j.sink != nil:
+		j.account(j.sink.bytesWritten())
+	}
+	return j.opts.budget.check()
+}
+
+// account 함수는 이 인덱스가 지금까지 쓴 바이트가 n일 때 늘어난 만큼을 예산에 더합니다.
+func (j *exportJob) account(n int64) {
+	j.opts.budget.add(n - j.spent)
+	j.spent = n
+}
+
+// addNormalizers 함수는 매핑에서 normalizer가 있는 keyword 필드를 찾아 인덱스 설정의
+// 노멀라이저를 적용하는 변환을 chain에 더합니다. 흉내 낼 수 없는 노멀라이저의 필드는
+// 경고하고 그대로 둡니다.
+func (j *exportJob) addNormalizers(ctx context.Context, mapping []byte) error {
+	var m map[string]interface{}
+	if err := json.Unmarshal(mapping, &m); err != nil {
+		return schemaErrorf("decoding mapping of %s: %w", j.index, err)
+	}
+	props, _ := m["properties"].(map[string]interface{})
+	names := make(map[string]string)
+	normalizedKeywords(props, "", names)
+	if len(names) == 0 {
+		return nil
+	}
+	settings, err := j.client.analysis(ctx, j.index)
+	if err != nil {
+		return err
+	}
+	paths := make([]string, 0, len(names))
+	for path := range names {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	fields := make(map[string]func(string) string, len(names))
+	for _, path := range paths {
+		fn, err := keywordNormalizer(names[path], settings)
+		if err != nil {
+			j.warnings = append(j.warnings, fmt.Sprintf("%s: %s left unnormalized: %v", j.index, path, err))
+			continue
+		}
+		fields[path] = fn
+		j.override(path, "--apply-normalizers")
+	}
+	if len(fields) > 0 {
+		j.chain = append(j.chain, normalizerTransform(fields))
+	}
+	return nil
+}
+
+// addAnalysis 함수는 --analyze 필드 중 이 인덱스 매핑에 있는 필드의 토큰 컬럼을 만드는
+// 변환을 chain에 더하고, 스키마에 더할 토큰 컬럼을 반환합니다.
+func (j *exportJob) addAnalysis(ctx context.Context, mapping []byte) ([]arrow.Field, error) {
+	if len(j.opts.analyze.specs) == 0 {
+		return nil, nil
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(mapping, &m); err != nil {
+		return nil, schemaErrorf("decoding mapping of %s: %w", j.index, err)
+	}
+	var specs []analyzeSpec
+	var fields []arrow.Field
+	for _, spec := range j.opts.analyze.specs {
+		if lookupMappingField(m, strings.Split(spec.path, ".")) == nil {
+			j.warnings = append(j.warnings, fmt.Sprintf("%s: --analyze %s is not a field of the mapping", j.index, spec.path))
+			continue
+		}
+		if lookupMappingField(m, []string{spec.column()}) != nil {
+			return nil, schemaErrorf("%s already has a field %s for the tokens of --analyze %s", j.index, spec.column(), spec.path)
+		}
+		specs = append(specs, spec)
+		fields = append(fields, tokenField(spec))
+	}
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	analyze := remoteAnalyze(ctx, j.client, j.index)
+	if j.opts.analyze.local {
+		analyze = localAnalyze
+	}
+	j.chain = append(j.chain, tokensTransform(specs, analyze))
+	return fields, nil
+}
+
+// addParentViews 함수는 include_in_parent나 include_in_root가 있는 nested 필드를 부모
+// 문서가 보는 모양의 컬럼으로 바꾸고, 값을 그 모양으로 모으는 변환을 chain에 더합니다.
+func (j *exportJob) addParentViews(mapping []byte, fields []arrow.Field) ([]arrow.Field, error) {
+	paths, err := copiedNestedPaths(mapping)
+	if err != nil {
+		return nil, err
+	}
+	var applied []string
+	for _, path := range paths {
+		if viewed, ok := parentView(fields, strings.Split(path, ".")); ok {
+			fields = viewed
+			applied = append(applied, path)
+			j.override(path, "--include-in-parent-as parent")
+		}
+	}
+	if len(applied) > 0 {
+		j.chain = append(j.chain, parentTransform(applied))
+	}
+	return fields, nil
+}
+
+// addGeo 함수는 --geo-wkb와 --geo-lat-lon에 따라 top-level geo 필드의 컬럼을 바꾸거나
+// 더한 필드 목록을 반환하고, 값을 바꾸는 변환을 chain에 더합니다. 경계 상자는 파일을
+// 쓰기 전에 메타데이터에 넣어야 하므로 geo_bounds 집계로 미리 구합니다. 집계가 실패한
+// 필드(geo_shape 집계가 없는 버전 등)는 경계 상자 없이 쓰고 경고합니다.
+func (j *exportJob) addGeo(ctx context.Context, mapping []byte, fields []arrow.Field) ([]arrow.Field, error) {
+	types, err := geoFieldTypes(fields, mapping)
+	if err != nil || len(types) == 0 {
+		return fields, err
+	}
+	bounded := make(map[string]bool)
+	if j.opts.geoLatLon {
+		derived, err := latLonColumns(fields, types)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, derived...)
+		j.chain = append(j.chain, latLonTransform(types))
+		for name, t := range types {
+			if t == "geo_point" {
+				bounded[name] = true
+			}
+		}
+	}
+	if j.opts.geoWKB {
+		if j.opts.geoShape.format == "json" {
+			// --geo-shape json의 geo_shape 컬럼은 JSON 텍스트로 둡니다.
+			for name, t := range types {
+				if t == "geo_shape" {
+					delete(types, name)
+				}
+			}
+		}
+		fields = geoColumns(fields, types)
+		j.chain = append(j.chain, geoTransform(types))
+		for name := range types {
+			bounded[name] = true
+		}
+	}
+	names := make([]string, 0, len(bounded))
+	for name := range bounded {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	j.geoBounds = make(map[string][]float64, len(names))
+	for _, name := range names {
+		bbox, err := j.client.geoBounds(ctx, j.index, json.RawMessage(j.opts.dataQuery), name)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			j.warnings = append(j.warnings, fmt.Sprintf("%s: no bounding box for %s: %v", j.index, name, err))
+			continue
+		}
+		if bbox != nil {
+			j.geoBounds[name] = bbox
+		}
+	}
+	return fields, nil
+}
+
+// scrollAll 함수는 preference마다 scroll 하나를 작업자 풀에서 실행합니다. --pit이면
+// scroll 대신 point in time을 preference 수만큼의 조각으로 나눠 읽고, --slices이면
+// preference 없이 sliced scroll의 조각을 읽습니다. 하나가 실패하면 나머지를 취소하고 처음
+// 오류를 반환합니다.
+func (j *exportJob) scrollAll(ctx context.Context, preferences []string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make([]error, len(preferences))
+	var wg sync.WaitGroup
+	for i, pref := range preferences {
+		wg.Add(1)
+		go func(i int, pref string) {
+			defer wg.Done()
+			if errs[i] = j.pool.acquire(ctx); errs[i] != nil {
+				return
+			}
+			defer j.pool.release()
+			var params url.Values
+			if j.opts.seqNo || j.delta != nil {
+				params = url.Values{"seq_no_primary_term": {"true"}}
+			}
+			write := func(hits []searchHit) error {
+				return j.writePage(i, hits)
+			}
+			var slice *searchSlice
+			if len(preferences) > 1 && (j.pit != nil || j.opts.slices.enabled()) {
+				slice = &searchSlice{ID: i, Max: len(preferences)}
+			}
+			if j.pit != nil {
+				errs[i] = j.client.searchPIT(ctx, j.pit, pitQuery(j.index, j.opts.dataQuery), j.opts.scrollSize, j.opts.keepAlive, slice, params, write)
+				if errs[i] != nil {
+					cancel()
+				}
+				return
+			}
+			if pref != "" {
+				if params == nil {
+					params = url.Values{}
+				}
+				params.Set("preference", pref)
+			}
+			errs[i] = j.client.scroll(ctx, j.index, json.RawMessage(j.opts.dataQuery), j.opts.scrollSize, j.opts.keepAlive, slice, params, write)
+			if errs[i] != nil {
+				cancel()
+			}
+		}(i, pref)
+	}
+	wg.Wait()
+	// 취소로 끝난 scroll의 오류보다 취소를 일으킨 오류를 돌려줍니다.
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return err
+		}
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectTombstones 함수는 이 인덱스의 tombstone으로 쓸 _id를 정렬해 반환합니다.
+// --deleted-query에 맞는 문서와, --id-snapshot의 지난 목록에 있었지만 이번에 읽지 못한
+// 문서입니다.
+func (j *exportJob) collectTombstones(ctx context.Context) ([]string, error) {
+	deleted := make(map[string]bool)
+	if j.opts.tombstones.deletedQuery != "" {
+		collect := func(hits []searchHit) error {
+			for _, hit := range hits {
+				deleted[hit.ID] = true
+			}
+			return nil
+		}
+		query := j.opts.tombstones.deletedSearch(j.opts.query)
+		params := url.Values{"_source": {"false"}}
+		var err error
+		if j.pit != nil {
+			err = j.client.searchPIT(ctx, j.pit, pitQuery(j.index, query), j.opts.scrollSize, j.opts.keepAlive, nil, params, collect)
+		} else {
+			err = j.client.scroll(ctx, j.index, json.RawMessage(query), j.opts.scrollSize, j.opts.keepAlive, nil, params, collect)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if j.ids != nil {
+		previous, err := readIDSnapshot(j.opts.tombstones.snapshotPath(j.label()))
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range missingIDs(previous, j.ids) {
+			deleted[id] = true
+		}
+	}
+	ids := make([]string, 0, len(deleted))
+	for id := range deleted {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// writeTombstones 함수는 ids를 --tombstones에 따라 데이터 파일의 행이나 삭제 파일로 씁니다.
+func (j *exportJob) writeTombstones(ids []string) error {
+	if j.opts.tombstones.rows() {
+		if len(ids) == 0 {
+			return nil
+		}
+		return j.sink.writeTombstones(ids)
+	}
+	path := filepath.Join(j.dir(), j.index+".deletes.parquet")
+	if err := writeDeletesFile(path, ids, &j.opts.parquet); err != nil {
+		return err
+	}
+	j.deletesFile = path
+	return nil
+}
+
+// sinkColumns 함수는 데이터 파일에 _id 다음으로 쓸 메타데이터 컬럼을 정합니다.
+func (o *exportOptions) sinkColumns() sinkColumns {
+	return sinkColumns{seqNo: o.seqNo, deleted: o.tombstones.rows(), docHash: o.docHash}
+}
+
+// sinkColumns 함수는 내보내기 설정의 컬럼에 이 인덱스 geo 필드의 경계 상자를 더합니다.
+func (j *exportJob) sinkColumns() sinkColumns {
+	cols := j.opts.sinkColumns()
+	cols.geoBounds = j.geoBounds
+	cols.overrides = j.overrides
+	return cols
+}
+
+// override 함수는 path 필드의 변환을 flag가 바꿨음을 컬럼 계보에 남깁니다.
+func (j *exportJob) override(path, flag string) {
+	if j.overrides == nil {
+		j.overrides = make(map[string][]string)
+	}
+	j.overrides[path] = append(j.overrides[path], flag)
+}
+
+// pitQuery 함수는 query를 index의 문서로 좁힌 검색 조건을 만듭니다. point in time은 실행의
+// 모든 인덱스에 걸쳐 열리므로 인덱스마다 _index로 거릅니다.
+func pitQuery(index, query string) json.RawMessage {
+	filter := []interface{}{map[string]interface{}{"term": map[string]string{"_index": index}}}
+	if query != "" {
+		filter = append(filter, json.RawMessage(query))
+	}
+	data, _ := json.Marshal(map[string]interface{}{"bool": map[string]interface{}{"filter": filter}})
+	return data
+}
+
+// writePage 함수는 slice번째 scroll의 한 페이지를 정규화해 Parquet 파일에 씁니다. 여러
+// scroll이 동시에 부르므로 스키마와 파일은 mu로 보호합니다. 첫 페이지가 파일의 스키마를
+// 정한 뒤에는 스키마가 바뀌지 않으므로, 레코드 변환은 잠금 밖에서 scroll마다 동시에 합니다.
+func (j *exportJob) writePage(slice int, hits []searchHit) error {
+	if err := j.opts.budget.check(); err != nil {
+		return err
+	}
+	if j.ids != nil {
+		j.mu.Lock()
+		for _, hit := range hits {
+			j.ids[hit.ID] = true
+		}
+		j.mu.Unlock()
+	}
+	if j.delta != nil {
+		if hits = j.delta.changed(hits); len(hits) == 0 {
+			return nil
+		}
+	}
+	docs, hits, err := j.rejects.decodeHits(hits)
+	if err != nil {
+		return err
+	}
+	if hits, docs, err = j.rejects.transformHits(j.chain, hits, docs, &j.dropped); err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	if changed := j.norm.widen(docs); changed != "" && j.opened() {
+		j.mu.Unlock()
+		return schemaErrorf("field %s holds arrays only after the first scroll page, which changes the Parquet schema; rerun with --list-fields %s", changed, changed)
+	}
+	if !j.opened() {
+		if err := j.opts.schemaLock.check(j.label(), j.norm.schema, &j.opts.names); err != nil {
+			j.mu.Unlock()
+			return err
+		}
+	}
+	schema := j.norm.schema
+	if j.opts.docHash {
+		setDocHashes(schema.Fields(), hits, docs)
+	}
+	if j.parts != nil {
+		defer j.mu.Unlock()
+		if err := j.bufferPartitions(hits, docs); err != nil {
+			return err
+		}
+		return j.spend()
+	}
+	// 아직 파일이 없으면 이 페이지로 파일을 열 때까지 잠금을 쥐어, 다른 scroll이 그 사이에
+	// 스키마를 넓히지 못하게 합니다.
+	opened := j.opened()
+	if opened {
+		j.mu.Unlock()
+	}
+	rec, hits, err := j.convert(schema, hits, docs)
+	if err == nil && !opened {
+		err = j.open(slice, rec.Schema())
+	}
+	if !opened {
+		j.mu.Unlock()
+	}
+	if rec != nil {
+		defer rec.Release()
+	}
+	if err != nil {
+		return err
+	}
+	if j.slices != nil {
+		return j.writeSlice(slice, rec, hits)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.opts.memoryStats {
+		j.memory.add(rec)
+	}
+	if err := j.sink.writeHits(rec, hits); err != nil {
+		return err
+	}
+	j.rows += int64(len(hits))
+	j.progress.report(j.label(), j.rows, j.total, false)
+	return j.spend()
+}
+
+// convert 함수는 docs를 schema의 레코드로 바꾸고 레코드 훅을 적용합니다. 거부된 문서는
+// 기록하고 hits에서 뺍니다. 잠금 없이 부를 수 있습니다.
+func (j *exportJob) convert(schema *arrow.Schema, hits []searchHit, docs []map[string]interface{}) (arrow.Record, []searchHit, error) {
+	converted, rejected := j.norm.recordAs(schema, docs)
+	defer converted.Release()
+	hits, _, err := j.rejects.keep(hits, docs, rejected)
+	if err != nil {
+		return nil, nil, err
+	}
+	rec, err := j.opts.hooks.apply(converted)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rec, hits, nil
+}
+
+// opened 함수는 이 인덱스의 파일을 하나라도 열었는지 알려 줍니다. 그 뒤에는 스키마를 바꿀
+// 수 없습니다. mu를 잡고 부릅니다.
+func (j *exportJob) opened() bool {
+	return j.sink != nil || j.parts.opened() || j.sliceSchema != nil
+}
+
+// open 함수는 첫 페이지의 레코드 스키마로 파일을 엽니다. mu를 잡고 부릅니다.
+func (j *exportJob) open(slice int, schema *arrow.Schema) error {
+	if j.slices != nil {
+		_, err := j.sliceSink(slice, schema)
+		return err
+	}
+	sink, err := newParquetSink(j.path, schema, j.mappingJSON, j.sinkColumns(), &j.opts.names, &j.opts.parquet)
+	if err != nil {
+		return err
+	}
+	j.sink = sink
+	j.renames = sink.renames
+	j.lineage = sink.lineage
+	return nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkerPoolLimitsConcurrency(t *testing.T) {
+	pool := newWorkerPool(2)
+	var running, peak int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := pool.acquire(context.Background()); err != nil {
+				t.Error(err)
+				return
+			}
+			defer pool.release()
+			n := atomic.AddInt32(&running, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		}()
+	}
+	wg.Wait()
+	if peak > 2 {
+		t.Errorf("%d workers ran at once, want at most 2", peak)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	pool.acquire(context.Background())
+	pool.acquire(context.Background())
+	if err := pool.acquire(ctx); err == nil {
+		t.Error("acquire on a full pool with a cancelled context succeeded")
+	}
+}
+
+func TestProgressPrinterThrottlesPerIndex(t *testing.T) {
+	var buf bytes.Buffer
+	p := &progressPrinter{w: &buf, every: time.Hour}
+	p.report("logs-a", 100, 1000, false)
+	p.report("logs-a", 200, 1000, false)
+	p.report("logs-b", 50, 0, false)
+	p.report("logs-a", 1000, 1000, true)
+	want := "logs-a: 100/1000 documents (10%)\n" +
+		"logs-b: 50 documents\n" +
+		"logs-a: 1000/1000 documents (100%)\n"
+	if buf.String() != want {
+		t.Errorf("progress output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestShardCountReadsIndexSettings(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/logs/_settings/index.number_of_shards" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"logs-000001": {"settings": {"index": {"number_of_shards": "6"}}}}`))
+	}))
+	defer srv.Close()
+
+	client := &esClient{baseURL: srv.URL, http: srv.Client()}
+	n, err := client.shardCount(context.Background(), "logs")
+	if err != nil || n != 6 {
+		t.Errorf("shardCount = %d, %v; want 6", n, err)
+	}
+}
+
+func TestSearchPITPagesWithSearchAfter(t *testing.T) {
+	var bodies []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		switch len(bodies) {
+		case 1:
+			w.Write([]byte(`{"pit_id": "pit-2", "hits": {"hits": [{"_id": "a", "sort": [7]}, {"_id": "b", "sort": [9]}]}}`))
+		default:
+			w.Write([]byte(`{"pit_id": "pit-2", "hits": {"hits": []}}`))
+		}
+	}))
+	defer srv.Close()
+
+	client := &esClient{baseURL: srv.URL, http: srv.Client()}
+	pit := &pointInTime{id: "pit-1"}
+	var ids []string
+	err := client.searchPIT(context.Background(), pit, pitQuery("logs-a", `{"match_all": {}}`), 2, time.Minute, &searchSlice{ID: 1, Max: 3}, nil, func(hits []searchHit) error {
+		for _, h := range hits {
+			ids = append(ids, h.ID)
+		}
+		return nil
+	})
+	if err != nil || strings.Join(ids, ",") != "a,b" || len(bodies) != 2 {
+		t.Fatalf("searchPIT read %v in %d requests: %v", ids, len(bodies), err)
+	}
+	first, _ := json.Marshal(bodies[0])
+	want := `{"pit":{"id":"pit-1","keep_alive":"60s"},"query":{"bool":{"filter":[{"term":{"_index":"logs-a"}},{"match_all":{}}]}},"size":2,"slice":{"id":1,"max":3},"sort":[{"_shard_doc":"asc"}]}`
+	if string(first) != want {
+		t.Errorf("first request = %s\nwant %s", first, want)
+	}
+	if got := fmt.Sprint(bodies[1]["pit"], bodies[1]["search_after"]); got != "map[id:pit-2 keep_alive:60s] [9]" {
+		t.Errorf("second request pit, search_after = %s", got)
+	}
+	if pit.current() != "pit-2" {
+		t.Errorf("point in time id after the search = %q, want pit-2", pit.current())
+	}
+}
+
+func TestPITKeepAliveAndCloseUseLatestID(t *testing.T) {
+	var mu sync.Mutex
+	var kept []string
+	var closed string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			ID  string `json:"id"`
+			PIT struct {
+				ID string `json:"id"`
+			} `json:"pit"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/_search":
+			kept = append(kept, body.PIT.ID)
+			fmt.Fprintf(w, `{"pit_id": "pit-%d", "hits": {"hits": []}}`, len(kept)+1)
+		case r.Method == http.MethodDelete && r.URL.Path == "/_pit":
+			closed = body.ID
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer srv.Close()
+
+	client := &esClient{baseURL: srv.URL, http: srv.Client()}
+	pit := &pointInTime{id: "pit-1"}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		client.keepPITAlive(ctx, pit, 20*time.Millisecond)
+		close(done)
+	}()
+	for pit.current() != "pit-3" {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+	client.closePIT(pit)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(kept) < 2 || kept[0] != "pit-1" || kept[1] != "pit-2" {
+		t.Errorf("keep-alive searches used %v, want each the id of the previous response", kept)
+	}
+	if want := fmt.Sprintf("pit-%d", len(kept)+1); closed != want {
+		t.Errorf("closed %q, want the latest id %s", closed, want)
+	}
+}
+
+func TestSearchHitDecodesSeqNoAndPrimaryTerm(t *testing.T) {
+	var hits []searchHit
+	json.Unmarshal([]byte(`[{"_id": "a", "_seq_no": 12, "_primary_term": 3}, {"_id": "b"}]`), &hits)
+	if hits[0].SeqNo == nil || *hits[0].SeqNo != 12 || hits[0].PrimaryTerm == nil || *hits[0].PrimaryTerm != 3 {
+		t.Errorf("hit a = %+v", hits[0])
+	}
+	if hits[1].SeqNo != nil || hits[1].PrimaryTerm != nil {
+		t.Errorf("hit b without versions = %+v", hits[1])
+	}
+	if !isMetadataColumn("_seq_no") || !isMetadataColumn("_primary_term") || isMetadataColumn("seq_no") {
+		t.Error("isMetadataColumn does not match esMetadataColumns")
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+// fieldSelection은 browse가 저장하는 필드 선택 파일입니다. Exclude의 필드(점으로 이은
+// 경로)는 매핑에 없는 것처럼 다뤄 컬럼으로 만들지 않습니다. Mapping은 선택을 만든 매핑
+// 파일로, browse가 파일을 다시 열 때 씁니다.
+type fieldSelection struct {
+	Mapping string   `json:"mapping,omitempty"`
+	Exclude []string `json:"exclude"`
+}
+
+// readFieldSelection 함수는 필드 선택 파일을 읽습니다.
+func readFieldSelection(path string) (*fieldSelection, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, configErrorf("reading field selection: %w", err)
+	}
+	var sel fieldSelection
+	if err := json.Unmarshal(data, &sel); err != nil {
+		return nil, configErrorf("decoding field selection %s: %w", path, err)
+	}
+	return &sel, nil
+}
+
+// fieldSelectionOptions는 --fields 플래그입니다.
+type fieldSelectionOptions struct {
+	path      string
+	selection *fieldSelection
+}
+
+func (o *fieldSelectionOptions) bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.path, "fields", "", "field selection file saved by browse; its excluded fields are left out of the output")
+}
+
+// load 함수는 --fields가 있으면 선택 파일을 읽어 둡니다.
+func (o *fieldSelectionOptions) load() error {
+	if o.path == "" {
+		return nil
+	}
+	sel, err := readFieldSelection(o.path)
+	if err != nil {
+		return err
+	}
+	o.selection = sel
+	return nil
+}
+
+// apply 함수는 매핑 JSON에서 제외한 필드를 지운 매핑과, 매핑에 없어 지우지 못한 경로를
+// 반환합니다. --fields가 없으면 mapping을 그대로 반환합니다.
+func (o *fieldSelectionOptions) apply(mapping []byte) ([]byte, []string, error) {
+	if o.selection == nil || len(o.selection.Exclude) == 0 {
+		return mapping, nil, nil
+	}
+	return excludeFields(mapping, o.selection.Exclude)
+}
+
+// loadMapping 함수는 loadMappingFile처럼 매핑 파일을 스키마로 읽되, 제외한 필드를 뺍니다.
+// 선택 파일의 경로가 매핑에 없으면 다른 매핑으로 만든 선택으로 보고 오류를 반환합니다.
+func (o *fieldSelectionOptions) loadMapping(path string) (*arrow.Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, configErrorf("reading mapping: %w", err)
+	}
+	data, missing, err := o.apply(data)
+	if err != nil {
+		return nil, schemaErrorf("parsing mapping %s: %w", path, err)
+	}
+	if len(missing) > 0 {
+		return nil, configErrorf("--fields %s excludes %s, which the mapping %s does not have", o.path, strings.Join(missing, ", "), path)
+	}
+	schema, err := schemaFromMapping(data)
+	if err != nil {
+		return nil, schemaErrorf("parsing mapping %s: %w", path, err)
+	}
+	return schema, nil
+}
+
+// excludeFields 함수는 매핑 JSON의 properties에서 exclude의 경로를 지웁니다. 객체 필드를
+// 지우면 하위 필드도 함께 빠집니다. 찾지 못한 경로는 missing으로 반환합니다.
+func excludeFields(mapping []byte, exclude []string) ([]byte, []string, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal(mapping, &m); err != nil {
+		return nil, nil, err
+	}
+	var missing []string
+	for _, path := range exclude {
+		if !deleteMappingField(m, strings.Split(path, ".")) {
+			missing = append(missing, path)
+		}
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, missing, nil
+}
+
+func deleteMappingField(mapping map[string]interface{}, path []string) bool {
+	for _, key := range path[:len(path)-1] {
+		props, _ := mapping["properties"].(map[string]interface{})
+		next, ok := props[key].(map[string]interface{})
+		if !ok {
+			return false
+		}
+		mapping = next
+	}
+	props, _ := mapping["properties"].(map[string]interface{})
+	last := path[len(path)-1]
+	if _, ok := props[last]; !ok {
+		return false
+	}
+	delete(props, last)
+	return true
+}
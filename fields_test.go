@@ -0,0 +1,33 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestExcludeFields(t *testing.T) {
+	mapping := `{"properties": {
+		"age": {"type": "long"},
+		"user": {"properties": {"name": {"type": "text"}, "secret": {"type": "keyword"}}}
+	}}`
+	data, missing, err := excludeFields([]byte(mapping), []string{"user.secret", "age", "user.nope"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(missing, []string{"user.nope"}) {
+		t.Errorf("missing = %v, want [user.nope]", missing)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"properties": map[string]interface{}{
+		"user": map[string]interface{}{"properties": map[string]interface{}{
+			"name": map[string]interface{}{"type": "text"},
+		}},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mapping = %s", data)
+	}
+}
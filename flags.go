@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// stringListFlag는 여러 번 지정할 수 있는 문자열 플래그입니다.
+// 쉼표로 구분된 값도 각각의 항목으로 나눕니다.
+type stringListFlag []string
+
+func (s *stringListFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringListFlag) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*s = append(*s, v)
+		}
+	}
+	return nil
+}
+
+// parseInterspersed 함수는 위치 인자 뒤에 온 플래그(head a.parquet -n 20)까지 fs로 읽고
+// 위치 인자만 반환합니다. flag 패키지는 첫 위치 인자에서 멈추므로 남은 인자를 위치 인자
+// 하나씩 떼며 다시 읽습니다. "--" 뒤의 인자는 모두 위치 인자입니다.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		if len(rest) < len(args) && args[len(args)-len(rest)-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// byteSizeFlag는 10gb, 512mb처럼 단위를 붙일 수 있는 바이트 수 플래그입니다. 단위는
+// Elasticsearch처럼 대소문자를 가리지 않고 1024의 거듭제곱이며, 단위가 없으면 바이트입니다.
+type byteSizeFlag int64
+
+func (b *byteSizeFlag) String() string {
+	if *b == 0 {
+		return "0"
+	}
+	return formatBytes(int64(*b))
+}
+
+func (b *byteSizeFlag) Set(value string) error {
+	s := strings.ToLower(strings.TrimSpace(value))
+	num := strings.TrimRight(s, "abcdefghijklmnopqrstuvwxyz ")
+	unit := strings.TrimSpace(s[len(num):])
+	shift, ok := map[string]uint{"": 0, "b": 0, "k": 10, "kb": 10, "kib": 10, "m": 20, "mb": 20, "mib": 20, "g": 30, "gb": 30, "gib": 30, "t": 40, "tb": 40, "tib": 40, "p": 50, "pb": 50, "pib": 50}[unit]
+	n, err := strconv.ParseFloat(num, 64)
+	if !ok || err != nil || n < 0 {
+		return fmt.Errorf("invalid byte size %q (want a number with an optional unit such as 500mb or 10gb)", value)
+	}
+	*b = byteSizeFlag(n * float64(int64(1)<<shift))
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/apache/arrow/go/v10/arrow"
+
+	"es-schema/esschema"
+)
+
+// flattenedFormatOptions는 flattened 필드의 컬럼 형식을 고르는 --flattened-format
+// 플래그입니다. map은 점으로 이은 키와 문자열 값의 map<utf8, utf8>로, json은 객체 그대로의
+// JSON 텍스트로 씁니다.
+type flattenedFormatOptions struct {
+	format string
+}
+
+func (o *flattenedFormatOptions) bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.format, "flattened-format", "map", "column type of flattened fields: map (map<utf8, utf8> keyed by the dotted path of each leaf, values as text like Elasticsearch indexes them) or json (the object as JSON text, for readers without map support)")
+}
+
+func (o *flattenedFormatOptions) validate() error {
+	switch o.format {
+	case "", "map", "json":
+		return nil
+	}
+	return configErrorf("unknown --flattened-format %q (want map or json)", o.format)
+}
+
+// apply 함수는 json이면 fields의 flattened 필드를 문자열 컬럼으로 바꿉니다.
+func (o *flattenedFormatOptions) apply(fields []arrow.Field) []arrow.Field {
+	if o.format != "json" {
+		return fields
+	}
+	return esschema.FlattenedAsJSON(fields)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestFlattenedFormatValidate(t *testing.T) {
+	for _, format := range []string{"map", "json"} {
+		if err := (&flattenedFormatOptions{format: format}).validate(); err != nil {
+			t.Errorf("--flattened-format %s: %v", format, err)
+		}
+	}
+	if err := (&flattenedFormatOptions{format: "struct"}).validate(); exitCodeFor(err) != exitConfigError {
+		t.Errorf("--flattened-format struct = %v, want a config error", err)
+	}
+}
+
+func TestFlattenedRecordDocuments(t *testing.T) {
+	schema, err := schemaFromMapping([]byte(`{"properties": {"id": {"type": "long"}, "labels": {"type": "flattened"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, _, err := createArrowRecord(context.Background(), schema, []map[string]interface{}{
+		{"id": 1, "labels": map[string]interface{}{"env": "prod", "release": map[string]interface{}{"channel": "beta"}}},
+		{"id": 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Release()
+	docs := recordDocuments(rec)
+	want := map[string]interface{}{"env": "prod", "release.channel": "beta"}
+	if got := docs[0]["labels"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("labels = %#v, want %#v", got, want)
+	}
+	if _, ok := docs[1]["labels"]; ok {
+		t.Errorf("a document without labels got %v", docs[1]["labels"])
+	}
+}
@@ -0,0 +1,262 @@
+//go:build !(js && wasm)
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/flight"
+	"github.com/apache/arrow/go/v10/arrow/ipc"
+)
+
+// flightOptions는 flight 명령의 설정입니다. 매핑을 읽는 설정(--list-fields, --ip-format 등)과
+// 배치, 잘못된 문서, 범위를 넘는 숫자의 처리는 convert와 같고 모든 교환에 적용됩니다.
+type flightOptions struct {
+	in         mappingInputOptions
+	mappingDir string
+	listen     string
+	batch      batchOptions
+	bad        badDocumentOptions
+	overflow   overflowOptions
+	sanitize   sanitizeOptions
+}
+
+func setupFlight(fs *flag.FlagSet) func(ctx context.Context, report *runReport, args []string) error {
+	var o flightOptions
+	o.in.bind(fs)
+	fs.StringVar(&o.mappingDir, "mapping-dir", "", "directory of mapping files that an exchange can name with a path descriptor: path [\"logs\"] reads logs.json from it")
+	fs.StringVar(&o.listen, "listen", "localhost:8815", "address to serve the Flight service on")
+	o.batch.bind(fs)
+	o.bad.bind(fs)
+	o.overflow.bind(fs)
+	o.sanitize.bind(fs)
+
+	return func(ctx context.Context, report *runReport, args []string) error {
+		if len(args) > 0 {
+			return configErrorf("flight: unexpected arguments %v", args)
+		}
+		for _, v := range []interface{ validate() error }{&o.batch, &o.bad, &o.overflow, &o.sanitize, &o.in.ipFormat, &o.in.scaled, &o.in.tsUnit, &o.in.flattened, &o.in.geoShape, &o.in.vectorDims} {
+			if err := v.validate(); err != nil {
+				return err
+			}
+		}
+		if o.mappingDir != "" {
+			if info, err := os.Stat(o.mappingDir); err != nil || !info.IsDir() {
+				return configErrorf("flight: --mapping-dir %s is not a directory", o.mappingDir)
+			}
+		}
+		svc := &flightConverter{opts: &o}
+		srv := flight.NewServerWithMiddleware(nil)
+		if err := srv.Init(o.listen); err != nil {
+			return configErrorf("flight: --listen: %w", err)
+		}
+		srv.RegisterFlightService(svc)
+		served := make(chan error, 1)
+		go func() { served <- srv.Serve() }()
+		fmt.Printf("flight: DoExchange listening on %s\n", srv.Addr())
+		select {
+		case <-ctx.Done():
+			srv.Shutdown()
+			<-served
+		case err := <-served:
+			if err != nil {
+				return connectionErrorf("flight: %w", err)
+			}
+		}
+		svc.finish(report)
+		return nil
+	}
+}
+
+// flightConverter는 DoExchange로 NDJSON 문서를 받아 매핑의 Arrow 레코드 배치로 돌려주는
+// Flight 서비스입니다. 한 교환은 다음과 같습니다.
+//
+//   - 클라이언트의 첫 메시지의 descriptor가 매핑을 정합니다. CMD는 매핑 JSON 자체이고,
+//     PATH는 --mapping-dir의 매핑 이름 하나입니다. descriptor가 없으면 --mapping이나
+//     --index의 매핑입니다.
+//   - 클라이언트는 NDJSON(gzip도 됩니다)을 메시지마다 data_body에 나눠 보냅니다. 문서는
+//     메시지 경계에 걸쳐도 됩니다. 다 보내면 보내는 쪽을 닫습니다.
+//   - 서버는 --batch-size 문서마다 레코드 배치를 바로 돌려보내므로, 클라이언트는 보내는
+//     동안에도 받아야 합니다. 스키마는 convert처럼 첫 배치에서만 넓어집니다.
+//   - 마지막 메시지의 app_metadata는 교환의 결과를 담은 JSON(exchangeSummary)입니다.
+type flightConverter struct {
+	flight.BaseFlightServer
+	opts *flightOptions
+
+	mu        sync.Mutex
+	exchanges int64
+	rows      int64
+	rejects   docRejects
+}
+
+// exchangeSummary는 교환 하나의 결과입니다.
+type exchangeSummary struct {
+	Rows             int64            `json:"rows"`
+	DocumentsFailed  int64            `json:"documents_failed,omitempty"`
+	FailureReasons   map[string]int64 `json:"failure_reasons,omitempty"`
+	ValuesNulled     int64            `json:"values_nulled,omitempty"`
+	NumericOverflows map[string]int64 `json:"numeric_overflows,omitempty"`
+	SanitizedStrings map[string]int64 `json:"sanitized_strings,omitempty"`
+}
+
+func (c *flightConverter) DoExchange(stream flight.FlightService_DoExchangeServer) error {
+	ctx := stream.Context()
+	first, err := stream.Recv()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	schema, source, err := c.opts.exchangeSchema(ctx, first.FlightDescriptor)
+	if err != nil {
+		return err
+	}
+	src, err := readNDJSON(source, &exchangeReader{stream: stream, next: first}, nil)
+	if err != nil {
+		return err
+	}
+	defer src.close()
+
+	norm := newNormalizer(schema)
+	norm.rejectBad = c.opts.bad.skip()
+	c.opts.overflow.apply(norm)
+	c.opts.sanitize.apply(norm)
+	var rejects docRejects
+	var w *flight.Writer
+	var rows int64
+	var batcher *recordBatcher
+	batcher = newRecordBatcher(c.opts.batch, func(hits []searchHit) error {
+		docs, hits, err := rejects.decodeHits(hits)
+		if err != nil {
+			return err
+		}
+		if changed := norm.widen(docs); changed != "" && w != nil {
+			return schemaErrorf("field %s holds arrays only after the first %d documents, which changes the schema of the stream; start the server with --list-fields %s", changed, batcher.firstRows, changed)
+		}
+		rec, rejected := norm.record(docs)
+		defer rec.Release()
+		if _, _, err := rejects.keep(hits, docs, rejected); err != nil {
+			return err
+		}
+		if w == nil {
+			w = flight.NewRecordWriter(stream, ipc.WithSchema(rec.Schema()))
+		}
+		rows += rec.NumRows()
+		return w.Write(rec)
+	})
+	if err := src.feed(ctx, batcher); err != nil {
+		return err
+	}
+	if w == nil {
+		// 문서가 없어도 클라이언트가 스키마는 받도록 빈 스트림을 보냅니다.
+		w = flight.NewRecordWriter(stream, ipc.WithSchema(norm.schema))
+	}
+	summary := exchangeSummary{
+		Rows:             rows,
+		DocumentsFailed:  rejects.count,
+		FailureReasons:   rejects.reasons,
+		ValuesNulled:     norm.dropped,
+		NumericOverflows: norm.overflowCounts(),
+		SanitizedStrings: norm.sanitizedCounts(),
+	}
+	meta, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteMetadata(meta); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.exchanges++
+	c.rows += rows
+	c.rejects.merge(&rejects)
+	c.mu.Unlock()
+	fmt.Printf("flight: %s: converted %d documents, left out %d\n", source, rows, rejects.count)
+	return nil
+}
+
+// finish 함수는 서버가 멈출 때 모든 교환의 결과를 보고서에 더합니다. 빠진 문서는 교환마다
+// 클라이언트에 알렸으므로 서버의 실패로 보지 않습니다.
+func (c *flightConverter) finish(report *runReport) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	report.RowsExported += c.rows
+	report.DocumentsFailed += c.rejects.count
+	for reason, n := range c.rejects.reasons {
+		report.addFailures(reason, n)
+	}
+	fmt.Printf("flight: served %d exchanges, %d rows\n", c.exchanges, c.rows)
+}
+
+// exchangeSchema 함수는 교환의 첫 메시지의 descriptor로 매핑을 정해 스키마와 매핑의 이름을
+// 반환합니다.
+func (o *flightOptions) exchangeSchema(ctx context.Context, d *flight.FlightDescriptor) (*arrow.Schema, string, error) {
+	switch {
+	case len(d.GetCmd()) == 0 && len(d.GetPath()) == 0:
+		if o.in.mapping == "" && o.in.index == "" {
+			return nil, "", configErrorf("flight: the exchange names no mapping and the server has no --mapping or --index")
+		}
+		schema, _, err := o.in.schema(ctx)
+		return schema, o.in.source(), err
+	case d.GetType() == flight.DescriptorCMD:
+		schema, err := o.in.schemaOf(d.GetCmd(), "the exchange's command descriptor")
+		return schema, "command descriptor", err
+	case d.GetType() == flight.DescriptorPATH:
+		path := d.GetPath()
+		if o.mappingDir == "" {
+			return nil, "", configErrorf("flight: path descriptor %q needs --mapping-dir on the server", path)
+		}
+		if len(path) != 1 || path[0] == "" || path[0] == ".." || strings.ContainsAny(path[0], `/\`) {
+			return nil, "", configErrorf("flight: path descriptor must be one mapping name, got %q", path)
+		}
+		data, err := os.ReadFile(filepath.Join(o.mappingDir, path[0]+".json"))
+		if err != nil {
+			return nil, "", configErrorf("flight: reading mapping %s: %w", path[0], err)
+		}
+		schema, err := o.in.schemaOf(data, path[0])
+		return schema, path[0], err
+	}
+	return nil, "", configErrorf("flight: unknown descriptor type %v", d.GetType())
+}
+
+// exchangeReader는 교환에서 받은 메시지의 data_body를 이어 읽는 io.Reader입니다. 클라이언트가
+// 보내는 쪽을 닫으면 io.EOF입니다.
+type exchangeReader struct {
+	stream flight.FlightService_DoExchangeServer
+	next   *flight.FlightData
+	buf    []byte
+}
+
+func (r *exchangeReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		msg := r.next
+		r.next = nil
+		if msg == nil {
+			var err error
+			if msg, err = r.stream.Recv(); err != nil {
+				return 0, err
+			}
+		}
+		if len(msg.DataHeader) > 0 {
+			return 0, errors.New("the exchange sent Arrow data; send NDJSON bytes in data_body")
+		}
+		r.buf = msg.DataBody
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
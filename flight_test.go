@@ -0,0 +1,129 @@
+//go:build !(js && wasm)
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/v10/arrow/flight"
+)
+
+// exchangeStream은 클라이언트 대신 in을 보내고 서버가 보낸 메시지를 out에 모읍니다.
+type exchangeStream struct {
+	flight.FlightService_DoExchangeServer
+	in  []*flight.FlightData
+	out []*flight.FlightData
+}
+
+func (s *exchangeStream) Context() context.Context { return context.Background() }
+
+func (s *exchangeStream) Recv() (*flight.FlightData, error) {
+	if len(s.in) == 0 {
+		return nil, io.EOF
+	}
+	msg := s.in[0]
+	s.in = s.in[1:]
+	return msg, nil
+}
+
+func (s *exchangeStream) Send(msg *flight.FlightData) error {
+	// Writer는 같은 FlightData를 다시 쓰므로 복사해 둡니다.
+	s.out = append(s.out, &flight.FlightData{
+		FlightDescriptor: msg.FlightDescriptor,
+		DataHeader:       append([]byte(nil), msg.DataHeader...),
+		AppMetadata:      append([]byte(nil), msg.AppMetadata...),
+		DataBody:         append([]byte(nil), msg.DataBody...),
+	})
+	return nil
+}
+
+// sentStream은 서버가 보낸 메시지를 다시 읽습니다.
+type sentStream struct{ msgs []*flight.FlightData }
+
+func (s *sentStream) Recv() (*flight.FlightData, error) {
+	if len(s.msgs) == 0 {
+		return nil, io.EOF
+	}
+	msg := s.msgs[0]
+	s.msgs = s.msgs[1:]
+	return msg, nil
+}
+
+func TestExchangeReader(t *testing.T) {
+	stream := &exchangeStream{in: []*flight.FlightData{{DataBody: []byte("b\n")}, {}, {DataBody: []byte("c")}}}
+	r := &exchangeReader{stream: stream, next: &flight.FlightData{DataBody: []byte("{\"a\":")}}
+	data, err := io.ReadAll(r)
+	if err != nil || string(data) != "{\"a\":b\nc" {
+		t.Errorf("read %q, %v", data, err)
+	}
+	r = &exchangeReader{stream: stream, next: &flight.FlightData{DataHeader: []byte{1}}}
+	if _, err := io.ReadAll(r); err == nil || !strings.Contains(err.Error(), "NDJSON") {
+		t.Errorf("Arrow data: %v", err)
+	}
+}
+
+func TestExchangeSchemaErrors(t *testing.T) {
+	var o flightOptions
+	for _, d := range []*flight.FlightDescriptor{
+		nil,
+		{Type: flight.DescriptorPATH, Path: []string{"logs"}},
+	} {
+		if _, _, err := o.exchangeSchema(context.Background(), d); err == nil {
+			t.Errorf("%v without --mapping-dir: no error", d)
+		}
+	}
+	o.mappingDir = t.TempDir()
+	for _, path := range [][]string{{".."}, {"a/b"}, {"a", "b"}, {"missing"}} {
+		if _, _, err := o.exchangeSchema(context.Background(), &flight.FlightDescriptor{Type: flight.DescriptorPATH, Path: path}); err == nil {
+			t.Errorf("path %q: no error", path)
+		}
+	}
+}
+
+func TestFlightExchange(t *testing.T) {
+	var o flightOptions
+	o.batch = batchOptions{rows: 2, bytes: defaultBatchBytes}
+	o.bad.mode = badDocumentNull
+	o.in.tsUnit.unit = "ns"
+	mapping := `{"properties": {"status": {"type": "integer"}, "msg": {"type": "keyword"}}}`
+	stream := &exchangeStream{in: []*flight.FlightData{
+		{FlightDescriptor: &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: []byte(mapping)}, DataBody: []byte(`{"status": 200, "ms`)},
+		{DataBody: []byte("g\": \"ok\"}\n{\"status\": \"x\"}\n")},
+		{DataBody: []byte("{\"status\": 404}\nnot json\n")},
+	}}
+	c := &flightConverter{opts: &o}
+	if err := c.DoExchange(stream); err != nil {
+		t.Fatal(err)
+	}
+
+	rdr, err := flight.NewRecordReader(&sentStream{msgs: stream.out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rdr.Release()
+	var rows int64
+	for rdr.Next() {
+		rows += rdr.Record().NumRows()
+	}
+	if rows != 3 {
+		t.Errorf("rows = %d, want 3", rows)
+	}
+	var summary exchangeSummary
+	last := stream.out[len(stream.out)-1]
+	if err := json.Unmarshal(last.AppMetadata, &summary); err != nil {
+		t.Fatalf("app_metadata %q: %v", last.AppMetadata, err)
+	}
+	if summary.Rows != 3 || summary.DocumentsFailed != 1 || summary.ValuesNulled != 1 {
+		t.Errorf("summary = %+v", summary)
+	}
+
+	report := newRunReport("flight")
+	c.finish(report)
+	if report.RowsExported != 3 || report.DocumentsFailed != 1 {
+		t.Errorf("report rows = %d, failed = %d", report.RowsExported, report.DocumentsFailed)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"context"
+	"flag"
+)
+
+// setupFlight 함수는 WebAssembly 빌드에서 오류만 반환합니다. 브라우저에서는 gRPC 서버를 열 수
+// 없습니다.
+func setupFlight(fs *flag.FlagSet) func(ctx context.Context, report *runReport, args []string) error {
+	return func(ctx context.Context, report *runReport, args []string) error {
+		return configErrorf("flight: the Flight service is not available in the WebAssembly build")
+	}
+}
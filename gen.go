@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+// genTargets는 gen이 만드는 코드의 종류입니다. arrow는 스키마를 만드는 Go 소스, avro는 Avro
+// 스키마(.avsc)입니다.
+var genTargets = []string{"arrow", "avro"}
+
+// genOptions는 gen 명령의 설정입니다.
+type genOptions struct {
+	in       mappingInputOptions
+	names    nameOptions
+	pkg      string
+	varName  string
+	arrowPkg string
+	record   string
+	out      string
+}
+
+func setupGen(fs *flag.FlagSet) func(ctx context.Context, report *runReport, args []string) error {
+	var o genOptions
+	o.in.bind(fs)
+	fs.StringVar(&o.in.mapping, "m", "", "shorthand for --mapping")
+	o.names.bind(fs)
+	fs.StringVar(&o.pkg, "package", "schema", "package clause of the generated file")
+	fs.StringVar(&o.varName, "var", "Schema", "name of the generated *arrow.Schema variable")
+	fs.StringVar(&o.arrowPkg, "arrow-import", "github.com/apache/arrow/go/v10/arrow", "import path of the arrow package the generated code builds the schema with")
+	fs.StringVar(&o.record, "record", "Document", "name of the top-level Avro record (gen avro)")
+	fs.StringVar(&o.out, "out", "-", "file to write, or - for standard output")
+
+	return func(ctx context.Context, report *runReport, args []string) error {
+		// 대상 이름 뒤의 플래그(gen arrow -m mapping.json)는 flag 패키지가 읽지 않고 남기므로
+		// 다시 읽습니다.
+		if len(args) == 0 {
+			return configErrorf("gen: missing target (want %s)", strings.Join(genTargets, " or "))
+		}
+		target := args[0]
+		if err := fs.Parse(args[1:]); err != nil {
+			return configErrorf("gen: %w", err)
+		}
+		if err := o.validate(target, fs.Args()); err != nil {
+			return err
+		}
+		if target == "avro" {
+			// Avro 이름은 [A-Za-z_][A-Za-z0-9_]*뿐이므로 필드 이름을 언제나 정리합니다.
+			o.names.sanitize = true
+		}
+		schema, _, err := o.in.schema(ctx)
+		if err != nil {
+			return err
+		}
+		if o.names.enabled() {
+			var renames []fieldRename
+			if schema, renames, err = o.names.apply(schema); err != nil {
+				return err
+			}
+			recordRenames(report, renames)
+		}
+		var src []byte
+		if target == "avro" {
+			src, err = avroSchema(o.record, schema.Fields())
+			if err != nil {
+				err = schemaErrorf("gen avro: %w", err)
+			}
+		} else {
+			src, err = goArrowSchema(o.pkg, o.varName, o.arrowPkg, "es-schema gen arrow from "+o.in.source(), schema)
+		}
+		if err != nil {
+			return err
+		}
+		if o.out == "-" {
+			_, err = os.Stdout.Write(src)
+			return err
+		}
+		if err := os.WriteFile(o.out, src, 0o644); err != nil {
+			return configErrorf("writing %s: %w", o.out, err)
+		}
+		return nil
+	}
+}
+
+func (o *genOptions) validate(target string, args []string) error {
+	if target != "arrow" && target != "avro" {
+		return configErrorf("gen: unknown target %q (want %s)", target, strings.Join(genTargets, " or "))
+	}
+	if len(args) > 0 {
+		return configErrorf("gen: unexpected arguments %v", args)
+	}
+	if target == "avro" {
+		if !avroName.MatchString(o.record) {
+			return configErrorf("gen: --record %q is not an Avro name", o.record)
+		}
+		return o.names.validate()
+	}
+	if !token.IsIdentifier(o.pkg) || o.pkg == "_" {
+		return configErrorf("gen: --package %q is not a Go package name", o.pkg)
+	}
+	if !token.IsIdentifier(o.varName) || o.varName == "_" {
+		return configErrorf("gen: --var %q is not a Go identifier", o.varName)
+	}
+	if o.arrowPkg == "" || !strings.HasSuffix(o.arrowPkg, "/arrow") {
+		return configErrorf("gen: --arrow-import %q is not the path of an arrow package", o.arrowPkg)
+	}
+	return o.names.validate()
+}
+
+// goArrowSchema 함수는 schema를 만드는 Go 소스 파일을 반환합니다. 파일은 패키지 변수
+// 하나(var Schema = arrow.NewSchema(...))만 정의하므로, Arrow를 직접 쓰는 프로그램이 매핑에서
+// 만든 스키마를 고정된 코드로 가져다 쓸 수 있습니다. source는 생성 주석에 남길 출처입니다.
+func goArrowSchema(pkg, varName, arrowPkg, source string, schema *arrow.Schema) ([]byte, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "// Code generated by %s; DO NOT EDIT.\n\n", source)
+	fmt.Fprintf(&sb, "package %s\n\n", pkg)
+	fmt.Fprintf(&sb, "import %q\n\n", arrowPkg)
+	fmt.Fprintf(&sb, "// %s is the Arrow schema of the mapping.\n", varName)
+	fmt.Fprintf(&sb, "var %s = arrow.NewSchema(", varName)
+	if err := goFields(&sb, schema.Fields()); err != nil {
+		return nil, err
+	}
+	if md := schema.Metadata(); md.Len() > 0 {
+		sb.WriteString(", &")
+		goNewMetadata(&sb, md)
+		sb.WriteString(")\n")
+	} else {
+		sb.WriteString(", nil)\n")
+	}
+	src, err := format.Source([]byte(sb.String()))
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w", err)
+	}
+	return src, nil
+}
+
+func goFields(sb *strings.Builder, fields []arrow.Field) error {
+	sb.WriteString("[]arrow.Field{\n")
+	for _, f := range fields {
+		sb.WriteString("{Name: " + strconv.Quote(f.Name) + ", Type: ")
+		if err := goType(sb, f.Type); err != nil {
+			return fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		if f.Nullable {
+			sb.WriteString(", Nullable: true")
+		}
+		if f.HasMetadata() {
+			sb.WriteString(", Metadata: ")
+			goNewMetadata(sb, f.Metadata)
+		}
+		sb.WriteString("},\n")
+	}
+	sb.WriteString("}")
+	return nil
+}
+
+// goNewMetadata 함수는 md를 만드는 arrow.NewMetadata 호출을 씁니다.
+func goNewMetadata(sb *strings.Builder, md arrow.Metadata) {
+	sb.WriteString("arrow.NewMetadata(")
+	for i, values := range [][]string{md.Keys(), md.Values()} {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		quoted := make([]string, len(values))
+		for j, v := range values {
+			quoted[j] = strconv.Quote(v)
+		}
+		sb.WriteString("[]string{" + strings.Join(quoted, ", ") + "}")
+	}
+	sb.WriteString(")")
+}
+
+// goTypeNames는 인자 없는 타입의 arrow 패키지 변수입니다.
+var goTypeNames = map[arrow.Type]string{
+	arrow.STRING:  "arrow.BinaryTypes.String",
+	arrow.BINARY:  "arrow.BinaryTypes.Binary",
+	arrow.INT8:    "arrow.PrimitiveTypes.Int8",
+	arrow.INT16:   "arrow.PrimitiveTypes.Int16",
+	arrow.INT32:   "arrow.PrimitiveTypes.Int32",
+	arrow.INT64:   "arrow.PrimitiveTypes.Int64",
+	arrow.UINT8:   "arrow.PrimitiveTypes.Uint8",
+	arrow.UINT16:  "arrow.PrimitiveTypes.Uint16",
+	arrow.UINT32:  "arrow.PrimitiveTypes.Uint32",
+	arrow.UINT64:  "arrow.PrimitiveTypes.Uint64",
+	arrow.FLOAT16: "arrow.FixedWidthTypes.Float16",
+	arrow.FLOAT32: "arrow.PrimitiveTypes.Float32",
+	arrow.FLOAT64: "arrow.PrimitiveTypes.Float64",
+	arrow.BOOL:    "arrow.FixedWidthTypes.Boolean",
+}
+
+// goTimeUnits는 arrow.TimeUnit 상수의 이름입니다.
+var goTimeUnits = map[arrow.TimeUnit]string{
+	arrow.Second:      "arrow.Second",
+	arrow.Millisecond: "arrow.Millisecond",
+	arrow.Microsecond: "arrow.Microsecond",
+	arrow.Nanosecond:  "arrow.Nanosecond",
+}
+
+// goType 함수는 t를 만드는 Go 식을 씁니다. 매핑에서 만들 수 없는 타입은 오류입니다.
+func goType(sb *strings.Builder, t arrow.DataType) error {
+	if name, ok := goTypeNames[t.ID()]; ok {
+		sb.WriteString(name)
+		return nil
+	}
+	switch t := t.(type) {
+	case *arrow.TimestampType:
+		fmt.Fprintf(sb, "&arrow.TimestampType{Unit: %s", goTimeUnits[t.Unit])
+		if t.TimeZone != "" {
+			sb.WriteString(", TimeZone: " + strconv.Quote(t.TimeZone))
+		}
+		sb.WriteString("}")
+	case *arrow.FixedSizeBinaryType:
+		fmt.Fprintf(sb, "&arrow.FixedSizeBinaryType{ByteWidth: %d}", t.ByteWidth)
+	case *arrow.Decimal128Type:
+		fmt.Fprintf(sb, "&arrow.Decimal128Type{Precision: %d, Scale: %d}", t.Precision, t.Scale)
+	case *arrow.ListType:
+		sb.WriteString("arrow.ListOf(")
+		if err := goType(sb, t.Elem()); err != nil {
+			return err
+		}
+		sb.WriteString(")")
+	case *arrow.FixedSizeListType:
+		fmt.Fprintf(sb, "arrow.FixedSizeListOf(%d, ", t.Len())
+		if err := goType(sb, t.Elem()); err != nil {
+			return err
+		}
+		sb.WriteString(")")
+	case *arrow.MapType:
+		sb.WriteString("arrow.MapOf(")
+		if err := goType(sb, t.KeyType()); err != nil {
+			return err
+		}
+		sb.WriteString(", ")
+		if err := goType(sb, t.ItemType()); err != nil {
+			return err
+		}
+		sb.WriteString(")")
+	case *arrow.StructType:
+		sb.WriteString("arrow.StructOf(")
+		if err := goFields(sb, t.Fields()); err != nil {
+			return err
+		}
+		sb.WriteString("...)")
+	case *arrow.DictionaryType:
+		sb.WriteString("&arrow.DictionaryType{IndexType: ")
+		if err := goType(sb, t.IndexType); err != nil {
+			return err
+		}
+		sb.WriteString(", ValueType: ")
+		if err := goType(sb, t.ValueType); err != nil {
+			return err
+		}
+		if t.Ordered {
+			sb.WriteString(", Ordered: true")
+		}
+		sb.WriteString("}")
+	default:
+		return fmt.Errorf("no Go expression for Arrow type %s", t)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestGoArrowSchema(t *testing.T) {
+	schema, err := schemaFromMapping([]byte(`{"properties": {
+		"title":     {"type": "text"},
+		"views":     {"type": "long"},
+		"at":        {"type": "date"},
+		"client_ip": {"type": "ip"},
+		"embedding": {"type": "dense_vector", "dims": 3},
+		"comments":  {"type": "nested", "properties": {"likes": {"type": "integer"}}}
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := goArrowSchema("docs", "Docs", "github.com/apache/arrow/go/v10/arrow", "es-schema gen arrow from m.json", schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"// Code generated by es-schema gen arrow from m.json; DO NOT EDIT.\n",
+		"package docs\n",
+		`var Docs = arrow.NewSchema([]arrow.Field{`,
+		`{Name: "at", Type: &arrow.TimestampType{Unit: arrow.Nanosecond, TimeZone: "UTC"}, Nullable: true},`,
+		`{Name: "client_ip", Type: &arrow.FixedSizeBinaryType{ByteWidth: 16}, Nullable: true},`,
+		`{Name: "embedding", Type: arrow.FixedSizeListOf(3, arrow.PrimitiveTypes.Float32), Nullable: true},`,
+		`{Name: "likes", Type: arrow.PrimitiveTypes.Int32, Nullable: true},`,
+		"}, nil)\n",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated code has no %q:\n%s", want, src)
+		}
+	}
+}
+
+func TestGenValidate(t *testing.T) {
+	o := genOptions{pkg: "schema", varName: "Schema", arrowPkg: "github.com/apache/arrow/go/v12/arrow"}
+	o.names.bind(flag.NewFlagSet("gen", flag.ContinueOnError))
+	if err := o.validate("arrow", nil); err != nil {
+		t.Fatal(err)
+	}
+	bad := []genOptions{
+		{pkg: "my-schema", varName: "Schema", arrowPkg: o.arrowPkg},
+		{pkg: "schema", varName: "1st", arrowPkg: o.arrowPkg},
+		{pkg: "schema", varName: "Schema", arrowPkg: "github.com/apache/arrow/go/v12"},
+	}
+	for _, b := range bad {
+		b.names = o.names
+		if err := b.validate("arrow", nil); exitCodeFor(err) != exitConfigError {
+			t.Errorf("validate(%+v) = %v, want a config error", b, err)
+		}
+	}
+	if err := (&genOptions{record: "Document", names: o.names}).validate("avro", nil); err != nil {
+		t.Errorf("avro target: %v", err)
+	}
+	if err := (&genOptions{record: "log-event", names: o.names}).validate("avro", nil); exitCodeFor(err) != exitConfigError {
+		t.Errorf("avro --record log-event = %v, want a config error", err)
+	}
+	if err := o.validate("proto", nil); err == nil || !strings.Contains(err.Error(), `unknown target "proto"`) {
+		t.Errorf("unknown target = %v", err)
+	}
+}
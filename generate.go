@@ -0,0 +1,372 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+)
+
+// generateOptions는 generate 명령의 설정입니다.
+type generateOptions struct {
+	mappingPath string
+	out         string
+	count       int
+	seed        int64
+	maxDepth    int
+	maxArray    int
+}
+
+func setupGenerate(fs *flag.FlagSet) func(ctx context.Context, report *runReport, args []string) error {
+	var o generateOptions
+	fs.StringVar(&o.mappingPath, "mapping", "", "mapping JSON file to generate documents for (required)")
+	fs.StringVar(&o.out, "out", "-", "bulk file to write, or - for standard output")
+	fs.IntVar(&o.count, "count", 100, "number of documents")
+	fs.Int64Var(&o.seed, "seed", 0, "random seed; the same seed and mapping give the same documents (0 picks one from the clock)")
+	fs.IntVar(&o.maxDepth, "max-depth", 4, "deepest object level to generate, counting the document as 1; deeper object and nested fields are left out")
+	fs.IntVar(&o.maxArray, "max-array", 3, "most values in one generated array; nested fields get 0 to this many objects")
+
+	return func(ctx context.Context, report *runReport, args []string) error {
+		if len(args) > 0 {
+			return configErrorf("generate: unexpected arguments %v", args)
+		}
+		if o.mappingPath == "" {
+			return configErrorf("generate: --mapping is required")
+		}
+		if o.count < 0 || o.maxDepth < 1 || o.maxArray < 1 {
+			return configErrorf("generate: --count must not be negative and --max-depth and --max-array must be positive")
+		}
+		data, err := os.ReadFile(o.mappingPath)
+		if err != nil {
+			return configErrorf("reading mapping: %w", err)
+		}
+		mapping, err := typelessMapping(data)
+		if err != nil {
+			return err
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal(mapping, &m); err != nil {
+			return schemaErrorf("parsing mapping %s: %w", o.mappingPath, err)
+		}
+		props, _ := m["properties"].(map[string]interface{})
+		seed := o.seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		g := newGenerator(seed, o.maxDepth, o.maxArray)
+
+		var w io.Writer = os.Stdout
+		if o.out != "-" {
+			f, err := os.Create(o.out)
+			if err != nil {
+				return configErrorf("creating %s: %w", o.out, err)
+			}
+			defer f.Close()
+			w = f
+		}
+		if err := g.writeBulk(ctx, w, props, o.count); err != nil {
+			return err
+		}
+		if o.out != "-" {
+			fmt.Fprintf(os.Stderr, "wrote %d documents to %s (seed %d)\n", o.count, o.out, seed)
+		}
+		return nil
+	}
+}
+
+// generator는 매핑에 맞는 무작위 문서를 만듭니다. 같은 시드는 같은 문서를 만듭니다.
+type generator struct {
+	rng *rand.Rand
+	// maxDepth는 만들 객체의 가장 깊은 단계로, 문서 자체가 1입니다. 더 깊은 object와
+	// nested 필드는 문서에서 뺍니다.
+	maxDepth int
+	// maxArray는 배열 하나의 최대 원소 수입니다.
+	maxArray int
+}
+
+func newGenerator(seed int64, maxDepth, maxArray int) *generator {
+	return &generator{rng: rand.New(rand.NewSource(seed)), maxDepth: maxDepth, maxArray: maxArray}
+}
+
+// writeBulk 함수는 문서 count개를 _bulk API의 index 작업으로 w에 씁니다.
+func (g *generator) writeBulk(ctx context.Context, w io.Writer, properties map[string]interface{}, count int) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for i := 0; i < count; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, err := bw.WriteString(`{"index":{}}` + "\n"); err != nil {
+			return err
+		}
+		if err := enc.Encode(g.document(properties, 1)); err != nil {
+			return dataErrorf("encoding document %d: %w", i, err)
+		}
+	}
+	return bw.Flush()
+}
+
+func (g *generator) documents(properties map[string]interface{}, count int) []map[string]interface{} {
+	data := make([]map[string]interface{}, count)
+	for i := range data {
+		data[i] = g.document(properties, 1)
+	}
+	return data
+}
+
+// document 함수는 depth 단계의 객체 하나를 만듭니다. nested 필드는 0개 이상의 객체 배열,
+// object 필드는 20% 확률로 객체 배열이 되어 리스트 안의 구조체가 여러 단계로 생깁니다.
+// 원시 필드도 20% 확률로 배열이 됩니다.
+func (g *generator) document(properties map[string]interface{}, depth int) map[string]interface{} {
+	doc := make(map[string]interface{})
+	for _, fieldName := range sortedParams(properties) {
+		props, _ := properties[fieldName].(map[string]interface{})
+		fieldType, _ := props["type"].(string)
+		sub, isObject := props["properties"].(map[string]interface{})
+		if fieldType == "nested" || fieldType == "object" {
+			isObject = true
+		}
+		if !isObject {
+			if arrayable(fieldType) && g.rng.Float32() < 0.2 {
+				items := make([]interface{}, 1+g.rng.Intn(g.maxArray))
+				for i := range items {
+					items[i] = g.value(fieldType, props)
+				}
+				doc[fieldName] = items
+			} else {
+				doc[fieldName] = g.value(fieldType, props)
+			}
+			continue
+		}
+		if depth >= g.maxDepth {
+			continue
+		}
+		n := 1
+		switch {
+		case fieldType == "nested":
+			n = g.rng.Intn(g.maxArray + 1)
+		case g.rng.Float32() < 0.2:
+			n = 1 + g.rng.Intn(g.maxArray)
+		default:
+			doc[fieldName] = g.document(sub, depth+1)
+			continue
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			items[i] = g.document(sub, depth+1)
+		}
+		doc[fieldName] = items
+	}
+	return doc
+}
+
+// generateEpoch는 만든 date 값의 기준 시각으로, 시드가 같으면 실행 시각과 관계없이 같은
+// 값을 만들도록 고정합니다.
+var generateEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// integerRanges는 정수 타입마다 Elasticsearch가 받는 범위입니다.
+var integerRanges = map[string][2]int64{
+	"byte":        {math.MinInt8, math.MaxInt8},
+	"short":       {math.MinInt16, math.MaxInt16},
+	"integer":     {math.MinInt32, math.MaxInt32},
+	"token_count": {0, math.MaxInt32},
+}
+
+// maxHalfFloat는 half_float가 담을 수 있는 가장 큰 유한 값입니다.
+const maxHalfFloat = 65504
+
+// value 함수는 원시 타입 필드의 값 하나를 매핑의 제약에 맞춰 만듭니다. 인덱싱할 수 없는
+// 값을 만들지 않도록, 만들 수 없는 타입이나 설정이면 nil입니다.
+func (g *generator) value(fieldType string, props map[string]interface{}) interface{} {
+	if r, ok := integerRanges[fieldType]; ok {
+		return r[0] + g.rng.Int63n(r[1]-r[0]+1)
+	}
+	switch fieldType {
+	case "text", "match_only_text", "search_as_you_type", "wildcard":
+		return fmt.Sprintf("dummy_%d", g.rng.Intn(1000))
+	case "keyword":
+		s := fmt.Sprintf("dummy_%d", g.rng.Intn(1000))
+		if limit, ok := props["ignore_above"].(float64); ok && len(s) > int(limit) {
+			s = s[:int(math.Max(limit, 0))]
+		}
+		return s
+	case "constant_keyword":
+		// 값이 정해지지 않은 constant_keyword는 처음 색인한 값으로 정해지므로 모든 문서가
+		// 같은 값을 써야 합니다.
+		if v, ok := props["value"].(string); ok {
+			return v
+		}
+		return "constant"
+	case "long":
+		return int64(g.rng.Uint64())
+	case "unsigned_long":
+		return g.rng.Uint64()
+	case "float":
+		return float32(g.rng.NormFloat64() * 1000)
+	case "half_float":
+		return math.Round((g.rng.Float64()*2 - 1) * maxHalfFloat)
+	case "double":
+		return g.rng.NormFloat64() * 1e6
+	case "scaled_float":
+		// scaling_factor보다 정밀한 자리는 색인할 때 버려지므로 미리 맞춥니다.
+		factor, _ := props["scaling_factor"].(float64)
+		if factor <= 0 {
+			return nil
+		}
+		return math.Round(g.rng.Float64()*1000*factor) / factor
+	case "boolean":
+		return g.rng.Intn(2) == 1
+	case "date", "date_nanos":
+		return g.date(fieldType, props)
+	case "dense_vector":
+		return g.vector(props)
+	case "ip":
+		return fmt.Sprintf("10.%d.%d.%d", g.rng.Intn(256), g.rng.Intn(256), 1+g.rng.Intn(254))
+	case "version":
+		return fmt.Sprintf("%d.%d.%d", g.rng.Intn(10), g.rng.Intn(20), g.rng.Intn(100))
+	case "geo_point":
+		return map[string]interface{}{"lat": g.rng.Float64()*180 - 90, "lon": g.rng.Float64()*360 - 180}
+	}
+	return nil
+}
+
+// arrayable 함수는 fieldType의 값을 배열로 만들어도 되는지 알려 줍니다. dense_vector는
+// 문서마다 벡터 하나만 받고, constant_keyword 배열은 값 하나와 같지 않습니다.
+func arrayable(fieldType string) bool {
+	return fieldType != "dense_vector" && fieldType != "constant_keyword"
+}
+
+// vector 함수는 dims 길이의 dense_vector 값을 만듭니다. element_type이 byte이면 -128~127
+// 정수를, similarity가 dot_product인 float 벡터는 길이 1로 맞춘 값을 만듭니다. dims가
+// 없으면 첫 문서가 차원을 정하게 되므로 값을 만들지 않습니다.
+func (g *generator) vector(props map[string]interface{}) interface{} {
+	dims, ok := props["dims"].(float64)
+	if !ok || dims < 1 {
+		return nil
+	}
+	if props["element_type"] == "byte" {
+		v := make([]int, int(dims))
+		for i := range v {
+			v[i] = g.rng.Intn(256) - 128
+		}
+		return v
+	}
+	v := make([]float32, int(dims))
+	norm := 0.0
+	for i := range v {
+		x := g.rng.Float64()*2 - 1
+		v[i] = float32(x)
+		norm += x * x
+	}
+	if props["similarity"] == "dot_product" && norm > 0 {
+		norm = math.Sqrt(norm)
+		for i := range v {
+			v[i] = float32(float64(v[i]) / norm)
+		}
+	}
+	return v
+}
+
+// date 함수는 매핑의 format 중 첫 번째 형식으로 날짜 값을 만듭니다. 만들 수 없는 형식이면
+// 다음 형식을 봅니다.
+func (g *generator) date(fieldType string, props map[string]interface{}) interface{} {
+	t := generateEpoch.Add(time.Duration(g.rng.Int63n(int64(5 * 365 * 24 * time.Hour))))
+	format, _ := props["format"].(string)
+	if format == "" {
+		format = "strict_date_optional_time||epoch_millis"
+		if fieldType == "date_nanos" {
+			format = "strict_date_optional_time_nanos||epoch_millis"
+		}
+	}
+	for _, f := range strings.Split(format, "||") {
+		switch f = strings.TrimSpace(f); f {
+		case "epoch_millis":
+			return t.UnixMilli()
+		case "epoch_second":
+			return t.Unix()
+		}
+		if layout, ok := dateLayout(f); ok {
+			return t.Format(layout)
+		}
+	}
+	return nil
+}
+
+// namedDateLayouts는 Elasticsearch 내장 날짜 형식의 Go 레이아웃입니다. strict_ 접두사가
+// 붙은 형식도 같은 레이아웃을 씁니다.
+var namedDateLayouts = map[string]string{
+	"date_optional_time":        "2006-01-02T15:04:05.000Z07:00",
+	"date_optional_time_nanos":  "2006-01-02T15:04:05.000000000Z07:00",
+	"date_time":                 "2006-01-02T15:04:05.000Z07:00",
+	"date_time_no_millis":       "2006-01-02T15:04:05Z07:00",
+	"date":                      "2006-01-02",
+	"year_month_day":            "2006-01-02",
+	"year_month":                "2006-01",
+	"year":                      "2006",
+	"basic_date":                "20060102",
+	"basic_date_time":           "20060102T150405.000Z0700",
+	"basic_date_time_no_millis": "20060102T150405Z0700",
+	"date_hour_minute_second":   "2006-01-02T15:04:05",
+	"date_hour_minute":          "2006-01-02T15:04",
+	"date_hour":                 "2006-01-02T15",
+}
+
+// javaDateTokens는 Java DateTimeFormatter 패턴 문자를 Go 레이아웃으로 바꾸는 표입니다.
+// 긴 토큰을 먼저 봅니다.
+var javaDateTokens = []struct{ java, layout string }{
+	{"yyyy", "2006"}, {"uuuu", "2006"}, {"yy", "06"}, {"uu", "06"},
+	{"MMMM", "January"}, {"MMM", "Jan"}, {"MM", "01"}, {"M", "1"},
+	{"dd", "02"}, {"d", "2"}, {"EEEE", "Monday"}, {"EEE", "Mon"},
+	{"HH", "15"}, {"hh", "03"}, {"h", "3"}, {"mm", "04"}, {"ss", "05"},
+	{"SSSSSSSSS", "000000000"}, {"SSSSSS", "000000"}, {"SSS", "000"},
+	{"a", "PM"}, {"XXX", "Z07:00"}, {"XX", "Z0700"}, {"X", "Z07"}, {"ZZZ", "-0700"}, {"Z", "-0700"},
+}
+
+// dateLayout 함수는 Elasticsearch 날짜 형식 하나를 Go 레이아웃으로 바꿉니다. 내장 형식과
+// 흔한 Java 패턴을 읽고, 표에 없는 패턴 문자가 있으면 false를 반환합니다.
+func dateLayout(format string) (string, bool) {
+	if layout, ok := namedDateLayouts[strings.TrimPrefix(format, "strict_")]; ok {
+		return layout, true
+	}
+	var sb strings.Builder
+	for i := 0; i < len(format); {
+		c := format[i]
+		switch {
+		case c == '\'':
+			end := strings.IndexByte(format[i+1:], '\'')
+			if end < 0 {
+				return "", false
+			}
+			sb.WriteString(format[i+1 : i+1+end])
+			i += end + 2
+		case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			matched := false
+			for _, tok := range javaDateTokens {
+				if strings.HasPrefix(format[i:], tok.java) {
+					// Go는 소수 초를 점 뒤에서만 읽습니다.
+					if tok.java[0] == 'S' && (i == 0 || format[i-1] != '.') {
+						return "", false
+					}
+					sb.WriteString(tok.layout)
+					i += len(tok.java)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return "", false
+			}
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+	return sb.String(), sb.Len() > 0
+}
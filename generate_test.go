@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+// deepMapping은 nested 안에 object와 nested가 네 단계로 들어 있는 매핑입니다.
+const deepMapping = `{"properties": {
+	"title": {"type": "keyword"},
+	"users": {"type": "nested", "properties": {
+		"name": {"type": "keyword"},
+		"address": {"properties": {
+			"city": {"type": "keyword"},
+			"phones": {"type": "nested", "properties": {
+				"number": {"type": "keyword"},
+				"ext": {"properties": {"code": {"type": "integer"}}}
+			}}
+		}}
+	}}
+}}`
+
+func generatorProperties(t *testing.T) map[string]interface{} {
+	t.Helper()
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(deepMapping), &m); err != nil {
+		t.Fatal(err)
+	}
+	return m["properties"].(map[string]interface{})
+}
+
+// objectDepth 함수는 v 안의 가장 깊은 객체 단계를 반환하고, 객체 배열의 길이를 lengths에 모읍니다.
+func objectDepth(v interface{}, lengths map[int]bool) int {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		deepest := 1
+		for _, child := range x {
+			if d := objectDepth(child, lengths); d+1 > deepest {
+				deepest = d + 1
+			}
+		}
+		return deepest
+	case []interface{}:
+		deepest := 0
+		objects := false
+		for _, item := range x {
+			if _, ok := item.(map[string]interface{}); ok {
+				objects = true
+			}
+			if d := objectDepth(item, lengths); d > deepest {
+				deepest = d
+			}
+		}
+		if objects {
+			lengths[len(x)] = true
+		}
+		return deepest
+	}
+	return 0
+}
+
+func TestGeneratorObjectArrays(t *testing.T) {
+	props := generatorProperties(t)
+	for _, maxDepth := range []int{1, 2, 4} {
+		g := newGenerator(1, maxDepth, 3)
+		lengths := make(map[int]bool)
+		deepest := 0
+		for _, doc := range g.documents(props, 300) {
+			if d := objectDepth(doc, lengths); d > deepest {
+				deepest = d
+			}
+		}
+		if deepest != maxDepth {
+			t.Errorf("max depth %d: deepest object level = %d", maxDepth, deepest)
+		}
+		for n := range lengths {
+			if n > 3 {
+				t.Errorf("max depth %d: object array of %d items, more than --max-array 3", maxDepth, n)
+			}
+		}
+		if maxDepth > 1 && !(lengths[1] && lengths[2] && lengths[3]) {
+			t.Errorf("max depth %d: object array lengths %v, want every length from 1 to 3", maxDepth, lengths)
+		}
+	}
+}
+
+func TestGeneratorSeed(t *testing.T) {
+	props := generatorProperties(t)
+	var a, b bytes.Buffer
+	if err := newGenerator(42, 4, 3).writeBulk(context.Background(), &a, props, 20); err != nil {
+		t.Fatal(err)
+	}
+	if err := newGenerator(42, 4, 3).writeBulk(context.Background(), &b, props, 20); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Error("the same seed wrote different documents")
+	}
+
+	scanner := bufio.NewScanner(&a)
+	lines := 0
+	for scanner.Scan() {
+		var v map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &v); err != nil {
+			t.Fatalf("line %d: %v", lines+1, err)
+		}
+		if lines%2 == 0 && !reflect.DeepEqual(v, map[string]interface{}{"index": map[string]interface{}{}}) {
+			t.Errorf("line %d = %v, want an index action", lines+1, v)
+		}
+		lines++
+	}
+	if lines != 40 {
+		t.Errorf("bulk file has %d lines, want 40", lines)
+	}
+}
+
+func TestGeneratedListOfStructs(t *testing.T) {
+	schema, err := schemaFromMapping([]byte(deepMapping))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 생성한 문서는 bulk 파일처럼 JSON을 거쳐 _source와 같은 값이 됩니다.
+	var docs []map[string]interface{}
+	for _, doc := range newGenerator(7, 4, 3).documents(generatorProperties(t), 100) {
+		data, err := json.Marshal(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := decodeDocument(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		docs = append(docs, decoded)
+	}
+	n := newNormalizer(schema)
+	n.widen(docs)
+	rec, rejected := n.record(docs)
+	defer rec.Release()
+	if rec.NumRows() != 100 || len(rejected) != 0 || n.dropped != 0 {
+		t.Errorf("rows = %d, rejected = %d, dropped = %d; want 100, 0, 0", rec.NumRows(), len(rejected), n.dropped)
+	}
+	users, _ := n.schema.FieldsByName("users")
+	lt, ok := users[0].Type.(*arrow.ListType)
+	if !ok || lt.Elem().ID() != arrow.STRUCT {
+		t.Fatalf("users = %s, want a list of structs", users[0].Type)
+	}
+	// address는 object이지만 생성기가 배열로도 만들므로 구조체 리스트로 넓어집니다.
+	address, _ := lt.Elem().(*arrow.StructType).FieldByName("address")
+	at, ok := address.Type.(*arrow.ListType)
+	if !ok || at.Elem().ID() != arrow.STRUCT {
+		t.Fatalf("users.address = %s, want a list of structs", address.Type)
+	}
+	phones, _ := at.Elem().(*arrow.StructType).FieldByName("phones")
+	if pt, ok := phones.Type.(*arrow.ListType); !ok || pt.Elem().ID() != arrow.STRUCT {
+		t.Errorf("users.address.phones = %s, want a list of structs", phones.Type)
+	}
+}
+
+func TestGeneratorConstraints(t *testing.T) {
+	var props map[string]interface{}
+	if err := json.Unmarshal([]byte(`{
+		"vec": {"type": "dense_vector", "dims": 8, "similarity": "dot_product"},
+		"bytes": {"type": "dense_vector", "dims": 4, "element_type": "byte"},
+		"code": {"type": "keyword", "ignore_above": 4},
+		"day": {"type": "date", "format": "yyyy/MM/dd||epoch_millis"},
+		"ts": {"type": "date", "format": "epoch_second"},
+		"small": {"type": "byte"},
+		"half": {"type": "half_float"},
+		"price": {"type": "scaled_float", "scaling_factor": 100}
+	}`), &props); err != nil {
+		t.Fatal(err)
+	}
+	g := newGenerator(3, 4, 3)
+	for i := 0; i < 200; i++ {
+		doc := g.document(props, 1)
+		vec, ok := doc["vec"].([]float32)
+		if !ok || len(vec) != 8 {
+			t.Fatalf("vec = %v, want 8 float dimensions", doc["vec"])
+		}
+		norm := 0.0
+		for _, x := range vec {
+			norm += float64(x) * float64(x)
+		}
+		if math.Abs(norm-1) > 1e-4 {
+			t.Errorf("dot_product vector has squared length %v, want 1", norm)
+		}
+		if b, ok := doc["bytes"].([]int); !ok || len(b) != 4 {
+			t.Errorf("bytes = %v, want 4 byte dimensions", doc["bytes"])
+		}
+		for _, v := range flatValues(doc["code"]) {
+			if len(v.(string)) > 4 {
+				t.Errorf("keyword %q is longer than ignore_above 4", v)
+			}
+		}
+		for _, v := range flatValues(doc["day"]) {
+			if _, err := time.Parse("2006/01/02", v.(string)); err != nil {
+				t.Errorf("date %v does not match yyyy/MM/dd: %v", v, err)
+			}
+		}
+		for _, v := range flatValues(doc["ts"]) {
+			if _, ok := v.(int64); !ok {
+				t.Errorf("epoch_second date = %v (%T), want int64", v, v)
+			}
+		}
+		for _, v := range flatValues(doc["small"]) {
+			if n := v.(int64); n < math.MinInt8 || n > math.MaxInt8 {
+				t.Errorf("byte value %d is out of range", n)
+			}
+		}
+		for _, v := range flatValues(doc["half"]) {
+			if math.Abs(v.(float64)) > maxHalfFloat {
+				t.Errorf("half_float value %v is out of range", v)
+			}
+		}
+		for _, v := range flatValues(doc["price"]) {
+			if p := v.(float64) * 100; math.Abs(p-math.Round(p)) > 1e-6 {
+				t.Errorf("scaled_float value %v is finer than scaling_factor 100", v)
+			}
+		}
+	}
+}
+
+// flatValues 함수는 값 하나나 배열을 값 목록으로 펼칩니다.
+func flatValues(v interface{}) []interface{} {
+	if items, ok := v.([]interface{}); ok {
+		return items
+	}
+	return []interface{}{v}
+}
+
+func TestDateLayout(t *testing.T) {
+	tests := []struct {
+		format, layout string
+		ok             bool
+	}{
+		{"strict_date_optional_time", "2006-01-02T15:04:05.000Z07:00", true},
+		{"basic_date", "20060102", true},
+		{"yyyy-MM-dd HH:mm:ss", "2006-01-02 15:04:05", true},
+		{"yyyy-MM-dd'T'HH:mm:ss.SSSXXX", "2006-01-02T15:04:05.000Z07:00", true},
+		{"dd.MM.yyyy", "02.01.2006", true},
+		{"yyyy-DDD", "", false},
+		{"'unterminated", "", false},
+	}
+	for _, tt := range tests {
+		layout, ok := dateLayout(tt.format)
+		if layout != tt.layout && tt.ok || ok != tt.ok {
+			t.Errorf("dateLayout(%q) = %q, %v; want %q, %v", tt.format, layout, ok, tt.layout, tt.ok)
+		}
+	}
+}
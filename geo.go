@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/apache/arrow/go/v10/arrow"
+
+	"es-schema/esschema"
+)
+
+const (
+	// geoMetadataKey는 GeoParquet가 정한 파일 메타데이터 키입니다. GDAL과 GeoPandas는 이
+	// 키가 있는 Parquet 파일을 지오메트리가 있는 파일로 읽습니다.
+	geoMetadataKey = "geo"
+	// geoParquetVersion은 geoMetadataKey에 쓰는 GeoParquet 명세 버전입니다.
+	geoParquetVersion = "1.0.0"
+	// geoTypeKey는 --geo-wkb로 WKB 컬럼이 된 필드에 원래 Elasticsearch 타입을 적는 필드
+	// 메타데이터 키입니다.
+	geoTypeKey = esschema.GeoTypeKey
+	// geoBoundsKey는 내보낸 geo 필드의 경계 상자를 필드 이름별 [xmin, ymin, xmax, ymax]
+	// JSON으로 남기는 파일 메타데이터 키입니다. GeoParquet를 모르는 엔진도 읽을 수 있습니다.
+	geoBoundsKey = "es_schema.geo_bounds"
+	// derivedFromKey는 --geo-lat-lon 컬럼에 원래 필드 이름을 남기는 필드 메타데이터 키입니다.
+	derivedFromKey = "es_schema.derived_from"
+	latSuffix      = "_lat"
+	lonSuffix      = "_lon"
+)
+
+// geoFieldTypes 함수는 fields 중 매핑에서 geo_point나 geo_shape인 top-level 필드의
+// Elasticsearch 타입을 반환합니다. GeoParquet의 지오메트리 컬럼은 top-level이어야 하므로
+// 객체 안의 geo 필드는 고르지 않습니다.
+func geoFieldTypes(fields []arrow.Field, mapping []byte) (map[string]string, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal(mapping, &m); err != nil {
+		return nil, schemaErrorf("decoding mapping: %w", err)
+	}
+	props, _ := m["properties"].(map[string]interface{})
+	types := make(map[string]string)
+	for _, f := range fields {
+		field, _ := props[f.Name].(map[string]interface{})
+		if t, _ := field["type"].(string); t == "geo_point" || t == "geo_shape" {
+			types[f.Name] = t
+		}
+	}
+	return types, nil
+}
+
+// geoColumns 함수는 types의 필드를 WKB 바이너리 컬럼으로 바꾼 필드 목록을 반환합니다.
+func geoColumns(fields []arrow.Field, types map[string]string) []arrow.Field {
+	out := append([]arrow.Field(nil), fields...)
+	for i, f := range out {
+		if t, ok := types[f.Name]; ok {
+			out[i] = arrow.Field{Name: f.Name, Type: arrow.BinaryTypes.Binary, Nullable: true, Metadata: withMetadataValue(f.Metadata, geoTypeKey, t)}
+		}
+	}
+	return out
+}
+
+// latLonColumns 함수는 geo_point 필드마다 <필드>_lat과 <필드>_lon float64 컬럼을 만듭니다.
+// 이미 같은 이름의 필드가 있으면 오류입니다.
+func latLonColumns(fields []arrow.Field, types map[string]string) ([]arrow.Field, error) {
+	existing := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		existing[f.Name] = true
+	}
+	var out []arrow.Field
+	for _, f := range fields {
+		if types[f.Name] != "geo_point" {
+			continue
+		}
+		for _, suffix := range []string{latSuffix, lonSuffix} {
+			name := f.Name + suffix
+			if existing[name] {
+				return nil, schemaErrorf("field %s already exists, so --geo-lat-lon cannot add it for %s", name, f.Name)
+			}
+			out = append(out, arrow.Field{
+				Name:     name,
+				Type:     arrow.PrimitiveTypes.Float64,
+				Nullable: true,
+				Metadata: arrow.NewMetadata([]string{derivedFromKey}, []string{f.Name}),
+			})
+		}
+	}
+	return out, nil
+}
+
+// isDerivedColumn 함수는 field가 --geo-lat-lon처럼 다른 필드에서 만든 컬럼인지 알려
+// 줍니다. 가져오기는 이 컬럼을 문서에 넣지 않습니다.
+func isDerivedColumn(f arrow.Field) bool {
+	return f.Metadata.FindKey(derivedFromKey) >= 0
+}
+
+// latLonTransform 함수는 geo_point 값의 위도와 경도를 <필드>_lat과 <필드>_lon에 씁니다.
+// 여러 값이 있으면 첫 번째 점을 쓰고, 읽을 수 없는 값이면 두 컬럼 모두 null입니다.
+// geoTransform보다 먼저 적용해야 원래 값을 읽습니다.
+func latLonTransform(types map[string]string) docTransform {
+	var names []string
+	for name, t := range types {
+		if t == "geo_point" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return func(doc map[string]interface{}) (map[string]interface{}, error) {
+		for _, name := range names {
+			v, ok := doc[name]
+			if !ok || v == nil {
+				continue
+			}
+			g, ok := esschema.ParseGeoPoints(v)
+			if ok && g.Kind == esschema.WKBMultiPoint {
+				g = g.Parts[0]
+			}
+			if ok && len(g.Coords) > 0 {
+				doc[name+lonSuffix], doc[name+latSuffix] = g.Coords[0][0], g.Coords[0][1]
+			}
+		}
+		return doc, nil
+	}
+}
+
+// geoTransform 함수는 types의 필드 값을 WKB로 바꾸는 변환을 만듭니다. 여러 값이 있는
+// geo_point는 MultiPoint, 여러 도형이 있는 geo_shape는 GeometryCollection이 됩니다.
+// 읽을 수 없는 값은 그대로 두어, 정규화에서 타입이 맞지 않는 값으로 null이 됩니다.
+func geoTransform(types map[string]string) docTransform {
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return func(doc map[string]interface{}) (map[string]interface{}, error) {
+		for _, name := range names {
+			v, ok := doc[name]
+			if !ok || v == nil {
+				continue
+			}
+			if items, ok := v.([]interface{}); ok && len(items) == 0 {
+				doc[name] = nil
+				continue
+			}
+			parse := esschema.ParseGeoShape
+			if types[name] == "geo_point" {
+				parse = esschema.ParseGeoPoints
+			}
+			if g, ok := parse(v); ok {
+				doc[name] = g.AppendWKB(nil)
+			}
+		}
+		return doc, nil
+	}
+}
+
+// geoFileMetadata는 GeoParquet 1.0의 "geo" 파일 메타데이터입니다.
+type geoFileMetadata struct {
+	Version       string                       `json:"version"`
+	PrimaryColumn string                       `json:"primary_column"`
+	Columns       map[string]geoColumnMetadata `json:"columns"`
+}
+
+// geoColumnMetadata는 지오메트리 컬럼 하나의 GeoParquet 메타데이터입니다. crs를 쓰지
+// 않으면 OGC:CRS84(경도, 위도 순서의 WGS 84)이고, Elasticsearch의 좌표계가 이것입니다.
+type geoColumnMetadata struct {
+	Encoding      string    `json:"encoding"`
+	GeometryTypes []string  `json:"geometry_types"`
+	Bbox          []float64 `json:"bbox,omitempty"`
+}
+
+// geoMetadata 함수는 schema에서 --geo-wkb로 만든 top-level 컬럼을 찾아 GeoParquet
+// 메타데이터 JSON을 만듭니다. 처음 나오는 지오메트리 컬럼이 primary 컬럼이고, bounds는
+// 원래 필드 이름별 [xmin, ymin, xmax, ymax]입니다. 지오메트리 컬럼이 없으면 false입니다.
+func geoMetadata(schema *arrow.Schema, bounds map[string][]float64) (string, bool) {
+	md := geoFileMetadata{Version: geoParquetVersion, Columns: make(map[string]geoColumnMetadata)}
+	for _, f := range schema.Fields() {
+		idx := f.Metadata.FindKey(geoTypeKey)
+		if idx < 0 {
+			continue
+		}
+		col := geoColumnMetadata{Encoding: "WKB", GeometryTypes: []string{}, Bbox: bounds[originalName(f)]}
+		if f.Metadata.Values()[idx] == "geo_point" {
+			col.GeometryTypes = []string{"Point", "MultiPoint"}
+		}
+		if md.PrimaryColumn == "" {
+			md.PrimaryColumn = f.Name
+		}
+		md.Columns[f.Name] = col
+	}
+	if md.PrimaryColumn == "" {
+		return "", false
+	}
+	data, _ := json.Marshal(md)
+	return string(data), true
+}
+
+// geoShapeValue 함수는 geo_shape WKB 컬럼(GeoTypeKey) f의 값 b를 GeoJSON 객체로
+// 되돌립니다. GeoParquet 메타데이터는 top-level 컬럼만 다루므로 구조체 안의 geo_shape
+// 필드는 필드 메타데이터로 찾습니다. 다른 컬럼이나 읽을 수 없는 WKB는 그대로 둡니다.
+func geoShapeValue(f arrow.Field, b []byte) interface{} {
+	if idx := f.Metadata.FindKey(geoTypeKey); idx < 0 || f.Metadata.Values()[idx] != "geo_shape" {
+		return b
+	}
+	g, err := esschema.DecodeWKB(b)
+	if err != nil {
+		return b
+	}
+	return g.GeoJSON()
+}
+
+// geoValues 함수는 스키마의 GeoParquet 메타데이터에서 지오메트리 컬럼마다 WKB 값을
+// 문서 값으로 되돌리는 함수를 만듭니다. Point와 MultiPoint만 있는 컬럼은 geo_point가
+// 받는 [lon, lat] 배열로, 나머지는 GeoJSON 객체로 되돌립니다.
+func geoValues(schema *arrow.Schema) map[string]func([]byte) (interface{}, bool) {
+	md := schema.Metadata()
+	idx := md.FindKey(geoMetadataKey)
+	if idx < 0 {
+		return nil
+	}
+	var geo geoFileMetadata
+	if err := json.Unmarshal([]byte(md.Values()[idx]), &geo); err != nil {
+		return nil
+	}
+	out := make(map[string]func([]byte) (interface{}, bool), len(geo.Columns))
+	for name, col := range geo.Columns {
+		if col.Encoding != "WKB" {
+			continue
+		}
+		points := len(col.GeometryTypes) > 0
+		for _, t := range col.GeometryTypes {
+			points = points && (t == "Point" || t == "MultiPoint")
+		}
+		out[name] = func(b []byte) (interface{}, bool) {
+			g, err := esschema.DecodeWKB(b)
+			if err != nil {
+				return nil, false
+			}
+			if points && (g.Kind == esschema.WKBPoint || g.Kind == esschema.WKBMultiPoint) {
+				return g.Coordinates(), true
+			}
+			return g.GeoJSON(), true
+		}
+	}
+	return out
+}
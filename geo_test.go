@@ -0,0 +1,129 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/apache/arrow/go/v10/arrow"
+
+	"es-schema/esschema"
+)
+
+func TestGeoTransform(t *testing.T) {
+	transform := geoTransform(map[string]string{"location": "geo_point", "area": "geo_shape"})
+	doc := geoValue(t, `{"location": "41.12,-71.34", "area": "not wkt", "other": "10,20", "tags": []}`).(map[string]interface{})
+	doc["empty"] = []interface{}{}
+	out, err := transform(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := out["location"].([]byte); !ok {
+		t.Errorf("location = %#v, want WKB", out["location"])
+	}
+	if out["area"] != "not wkt" || out["other"] != "10,20" {
+		t.Errorf("unparsed and unrelated values changed: %v", out)
+	}
+}
+
+func TestGeoMetadata(t *testing.T) {
+	fields := []arrow.Field{
+		{Name: "name", Type: arrow.BinaryTypes.String},
+		{Name: "location", Type: arrow.BinaryTypes.String},
+		{Name: "area", Type: arrow.BinaryTypes.String},
+	}
+	types, err := geoFieldTypes(fields, []byte(`{"properties": {"name": {"type": "keyword"}, "location": {"type": "geo_point"}, "area": {"type": "geo_shape"}, "other": {"type": "geo_point"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"location": "geo_point", "area": "geo_shape"}; !reflect.DeepEqual(types, want) {
+		t.Errorf("types = %v, want %v", types, want)
+	}
+	fields = geoColumns(fields, types)
+	if fields[1].Type.ID() != arrow.BINARY || fields[0].Type.ID() != arrow.STRING {
+		t.Errorf("fields = %v", fields)
+	}
+	md, ok := geoMetadata(arrow.NewSchema(fields, nil), map[string][]float64{"location": {-72, 40, -70, 42}})
+	if !ok {
+		t.Fatal("no geo metadata")
+	}
+	want := `{"version":"1.0.0","primary_column":"location","columns":{"area":{"encoding":"WKB","geometry_types":[]},"location":{"encoding":"WKB","geometry_types":["Point","MultiPoint"],"bbox":[-72,40,-70,42]}}}`
+	if md != want {
+		t.Errorf("geo metadata = %s, want %s", md, want)
+	}
+
+	meta := arrow.NewMetadata([]string{geoMetadataKey}, []string{md})
+	values := geoValues(arrow.NewSchema(fields, &meta))
+	point, _ := esschema.ParseGeoPoints("41.12,-71.34")
+	if v, ok := values["location"](point.AppendWKB(nil)); !ok || !reflect.DeepEqual(v, []float64{-71.34, 41.12}) {
+		t.Errorf("location value = %v, %v", v, ok)
+	}
+	shape, _ := esschema.ParseGeoShape("LINESTRING (0 0, 1 1)")
+	if v, ok := values["area"](shape.AppendWKB(nil)); !ok || v.(map[string]interface{})["type"] != "LineString" {
+		t.Errorf("area value = %v, %v", v, ok)
+	}
+}
+
+func TestLatLonTransform(t *testing.T) {
+	transform := latLonTransform(map[string]string{"location": "geo_point", "area": "geo_shape"})
+	for _, tc := range []struct {
+		src      string
+		lat, lon interface{}
+	}{
+		{`{"location": {"lat": 41.12, "lon": -71.34}}`, 41.12, -71.34},
+		{`{"location": [[10, 20], "30,40"]}`, 20.0, 10.0},
+		{`{"location": "not a point!"}`, nil, nil},
+		{`{"area": "POINT (1 2)"}`, nil, nil},
+	} {
+		doc := geoValue(t, tc.src).(map[string]interface{})
+		out, err := transform(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out["location_lat"] != tc.lat || out["location_lon"] != tc.lon {
+			t.Errorf("%s: lat/lon = %v/%v, want %v/%v", tc.src, out["location_lat"], out["location_lon"], tc.lat, tc.lon)
+		}
+		if _, ok := out["area_lat"]; ok {
+			t.Errorf("%s: geo_shape got a lat column", tc.src)
+		}
+	}
+}
+
+func TestLatLonColumns(t *testing.T) {
+	fields := []arrow.Field{{Name: "location", Type: arrow.BinaryTypes.String}, {Name: "area", Type: arrow.BinaryTypes.String}}
+	types := map[string]string{"location": "geo_point", "area": "geo_shape"}
+	derived, err := latLonColumns(fields, types)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(derived) != 2 || derived[0].Name != "location_lat" || derived[1].Name != "location_lon" || !isDerivedColumn(derived[0]) {
+		t.Errorf("derived = %v", derived)
+	}
+	fields = append(fields, arrow.Field{Name: "location_lon", Type: arrow.PrimitiveTypes.Float64})
+	if _, err := latLonColumns(fields, types); err == nil {
+		t.Error("latLonColumns accepted an existing location_lon field")
+	}
+}
+
+func geoValue(t *testing.T, src string) interface{} {
+	t.Helper()
+	v, err := decodeJSONValue([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestGeoShapeValue(t *testing.T) {
+	shape := arrow.Field{Name: "boundary", Type: arrow.BinaryTypes.Binary, Metadata: arrow.NewMetadata([]string{geoTypeKey}, []string{"geo_shape"})}
+	line, _ := esschema.ParseGeoShape("LINESTRING (0 0, 1 1)")
+	if v, ok := geoShapeValue(shape, line.AppendWKB(nil)).(map[string]interface{}); !ok || v["type"] != "LineString" {
+		t.Errorf("geo_shape value = %v", v)
+	}
+	raw := []byte("not wkb")
+	if v := geoShapeValue(shape, raw); !reflect.DeepEqual(v, raw) {
+		t.Errorf("unreadable WKB = %v", v)
+	}
+	if v := geoShapeValue(arrow.Field{Name: "thumbnail", Type: arrow.BinaryTypes.Binary}, line.AppendWKB(nil)); !reflect.DeepEqual(v, line.AppendWKB(nil)) {
+		t.Errorf("binary field value = %v", v)
+	}
+}
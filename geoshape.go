@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/apache/arrow/go/v10/arrow"
+
+	"es-schema/esschema"
+)
+
+// geoShapeFormatOptions는 geo_shape 필드의 컬럼 형식을 고르는 --geo-shape 플래그입니다.
+// wkb는 GeoJSON 객체와 WKT 문자열을 WKB 바이너리로 바꾸고 top-level 컬럼에 GeoParquet
+// 메타데이터를 남기며, json은 값 그대로의 JSON 텍스트로 씁니다.
+type geoShapeFormatOptions struct {
+	format string
+}
+
+func (o *geoShapeFormatOptions) bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.format, "geo-shape", "wkb", "column type of geo_shape fields: wkb (binary WKB geometry; top-level columns get GeoParquet 1.0 metadata so GDAL and GeoPandas read them as geometry) or json (the GeoJSON object or WKT string as JSON text, which also keeps circles WKB cannot represent)")
+}
+
+func (o *geoShapeFormatOptions) validate() error {
+	switch o.format {
+	case "", "wkb", "json":
+		return nil
+	}
+	return configErrorf("unknown --geo-shape %q (want wkb or json)", o.format)
+}
+
+// apply 함수는 json이면 fields의 geo_shape 필드를 문자열 컬럼으로 바꿉니다.
+func (o *geoShapeFormatOptions) apply(fields []arrow.Field) []arrow.Field {
+	if o.format != "json" {
+		return fields
+	}
+	return esschema.GeoShapesAsJSON(fields)
+}
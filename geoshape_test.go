@@ -0,0 +1,14 @@
+package main
+
+import "testing"
+
+func TestGeoShapeFormatValidate(t *testing.T) {
+	for _, format := range []string{"wkb", "json"} {
+		if err := (&geoShapeFormatOptions{format: format}).validate(); err != nil {
+			t.Errorf("--geo-shape %s: %v", format, err)
+		}
+	}
+	if err := (&geoShapeFormatOptions{format: "wkt"}).validate(); exitCodeFor(err) != exitConfigError {
+		t.Errorf("--geo-shape wkt = %v, want a config error", err)
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+// gxOptions는 emit --builtin gx의 설정입니다.
+type gxOptions struct {
+	suiteName string
+	report    string
+	// nullTolerance는 관측한 null이 아닌 값의 비율에서 mostly를 낮추는 폭입니다.
+	nullTolerance float64
+}
+
+func (o *gxOptions) bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.suiteName, "gx-suite", "", "--builtin gx: expectation suite name (default: the --mapping file name without its extension, or --index, followed by .baseline)")
+	fs.StringVar(&o.report, "gx-report", "", "--builtin gx: JSON run summary (--status-file) of the convert or export run that wrote the dataset; adds the row count and, from the footers of its Parquet files, null thresholds and value ranges")
+	fs.Float64Var(&o.nullTolerance, "gx-null-tolerance", 0.01, "--builtin gx: fraction by which the expected share of non-null values may fall below the share seen in --gx-report")
+}
+
+func (o *gxOptions) validate() error {
+	if o.nullTolerance < 0 || o.nullTolerance > 1 {
+		return configErrorf("--gx-null-tolerance must be between 0 and 1, got %v", o.nullTolerance)
+	}
+	return nil
+}
+
+// gxSuite는 Great Expectations의 expectation suite JSON입니다.
+type gxSuite struct {
+	Name         string                 `json:"expectation_suite_name"`
+	Meta         map[string]interface{} `json:"meta"`
+	Expectations []gxExpectation        `json:"expectations"`
+}
+
+type gxExpectation struct {
+	Type   string                 `json:"expectation_type"`
+	Kwargs map[string]interface{} `json:"kwargs"`
+	Meta   map[string]interface{} `json:"meta,omitempty"`
+}
+
+// suite 함수는 model의 최상위 컬럼으로 기본 expectation suite를 만듭니다. 매핑에서는 컬럼
+// 목록과 컬럼마다의 존재와 타입을, --gx-report가 있으면 행 수와 Parquet footer 통계의 null
+// 비율과 숫자 범위를 기대값으로 둡니다. 타입 이름은 Parquet 파일을 읽은 pandas의 dtype이며,
+// 구조체와 리스트처럼 pandas에서 object가 되는 컬럼은 존재만 확인합니다.
+func (o *gxOptions) suite(model *emitModel, mappingPath string) ([]byte, error) {
+	s := gxSuite{
+		Name: o.suiteName,
+		Meta: map[string]interface{}{"es_schema_source": model.Source},
+	}
+	if s.Name == "" {
+		s.Name = datasetName(model, mappingPath) + ".baseline"
+	}
+	var profile *datasetProfile
+	if o.report != "" {
+		report, err := readRunReport(o.report)
+		if err != nil {
+			return nil, err
+		}
+		if profile, err = reportProfile(report); err != nil {
+			return nil, err
+		}
+		s.Meta["es_schema_report"] = map[string]interface{}{
+			"command":          report.Command,
+			"finished_at":      report.FinishedAt,
+			"rows_exported":    report.RowsExported,
+			"documents_failed": report.DocumentsFailed,
+		}
+	}
+
+	s.addBaseline(model, profile, o.nullTolerance)
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// addBaseline 함수는 model의 컬럼과 profile(없으면 nil)의 통계로 expectation을 추가합니다.
+func (s *gxSuite) addBaseline(model *emitModel, profile *datasetProfile, nullTolerance float64) {
+	columns := make([]string, len(model.Fields))
+	for i, f := range model.Fields {
+		columns[i] = f.Name
+	}
+	s.add("expect_table_columns_to_match_ordered_list", map[string]interface{}{"column_list": columns})
+	if profile != nil {
+		s.add("expect_table_row_count_to_equal", map[string]interface{}{"value": profile.rows})
+	}
+	for _, f := range model.Fields {
+		s.add("expect_column_to_exist", map[string]interface{}{"column": f.Name})
+		if types := gxTypeList(f.DataType); types != nil {
+			s.add("expect_column_values_to_be_in_type_list", map[string]interface{}{"column": f.Name, "type_list": types})
+		}
+		if profile == nil || f.Fields != nil || f.Repeated {
+			continue
+		}
+		c, ok := profile.columns[f.Name]
+		if !ok || !c.hasStats {
+			continue
+		}
+		if profile.rows > 0 {
+			mostly := float64(profile.rows-c.nulls)/float64(profile.rows) - nullTolerance
+			mostly = math.Max(0, math.Floor(mostly*1e4)/1e4)
+			if mostly > 0 {
+				s.add("expect_column_values_to_not_be_null", map[string]interface{}{"column": f.Name, "mostly": mostly})
+			}
+		}
+		if min, max, ok := gxRange(f.DataType, c); ok {
+			s.add("expect_column_values_to_be_between", map[string]interface{}{"column": f.Name, "min_value": min, "max_value": max})
+		}
+	}
+}
+
+func (s *gxSuite) add(typ string, kwargs map[string]interface{}) {
+	s.Expectations = append(s.Expectations, gxExpectation{Type: typ, Kwargs: kwargs})
+}
+
+// gxTypeList 함수는 dt 컬럼을 pandas로 읽었을 때의 dtype 이름을 반환합니다. null이 있는
+// 정수 컬럼은 float64가, null이 있는 boolean 컬럼은 object가 되므로 함께 넣습니다.
+func gxTypeList(dt arrow.DataType) []string {
+	switch t := dt.(type) {
+	case *arrow.Int32Type:
+		return []string{"int32", "Int32", "float64"}
+	case *arrow.Int64Type:
+		return []string{"int64", "Int64", "float64"}
+	case *arrow.Uint64Type:
+		return []string{"uint64", "UInt64", "float64"}
+	case *arrow.Float16Type, *arrow.Float32Type:
+		// half_float는 Parquet에 float32로 씁니다(parquetFileSchema).
+		return []string{"float32"}
+	case *arrow.Float64Type:
+		return []string{"float64"}
+	case *arrow.BooleanType:
+		return []string{"bool", "boolean", "object"}
+	case *arrow.StringType:
+		return []string{"object", "string"}
+	case *arrow.BinaryType:
+		return []string{"object"}
+	case *arrow.TimestampType:
+		if t.TimeZone != "" {
+			return []string{"datetime64[ns, " + t.TimeZone + "]"}
+		}
+		return []string{"datetime64[ns]"}
+	case *arrow.DictionaryType:
+		return []string{"category", "object"}
+	}
+	return nil
+}
+
+// gxRange 함수는 숫자 컬럼 c의 footer 최솟값과 최댓값을 반환합니다. decodeStat은 물리 값을
+// 그대로 읽으므로 unsigned_long은 부호 없는 값으로 되돌립니다.
+func gxRange(dt arrow.DataType, c *columnSummary) (min, max interface{}, ok bool) {
+	if c.min == nil || c.max == nil {
+		return nil, nil, false
+	}
+	switch dt.ID() {
+	case arrow.INT32, arrow.INT64, arrow.FLOAT16, arrow.FLOAT32, arrow.FLOAT64:
+		return c.min, c.max, true
+	case arrow.UINT64:
+		lo, ok1 := c.min.(int64)
+		hi, ok2 := c.max.(int64)
+		return uint64(lo), uint64(hi), ok1 && ok2
+	}
+	return nil, nil, false
+}
+
+// readRunReport 함수는 --status-file로 쓴 실행 요약을 읽습니다. 실패한 실행의 요약은
+// 데이터셋을 설명하지 않으므로 오류입니다.
+func readRunReport(path string) (*runReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, configErrorf("reading run report: %w", err)
+	}
+	var r runReport
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, configErrorf("parsing run report %s: %w", path, err)
+	}
+	if r.Status == statusFailed {
+		return nil, configErrorf("run report %s is from a failed %s run", path, r.Command)
+	}
+	return &r, nil
+}
+
+// reportProfile 함수는 r의 Parquet 출력 파일의 footer 통계를 모읍니다.
+func reportProfile(r *runReport) (*datasetProfile, error) {
+	var paths []string
+	for _, f := range r.Files {
+		if strings.HasSuffix(f.Path, ".parquet") {
+			paths = append(paths, f.Path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, configErrorf("run report of %s lists no Parquet files", r.Command)
+	}
+	profile, err := profileFiles(paths)
+	if err != nil {
+		return nil, fmt.Errorf("profiling the files of the run report: %w", err)
+	}
+	return profile, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+func TestGXBaseline(t *testing.T) {
+	model := &emitModel{Source: "logs.json", Fields: []emitField{
+		{Name: "bytes", DataType: arrow.PrimitiveTypes.Uint64},
+		{Name: "status", DataType: arrow.PrimitiveTypes.Int32},
+		{Name: "user", DataType: arrow.StructOf(arrow.Field{Name: "id", Type: arrow.BinaryTypes.String}), Fields: []emitField{{Name: "id"}}},
+	}}
+	profile := &datasetProfile{rows: 200, columns: map[string]*columnSummary{
+		"bytes":   {hasStats: true, min: int64(0), max: int64(-1)},
+		"status":  {hasStats: true, nulls: 10, min: int64(200), max: int64(503)},
+		"user.id": {hasStats: true, nulls: 200},
+	}}
+	var s gxSuite
+	s.addBaseline(model, profile, 0.01)
+	data, _ := json.Marshal(s.Expectations)
+	want := `[` +
+		`{"expectation_type":"expect_table_columns_to_match_ordered_list","kwargs":{"column_list":["bytes","status","user"]}},` +
+		`{"expectation_type":"expect_table_row_count_to_equal","kwargs":{"value":200}},` +
+		`{"expectation_type":"expect_column_to_exist","kwargs":{"column":"bytes"}},` +
+		`{"expectation_type":"expect_column_values_to_be_in_type_list","kwargs":{"column":"bytes","type_list":["uint64","UInt64","float64"]}},` +
+		`{"expectation_type":"expect_column_values_to_not_be_null","kwargs":{"column":"bytes","mostly":0.99}},` +
+		`{"expectation_type":"expect_column_values_to_be_between","kwargs":{"column":"bytes","max_value":18446744073709551615,"min_value":0}},` +
+		`{"expectation_type":"expect_column_to_exist","kwargs":{"column":"status"}},` +
+		`{"expectation_type":"expect_column_values_to_be_in_type_list","kwargs":{"column":"status","type_list":["int32","Int32","float64"]}},` +
+		`{"expectation_type":"expect_column_values_to_not_be_null","kwargs":{"column":"status","mostly":0.94}},` +
+		`{"expectation_type":"expect_column_values_to_be_between","kwargs":{"column":"status","max_value":503,"min_value":200}},` +
+		`{"expectation_type":"expect_column_to_exist","kwargs":{"column":"user"}}` +
+		`]`
+	if string(data) != want {
+		t.Errorf("expectations =\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestReadRunReport(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "status.json")
+	os.WriteFile(path, []byte(`{"command": "export", "status": "failed", "files": []}`), 0o644)
+	if _, err := readRunReport(path); exitCodeFor(err) != exitConfigError {
+		t.Errorf("failed run: err = %v", err)
+	}
+
+	os.WriteFile(path, []byte(`{"command": "export", "status": "success", "files": [{"path": "out.ndjson", "rows": 3}]}`), 0o644)
+	r, err := readRunReport(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reportProfile(r); exitCodeFor(err) != exitConfigError {
+		t.Errorf("no Parquet files: err = %v", err)
+	}
+
+	for _, tolerance := range []float64{-0.1, 1.5} {
+		o := gxOptions{nullTolerance: tolerance}
+		if err := o.validate(); exitCodeFor(err) != exitConfigError {
+			t.Errorf("tolerance %v: err = %v", tolerance, err)
+		}
+	}
+}
+
+func TestDatasetName(t *testing.T) {
+	model := &emitModel{Source: "logs-*"}
+	got := []string{datasetName(model, ""), datasetName(model, "mappings/web.json")}
+	if want := []string{"logs-*", "web"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("datasetName = %v, want %v", got, want)
+	}
+}
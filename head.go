@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+const (
+	headFormatJSON   = "json"
+	headFormatNDJSON = "ndjson"
+)
+
+// errHeadDone는 head가 필요한 문서를 모두 읽은 뒤 읽기를 멈추는 데 쓰는 표시입니다.
+var errHeadDone = errors.New("enough documents")
+
+func setupHead(fs *flag.FlagSet) func(ctx context.Context, report *runReport, args []string) error {
+	n := fs.Int("n", 10, "number of documents to print from each file")
+	format := fs.String("format", headFormatJSON, "output format: json (indented) or ndjson (one document per line)")
+	var columns stringListFlag
+	fs.Var(&columns, "columns", "only read these columns; dotted paths select nested fields (comma-separated or repeated)")
+
+	return func(ctx context.Context, report *runReport, args []string) error {
+		// 파일 뒤의 플래그(head file.parquet -n 20)도 읽습니다.
+		args, err := parseInterspersed(fs, args)
+		if err != nil {
+			return configErrorf("head: %w", err)
+		}
+		if len(args) == 0 {
+			return configErrorf("head: no Parquet files given")
+		}
+		if *n <= 0 {
+			return configErrorf("head: -n must be positive")
+		}
+		switch *format {
+		case headFormatJSON, headFormatNDJSON:
+		default:
+			return configErrorf("head: unknown --format %q (want json or ndjson)", *format)
+		}
+		for _, path := range args {
+			docs, err := headDocuments(ctx, path, columns, *n)
+			if err != nil {
+				return err
+			}
+			report.RowsRead += int64(len(docs))
+			if len(args) > 1 && *format == headFormatJSON {
+				fmt.Printf("==> %s <==\n", path)
+			}
+			if err := writeHits(os.Stdout, docs, *format); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// headDocuments 함수는 path의 처음 n개 행을 문서로 되돌립니다. n개를 얻으면 나머지 레코드는
+// 읽지 않습니다.
+func headDocuments(ctx context.Context, path string, columns []string, n int) ([]map[string]interface{}, error) {
+	pf, err := openParquetFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer pf.Close()
+	var docs []map[string]interface{}
+	_, err = pf.records(ctx, columns, func(rec arrow.Record) error {
+		for _, doc := range recordDocuments(rec) {
+			docs = append(docs, asHit(doc))
+			if len(docs) == n {
+				return errHeadDone
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errHeadDone) {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// asHit 함수는 되돌린 문서를 검색 결과의 hit 모양으로 바꿉니다. _id 같은 메타데이터
+// 컬럼은 최상위에, 나머지 필드는 _source에 둡니다. tombstone 행은 _source가 없습니다.
+func asHit(doc map[string]interface{}) map[string]interface{} {
+	hit := make(map[string]interface{})
+	for c, v := range doc {
+		if isMetadataColumn(c) || c == deletedColumn || c == docHashColumn {
+			hit[c] = v
+			delete(doc, c)
+		}
+	}
+	if deleted, _ := hit[deletedColumn].(bool); !deleted {
+		hit["_source"] = doc
+	}
+	return hit
+}
+
+func writeHits(w io.Writer, hits []map[string]interface{}, format string) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if format == headFormatJSON {
+		enc.SetIndent("", "  ")
+	}
+	for _, hit := range hits {
+		if err := enc.Encode(hit); err != nil {
+			return dataErrorf("encoding document: %w", err)
+		}
+	}
+	return nil
+}
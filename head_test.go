@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"testing"
+)
+
+func TestWriteHitsShapesDocumentsLikeSearchHits(t *testing.T) {
+	hits := []map[string]interface{}{
+		asHit(map[string]interface{}{"_id": "1", "_seq_no": int64(4), "user": map[string]interface{}{"name": "<kim>"}}),
+		asHit(map[string]interface{}{"_id": "2", deletedColumn: true}),
+	}
+	var buf bytes.Buffer
+	if err := writeHits(&buf, hits, headFormatNDJSON); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"_id":"1","_seq_no":4,"_source":{"user":{"name":"<kim>"}}}` + "\n" +
+		`{"_deleted":true,"_id":"2"}` + "\n"
+	if buf.String() != want {
+		t.Errorf("ndjson =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestHeadFlagsAfterFiles(t *testing.T) {
+	path := convertForTest(t, `{"properties": {"n": {"type": "long"}}}`, `{"n": 1}`, `{"n": 2}`, `{"n": 3}`)
+
+	// 요청의 문서대로 파일 뒤에 플래그를 씁니다.
+	fs := flag.NewFlagSet("head", flag.ContinueOnError)
+	run := setupHead(fs)
+	if err := fs.Parse([]string{path, "-n", "2", "--format", "ndjson"}); err != nil {
+		t.Fatal(err)
+	}
+	report := newRunReport("head")
+	if err := run(context.Background(), report, fs.Args()); err != nil {
+		t.Fatal(err)
+	}
+	if report.RowsRead != 2 {
+		t.Errorf("rows read = %d, want 2", report.RowsRead)
+	}
+}
+
+func TestParseInterspersed(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	n := fs.Int("n", 10, "")
+	args, err := parseInterspersed(fs, []string{"a", "-n", "3", "b", "--", "-c", "-n"})
+	if err != nil || *n != 3 || len(args) != 4 || args[0] != "a" || args[1] != "b" || args[2] != "-c" || args[3] != "-n" {
+		t.Errorf("parseInterspersed = %q, n = %d, %v", args, *n, err)
+	}
+	if _, err := parseInterspersed(fs, []string{"a", "-x"}); err == nil {
+		t.Error("unknown flag after a file was accepted")
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+)
+
+const (
+	notifyAlways  = "always"
+	notifyFailure = "failure"
+	notifyNever   = "never"
+)
+
+// hookTimeout은 웹훅 요청이나 exec 훅 하나에 허용하는 최대 시간입니다.
+const hookTimeout = 30 * time.Second
+
+// notificationOptions는 실행 종료 알림 설정입니다.
+type notificationOptions struct {
+	webhookURLs stringListFlag
+	execHook    string
+	notifyOn    string
+	minRows     int64
+}
+
+func (n *notificationOptions) bind(fs *flag.FlagSet) {
+	fs.Var(&n.webhookURLs, "webhook-url", "POST the run report as JSON to this URL when the run ends (repeatable)")
+	fs.StringVar(&n.execHook, "exec-hook", "", "run this shell command with the run report as JSON on stdin when the run ends")
+	fs.StringVar(&n.notifyOn, "notify-on", notifyFailure, "when to send notifications: always, failure or never")
+	fs.Int64Var(&n.minRows, "min-rows", 0, "treat a successful run that exported fewer rows than this as suspicious and notify")
+}
+
+func (n *notificationOptions) validate() error {
+	switch n.notifyOn {
+	case notifyAlways, notifyFailure, notifyNever:
+		return nil
+	}
+	return configErrorf("unknown --notify-on %q (want always, failure or never)", n.notifyOn)
+}
+
+// notification은 웹훅으로 전송되는 본문입니다. text 필드는 Slack 호환
+// incoming webhook에서 바로 메시지로 표시됩니다.
+type notification struct {
+	Text   string     `json:"text"`
+	Report *runReport `json:"report"`
+}
+
+// checkSuspicious 함수는 성공했지만 --min-rows보다 적은 행을 내보낸 실행에 경고를 남기고
+// 알림이 필요한지 여부를 반환합니다. 이력과 다른 결과(--anomaly-history)도 알림 대상입니다.
+func (n *notificationOptions) checkSuspicious(report *runReport) bool {
+	if len(report.Anomalies) > 0 {
+		return true
+	}
+	if n.minRows <= 0 || report.Status != statusSuccess || report.RowsExported >= n.minRows {
+		return false
+	}
+	report.warnf("exported %d rows, fewer than --min-rows %d", report.RowsExported, n.minRows)
+	return true
+}
+
+// notify 함수는 설정에 따라 웹훅과 exec 훅을 실행합니다. 알림 실패는 실행 결과를
+// 바꾸지 않고 stderr에만 기록합니다.
+func (n *notificationOptions) notify(report *runReport, suspicious bool) {
+	if len(n.webhookURLs) == 0 && n.execHook == "" {
+		return
+	}
+	switch n.notifyOn {
+	case notifyNever:
+		return
+	case notifyFailure:
+		if report.Status == statusSuccess && !suspicious {
+			return
+		}
+	}
+
+	payload, err := json.Marshal(notification{Text: summarizeReport(report), Report: report})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "es-schema: encoding notification: %v\n", err)
+		return
+	}
+	for _, url := range n.webhookURLs {
+		if err := postWebhook(url, payload); err != nil {
+			fmt.Fprintf(os.Stderr, "es-schema: webhook %s: %v\n", url, err)
+		}
+	}
+	if n.execHook != "" {
+		if err := runExecHook(n.execHook, report, payload); err != nil {
+			fmt.Fprintf(os.Stderr, "es-schema: exec hook: %v\n", err)
+		}
+	}
+}
+
+// summarizeReport 함수는 사람이 읽을 한 줄 요약을 만듭니다.
+func summarizeReport(report *runReport) string {
+	summary := fmt.Sprintf("es-schema %s %s: %d rows, %d files in %.1fs",
+		report.Command, report.Status, report.RowsExported, len(report.Files), report.DurationSeconds)
+	if report.Error != nil {
+		summary += fmt.Sprintf(" (%s error: %s)", report.Error.Kind, report.Error.Error)
+	}
+	if len(report.Warnings) > 0 {
+		summary += fmt.Sprintf(", %d warnings", len(report.Warnings))
+	}
+	return summary
+}
+
+func postWebhook(url string, payload []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// runExecHook 함수는 훅 명령에 보고서 JSON을 stdin으로 전달하고, 주요 값은
+// ES_SCHEMA_* 환경 변수로도 제공합니다.
+func runExecHook(command string, report *runReport, payload []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"ES_SCHEMA_COMMAND="+report.Command,
+		"ES_SCHEMA_STATUS="+report.Status,
+		fmt.Sprintf("ES_SCHEMA_ROWS=%d", report.RowsExported),
+	)
+	return cmd.Run()
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhookNotifiesOnFailureOnly(t *testing.T) {
+	var received []notification
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n notification
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			t.Errorf("decoding webhook body: %v", err)
+		}
+		received = append(received, n)
+	}))
+	defer srv.Close()
+
+	opts := notificationOptions{webhookURLs: stringListFlag{srv.URL}, notifyOn: notifyFailure}
+
+	ok := newRunReport("demo")
+	ok.finish(nil)
+	opts.notify(ok, opts.checkSuspicious(ok))
+	if len(received) != 0 {
+		t.Fatalf("successful run sent %d notifications, want 0", len(received))
+	}
+
+	failed := newRunReport("demo")
+	failed.finish(connectionErrorf("connection refused"))
+	opts.notify(failed, opts.checkSuspicious(failed))
+	if len(received) != 1 {
+		t.Fatalf("failed run sent %d notifications, want 1", len(received))
+	}
+	if got := received[0].Report.Error; got == nil || got.Kind != kindConnection {
+		t.Errorf("notification error = %+v, want kind %q", got, kindConnection)
+	}
+}
+
+func TestMinRowsMarksSmallExportSuspicious(t *testing.T) {
+	opts := notificationOptions{minRows: 100}
+
+	report := newRunReport("demo")
+	report.RowsExported = 3
+	report.finish(nil)
+	if !opts.checkSuspicious(report) {
+		t.Fatal("expected a 3-row export to be suspicious with --min-rows 100")
+	}
+	if len(report.Warnings) != 1 {
+		t.Errorf("warnings = %v, want one entry", report.Warnings)
+	}
+
+	failed := newRunReport("demo")
+	failed.finish(errors.New("boom"))
+	if opts.checkSuspicious(failed) {
+		t.Error("failed runs are reported as failures, not as suspicious")
+	}
+}
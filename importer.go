@@ -0,0 +1,509 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+const (
+	actionIndex  = "index"
+	actionCreate = "create"
+	actionUpdate = "update"
+	actionUpsert = "upsert"
+)
+
+// esMetadataColumns는 Elasticsearch 메타데이터 필드라서 _source에 넣으면 색인이
+// 거부되는 컬럼입니다. 가져오기 시 문서 본문에서 항상 제거합니다.
+var esMetadataColumns = []string{"_id", "_index", "_routing", "_seq_no", "_primary_term", "_version"}
+
+// isMetadataColumn 함수는 name이 esMetadataColumns 중 하나인지 알려 줍니다.
+func isMetadataColumn(name string) bool {
+	for _, c := range esMetadataColumns {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// maxReportedFailures는 보고서 경고에 남기는 실패 문서 사유의 최대 개수입니다.
+const maxReportedFailures = 10
+
+type importOptions struct {
+	es        esOptions
+	index     string
+	columns   stringListFlag
+	idColumn  string
+	idHash    stringListFlag
+	action    string
+	bulkDocs  int
+	bulkBytes int
+
+	createIndex  bool
+	settingsPath string
+	pipeline     string
+
+	deadLetterPath  string
+	retryDeadLetter string
+}
+
+func setupImport(fs *flag.FlagSet) func(ctx context.Context, report *runReport, args []string) error {
+	var o importOptions
+	o.es.bind(fs, "es-", "destination")
+	fs.StringVar(&o.index, "index", "", "destination index (required)")
+	fs.Var(&o.columns, "columns", "only import these columns; dotted paths select nested fields (comma-separated or repeated)")
+	fs.StringVar(&o.idColumn, "id-column", "", "use this column's value as the document _id")
+	fs.Var(&o.idHash, "id-hash", "use a SHA-256 hash of these columns as the document _id (comma-separated or repeated)")
+	fs.StringVar(&o.action, "action", actionIndex, "bulk action: index, create, update or upsert")
+	fs.IntVar(&o.bulkDocs, "bulk-docs", 1000, "maximum number of documents per _bulk request")
+	fs.IntVar(&o.bulkBytes, "bulk-bytes", 5<<20, "send a _bulk request once its body reaches this many bytes")
+	fs.BoolVar(&o.createIndex, "create-index", false, "create the destination index from the mapping embedded in the first file if it does not exist")
+	fs.StringVar(&o.settingsPath, "settings", "", "index settings JSON file to use with --create-index")
+	fs.StringVar(&o.pipeline, "pipeline", "", "ingest pipeline to run every document through")
+	fs.StringVar(&o.deadLetterPath, "dead-letter", "", "write rejected documents with their error to this NDJSON file")
+	fs.StringVar(&o.retryDeadLetter, "retry-dead-letters", "", "re-send the documents of a previous --dead-letter file instead of reading Parquet files")
+
+	return func(ctx context.Context, report *runReport, args []string) error {
+		if err := o.validate(args); err != nil {
+			return err
+		}
+		client, err := o.es.client()
+		if err != nil {
+			return err
+		}
+		if o.createIndex {
+			if len(args) == 0 {
+				return configErrorf("import: --create-index needs a Parquet file with an embedded mapping")
+			}
+			if err := o.ensureIndex(ctx, client, args[0], report); err != nil {
+				return err
+			}
+		}
+		indexer := newBulkIndexer(client, o.index, o.action, o.bulkDocs, o.bulkBytes)
+		if o.pipeline != "" {
+			indexer.query.Set("pipeline", o.pipeline)
+		}
+		if o.deadLetterPath != "" {
+			if indexer.deadLetters, err = createDeadLetterFile(o.deadLetterPath); err != nil {
+				return err
+			}
+		}
+		if err := o.importAll(ctx, args, indexer, report); err != nil {
+			indexer.closeDeadLetters(report)
+			return err
+		}
+		if err := indexer.closeDeadLetters(report); err != nil {
+			return err
+		}
+		return indexer.finish(report)
+	}
+}
+
+func (o *importOptions) importAll(ctx context.Context, args []string, indexer *bulkIndexer, report *runReport) error {
+	if o.retryDeadLetter != "" {
+		err := readDeadLetters(o.retryDeadLetter, func(dl deadLetter) error {
+			report.RowsRead++
+			if dl.Op == "delete" {
+				return indexer.delete(ctx, dl.ID)
+			}
+			doc, err := decodeDocument(dl.Document)
+			if err != nil {
+				return dataErrorf("dead letter %q: %w", dl.ID, err)
+			}
+			return indexer.add(ctx, dl.ID, doc)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, path := range args {
+		if err := o.importFile(ctx, path, indexer, report); err != nil {
+			return err
+		}
+	}
+	return indexer.flush(ctx)
+}
+
+func (o *importOptions) validate(args []string) error {
+	if len(args) == 0 && o.retryDeadLetter == "" {
+		return configErrorf("import: no Parquet files given")
+	}
+	if o.retryDeadLetter != "" && o.retryDeadLetter == o.deadLetterPath {
+		return configErrorf("import: --dead-letter must differ from --retry-dead-letters")
+	}
+	if o.index == "" {
+		return configErrorf("import: --index is required")
+	}
+	switch o.action {
+	case actionIndex, actionCreate:
+	case actionUpdate, actionUpsert:
+		if o.idColumn == "" && len(o.idHash) == 0 {
+			return configErrorf("import: --action %s needs --id-column or --id-hash", o.action)
+		}
+	default:
+		return configErrorf("import: unknown --action %q (want index, create, update or upsert)", o.action)
+	}
+	if o.idColumn != "" && len(o.idHash) > 0 {
+		return configErrorf("import: --id-column and --id-hash are mutually exclusive")
+	}
+	if o.bulkDocs <= 0 || o.bulkBytes <= 0 {
+		return configErrorf("import: --bulk-docs and --bulk-bytes must be positive")
+	}
+	if o.settingsPath != "" && !o.createIndex {
+		return configErrorf("import: --settings is only used together with --create-index")
+	}
+	return nil
+}
+
+// ensureIndex 함수는 대상 인덱스가 없으면 path 파일에 저장된 원본 매핑(과 --settings)으로
+// 만듭니다. 이미 있으면 기존 매핑을 그대로 두고 경고만 남깁니다.
+func (o *importOptions) ensureIndex(ctx context.Context, client *esClient, path string, report *runReport) error {
+	exists, err := client.indexExists(ctx, o.index)
+	if err != nil {
+		return err
+	}
+	if exists {
+		report.warnf("index %s already exists; --create-index left its mapping unchanged", o.index)
+		return nil
+	}
+
+	pf, err := openParquetFile(path)
+	if err != nil {
+		return err
+	}
+	mapping, ok := pf.embeddedMapping()
+	pf.Close()
+	if !ok {
+		return schemaErrorf("%s has no embedded %s metadata; cannot create index %s", path, mappingMetadataKey, o.index)
+	}
+
+	body := map[string]interface{}{"mappings": mapping}
+	if o.settingsPath != "" {
+		data, err := os.ReadFile(o.settingsPath)
+		if err != nil {
+			return configErrorf("reading --settings: %w", err)
+		}
+		if !json.Valid(data) {
+			return configErrorf("--settings %s is not valid JSON", o.settingsPath)
+		}
+		body["settings"] = json.RawMessage(data)
+	}
+	if err := client.createIndex(ctx, o.index, body); err != nil {
+		return err
+	}
+	fmt.Printf("created index %s from the mapping embedded in %s\n", o.index, path)
+	return nil
+}
+
+// readColumns 함수는 실제로 읽어야 할 컬럼 목록을 반환합니다. --columns가 주어지면
+// _id 계산에 필요한 컬럼을 추가하고, 추가한 컬럼은 문서 본문에서 빼도록 따로 돌려줍니다.
+func (o *importOptions) readColumns() (columns []string, extra []string) {
+	if len(o.columns) == 0 {
+		return nil, nil
+	}
+	columns = append(columns, o.columns...)
+	requested := make(map[string]bool, len(o.columns))
+	for _, c := range o.columns {
+		requested[c] = true
+	}
+	idColumns := append([]string(nil), o.idHash...)
+	if o.idColumn != "" {
+		idColumns = append(idColumns, o.idColumn)
+	}
+	for _, c := range idColumns {
+		if !requested[c] {
+			requested[c] = true
+			columns = append(columns, c)
+			extra = append(extra, c)
+		}
+	}
+	return columns, extra
+}
+
+func (o *importOptions) importFile(ctx context.Context, path string, indexer *bulkIndexer, report *runReport) error {
+	pf, err := openParquetFile(path)
+	if err != nil {
+		return err
+	}
+	defer pf.Close()
+
+	columns, extra := o.readColumns()
+	_, err = pf.records(ctx, columns, func(rec arrow.Record) error {
+		report.RowsRead += rec.NumRows()
+		for _, doc := range recordDocuments(rec) {
+			id, err := o.documentID(doc)
+			if err != nil {
+				return dataErrorf("%s: %w", path, err)
+			}
+			if deleted, _ := doc[deletedColumn].(bool); deleted {
+				// export --tombstones rows가 쓴 삭제 표시 행입니다.
+				if id == "" {
+					return dataErrorf("%s: tombstone row has no _id; import it with --id-column _id", path)
+				}
+				if err := indexer.delete(ctx, id); err != nil {
+					return err
+				}
+				continue
+			}
+			for _, c := range extra {
+				delete(doc, c)
+			}
+			for _, c := range esMetadataColumns {
+				delete(doc, c)
+			}
+			delete(doc, deletedColumn)
+			delete(doc, docHashColumn)
+			if err := indexer.add(ctx, id, doc); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+// documentID 함수는 설정에 따라 문서의 _id를 정합니다. 빈 문자열이면 Elasticsearch가
+// _id를 생성합니다.
+func (o *importOptions) documentID(doc map[string]interface{}) (string, error) {
+	switch {
+	case o.idColumn != "":
+		v, ok := doc[o.idColumn]
+		if !ok || v == nil {
+			return "", fmt.Errorf("row has no value in id column %q", o.idColumn)
+		}
+		return idString(v), nil
+	case len(o.idHash) > 0:
+		h := sha256.New()
+		for _, c := range o.idHash {
+			data, err := json.Marshal(doc[c])
+			if err != nil {
+				return "", fmt.Errorf("hashing column %q: %w", c, err)
+			}
+			h.Write(data)
+			h.Write([]byte{0})
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+	return "", nil
+}
+
+// idString 함수는 컬럼 값을 _id 문자열로 바꿉니다. 실수는 지수 표기 없이 씁니다.
+func idString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+// bulkIndexer는 문서를 모아 문서 수나 본문 크기가 한도에 이르면 _bulk 요청으로 보냅니다.
+type bulkIndexer struct {
+	client   *esClient
+	index    string
+	action   string
+	query    url.Values
+	maxDocs  int
+	maxBytes int
+
+	buf       bytes.Buffer
+	pending   int
+	succeeded int64
+	failed    int64
+	failures  []string
+	reasons   map[string]int64
+
+	// docType은 Elasticsearch 6 이하 클러스터에 보낼 때 필요한 매핑 타입입니다.
+	docType string
+
+	// deadLetters가 설정되면 전송 중인 문서의 원본을 docs에 보관했다가,
+	// 거부된 문서를 사유와 함께 기록합니다.
+	deadLetters *deadLetterWriter
+	docs        []json.RawMessage
+}
+
+func newBulkIndexer(client *esClient, index, action string, maxDocs, maxBytes int) *bulkIndexer {
+	return &bulkIndexer{
+		client:   client,
+		index:    index,
+		action:   action,
+		query:    url.Values{},
+		maxDocs:  maxDocs,
+		maxBytes: maxBytes,
+		reasons:  make(map[string]int64),
+	}
+}
+
+// add 함수는 문서 하나를 bulk 본문에 추가합니다. update는 부분 문서로, upsert는
+// doc_as_upsert로 보냅니다.
+func (b *bulkIndexer) add(ctx context.Context, id string, doc map[string]interface{}) error {
+	meta := map[string]interface{}{}
+	if id != "" {
+		meta["_id"] = id
+	}
+	if b.docType != "" {
+		meta["_type"] = b.docType
+	}
+	docJSON, err := json.Marshal(doc)
+	if err != nil {
+		return dataErrorf("encoding document %q: %w", id, err)
+	}
+	action := b.action
+	source := docJSON
+	switch b.action {
+	case actionUpdate:
+		source, err = json.Marshal(map[string]interface{}{"doc": json.RawMessage(docJSON)})
+	case actionUpsert:
+		action = actionUpdate
+		source, err = json.Marshal(map[string]interface{}{"doc": json.RawMessage(docJSON), "doc_as_upsert": true})
+	}
+	if err != nil {
+		return dataErrorf("encoding document %q: %w", id, err)
+	}
+	header, err := json.Marshal(map[string]interface{}{action: meta})
+	if err != nil {
+		return dataErrorf("encoding bulk action: %w", err)
+	}
+	return b.append(ctx, header, source, docJSON)
+}
+
+// delete 함수는 id의 문서를 지우는 요청을 bulk 본문에 추가합니다. 이미 없는 문서는
+// Elasticsearch가 not_found로 답하며 실패로 세지 않습니다.
+func (b *bulkIndexer) delete(ctx context.Context, id string) error {
+	meta := map[string]interface{}{"_id": id}
+	if b.docType != "" {
+		meta["_type"] = b.docType
+	}
+	header, err := json.Marshal(map[string]interface{}{"delete": meta})
+	if err != nil {
+		return dataErrorf("encoding bulk action: %w", err)
+	}
+	return b.append(ctx, header, nil, json.RawMessage("null"))
+}
+
+// append 함수는 동작 줄과 (delete가 아니면) 문서 줄을 본문에 더하고, 한도에 이르면
+// 전송합니다. doc은 dead-letter에 남길 원본입니다.
+func (b *bulkIndexer) append(ctx context.Context, header, source []byte, doc json.RawMessage) error {
+	if b.pending > 0 && b.buf.Len()+len(header)+len(source)+2 > b.maxBytes {
+		if err := b.flush(ctx); err != nil {
+			return err
+		}
+	}
+	b.buf.Write(header)
+	b.buf.WriteByte('\n')
+	if source != nil {
+		b.buf.Write(source)
+		b.buf.WriteByte('\n')
+	}
+	b.pending++
+	if b.deadLetters != nil {
+		b.docs = append(b.docs, doc)
+	}
+
+	if b.pending >= b.maxDocs || b.buf.Len() >= b.maxBytes {
+		return b.flush(ctx)
+	}
+	return nil
+}
+
+// flush 함수는 모아 둔 문서를 전송하고 문서별 결과를 집계합니다. 요청 자체가 실패하면
+// 오류를 반환하지만, 개별 문서의 실패는 집계만 하고 계속 진행합니다.
+func (b *bulkIndexer) flush(ctx context.Context) error {
+	if b.pending == 0 {
+		return nil
+	}
+	resp, err := b.client.bulk(ctx, b.index, b.buf.Bytes(), b.query)
+	if err != nil {
+		return err
+	}
+	for i, item := range resp.Items {
+		for op, res := range item {
+			if res.Error == nil {
+				b.succeeded++
+				continue
+			}
+			b.failed++
+			b.reasons[res.Error.category()]++
+			if len(b.failures) < maxReportedFailures {
+				b.failures = append(b.failures, fmt.Sprintf("document %q: %s", res.ID, res.Error))
+			}
+			if b.deadLetters != nil && i < len(b.docs) {
+				dl := deadLetter{
+					Index:    res.Index,
+					ID:       res.ID,
+					Status:   res.Status,
+					Category: res.Error.category(),
+					Reason:   res.Error.String(),
+					Error:    res.Error,
+					Document: b.docs[i],
+					Op:       op,
+				}
+				if err := b.deadLetters.write(dl); err != nil {
+					return configErrorf("writing dead-letter file: %w", err)
+				}
+			}
+		}
+	}
+	b.buf.Reset()
+	b.pending = 0
+	b.docs = b.docs[:0]
+	return nil
+}
+
+// closeDeadLetters 함수는 dead-letter 파일을 닫고 보고서에 기록합니다.
+func (b *bulkIndexer) closeDeadLetters(report *runReport) error {
+	if b.deadLetters == nil {
+		return nil
+	}
+	dl := b.deadLetters
+	b.deadLetters = nil
+	if err := dl.Close(); err != nil {
+		return configErrorf("closing dead-letter file: %w", err)
+	}
+	if dl.count > 0 {
+		report.DeadLetterFile = dl.path
+		fmt.Printf("wrote %d rejected documents to %s\n", dl.count, dl.path)
+	}
+	return nil
+}
+
+// finish 함수는 집계 결과를 보고서에 반영합니다. 일부 문서만 실패하면 부분 성공,
+// 모든 문서가 실패하면 데이터 오류를 반환합니다.
+func (b *bulkIndexer) finish(report *runReport) error {
+	report.RowsExported += b.succeeded
+	report.DocumentsFailed += b.failed
+	for reason, n := range b.reasons {
+		report.addFailures(reason, n)
+	}
+	for _, f := range b.failures {
+		report.warnf("%s", f)
+	}
+	fmt.Printf("imported %d documents into %s (%d failed)\n", b.succeeded, b.index, b.failed)
+	for _, reason := range sortedKeys(b.reasons) {
+		fmt.Printf("  %s: %d\n", reason, b.reasons[reason])
+	}
+
+	switch {
+	case b.failed == 0:
+		return nil
+	case b.succeeded == 0:
+		return dataErrorf("all %d documents were rejected by %s", b.failed, b.index)
+	default:
+		return withKind(kindPartial, fmt.Errorf("%d of %d documents were rejected by %s", b.failed, b.failed+b.succeeded, b.index))
+	}
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeBulkServer는 받은 bulk 요청의 줄을 기록하고, rejectID와 같은 _id의 문서를
+// mapper_parsing_exception으로 거부하는 테스트용 서버입니다.
+func fakeBulkServer(t *testing.T, rejectID string, requests *[][]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var lines []string
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		*requests = append(*requests, lines)
+
+		resp := bulkResponse{}
+		for i := 0; i < len(lines); i += 2 {
+			var header map[string]map[string]interface{}
+			if err := json.Unmarshal([]byte(lines[i]), &header); err != nil {
+				t.Errorf("bad action line %q: %v", lines[i], err)
+				return
+			}
+			for action, meta := range header {
+				id, _ := meta["_id"].(string)
+				res := bulkItemResult{ID: id, Status: 201}
+				if id == rejectID {
+					res.Status = 400
+					res.Error = &bulkItemError{Type: "mapper_parsing_exception", Reason: "failed to parse field [count]"}
+					resp.Errors = true
+				}
+				resp.Items = append(resp.Items, map[string]bulkItemResult{action: res})
+			}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestBulkIndexerFlushesByDocCountAndReportsFailures(t *testing.T) {
+	var requests [][]string
+	srv := fakeBulkServer(t, "2", &requests)
+	defer srv.Close()
+
+	indexer := newBulkIndexer(&esClient{baseURL: srv.URL, http: srv.Client()}, "logs", actionUpsert, 2, 1<<20)
+	ctx := context.Background()
+	for _, id := range []string{"1", "2", "3"} {
+		if err := indexer.add(ctx, id, map[string]interface{}{"count": id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := indexer.flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("sent %d bulk requests, want 2 (--bulk-docs 2)", len(requests))
+	}
+	if got := requests[0][0]; got != `{"update":{"_id":"1"}}` {
+		t.Errorf("upsert action line = %s", got)
+	}
+	if got := requests[0][1]; !strings.Contains(got, `"doc_as_upsert":true`) {
+		t.Errorf("upsert body = %s, want doc_as_upsert", got)
+	}
+
+	report := newRunReport("import")
+	err := indexer.finish(report)
+	if kindOf(err) != kindPartial {
+		t.Fatalf("finish error = %v, want partial success", err)
+	}
+	if report.RowsExported != 2 || report.DocumentsFailed != 1 {
+		t.Errorf("succeeded/failed = %d/%d, want 2/1", report.RowsExported, report.DocumentsFailed)
+	}
+}
+
+func TestDocumentIDFromHashIsStable(t *testing.T) {
+	o := importOptions{idHash: stringListFlag{"host", "port"}}
+	a, err := o.documentID(map[string]interface{}{"host": "db1", "port": int32(5432), "other": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := o.documentID(map[string]interface{}{"host": "db1", "port": int32(5432), "other": 2})
+	if a != b || len(a) != 64 {
+		t.Errorf("hash ids %q and %q should be equal 64-char hex strings", a, b)
+	}
+
+	o = importOptions{idColumn: "id"}
+	if id, _ := o.documentID(map[string]interface{}{"id": float64(1e6)}); id != "1000000" {
+		t.Errorf("float id = %q, want 1000000", id)
+	}
+}
+
+// runImportForTest 함수는 import 명령을 args로 실행하고 보고서를 반환합니다.
+func runImportForTest(t *testing.T, args ...string) (*runReport, error) {
+	t.Helper()
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	run := setupImport(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	report := newRunReport("import")
+	return report, run(context.Background(), report, fs.Args())
+}
+
+// bulkAccepted 함수는 요청의 모든 문서를 받아들인 bulk 응답을 씁니다.
+func bulkAccepted(w http.ResponseWriter, r *http.Request) {
+	resp := bulkResponse{}
+	scanner := bufio.NewScanner(r.Body)
+	for i := 0; scanner.Scan(); i++ {
+		if i%2 == 0 {
+			resp.Items = append(resp.Items, map[string]bulkItemResult{"index": {Status: 201}})
+		}
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func TestImportCreateIndex(t *testing.T) {
+	const mapping = `{"properties":{"id":{"type":"long"},"message":{"type":"keyword"}}}`
+	docs := []map[string]interface{}{
+		{"id": 1, "message": "GET /"},
+		{"id": 2, "message": "GET /about"},
+		{"id": 3, "message": "POST /login"},
+	}
+	input := writeMappingParquet(t, mapping, docs)
+	settings := filepath.Join(t.TempDir(), "settings.json")
+	if err := os.WriteFile(settings, []byte(`{"number_of_shards":1}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	exists := false
+	var created []map[string]json.RawMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodHead && r.URL.Path == "/logs":
+			if !exists {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case r.Method == http.MethodPut && r.URL.Path == "/logs":
+			var body map[string]json.RawMessage
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("create index body: %v", err)
+			}
+			created = append(created, body)
+			exists = true
+			w.Write([]byte(`{"acknowledged":true}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/logs/_bulk":
+			if !exists {
+				t.Error("bulk request sent before the index was created")
+			}
+			bulkAccepted(w, r)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	args := []string{"--es-url", srv.URL, "--index", "logs", "--create-index", "--settings", settings, input}
+	report, err := runImportForTest(t, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != 1 {
+		t.Fatalf("created the index %d times, want 1", len(created))
+	}
+	if got := string(created[0]["mappings"]); got != mapping {
+		t.Errorf("mappings = %s, want the embedded mapping %s", got, mapping)
+	}
+	if got := string(created[0]["settings"]); got != `{"number_of_shards":1}` {
+		t.Errorf("settings = %s", got)
+	}
+	if report.RowsExported != 3 {
+		t.Errorf("imported %d documents, want 3", report.RowsExported)
+	}
+
+	// 인덱스가 이미 있으면 매핑을 건드리지 않고 경고만 남깁니다.
+	report, err = runImportForTest(t, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != 1 {
+		t.Errorf("created an existing index again")
+	}
+	if !strings.Contains(strings.Join(report.Warnings, "\n"), "index logs already exists") {
+		t.Errorf("warnings = %q, want the existing index to be reported", report.Warnings)
+	}
+
+	// 매핑 메타데이터가 없는 파일로는 인덱스를 만들지 않습니다.
+	exists = false
+	schema, err := schemaFromMapping([]byte(mapping))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := writeSchemaParquet(t, schema, docs[:1])
+	_, err = runImportForTest(t, "--es-url", srv.URL, "--index", "logs", "--create-index", plain)
+	if exitCodeFor(err) != exitSchemaError {
+		t.Errorf("file without a mapping: error = %v, want a schema error", err)
+	}
+	if len(created) != 1 {
+		t.Errorf("created the index from a file without a mapping")
+	}
+}
+
+func TestImportPipelineFailures(t *testing.T) {
+	input := writeMappingParquet(t, `{"properties":{"id":{"type":"long"}}}`,
+		[]map[string]interface{}{{"id": 0}, {"id": 1}, {"id": 2}, {"id": 3}})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("pipeline"); got != "logs-ingest" {
+			t.Errorf("bulk request pipeline = %q, want logs-ingest", got)
+		}
+		resp := bulkResponse{}
+		scanner := bufio.NewScanner(r.Body)
+		for i := 0; scanner.Scan(); i++ {
+			if i%2 == 1 {
+				continue
+			}
+			var header map[string]map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+				t.Errorf("bad action line %q: %v", scanner.Text(), err)
+				return
+			}
+			id, _ := header["index"]["_id"].(string)
+			res := bulkItemResult{ID: id, Status: 201}
+			switch id {
+			case "0":
+				res.Status = 400
+				res.Error = &bulkItemError{
+					Type:   "illegal_argument_exception",
+					Reason: "Provided Grok expressions do not match field value",
+					Header: map[string]interface{}{"processor_type": "grok"},
+				}
+			case "1":
+				res.Status = 400
+				res.Error = &bulkItemError{Type: "mapper_parsing_exception", Reason: "failed to parse field [id]"}
+			}
+			resp.Errors = resp.Errors || res.Error != nil
+			resp.Items = append(resp.Items, map[string]bulkItemResult{"index": res})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	report, err := runImportForTest(t, "--es-url", srv.URL, "--index", "logs", "--pipeline", "logs-ingest", "--id-column", "id", input)
+	if kindOf(err) != kindPartial {
+		t.Fatalf("error = %v, want partial success", err)
+	}
+	if report.RowsExported != 2 || report.DocumentsFailed != 2 {
+		t.Errorf("succeeded/failed = %d/%d, want 2/2", report.RowsExported, report.DocumentsFailed)
+	}
+	want := map[string]int64{
+		"pipeline:grok:illegal_argument_exception": 1,
+		"indexing:mapper_parsing_exception":        1,
+	}
+	if !reflect.DeepEqual(report.FailureReasons, want) {
+		t.Errorf("failure reasons = %v, want %v", report.FailureReasons, want)
+	}
+	if warnings := strings.Join(report.Warnings, "\n"); !strings.Contains(warnings, `document "0": pipeline processor [grok] illegal_argument_exception`) {
+		t.Errorf("warnings = %q, want the grok processor named", report.Warnings)
+	}
+}
+
+func TestDeadLettersRecordRejectedDocuments(t *testing.T) {
+	var requests [][]string
+	srv := fakeBulkServer(t, "2", &requests)
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "rejected.ndjson")
+	indexer := newBulkIndexer(&esClient{baseURL: srv.URL, http: srv.Client()}, "logs", actionIndex, 10, 1<<20)
+	var err error
+	if indexer.deadLetters, err = createDeadLetterFile(path); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, id := range []string{"1", "2", "3"} {
+		if err := indexer.add(ctx, id, map[string]interface{}{"count": id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := indexer.flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	report := newRunReport("import")
+	if err := indexer.closeDeadLetters(report); err != nil {
+		t.Fatal(err)
+	}
+	if report.DeadLetterFile != path {
+		t.Errorf("report dead_letter_file = %q, want %q", report.DeadLetterFile, path)
+	}
+
+	var letters []deadLetter
+	if err := readDeadLetters(path, func(dl deadLetter) error {
+		letters = append(letters, dl)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(letters) != 1 || letters[0].ID != "2" {
+		t.Fatalf("dead letters = %+v, want only document 2", letters)
+	}
+	if got := string(letters[0].Document); got != `{"count":"2"}` {
+		t.Errorf("dead letter document = %s", got)
+	}
+	if letters[0].Category != "indexing:mapper_parsing_exception" {
+		t.Errorf("dead letter category = %q", letters[0].Category)
+	}
+}
+
+func TestRetryDeadLettersReplaysDeletes(t *testing.T) {
+	var requests [][]string
+	srv := fakeBulkServer(t, "2", &requests)
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "rejected.ndjson")
+	indexer := newBulkIndexer(&esClient{baseURL: srv.URL, http: srv.Client()}, "logs", actionIndex, 10, 1<<20)
+	var err error
+	if indexer.deadLetters, err = createDeadLetterFile(path); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := indexer.delete(ctx, "2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := indexer.flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := indexer.closeDeadLetters(newRunReport("import")); err != nil {
+		t.Fatal(err)
+	}
+
+	requests = nil
+	if _, err := runImportForTest(t, "--es-url", srv.URL, "--index", "logs", "--retry-dead-letters", path); err == nil {
+		t.Fatal("retry succeeded, want the delete rejected again")
+	}
+	if len(requests) != 1 || len(requests[0]) != 1 || requests[0][0] != `{"delete":{"_id":"2"}}` {
+		t.Errorf("retried bulk requests = %q, want one delete of 2", requests)
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/apache/arrow/go/v10/parquet"
+	"github.com/apache/arrow/go/v10/parquet/metadata"
+)
+
+// maxStatWidth는 inspect가 문자열 최솟값·최댓값을 출력할 때 남기는 최대 글자 수입니다.
+const maxStatWidth = 32
+
+// inspection은 inspect가 Parquet 파일 하나에서 모은 정보입니다.
+type inspection struct {
+	path      string
+	size      int64
+	rows      int64
+	createdBy string
+	version   string
+	schema    string
+	rowGroups []rowGroupSummary
+	columns   []*columnSummary
+	mapping   json.RawMessage
+	// metadata는 footer의 key-value 메타데이터 중 매핑과 Arrow 스키마를 뺀 것입니다.
+	metadata map[string]string
+}
+
+type rowGroupSummary struct {
+	rows  int64
+	bytes int64
+}
+
+// columnSummary는 leaf 컬럼 하나를 모든 row group에 걸쳐 모은 값입니다. min과 max는 모든
+// row group에 통계가 있을 때만 채웁니다.
+type columnSummary struct {
+	path         string
+	physical     string
+	codec        string
+	compressed   int64
+	uncompressed int64
+	values       int64
+	nulls        int64
+	hasStats     bool
+	min, max     interface{}
+}
+
+// add 함수는 row group 하나의 컬럼 청크를 더합니다.
+func (c *columnSummary) add(chunk *metadata.ColumnChunkMetaData, first bool) {
+	c.compressed += chunk.TotalCompressedSize()
+	c.uncompressed += chunk.TotalUncompressedSize()
+	c.values += chunk.NumValues()
+	ok, _ := chunk.StatsSet()
+	var stats metadata.TypedStatistics
+	if ok {
+		stats, _ = chunk.Statistics()
+	}
+	if stats == nil {
+		c.hasStats = false
+		return
+	}
+	if first {
+		c.hasStats = true
+	}
+	if stats.HasNullCount() {
+		c.nulls += stats.NullCount()
+	}
+	if !c.hasStats || !stats.HasMinMax() {
+		return
+	}
+	c.min = minStat(c.min, decodeStat(chunk.Type(), stats.EncodeMin()))
+	c.max = maxStat(c.max, decodeStat(chunk.Type(), stats.EncodeMax()))
+}
+
+func setupInspect(fs *flag.FlagSet) func(ctx context.Context, report *runReport, args []string) error {
+	showMapping := fs.Bool("show-mapping", false, "print the embedded Elasticsearch mapping in full instead of only its size")
+	rowGroups := fs.Bool("row-groups", false, "also list every row group")
+
+	return func(ctx context.Context, report *runReport, args []string) error {
+		if len(args) == 0 {
+			return configErrorf("inspect: no Parquet files given")
+		}
+		for i, path := range args {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			in, err := inspectParquetFile(path)
+			if err != nil {
+				return err
+			}
+			if i > 0 {
+				fmt.Println()
+			}
+			in.write(os.Stdout, *showMapping, *rowGroups)
+			report.RowsRead += in.rows
+		}
+		return nil
+	}
+}
+
+// inspectParquetFile 함수는 path의 footer만 읽어 inspection을 만듭니다. 데이터 페이지는
+// 읽지 않으므로 큰 파일도 금방 끝납니다.
+func inspectParquetFile(path string) (*inspection, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, configErrorf("inspect: %w", err)
+	}
+	pf, err := openParquetFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer pf.Close()
+
+	md := pf.pf.MetaData()
+	in := &inspection{
+		path:      path,
+		size:      info.Size(),
+		rows:      pf.NumRows(),
+		createdBy: md.GetCreatedBy(),
+		version:   md.Version().String(),
+		metadata:  make(map[string]string),
+	}
+	if sc, err := pf.reader.Schema(); err == nil {
+		in.schema = sc.String()
+	}
+	in.mapping, _ = pf.embeddedMapping()
+	kv := md.KeyValueMetadata()
+	for i, k := range kv.Keys() {
+		if k != mappingMetadataKey && k != "ARROW:schema" {
+			in.metadata[k] = kv.Values()[i]
+		}
+	}
+
+	for i := range md.RowGroups {
+		rg := md.RowGroup(i)
+		in.rowGroups = append(in.rowGroups, rowGroupSummary{rows: rg.NumRows(), bytes: rg.TotalByteSize()})
+		for j := 0; j < rg.NumColumns(); j++ {
+			chunk, err := rg.ColumnChunk(j)
+			if err != nil {
+				return nil, dataErrorf("reading column chunk %d of row group %d in %s: %w", j, i, path, err)
+			}
+			if i == 0 {
+				in.columns = append(in.columns, &columnSummary{
+					path:     chunk.PathInSchema().String(),
+					physical: chunk.Type().String(),
+					codec:    chunk.Compression().String(),
+				})
+			}
+			in.columns[j].add(chunk, i == 0)
+		}
+	}
+	return in, nil
+}
+
+// write 함수는 inspection을 사람이 읽는 형태로 씁니다.
+func (in *inspection) write(w io.Writer, showMapping, rowGroups bool) {
+	fmt.Fprintf(w, "%s: %d rows, %d row groups, %s\n", in.path, in.rows, len(in.rowGroups), formatBytes(in.size))
+	fmt.Fprintf(w, "  created by: %s (format %s)\n", in.createdBy, in.version)
+	if in.schema != "" {
+		fmt.Fprintf(w, "\n%s\n", in.schema)
+	}
+
+	fmt.Fprintf(w, "\n  %-40s %-10s %-12s %10s %10s %6s %8s  %s\n", "column", "type", "codec", "stored", "raw", "ratio", "nulls", "min .. max")
+	for _, c := range in.columns {
+		ratio := "-"
+		if c.compressed > 0 {
+			ratio = fmt.Sprintf("%.1fx", float64(c.uncompressed)/float64(c.compressed))
+		}
+		stats := "-"
+		if c.hasStats && c.min != nil {
+			stats = formatStat(c.min) + " .. " + formatStat(c.max)
+		}
+		fmt.Fprintf(w, "  %-40s %-10s %-12s %10s %10s %6s %8d  %s\n", c.path, c.physical, c.codec, formatBytes(c.compressed), formatBytes(c.uncompressed), ratio, c.nulls, stats)
+	}
+
+	if rowGroups {
+		fmt.Fprintf(w, "\n  row groups:\n")
+		for i, rg := range in.rowGroups {
+			fmt.Fprintf(w, "    %d: %d rows, %s\n", i, rg.rows, formatBytes(rg.bytes))
+		}
+	}
+
+	switch {
+	case in.mapping == nil:
+		fmt.Fprintf(w, "\n  no embedded %s metadata\n", mappingMetadataKey)
+	case showMapping:
+		var pretty bytes.Buffer
+		json.Indent(&pretty, in.mapping, "  ", "  ")
+		fmt.Fprintf(w, "\n  embedded mapping:\n  %s\n", pretty.String())
+	default:
+		fmt.Fprintf(w, "\n  embedded mapping: %s (--show-mapping to print it)\n", formatBytes(int64(len(in.mapping))))
+	}
+	keys := make([]string, 0, len(in.metadata))
+	for k := range in.metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "  metadata %s: %s\n", k, truncateStat(in.metadata[k], 80))
+	}
+}
+
+// decodeStat 함수는 plain 인코딩된 통계 값을 물리 타입에 따라 Go 값으로 바꿉니다. 논리
+// 타입(타임스탬프, decimal 등)은 해석하지 않고 저장된 물리 값을 그대로 보여 줍니다.
+func decodeStat(typ parquet.Type, b []byte) interface{} {
+	switch {
+	case typ == parquet.Types.Boolean && len(b) >= 1:
+		return b[0] != 0
+	case typ == parquet.Types.Int32 && len(b) >= 4:
+		return int64(int32(binary.LittleEndian.Uint32(b)))
+	case typ == parquet.Types.Int64 && len(b) >= 8:
+		return int64(binary.LittleEndian.Uint64(b))
+	case typ == parquet.Types.Float && len(b) >= 4:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	case typ == parquet.Types.Double && len(b) >= 8:
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	case typ == parquet.Types.ByteArray && utf8.Valid(b):
+		return string(b)
+	}
+	return "0x" + hex.EncodeToString(b)
+}
+
+// lessStat 함수는 같은 물리 타입에서 나온 두 통계 값을 비교합니다. 타입이 다르면(읽지 못한
+// 값이 16진수 문자열이 된 경우) false입니다.
+func lessStat(a, b interface{}) bool {
+	switch a := a.(type) {
+	case bool:
+		b, ok := b.(bool)
+		return ok && !a && b
+	case int64:
+		b, ok := b.(int64)
+		return ok && a < b
+	case float64:
+		b, ok := b.(float64)
+		return ok && a < b
+	case string:
+		b, ok := b.(string)
+		return ok && a < b
+	}
+	return false
+}
+
+func minStat(cur, v interface{}) interface{} {
+	if cur == nil || lessStat(v, cur) {
+		return v
+	}
+	return cur
+}
+
+func maxStat(cur, v interface{}) interface{} {
+	if cur == nil || lessStat(cur, v) {
+		return v
+	}
+	return cur
+}
+
+func formatStat(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%q", truncateStat(s, maxStatWidth))
+	}
+	return fmt.Sprint(v)
+}
+
+// truncateStat 함수는 s를 최대 n글자로 자릅니다.
+func truncateStat(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:n]) + "…"
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/v10/parquet"
+)
+
+func TestDecodeStat(t *testing.T) {
+	i32 := binary.LittleEndian.AppendUint32(nil, uint32(0xfffffffe))
+	f64 := binary.LittleEndian.AppendUint64(nil, math.Float64bits(2.5))
+	for _, c := range []struct {
+		typ  parquet.Type
+		in   []byte
+		want interface{}
+	}{
+		{parquet.Types.Int32, i32, int64(-2)},
+		{parquet.Types.Double, f64, 2.5},
+		{parquet.Types.ByteArray, []byte("héllo"), "héllo"},
+		{parquet.Types.ByteArray, []byte{0xff, 0x00}, "0xff00"},
+	} {
+		if got := decodeStat(c.typ, c.in); got != c.want {
+			t.Errorf("decodeStat(%d, %x) = %#v, want %#v", c.typ, c.in, got, c.want)
+		}
+	}
+	if got := maxStat(minStat(nil, int64(3)), int64(7)); got != int64(7) {
+		t.Errorf("maxStat = %v", got)
+	}
+	if lessStat(int64(1), "0x00") {
+		t.Error("lessStat compared values of different types")
+	}
+}
+
+func TestInspectionWrite(t *testing.T) {
+	in := &inspection{
+		path: "logs.parquet", size: 2048, rows: 10, createdBy: "parquet-go", version: "v2.6",
+		rowGroups: []rowGroupSummary{{rows: 10, bytes: 4096}},
+		columns: []*columnSummary{
+			{path: "message", physical: "BYTE_ARRAY", codec: "ZSTD", compressed: 1000, uncompressed: 4000, nulls: 2, hasStats: true, min: "a", max: strings.Repeat("z", 40)},
+			{path: "count", physical: "INT64", codec: "ZSTD", compressed: 80, uncompressed: 80},
+		},
+		mapping:  json.RawMessage(`{"properties":{}}`),
+		metadata: map[string]string{"writer": "es-schema"},
+	}
+	var buf bytes.Buffer
+	in.write(&buf, false, true)
+	out := buf.String()
+	for _, want := range []string{
+		"logs.parquet: 10 rows, 1 row groups, 2.0 KiB",
+		"4.0x",
+		`"a" .. "` + strings.Repeat("z", maxStatWidth) + `…"`,
+		"    0: 10 rows, 4.0 KiB",
+		"embedded mapping: 17 B (--show-mapping to print it)",
+		"metadata writer: es-schema",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"unicode/utf8"
+)
+
+const (
+	// maxInternLength보다 긴 문자열은 본문 같은 값이라 겹칠 일이 드물므로 모으지 않습니다.
+	maxInternLength = 64
+	// maxInternStrings는 한 풀이 모으는 서로 다른 문자열의 최대 수입니다.
+	maxInternStrings = 1 << 16
+	// maxDecodeDepth는 encoding/json과 같은 중첩 한도입니다. 더 깊은 문서는 encoding/json에
+	// 넘겨 같은 오류를 받습니다.
+	maxDecodeDepth = 10000
+)
+
+// stringPool은 scroll 페이지 하나를 디코딩하는 동안 같은 문자열을 하나로 모읍니다.
+// keyword 값과 필드 이름은 문서마다 되풀이되므로, 이미 본 값은 새로 할당하지 않고
+// 처음 만든 문자열을 나눠 씁니다.
+type stringPool struct {
+	strings map[string]string
+}
+
+func newStringPool() *stringPool {
+	return &stringPool{strings: make(map[string]string)}
+}
+
+// intern 함수는 b와 같은 문자열을 반환합니다. 풀에 있으면 할당하지 않습니다.
+func (p *stringPool) intern(b []byte) string {
+	if len(b) > maxInternLength {
+		return string(b)
+	}
+	// 맵 조회의 string(b) 변환은 컴파일러가 할당 없이 처리합니다.
+	if s, ok := p.strings[string(b)]; ok {
+		return s
+	}
+	s := string(b)
+	if len(p.strings) < maxInternStrings {
+		p.strings[s] = s
+	}
+	return s
+}
+
+// decodeDocument 함수는 전역 decodeDocument와 같은 결과를 만들되, 문자열과 숫자를
+// 풀에서 가져와 할당을 줄입니다. 객체 하나로 끝나지 않는 입력과 잘못된 JSON은
+// encoding/json에 그대로 넘기므로 결과와 오류 메시지가 같습니다.
+func (p *stringPool) decodeDocument(data []byte) (map[string]interface{}, error) {
+	d := poolDecoder{data: data, pool: p}
+	d.space()
+	if d.pos < len(d.data) && d.data[d.pos] == '{' {
+		if doc, ok := d.object(0); ok {
+			if d.space(); d.pos == len(d.data) {
+				return doc, nil
+			}
+		}
+	}
+	return decodeDocument(data)
+}
+
+// poolDecoder는 stringPool.decodeDocument가 쓰는 JSON 디코더입니다. 실패하면 false만
+// 반환하고, 오류는 encoding/json이 보고합니다.
+type poolDecoder struct {
+	data []byte
+	pos  int
+	pool *stringPool
+}
+
+func (d *poolDecoder) space() {
+	for d.pos < len(d.data) {
+		switch d.data[d.pos] {
+		case ' ', '\t', '\n', '\r':
+			d.pos++
+		default:
+			return
+		}
+	}
+}
+
+// consume 함수는 공백 뒤에 c가 있으면 넘기고 true를 반환합니다.
+func (d *poolDecoder) consume(c byte) bool {
+	d.space()
+	if d.pos < len(d.data) && d.data[d.pos] == c {
+		d.pos++
+		return true
+	}
+	return false
+}
+
+func (d *poolDecoder) value(depth int) (interface{}, bool) {
+	d.space()
+	if d.pos >= len(d.data) {
+		return nil, false
+	}
+	switch c := d.data[d.pos]; {
+	case c == '{':
+		return d.object(depth)
+	case c == '[':
+		return d.array(depth)
+	case c == '"':
+		return d.string()
+	case c == 't':
+		return true, d.literal("true")
+	case c == 'f':
+		return false, d.literal("false")
+	case c == 'n':
+		return nil, d.literal("null")
+	case c == '-' || c >= '0' && c <= '9':
+		return d.number()
+	}
+	return nil, false
+}
+
+func (d *poolDecoder) object(depth int) (map[string]interface{}, bool) {
+	if depth++; depth > maxDecodeDepth {
+		return nil, false
+	}
+	d.pos++
+	obj := make(map[string]interface{})
+	if d.consume('}') {
+		return obj, true
+	}
+	for {
+		d.space()
+		if d.pos >= len(d.data) || d.data[d.pos] != '"' {
+			return nil, false
+		}
+		key, ok := d.string()
+		if !ok || !d.consume(':') {
+			return nil, false
+		}
+		v, ok := d.value(depth)
+		if !ok {
+			return nil, false
+		}
+		obj[key.(string)] = v
+		if d.consume(',') {
+			continue
+		}
+		return obj, d.consume('}')
+	}
+}
+
+func (d *poolDecoder) array(depth int) ([]interface{}, bool) {
+	if depth++; depth > maxDecodeDepth {
+		return nil, false
+	}
+	d.pos++
+	items := []interface{}{}
+	if d.consume(']') {
+		return items, true
+	}
+	for {
+		v, ok := d.value(depth)
+		if !ok {
+			return nil, false
+		}
+		items = append(items, v)
+		if d.consume(',') {
+			continue
+		}
+		return items, d.consume(']')
+	}
+}
+
+// string 함수는 따옴표로 시작하는 문자열을 읽습니다. 이스케이프나 잘못된 UTF-8이 있는
+// 드문 문자열은 encoding/json으로 풀어 같은 값을 얻습니다.
+func (d *poolDecoder) string() (interface{}, bool) {
+	start := d.pos
+	d.pos++
+	escaped, ascii := false, true
+	for ; d.pos < len(d.data); d.pos++ {
+		switch c := d.data[d.pos]; {
+		case c == '"':
+			d.pos++
+			raw := d.data[start+1 : d.pos-1]
+			if !escaped && (ascii || utf8.Valid(raw)) {
+				return d.pool.intern(raw), true
+			}
+			var s string
+			if err := json.Unmarshal(d.data[start:d.pos], &s); err != nil {
+				return nil, false
+			}
+			return s, true
+		case c == '\\':
+			escaped = true
+			d.pos++
+		case c < 0x20:
+			return nil, false
+		case c >= utf8.RuneSelf:
+			ascii = false
+		}
+	}
+	return nil, false
+}
+
+func (d *poolDecoder) literal(word string) bool {
+	if len(d.data)-d.pos < len(word) || string(d.data[d.pos:d.pos+len(word)]) != word {
+		return false
+	}
+	d.pos += len(word)
+	return true
+}
+
+// number 함수는 JSON 문법에 맞는 숫자를 json.Number로 읽습니다(decodeDocument의
+// UseNumber와 같습니다).
+func (d *poolDecoder) number() (interface{}, bool) {
+	start := d.pos
+	if d.data[d.pos] == '-' {
+		d.pos++
+	}
+	switch {
+	case d.pos < len(d.data) && d.data[d.pos] == '0':
+		d.pos++
+	case d.digits() == 0:
+		return nil, false
+	}
+	if d.pos < len(d.data) && d.data[d.pos] == '.' {
+		d.pos++
+		if d.digits() == 0 {
+			return nil, false
+		}
+	}
+	if d.pos < len(d.data) && (d.data[d.pos] == 'e' || d.data[d.pos] == 'E') {
+		d.pos++
+		if d.pos < len(d.data) && (d.data[d.pos] == '+' || d.data[d.pos] == '-') {
+			d.pos++
+		}
+		if d.digits() == 0 {
+			return nil, false
+		}
+	}
+	return json.Number(d.pool.intern(d.data[start:d.pos])), true
+}
+
+func (d *poolDecoder) digits() int {
+	n := 0
+	for d.pos < len(d.data) && d.data[d.pos] >= '0' && d.data[d.pos] <= '9' {
+		d.pos++
+		n++
+	}
+	return n
+}
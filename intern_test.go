@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStringPoolDecodeDocumentMatchesEncodingJSON(t *testing.T) {
+	docs := []string{
+		`{}`,
+		` {"a": "x", "b": ["x", "y", 1, -0.5e+3, true, false, null], "c": {"d": [], "e": {}}} `,
+		`{"esc": "tab\there \"quoted\" é 😀", "key": 1}`,
+		`{"utf8": "한글 값", "bad": "` + "\xff" + `"}`,
+		`{"dup": 1, "dup": 2}`,
+		`{"big": 12345678901234567890, "neg": -0, "exp": 1E5}`,
+		`{"a": 1} {"b": 2}`,
+		`{"a": 01}`,
+		`{"a": 1,}`,
+		`{"a": "` + "\x01" + `"}`,
+		`{"a": tru}`,
+		`{"a": [1, 2}`,
+		`{"a"}`,
+		`[1]`,
+		`null`,
+		``,
+	}
+	for _, doc := range docs {
+		want, wantErr := decodeDocument([]byte(doc))
+		got, gotErr := newStringPool().decodeDocument([]byte(doc))
+		if fmt.Sprint(gotErr) != fmt.Sprint(wantErr) || !reflect.DeepEqual(got, want) {
+			t.Errorf("decoding %q = %v, %v; want %v, %v", doc, got, gotErr, want, wantErr)
+		}
+	}
+}
+
+func TestStringPoolSharesStrings(t *testing.T) {
+	p := newStringPool()
+	a, err := p.decodeDocument([]byte(`{"level": "info"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := p.decodeDocument([]byte(`{"level": "info"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.strings) != 2 {
+		t.Errorf("pool holds %d strings, want 2 (key and value)", len(p.strings))
+	}
+	if a["level"] != b["level"] {
+		t.Errorf("values differ: %v, %v", a["level"], b["level"])
+	}
+	if s := strings.Repeat("x", maxInternLength+1); p.intern([]byte(s)) != s || len(p.strings) != 2 {
+		t.Error("long string was pooled")
+	}
+}
+
+// keywordPage는 keyword 값이 되풀이되는 로그 문서 한 페이지입니다.
+func keywordPage(n int) [][]byte {
+	levels := []string{"info", "warn", "error"}
+	page := make([][]byte, n)
+	for i := range page {
+		doc := map[string]interface{}{
+			"@timestamp": "2024-05-01T12:00:00Z",
+			"level":      levels[i%len(levels)],
+			"service":    map[string]interface{}{"name": "checkout", "env": "prod", "version": "1.4.2"},
+			"host":       map[string]interface{}{"name": fmt.Sprintf("web-%02d", i%16), "region": "eu-west-1"},
+			"tags":       []string{"http", "payments"},
+			"status":     200,
+			"message":    fmt.Sprintf("request %d completed", i),
+		}
+		page[i], _ = json.Marshal(doc)
+	}
+	return page
+}
+
+func BenchmarkDecodePage(b *testing.B) {
+	page := keywordPage(1000)
+	b.Run("encoding/json", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, doc := range page {
+				if _, err := decodeDocument(doc); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("pool", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			p := newStringPool()
+			for _, doc := range page {
+				if _, err := p.decodeDocument(doc); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
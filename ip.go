@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/apache/arrow/go/v10/arrow"
+
+	"es-schema/esschema"
+)
+
+// ipFormatOptions는 ip 필드의 컬럼 형식을 고르는 --ip-format 플래그입니다. binary는 주소를
+// 16바이트 fixed_size_binary로(IPv4는 IPv6에 사상해서), string은 정규형 주소 문자열로
+// 씁니다. 어느 쪽이든 주소가 아닌 값은 바꿀 수 없는 값으로 다룹니다.
+type ipFormatOptions struct {
+	format string
+}
+
+func (o *ipFormatOptions) bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.format, "ip-format", "binary", "column type of ip fields: binary (16-byte fixed_size_binary, IPv4 mapped into IPv6, sorts and compares by address) or string (canonical address text for readers that cannot decode binary addresses); values that are not IPv4 or IPv6 addresses are written as null either way")
+}
+
+func (o *ipFormatOptions) validate() error {
+	switch o.format {
+	case "", "binary", "string":
+		return nil
+	}
+	return configErrorf("unknown --ip-format %q (want binary or string)", o.format)
+}
+
+// apply 함수는 string이면 fields의 ip 필드를 문자열 컬럼으로 바꿉니다.
+func (o *ipFormatOptions) apply(fields []arrow.Field) []arrow.Field {
+	if o.format != "string" {
+		return fields
+	}
+	return esschema.IPStrings(fields)
+}
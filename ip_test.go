@@ -0,0 +1,14 @@
+package main
+
+import "testing"
+
+func TestIPFormatValidate(t *testing.T) {
+	for _, format := range []string{"binary", "string"} {
+		if err := (&ipFormatOptions{format: format}).validate(); err != nil {
+			t.Errorf("--ip-format %s: %v", format, err)
+		}
+	}
+	if err := (&ipFormatOptions{format: "text"}).validate(); exitCodeFor(err) != exitConfigError {
+		t.Errorf("--ip-format text = %v, want a config error", err)
+	}
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// 작업 상태입니다.
+const (
+	jobQueued    = "queued"
+	jobRunning   = "running"
+	jobSucceeded = "succeeded"
+	jobPartial   = "partial_success"
+	jobFailed    = "failed"
+	jobCanceled  = "canceled"
+	jobSkipped   = "skipped"
+)
+
+// 작업을 큐에 넣은 쪽입니다.
+const (
+	triggerSchedule = "schedule"
+	triggerAPI      = "api"
+)
+
+const defaultJobHistory = 100
+
+// daemonJob은 daemon이 실행하는 명령 한 번입니다. 일정이 되었거나 API로 제출되면 큐에
+// 들어가고, 동시에 --max-jobs개까지 실행합니다. 같은 파이프라인의 작업은 한 번에 하나씩
+// 실행하고, API로 직접 명령을 준 작업은 Pipeline이 비어 있습니다. --lock이 있으면 파이프라인
+// 작업은 잠금을 잡고 실행하며, 다른 복제본이 돌리고 있거나 같은 예약 시각을 이미 돌렸으면
+// skipped로 끝납니다.
+type daemonJob struct {
+	ID          string     `json:"id"`
+	Pipeline    string     `json:"pipeline,omitempty"`
+	Command     string     `json:"command"`
+	Trigger     string     `json:"trigger"`
+	State       string     `json:"state"`
+	SubmittedAt time.Time  `json:"submitted_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	// Progress는 export 작업의 인덱스별 진행 상황이고, Rows는 끝난 작업이 쓴 행 수입니다.
+	Progress map[string]*indexProgress `json:"progress,omitempty"`
+	Rows     int64                     `json:"rows,omitempty"`
+	Error    string                    `json:"error,omitempty"`
+
+	spec     *pipelineSpec
+	ctx      context.Context
+	cancel   context.CancelFunc
+	canceled bool
+	report   *runReport
+	// slot은 예약 실행의 실행 시각이고, lockErr는 실행 중에 잠금 임대를 늘리지 못한 오류입니다.
+	slot    time.Time
+	skipped bool
+	lockErr error
+}
+
+type indexProgress struct {
+	Done  int64 `json:"done"`
+	Total int64 `json:"total,omitempty"`
+}
+
+func (j *daemonJob) finished() bool {
+	return j.FinishedAt != nil
+}
+
+// jobRequest는 POST /jobs의 본문입니다. Pipeline이 있으면 설정 파일의 파이프라인을 지금
+// 실행하며 Flags는 그 파이프라인의 플래그를 덮어씁니다. 없으면 Command를 Flags로 실행합니다.
+type jobRequest struct {
+	Pipeline string                 `json:"pipeline"`
+	Command  string                 `json:"command"`
+	Flags    map[string]interface{} `json:"flags"`
+}
+
+// enqueue 함수는 p를 실행하는 작업을 큐에 넣습니다. d.mu를 잡고 부릅니다.
+func (d *daemon) enqueue(ctx context.Context, p *pipelineSpec, trigger string) *daemonJob {
+	d.seq++
+	job := &daemonJob{
+		ID:          strconv.Itoa(d.seq),
+		Pipeline:    p.Name,
+		Command:     p.Command,
+		Trigger:     trigger,
+		State:       jobQueued,
+		SubmittedAt: time.Now().UTC(),
+		spec:        p,
+	}
+	job.ctx, job.cancel = context.WithCancel(ctx)
+	d.jobs = append(d.jobs, job)
+	d.trimJobs()
+	return job
+}
+
+// trimJobs 함수는 끝난 작업이 history개를 넘으면 오래된 것부터 잊습니다.
+func (d *daemon) trimJobs() {
+	done := 0
+	for _, j := range d.jobs {
+		if j.finished() {
+			done++
+		}
+	}
+	kept := d.jobs[:0]
+	for _, j := range d.jobs {
+		if j.finished() && done > d.history {
+			done--
+			continue
+		}
+		kept = append(kept, j)
+	}
+	d.jobs = kept
+}
+
+// active 함수는 파이프라인의 작업이 큐에 있거나 돌고 있는지 알려 줍니다.
+func (d *daemon) active(pipeline string) bool {
+	for _, j := range d.jobs {
+		if j.Pipeline == pipeline && !j.finished() {
+			return true
+		}
+	}
+	return false
+}
+
+// dispatch 함수는 자리가 있으면 큐의 작업을 들어온 순서로 시작합니다. 같은 파이프라인의
+// 작업이 돌고 있으면 그 작업은 건너뛰고 다음 작업을 봅니다. d.mu를 잡고 부릅니다.
+func (d *daemon) dispatch() {
+	running := make(map[string]bool)
+	n := 0
+	for _, j := range d.jobs {
+		if j.State == jobRunning {
+			running[j.Pipeline] = true
+			n++
+		}
+	}
+	for _, j := range d.jobs {
+		if n >= d.maxJobs {
+			return
+		}
+		if j.State != jobQueued || j.Pipeline != "" && running[j.Pipeline] {
+			continue
+		}
+		running[j.Pipeline] = j.Pipeline != ""
+		n++
+		d.start(j)
+	}
+}
+
+func (d *daemon) start(j *daemonJob) {
+	now := time.Now().UTC()
+	j.State, j.StartedAt = jobRunning, &now
+	j.report = newRunReport(j.Command)
+	j.report.progress = func(index string, done, total int64) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if j.Progress == nil {
+			j.Progress = make(map[string]*indexProgress)
+		}
+		j.Progress[index] = &indexProgress{Done: done, Total: total}
+	}
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		if release, err := d.lockJob(j); err != nil {
+			var held *lockHeldError
+			j.skipped = errors.As(err, &held)
+			j.report.finish(err)
+			fmt.Printf("daemon: %s: not run: %v\n", j.Pipeline, err)
+		} else {
+			d.run(j.ctx, j.spec, j.report)
+			release()
+		}
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.finish(j)
+		d.dispatch()
+	}()
+}
+
+// finish 함수는 실행이 끝난 작업의 상태를 보고서에서 정합니다. d.mu를 잡고 부릅니다.
+func (d *daemon) finish(j *daemonJob) {
+	now := time.Now().UTC()
+	j.FinishedAt = &now
+	j.cancel()
+	j.Rows = j.report.RowsExported
+	if j.report.Error != nil {
+		j.Error = j.report.Error.Error
+	}
+	switch {
+	case j.lockErr != nil:
+		j.State, j.Error = jobFailed, fmt.Sprintf("lost the pipeline lock: %v", j.lockErr)
+	case j.canceled:
+		j.State = jobCanceled
+	case j.skipped:
+		j.State = jobSkipped
+	case j.report.Status == statusSuccess:
+		j.State = jobSucceeded
+	case j.report.Status == statusPartialSuccess:
+		j.State = jobPartial
+	default:
+		j.State = jobFailed
+	}
+	d.trimJobs()
+}
+
+// cancelJob 함수는 큐의 작업을 빼거나 돌고 있는 작업을 취소합니다. 취소된 작업은 쓰던
+// 파일을 지우고 canceled로 끝납니다.
+func (d *daemon) cancelJob(id string) (*daemonJob, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	j := d.job(id)
+	switch {
+	case j == nil:
+		return nil, fmt.Errorf("no job %s", id)
+	case j.finished():
+		return j, fmt.Errorf("job %s already %s", id, j.State)
+	}
+	j.canceled = true
+	j.cancel()
+	if j.State == jobQueued {
+		now := time.Now().UTC()
+		j.State, j.FinishedAt = jobCanceled, &now
+		d.trimJobs()
+	}
+	return j, nil
+}
+
+func (d *daemon) job(id string) *daemonJob {
+	for _, j := range d.jobs {
+		if j.ID == id {
+			return j
+		}
+	}
+	return nil
+}
+
+// submit 함수는 API로 받은 작업을 검사해 큐에 넣습니다.
+func (d *daemon) submit(ctx context.Context, req jobRequest) (*daemonJob, int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var p *pipelineSpec
+	if req.Pipeline != "" {
+		base := d.pipelines[req.Pipeline]
+		if base == nil {
+			return nil, http.StatusNotFound, fmt.Errorf("no pipeline %q", req.Pipeline)
+		}
+		p = base
+		if len(req.Flags) > 0 {
+			flags := make(map[string]interface{}, len(base.Flags)+len(req.Flags))
+			for k, v := range base.Flags {
+				flags[k] = v
+			}
+			for k, v := range req.Flags {
+				flags[k] = v
+			}
+			copied := *base
+			copied.Flags = flags
+			p = &copied
+		}
+	} else {
+		if req.Command == "" {
+			return nil, http.StatusBadRequest, fmt.Errorf("give a pipeline or a command")
+		}
+		p = &pipelineSpec{Name: req.Command, Command: req.Command, Flags: req.Flags}
+	}
+	if err := p.checkCommand(); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	job := d.enqueue(ctx, p, triggerAPI)
+	if req.Pipeline == "" {
+		job.Pipeline = ""
+	}
+	d.dispatch()
+	return job, http.StatusAccepted, nil
+}
+
+// handler 함수는 작업 API입니다.
+//
+//	GET  /pipelines            설정된 파이프라인과 다음 실행 시각
+//	GET  /jobs                 큐에 있거나 돌고 있거나 끝난 작업
+//	POST /jobs                 jobRequest로 작업 제출
+//	GET  /jobs/{id}            작업 하나
+//	POST /jobs/{id}/cancel     작업 취소
+//	GET  /jobs/{id}/report     끝난 작업의 실행 보고서(--status-file과 같은 JSON)
+//
+// token이 있으면 모든 요청에 Authorization: Bearer <token>이 있어야 합니다.
+func (d *daemon) handler(ctx context.Context, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /pipelines", func(w http.ResponseWriter, r *http.Request) {
+		// 플래그의 비밀 값은 가리고 보여 줍니다.
+		type pipelineView struct {
+			*pipelineSpec
+			Flags   map[string]interface{} `json:"flags,omitempty"`
+			NextRun time.Time              `json:"next_run"`
+		}
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		out := make([]pipelineView, 0, len(d.pipelines))
+		for name, p := range d.pipelines {
+			out = append(out, pipelineView{pipelineSpec: p, Flags: redactSettings(p.Flags), NextRun: d.next[name].UTC()})
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+		writeAPIJSON(w, http.StatusOK, out)
+	})
+	mux.HandleFunc("GET /jobs", func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		state := r.URL.Query().Get("state")
+		out := []*daemonJob{}
+		for _, j := range d.jobs {
+			if state == "" || j.State == state {
+				out = append(out, j)
+			}
+		}
+		writeAPIJSON(w, http.StatusOK, out)
+	})
+	mux.HandleFunc("POST /jobs", func(w http.ResponseWriter, r *http.Request) {
+		var req jobRequest
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			writeAPIError(w, http.StatusBadRequest, fmt.Errorf("decoding job: %w", err))
+			return
+		}
+		job, status, err := d.submit(ctx, req)
+		if err != nil {
+			writeAPIError(w, status, err)
+			return
+		}
+		w.Header().Set("Location", "/jobs/"+job.ID)
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		writeAPIJSON(w, status, job)
+	})
+	mux.HandleFunc("GET /jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if j := d.job(r.PathValue("id")); j != nil {
+			writeAPIJSON(w, http.StatusOK, j)
+			return
+		}
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("no job %s", r.PathValue("id")))
+	})
+	mux.HandleFunc("POST /jobs/{id}/cancel", func(w http.ResponseWriter, r *http.Request) {
+		j, err := d.cancelJob(r.PathValue("id"))
+		switch {
+		case j == nil:
+			writeAPIError(w, http.StatusNotFound, err)
+		case err != nil:
+			writeAPIError(w, http.StatusConflict, err)
+		default:
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			writeAPIJSON(w, http.StatusAccepted, j)
+		}
+	})
+	mux.HandleFunc("GET /jobs/{id}/report", func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		j := d.job(r.PathValue("id"))
+		switch {
+		case j == nil:
+			writeAPIError(w, http.StatusNotFound, fmt.Errorf("no job %s", r.PathValue("id")))
+		case !j.finished() || j.report == nil:
+			writeAPIError(w, http.StatusConflict, fmt.Errorf("job %s is %s and has no report yet", j.ID, j.State))
+		default:
+			writeAPIJSON(w, http.StatusOK, j.report)
+		}
+	})
+	if token == "" {
+		return mux
+	}
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			writeAPIError(w, http.StatusUnauthorized, fmt.Errorf("missing or wrong bearer token"))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	writeAPIJSON(w, status, map[string]string{"error": err.Error()})
+}
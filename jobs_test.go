@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJobAPI(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pipeline.yaml")
+	os.WriteFile(path, []byte(`
+pipelines:
+  - name: logs
+    every: 24h
+    flags:
+      out-dir: /data/logs
+      api-key: plain-secret
+`), 0o644)
+	d := newDaemon(path)
+	if err := d.reload(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	release := make(chan struct{})
+	d.run = func(ctx context.Context, p *pipelineSpec, report *runReport) {
+		report.progress("logs-a", 10, 40)
+		select {
+		case <-release:
+			report.RowsExported = 40
+			report.finish(nil)
+		case <-ctx.Done():
+			report.finish(ctx.Err())
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := httptest.NewServer(d.handler(ctx, "tok"))
+	defer srv.Close()
+
+	call := func(method, path, body string, out interface{}) int {
+		req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer tok")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if out != nil {
+			json.NewDecoder(resp.Body).Decode(out)
+		}
+		return resp.StatusCode
+	}
+	waitState := func(id, state string) daemonJob {
+		for i := 0; i < 1000; i++ {
+			var j daemonJob
+			call("GET", "/jobs/"+id, "", &j)
+			if j.State == state {
+				return j
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t.Fatalf("job %s never became %s", id, state)
+		return daemonJob{}
+	}
+
+	resp, err := http.Get(srv.URL + "/jobs")
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("request without token = %v, %v", resp.StatusCode, err)
+	}
+
+	var first, second daemonJob
+	if code := call("POST", "/jobs", `{"pipeline": "logs", "flags": {"out-dir": "/tmp/rerun"}}`, &first); code != http.StatusAccepted {
+		t.Fatalf("submit = %d", code)
+	}
+	// 같은 파이프라인의 두 번째 작업은 첫 작업이 끝날 때까지 큐에서 기다립니다.
+	call("POST", "/jobs", `{"pipeline": "logs"}`, &second)
+	running := waitState(first.ID, jobRunning)
+	if p := running.Progress["logs-a"]; p == nil || p.Done != 10 || p.Total != 40 {
+		t.Errorf("progress = %+v", running.Progress)
+	}
+	if second.State != jobQueued {
+		t.Errorf("second job = %s, want queued", second.State)
+	}
+	d.mu.Lock()
+	override := d.job(first.ID).spec.Flags["out-dir"]
+	d.mu.Unlock()
+	if override != "/tmp/rerun" {
+		t.Errorf("submitted flags out-dir = %v", override)
+	}
+	var conflict map[string]string
+	if code := call("GET", "/jobs/"+first.ID+"/report", "", &conflict); code != http.StatusConflict {
+		t.Errorf("report of a running job = %d %v", code, conflict)
+	}
+
+	if code := call("POST", "/jobs/"+second.ID+"/cancel", "", nil); code != http.StatusAccepted {
+		t.Errorf("cancel queued job = %d", code)
+	}
+	release <- struct{}{}
+	waitState(first.ID, jobSucceeded)
+	var report runReport
+	if code := call("GET", "/jobs/"+first.ID+"/report", "", &report); code != http.StatusOK || report.RowsExported != 40 {
+		t.Errorf("report = %d %+v", code, report)
+	}
+	if j := waitState(second.ID, jobCanceled); j.StartedAt != nil {
+		t.Errorf("canceled queued job was started")
+	}
+
+	// 돌고 있는 작업을 취소하면 그 작업의 컨텍스트가 취소됩니다.
+	var third daemonJob
+	call("POST", "/jobs", `{"command": "compact", "flags": {"target-mb": 64}}`, &third)
+	waitState(third.ID, jobRunning)
+	call("POST", "/jobs/"+third.ID+"/cancel", "", nil)
+	if j := waitState(third.ID, jobCanceled); j.Pipeline != "" || !strings.Contains(j.Error, "canceled") {
+		t.Errorf("canceled job = %+v", j)
+	}
+
+	var jobs []daemonJob
+	call("GET", "/jobs?state=canceled", "", &jobs)
+	if len(jobs) != 2 {
+		t.Errorf("canceled jobs = %+v", jobs)
+	}
+	var bad map[string]string
+	if code := call("POST", "/jobs", `{"command": "compact", "flags": {"index": "x"}}`, &bad); code != http.StatusBadRequest || !strings.Contains(bad["error"], "no flag --index") {
+		t.Errorf("submit with a foreign flag = %d %v", code, bad)
+	}
+	if code := call("POST", "/jobs", `{"pipeline": "nope"}`, nil); code != http.StatusNotFound {
+		t.Errorf("submit unknown pipeline = %d", code)
+	}
+	var pipelines []map[string]interface{}
+	call("GET", "/pipelines", "", &pipelines)
+	if len(pipelines) != 1 || pipelines[0]["flags"].(map[string]interface{})["api-key"] != "<redacted>" || pipelines[0]["next_run"] == nil {
+		t.Errorf("pipelines = %v", pipelines)
+	}
+	cancel()
+	d.wg.Wait()
+}
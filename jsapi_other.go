@@ -0,0 +1,9 @@
+//go:build !(js && wasm)
+
+package main
+
+// serveJS 함수는 WebAssembly 빌드에서만 JS API를 엽니다. 다른 플랫폼에서는 명령행
+// 도구로 실행되도록 false를 반환합니다.
+func serveJS() bool {
+	return false
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// TestWasmBuild 함수는 wasm/es-schema.js에 적은 js/wasm 빌드가 깨지지 않았는지 go vet으로
+// 확인합니다. 툴체인을 다시 부르므로 -short에서는 건너뜁니다.
+func TestWasmBuild(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the js/wasm build in -short mode")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+	cmd := exec.Command(goTool, "vet", "-tags", "noasm", ".")
+	cmd.Env = append(os.Environ(), "GOOS=js", "GOARCH=wasm")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("GOOS=js GOARCH=wasm go vet -tags noasm: %v\n%s", err, out)
+	}
+}
@@ -0,0 +1,45 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"encoding/json"
+	"syscall/js"
+)
+
+// serveJS 함수는 전역 객체 esSchemaGo에 변환 함수를 등록하고 돌아오지 않습니다. 각
+// 함수는 JSON 문자열을 받아 {"result": ...} 또는 {"error": "..."} JSON 문자열을
+// 반환하며, wasm/es-schema.js가 이를 Promise API로 감쌉니다.
+//
+// 빌드: GOOS=js GOARCH=wasm go build -tags noasm -o wasm/es-schema.wasm .
+// arrow의 어셈블리 구현은 js/wasm에 없으므로 noasm 태그가 필요합니다(TestWasmBuild 참고).
+func serveJS() bool {
+	api := js.Global().Get("Object").New()
+	api.Set("previewMapping", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) != 1 {
+			return jsResult(nil, configErrorf("previewMapping(mappingJSON) takes one argument"))
+		}
+		return jsResult(previewMapping([]byte(args[0].String())))
+	}))
+	api.Set("previewConversion", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) != 2 {
+			return jsResult(nil, configErrorf("previewConversion(mappingJSON, documents) takes two arguments"))
+		}
+		return jsResult(previewConversion([]byte(args[0].String()), []byte(args[1].String())))
+	}))
+	js.Global().Set("esSchemaGo", api)
+	js.Global().Call("dispatchEvent", js.Global().Get("Event").New("es-schema-ready"))
+	select {}
+}
+
+func jsResult(v interface{}, err error) interface{} {
+	out := map[string]interface{}{"result": v}
+	if err != nil {
+		out = map[string]interface{}{"error": err.Error(), "kind": string(kindOf(err))}
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		data, _ = json.Marshal(map[string]string{"error": err.Error()})
+	}
+	return string(data)
+}
@@ -0,0 +1,446 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	k8sKindCronJob = "cronjob"
+	k8sKindJob     = "job"
+
+	// k8sConfigDir는 파이프라인 설정을 컨테이너에 올리는 디렉터리입니다.
+	k8sConfigDir = "/etc/es-schema"
+)
+
+// k8sJobOptions는 k8s-job 명령의 설정입니다.
+type k8sJobOptions struct {
+	config         string
+	pipelines      stringListFlag
+	kind           string
+	image          string
+	namespace      string
+	serviceAccount string
+	envFrom        stringListFlag
+	estimate       string
+	out            string
+}
+
+func setupK8sJob(fs *flag.FlagSet) func(ctx context.Context, report *runReport, args []string) error {
+	var o k8sJobOptions
+	fs.StringVar(&o.config, "config", "", "pipeline YAML file of the daemon command to render (required)")
+	fs.Var(&o.pipelines, "pipeline", "only render these pipelines (comma-separated or repeated; default all)")
+	fs.StringVar(&o.kind, "kind", k8sKindCronJob, "cronjob (run on the pipeline's schedule) or job (run once)")
+	fs.StringVar(&o.image, "image", "es-schema:latest", "container image with the es-schema binary as its entrypoint")
+	fs.StringVar(&o.namespace, "namespace", "", "namespace of the rendered objects (default: the one kubectl applies to)")
+	fs.StringVar(&o.serviceAccount, "service-account", "", "service account of the pods")
+	fs.Var(&o.envFrom, "env-from-secret", "load these Kubernetes secrets as environment variables, for ${env:...} references and ES_API_KEY (comma-separated or repeated)")
+	fs.StringVar(&o.estimate, "estimate", "", "status file of migrate --dry-run --status-file; size the memory request and the deadline from its output estimate (needs a single pipeline)")
+	fs.StringVar(&o.out, "out", "-", "file to write the manifests to, or - for standard output")
+
+	return func(ctx context.Context, report *runReport, args []string) error {
+		if len(args) > 0 {
+			return configErrorf("k8s-job: unexpected arguments %v", args)
+		}
+		if o.config == "" {
+			return configErrorf("k8s-job: --config is required")
+		}
+		if o.kind != k8sKindCronJob && o.kind != k8sKindJob {
+			return configErrorf("k8s-job: unknown --kind %q (want cronjob or job)", o.kind)
+		}
+		specs, err := loadDaemonConfig(o.config)
+		if err != nil {
+			return err
+		}
+		names, err := o.selected(specs)
+		if err != nil {
+			return err
+		}
+		var estimate *outputEstimate
+		if o.estimate != "" {
+			if len(names) != 1 {
+				return configErrorf("k8s-job: --estimate sizes one pipeline; choose it with --pipeline")
+			}
+			if estimate, err = readEstimate(o.estimate); err != nil {
+				return err
+			}
+		}
+
+		var buf bytes.Buffer
+		for i, name := range names {
+			objects, err := o.manifests(specs[name], estimate, report)
+			if err != nil {
+				return err
+			}
+			for j, obj := range objects {
+				if i+j > 0 {
+					buf.WriteString("---\n")
+				}
+				if err := writeYAML(&buf, obj); err != nil {
+					return err
+				}
+			}
+		}
+		if o.out == "-" {
+			_, err = os.Stdout.Write(buf.Bytes())
+			return err
+		}
+		if err := os.WriteFile(o.out, buf.Bytes(), 0o644); err != nil {
+			return configErrorf("writing %s: %w", o.out, err)
+		}
+		fmt.Fprintf(os.Stderr, "wrote %d %s manifests to %s\n", len(names), o.kind, o.out)
+		return nil
+	}
+}
+
+// selected 함수는 그릴 파이프라인 이름을 정렬해 반환합니다.
+func (o *k8sJobOptions) selected(specs map[string]*pipelineSpec) ([]string, error) {
+	var names []string
+	if len(o.pipelines) == 0 {
+		for name := range specs {
+			names = append(names, name)
+		}
+	} else {
+		for _, name := range o.pipelines {
+			if specs[name] == nil {
+				return nil, configErrorf("k8s-job: %s has no pipeline %q", o.config, name)
+			}
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, configErrorf("k8s-job: %s has no pipelines", o.config)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// readEstimate 함수는 migrate --dry-run의 상태 파일에서 출력 추정치를 읽습니다.
+func readEstimate(path string) (*outputEstimate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, configErrorf("reading --estimate: %w", err)
+	}
+	var report runReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, configErrorf("parsing --estimate %s: %w", path, err)
+	}
+	if report.Estimate == nil || report.Estimate.SampleDocuments == 0 {
+		return nil, configErrorf("%s has no output estimate; write one with migrate --dry-run --estimate-sample N --status-file", path)
+	}
+	return report.Estimate, nil
+}
+
+// manifests 함수는 파이프라인 하나의 설정 객체와 Job 또는 CronJob을 만듭니다. 파이프라인의
+// 플래그는 그 이름의 프로필로 설정 객체에 넣어, 컨테이너가 es-schema <command> --profile
+// <name>으로 실행합니다. 그래서 비밀 참조는 파드 안에서 풀립니다. 비밀 값을 그대로 담은
+// 파이프라인의 설정은 ConfigMap 대신 Secret에 넣습니다.
+func (o *k8sJobOptions) manifests(p *pipelineSpec, estimate *outputEstimate, report *runReport) ([]interface{}, error) {
+	name := k8sName(p.Name)
+	labels := map[string]interface{}{
+		"app.kubernetes.io/name":     "es-schema",
+		"app.kubernetes.io/instance": name,
+		"es-schema/command":          p.Command,
+	}
+	meta := func() map[string]interface{} {
+		m := map[string]interface{}{"name": name, "labels": labels}
+		if o.namespace != "" {
+			m["namespace"] = o.namespace
+		}
+		return m
+	}
+
+	var profile bytes.Buffer
+	if err := writeYAML(&profile, profileConfig{Profiles: map[string]map[string]interface{}{p.Name: p.Flags}}); err != nil {
+		return nil, err
+	}
+	config := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   meta(),
+		"data":       map[string]interface{}{"config": profile.String()},
+	}
+	volume := map[string]interface{}{"name": "config", "configMap": map[string]interface{}{"name": name}}
+	if secret := profileSecret(p.Flags); secret != "" {
+		report.warnf("pipeline %q holds %s in plain text, so its config is rendered as a Secret; prefer a ${file:...} or ${vault:...} reference", p.Name, secret)
+		config["kind"] = "Secret"
+		config["type"] = "Opaque"
+		config["stringData"] = config["data"]
+		delete(config, "data")
+		volume = map[string]interface{}{"name": "config", "secret": map[string]interface{}{"secretName": name}}
+	}
+
+	container := map[string]interface{}{
+		"name":         "es-schema",
+		"image":        o.image,
+		"args":         []string{p.Command, "--profile", p.Name},
+		"env":          []interface{}{map[string]interface{}{"name": "ES_SCHEMA_CONFIG", "value": k8sConfigDir + "/config"}},
+		"volumeMounts": []interface{}{map[string]interface{}{"name": "config", "mountPath": k8sConfigDir, "readOnly": true}},
+		"resources":    jobResources(p, estimate),
+	}
+	if len(o.envFrom) > 0 {
+		var from []interface{}
+		for _, s := range o.envFrom {
+			from = append(from, map[string]interface{}{"secretRef": map[string]interface{}{"name": s}})
+		}
+		container["envFrom"] = from
+	}
+	pod := map[string]interface{}{
+		"restartPolicy": "Never",
+		"containers":    []interface{}{container},
+		"volumes":       []interface{}{volume},
+	}
+	if o.serviceAccount != "" {
+		pod["serviceAccountName"] = o.serviceAccount
+	}
+	// 설정, 스키마, 데이터 오류와 부분 성공, 이상(--fail-on-anomaly)은 다시 실행해도 같으므로
+	// 연결 오류와 내부 오류만 재시도합니다.
+	job := map[string]interface{}{
+		"backoffLimit": 3,
+		"podFailurePolicy": map[string]interface{}{"rules": []interface{}{map[string]interface{}{
+			"action": "FailJob",
+			"onExitCodes": map[string]interface{}{
+				"containerName": "es-schema",
+				"operator":      "In",
+				"values":        []int{exitConfigError, exitSchemaError, exitDataError, exitPartialSuccess, exitAnomaly},
+			},
+		}}},
+		"template": map[string]interface{}{
+			"metadata": map[string]interface{}{"labels": labels},
+			"spec":     pod,
+		},
+	}
+	if estimate != nil {
+		job["activeDeadlineSeconds"] = jobDeadline(estimate)
+	}
+
+	workload := map[string]interface{}{
+		"apiVersion": "batch/v1",
+		"kind":       "Job",
+		"metadata":   meta(),
+		"spec":       job,
+	}
+	if o.kind == k8sKindCronJob {
+		schedule, err := cronSchedule(p.every, p.offset)
+		if err != nil {
+			return nil, configErrorf("k8s-job: pipeline %q: %w", p.Name, err)
+		}
+		workload["kind"] = "CronJob"
+		workload["spec"] = map[string]interface{}{
+			"schedule":          schedule,
+			"timeZone":          "Etc/UTC",
+			"concurrencyPolicy": "Forbid",
+			"jobTemplate":       map[string]interface{}{"metadata": map[string]interface{}{"labels": labels}, "spec": job},
+		}
+	}
+	return []interface{}{config, workload}, nil
+}
+
+var k8sNameInvalid = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// k8sName 함수는 파이프라인 이름을 객체 이름으로 씁니다. CronJob이 만드는 Job 이름에
+// 접미사 11자가 붙으므로 52자로 자릅니다.
+func k8sName(pipeline string) string {
+	name := "es-schema-" + k8sNameInvalid.ReplaceAllString(strings.ToLower(pipeline), "-")
+	if len(name) > 52 {
+		name = name[:52]
+	}
+	return strings.TrimRight(name, "-")
+}
+
+// cronSchedule 함수는 파이프라인의 일정을 UTC cron 식으로 바꿉니다. daemon은 UTC 자정(주
+// 간격은 월요일 자정)부터 every마다 offset을 더해 실행하므로, 한 시간을 나누는 분 간격, 하루를
+// 나누는 시간 간격과 하루, 한 주만 같은 시각의 cron 식이 있습니다.
+func cronSchedule(every, offset time.Duration) (string, error) {
+	if every%time.Minute != 0 || offset%time.Minute != 0 {
+		return "", fmt.Errorf("every %s and offset %s must be whole minutes for a CronJob", every, offset)
+	}
+	m := int(offset % time.Hour / time.Minute)
+	h := int(offset % (24 * time.Hour) / time.Hour)
+	switch {
+	case every < time.Hour && time.Hour%every == 0:
+		return fmt.Sprintf("%d/%d * * * *", int(offset/time.Minute), int(every/time.Minute)), nil
+	case every < 24*time.Hour && every%time.Hour == 0 && 24*time.Hour%every == 0:
+		return fmt.Sprintf("%d %d/%d * * *", m, int(offset/time.Hour), int(every/time.Hour)), nil
+	case every == 24*time.Hour:
+		return fmt.Sprintf("%d %d * * *", m, h), nil
+	case every == 7*24*time.Hour:
+		return fmt.Sprintf("%d %d * * %d", m, h, (1+int(offset/(24*time.Hour)))%7), nil
+	}
+	return "", fmt.Errorf("every %s has no cron equivalent; use --kind job with your own scheduler, or run the daemon", every)
+}
+
+// jobResources 함수는 컨테이너의 자원 요청을 정합니다. 메모리는 동시에 처리하는 스크롤
+// 페이지(--workers × --scroll-size 문서)의 _source와 그 JSON 값, Arrow 버퍼가 함께 있다고 보아
+// 표본의 문서당 _source 크기의 네 배에 기본 256MiB를 더하고, 상한은 그 두 배입니다. 추정치가
+// 없으면 기본값만 요청합니다.
+func jobResources(p *pipelineSpec, estimate *outputEstimate) map[string]interface{} {
+	workers, pageSize := 1, 1000
+	if p.Command == "export" {
+		workers = intSetting(p.Flags, "workers", 4)
+		pageSize = intSetting(p.Flags, "scroll-size", pageSize)
+	}
+	memory := int64(256 << 20)
+	if estimate != nil && estimate.SampleDocuments > 0 {
+		perDoc := float64(estimate.SampleSourceBytes) / float64(estimate.SampleDocuments)
+		memory += int64(4 * perDoc * float64(workers*pageSize))
+	}
+	mib := (memory + 1<<20 - 1) >> 20
+	return map[string]interface{}{
+		"requests": map[string]interface{}{
+			"cpu":    fmt.Sprintf("%dm", 250*(workers+1)),
+			"memory": fmt.Sprintf("%dMi", mib),
+		},
+		"limits": map[string]interface{}{"memory": fmt.Sprintf("%dMi", 2*mib)},
+	}
+}
+
+// jobDeadline 함수는 추정 소요 시간의 세 배를 Job의 제한 시간으로 씁니다. 표본은 한 번의
+// 검색으로 재므로 짧은 실행도 10분은 줍니다.
+func jobDeadline(e *outputEstimate) int64 {
+	return int64(math.Max(600, math.Ceil(3*e.Seconds)))
+}
+
+// intSetting 함수는 파이프라인 플래그의 정수 값을 읽습니다. 없거나 정수가 아니면 def입니다.
+func intSetting(flags map[string]interface{}, key string, def int) int {
+	s, err := profileValue(flags[key])
+	if err != nil || s == "" {
+		return def
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		return def
+	}
+	return n
+}
+
+// writeYAML 함수는 v를 JSON으로 바꾼 뒤 블록 YAML로 씁니다. 키는 encoding/json처럼
+// 정렬하고, 따옴표가 필요한 문자열은 JSON 문자열로, 여러 줄 문자열은 | 블록으로 씁니다.
+func writeYAML(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return err
+	}
+	var b strings.Builder
+	writeYAMLValue(&b, value, 0)
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+func writeYAMLValue(b *strings.Builder, v interface{}, indent int) {
+	pad := strings.Repeat(" ", indent)
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			b.WriteString(pad + yamlString(k) + ":")
+			writeYAMLChild(b, v[k], indent)
+		}
+	case []interface{}:
+		for _, item := range v {
+			if isYAMLBlock(item) {
+				// 블록 원소는 들여 쓴 뒤 첫 줄의 들여쓰기를 "- "로 바꿉니다.
+				var inner strings.Builder
+				writeYAMLValue(&inner, item, indent+2)
+				b.WriteString(pad + "- " + inner.String()[indent+2:])
+				continue
+			}
+			b.WriteString(pad + "-")
+			writeYAMLChild(b, item, indent)
+		}
+	}
+}
+
+// writeYAMLChild 함수는 "key:"나 "-" 뒤에 오는 값을 씁니다.
+func writeYAMLChild(b *strings.Builder, v interface{}, indent int) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			b.WriteString(" {}\n")
+			return
+		}
+		b.WriteString("\n")
+		writeYAMLValue(b, v, indent+2)
+	case []interface{}:
+		if len(v) == 0 {
+			b.WriteString(" []\n")
+			return
+		}
+		b.WriteString("\n")
+		writeYAMLValue(b, v, indent+2)
+	case string:
+		if strings.Contains(v, "\n") && !strings.ContainsAny(v, "\r\t") && !strings.HasPrefix(v, " ") {
+			chomp := "|"
+			if !strings.HasSuffix(v, "\n") {
+				chomp = "|-"
+			}
+			b.WriteString(" " + chomp + "\n")
+			pad := strings.Repeat(" ", indent+2)
+			for _, line := range strings.Split(strings.TrimSuffix(v, "\n"), "\n") {
+				if line == "" {
+					b.WriteString("\n")
+					continue
+				}
+				b.WriteString(pad + line + "\n")
+			}
+			return
+		}
+		b.WriteString(" " + yamlString(v) + "\n")
+	case json.Number:
+		b.WriteString(" " + v.String() + "\n")
+	case bool:
+		b.WriteString(" " + strconv.FormatBool(v) + "\n")
+	default:
+		b.WriteString(" null\n")
+	}
+}
+
+func isYAMLBlock(v interface{}) bool {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return len(v) > 0
+	case []interface{}:
+		return len(v) > 0
+	}
+	return false
+}
+
+var yamlPlain = regexp.MustCompile(`^[A-Za-z_./][A-Za-z0-9_./-]*$`)
+
+// yamlString 함수는 문자열을 그대로 쓸 수 있으면 그대로, 아니면 JSON 문자열로 씁니다.
+// true, null처럼 다른 값으로 읽히는 낱말은 따옴표로 감쌉니다.
+func yamlString(s string) string {
+	switch strings.ToLower(s) {
+	case "true", "false", "null", "yes", "no", "on", "off", "y", "n":
+	default:
+		if yamlPlain.MatchString(s) {
+			return s
+		}
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCronSchedule(t *testing.T) {
+	cases := []struct {
+		every, offset time.Duration
+		want          string
+	}{
+		{15 * time.Minute, 5 * time.Minute, "5/15 * * * *"},
+		{time.Hour, 30 * time.Minute, "30 0/1 * * *"},
+		{6 * time.Hour, 2*time.Hour + 10*time.Minute, "10 2/6 * * *"},
+		{24 * time.Hour, 2 * time.Hour, "0 2 * * *"},
+		{7 * 24 * time.Hour, 6*24*time.Hour + 3*time.Hour, "0 3 * * 0"},
+	}
+	for _, c := range cases {
+		got, err := cronSchedule(c.every, c.offset)
+		if err != nil || got != c.want {
+			t.Errorf("cronSchedule(%s, %s) = %q, %v; want %q", c.every, c.offset, got, err, c.want)
+		}
+		// cron 식의 첫 실행이 daemon의 실행 시각과 같은지 봅니다.
+		p := &pipelineSpec{every: c.every, offset: c.offset}
+		if next := p.nextRun(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC).Add(-time.Nanosecond)); next.Minute() != int(c.offset%time.Hour/time.Minute) {
+			t.Errorf("daemon runs %s/%s at %s", c.every, c.offset, next)
+		}
+	}
+	for _, every := range []time.Duration{7 * time.Minute, 5 * time.Hour, 48 * time.Hour, 90 * time.Second} {
+		if got, err := cronSchedule(every, 0); err == nil {
+			t.Errorf("cronSchedule(%s) = %q, want an error", every, got)
+		}
+	}
+}
+
+func TestWriteYAML(t *testing.T) {
+	v := map[string]interface{}{
+		"name":  "logs",
+		"index": []interface{}{"logs-*", "metrics"},
+		"containers": []interface{}{
+			map[string]interface{}{"name": "es-schema", "args": []string{"export", "--profile", "true"}},
+		},
+		"backoffLimit": 3,
+		"query":        `{"term": {"level": "error"}}`,
+		"empty":        map[string]interface{}{},
+		"readOnly":     true,
+		"port":         "8080",
+	}
+	var buf bytes.Buffer
+	if err := writeYAML(&buf, v); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"    name: es-schema\n",
+		"  - args:\n      - export\n",
+		`    - "true"`,
+		`port: "8080"`,
+		`query: "{\"term\": {\"level\": \"error\"}}"`,
+		"empty: {}\n",
+		`  - "logs-*"`,
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("YAML missing %q:\n%s", line, buf.String())
+		}
+	}
+	// 여러 줄 문자열이 없으면 설정 파일 파서로 다시 읽을 수 있습니다.
+	got, err := parseYAML(buf.Bytes())
+	if err != nil {
+		t.Fatalf("parsing:\n%s\n%v", buf.String(), err)
+	}
+	if got.(map[string]interface{})["query"] != v["query"] || got.(map[string]interface{})["backoffLimit"] != 3.0 {
+		t.Errorf("round trip = %v", got)
+	}
+
+	buf.Reset()
+	writeYAML(&buf, map[string]interface{}{"data": map[string]string{"config": "profiles:\n  a: 1\n"}})
+	if want := "data:\n  config: |\n    profiles:\n      a: 1\n"; buf.String() != want {
+		t.Errorf("block string =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestK8sJobManifests(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pipeline.yaml")
+	os.WriteFile(path, []byte(`
+pipelines:
+  - name: Logs_Nightly
+    every: 24h
+    offset: 2h
+    flags:
+      url: https://es-prod:9200
+      api-key: ${file:/run/secrets/es-api-key}
+      index: [logs-*]
+      workers: 2
+  - name: metrics
+    every: 1h
+    flags:
+      index: metrics
+      password: hunter2
+`), 0o644)
+	specs, err := loadDaemonConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := k8sJobOptions{config: path, kind: k8sKindCronJob, image: "registry/es-schema:1.4", namespace: "data"}
+	estimate := &outputEstimate{Documents: 1000000, SampleDocuments: 1000, SampleSourceBytes: 2 << 20, Seconds: 1800}
+	report := newRunReport("k8s-job")
+	objects, err := o.manifests(specs["Logs_Nightly"], estimate, report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, cron := objects[0].(map[string]interface{}), objects[1].(map[string]interface{})
+	if config["kind"] != "ConfigMap" || cron["kind"] != "CronJob" {
+		t.Fatalf("kinds = %v, %v", config["kind"], cron["kind"])
+	}
+	if name := cron["metadata"].(map[string]interface{})["name"]; name != "es-schema-logs-nightly" {
+		t.Errorf("name = %v", name)
+	}
+	spec := cron["spec"].(map[string]interface{})
+	if spec["schedule"] != "0 2 * * *" || spec["concurrencyPolicy"] != "Forbid" {
+		t.Errorf("cron spec = %v", spec)
+	}
+	job := spec["jobTemplate"].(map[string]interface{})["spec"].(map[string]interface{})
+	if job["activeDeadlineSeconds"] != int64(5400) {
+		t.Errorf("deadline = %v", job["activeDeadlineSeconds"])
+	}
+	container := job["template"].(map[string]interface{})["spec"].(map[string]interface{})["containers"].([]interface{})[0].(map[string]interface{})
+	if !reflect.DeepEqual(container["args"], []string{"export", "--profile", "Logs_Nightly"}) {
+		t.Errorf("args = %v", container["args"])
+	}
+	// 2 workers × 1000 문서 × 2KiB × 4 + 256MiB
+	resources := container["resources"].(map[string]interface{})
+	if mem := resources["requests"].(map[string]interface{})["memory"]; mem != "272Mi" {
+		t.Errorf("memory request = %v", mem)
+	}
+
+	// 설정 객체의 프로필을 그대로 프로필로 읽을 수 있어야 합니다.
+	profile := filepath.Join(dir, "config")
+	os.WriteFile(profile, []byte(config["data"].(map[string]interface{})["config"].(string)), 0o600)
+	settings, err := loadProfile(profile, "Logs_Nightly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(settings, specs["Logs_Nightly"].Flags) {
+		t.Errorf("embedded profile = %v, want %v", settings, specs["Logs_Nightly"].Flags)
+	}
+	if len(report.Warnings) != 0 {
+		t.Errorf("warnings = %v", report.Warnings)
+	}
+
+	// 비밀 값을 그대로 담은 파이프라인은 Secret이 됩니다.
+	objects, err = o.manifests(specs["metrics"], nil, report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kind := objects[0].(map[string]interface{})["kind"]; kind != "Secret" || len(report.Warnings) != 1 {
+		t.Errorf("kind = %v, warnings = %v", kind, report.Warnings)
+	}
+	if spec := objects[1].(map[string]interface{})["spec"].(map[string]interface{}); spec["schedule"] != "0 0/1 * * *" {
+		t.Errorf("schedule = %v", spec["schedule"])
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// keywordFilters는 --apply-normalizers가 흉내 낼 수 있는 토큰 필터입니다. 노멀라이저는
+// keyword 값 전체를 토큰 하나로 다루므로 문자열 함수로 옮길 수 있습니다.
+var keywordFilters = map[string]func(string) string{
+	"lowercase":    strings.ToLower,
+	"uppercase":    strings.ToUpper,
+	"asciifolding": foldASCII,
+	"trim":         strings.TrimSpace,
+}
+
+// builtinNormalizers는 설정 없이 쓸 수 있는 Elasticsearch 내장 노멀라이저입니다.
+var builtinNormalizers = map[string][]string{
+	"lowercase": {"lowercase"},
+}
+
+// asciiFolds는 asciifolding 필터 중 라틴 문자(Latin-1 보충과 Latin Extended-A)에 해당하는
+// 부분입니다. 그 밖의 문자는 그대로 둡니다.
+var asciiFolds = func() map[rune]string {
+	groups := []struct{ to, from string }{
+		{"A", "ÀÁÂÃÄÅĀĂĄ"}, {"a", "àáâãäåāăą"}, {"AE", "Æ"}, {"ae", "æ"},
+		{"C", "ÇĆĈĊČ"}, {"c", "çćĉċč"}, {"D", "ÐĎĐ"}, {"d", "ðďđ"},
+		{"E", "ÈÉÊËĒĔĖĘĚ"}, {"e", "èéêëēĕėęě"}, {"G", "ĜĞĠĢ"}, {"g", "ĝğġģ"},
+		{"H", "ĤĦ"}, {"h", "ĥħ"}, {"I", "ÌÍÎÏĨĪĬĮİ"}, {"i", "ìíîïĩīĭįı"},
+		{"IJ", "Ĳ"}, {"ij", "ĳ"}, {"J", "Ĵ"}, {"j", "ĵ"}, {"K", "Ķ"}, {"k", "ķĸ"},
+		{"L", "ĹĻĽĿŁ"}, {"l", "ĺļľŀł"}, {"N", "ÑŃŅŇŊ"}, {"n", "ñńņňŉŋ"},
+		{"O", "ÒÓÔÕÖØŌŎŐ"}, {"o", "òóôõöøōŏő"}, {"OE", "Œ"}, {"oe", "œ"},
+		{"R", "ŔŖŘ"}, {"r", "ŕŗř"}, {"S", "ŚŜŞŠ"}, {"s", "śŝşšſ"}, {"ss", "ß"},
+		{"T", "ŢŤŦ"}, {"t", "ţťŧ"}, {"TH", "Þ"}, {"th", "þ"},
+		{"U", "ÙÚÛÜŨŪŬŮŰŲ"}, {"u", "ùúûüũūŭůűų"}, {"W", "Ŵ"}, {"w", "ŵ"},
+		{"Y", "ÝŶŸ"}, {"y", "ýÿŷ"}, {"Z", "ŹŻŽ"}, {"z", "źżž"},
+	}
+	folds := make(map[rune]string)
+	for _, g := range groups {
+		for _, r := range g.from {
+			folds[r] = g.to
+		}
+	}
+	return folds
+}()
+
+// foldASCII 함수는 악센트가 붙은 라틴 문자를 ASCII 문자로 바꿉니다.
+func foldASCII(s string) string {
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for _, r := range s {
+		if to, ok := asciiFolds[r]; ok {
+			sb.WriteString(to)
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// keywordNormalizer 함수는 settings의 노멀라이저 name과 같은 일을 하는 함수를 만듭니다.
+// 흉내 낼 수 없는 필터나 문자 필터가 있으면 오류를 반환합니다. 일부만 적용하면 검색에서
+// 보이는 값과 어긋나기 때문입니다.
+func keywordNormalizer(name string, settings *analysisSettings) (func(string) string, error) {
+	filters, ok := builtinNormalizers[name]
+	if def, custom := settings.Normalizer[name]; custom {
+		if len(def.CharFilter) > 0 {
+			return nil, fmt.Errorf("normalizer %s uses char_filter %s, which cannot be applied outside Elasticsearch", name, strings.Join(def.CharFilter, ", "))
+		}
+		filters, ok = def.Filter, true
+	}
+	if !ok {
+		return nil, fmt.Errorf("normalizer %s is not defined in the index settings", name)
+	}
+	fns := make([]func(string) string, 0, len(filters))
+	for _, f := range filters {
+		typ := f
+		if def, custom := settings.Filter[f]; custom {
+			typ = def.Type
+		}
+		fn, ok := keywordFilters[typ]
+		if !ok {
+			return nil, fmt.Errorf("normalizer %s uses filter %s, which es-schema cannot apply", name, f)
+		}
+		fns = append(fns, fn)
+	}
+	return func(s string) string {
+		for _, fn := range fns {
+			s = fn(s)
+		}
+		return s
+	}, nil
+}
+
+// normalizedKeywords 함수는 매핑에서 normalizer가 있는 keyword 필드의 경로와 노멀라이저
+// 이름을 찾습니다.
+func normalizedKeywords(props map[string]interface{}, prefix string, out map[string]string) {
+	for name, v := range props {
+		field, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if sub, ok := field["properties"].(map[string]interface{}); ok {
+			normalizedKeywords(sub, prefix+name+".", out)
+			continue
+		}
+		if field["type"] != "keyword" {
+			continue
+		}
+		if normalizer, ok := field["normalizer"].(string); ok {
+			out[prefix+name] = normalizer
+		}
+	}
+}
+
+// normalizerTransform 함수는 fields(경로에서 노멀라이저로의 맵)의 문자열 값에 노멀라이저를
+// 적용하는 변환을 만듭니다. 배열과 객체 배열 안의 값에도 적용합니다.
+func normalizerTransform(fields map[string]func(string) string) docTransform {
+	paths := make([]string, 0, len(fields))
+	for p := range fields {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return func(doc map[string]interface{}) (map[string]interface{}, error) {
+		for _, p := range paths {
+			applyAtPath(doc, strings.Split(p, "."), fields[p])
+		}
+		return doc, nil
+	}
+}
+
+func applyAtPath(v interface{}, path []string, fn func(string) string) interface{} {
+	switch x := v.(type) {
+	case []interface{}:
+		for i, item := range x {
+			x[i] = applyAtPath(item, path, fn)
+		}
+		return x
+	case map[string]interface{}:
+		if len(path) == 0 {
+			return x
+		}
+		if child, ok := x[path[0]]; ok {
+			x[path[0]] = applyAtPath(child, path[1:], fn)
+		}
+		return x
+	case string:
+		if len(path) == 0 {
+			return fn(x)
+		}
+	}
+	return v
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeywordNormalizer(t *testing.T) {
+	settings := &analysisSettings{
+		Normalizer: map[string]normalizerDef{
+			"folded":  {Type: "custom", Filter: []string{"lowercase", "my_folding"}},
+			"stemmed": {Type: "custom", Filter: []string{"porter_stem"}},
+		},
+		Filter: map[string]filterDef{"my_folding": {Type: "asciifolding"}},
+	}
+
+	fn, err := keywordNormalizer("folded", settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fn("Crème BRÛLÉE Straße"); got != "creme brulee strasse" {
+		t.Errorf("folded = %q", got)
+	}
+	if fn, err := keywordNormalizer("lowercase", settings); err != nil || fn("ABC") != "abc" {
+		t.Errorf("built-in lowercase normalizer: %v", err)
+	}
+	for _, name := range []string{"stemmed", "missing"} {
+		if _, err := keywordNormalizer(name, settings); err == nil {
+			t.Errorf("normalizer %s accepted", name)
+		}
+	}
+
+	doc, _ := decodeDocument([]byte(`{"tag": "ABC", "items": [{"sku": "X-1"}, {"sku": ["Y-2", 3]}]}`))
+	transform := normalizerTransform(map[string]func(string) string{"tag": fn, "items.sku": fn})
+	out, _ := transform(doc)
+	want, _ := decodeDocument([]byte(`{"tag": "abc", "items": [{"sku": "x-1"}, {"sku": ["y-2", 3]}]}`))
+	if !reflect.DeepEqual(out, want) {
+		t.Errorf("normalized document = %v", out)
+	}
+}
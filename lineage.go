@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+// lineageKey는 컬럼 계보를 JSON으로 담는 스키마 메타데이터 키입니다. 데이터 카탈로그가
+// 파일만 읽고도 컬럼마다 어느 Elasticsearch 필드에서 어떻게 만들었는지 보여 줄 수 있습니다.
+const lineageKey = "es_schema.lineage"
+
+// 컬럼을 만든 방법입니다.
+const (
+	ruleCopy       = "copy"
+	ruleMetadata   = "metadata"
+	ruleDictionary = "dictionary"
+	ruleJSON       = "json"
+	ruleWKB        = "wkb"
+	ruleLatitude   = "latitude"
+	ruleLongitude  = "longitude"
+	ruleTokens     = "tokens"
+	ruleOverflow   = "overflow"
+)
+
+// columnLineage는 출력 Parquet 컬럼(구조체 안의 잎 필드는 점으로 이은 경로) 하나의 계보입니다.
+// Source는 값을 가져온 Elasticsearch 필드 경로로, overflow 컬럼처럼 한 필드에서 오지 않으면
+// 비어 있습니다. Rule은 값을 만든 방법이고, Overrides는 기본 변환을 바꾼 플래그입니다.
+type columnLineage struct {
+	Column    string   `json:"column"`
+	Source    string   `json:"source,omitempty"`
+	ESType    string   `json:"es_type,omitempty"`
+	ArrowType string   `json:"arrow_type"`
+	Rule      string   `json:"rule"`
+	Overrides []string `json:"overrides,omitempty"`
+}
+
+// lineageFile은 --lineage가 인덱스마다 쓰는 파일입니다.
+type lineageFile struct {
+	Index   string          `json:"index"`
+	Columns []columnLineage `json:"columns"`
+}
+
+// schemaLineage 함수는 schema의 컬럼마다 계보를 만듭니다. overrides는 원래 필드 경로별로
+// 그 필드(객체이면 하위 필드 모두)의 변환을 바꾼 플래그입니다.
+func schemaLineage(schema *arrow.Schema, mapping []byte, overrides map[string][]string) ([]columnLineage, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal(mapping, &m); err != nil {
+		return nil, schemaErrorf("decoding mapping: %w", err)
+	}
+	props, _ := m["properties"].(map[string]interface{})
+	types := make(map[string]string)
+	mappingTypes(props, "", types)
+
+	var out []columnLineage
+	for _, f := range schema.Fields() {
+		if isMetadataColumn(f.Name) || f.Name == deletedColumn || f.Name == docHashColumn {
+			out = append(out, columnLineage{Column: f.Name, Source: f.Name, ArrowType: fmt.Sprint(f.Type), Rule: ruleMetadata})
+			continue
+		}
+		fieldLineage(f, "", "", types, &out)
+	}
+	for i := range out {
+		l := &out[i]
+		for path, flags := range overrides {
+			if l.Source == path || strings.HasPrefix(l.Source, path+".") {
+				l.Overrides = append(l.Overrides, flags...)
+			}
+		}
+		sort.Strings(l.Overrides)
+	}
+	return out, nil
+}
+
+// fieldLineage 함수는 f의 잎 컬럼마다 계보를 out에 더합니다. 구조체와 구조체 리스트는
+// 하위 필드로 내려갑니다.
+func fieldLineage(f arrow.Field, column, source string, types map[string]string, out *[]columnLineage) {
+	column += f.Name
+	source += originalName(f)
+	if st, ok := structElem(f.Type); ok && !isJSONColumn(f) {
+		for _, c := range st.Fields() {
+			fieldLineage(c, column+".", source+".", types, out)
+		}
+		return
+	}
+	l := columnLineage{Column: column, Source: source, ArrowType: fmt.Sprint(f.Type), Rule: ruleCopy}
+	if column != source {
+		// 이름 정리 옵션(--sanitize-names 등)이 바꾼 경로입니다.
+		l.Overrides = []string{"renamed"}
+	}
+	if idx := f.Metadata.FindKey(derivedFromKey); idx >= 0 {
+		l.Source = f.Metadata.Values()[idx]
+	}
+	switch {
+	case isOverflowColumn(f):
+		l.Source, l.Rule = "", ruleOverflow
+	case isTokenColumn(f):
+		l.Rule = ruleTokens
+	case isDerivedColumn(f) && strings.HasSuffix(f.Name, "_lat"):
+		l.Rule = ruleLatitude
+	case isDerivedColumn(f):
+		l.Rule = ruleLongitude
+	case f.Metadata.FindKey(geoTypeKey) >= 0:
+		l.Rule = ruleWKB
+	case isJSONColumn(f):
+		l.Rule = ruleJSON
+	case isDictionaryLeaf(f.Type):
+		l.Rule = ruleDictionary
+	}
+	l.ESType = types[l.Source]
+	*out = append(*out, l)
+}
+
+// structElem 함수는 구조체 타입이나 구조체 리스트 타입의 구조체를 반환합니다.
+func structElem(t arrow.DataType) (*arrow.StructType, bool) {
+	if lt, ok := t.(*arrow.ListType); ok {
+		t = lt.Elem()
+	}
+	st, ok := t.(*arrow.StructType)
+	return st, ok
+}
+
+func isDictionaryLeaf(t arrow.DataType) bool {
+	if lt, ok := t.(*arrow.ListType); ok {
+		t = lt.Elem()
+	}
+	return t.ID() == arrow.DICTIONARY
+}
+
+// mappingTypes 함수는 properties 아래 필드의 경로별 타입을 out에 모읍니다. 타입 없이
+// properties만 있는 필드는 object입니다.
+func mappingTypes(props map[string]interface{}, prefix string, out map[string]string) {
+	for name, v := range props {
+		field, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		t, _ := field["type"].(string)
+		sub, hasProps := field["properties"].(map[string]interface{})
+		if t == "" && hasProps {
+			t = "object"
+		}
+		out[prefix+name] = t
+		if hasProps {
+			mappingTypes(sub, prefix+name+".", out)
+		}
+	}
+}
+
+// writeLineageFile 함수는 index의 컬럼 계보를 path에 JSON으로 씁니다.
+func writeLineageFile(path, index string, columns []columnLineage) error {
+	data, err := json.MarshalIndent(lineageFile{Index: index, Columns: columns}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return configErrorf("writing lineage file: %w", err)
+	}
+	return nil
+}
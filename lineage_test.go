@@ -0,0 +1,42 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+func TestSchemaLineage(t *testing.T) {
+	mapping := []byte(`{"properties": {
+		"user": {"properties": {"first.name": {"type": "keyword"}, "tags": {"type": "keyword"}}},
+		"loc": {"type": "geo_point"},
+		"body": {"type": "text"}
+	}}`)
+	renamed := arrow.NewMetadata([]string{originalNameKey}, []string{"first.name"})
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "_id", Type: arrow.BinaryTypes.String},
+		{Name: "user", Type: arrow.StructOf(
+			arrow.Field{Name: "first_name", Type: arrow.BinaryTypes.String, Metadata: renamed},
+			arrow.Field{Name: "tags", Type: arrow.ListOf(arrow.BinaryTypes.String)},
+		)},
+		{Name: "loc", Type: arrow.BinaryTypes.Binary, Metadata: arrow.NewMetadata([]string{geoTypeKey}, []string{"geo_point"})},
+		{Name: "loc_lat", Type: arrow.PrimitiveTypes.Float64, Metadata: arrow.NewMetadata([]string{derivedFromKey}, []string{"loc"})},
+		tokenField(analyzeSpec{path: "body", analyzer: "standard"}),
+	}, nil)
+	got, err := schemaLineage(schema, mapping, map[string][]string{"user": {"--list-fields"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []columnLineage{
+		{Column: "_id", Source: "_id", ArrowType: "utf8", Rule: ruleMetadata},
+		{Column: "user.first_name", Source: "user.first.name", ESType: "keyword", ArrowType: "utf8", Rule: ruleCopy, Overrides: []string{"--list-fields", "renamed"}},
+		{Column: "user.tags", Source: "user.tags", ESType: "keyword", ArrowType: "list<item: utf8, nullable>", Rule: ruleCopy, Overrides: []string{"--list-fields"}},
+		{Column: "loc", Source: "loc", ESType: "geo_point", ArrowType: "binary", Rule: ruleWKB},
+		{Column: "loc_lat", Source: "loc", ESType: "geo_point", ArrowType: "float64", Rule: ruleLatitude},
+		{Column: "body_tokens", Source: "body", ESType: "text", ArrowType: "list<item: utf8, nullable>", Rule: ruleTokens},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("lineage =\n%+v\nwant\n%+v", got, want)
+	}
+}
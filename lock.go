@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const (
+	lockNone = "none"
+	lockFile = "file"
+	lockES   = "es"
+)
+
+// lockRecord는 파이프라인 잠금의 내용입니다. Slot은 예약 실행의 실행 시각으로, 같은 시각을
+// 다른 복제본이 이미 실행했으면 잠금이 풀린 뒤에도 다시 실행하지 않습니다. API로 제출한
+// 작업은 Slot이 비어 있어 서로 겹치지만 않게 합니다.
+type lockRecord struct {
+	Owner   string    `json:"owner"`
+	Slot    time.Time `json:"slot,omitempty"`
+	Expires time.Time `json:"expires"`
+}
+
+// blocks 함수는 r이 남아 있을 때 want가 잠금을 잡을 수 없는지 알려 줍니다.
+func (r lockRecord) blocks(want lockRecord, now time.Time) bool {
+	if now.Before(r.Expires) {
+		return r.Owner != want.Owner
+	}
+	return !want.Slot.IsZero() && !r.Slot.Before(want.Slot)
+}
+
+// lockHeldError는 다른 복제본이 파이프라인을 실행하고 있거나 이미 실행한 경우입니다.
+type lockHeldError struct {
+	name string
+	held lockRecord
+}
+
+func (e *lockHeldError) Error() string {
+	if time.Now().Before(e.held.Expires) {
+		return fmt.Sprintf("pipeline %s is locked by %s until %s", e.name, e.held.Owner, e.held.Expires.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("pipeline %s already ran for %s on %s", e.name, e.held.Slot.Format(time.RFC3339), e.held.Owner)
+}
+
+// pipelineLocker는 여러 daemon 복제본이 한 파이프라인을 동시에 실행하지 않게 하는 잠금입니다.
+// 잠금은 Expires까지 유효한 임대로, 실행하는 동안 renew로 늘리고 끝나면 release로 풉니다.
+// 복제본이 죽으면 임대가 끝난 뒤 다른 복제본이 잡을 수 있습니다.
+type pipelineLocker interface {
+	// acquire는 잠금을 want로 잡습니다. 잡을 수 없으면 *lockHeldError를 반환합니다.
+	acquire(ctx context.Context, name string, want lockRecord) error
+	// renew는 want.Owner가 잡고 있는 잠금을 want로 바꿉니다.
+	renew(ctx context.Context, name string, want lockRecord) error
+	// release는 want.Owner가 잡고 있는 잠금을 풀되 Slot은 남깁니다.
+	release(ctx context.Context, name string, want lockRecord) error
+}
+
+// lockOptions는 daemon의 잠금 설정입니다.
+type lockOptions struct {
+	kind  string
+	dir   string
+	index string
+	es    esOptions
+	ttl   time.Duration
+	owner string
+}
+
+func (o *lockOptions) bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.kind, "lock", lockNone, "keep replicas from running the same pipeline twice: none, file (a lock file per pipeline in --lock-dir on a shared volume) or es (a lock document per pipeline in --lock-index)")
+	fs.StringVar(&o.dir, "lock-dir", "", "shared directory for --lock file")
+	fs.StringVar(&o.index, "lock-index", "es-schema-locks", "index for --lock es")
+	o.es.bind(fs, "lock-", "lock")
+	fs.DurationVar(&o.ttl, "lock-ttl", 2*time.Minute, "lease of a pipeline lock; a running job renews it every third of this, and a crashed replica's lock can be taken over after it")
+	fs.StringVar(&o.owner, "replica-id", "", "name of this replica in locks (default the host name, which is the pod name in Kubernetes)")
+}
+
+// locker 함수는 설정에 맞는 잠금을 만듭니다. --lock none이면 nil입니다.
+func (o *lockOptions) locker() (pipelineLocker, error) {
+	if o.kind == lockNone {
+		return nil, nil
+	}
+	if o.owner == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return nil, configErrorf("daemon: --replica-id is required: %w", err)
+		}
+		o.owner = host
+	}
+	if o.ttl < 3*time.Second {
+		return nil, configErrorf("daemon: --lock-ttl must be at least 3s")
+	}
+	switch o.kind {
+	case lockFile:
+		if o.dir == "" {
+			return nil, configErrorf("daemon: --lock file needs --lock-dir")
+		}
+		if err := os.MkdirAll(o.dir, 0o755); err != nil {
+			return nil, configErrorf("creating --lock-dir: %w", err)
+		}
+		return &fileLocker{dir: o.dir}, nil
+	case lockES:
+		client, err := o.es.client()
+		if err != nil {
+			return nil, err
+		}
+		return &esLocker{client: client, index: o.index}, nil
+	}
+	return nil, configErrorf("unknown --lock %q (want none, file or es)", o.kind)
+}
+
+// fileLocker는 공유 디렉터리의 파이프라인별 파일로 잠급니다. 새 잠금은 임시 파일을 링크해
+// 만들고(이미 있으면 실패합니다), 끝난 임대는 이름을 바꿔 치운 뒤 다시 시도하므로 NFS 같은
+// 공유 볼륨에서도 두 복제본이 함께 잡지 않습니다.
+type fileLocker struct {
+	dir string
+}
+
+func (l *fileLocker) path(name string) string {
+	return filepath.Join(l.dir, url.PathEscape(name)+".lock")
+}
+
+func (l *fileLocker) acquire(ctx context.Context, name string, want lockRecord) error {
+	path := l.path(name)
+	for attempt := 0; attempt < 3; attempt++ {
+		tmp, err := l.writeTemp(want)
+		if err != nil {
+			return err
+		}
+		err = os.Link(tmp, path)
+		os.Remove(tmp)
+		if err == nil {
+			return nil
+		}
+		if !os.IsExist(err) {
+			return configErrorf("creating lock %s: %w", path, err)
+		}
+		held, err := readLockFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if held.blocks(want, time.Now()) {
+			return &lockHeldError{name: name, held: held}
+		}
+		// 끝난 임대를 치웁니다. 그 사이 다른 복제본이 새로 잡았으면 되돌려 놓습니다.
+		stale := path + "." + url.PathEscape(want.Owner) + ".stale"
+		if err := os.Rename(path, stale); err != nil {
+			continue
+		}
+		if moved, err := readLockFile(stale); err != nil || moved != held {
+			os.Link(stale, path)
+		}
+		os.Remove(stale)
+	}
+	return &lockHeldError{name: name, held: lockRecord{Owner: "another replica", Expires: time.Now().Add(time.Second)}}
+}
+
+func (l *fileLocker) renew(ctx context.Context, name string, want lockRecord) error {
+	path := l.path(name)
+	held, err := readLockFile(path)
+	if err != nil {
+		return err
+	}
+	if held.Owner != want.Owner {
+		return fmt.Errorf("lock %s was taken over by %s", path, held.Owner)
+	}
+	tmp, err := l.writeTemp(want)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return configErrorf("renewing lock %s: %w", path, err)
+	}
+	return nil
+}
+
+func (l *fileLocker) release(ctx context.Context, name string, want lockRecord) error {
+	want.Expires = time.Now()
+	return l.renew(ctx, name, want)
+}
+
+func (l *fileLocker) writeTemp(rec lockRecord) (string, error) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(l.dir, ".lock-*")
+	if err != nil {
+		return "", configErrorf("writing lock: %w", err)
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", configErrorf("writing lock: %w", err)
+	}
+	return f.Name(), nil
+}
+
+func readLockFile(path string) (lockRecord, error) {
+	var rec lockRecord
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return rec, err
+	}
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return rec, dataErrorf("reading lock %s: %w", path, err)
+	}
+	return rec, nil
+}
+
+// esLocker는 인덱스의 파이프라인별 문서로 잠급니다. 새 잠금은 _create로 만들고, 끝난 임대와
+// 갱신은 if_seq_no와 if_primary_term으로 읽은 그대로일 때만 바꿉니다.
+type esLocker struct {
+	client *esClient
+	index  string
+}
+
+type lockDoc struct {
+	SeqNo       int64      `json:"_seq_no"`
+	PrimaryTerm int64      `json:"_primary_term"`
+	Source      lockRecord `json:"_source"`
+}
+
+func (l *esLocker) docPath(op, name string) string {
+	return "/" + url.PathEscape(l.index) + "/" + op + "/" + url.PathEscape(name)
+}
+
+func (l *esLocker) acquire(ctx context.Context, name string, want lockRecord) error {
+	err := l.client.sendJSON(ctx, http.MethodPut, l.docPath("_create", name), nil, want, nil)
+	if !isESStatus(err, http.StatusConflict) {
+		return err
+	}
+	doc, err := l.get(ctx, name)
+	if err != nil {
+		return err
+	}
+	if doc.Source.blocks(want, time.Now()) {
+		return &lockHeldError{name: name, held: doc.Source}
+	}
+	if err := l.put(ctx, name, doc, want); isESStatus(err, http.StatusConflict) {
+		return &lockHeldError{name: name, held: lockRecord{Owner: "another replica", Expires: time.Now().Add(time.Second)}}
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (l *esLocker) renew(ctx context.Context, name string, want lockRecord) error {
+	doc, err := l.get(ctx, name)
+	if err != nil {
+		return err
+	}
+	if doc.Source.Owner != want.Owner {
+		return fmt.Errorf("lock %s was taken over by %s", name, doc.Source.Owner)
+	}
+	return l.put(ctx, name, doc, want)
+}
+
+func (l *esLocker) release(ctx context.Context, name string, want lockRecord) error {
+	want.Expires = time.Now()
+	return l.renew(ctx, name, want)
+}
+
+func (l *esLocker) get(ctx context.Context, name string) (lockDoc, error) {
+	var doc lockDoc
+	err := l.client.sendJSON(ctx, http.MethodGet, l.docPath("_doc", name), nil, nil, &doc)
+	return doc, err
+}
+
+// put 함수는 doc을 읽은 뒤 아무도 바꾸지 않았을 때만 잠금 문서를 rec으로 바꿉니다.
+func (l *esLocker) put(ctx context.Context, name string, doc lockDoc, rec lockRecord) error {
+	q := url.Values{"if_seq_no": {strconv.FormatInt(doc.SeqNo, 10)}, "if_primary_term": {strconv.FormatInt(doc.PrimaryTerm, 10)}}
+	return l.client.sendJSON(ctx, http.MethodPut, l.docPath("_doc", name), q, rec, nil)
+}
+
+func isESStatus(err error, status int) bool {
+	var ee *esError
+	return errors.As(err, &ee) && ee.Status == status
+}
+
+// lockJob 함수는 파이프라인 작업의 잠금을 잡고, 실행하는 동안 임대를 늘리는 고루틴을
+// 띄운 뒤 잠금을 푸는 함수를 반환합니다. 임대를 늘리지 못하면 다른 복제본이 잡을 수 있으므로
+// 작업을 취소합니다.
+func (d *daemon) lockJob(j *daemonJob) (func(), error) {
+	if d.locker == nil || j.Pipeline == "" {
+		return func() {}, nil
+	}
+	want := lockRecord{Owner: d.owner, Slot: j.slot, Expires: time.Now().Add(d.lockTTL)}
+	if err := d.locker.acquire(j.ctx, j.Pipeline, want); err != nil {
+		return nil, err
+	}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		t := time.NewTicker(d.lockTTL / 3)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+				want.Expires = time.Now().Add(d.lockTTL)
+				if err := d.locker.renew(j.ctx, j.Pipeline, want); err != nil {
+					d.mu.Lock()
+					j.lockErr = err
+					d.mu.Unlock()
+					j.cancel()
+					return
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := d.locker.release(ctx, j.Pipeline, want); err != nil {
+			fmt.Printf("daemon: %s: releasing lock: %v\n", j.Pipeline, err)
+		}
+	}, nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testLocker 함수는 두 복제본이 같은 잠금을 두고 다투는 경우를 검사합니다.
+func testLocker(t *testing.T, l pipelineLocker) {
+	ctx := context.Background()
+	slot := time.Date(2024, 5, 1, 3, 0, 0, 0, time.UTC)
+	a := lockRecord{Owner: "pod-a", Slot: slot, Expires: time.Now().Add(time.Minute)}
+	b := lockRecord{Owner: "pod-b", Slot: slot, Expires: time.Now().Add(time.Minute)}
+	held := func(err error) bool {
+		var h *lockHeldError
+		return errors.As(err, &h)
+	}
+
+	if err := l.acquire(ctx, "logs/daily", a); err != nil {
+		t.Fatalf("first acquire: %v", err)
+	}
+	if err := l.acquire(ctx, "logs/daily", b); !held(err) {
+		t.Errorf("acquire while held = %v, want lockHeldError", err)
+	}
+	if err := l.renew(ctx, "logs/daily", a); err != nil {
+		t.Errorf("renew: %v", err)
+	}
+	if err := l.renew(ctx, "logs/daily", b); err == nil {
+		t.Error("renew by another owner succeeded")
+	}
+	if err := l.release(ctx, "logs/daily", a); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	// 풀린 뒤에도 같은 예약 시각은 다시 돌리지 않습니다.
+	if err := l.acquire(ctx, "logs/daily", b); !held(err) || !strings.Contains(err.Error(), "already ran") {
+		t.Errorf("acquire of a finished slot = %v", err)
+	}
+	b.Slot = slot.Add(24 * time.Hour)
+	if err := l.acquire(ctx, "logs/daily", b); err != nil {
+		t.Errorf("acquire of the next slot: %v", err)
+	}
+
+	// 죽은 복제본의 임대가 끝나면 다른 복제본이 이어받고, 죽은 쪽은 더 늘리지 못합니다.
+	dead := lockRecord{Owner: "pod-a", Expires: time.Now().Add(-time.Second)}
+	if err := l.acquire(ctx, "metrics", dead); err != nil {
+		t.Fatal(err)
+	}
+	adhoc := lockRecord{Owner: "pod-b", Expires: time.Now().Add(time.Minute)}
+	if err := l.acquire(ctx, "metrics", adhoc); err != nil {
+		t.Errorf("taking over an expired lock: %v", err)
+	}
+	if err := l.renew(ctx, "metrics", dead); err == nil || !strings.Contains(err.Error(), "taken over by pod-b") {
+		t.Errorf("renew after takeover = %v", err)
+	}
+}
+
+func TestFileLocker(t *testing.T) {
+	dir := t.TempDir()
+	testLocker(t, &fileLocker{dir: dir})
+	if _, err := os.Stat(filepath.Join(dir, "logs%2Fdaily.lock")); err != nil {
+		t.Errorf("lock file: %v", err)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(dir, ".lock-*")); len(leftovers) > 0 {
+		t.Errorf("temporary files left behind: %v", leftovers)
+	}
+}
+
+// lockCluster는 _create와 if_seq_no를 흉내 내는 잠금 인덱스입니다.
+type lockCluster struct {
+	mu    sync.Mutex
+	docs  map[string]json.RawMessage
+	seqNo map[string]int64
+	next  int64
+}
+
+func (c *lockCluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	parts := strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/")
+	if len(parts) != 3 || parts[0] != "es-schema-locks" {
+		http.Error(w, `{"error": {"type": "bad_request"}}`, http.StatusBadRequest)
+		return
+	}
+	id := parts[2]
+	conflict := func() {
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprint(w, `{"error": {"type": "version_conflict_engine_exception", "reason": "conflict"}, "status": 409}`)
+	}
+	switch {
+	case r.Method == http.MethodGet:
+		doc, ok := c.docs[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"found": false}`)
+			return
+		}
+		fmt.Fprintf(w, `{"_seq_no": %d, "_primary_term": 1, "_source": %s}`, c.seqNo[id], doc)
+		return
+	case parts[1] == "_create":
+		if _, ok := c.docs[id]; ok {
+			conflict()
+			return
+		}
+	case r.URL.Query().Get("if_seq_no") != fmt.Sprint(c.seqNo[id]) || r.URL.Query().Get("if_primary_term") != "1":
+		conflict()
+		return
+	}
+	var body json.RawMessage
+	json.NewDecoder(r.Body).Decode(&body)
+	if c.docs == nil {
+		c.docs, c.seqNo = make(map[string]json.RawMessage), make(map[string]int64)
+	}
+	c.next++
+	c.docs[id], c.seqNo[id] = body, c.next
+	fmt.Fprint(w, `{"result": "created"}`)
+}
+
+func TestESLocker(t *testing.T) {
+	srv := httptest.NewServer(&lockCluster{})
+	defer srv.Close()
+	testLocker(t, &esLocker{client: &esClient{baseURL: srv.URL, http: srv.Client()}, index: "es-schema-locks"})
+}
+
+// 같은 설정으로 도는 두 복제본은 같은 예약 시각을 한 번만 실행합니다.
+func TestDaemonLockSkipsOtherReplicas(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pipeline.yaml")
+	os.WriteFile(path, []byte(`
+pipelines:
+  - name: logs
+    every: 1h
+`), 0o644)
+	var mu sync.Mutex
+	runs := 0
+	replica := func(owner string) *daemon {
+		d := newDaemon(path)
+		d.locker, d.owner, d.lockTTL = &fileLocker{dir: dir}, owner, time.Minute
+		d.run = func(ctx context.Context, p *pipelineSpec, report *runReport) {
+			mu.Lock()
+			runs++
+			mu.Unlock()
+			report.finish(nil)
+		}
+		if err := d.reload(time.Date(2024, 5, 1, 2, 30, 0, 0, time.UTC)); err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	a, b := replica("pod-a"), replica("pod-b")
+	due := time.Date(2024, 5, 1, 3, 0, 1, 0, time.UTC)
+	a.tick(context.Background(), due)
+	a.wg.Wait()
+	b.tick(context.Background(), due.Add(2*time.Second))
+	b.wg.Wait()
+
+	if runs != 1 {
+		t.Errorf("runs = %d, want 1", runs)
+	}
+	if a.jobs[0].State != jobSucceeded || b.jobs[0].State != jobSkipped {
+		t.Errorf("states = %s, %s; want succeeded, skipped", a.jobs[0].State, b.jobs[0].State)
+	}
+	if !strings.Contains(b.jobs[0].Error, "already ran for 2024-05-01T03:00:00Z on pod-a") {
+		t.Errorf("skip reason = %q", b.jobs[0].Error)
+	}
+}
@@ -1,22 +1,250 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"flag"
 	"fmt"
-	"log"
-	"math/rand"
+	"io"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/apache/arrow/go/v10/arrow"
 	"github.com/apache/arrow/go/v10/arrow/array"
 	"github.com/apache/arrow/go/v10/arrow/memory"
-	"github.com/apache/arrow/go/v10/parquet"
-	"github.com/apache/arrow/go/v10/parquet/compress"
-	"github.com/apache/arrow/go/v10/parquet/pqarrow"
+
+	"es-schema/esschema"
 )
 
 func main() {
+	if serveJS() {
+		return
+	}
+	os.Exit(run(os.Args[1:]))
+}
+
+// globalOptions는 모든 실행 방식에 공통으로 적용되는 옵션입니다.
+type globalOptions struct {
+	errorFormat string
+	statusFile  string
+	// profile은 ~/.es-schema/config에서 플래그 기본값을 가져올 프로필 이름입니다.
+	profile string
+	notify  notificationOptions
+	audit   auditOptions
+	anomaly anomalyOptions
+	runs    runStoreOptions
+}
+
+func (g *globalOptions) bind(fs *flag.FlagSet) {
+	fs.StringVar(&g.errorFormat, "error-format", errorFormatText, "error output format on stderr: text or json")
+	fs.StringVar(&g.statusFile, "status-file", "", "write a JSON run summary to this path when the run ends")
+	fs.StringVar(&g.profile, "profile", os.Getenv("ES_SCHEMA_PROFILE"), "take flags not given on the command line from this profile of ~/.es-schema/config or $ES_SCHEMA_CONFIG (default $ES_SCHEMA_PROFILE)")
+	g.notify.bind(fs)
+	g.audit.bind(fs)
+	g.anomaly.bind(fs)
+	g.runs.bind(fs)
+}
+
+// applyProfile 함수는 --profile의 설정을 명령행에 주지 않은 플래그에 넣습니다.
+// 비밀 값이 든 프로필을 다른 사용자가 읽을 수 있으면 경고합니다.
+func (g *globalOptions) applyProfile(ctx context.Context, fs *flag.FlagSet, report *runReport) error {
+	path, err := profilePath()
+	if err != nil {
+		return err
+	}
+	settings, err := loadProfile(path, g.profile)
+	if err != nil {
+		return err
+	}
+	if secret := profileSecret(settings); secret != "" {
+		if info, err := os.Stat(path); err == nil && info.Mode().Perm()&0o077 != 0 {
+			report.warnf("%s holds %s but is readable by other users; chmod 600 it", path, secret)
+		}
+	}
+	return applySettings(ctx, fs, fmt.Sprintf("profile %q", g.profile), settings)
+}
+
+func (g *globalOptions) validate() error {
+	switch g.errorFormat {
+	case errorFormatText, errorFormatJSON:
+	default:
+		return configErrorf("unknown --error-format %q (want text or json)", g.errorFormat)
+	}
+	if err := g.audit.validate(); err != nil {
+		return err
+	}
+	if err := g.anomaly.validate(); err != nil {
+		return err
+	}
+	if err := g.runs.validate(); err != nil {
+		return err
+	}
+	return g.notify.validate()
+}
+
+// command는 하나의 하위 명령입니다. setup은 명령 전용 플래그를 fs에 등록하고,
+// 플래그 파싱이 끝난 뒤 호출될 실행 함수를 반환합니다.
+type command struct {
+	name    string
+	summary string
+	setup   func(fs *flag.FlagSet) func(ctx context.Context, report *runReport, args []string) error
+}
+
+// commands는 지원하는 하위 명령 목록입니다. 하위 명령 없이 실행하면 demo가 실행됩니다.
+// daemon은 이 목록의 명령을 실행하므로, 초기화 순환이 생기지 않게 init에서 채웁니다.
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{name: "demo", summary: "convert the built-in sample mapping and documents to output.parquet", setup: setupDemo},
+		{name: "convert", summary: "convert an NDJSON file of documents to Parquet with a mapping file", setup: setupConvert},
+		{name: "validate", summary: "check the documents of an NDJSON file against a mapping file without writing anything", setup: setupValidate},
+		{name: "schema", summary: "print the Arrow schema of a mapping file or of a live index, or write the schemas of many mapping files to --out-dir", setup: setupSchema},
+		{name: "export", summary: "export one or more indices to Parquet files in parallel", setup: setupExport},
+		{name: "reconcile", summary: "compare field types across indices with _field_caps and write one unified mapping", setup: setupReconcile},
+		{name: "verify", summary: "read Parquet files back and check them against a mapping", setup: setupVerify},
+		{name: "inspect", summary: "print the schema, row groups, column sizes and statistics of Parquet files", setup: setupInspect},
+		{name: "head", summary: "print the first documents of Parquet files as JSON", setup: setupHead},
+		{name: "query", summary: "run SQL over exported Parquet files with the DuckDB CLI", setup: setupQuery},
+		{name: "bench", summary: "time equivalent queries against an index and its exported Parquet files", setup: setupBench},
+		{name: "compact", summary: "merge small Parquet files in each directory into larger ones", setup: setupCompact},
+		{name: "prune", summary: "delete exported files or partitions older than a retention period", setup: setupPrune},
+		{name: "import", summary: "bulk-load Parquet files into an Elasticsearch index", setup: setupImport},
+		{name: "browse", summary: "browse the fields of a mapping and choose which ones to export", setup: setupBrowse},
+		{name: "watch", summary: "regenerate schema dumps, DDL and Avro schemas for a directory of mapping files whenever they change", setup: setupWatch},
+		{name: "ddl", summary: "print a BigQuery, Snowflake or Trino CREATE TABLE statement for a mapping", setup: setupDDL},
+		{name: "gen", summary: "print Go source that builds the Arrow schema of a mapping (gen arrow) or its Avro schema (gen avro)", setup: setupGen},
+		{name: "emit", summary: "render a Go text/template with the schema of a mapping (Terraform, dbt sources, docs, ...)", setup: setupEmit},
+		{name: "detokenize", summary: "look up the original values of tokens written by the tokenize masking rule", setup: setupDetokenize},
+		{name: "migrate", summary: "copy an index between clusters through the Arrow normalization layer", setup: setupMigrate},
+		{name: "generate", summary: "write random documents for a mapping as an Elasticsearch bulk file", setup: setupGenerate},
+		{name: "scenario", summary: "build a benchmark dataset (mapping, bulk file and Parquet) from a YAML description", setup: setupScenario},
+		{name: "tune", summary: "try compression and row group settings on sample documents and recommend one", setup: setupTune},
+		{name: "daemon", summary: "run the pipelines of a YAML file on schedules, reloading it when it changes", setup: setupDaemon},
+		{name: "k8s-job", summary: "render Kubernetes CronJob or Job manifests for the pipelines of a daemon YAML file", setup: setupK8sJob},
+		{name: "flight", summary: "serve an Arrow Flight DoExchange endpoint that converts streamed NDJSON documents to record batches under a mapping", setup: setupFlight},
+		{name: "runs", summary: "list and show the runs recorded by --runs-store or --runs-index (runs list, runs show <id>)", setup: setupRuns},
+	}
+}
+
+func lookupCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+func printCommands(w io.Writer) {
+	fmt.Fprintln(w, "usage: es-schema [command] [flags]")
+	fmt.Fprintln(w, "\ncommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+}
+
+// run 함수는 명령행 인자를 해석해 실행하고 프로세스 종료 코드를 반환합니다.
+func run(args []string) int {
+	name := "demo"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	cmd := lookupCommand(name)
+	if cmd == nil {
+		if name == "help" {
+			printCommands(os.Stdout)
+			return exitOK
+		}
+		reportError(os.Stderr, configErrorf("unknown command %q", name), errorFormatText)
+		printCommands(os.Stderr)
+		return exitConfigError
+	}
+
+	var opts globalOptions
+	fs := flag.NewFlagSet("es-schema "+cmd.name, flag.ContinueOnError)
+	opts.bind(fs)
+	execute := cmd.setup(fs)
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitConfigError
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	report := newRunReport(cmd.name)
+	var err error
+	if opts.profile != "" {
+		err = opts.applyProfile(ctx, fs, report)
+	}
+	if err == nil {
+		err = opts.validate()
+	}
+	if err == nil {
+		err = opts.audit.begin(fs)
+	}
+	if err == nil {
+		opts.anomaly.begin(fs, cmd.name)
+		err = execute(ctx, report, fs.Args())
+	}
+	return finishRun(&opts, report, err)
+}
+
+// finishRun 함수는 실행 결과를 보고서와 상태 파일에 반영하고 종료 코드를 결정합니다.
+func finishRun(opts *globalOptions, report *runReport, err error) int {
+	if err == nil {
+		err = opts.anomaly.check(report)
+	}
+	report.finish(err)
+	suspicious := opts.notify.checkSuspicious(report)
+	if opts.statusFile != "" {
+		if writeErr := writeJSONFileAtomic(opts.statusFile, report); writeErr != nil && err == nil {
+			err = configErrorf("writing status file: %w", writeErr)
+		}
+	}
+	if auditErr := opts.audit.record(report); auditErr != nil && err == nil {
+		err = configErrorf("writing audit record: %w", auditErr)
+	}
+	if runsErr := opts.runs.record(report, opts.anomaly.key); runsErr != nil && err == nil {
+		err = configErrorf("recording run history: %w", runsErr)
+	}
+	opts.notify.notify(report, suspicious)
+	if err != nil {
+		reportError(os.Stderr, err, opts.errorFormat)
+		return exitCodeFor(err)
+	}
+	return exitOK
+}
+
+func setupDemo(fs *flag.FlagSet) func(ctx context.Context, report *runReport, args []string) error {
+	var names nameOptions
+	var pqOpts parquetOptions
+	var batchRows int
+	names.bind(fs)
+	pqOpts.bind(fs)
+	fs.IntVar(&batchRows, "batch-size", defaultBatchRows, batchSizeHelp)
+	return func(ctx context.Context, report *runReport, args []string) error {
+		if err := names.validate(); err != nil {
+			return err
+		}
+		if err := pqOpts.validate(); err != nil {
+			return err
+		}
+		if batchRows <= 0 {
+			return configErrorf("--batch-size must be positive")
+		}
+		return runDemo(ctx, report, &names, &pqOpts, batchRows)
+	}
+}
+
+// runDemo 함수는 내장 매핑과 샘플 데이터를 batchRows 행씩 레코드로 바꿔 output.parquet
+// 파일에 씁니다.
+func runDemo(ctx context.Context, report *runReport, names *nameOptions, pqOpts *parquetOptions, batchRows int) error {
 	// JSON 매핑 테이블
 	mapping := `{
     "properties": {
@@ -36,26 +264,21 @@ func main() {
             },
             "type": "nested"
         },
+        "location": { "type": "geo_point" },
+        "client_ip": { "type": "ip" },
         "timestamp": { "type": "date" }
     }
 }`
 
-	// JSON 파싱
-	var esMapping map[string]interface{}
-	err := json.Unmarshal([]byte(mapping), &esMapping)
+	// JSON 파싱 및 Arrow 스키마 생성
+	originalSchema, err := schemaFromMapping([]byte(mapping))
 	if err != nil {
-		log.Fatalf("Error parsing JSON: %v", err)
+		return schemaErrorf("parsing mapping: %w", err)
 	}
 
-	// Arrow 스키마 생성
-	fields := parseProperties(esMapping["properties"].(map[string]interface{}))
-	originalSchema := arrow.NewSchema(fields, nil)
-
 	// 원래 스키마 출력
 	fmt.Println("Original Schema:")
-	for _, field := range originalSchema.Fields() {
-		fmt.Printf("  %s: %s\n", field.Name, field.Type)
-	}
+	fmt.Print(formatSchema(originalSchema, "  "))
 
 	// 고정된 샘플 데이터 생성
 	sampleData := generateSampleData()
@@ -63,46 +286,72 @@ func main() {
 	// 스키마 조정 (리스트 타입 확인)
 	adjustedSchema := adjustSchemaForLists(originalSchema, sampleData)
 
+	// 가져오기 시 인덱스를 다시 만들 수 있도록 원본 매핑을 메타데이터로 저장
+	adjustedSchema, err = withMappingMetadata(adjustedSchema, []byte(mapping))
+	if err != nil {
+		return schemaErrorf("embedding mapping metadata: %w", err)
+	}
+
 	// 변경된 스키마 출력
 	fmt.Println("\nAdjusted Schema:")
-	for _, field := range adjustedSchema.Fields() {
-		fmt.Printf("  %s: %s\n", field.Name, field.Type)
+	fmt.Print(formatSchema(adjustedSchema, "  "))
+
+	// 다른 시스템에서 쓸 수 있도록 컬럼 이름 정리
+	outputSchema := adjustedSchema
+	if names.enabled() {
+		renamedSchema, renames, err := names.apply(adjustedSchema)
+		if err != nil {
+			return err
+		}
+		recordRenames(report, renames)
+		outputSchema = renamedSchema
 	}
 
-	// Arrow 레코드 생성
-	record := createArrowRecord(adjustedSchema, sampleData)
-
-	fmt.Println("\nArrow Record:", record)
-
 	// Parquet 파일로 저장
-	outputFile, err := os.Create("output.parquet")
+	writer, err := createParquetFile("output.parquet", outputSchema, pqOpts)
 	if err != nil {
-		log.Fatalf("Failed to create output file: %v", err)
+		return err
+	}
+	// 전체 데이터를 레코드 하나로 만들지 않고 batchRows 행씩 만들어 바로 씁니다.
+	nullified := 0
+	for off := 0; off < len(sampleData); off += batchRows {
+		end := off + batchRows
+		if end > len(sampleData) {
+			end = len(sampleData)
+		}
+		if err := writeDemoBatch(ctx, writer, adjustedSchema, outputSchema, sampleData[off:end], &nullified); err != nil {
+			writer.abort()
+			return err
+		}
+	}
+	if err := writer.close(); err != nil {
+		return err
+	}
+	if nullified > 0 {
+		report.warnf("%d values could not be converted to their mapped type and were written as null", nullified)
 	}
-	defer outputFile.Close()
 
-	writerProps := parquet.NewWriterProperties(parquet.WithCompression(compress.Codecs.Snappy))
-	arrowWriterProps := pqarrow.NewArrowWriterProperties(pqarrow.WithStoreSchema())
+	report.addFile("output.parquet", writer.rows)
+	fmt.Println("Parquet file created successfully: output.parquet")
+	return nil
+}
 
-	writer, err := pqarrow.NewFileWriter(
-		record.Schema(),
-		outputFile,
-		writerProps,
-		arrowWriterProps,
-	)
+// writeDemoBatch 함수는 data를 schema의 Arrow 레코드로 만들어 outputSchema의 이름으로 씁니다.
+func writeDemoBatch(ctx context.Context, writer *parquetWriter, schema, outputSchema *arrow.Schema, data []map[string]interface{}, nullified *int) error {
+	record, n, err := createArrowRecord(ctx, schema, data)
 	if err != nil {
-		log.Fatalf("Failed to create Parquet writer: %v", err)
+		return err
 	}
-
-	if err := writer.Write(record); err != nil {
-		log.Fatalf("Failed to write record to Parquet file: %v", err)
-	}
-
-	if err := writer.Close(); err != nil {
-		log.Fatalf("Failed to close Parquet writer: %v", err)
+	defer record.Release()
+	*nullified += n
+	if outputSchema != schema {
+		renamed := renameRecord(record, outputSchema)
+		defer renamed.Release()
+		record = renamed
 	}
 
-	fmt.Println("Parquet file created successfully: output.parquet")
+	fmt.Println("\nArrow Record:", record)
+	return writer.write(record)
 }
 
 func generateSampleData() []map[string]interface{} {
@@ -118,19 +367,33 @@ func generateSampleData() []map[string]interface{} {
 				"tags":   []string{"developer", "golang"},
 				"scores": []float32{85.5, 92.0, 78.5},
 			},
+			// geo_point는 Elasticsearch가 받는 어느 형식이든 lat/lon 구조체가 됩니다.
+			"location": map[string]interface{}{"lat": 40.71, "lon": -74.0},
+			// ip는 IPv4와 IPv6 모두 16바이트 주소가 됩니다.
+			"client_ip": "192.168.0.10",
 			"timestamp": time.Now(),
 		},
 		{
 			"user": map[string]interface{}{
 				"name": "Jane Smith",
-				"address": map[string]interface{}{
-					"street":  "456 Elm St",
-					"city":    "Los Angeles",
-					"zipcode": 90001,
+				// nested 필드는 여러 객체를 가질 수 있습니다.
+				"address": []map[string]interface{}{
+					{
+						"street":  "456 Elm St",
+						"city":    "Los Angeles",
+						"zipcode": 90001,
+					},
+					{
+						"street":  "12 Pine Ave",
+						"city":    "San Diego",
+						"zipcode": 92101,
+					},
 				},
 				"tags":   []string{"designer", "ui/ux"},
 				"scores": []float32{88.0, 95.5},
 			},
+			"location":  "34.05,-118.24",
+			"client_ip": "2001:db8::1",
 			"timestamp": time.Now().Add(-24 * time.Hour),
 		},
 		{
@@ -144,113 +407,53 @@ func generateSampleData() []map[string]interface{} {
 				"tags":   "manager",
 				"scores": []float32{79.0, 82.5, 91.0, 87.5},
 			},
+			"location":  []interface{}{-87.63, 41.88},
 			"timestamp": time.Now().Add(-48 * time.Hour),
 		},
 	}
 }
 
-// parseProperties 함수는 주어진 properties 맵을 순회하여 Arrow 필드 목록을 생성합니다.
-func parseProperties(properties map[string]interface{}) []arrow.Field {
-	fields := []arrow.Field{}
-	for fieldName, fieldProperties := range properties {
-		fieldProps := fieldProperties.(map[string]interface{})
-		fieldType, ok := fieldProps["type"].(string)
-		if !ok {
-			// "type"이 없는 경우 "object"로 가정
-			fieldType = "object"
-		}
-		arrowType := esTypeToArrowType(fieldType, fieldProps)
-		fields = append(fields, arrow.Field{Name: fieldName, Type: arrowType})
-	}
-	return fields
-}
-
-// esTypeToArrowType 함수는 Elasticsearch 타입을 Arrow 타입으로 매핑합니다.
-func esTypeToArrowType(esType string, fieldProps map[string]interface{}) arrow.DataType {
-	switch esType {
-	case "text", "keyword":
-		return arrow.BinaryTypes.String
-	case "integer":
-		return arrow.PrimitiveTypes.Int32
-	case "long":
-		return arrow.PrimitiveTypes.Int64
-	case "float":
-		return arrow.PrimitiveTypes.Float32
-	case "double":
-		return arrow.PrimitiveTypes.Float64
-	case "boolean":
-		return arrow.FixedWidthTypes.Boolean
-	case "date":
-		// Date 타입은 Arrow의 timestamp 타입으로 매핑합니다.
-		return arrow.FixedWidthTypes.Timestamp_ns
-	case "dense_vector":
-		// Dense vector 타입은 Arrow의 fixed-size list 타입으로 매핑합니다.
-		if dims, ok := fieldProps["dims"].(float64); ok {
-			return arrow.FixedSizeListOf(int32(dims), arrow.PrimitiveTypes.Float32)
-		}
-		// dims가 지정되지 않은 경우 기본값으로 0을 사용
-		return arrow.FixedSizeListOf(0, arrow.PrimitiveTypes.Float32)
-	case "nested", "object":
-		// Nested 또는 Object 타입은 재귀적으로 처리합니다.
-		if properties, ok := fieldProps["properties"].(map[string]interface{}); ok {
-			return arrow.StructOf(parseProperties(properties)...)
-		}
-		return arrow.StructOf()
-	default:
-		return arrow.BinaryTypes.String
+// schemaFromMapping 함수는 Elasticsearch 매핑 JSON을 Arrow 스키마로 변환합니다.
+func schemaFromMapping(data []byte) (*arrow.Schema, error) {
+	mapping, err := esschema.ParseMapping(data)
+	if err != nil {
+		return nil, err
 	}
+	return esschema.ToArrowSchema(mapping)
 }
 
-func generateDummyData(properties map[string]interface{}, count int) []map[string]interface{} {
-	data := make([]map[string]interface{}, count)
-	for i := 0; i < count; i++ {
-		data[i] = generateDocument(properties)
+// loadMappingFile 함수는 파일에서 매핑을 읽어 Arrow 스키마로 변환합니다.
+func loadMappingFile(path string) (*arrow.Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, configErrorf("reading mapping: %w", err)
 	}
-	return data
-}
-
-func generateDocument(properties map[string]interface{}) map[string]interface{} {
-	doc := make(map[string]interface{})
-	for fieldName, fieldProps := range properties {
-		props := fieldProps.(map[string]interface{})
-		fieldType, _ := props["type"].(string)
-
-		// 랜덤하게 리스트로 생성 (20% 확률)
-		if rand.Float32() < 0.2 {
-			doc[fieldName] = []interface{}{generateValue(fieldType, props)}
-		} else {
-			doc[fieldName] = generateValue(fieldType, props)
-		}
+	schema, err := schemaFromMapping(data)
+	if err != nil {
+		return nil, schemaErrorf("parsing mapping %s: %w", path, err)
 	}
-	return doc
+	return schema, nil
 }
 
-func generateValue(fieldType string, props map[string]interface{}) interface{} {
-	switch fieldType {
-	case "text", "keyword":
-		return fmt.Sprintf("dummy_%d", rand.Intn(1000))
-	case "integer":
-		return int32(rand.Intn(1000))
-	case "long":
-		return int64(rand.Int63())
-	case "float":
-		return rand.Float32()
-	case "double":
-		return rand.Float64()
-	case "boolean":
-		return rand.Intn(2) == 1
-	case "date":
-		return time.Now()
-	case "nested", "object":
-		if nestedProps, ok := props["properties"].(map[string]interface{}); ok {
-			return generateDocument(nestedProps)
-		}
+// formatSchema 함수는 스키마의 각 필드를 "이름: 타입" 형식의 줄로 출력합니다.
+func formatSchema(schema *arrow.Schema, indent string) string {
+	var sb strings.Builder
+	for _, field := range schema.Fields() {
+		fmt.Fprintf(&sb, "%s%s: %s\n", indent, field.Name, field.Type)
 	}
-	return nil
+	return sb.String()
 }
 
 func adjustField(field arrow.Field, value interface{}) arrow.Field {
-	if value == nil {
+	if value == nil || esschema.IsGeoPoint(field.Type) {
+		return field
+	}
+	// nested 필드는 이미 구조체 리스트이므로 원소 구조체의 필드만 맞춥니다.
+	if lt, ok := field.Type.(*arrow.ListType); ok {
+		if obj := firstObject(value); obj != nil && lt.Elem().ID() == arrow.STRUCT {
+			elem := adjustField(arrow.Field{Name: field.Name, Type: lt.Elem()}, obj)
+			return arrow.Field{Name: field.Name, Type: arrow.ListOf(elem.Type), Nullable: true}
+		}
 		return field
 	}
 
@@ -284,6 +487,25 @@ func adjustField(field arrow.Field, value interface{}) arrow.Field {
 	return field
 }
 
+// firstObject 함수는 객체 하나나 객체 배열의 첫 객체를 반환합니다.
+func firstObject(value interface{}) map[string]interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v
+	case []map[string]interface{}:
+		if len(v) > 0 {
+			return v[0]
+		}
+	case []interface{}:
+		for _, item := range v {
+			if obj, ok := item.(map[string]interface{}); ok {
+				return obj
+			}
+		}
+	}
+	return nil
+}
+
 func adjustSchemaForLists(schema *arrow.Schema, data []map[string]interface{}) *arrow.Schema {
 	adjustedFields := make([]arrow.Field, len(schema.Fields()))
 	for i, field := range schema.Fields() {
@@ -309,26 +531,48 @@ func adjustSchemaForLists(schema *arrow.Schema, data []map[string]interface{}) *
 	return arrow.NewSchema(adjustedFields, nil)
 }
 
-func createArrowRecord(schema *arrow.Schema, data []map[string]interface{}) arrow.Record {
-	builders := make([]array.Builder, len(schema.Fields()))
-	for i, field := range schema.Fields() {
-		builders[i] = array.NewBuilder(memory.DefaultAllocator, field.Type)
-	}
-
-	for _, doc := range data {
-		for i, field := range schema.Fields() {
-			value := doc[field.Name]
-			fmt.Printf("Field: %s, Value: %v, Type: %T\n", field.Name, value, value)
-			appendValue(builders[i], value, schema)
+// createArrowRecord 함수는 data를 schema의 레코드로 만듭니다. 최상위 원시 타입 컬럼은
+// 값의 Go 타입 그대로 만든 뒤 cast 커널로 매핑의 타입에 맞추고(castColumn 참고),
+// struct와 list 컬럼은 빌더로 직접 만듭니다. 변환하지 못해 null로 쓴 값의 수를 함께
+// 반환합니다.
+func createArrowRecord(ctx context.Context, schema *arrow.Schema, data []map[string]interface{}) (arrow.Record, int, error) {
+	columns := make([]arrow.Array, 0, len(schema.Fields()))
+	defer func() {
+		for _, col := range columns {
+			col.Release()
 		}
+	}()
+
+	nullified := 0
+	for _, field := range schema.Fields() {
+		values := make([]interface{}, len(data))
+		for j, doc := range data {
+			values[j] = doc[field.Name]
+			fmt.Printf("Field: %s, Value: %v, Type: %T\n", field.Name, values[j], values[j])
+		}
+		if field.Type.ID() == arrow.TIMESTAMP {
+			// 읽을 수 없는 format은 browse가 알려 주고, 값은 기본 형식으로 읽습니다.
+			df, _ := esschema.FieldDateFormat(field)
+			values = parseTimestamps(values, df)
+		}
+		if castable(field.Type) {
+			col, n, err := stagedColumn(ctx, field.Type, values)
+			if err != nil {
+				return nil, 0, dataErrorf("converting field %s: %w", field.Name, err)
+			}
+			columns = append(columns, col)
+			nullified += n
+			continue
+		}
+		builder := array.NewBuilder(memory.DefaultAllocator, field.Type)
+		for _, value := range values {
+			appendValue(builder, value, schema)
+		}
+		columns = append(columns, builder.NewArray())
+		builder.Release()
 	}
 
-	columns := make([]arrow.Array, len(builders))
-	for i, builder := range builders {
-		columns[i] = builder.NewArray()
-	}
-
-	return array.NewRecord(schema, columns, int64(len(data)))
+	return array.NewRecord(schema, columns, int64(len(data))), nullified, nil
 }
 
 func appendValue(builder array.Builder, value interface{}, schema *arrow.Schema) {
@@ -338,14 +582,16 @@ func appendValue(builder array.Builder, value interface{}, schema *arrow.Schema)
 	}
 
 	switch b := builder.(type) {
+	// 범위를 넘는 숫자는 잘리지 않도록 esschema의 규칙(--on-numeric-overflow null)으로 null이
+	// 됩니다.
 	case *array.Int32Builder:
 		switch v := value.(type) {
 		case int32:
 			b.Append(v)
 		case int:
-			b.Append(int32(v))
+			esschema.AppendValue(b, arrow.PrimitiveTypes.Int32, float64(v))
 		case float64:
-			b.Append(int32(v))
+			esschema.AppendValue(b, arrow.PrimitiveTypes.Int32, v)
 		default:
 			b.AppendNull()
 		}
@@ -356,16 +602,23 @@ func appendValue(builder array.Builder, value interface{}, schema *arrow.Schema)
 		case int:
 			b.Append(int64(v))
 		case float64:
-			b.Append(int64(v))
+			esschema.AppendValue(b, arrow.PrimitiveTypes.Int64, v)
 		default:
 			b.AppendNull()
 		}
+	case *array.Uint64Builder:
+		// unsigned_long은 math.MaxInt64보다 큰 json.Number와 문자열도 정밀도를 잃지 않고 읽습니다.
+		if v, ok := value.(uint64); ok {
+			b.Append(v)
+		} else {
+			esschema.AppendValue(b, arrow.PrimitiveTypes.Uint64, value)
+		}
 	case *array.Float32Builder:
 		switch v := value.(type) {
 		case float32:
 			b.Append(v)
 		case float64:
-			b.Append(float32(v))
+			esschema.AppendValue(b, arrow.PrimitiveTypes.Float32, v)
 		default:
 			b.AppendNull()
 		}
@@ -384,6 +637,9 @@ func appendValue(builder array.Builder, value interface{}, schema *arrow.Schema)
 		} else {
 			b.AppendNull()
 		}
+	case *array.BinaryBuilder:
+		// binary 필드의 _source 값은 base64 문자열이므로 디코딩한 바이트를 씁니다.
+		esschema.AppendValue(b, arrow.BinaryTypes.Binary, value)
 	case *array.BooleanBuilder:
 		if v, ok := value.(bool); ok {
 			b.Append(v)
@@ -391,31 +647,44 @@ func appendValue(builder array.Builder, value interface{}, schema *arrow.Schema)
 			b.AppendNull()
 		}
 	case *array.TimestampBuilder:
-		switch v := value.(type) {
-		case time.Time:
-			b.Append(arrow.Timestamp(v.UnixNano()))
-		case string:
-			t, err := time.Parse(time.RFC3339, v)
-			if err == nil {
-				b.Append(arrow.Timestamp(t.UnixNano()))
-			} else {
-				b.AppendNull()
-			}
-		default:
-			b.AppendNull()
-		}
+		// time.Time이 아닌 값은 기본 형식(RFC 3339 문자열과 epoch 밀리초)으로 읽습니다. 매핑의
+		// format으로 읽을 값은 parseTimestamps가 미리 time.Time으로 바꿔 둡니다.
+		esschema.AppendValue(b, b.Type(), value)
+	case *array.FixedSizeBinaryBuilder:
+		esschema.AppendValue(b, esschema.IPType(), value)
+	case *array.Float16Builder:
+		esschema.AppendValue(b, arrow.FixedWidthTypes.Float16, value)
+	case *array.Decimal128Builder:
+		// scaled_float는 scaling_factor의 자릿수로 반올림한 decimal이 됩니다.
+		esschema.AppendValue(b, b.Type(), value)
+	case *array.MapBuilder:
+		// flattened 객체는 점으로 이은 키와 문자열 값의 맵이 됩니다.
+		esschema.AppendValue(b, b.Type(), value)
 	case *array.StructBuilder:
+		// range 필드는 gt와 lt 경계와 CIDR 문자열도 esschema의 규칙으로 gte와 lte가 됩니다.
+		if esschema.IsGeoPoint(b.Type()) || esschema.IsRange(b.Type()) {
+			esschema.AppendValue(b, b.Type(), value)
+			return
+		}
 		if v, ok := value.(map[string]interface{}); ok {
 			b.Append(true)
 			for j := 0; j < b.NumField(); j++ {
 				fieldBuilder := b.FieldBuilder(j)
-				fieldValue := v[b.Type().(*arrow.StructType).Field(j).Name]
+				field := b.Type().(*arrow.StructType).Field(j)
+				fieldValue := v[field.Name]
+				if df, _ := esschema.FieldDateFormat(field); df != nil && field.Type.ID() == arrow.TIMESTAMP {
+					fieldValue = parseTimestamps([]interface{}{fieldValue}, df)[0]
+				}
 				appendValue(fieldBuilder, fieldValue, schema)
 			}
 		} else {
 			b.AppendNull()
 		}
 	case *array.ListBuilder:
+		if esschema.IsGeoPoint(b.Type().(*arrow.ListType).Elem()) {
+			esschema.AppendValue(b, b.Type(), value)
+			return
+		}
 		b.Append(true)
 		switch v := value.(type) {
 		case []interface{}:
@@ -430,6 +699,11 @@ func appendValue(builder array.Builder, value interface{}, schema *arrow.Schema)
 			for _, item := range v {
 				appendValue(b.ValueBuilder(), item, schema)
 			}
+		case []map[string]interface{}:
+			// nested 필드의 객체 배열은 객체마다 원소 하나입니다.
+			for _, item := range v {
+				appendValue(b.ValueBuilder(), item, schema)
+			}
 		default:
 			// 단일 값을 리스트의 단일 요소로 처리
 			appendValue(b.ValueBuilder(), value, schema)
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 변환 규칙을 의도적으로 바꾼 경우에는 `go test -run TestSchemaGolden -update`로
+// golden 파일을 다시 생성하고, 변경된 내용을 리뷰에서 함께 확인합니다.
+var update = flag.Bool("update", false, "rewrite testdata/golden files from the current output")
+
+const (
+	mappingsDir = "testdata/mappings"
+	goldenDir   = "testdata/golden"
+)
+
+// TestSchemaGolden 함수는 testdata/mappings의 각 매핑에서 만든 Arrow 스키마를
+// testdata/golden의 스냅샷과 비교합니다.
+func TestSchemaGolden(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join(mappingsDir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatalf("no mapping fixtures found in %s", mappingsDir)
+	}
+
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			schema, err := schemaFromMapping(data)
+			if err != nil {
+				t.Fatalf("schemaFromMapping: %v", err)
+			}
+			got := formatSchema(schema, "")
+
+			goldenPath := filepath.Join(goldenDir, name+".golden")
+			if *update {
+				if err := os.WriteFile(goldenPath, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("missing golden file (run with -update to create it): %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("schema for %s changed\n--- want\n%s--- got\n%s", path, want, got)
+			}
+		})
+	}
+}
+
+// TestGoldenFilesHaveFixtures 함수는 매핑이 삭제되었는데 golden 파일만 남아 있는
+// 경우를 잡아냅니다.
+func TestGoldenFilesHaveFixtures(t *testing.T) {
+	goldens, err := filepath.Glob(filepath.Join(goldenDir, "*.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, golden := range goldens {
+		name := strings.TrimSuffix(filepath.Base(golden), ".golden")
+		if _, err := os.Stat(filepath.Join(mappingsDir, name+".json")); err != nil {
+			t.Errorf("golden file %s has no matching mapping fixture", golden)
+		}
+	}
+}
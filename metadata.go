@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+// mappingMetadataKey는 원본 Elasticsearch 매핑을 저장하는 스키마 메타데이터 키입니다.
+// pqarrow가 스키마 메타데이터를 Parquet footer의 key-value 메타데이터로 기록하므로,
+// 내보낸 파일만으로 원래 인덱스를 다시 만들 수 있습니다.
+const mappingMetadataKey = "es_schema.mapping"
+
+// setSchemaMetadata 함수는 key에 value를 설정한(이미 있으면 바꾼) 새 스키마를 반환합니다.
+func setSchemaMetadata(schema *arrow.Schema, key, value string) *arrow.Schema {
+	md := withMetadataValue(schema.Metadata(), key, value)
+	return arrow.NewSchema(schema.Fields(), &md)
+}
+
+// withMetadataValue 함수는 key에 value를 설정한(이미 있으면 바꾼) 메타데이터 사본을 반환합니다.
+func withMetadataValue(md arrow.Metadata, key, value string) arrow.Metadata {
+	keys := append([]string(nil), md.Keys()...)
+	values := append([]string(nil), md.Values()...)
+	if idx := md.FindKey(key); idx >= 0 {
+		values[idx] = value
+	} else {
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+	return arrow.NewMetadata(keys, values)
+}
+
+// withMappingMetadata 함수는 매핑 JSON을 공백 없이 압축해 스키마 메타데이터에 넣습니다.
+func withMappingMetadata(schema *arrow.Schema, mapping []byte) (*arrow.Schema, error) {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, mapping); err != nil {
+		return nil, err
+	}
+	return setSchemaMetadata(schema, mappingMetadataKey, compact.String()), nil
+}
+
+// embeddedMapping 함수는 Parquet 파일에 저장된 원본 매핑을 찾습니다. footer의
+// key-value 메타데이터를 먼저 보고, 없으면 저장된 Arrow 스키마의 메타데이터를 봅니다.
+func (f *parquetFile) embeddedMapping() (json.RawMessage, bool) {
+	if v := f.pf.MetaData().KeyValueMetadata().FindValue(mappingMetadataKey); v != nil {
+		return json.RawMessage(*v), true
+	}
+	if sc, err := f.reader.Schema(); err == nil {
+		md := sc.Metadata()
+		if idx := md.FindKey(mappingMetadataKey); idx >= 0 {
+			return json.RawMessage(md.Values()[idx]), true
+		}
+	}
+	return nil, false
+}
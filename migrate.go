@@ -0,0 +1,571 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+	"github.com/apache/arrow/go/v10/arrow/memory"
+)
+
+type migrateOptions struct {
+	source      esOptions
+	dest        esOptions
+	index       string
+	destIndex   string
+	query       string
+	scrollSize  int
+	keepAlive   time.Duration
+	parquetPath string
+	listFields  stringListFlag
+	bulkDocs    int
+	bulkBytes   int
+
+	createIndex    bool
+	settingsPath   string
+	deadLetterPath string
+
+	dryRun         bool
+	targetVersion  int
+	mappingOut     string
+	estimateSample int
+
+	names        nameOptions
+	parquet      parquetOptions
+	badDocuments badDocumentOptions
+	overflow     overflowOptions
+	sanitize     sanitizeOptions
+	transforms   transformOptions
+	recordHooks  recordHookOptions
+}
+
+func setupMigrate(fs *flag.FlagSet) func(ctx context.Context, report *runReport, args []string) error {
+	var o migrateOptions
+	o.source.bind(fs, "source-", "source")
+	o.dest.bind(fs, "dest-", "destination")
+	fs.StringVar(&o.index, "index", "", "source index to migrate (required)")
+	fs.StringVar(&o.destIndex, "dest-index", "", "destination index (default: same as --index)")
+	fs.StringVar(&o.query, "query", "", "only migrate documents matching this query DSL JSON")
+	fs.IntVar(&o.scrollSize, "scroll-size", 1000, "documents per scroll page")
+	fs.DurationVar(&o.keepAlive, "scroll-keep-alive", 5*time.Minute, "how long the source cluster keeps the scroll context between pages")
+	fs.StringVar(&o.parquetPath, "parquet", "", "also write the normalized documents to this Parquet file")
+	fs.Var(&o.listFields, "list-fields", "treat these fields as arrays from the start; dotted paths select nested fields (comma-separated or repeated)")
+	fs.IntVar(&o.bulkDocs, "bulk-docs", 1000, "maximum number of documents per _bulk request")
+	fs.IntVar(&o.bulkBytes, "bulk-bytes", 5<<20, "send a _bulk request once its body reaches this many bytes")
+	fs.BoolVar(&o.createIndex, "create-index", true, "create the destination index from the translated source mapping if it does not exist")
+	fs.StringVar(&o.settingsPath, "settings", "", "index settings JSON file to use when creating the destination index")
+	fs.StringVar(&o.deadLetterPath, "dead-letter", "", "write rejected documents with their error to this NDJSON file")
+	fs.BoolVar(&o.dryRun, "dry-run", false, "only report the mapping changes and print the translated destination mapping")
+	fs.IntVar(&o.targetVersion, "target-version", 0, "destination major version for --dry-run without --dest-url")
+	fs.IntVar(&o.estimateSample, "estimate-sample", 1000, "with --dry-run, convert this many random documents to estimate the Parquet output size and duration (0: skip the estimate)")
+	o.names.bind(fs)
+	o.parquet.bind(fs)
+	o.badDocuments.bind(fs)
+	o.overflow.bind(fs)
+	o.sanitize.bind(fs)
+	o.transforms.bind(fs)
+	o.recordHooks.bind(fs)
+	fs.StringVar(&o.mappingOut, "mapping-out", "", "write the translated destination mapping to this file (default with --dry-run: stdout)")
+
+	return func(ctx context.Context, report *runReport, args []string) error {
+		if err := o.validate(args); err != nil {
+			return err
+		}
+		return o.migrate(ctx, report)
+	}
+}
+
+func (o *migrateOptions) validate(args []string) error {
+	if len(args) > 0 {
+		return configErrorf("migrate: unexpected arguments %v", args)
+	}
+	if o.index == "" {
+		return configErrorf("migrate: --index is required")
+	}
+	if o.destIndex == "" {
+		o.destIndex = o.index
+	}
+	if o.query != "" && !json.Valid([]byte(o.query)) {
+		return configErrorf("migrate: --query is not valid JSON")
+	}
+	if o.scrollSize <= 0 || o.keepAlive < time.Second {
+		return configErrorf("migrate: --scroll-size must be positive and --scroll-keep-alive at least 1s")
+	}
+	if o.bulkDocs <= 0 || o.bulkBytes <= 0 {
+		return configErrorf("migrate: --bulk-docs and --bulk-bytes must be positive")
+	}
+	if o.settingsPath != "" && !o.createIndex {
+		return configErrorf("migrate: --settings is only used together with --create-index")
+	}
+	if o.targetVersion != 0 && !o.dryRun {
+		return configErrorf("migrate: --target-version is only used with --dry-run; a real migration reads the version from --dest-url")
+	}
+	if o.estimateSample < 0 || o.estimateSample > maxEstimateSample {
+		return configErrorf("migrate: --estimate-sample must be between 0 and %d", maxEstimateSample)
+	}
+	if o.targetVersion < 0 {
+		return configErrorf("migrate: --target-version must be a major version such as 8")
+	}
+	if o.names.enabled() && o.parquetPath == "" && !o.dryRun {
+		return configErrorf("migrate: field name options only apply to the --parquet copy")
+	}
+	if err := o.parquet.validate(); err != nil {
+		return err
+	}
+	if err := o.badDocuments.validate(); err != nil {
+		return err
+	}
+	if err := o.overflow.validate(); err != nil {
+		return err
+	}
+	if err := o.sanitize.validate(); err != nil {
+		return err
+	}
+	return o.names.validate()
+}
+
+// migrate 함수는 원본 클러스터의 문서를 scroll로 읽어 Arrow 레코드로 정규화한 뒤
+// 대상 클러스터에 bulk로 넣습니다. 매핑은 대상 클러스터 버전에 맞게 바꿉니다.
+func (o *migrateOptions) migrate(ctx context.Context, report *runReport) error {
+	src, err := o.source.client()
+	if err != nil {
+		return err
+	}
+	if err := o.source.checkReadOnly(ctx, src, report, []string{o.index}, "read", "view_index_metadata"); err != nil {
+		return err
+	}
+	srcMajor, err := src.serverVersion(ctx)
+	if err != nil {
+		return err
+	}
+	var dst *esClient
+	dstMajor := o.targetVersion
+	if dstMajor == 0 {
+		if dst, err = o.dest.client(); err != nil {
+			return err
+		}
+		if dstMajor, err = dst.serverVersion(ctx); err != nil {
+			return err
+		}
+	}
+
+	rawMapping, err := src.getMapping(ctx, o.index)
+	if err != nil {
+		return err
+	}
+	mapping, changes, err := translateMapping(rawMapping, dstMajor)
+	if err != nil {
+		return err
+	}
+	for _, c := range changes {
+		report.warnf("mapping Elasticsearch %d -> %d: %s", srcMajor, dstMajor, c)
+	}
+	mappingJSON, err := json.Marshal(mapping)
+	if err != nil {
+		return schemaErrorf("encoding translated mapping: %w", err)
+	}
+	if o.dryRun {
+		if o.estimateSample > 0 {
+			if report.Estimate, err = o.estimateOutput(ctx, src, mappingJSON); err != nil {
+				return err
+			}
+		}
+		return o.writeDryRun(srcMajor, dstMajor, mapping, changes, report.Estimate)
+	}
+	if o.mappingOut != "" {
+		if err := writeMappingFile(o.mappingOut, wrapMappingType(mapping, dstMajor)); err != nil {
+			return err
+		}
+	}
+	schema, err := o.normalizationSchema(mappingJSON)
+	if err != nil {
+		return err
+	}
+
+	if o.createIndex {
+		if err := o.ensureDestIndex(ctx, dst, wrapMappingType(mapping, dstMajor), report); err != nil {
+			return err
+		}
+	}
+
+	indexer := newBulkIndexer(dst, o.destIndex, actionIndex, o.bulkDocs, o.bulkBytes)
+	if dstMajor < 7 {
+		indexer.docType = "_doc"
+	}
+	if o.deadLetterPath != "" {
+		if indexer.deadLetters, err = createDeadLetterFile(o.deadLetterPath); err != nil {
+			return err
+		}
+	}
+
+	chain, err := o.transforms.build()
+	if err != nil {
+		return err
+	}
+	hooks, err := o.recordHooks.build()
+	if err != nil {
+		return err
+	}
+	norm := newNormalizer(schema)
+	norm.rejectBad = o.badDocuments.skip()
+	o.overflow.apply(norm)
+	o.sanitize.apply(norm)
+	rejects := &docRejects{deadLetters: indexer.deadLetters}
+	var sink *parquetSink
+	defer func() {
+		if sink != nil {
+			sink.abort()
+		}
+	}()
+
+	err = src.scroll(ctx, o.index, json.RawMessage(o.query), o.scrollSize, o.keepAlive, nil, nil, func(hits []searchHit) error {
+		report.RowsRead += int64(len(hits))
+		docs, hits, err := rejects.decodeHits(hits)
+		if err != nil {
+			return err
+		}
+		if hits, docs, err = rejects.transformHits(chain, hits, docs, &report.DocumentsDropped); err != nil {
+			return err
+		}
+
+		if changed := norm.widen(docs); changed != "" && sink != nil {
+			return schemaErrorf("field %s holds arrays only after the first scroll page, which changes the --parquet schema; rerun with --list-fields %s", changed, changed)
+		}
+		converted, rejected := norm.record(docs)
+		defer converted.Release()
+		if hits, docs, err = rejects.keep(hits, docs, rejected); err != nil {
+			return err
+		}
+		rec, err := hooks.apply(converted)
+		if err != nil {
+			return err
+		}
+		defer rec.Release()
+
+		if o.parquetPath != "" {
+			if sink == nil {
+				if sink, err = newParquetSink(o.parquetPath, rec.Schema(), mappingJSON, sinkColumns{}, &o.names, &o.parquet); err != nil {
+					return err
+				}
+				recordRenames(report, sink.renames)
+			}
+			if err := sink.writeHits(rec, hits); err != nil {
+				return err
+			}
+		}
+
+		for i, out := range recordDocuments(rec) {
+			copyUnmapped(out, docs[i], rec.Schema().Fields())
+			if err := indexer.add(ctx, hits[i].ID, out); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		err = indexer.flush(ctx)
+	}
+	if err != nil {
+		indexer.closeDeadLetters(report)
+		return err
+	}
+	if err := indexer.closeDeadLetters(report); err != nil {
+		return err
+	}
+
+	if sink != nil {
+		s := sink
+		sink = nil
+		if err := s.close(); err != nil {
+			return err
+		}
+		report.addCopyFile(s.path, s.rows)
+	}
+	if norm.dropped > 0 {
+		report.warnf("%d values did not match their mapped type and were left out of the migrated documents", norm.dropped)
+	}
+	o.overflow.report(report, norm.overflowCounts())
+	o.sanitize.report(report, norm.sanitizedCounts())
+	if report.DocumentsDropped > 0 {
+		fmt.Printf("dropped %d documents by --transform\n", report.DocumentsDropped)
+	}
+	if err := indexer.finish(report); err != nil {
+		rejects.finish(report)
+		return err
+	}
+	return rejects.finish(report)
+}
+
+// writeDryRun 함수는 매핑 변경 보고서와 출력 추정치(estimate가 있으면)를 출력하고,
+// 대상 클러스터에 만들 매핑을 --mapping-out 파일이나 표준 출력에 씁니다. 문서는 옮기지
+// 않습니다.
+func (o *migrateOptions) writeDryRun(srcMajor, dstMajor int, mapping map[string]interface{}, changes []mappingChange, estimate *outputEstimate) error {
+	target := wrapMappingType(mapping, dstMajor)
+	// 매핑 JSON을 표준 출력으로 파이프할 수 있도록, 매핑을 파일로 쓰지 않으면 보고서는
+	// 표준 에러로 보냅니다.
+	w := os.Stderr
+	if o.mappingOut != "" {
+		w = os.Stdout
+	}
+	writeMappingChanges(w, srcMajor, dstMajor, changes)
+	if estimate != nil {
+		writeEstimate(w, estimate)
+	}
+	if o.mappingOut != "" {
+		return writeMappingFile(o.mappingOut, target)
+	}
+	data, err := encodeIndexMapping(target)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+// encodeIndexMapping 함수는 인덱스 생성 요청 본문 형태({"mappings": ...})의 JSON을 만듭니다.
+func encodeIndexMapping(mapping map[string]interface{}) ([]byte, error) {
+	data, err := json.MarshalIndent(map[string]interface{}{"mappings": mapping}, "", "  ")
+	if err != nil {
+		return nil, schemaErrorf("encoding translated mapping: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+func writeMappingFile(path string, mapping map[string]interface{}) error {
+	data, err := encodeIndexMapping(mapping)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return configErrorf("writing --mapping-out: %w", err)
+	}
+	return nil
+}
+
+// normalizationSchema 함수는 매핑으로 스키마를 만들고 --list-fields를 적용합니다.
+func (o *migrateOptions) normalizationSchema(mapping []byte) (*arrow.Schema, error) {
+	schema, err := schemaFromMapping(mapping)
+	if err != nil {
+		return nil, schemaErrorf("source mapping of %s: %w", o.index, err)
+	}
+	fields := schema.Fields()
+	for _, path := range o.listFields {
+		var ok bool
+		if fields, ok = forceList(fields, strings.Split(path, ".")); !ok {
+			return nil, configErrorf("--list-fields: %s is not a field of the %s mapping", path, o.index)
+		}
+	}
+	return arrow.NewSchema(fields, nil), nil
+}
+
+// ensureDestIndex 함수는 대상 인덱스가 없으면 바꾼 매핑(과 --settings)으로 만듭니다.
+func (o *migrateOptions) ensureDestIndex(ctx context.Context, client *esClient, mapping map[string]interface{}, report *runReport) error {
+	exists, err := client.indexExists(ctx, o.destIndex)
+	if err != nil {
+		return err
+	}
+	if exists {
+		report.warnf("index %s already exists on the destination; its mapping was left unchanged", o.destIndex)
+		return nil
+	}
+	body := map[string]interface{}{"mappings": mapping}
+	if o.settingsPath != "" {
+		data, err := os.ReadFile(o.settingsPath)
+		if err != nil {
+			return configErrorf("reading --settings: %w", err)
+		}
+		if !json.Valid(data) {
+			return configErrorf("--settings %s is not valid JSON", o.settingsPath)
+		}
+		body["settings"] = json.RawMessage(data)
+	}
+	if err := client.createIndex(ctx, o.destIndex, body); err != nil {
+		return err
+	}
+	fmt.Printf("created index %s on the destination cluster\n", o.destIndex)
+	return nil
+}
+
+// parquetSink는 migrate가 정규화한 레코드를 _id 컬럼과 함께 Parquet 파일로 씁니다.
+// 원본 매핑을 메타데이터로 넣으므로 나중에 import --create-index --id-column _id로
+// 다시 가져올 수 있습니다. extra에 따라 _id 다음에 메타데이터 컬럼을 더 씁니다.
+type parquetSink struct {
+	*parquetWriter
+	schema  *arrow.Schema
+	renames []fieldRename
+	extra   sinkColumns
+	lineage []columnLineage
+}
+
+// sinkColumns는 parquetSink가 _id 다음에 더 쓰는 컬럼입니다. seqNo이면 _seq_no와
+// _primary_term을, deleted이면 tombstone 행을 표시하는 _deleted를 씁니다. 가져오기는 이
+// 컬럼들을 문서에서 뺍니다.
+type sinkColumns struct {
+	seqNo   bool
+	deleted bool
+	docHash bool
+	// geoBounds는 geo 필드의 원래 이름별 [xmin, ymin, xmax, ymax]로, geoBoundsKey 메타데이터와
+	// GeoParquet 메타데이터의 bbox가 됩니다.
+	geoBounds map[string][]float64
+	// overrides는 원래 필드 경로별로 그 필드의 변환을 바꾼 플래그로, 컬럼 계보에 씁니다.
+	overrides map[string][]string
+}
+
+func newParquetSink(path string, schema *arrow.Schema, mapping []byte, extra sinkColumns, names *nameOptions, opts *parquetOptions) (*parquetSink, error) {
+	var renames []fieldRename
+	if names.enabled() {
+		var err error
+		if schema, renames, err = names.apply(schema); err != nil {
+			return nil, err
+		}
+	}
+	fields := []arrow.Field{{Name: "_id", Type: arrow.BinaryTypes.String}}
+	if extra.seqNo {
+		fields = append(fields,
+			arrow.Field{Name: "_seq_no", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+			arrow.Field{Name: "_primary_term", Type: arrow.PrimitiveTypes.Int64, Nullable: true})
+	}
+	if extra.deleted {
+		fields = append(fields, arrow.Field{Name: deletedColumn, Type: arrow.FixedWidthTypes.Boolean})
+	}
+	if extra.docHash {
+		fields = append(fields, arrow.Field{Name: docHashColumn, Type: arrow.BinaryTypes.String, Nullable: true})
+	}
+	fields = append(fields, schema.Fields()...)
+	withID, err := withMappingMetadata(arrow.NewSchema(fields, nil), mapping)
+	if err != nil {
+		return nil, schemaErrorf("embedding mapping metadata: %w", err)
+	}
+	lineage, err := schemaLineage(withID, mapping, extra.overrides)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := json.Marshal(lineage); err == nil {
+		withID = setSchemaMetadata(withID, lineageKey, string(data))
+	}
+	if geo, ok := geoMetadata(withID, extra.geoBounds); ok {
+		withID = setSchemaMetadata(withID, geoMetadataKey, geo)
+	}
+	if len(extra.geoBounds) > 0 {
+		bounds, _ := json.Marshal(extra.geoBounds)
+		withID = setSchemaMetadata(withID, geoBoundsKey, string(bounds))
+	}
+	w, err := createParquetFile(path, withID, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &parquetSink{parquetWriter: w, schema: withID, renames: renames, extra: extra, lineage: lineage}, nil
+}
+
+func (s *parquetSink) writeHits(rec arrow.Record, hits []searchHit) error {
+	ids := array.NewBuilder(memory.DefaultAllocator, arrow.BinaryTypes.String).(*array.StringBuilder)
+	defer ids.Release()
+	for _, hit := range hits {
+		ids.Append(hit.ID)
+	}
+	idArr := ids.NewArray()
+	defer idArr.Release()
+
+	cols := []arrow.Array{idArr}
+	if s.extra.seqNo {
+		seqNos, terms := hitVersions(hits)
+		defer seqNos.Release()
+		defer terms.Release()
+		cols = append(cols, seqNos, terms)
+	}
+	if s.extra.deleted {
+		deleted := constantBooleans(false, len(hits))
+		defer deleted.Release()
+		cols = append(cols, deleted)
+	}
+	if s.extra.docHash {
+		hashes := hitHashes(hits)
+		defer hashes.Release()
+		cols = append(cols, hashes)
+	}
+	offset := len(cols)
+	for i, col := range rec.Columns() {
+		col = retypeArray(col, s.schema.Field(i+offset).Type)
+		defer col.Release()
+		cols = append(cols, col)
+	}
+	out := array.NewRecord(s.schema, cols, rec.NumRows())
+	defer out.Release()
+	return s.write(out)
+}
+
+// writeTombstones 함수는 ids마다 _deleted가 true이고 다른 컬럼은 모두 null인 행을 씁니다.
+func (s *parquetSink) writeTombstones(ids []string) error {
+	b := array.NewBuilder(memory.DefaultAllocator, arrow.BinaryTypes.String).(*array.StringBuilder)
+	defer b.Release()
+	b.AppendValues(ids, nil)
+	idArr := b.NewArray()
+	defer idArr.Release()
+
+	cols := []arrow.Array{idArr}
+	for _, f := range s.schema.Fields()[1:] {
+		var col arrow.Array
+		if f.Name == deletedColumn {
+			col = constantBooleans(true, len(ids))
+		} else {
+			col = array.MakeArrayOfNull(memory.DefaultAllocator, f.Type, len(ids))
+		}
+		defer col.Release()
+		cols = append(cols, col)
+	}
+	out := array.NewRecord(s.schema, cols, int64(len(ids)))
+	defer out.Release()
+	return s.write(out)
+}
+
+// hitHashes 함수는 hits의 _doc_hash를 컬럼으로 만듭니다. 해시가 없는 문서는 null입니다.
+func hitHashes(hits []searchHit) arrow.Array {
+	b := array.NewBuilder(memory.DefaultAllocator, arrow.BinaryTypes.String).(*array.StringBuilder)
+	defer b.Release()
+	for _, hit := range hits {
+		if hit.docHash == "" {
+			b.AppendNull()
+		} else {
+			b.Append(hit.docHash)
+		}
+	}
+	return b.NewArray()
+}
+
+// hitVersions 함수는 hits의 _seq_no와 _primary_term을 컬럼으로 만듭니다. 값이 없는 문서는
+// null입니다.
+func hitVersions(hits []searchHit) (arrow.Array, arrow.Array) {
+	seqNos := array.NewBuilder(memory.DefaultAllocator, arrow.PrimitiveTypes.Int64).(*array.Int64Builder)
+	defer seqNos.Release()
+	terms := array.NewBuilder(memory.DefaultAllocator, arrow.PrimitiveTypes.Int64).(*array.Int64Builder)
+	defer terms.Release()
+	for _, hit := range hits {
+		appendOptionalInt64(seqNos, hit.SeqNo)
+		appendOptionalInt64(terms, hit.PrimaryTerm)
+	}
+	return seqNos.NewArray(), terms.NewArray()
+}
+
+func appendOptionalInt64(b *array.Int64Builder, v *int64) {
+	if v == nil {
+		b.AppendNull()
+		return
+	}
+	b.Append(*v)
+}
+
+// constantBooleans 함수는 n개의 값이 모두 v인 불리언 배열을 만듭니다.
+func constantBooleans(v bool, n int) arrow.Array {
+	b := array.NewBuilder(memory.DefaultAllocator, arrow.FixedWidthTypes.Boolean).(*array.BooleanBuilder)
+	defer b.Release()
+	for i := 0; i < n; i++ {
+		b.Append(v)
+	}
+	return b.NewArray()
+}
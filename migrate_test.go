@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMigrateWritesDatesTheDestinationReads(t *testing.T) {
+	src := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/":
+			fmt.Fprint(w, `{"version": {"number": "6.8.23"}}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/logs/_mapping":
+			fmt.Fprint(w, `{"logs": {"mappings": {"doc": {"properties": {"at": {"type": "date", "format": "epoch_millis"}, "message": {"type": "keyword"}}}}}}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/logs/_search":
+			fmt.Fprint(w, `{"_scroll_id": "s1", "hits": {"hits": [{"_id": "1", "_source": {"at": 1700000000000, "message": "GET /"}}]}}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/_search/scroll":
+			fmt.Fprint(w, `{"_scroll_id": "s1", "hits": {"hits": []}}`)
+		case r.Method == http.MethodDelete:
+		default:
+			t.Errorf("source: unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer src.Close()
+
+	var created map[string]interface{}
+	var docs []map[string]interface{}
+	dst := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/":
+			fmt.Fprint(w, `{"version": {"number": "8.11.0"}}`)
+		case r.Method == http.MethodHead && r.URL.Path == "/logs":
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodPut && r.URL.Path == "/logs":
+			data, _ := io.ReadAll(r.Body)
+			json.Unmarshal(data, &created)
+			fmt.Fprint(w, `{"acknowledged": true}`)
+		case r.Method == http.MethodPost && (r.URL.Path == "/_bulk" || r.URL.Path == "/logs/_bulk"):
+			resp := bulkResponse{}
+			scanner := bufio.NewScanner(r.Body)
+			for i := 0; scanner.Scan(); i++ {
+				if i%2 == 1 {
+					var doc map[string]interface{}
+					json.Unmarshal(scanner.Bytes(), &doc)
+					docs = append(docs, doc)
+					resp.Items = append(resp.Items, map[string]bulkItemResult{"index": {Status: 201}})
+				}
+			}
+			json.NewEncoder(w).Encode(resp)
+		default:
+			t.Errorf("destination: unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer dst.Close()
+
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	run := setupMigrate(fs)
+	if err := fs.Parse([]string{"--source-url", src.URL, "--dest-url", dst.URL, "--index", "logs"}); err != nil {
+		t.Fatal(err)
+	}
+	report := newRunReport("migrate")
+	if err := run(context.Background(), report, fs.Args()); err != nil {
+		t.Fatal(err)
+	}
+
+	at := created["mappings"].(map[string]interface{})["properties"].(map[string]interface{})["at"].(map[string]interface{})
+	format, _ := at["format"].(string)
+	if !acceptsISODates(format) {
+		t.Errorf("destination format of at = %q, which cannot read RFC 3339 dates", format)
+	}
+	if len(docs) != 1 || docs[0]["at"] != "2023-11-14T22:13:20Z" || docs[0]["message"] != "GET /" {
+		t.Errorf("migrated documents = %v", docs)
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+	"github.com/apache/arrow/go/v10/arrow/memory"
+)
+
+// originalNameKey는 이름을 바꾼 필드에 원래 Elasticsearch 필드 이름을 남기는 필드 메타데이터
+// 키입니다. 가져오기 시 이 이름으로 문서를 되돌립니다.
+const originalNameKey = "es_schema.original_name"
+
+const (
+	collisionSuffix = "suffix"
+	collisionError  = "error"
+)
+
+// nameOptions는 Elasticsearch 필드 이름을 다른 시스템이 받아들이는 컬럼 이름으로 바꾸는
+// 규칙입니다. 이름이 바뀐 필드는 originalNameKey 메타데이터에 원래 이름을 남깁니다.
+type nameOptions struct {
+	sanitize  bool
+	lowercase bool
+	maxLength int
+	collision string
+}
+
+func (o *nameOptions) bind(fs *flag.FlagSet) {
+	fs.BoolVar(&o.sanitize, "sanitize-names", false, "replace dots, spaces and other characters outside [A-Za-z0-9_] in field names with _")
+	fs.BoolVar(&o.lowercase, "lowercase-names", false, "lowercase field names")
+	fs.IntVar(&o.maxLength, "max-name-length", 0, "truncate field names to this many characters (0: no limit)")
+	fs.StringVar(&o.collision, "name-collision", collisionSuffix, "when renamed fields collide: suffix (append _2, _3, ...) or error")
+}
+
+func (o *nameOptions) validate() error {
+	switch o.collision {
+	case collisionSuffix, collisionError:
+	default:
+		return configErrorf("unknown --name-collision %q (want suffix or error)", o.collision)
+	}
+	if o.maxLength < 0 || (o.maxLength > 0 && o.maxLength < 4) {
+		return configErrorf("--max-name-length must be 0 or at least 4")
+	}
+	return nil
+}
+
+func (o *nameOptions) enabled() bool {
+	return o.sanitize || o.lowercase || o.maxLength > 0
+}
+
+// fieldRename은 이름이 바뀐 필드 하나의 원래 경로와 새 경로입니다.
+type fieldRename struct {
+	From string
+	To   string
+}
+
+// apply 함수는 스키마의 모든 필드(구조체 안의 필드 포함) 이름을 규칙대로 바꾼 스키마와
+// 바뀐 이름 목록을 반환합니다. 스키마 메타데이터는 그대로 유지합니다.
+func (o *nameOptions) apply(schema *arrow.Schema) (*arrow.Schema, []fieldRename, error) {
+	var renames []fieldRename
+	fields, err := o.renameFields(schema.Fields(), "", "", &renames)
+	if err != nil {
+		return nil, nil, err
+	}
+	md := schema.Metadata()
+	return arrow.NewSchema(fields, &md), renames, nil
+}
+
+// renameFields 함수는 한 단계의 형제 필드 이름을 바꿉니다. 이름이 그대로인 필드가 먼저
+// 이름을 차지하고, 바뀐 이름끼리 또는 기존 이름과 겹치면 --name-collision을 따릅니다.
+func (o *nameOptions) renameFields(fields []arrow.Field, prefix, newPrefix string, renames *[]fieldRename) ([]arrow.Field, error) {
+	cleaned := make([]string, len(fields))
+	taken := make(map[string]bool, len(fields))
+	for i, f := range fields {
+		cleaned[i] = o.clean(f.Name)
+		if cleaned[i] == f.Name {
+			taken[f.Name] = true
+		}
+	}
+
+	out := make([]arrow.Field, len(fields))
+	for i, f := range fields {
+		name := cleaned[i]
+		if name != f.Name {
+			if taken[name] {
+				if o.collision == collisionError {
+					return nil, schemaErrorf("field %s%s would be renamed to %s%s, which is already taken", prefix, f.Name, newPrefix, name)
+				}
+				for n := 2; taken[name]; n++ {
+					name = o.withSuffix(cleaned[i], n)
+				}
+			}
+			taken[name] = true
+			*renames = append(*renames, fieldRename{From: prefix + f.Name, To: newPrefix + name})
+		}
+
+		t, err := o.renameType(f.Type, prefix+f.Name+".", newPrefix+name+".", renames)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = arrow.Field{Name: name, Type: t, Nullable: f.Nullable, Metadata: f.Metadata}
+		if name != f.Name {
+			out[i].Metadata = withMetadataValue(f.Metadata, originalNameKey, f.Name)
+		}
+	}
+	return out, nil
+}
+
+// renameType 함수는 구조체(와 구조체 리스트) 안의 필드 이름을 바꿉니다.
+func (o *nameOptions) renameType(t arrow.DataType, prefix, newPrefix string, renames *[]fieldRename) (arrow.DataType, error) {
+	switch t := t.(type) {
+	case *arrow.StructType:
+		fields, err := o.renameFields(t.Fields(), prefix, newPrefix, renames)
+		if err != nil {
+			return nil, err
+		}
+		return arrow.StructOf(fields...), nil
+	case *arrow.ListType:
+		elem, err := o.renameType(t.Elem(), prefix, newPrefix, renames)
+		if err != nil {
+			return nil, err
+		}
+		return arrow.ListOf(elem), nil
+	}
+	return t, nil
+}
+
+// clean 함수는 이름 하나에 규칙을 적용합니다.
+func (o *nameOptions) clean(name string) string {
+	if o.sanitize {
+		name = strings.Map(func(r rune) rune {
+			if r == '_' || (r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r))) {
+				return r
+			}
+			return '_'
+		}, name)
+		if name == "" || unicode.IsDigit(rune(name[0])) {
+			name = "_" + name
+		}
+	}
+	if o.lowercase {
+		name = strings.ToLower(name)
+	}
+	return o.truncate(name, o.maxLength)
+}
+
+// withSuffix 함수는 겹치는 이름에 "_n"을 붙이되, 최대 길이를 넘지 않도록 앞부분을 줄입니다.
+func (o *nameOptions) withSuffix(name string, n int) string {
+	suffix := "_" + strconv.Itoa(n)
+	if o.maxLength > 0 {
+		name = o.truncate(name, o.maxLength-len(suffix))
+	}
+	return name + suffix
+}
+
+func (o *nameOptions) truncate(name string, max int) string {
+	if max <= 0 {
+		return name
+	}
+	if runes := []rune(name); len(runes) > max {
+		return string(runes[:max])
+	}
+	return name
+}
+
+// originalName 함수는 필드의 원래 Elasticsearch 이름을 반환합니다.
+func originalName(f arrow.Field) string {
+	if idx := f.Metadata.FindKey(originalNameKey); idx >= 0 {
+		return f.Metadata.Values()[idx]
+	}
+	return f.Name
+}
+
+// recordRenames 함수는 바뀐 이름을 출력하고 보고서에 남깁니다.
+func recordRenames(report *runReport, renames []fieldRename) {
+	if len(renames) == 0 {
+		return
+	}
+	if report.RenamedFields == nil {
+		report.RenamedFields = make(map[string]string, len(renames))
+	}
+	fmt.Printf("renamed %d fields:\n", len(renames))
+	for _, r := range renames {
+		fmt.Printf("  %s -> %s\n", r.From, r.To)
+		report.RenamedFields[r.From] = r.To
+	}
+}
+
+// renameRecord 함수는 rec의 열을 이름만 바뀐 schema의 레코드로 다시 감쌉니다. 구조체
+// 열은 타입에 필드 이름이 들어 있으므로 버퍼는 그대로 두고 타입만 바꿔 다시 만듭니다.
+func renameRecord(rec arrow.Record, schema *arrow.Schema) arrow.Record {
+	cols := make([]arrow.Array, len(rec.Columns()))
+	for i, col := range rec.Columns() {
+		cols[i] = retypeArray(col, schema.Field(i).Type)
+		defer cols[i].Release()
+	}
+	return array.NewRecord(schema, cols, rec.NumRows())
+}
+
+// retypeArray 함수는 arr과 물리적 구조가 같은 dt 타입의 배열을 만듭니다. float16 값만은
+// float32로 새로 씁니다(parquetFileSchema).
+func retypeArray(arr arrow.Array, dt arrow.DataType) arrow.Array {
+	if arrow.TypeEqual(arr.DataType(), dt) {
+		arr.Retain()
+		return arr
+	}
+	data := retypeData(arr.Data(), dt)
+	defer data.Release()
+	return array.MakeFromData(data)
+}
+
+func retypeData(d arrow.ArrayData, dt arrow.DataType) arrow.ArrayData {
+	if arrow.TypeEqual(d.DataType(), dt) {
+		// 사전 배열처럼 타입만으로 다시 만들 수 없는 배열도 그대로 둡니다.
+		d.Retain()
+		return d
+	}
+	if d.DataType().ID() == arrow.FLOAT16 && dt.ID() == arrow.FLOAT32 {
+		return float16AsFloat32(d)
+	}
+	children := d.Children()
+	var childTypes []arrow.DataType
+	switch t := dt.(type) {
+	case *arrow.StructType:
+		for _, f := range t.Fields() {
+			childTypes = append(childTypes, f.Type)
+		}
+	case *arrow.ListType:
+		childTypes = []arrow.DataType{t.Elem()}
+	case *arrow.FixedSizeListType:
+		childTypes = []arrow.DataType{t.Elem()}
+	}
+	if len(childTypes) == len(children) {
+		retyped := make([]arrow.ArrayData, len(children))
+		for i, c := range children {
+			retyped[i] = retypeData(c, childTypes[i])
+			defer retyped[i].Release()
+		}
+		children = retyped
+	}
+	return array.NewData(dt, d.Len(), d.Buffers(), children, d.NullN(), d.Offset())
+}
+
+// float16AsFloat32 함수는 float16 배열을 같은 값의 float32 배열로 바꿉니다.
+func float16AsFloat32(d arrow.ArrayData) arrow.ArrayData {
+	src := array.NewFloat16Data(d)
+	defer src.Release()
+	b := array.NewFloat32Builder(memory.DefaultAllocator)
+	defer b.Release()
+	b.Reserve(src.Len())
+	for i := 0; i < src.Len(); i++ {
+		if src.IsNull(i) {
+			b.AppendNull()
+			continue
+		}
+		b.Append(src.Value(i).Float32())
+	}
+	arr := b.NewArray()
+	defer arr.Release()
+	data := arr.Data()
+	data.Retain()
+	return data
+}
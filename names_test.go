@@ -0,0 +1,58 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+func TestNameOptionsRenameAndResolveCollisions(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "a.b", Type: arrow.BinaryTypes.String},
+		{Name: "a_b", Type: arrow.BinaryTypes.String},
+		{Name: "Host Name", Type: arrow.BinaryTypes.String},
+		{Name: "user", Type: arrow.StructOf(arrow.Field{Name: "First.Name", Type: arrow.BinaryTypes.String})},
+	}, nil)
+
+	o := nameOptions{sanitize: true, lowercase: true, collision: collisionSuffix}
+	renamed, renames, err := o.apply(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []fieldRename{
+		{From: "a.b", To: "a_b_2"},
+		{From: "Host Name", To: "host_name"},
+		{From: "user.First.Name", To: "user.first_name"},
+	}
+	if !reflect.DeepEqual(renames, want) {
+		t.Errorf("renames = %v, want %v", renames, want)
+	}
+	if got := originalName(renamed.Field(0)); got != "a.b" {
+		t.Errorf("original name of %s = %q, want a.b", renamed.Field(0).Name, got)
+	}
+	inner := renamed.Field(3).Type.(*arrow.StructType).Field(0)
+	if inner.Name != "first_name" || originalName(inner) != "First.Name" {
+		t.Errorf("nested field = %s (original %s)", inner.Name, originalName(inner))
+	}
+
+	o.collision = collisionError
+	if _, _, err := o.apply(schema); kindOf(err) != kindSchema {
+		t.Errorf("collision with --name-collision error: err = %v, want schema error", err)
+	}
+}
+
+func TestNameOptionsMaxLengthKeepsSuffixInsideLimit(t *testing.T) {
+	o := nameOptions{maxLength: 6, collision: collisionSuffix}
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "abcdefgh", Type: arrow.BinaryTypes.String},
+		{Name: "abcdefxy", Type: arrow.BinaryTypes.String},
+	}, nil)
+	renamed, _, err := o.apply(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := renamed.Field(0).Name, renamed.Field(1).Name; a != "abcdef" || b != "abcd_2" {
+		t.Errorf("names = %q, %q; want abcdef, abcd_2", a, b)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"sort"
+	"strings"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+const (
+	nestedAsNested = "nested"
+	nestedAsParent = "parent"
+)
+
+// nestedCopyOptions는 include_in_parent나 include_in_root가 있는 nested 필드를 어떤 모양으로
+// 내보낼지 정합니다. 이런 필드의 값은 인덱스에서 nested 문서와 부모 문서 양쪽에 들어가지만
+// _source에는 한 번만 있으므로, 컬럼도 둘 중 한 모양으로 한 번만 씁니다.
+type nestedCopyOptions struct {
+	as string
+}
+
+func (o *nestedCopyOptions) bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.as, "include-in-parent-as", nestedAsNested, "column shape for nested fields with include_in_parent or include_in_root, whose values the index holds twice: nested (a list of structs, as in _source) or parent (a struct of value lists, as queries on the parent document see them; which values came from the same object is lost)")
+}
+
+func (o *nestedCopyOptions) validate() error {
+	switch o.as {
+	case nestedAsNested, nestedAsParent:
+		return nil
+	}
+	return configErrorf("unknown --include-in-parent-as %q (want nested or parent)", o.as)
+}
+
+// copiedNestedPaths 함수는 매핑에서 include_in_parent나 include_in_root가 켜진 nested 필드의
+// 경로를 정렬해 반환합니다. 그런 필드 안의 nested 필드는 바깥 필드와 함께 바뀌므로 따로
+// 고르지 않습니다.
+func copiedNestedPaths(mapping []byte) ([]string, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal(mapping, &m); err != nil {
+		return nil, schemaErrorf("decoding mapping: %w", err)
+	}
+	props, _ := m["properties"].(map[string]interface{})
+	var paths []string
+	collectCopiedNested(props, "", &paths)
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func collectCopiedNested(props map[string]interface{}, prefix string, out *[]string) {
+	for name, v := range props {
+		field, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if field["type"] == "nested" && (field["include_in_parent"] == true || field["include_in_root"] == true) {
+			*out = append(*out, prefix+name)
+			continue
+		}
+		if sub, ok := field["properties"].(map[string]interface{}); ok {
+			collectCopiedNested(sub, prefix+name+".", out)
+		}
+	}
+}
+
+// parentView 함수는 경로의 nested 필드를 부모 문서가 보는 모양, 즉 잎 필드마다 값 리스트를
+// 가진 구조체로 바꾼 필드 목록을 반환합니다. 경로에 구조체(리스트) 필드가 없으면 false입니다.
+func parentView(fields []arrow.Field, path []string) ([]arrow.Field, bool) {
+	return updateField(fields, path, func(f arrow.Field) (arrow.Field, bool) {
+		t, ok := parentType(f.Type)
+		f.Type, f.Nullable = t, true
+		return f, ok
+	})
+}
+
+// parentType 함수는 구조체(리스트) 타입을 잎마다 리스트인 구조체 타입으로 바꿉니다.
+func parentType(t arrow.DataType) (arrow.DataType, bool) {
+	if lt, ok := t.(*arrow.ListType); ok {
+		t = lt.Elem()
+	}
+	st, ok := t.(*arrow.StructType)
+	if !ok {
+		return nil, false
+	}
+	children := make([]arrow.Field, len(st.Fields()))
+	for i, c := range st.Fields() {
+		children[i] = arrow.Field{Name: c.Name, Type: c.Type, Nullable: true, Metadata: c.Metadata}
+		if sub, ok := parentType(c.Type); ok {
+			children[i].Type = sub
+		} else if c.Type.ID() != arrow.LIST {
+			children[i].Type = arrow.ListOf(c.Type)
+		}
+	}
+	return arrow.StructOf(children...), true
+}
+
+// parentTransform 함수는 paths의 nested 객체 배열을 parentView 모양의 객체 하나로 모으는
+// 변환을 만듭니다. 객체마다 같은 잎의 값은 나온 순서대로 한 리스트에 이어 붙입니다.
+func parentTransform(paths []string) docTransform {
+	return func(doc map[string]interface{}) (map[string]interface{}, error) {
+		for _, p := range paths {
+			collapseAtPath(doc, strings.Split(p, "."))
+		}
+		return doc, nil
+	}
+}
+
+func collapseAtPath(v interface{}, path []string) {
+	switch x := v.(type) {
+	case []interface{}:
+		for _, item := range x {
+			collapseAtPath(item, path)
+		}
+	case map[string]interface{}:
+		child, ok := x[path[0]]
+		if !ok || child == nil {
+			return
+		}
+		if len(path) > 1 {
+			collapseAtPath(child, path[1:])
+			return
+		}
+		merged := make(map[string]interface{})
+		mergeParent(merged, child)
+		x[path[0]] = merged
+	}
+}
+
+// mergeParent 함수는 객체(또는 객체 배열) v의 값을 dst에 합칩니다. 하위 객체는 dst 안의
+// 객체로 들어가 합치고, 나머지 값은 리스트에 더합니다.
+func mergeParent(dst map[string]interface{}, v interface{}) {
+	switch x := v.(type) {
+	case []interface{}:
+		for _, item := range x {
+			mergeParent(dst, item)
+		}
+	case map[string]interface{}:
+		for k, val := range x {
+			if isObjectValue(val) {
+				sub, _ := dst[k].(map[string]interface{})
+				if sub == nil {
+					sub = make(map[string]interface{})
+					dst[k] = sub
+				}
+				mergeParent(sub, val)
+				continue
+			}
+			list, _ := dst[k].([]interface{})
+			switch val := val.(type) {
+			case nil:
+			case []interface{}:
+				for _, item := range val {
+					if item != nil {
+						list = append(list, item)
+					}
+				}
+			default:
+				list = append(list, val)
+			}
+			if list != nil {
+				dst[k] = list
+			}
+		}
+	}
+}
+
+// isObjectValue 함수는 v가 객체이거나 객체 배열인지 알려 줍니다.
+func isObjectValue(v interface{}) bool {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		return true
+	case []interface{}:
+		for _, item := range x {
+			if item != nil {
+				_, ok := item.(map[string]interface{})
+				return ok
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+func TestCopiedNestedPaths(t *testing.T) {
+	paths, err := copiedNestedPaths([]byte(`{"properties": {
+		"users": {"type": "nested", "include_in_parent": true, "properties": {
+			"addresses": {"type": "nested", "include_in_root": true, "properties": {"city": {"type": "keyword"}}}
+		}},
+		"plain": {"type": "nested", "properties": {"name": {"type": "keyword"}}},
+		"meta": {"properties": {"tags": {"type": "nested", "include_in_root": true, "properties": {"k": {"type": "keyword"}}}}}
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"meta.tags", "users"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("paths = %v, want %v", paths, want)
+	}
+}
+
+func TestParentTransform(t *testing.T) {
+	doc, err := decodeDocument([]byte(`{
+		"users": [
+			{"first": "Ann", "tags": ["a", "b"], "address": {"city": "Oslo"}},
+			{"first": "Bo", "last": null, "address": [{"city": "Rome"}, {"city": "Lima"}]}
+		],
+		"meta": {"tags": {"k": "x"}}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := parentTransform([]string{"meta.tags", "users"})(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := decodeJSONValue([]byte(`{
+		"users": {"first": ["Ann", "Bo"], "tags": ["a", "b"], "address": {"city": ["Oslo", "Rome", "Lima"]}},
+		"meta": {"tags": {"k": ["x"]}}
+	}`))
+	if !reflect.DeepEqual(out, want) {
+		t.Errorf("parent view = %v, want %v", out, want)
+	}
+
+	if err := (&nestedCopyOptions{as: "both"}).validate(); err == nil {
+		t.Error("validate accepted an unknown shape")
+	}
+}
+
+func TestParentView(t *testing.T) {
+	users := arrow.StructOf(
+		arrow.Field{Name: "first", Type: arrow.BinaryTypes.String},
+		arrow.Field{Name: "address", Type: arrow.StructOf(arrow.Field{Name: "city", Type: arrow.BinaryTypes.String})},
+	)
+	fields, ok := parentView([]arrow.Field{{Name: "users", Type: arrow.ListOf(users)}}, []string{"users"})
+	if !ok {
+		t.Fatal("parentView failed")
+	}
+	want := arrow.StructOf(
+		arrow.Field{Name: "first", Type: arrow.ListOf(arrow.BinaryTypes.String), Nullable: true},
+		arrow.Field{Name: "address", Type: arrow.StructOf(arrow.Field{Name: "city", Type: arrow.ListOf(arrow.BinaryTypes.String), Nullable: true}), Nullable: true},
+	)
+	if !arrow.TypeEqual(fields[0].Type, want) {
+		t.Errorf("parent view type = %s, want %s", fields[0].Type, want)
+	}
+	if _, ok := parentView([]arrow.Field{{Name: "users", Type: arrow.BinaryTypes.String}}, []string{"users"}); ok {
+		t.Error("parentView accepted a string field")
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/apache/arrow/go/v10/arrow"
+
+	"es-schema/esschema"
+)
+
+const (
+	nonDataSkip = "skip"
+	nonDataJSON = "json"
+
+	// jsonValueKey는 값을 JSON 문자열로 담은 컬럼에 붙이는 필드 메타데이터 키입니다.
+	// 가져오기는 이 컬럼의 문자열을 다시 JSON 값으로 풉니다.
+	jsonValueKey = "es_schema.json_value"
+)
+
+// nonDataTypes는 문서 데이터가 아닌 것을 담는 필드 타입과 그 이유입니다. percolator는
+// 검색 조건을, alias는 다른 필드를 가리킬 뿐 _source에 값이 없습니다.
+var nonDataTypes = map[string]string{
+	"percolator": "holds stored queries, not document data",
+	"alias":      "has no values of its own in _source",
+}
+
+// nonDataOptions는 --non-data-fields 설정입니다. skip이면 이런 필드를 컬럼으로 만들지 않고,
+// json이면 percolator 쿼리를 JSON 문자열 컬럼으로 씁니다. alias는 쓸 값이 없으므로 늘
+// 건너뜁니다.
+type nonDataOptions struct {
+	policy string
+}
+
+func (o *nonDataOptions) bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.policy, "non-data-fields", nonDataSkip, "fields that hold no document data: skip (leave percolator and alias fields out with a warning) or json (write percolator queries as JSON string columns; alias fields are still left out)")
+}
+
+func (o *nonDataOptions) validate() error {
+	switch o.policy {
+	case nonDataSkip, nonDataJSON:
+		return nil
+	}
+	return configErrorf("unknown --non-data-fields %q (want skip or json)", o.policy)
+}
+
+// split 함수는 매핑의 문서 데이터가 아닌 필드를 건너뛸 경로와 JSON으로 쓸 경로로 나눠
+// 정렬해 반환합니다.
+func (o *nonDataOptions) split(mapping []byte) (skip, asJSON []string, types map[string]string, err error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal(mapping, &m); err != nil {
+		return nil, nil, nil, schemaErrorf("decoding mapping: %w", err)
+	}
+	props, _ := m["properties"].(map[string]interface{})
+	types = make(map[string]string)
+	nonDataFields(props, "", types)
+	for path, t := range types {
+		if o.policy == nonDataJSON && t == "percolator" {
+			asJSON = append(asJSON, path)
+		} else {
+			skip = append(skip, path)
+		}
+	}
+	sort.Strings(skip)
+	sort.Strings(asJSON)
+	return skip, asJSON, types, nil
+}
+
+// nonDataFields 함수는 properties 아래의 문서 데이터가 아닌 필드 경로와 타입을 out에
+// 모읍니다.
+func nonDataFields(props map[string]interface{}, prefix string, out map[string]string) {
+	for name, v := range props {
+		field, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if sub, ok := field["properties"].(map[string]interface{}); ok {
+			nonDataFields(sub, prefix+name+".", out)
+			continue
+		}
+		if t, _ := field["type"].(string); nonDataTypes[t] != "" {
+			out[prefix+name] = t
+		}
+	}
+}
+
+// skipWarning 함수는 건너뛴 필드의 경고 문구를 만듭니다.
+func (o *nonDataOptions) skipWarning(path, esType string) string {
+	msg := fmt.Sprintf("skipped %s field %s, which %s", esType, path, nonDataTypes[esType])
+	if esType == "percolator" {
+		msg += "; pass --non-data-fields json to export its queries as JSON strings"
+	}
+	return msg
+}
+
+// jsonColumn 함수는 점으로 구분된 경로의 문자열 필드에 jsonValueKey를 붙인 필드 목록을
+// 반환합니다. 경로에 문자열 필드가 없으면 false입니다.
+func jsonColumn(fields []arrow.Field, path []string) ([]arrow.Field, bool) {
+	return updateField(fields, path, func(f arrow.Field) (arrow.Field, bool) {
+		f.Metadata = withMetadataValue(f.Metadata, jsonValueKey, "true")
+		return f, f.Type.ID() == arrow.STRING
+	})
+}
+
+// isJSONColumn 함수는 f가 값을 JSON 텍스트로 담은 컬럼(--non-data-fields json,
+// --geo-shape json)인지 알려 줍니다.
+func isJSONColumn(f arrow.Field) bool {
+	return f.Metadata.FindKey(jsonValueKey) >= 0 || f.Metadata.FindKey(esschema.GeoShapeJSONKey) >= 0
+}
+
+// jsonTransform 함수는 paths의 값을 압축한 JSON 문자열로 바꾸는 변환을 만듭니다. 객체
+// 배열 안의 값은 원소마다 바꿉니다.
+func jsonTransform(paths []string) docTransform {
+	return func(doc map[string]interface{}) (map[string]interface{}, error) {
+		for _, p := range paths {
+			if err := marshalAtPath(doc, strings.Split(p, ".")); err != nil {
+				return nil, dataErrorf("encoding %s as JSON: %w", p, err)
+			}
+		}
+		return doc, nil
+	}
+}
+
+func marshalAtPath(v interface{}, path []string) error {
+	switch x := v.(type) {
+	case []interface{}:
+		for _, item := range x {
+			if err := marshalAtPath(item, path); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		child, ok := x[path[0]]
+		if !ok || child == nil {
+			return nil
+		}
+		if len(path) > 1 {
+			return marshalAtPath(child, path[1:])
+		}
+		data, err := json.Marshal(child)
+		if err != nil {
+			return err
+		}
+		x[path[0]] = string(data)
+	}
+	return nil
+}
+
+// unmarshalJSONValue 함수는 jsonValueKey 컬럼의 문자열을 JSON 값으로 되돌립니다. 풀 수
+// 없는 문자열은 그대로 둡니다.
+func unmarshalJSONValue(v interface{}) interface{} {
+	s, ok := v.(string)
+	if !ok {
+		return v
+	}
+	decoded, err := decodeJSONValue([]byte(s))
+	if err != nil {
+		return v
+	}
+	return decoded
+}
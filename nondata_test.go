@@ -0,0 +1,74 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNonDataSplit(t *testing.T) {
+	mapping := []byte(`{"properties": {
+		"query": {"type": "percolator"},
+		"title": {"type": "text"},
+		"title_alias": {"type": "alias", "path": "title"},
+		"rules": {"type": "nested", "properties": {"match": {"type": "percolator"}, "name": {"type": "keyword"}}}
+	}}`)
+	o := nonDataOptions{policy: nonDataSkip}
+	skip, asJSON, types, err := o.split(mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"query", "rules.match", "title_alias"}; !reflect.DeepEqual(skip, want) || asJSON != nil {
+		t.Errorf("skip = %v, json = %v; want %v and none", skip, asJSON, want)
+	}
+	if types["title_alias"] != "alias" || types["query"] != "percolator" {
+		t.Errorf("types = %v", types)
+	}
+
+	o.policy = nonDataJSON
+	if skip, asJSON, _, err = o.split(mapping); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(skip, []string{"title_alias"}) || !reflect.DeepEqual(asJSON, []string{"query", "rules.match"}) {
+		t.Errorf("skip = %v, json = %v", skip, asJSON)
+	}
+
+	if err := (&nonDataOptions{policy: "string"}).validate(); err == nil {
+		t.Error("validate accepted an unknown policy")
+	}
+}
+
+func TestJSONTransform(t *testing.T) {
+	doc, err := decodeDocument([]byte(`{
+		"query": {"match": {"title": "big cat"}, "boost": 1.50},
+		"rules": [{"match": {"term": {"tag": "x"}}}, {"name": "no query"}],
+		"title": "kept"
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := jsonTransform([]string{"query", "rules.match"})(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out["query"], `{"boost":1.50,"match":{"title":"big cat"}}`; got != want {
+		t.Errorf("query = %v, want %s", got, want)
+	}
+	rules := out["rules"].([]interface{})
+	if got := rules[0].(map[string]interface{})["match"]; got != `{"term":{"tag":"x"}}` {
+		t.Errorf("rules[0].match = %v", got)
+	}
+	if _, ok := rules[1].(map[string]interface{})["match"]; ok {
+		t.Error("a missing value was added")
+	}
+	if out["title"] != "kept" {
+		t.Errorf("title = %v", out["title"])
+	}
+
+	back := unmarshalJSONValue(out["query"])
+	if want, _ := decodeJSONValue([]byte(`{"match": {"title": "big cat"}, "boost": 1.50}`)); !reflect.DeepEqual(back, want) {
+		t.Errorf("query did not round-trip: %v", back)
+	}
+	if got := unmarshalJSONValue("not json"); got != "not json" {
+		t.Errorf("invalid JSON = %v, want it unchanged", got)
+	}
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/memory"
+
+	"es-schema/esschema"
+)
+
+// normalizer는 _source JSON 문서를 매핑으로 만든 Arrow 스키마에 맞춰 레코드로 바꿉니다.
+// Elasticsearch에서는 어떤 필드든 배열일 수 있으므로, 문서에서 배열로 나타난 필드는
+// 리스트 타입으로 넓히고 넓힌 스키마를 이후 배치에도 그대로 씁니다.
+type normalizer struct {
+	schema *arrow.Schema
+	// dropped는 매핑된 타입으로 바꿀 수 없어 null로 남긴 값의 수입니다.
+	dropped int64
+	// rejectBad이면 그런 값이 있는 문서를 null로 채우지 않고 통째로 뺍니다.
+	rejectBad bool
+	// overflow는 숫자 타입의 범위를 넘는 값의 처리입니다. OverflowWiden이면 첫 widen에서만
+	// 숫자 타입을 넓힙니다. 그 뒤에는 파일의 스키마가 정해져 있기 때문입니다. dims가 없는
+	// dense_vector의 길이도 같은 이유로 첫 widen에서만 정합니다.
+	overflow esschema.OverflowPolicy
+	widened  bool
+	// sanitize는 문자열 값을 쓰기 전에 고치는 방법입니다.
+	sanitize esschema.Sanitize
+
+	mu sync.Mutex
+	// overflows는 범위를 넘은 숫자 값의 수를, sanitized는 sanitize로 고친 문자열 값의 수를
+	// 필드 경로별로 셉니다.
+	overflows map[string]int64
+	sanitized map[string]int64
+}
+
+func newNormalizer(schema *arrow.Schema) *normalizer {
+	return &normalizer{schema: schema}
+}
+
+// widen 함수는 docs를 모두 담을 수 있도록 스키마를 넓힙니다. 스키마가 바뀌었으면
+// 처음 바뀐 최상위 필드의 이름을, 아니면 빈 문자열을 반환합니다.
+func (n *normalizer) widen(docs []map[string]interface{}) string {
+	fields := append([]arrow.Field(nil), n.schema.Fields()...)
+	vectors := !n.widened
+	numbers := n.overflow == esschema.OverflowWiden && vectors
+	if len(docs) > 0 {
+		n.widened = true
+	}
+	changed := ""
+	for i, f := range fields {
+		t := f.Type
+		for _, doc := range docs {
+			t = widenField(f, t, doc[f.Name], numbers, vectors)
+		}
+		if !arrow.TypeEqual(t, f.Type) {
+			fields[i].Type = t
+			fields[i].Nullable = true
+			if changed == "" {
+				changed = f.Name
+			}
+		}
+	}
+	if changed != "" {
+		md := n.schema.Metadata()
+		n.schema = arrow.NewSchema(fields, &md)
+	}
+	return changed
+}
+
+// widenType 함수는 값 v를 담을 수 있도록 타입 t를 넓힙니다. 배열 값은 리스트로,
+// 객체 배열(nested)은 구조체 리스트가 됩니다. numbers이면 범위를 넘는 숫자를 담도록
+// 숫자 타입도 넓히고, vectors이면 dims가 없는 dense_vector의 길이를 정합니다.
+func widenType(t arrow.DataType, v interface{}, numbers, vectors bool) arrow.DataType {
+	switch v := v.(type) {
+	case []interface{}:
+		if t.ID() == arrow.FIXED_SIZE_LIST {
+			return t
+		}
+		if esschema.IsGeoPoint(t) {
+			if _, _, point := esschema.GeoPoint(v); point {
+				return t
+			}
+		}
+		elem := t
+		if lt, ok := t.(*arrow.ListType); ok {
+			elem = lt.Elem()
+		}
+		for _, item := range v {
+			if _, nestedArray := item.([]interface{}); !nestedArray {
+				elem = widenType(elem, item, numbers, vectors)
+			}
+		}
+		return arrow.ListOf(elem)
+	case map[string]interface{}:
+		if lt, ok := t.(*arrow.ListType); ok {
+			return arrow.ListOf(widenType(lt.Elem(), v, numbers, vectors))
+		}
+		st, ok := t.(*arrow.StructType)
+		if !ok {
+			return t
+		}
+		fields := append([]arrow.Field(nil), st.Fields()...)
+		for i, f := range fields {
+			fields[i].Type = widenField(f, f.Type, v[f.Name], numbers, vectors)
+		}
+		if esschema.IsRange(st) && !arrow.TypeEqual(fields[0].Type, fields[1].Type) {
+			// range의 두 경계는 같은 타입이어야 하므로 넓어진 쪽에 맞춥니다.
+			wide := fields[0].Type
+			if arrow.TypeEqual(wide, st.Field(0).Type) {
+				wide = fields[1].Type
+			}
+			fields[0].Type, fields[1].Type = wide, wide
+		}
+		return arrow.StructOf(fields...)
+	}
+	if !numbers {
+		return t
+	}
+	if lt, ok := t.(*arrow.ListType); ok {
+		if elem := widenType(lt.Elem(), v, numbers, vectors); !arrow.TypeEqual(elem, lt.Elem()) {
+			return arrow.ListOf(elem)
+		}
+		return t
+	}
+	return esschema.WidenNumber(t, v)
+}
+
+// widenField 함수는 필드 f의 타입 t를 값 v에 맞춰 넓힙니다. geo_shape의 WKB 컬럼과 JSON
+// 텍스트 컬럼은 여러 도형을 GeometryCollection이나 JSON 배열 하나로 쓰므로 배열 값에도
+// 리스트로 넓히지 않습니다. dims가 없는 dense_vector는 vectors일 때 처음 본 벡터의 길이로
+// 정하고, 그 배치에 벡터가 없었으면 길이 없는 리스트로 남습니다.
+func widenField(f arrow.Field, t arrow.DataType, v interface{}, numbers, vectors bool) arrow.DataType {
+	if f.Metadata.FindKey(esschema.GeoTypeKey) >= 0 || f.Metadata.FindKey(esschema.GeoShapeJSONKey) >= 0 {
+		return t
+	}
+	if esschema.IsUnsizedVector(f) {
+		if vectors {
+			return esschema.SizeVector(t, v)
+		}
+		return t
+	}
+	return widenType(t, v, numbers, vectors)
+}
+
+// forceList 함수는 점으로 구분된 경로의 필드를 리스트 타입으로 바꾼 필드 목록을 반환합니다.
+// 경로에 해당하는 필드가 없으면 false를 반환합니다.
+func forceList(fields []arrow.Field, path []string) ([]arrow.Field, bool) {
+	out := append([]arrow.Field(nil), fields...)
+	for i, f := range out {
+		if f.Name != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			if f.Type.ID() != arrow.LIST {
+				out[i].Type = arrow.ListOf(f.Type)
+				out[i].Nullable = true
+			}
+			return out, true
+		}
+		t := f.Type
+		lt, isList := t.(*arrow.ListType)
+		if isList {
+			t = lt.Elem()
+		}
+		st, ok := t.(*arrow.StructType)
+		if !ok {
+			return nil, false
+		}
+		children, ok := forceList(st.Fields(), path[1:])
+		if !ok {
+			return nil, false
+		}
+		t = arrow.StructOf(children...)
+		if isList {
+			t = arrow.ListOf(t)
+		}
+		out[i].Type = t
+		return out, true
+	}
+	return nil, false
+}
+
+// updateField 함수는 점으로 구분된 경로의 필드를 fn으로 바꾼 필드 목록을 반환합니다.
+// 구조체와 구조체 리스트 안으로 들어갑니다. 경로가 없거나 fn이 false를 반환하면 false입니다.
+func updateField(fields []arrow.Field, path []string, fn func(arrow.Field) (arrow.Field, bool)) ([]arrow.Field, bool) {
+	out := append([]arrow.Field(nil), fields...)
+	for i, f := range out {
+		if f.Name != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			updated, ok := fn(f)
+			if !ok {
+				return nil, false
+			}
+			out[i] = updated
+			return out, true
+		}
+		t := f.Type
+		lt, isList := t.(*arrow.ListType)
+		if isList {
+			t = lt.Elem()
+		}
+		st, ok := t.(*arrow.StructType)
+		if !ok {
+			return nil, false
+		}
+		children, ok := updateField(st.Fields(), path[1:], fn)
+		if !ok {
+			return nil, false
+		}
+		t = arrow.StructOf(children...)
+		if isList {
+			t = arrow.ListOf(t)
+		}
+		out[i].Type = t
+		return out, true
+	}
+	return nil, false
+}
+
+// record 함수는 docs를 현재 스키마의 레코드로 바꿉니다. 먼저 widen을 호출해야 합니다.
+// 문서마다 모든 필드를 먼저 변환해 본 뒤에 빌더에 추가하므로, rejectBad일 때 변환할 수
+// 없는 값이 있는 문서는 어느 빌더에도 흔적을 남기지 않고 빠집니다. 빠진 문서는 docs 안의
+// 위치와 함께 rejected로 반환하고, 레코드의 행은 나머지 문서의 순서를 따릅니다.
+func (n *normalizer) record(docs []map[string]interface{}) (arrow.Record, []rejection) {
+	return n.recordAs(n.schema, docs)
+}
+
+// recordAs 함수는 record와 같지만 미리 잡아 둔 schema로 레코드를 만듭니다. 스키마가 더
+// 넓어지지 않을 때 여러 고루틴이 잠금 없이 함께 부를 수 있도록, n에서는 dropped만 원자적으로
+// 고치고 overflows와 sanitized는 mu로 보호합니다.
+func (n *normalizer) recordAs(schema *arrow.Schema, docs []map[string]interface{}) (arrow.Record, []rejection) {
+	b := esschema.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer b.Release()
+	b.Reject = n.rejectBad
+	b.Overflow = n.overflow
+	b.Sanitize = n.sanitize
+	var rejected []rejection
+	for i, doc := range docs {
+		bad, err := b.Append(doc)
+		if err != nil {
+			var rangeErr *esschema.RangeError
+			if errors.As(err, &rangeErr) {
+				bad = []string{rangeErr.Path}
+			}
+			rejected = append(rejected, rejection{index: i, fields: bad, reason: err.Error(), overflow: rangeErr != nil})
+			continue
+		}
+		atomic.AddInt64(&n.dropped, int64(len(bad)))
+	}
+	if overflows, sanitized := b.Overflows(), b.Sanitized(); len(overflows) > 0 || len(sanitized) > 0 {
+		n.mu.Lock()
+		n.overflows = addCounts(n.overflows, overflows)
+		n.sanitized = addCounts(n.sanitized, sanitized)
+		n.mu.Unlock()
+	}
+	return b.NewRecord(), rejected
+}
+
+// addCounts 함수는 경로별 수 src를 dst에 더한 맵을 반환합니다.
+func addCounts(dst, src map[string]int64) map[string]int64 {
+	if len(src) > 0 && dst == nil {
+		dst = make(map[string]int64, len(src))
+	}
+	for path, c := range src {
+		dst[path] += c
+	}
+	return dst
+}
+
+// overflowCounts 함수는 지금까지 범위를 넘은 숫자 값의 수를 필드 경로별로 반환합니다.
+func (n *normalizer) overflowCounts() map[string]int64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return addCounts(make(map[string]int64, len(n.overflows)), n.overflows)
+}
+
+// sanitizedCounts 함수는 지금까지 --sanitize-strings로 고친 문자열 값의 수를 필드 경로별로
+// 반환합니다.
+func (n *normalizer) sanitizedCounts() map[string]int64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return addCounts(make(map[string]int64, len(n.sanitized)), n.sanitized)
+}
+
+// rejection은 record에서 빠진 문서 하나입니다. overflow이면 --on-numeric-overflow error로
+// 빠졌습니다.
+type rejection struct {
+	index    int
+	fields   []string
+	reason   string
+	overflow bool
+}
+
+// copyUnmapped 함수는 스키마에 없는 필드(와 하위 필드가 정의되지 않은 객체)를 src에서
+// dst로 그대로 옮깁니다. 매핑이 색인하지 않는 값도 _source에는 남아 있어야 하기 때문입니다.
+func copyUnmapped(dst, src map[string]interface{}, fields []arrow.Field) {
+	for k, v := range src {
+		var field *arrow.Field
+		for i := range fields {
+			if fields[i].Name == k {
+				field = &fields[i]
+				break
+			}
+		}
+		if field == nil {
+			dst[k] = v
+			continue
+		}
+		st, ok := structElem(field.Type)
+		if !ok {
+			continue
+		}
+		if len(st.Fields()) == 0 {
+			dst[k] = v
+			continue
+		}
+		if srcObj, ok := v.(map[string]interface{}); ok {
+			v = []interface{}{srcObj}
+		}
+		dstItems, ok := dst[k].([]interface{})
+		if obj, isObj := dst[k].(map[string]interface{}); isObj {
+			dstItems, ok = []interface{}{obj}, true
+		}
+		// nested 필드의 객체 배열은 같은 위치의 객체끼리 옮깁니다.
+		srcItems, _ := v.([]interface{})
+		if !ok || len(srcItems) != len(dstItems) {
+			continue
+		}
+		for i := range srcItems {
+			srcObj, ok1 := srcItems[i].(map[string]interface{})
+			dstObj, ok2 := dstItems[i].(map[string]interface{})
+			if ok1 && ok2 {
+				copyUnmapped(dstObj, srcObj, st.Fields())
+			}
+		}
+	}
+}
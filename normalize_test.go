@@ -0,0 +1,31 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+func TestCopyUnmappedNested(t *testing.T) {
+	fields := []arrow.Field{
+		{Name: "comments", Type: arrow.ListOf(arrow.StructOf(arrow.Field{Name: "author", Type: arrow.BinaryTypes.String}))},
+		{Name: "user", Type: arrow.StructOf(arrow.Field{Name: "name", Type: arrow.BinaryTypes.String})},
+	}
+	src := map[string]interface{}{
+		"comments": []interface{}{
+			map[string]interface{}{"author": "ann", "draft": true},
+			map[string]interface{}{"author": "bo", "draft": false},
+		},
+		"user":  map[string]interface{}{"name": "kim", "raw": "x"},
+		"extra": 1.0,
+	}
+	dst := map[string]interface{}{
+		"comments": []interface{}{map[string]interface{}{"author": "ann"}, map[string]interface{}{"author": "bo"}},
+		"user":     map[string]interface{}{"name": "kim"},
+	}
+	copyUnmapped(dst, src, fields)
+	if !reflect.DeepEqual(dst, src) {
+		t.Errorf("copied = %v\nwant %v", dst, src)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"es-schema/esschema"
+)
+
+// overflowOptions는 --on-numeric-overflow 플래그입니다. 매핑된 숫자 타입의 범위를 넘는 값은
+// 어떤 정책이든 필드별로 세어 보고서의 numeric_overflows에 남깁니다.
+type overflowOptions struct {
+	policy string
+}
+
+func (o *overflowOptions) bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.policy, "on-numeric-overflow", string(esschema.OverflowNull), "numbers beyond the range of their integer, long, unsigned_long, half_float, float or scaled_float column: null (write null), error (leave the document out and report it), saturate (write the largest or smallest value of the type) or widen (make the column long, float or double when the first batch needs it; later values that still overflow are written as null)")
+}
+
+func (o *overflowOptions) validate() error {
+	if _, err := esschema.ParseOverflowPolicy(o.policy); err != nil {
+		return configErrorf("unknown --on-numeric-overflow %q (want null, error, saturate or widen)", o.policy)
+	}
+	return nil
+}
+
+// apply 함수는 n이 범위를 넘는 숫자를 정책대로 다루게 합니다. validate 뒤에 부릅니다.
+func (o *overflowOptions) apply(n *normalizer) {
+	n.overflow, _ = esschema.ParseOverflowPolicy(o.policy)
+}
+
+// report 함수는 counts를 보고서에 더하고, 범위를 넘은 값이 있으면 경고 하나를 남깁니다.
+// 여러 인덱스의 수는 모두 더한 뒤에 한 번 부릅니다.
+func (o *overflowOptions) report(report *runReport, counts map[string]int64) {
+	for path, n := range counts {
+		if report.NumericOverflows == nil {
+			report.NumericOverflows = make(map[string]int64)
+		}
+		report.NumericOverflows[path] += n
+	}
+	if len(report.NumericOverflows) == 0 {
+		return
+	}
+	var total int64
+	paths := sortedKeys(report.NumericOverflows)
+	sort.SliceStable(paths, func(i, j int) bool {
+		return report.NumericOverflows[paths[i]] > report.NumericOverflows[paths[j]]
+	})
+	parts := make([]string, 0, len(paths))
+	for _, path := range paths {
+		total += report.NumericOverflows[path]
+		parts = append(parts, fmt.Sprintf("%s (%d)", path, report.NumericOverflows[path]))
+	}
+	done := "written as null"
+	switch esschema.OverflowPolicy(o.policy) {
+	case esschema.OverflowError:
+		done = "left out with their documents"
+	case esschema.OverflowSaturate:
+		done = "clamped to the range of the type"
+	}
+	report.warnf("%d numbers overflowed their mapped type and were %s (--on-numeric-overflow %s): %s", total, done, o.policy, strings.Join(parts, ", "))
+}
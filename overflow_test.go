@@ -0,0 +1,31 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOverflowReport(t *testing.T) {
+	o := overflowOptions{policy: "saturate"}
+	if err := o.validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&overflowOptions{policy: "wrap"}).validate(); exitCodeFor(err) != exitConfigError {
+		t.Errorf("validate(wrap) = %v, want a config error", err)
+	}
+
+	report := newRunReport("export")
+	o.report(report, nil)
+	if len(report.Warnings) != 0 || report.NumericOverflows != nil {
+		t.Errorf("report without overflows = %v, %v", report.Warnings, report.NumericOverflows)
+	}
+	report.NumericOverflows = map[string]int64{"size": 1}
+	o.report(report, map[string]int64{"size": 1, "user.age": 3})
+	if want := map[string]int64{"size": 2, "user.age": 3}; !reflect.DeepEqual(report.NumericOverflows, want) {
+		t.Errorf("numeric_overflows = %v, want %v", report.NumericOverflows, want)
+	}
+	if len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0], "5 numbers overflowed their mapped type and were clamped") || !strings.Contains(report.Warnings[0], "user.age (3), size (2)") {
+		t.Errorf("warnings = %v", report.Warnings)
+	}
+}
@@ -0,0 +1,436 @@
+package main
+
+import (
+	"container/list"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/apache/arrow/go/v10/arrow"
+
+	"es-schema/esschema"
+)
+
+// maxHashBuckets는 --partition-by hash(field, n)의 n 상한입니다.
+const maxHashBuckets = 1 << 16
+
+// defaultPartition은 파티션 값이 없는 문서의 파티션 값으로, Hive와 Spark가 null로 읽는
+// 이름입니다.
+const defaultPartition = "__HIVE_DEFAULT_PARTITION__"
+
+// partitionTruncations는 --partition-by의 날짜 함수와 파티션 값의 형식입니다. prune --by
+// partition이 읽는 형식과 같습니다.
+var partitionTruncations = map[string]string{
+	"year":  "2006",
+	"month": "2006-01",
+	"day":   "2006-01-02",
+	"hour":  "2006-01-02T15",
+}
+
+// partitionOptions는 export가 인덱스를 <key>=<value> 디렉터리로 나눠 쓰는 설정입니다.
+type partitionOptions struct {
+	by           string
+	maxOpenFiles int
+	bufferRows   int
+	spec         *partitionSpec
+}
+
+func (o *partitionOptions) bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.by, "partition-by", "", "write each index as a directory of <key>=<value>/part-NNNNN.parquet files, partitioned by a field's value, by year(field), month(field), day(field) or hour(field) as date=<value>, or by hash(field, n) into n evenly filled bucket=<0..n-1> partitions (field may be _id)")
+	fs.IntVar(&o.maxOpenFiles, "max-open-files", 64, "with --partition-by, keep at most this many partition files open per index; the least recently written one is closed and a later write to it starts a new part file")
+	fs.IntVar(&o.bufferRows, "partition-buffer-rows", 10000, "with --partition-by, buffer this many documents per partition and write them as one batch")
+}
+
+func (o *partitionOptions) validate() error {
+	if o.by == "" {
+		return nil
+	}
+	if o.maxOpenFiles <= 0 || o.bufferRows <= 0 {
+		return configErrorf("--max-open-files and --partition-buffer-rows must be positive")
+	}
+	var err error
+	o.spec, err = parsePartitionSpec(o.by)
+	return err
+}
+
+func (o *partitionOptions) enabled() bool {
+	return o.by != ""
+}
+
+// partitionSpec은 문서가 속할 파티션 디렉터리를 정하는 규칙입니다. layout이 있으면 필드를
+// 날짜로 읽어 그 형식으로 자르고, buckets가 있으면 값의 해시로 나누고, 둘 다 없으면 필드
+// 값을 그대로 씁니다.
+type partitionSpec struct {
+	key     string
+	path    []string
+	layout  string
+	buckets int
+}
+
+func parsePartitionSpec(s string) (*partitionSpec, error) {
+	fn, rest, call := strings.Cut(s, "(")
+	if !call {
+		return &partitionSpec{key: s, path: strings.Split(s, ".")}, nil
+	}
+	arg, closed := strings.CutSuffix(rest, ")")
+	arg = strings.TrimSpace(arg)
+	if fn == "hash" && closed {
+		field, n, _ := strings.Cut(arg, ",")
+		field = strings.TrimSpace(field)
+		buckets, err := strconv.Atoi(strings.TrimSpace(n))
+		if field == "" || err != nil || buckets < 1 || buckets > maxHashBuckets {
+			return nil, configErrorf("--partition-by %q: want hash(field, n) with 1 <= n <= %d", s, maxHashBuckets)
+		}
+		return &partitionSpec{key: "bucket", path: strings.Split(field, "."), buckets: buckets}, nil
+	}
+	layout, ok := partitionTruncations[fn]
+	if !closed || !ok || arg == "" {
+		return nil, configErrorf("--partition-by %q: want a field path, year(field), month(field), day(field), hour(field) or hash(field, n)", s)
+	}
+	return &partitionSpec{key: "date", path: strings.Split(arg, "."), layout: layout}, nil
+}
+
+// partition 함수는 문서가 속한 파티션의 디렉터리 이름(key=value)을 반환합니다. 값이
+// 없거나 쓸 수 없는 문서는 defaultPartition에 넣습니다.
+func (s *partitionSpec) partition(hit searchHit, doc map[string]interface{}) string {
+	value := defaultPartition
+	switch v := s.value(hit, doc); {
+	case v == nil:
+	case s.layout != "":
+		if t, ok := esschema.Time(v); ok {
+			value = t.UTC().Format(s.layout)
+		}
+	case s.buckets > 0:
+		value = strconv.Itoa(hashBucket(fmt.Sprint(v), s.buckets))
+	default:
+		value = escapePartitionValue(fmt.Sprint(v))
+	}
+	return escapePartitionValue(s.key) + "=" + value
+}
+
+// value 함수는 파티션을 정할 값을 반환합니다. 필드 _id는 hit의 _id이고, 배열은 첫 원소로
+// 정합니다. 빈 문자열이나 객체처럼 쓸 수 없는 값이면 nil입니다.
+func (s *partitionSpec) value(hit searchHit, doc map[string]interface{}) interface{} {
+	if len(s.path) == 1 && s.path[0] == "_id" {
+		return hit.ID
+	}
+	v, _ := getPath(doc, s.path)
+	if items, ok := v.([]interface{}); ok {
+		v = nil
+		if len(items) > 0 {
+			v = items[0]
+		}
+	}
+	switch x := v.(type) {
+	case string:
+		if x != "" {
+			return x
+		}
+	case json.Number, bool:
+		return x
+	}
+	return nil
+}
+
+// hashBucket 함수는 v를 FNV-1a 해시로 n개 버킷 중 하나에 넣습니다. 실행과 플랫폼이 달라도
+// 같은 값은 같은 버킷에 들어갑니다.
+func hashBucket(v string, n int) int {
+	h := fnv.New64a()
+	h.Write([]byte(v))
+	return int(h.Sum64() % uint64(n))
+}
+
+// escapePartitionValue 함수는 Hive처럼 디렉터리 이름에 쓸 수 없거나 파티션 경로를 헷갈리게
+// 하는 문자를 %XX로 바꿉니다.
+func escapePartitionValue(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c == 0x7f || strings.IndexByte(`"#%'*/:=?\{[]^`, c) >= 0 {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		} else {
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// partitionFile은 다 쓴 파티션 파일 하나입니다.
+type partitionFile struct {
+	path string
+	rows int64
+}
+
+// partitionBuffer는 아직 쓰지 않은 파티션 하나의 문서입니다.
+type partitionBuffer struct {
+	hits []searchHit
+	docs []map[string]interface{}
+}
+
+// openPartition은 열려 있는 파티션 파일입니다.
+type openPartition struct {
+	partition string
+	sink      *parquetSink
+}
+
+// partitionWriters는 인덱스 하나의 파티션 파일을 씁니다. 파티션마다 문서를 bufferRows까지
+// 모아 한 배치로 쓰고, 열린 파일은 maxOpen개까지만 둡니다. 한도를 넘으면 가장 오래 쓰지
+// 않은 파일을 닫고, 그 파티션에 다시 쓸 때 다음 part 파일을 엽니다. 파티션 값의 종류가
+// 많아도 파일 디스크립터가 바닥나지 않습니다.
+type partitionWriters struct {
+	dir        string
+	maxOpen    int
+	bufferRows int
+	newSink    func(path string, schema *arrow.Schema) (*parquetSink, error)
+
+	buffers map[string]*partitionBuffer
+	open    map[string]*list.Element
+	// lru는 열린 파일을 최근에 쓴 순서로 둡니다. 앞이 가장 최근입니다.
+	lru   *list.List
+	parts map[string]int
+	files []partitionFile
+	// closedBytes는 닫은 파일들의 크기입니다.
+	closedBytes int64
+	// done이면 모든 파일을 닫았으므로 abort가 아무것도 지우지 않습니다.
+	done bool
+}
+
+func newPartitionWriters(dir string, opts *partitionOptions, newSink func(string, *arrow.Schema) (*parquetSink, error)) *partitionWriters {
+	return &partitionWriters{
+		dir:        dir,
+		maxOpen:    opts.maxOpenFiles,
+		bufferRows: opts.bufferRows,
+		newSink:    newSink,
+		buffers:    make(map[string]*partitionBuffer),
+		open:       make(map[string]*list.Element),
+		lru:        list.New(),
+		parts:      make(map[string]int),
+	}
+}
+
+// opened 함수는 파일을 하나라도 열었는지 알려 줍니다. 그 뒤에는 스키마를 바꿀 수 없습니다.
+func (w *partitionWriters) opened() bool {
+	return w != nil && len(w.parts) > 0
+}
+
+func (w *partitionWriters) add(partition string, hit searchHit, doc map[string]interface{}) {
+	b := w.buffers[partition]
+	if b == nil {
+		b = &partitionBuffer{}
+		w.buffers[partition] = b
+	}
+	b.hits = append(b.hits, hit)
+	b.docs = append(b.docs, doc)
+}
+
+// full 함수는 버퍼가 bufferRows에 이른 파티션을 정렬해 반환합니다.
+func (w *partitionWriters) full() []string {
+	var out []string
+	for p, b := range w.buffers {
+		if len(b.docs) >= w.bufferRows {
+			out = append(out, p)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// pending 함수는 버퍼에 문서가 남은 파티션을 모두 정렬해 반환합니다.
+func (w *partitionWriters) pending() []string {
+	out := make([]string, 0, len(w.buffers))
+	for p := range w.buffers {
+		out = append(out, p)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// take 함수는 파티션의 버퍼를 비우고 그 문서를 반환합니다.
+func (w *partitionWriters) take(partition string) ([]searchHit, []map[string]interface{}) {
+	b := w.buffers[partition]
+	delete(w.buffers, partition)
+	if b == nil {
+		return nil, nil
+	}
+	return b.hits, b.docs
+}
+
+// sink 함수는 파티션의 열린 파일을 반환합니다. 없으면 새 part 파일을 열고, 그 때문에
+// 한도를 넘으면 가장 오래 쓰지 않은 파일을 닫습니다.
+func (w *partitionWriters) sink(partition string, schema *arrow.Schema) (*parquetSink, error) {
+	if e, ok := w.open[partition]; ok {
+		w.lru.MoveToFront(e)
+		return e.Value.(*openPartition).sink, nil
+	}
+	if w.lru.Len() >= w.maxOpen {
+		if err := w.closeOldest(); err != nil {
+			return nil, err
+		}
+	}
+	dir := filepath.Join(w.dir, partition)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, configErrorf("creating partition directory: %w", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("part-%05d.parquet", w.parts[partition]))
+	s, err := w.newSink(path, schema)
+	if err != nil {
+		return nil, err
+	}
+	w.parts[partition]++
+	w.open[partition] = w.lru.PushFront(&openPartition{partition: partition, sink: s})
+	return s, nil
+}
+
+func (w *partitionWriters) closeOldest() error {
+	e := w.lru.Back()
+	op := e.Value.(*openPartition)
+	w.lru.Remove(e)
+	delete(w.open, op.partition)
+	if err := op.sink.close(); err != nil {
+		os.Remove(op.sink.path)
+		return err
+	}
+	w.files = append(w.files, partitionFile{path: op.sink.path, rows: op.sink.rows})
+	w.closedBytes += op.sink.bytesWritten()
+	return nil
+}
+
+// bytesWritten 함수는 닫은 파일과 열린 파일에 지금까지 쓴 바이트 수를 반환합니다.
+func (w *partitionWriters) bytesWritten() int64 {
+	n := w.closedBytes
+	for e := w.lru.Front(); e != nil; e = e.Next() {
+		n += e.Value.(*openPartition).sink.bytesWritten()
+	}
+	return n
+}
+
+// close 함수는 열린 파일을 모두 닫습니다. 버퍼는 먼저 비워야 합니다.
+func (w *partitionWriters) close() error {
+	for w.lru.Len() > 0 {
+		if err := w.closeOldest(); err != nil {
+			return err
+		}
+	}
+	sort.Slice(w.files, func(i, j int) bool { return w.files[i].path < w.files[j].path })
+	w.done = true
+	return nil
+}
+
+// abort 함수는 실패한 인덱스의 파티션 파일을 열린 것과 닫은 것 모두 지웁니다.
+func (w *partitionWriters) abort() {
+	if w.done {
+		return
+	}
+	for e := w.lru.Front(); e != nil; e = e.Next() {
+		e.Value.(*openPartition).sink.abort()
+	}
+	for _, f := range w.files {
+		os.Remove(f.path)
+	}
+	w.lru.Init()
+	w.open = make(map[string]*list.Element)
+	w.files = nil
+}
+
+// bufferPartitions 함수는 페이지의 문서를 파티션 버퍼에 나눠 넣고, 가득 찬 버퍼를 씁니다.
+func (j *exportJob) bufferPartitions(hits []searchHit, docs []map[string]interface{}) error {
+	for i, doc := range docs {
+		j.parts.add(j.opts.partition.spec.partition(hits[i], doc), hits[i], doc)
+	}
+	return j.flushPartitions(j.parts.full())
+}
+
+// flushPartitions 함수는 partitions의 버퍼를 레코드로 바꿔 각 파티션 파일에 씁니다. 한
+// 번에 maxOpen개 파티션씩 처리해, 쓰는 동안 그 파일이 닫히지 않게 합니다.
+func (j *exportJob) flushPartitions(partitions []string) error {
+	for len(partitions) > 0 {
+		n := min(len(partitions), j.parts.maxOpen)
+		if err := j.flushPartitionBatch(partitions[:n]); err != nil {
+			return err
+		}
+		partitions = partitions[n:]
+	}
+	return nil
+}
+
+// flushPartitionBatch 함수는 레코드를 차례로 만든 뒤(normalizer를 함께 쓰므로) 인코딩과
+// 압축이 드는 파일 쓰기를 파티션마다 동시에 합니다.
+func (j *exportJob) flushPartitionBatch(partitions []string) error {
+	type write struct {
+		sink *parquetSink
+		rec  arrow.Record
+		hits []searchHit
+	}
+	var writes []write
+	defer func() {
+		for _, w := range writes {
+			w.rec.Release()
+		}
+	}()
+	for _, p := range partitions {
+		hits, docs := j.parts.take(p)
+		converted, rejected := j.norm.record(docs)
+		hits, _, err := j.rejects.keep(hits, docs, rejected)
+		if err != nil {
+			converted.Release()
+			return err
+		}
+		rec, err := j.opts.hooks.apply(converted)
+		converted.Release()
+		if err != nil {
+			return err
+		}
+		if rec.NumRows() == 0 {
+			rec.Release()
+			continue
+		}
+		if j.opts.memoryStats {
+			j.memory.add(rec)
+		}
+		sink, err := j.parts.sink(p, rec.Schema())
+		if err != nil {
+			rec.Release()
+			return err
+		}
+		j.renames = sink.renames
+		writes = append(writes, write{sink: sink, rec: rec, hits: hits})
+	}
+
+	errs := make([]error, len(writes))
+	var wg sync.WaitGroup
+	for i, w := range writes {
+		wg.Add(1)
+		go func(i int, w write) {
+			defer wg.Done()
+			errs[i] = w.sink.writeHits(w.rec, w.hits)
+		}(i, w)
+	}
+	wg.Wait()
+	for i, w := range writes {
+		if errs[i] != nil {
+			return errs[i]
+		}
+		j.rows += int64(len(w.hits))
+	}
+	j.progress.report(j.label(), j.rows, j.total, false)
+	return nil
+}
+
+// finishPartitions 함수는 남은 버퍼를 모두 쓰고 파티션 파일을 닫습니다.
+func (j *exportJob) finishPartitions() error {
+	if err := j.flushPartitions(j.parts.pending()); err != nil {
+		return err
+	}
+	if err := j.parts.close(); err != nil {
+		return err
+	}
+	j.account(j.parts.bytesWritten())
+	return nil
+}
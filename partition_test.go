@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestPartitionSpec(t *testing.T) {
+	doc := map[string]interface{}{
+		"@timestamp": "2024-03-05T23:30:00-02:00",
+		"service":    map[string]interface{}{"name": "check/out"},
+		"status":     json.Number("200"),
+		"tags":       []interface{}{"a", "b"},
+	}
+	cases := map[string]string{
+		"service.name":     "service.name=check%2Fout",
+		"status":           "status=200",
+		"tags":             "tags=a",
+		"missing":          "missing=" + defaultPartition,
+		"day(@timestamp)":  "date=2024-03-06",
+		"hour(@timestamp)": "date=2024-03-06T01",
+		"month(missing)":   "date=" + defaultPartition,
+	}
+	for by, want := range cases {
+		spec, err := parsePartitionSpec(by)
+		if err != nil {
+			t.Fatalf("parsePartitionSpec(%q): %v", by, err)
+		}
+		if got := spec.partition(searchHit{ID: "x"}, doc); got != want {
+			t.Errorf("%s: partition = %q, want %q", by, got, want)
+		}
+	}
+	for _, bad := range []string{"week(@timestamp)", "day(@timestamp", "day()", "hash(_id)", "hash(_id, 0)", "hash(, 4)"} {
+		if _, err := parsePartitionSpec(bad); err == nil {
+			t.Errorf("parsePartitionSpec(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestHashPartitions(t *testing.T) {
+	spec, err := parsePartitionSpec("hash(_id, 16)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[string]int)
+	for i := 0; i < 16000; i++ {
+		hit := searchHit{ID: fmt.Sprintf("doc-%d", i)}
+		p := spec.partition(hit, nil)
+		if again := spec.partition(hit, map[string]interface{}{"_id": "other"}); again != p {
+			t.Fatalf("%s: partition changed from %s to %s", hit.ID, p, again)
+		}
+		counts[p]++
+	}
+	if len(counts) != 16 {
+		t.Fatalf("got %d buckets, want 16: %v", len(counts), counts)
+	}
+	for p, n := range counts {
+		if n < 800 || n > 1200 {
+			t.Errorf("%s holds %d of 16000 documents", p, n)
+		}
+	}
+	// 다른 실행과 다른 도구가 같은 버킷을 계산할 수 있도록 FNV-1a 값 하나를 고정합니다.
+	if got := hashBucket("doc-0", 16); got != 8 {
+		t.Errorf("hashBucket(doc-0, 16) = %d, want 8", got)
+	}
+
+	spec, _ = parsePartitionSpec("hash(user.id, 4)")
+	doc := map[string]interface{}{"user": map[string]interface{}{"id": json.Number("42")}}
+	if got, want := spec.partition(searchHit{}, doc), fmt.Sprintf("bucket=%d", hashBucket("42", 4)); got != want {
+		t.Errorf("partition = %s, want %s", got, want)
+	}
+	if got := spec.partition(searchHit{}, map[string]interface{}{}); got != "bucket="+defaultPartition {
+		t.Errorf("missing field went to %s", got)
+	}
+}
+
+func TestPartitionWritersBuffers(t *testing.T) {
+	w := newPartitionWriters(t.TempDir(), &partitionOptions{maxOpenFiles: 2, bufferRows: 2}, nil)
+	for i, p := range []string{"k=a", "k=b", "k=a", "k=c"} {
+		w.add(p, searchHit{ID: string(rune('0' + i))}, map[string]interface{}{})
+	}
+	if got, want := w.full(), []string{"k=a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("full = %v, want %v", got, want)
+	}
+	hits, docs := w.take("k=a")
+	if len(hits) != 2 || len(docs) != 2 || hits[1].ID != "2" {
+		t.Errorf("take = %v, %d docs", hits, len(docs))
+	}
+	if got, want := w.pending(), []string{"k=b", "k=c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("pending = %v, want %v", got, want)
+	}
+	if w.opened() {
+		t.Error("opened before any file was created")
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+// maxPreviewDocuments는 미리 보기 한 번에 변환하는 최대 문서 수입니다. 미리 보기는
+// 브라우저 안에서 도는 작은 변환을 위한 것입니다.
+const maxPreviewDocuments = 1000
+
+// schemaField는 미리 보기에서 보여 주는 Arrow 필드 하나입니다. 구조체(와 구조체 리스트)는
+// 하위 필드를 Children에 담습니다.
+type schemaField struct {
+	Name     string        `json:"name"`
+	Type     string        `json:"type"`
+	Nullable bool          `json:"nullable"`
+	Children []schemaField `json:"children,omitempty"`
+}
+
+// conversionPreview는 매핑과 문서 몇 개를 변환한 결과입니다.
+type conversionPreview struct {
+	Schema     []schemaField            `json:"schema"`
+	Rows       []map[string]interface{} `json:"rows"`
+	NullValues int64                    `json:"null_values"`
+	Rejected   []string                 `json:"rejected,omitempty"`
+}
+
+// describeSchema 함수는 Arrow 필드를 미리 보기용 트리로 바꿉니다.
+func describeSchema(fields []arrow.Field) []schemaField {
+	out := make([]schemaField, len(fields))
+	for i, f := range fields {
+		out[i] = schemaField{Name: f.Name, Type: fmt.Sprint(f.Type), Nullable: f.Nullable}
+		t := f.Type
+		if lt, ok := t.(*arrow.ListType); ok {
+			t = lt.Elem()
+		}
+		if st, ok := t.(*arrow.StructType); ok {
+			out[i].Children = describeSchema(st.Fields())
+			out[i].Type = "struct"
+			if t != f.Type {
+				out[i].Type = "list<struct>"
+			}
+		}
+	}
+	return out
+}
+
+// previewMapping 함수는 매핑 JSON으로 만든 Arrow 스키마를 미리 보기용 트리로 반환합니다.
+func previewMapping(mapping []byte) ([]schemaField, error) {
+	schema, err := schemaFromMapping(mapping)
+	if err != nil {
+		return nil, schemaErrorf("parsing mapping: %w", err)
+	}
+	return describeSchema(schema.Fields()), nil
+}
+
+// previewConversion 함수는 docs(JSON 배열이나 NDJSON)를 매핑에 맞춰 Arrow 레코드로 바꾼
+// 뒤 다시 문서로 되돌려, 변환으로 어떤 값이 어떻게 바뀌는지 보여 줍니다.
+func previewConversion(mapping, docs []byte) (*conversionPreview, error) {
+	schema, err := schemaFromMapping(mapping)
+	if err != nil {
+		return nil, schemaErrorf("parsing mapping: %w", err)
+	}
+	parsed, err := parsePreviewDocuments(docs)
+	if err != nil {
+		return nil, err
+	}
+	norm := newNormalizer(schema)
+	norm.widen(parsed)
+	rec, rejected := norm.record(parsed)
+	defer rec.Release()
+
+	p := &conversionPreview{
+		Schema:     describeSchema(rec.Schema().Fields()),
+		Rows:       recordDocuments(rec),
+		NullValues: norm.dropped,
+	}
+	for _, r := range rejected {
+		p.Rejected = append(p.Rejected, fmt.Sprintf("document %d: %s", r.index, r.reason))
+	}
+	return p, nil
+}
+
+// parsePreviewDocuments 함수는 JSON 배열이나 한 줄에 문서 하나인 NDJSON을 읽습니다.
+func parsePreviewDocuments(data []byte) ([]map[string]interface{}, error) {
+	data = bytes.TrimSpace(data)
+	var docs []map[string]interface{}
+	if len(data) > 0 && data[0] == '[' {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&docs); err != nil {
+			return nil, dataErrorf("decoding documents: %w", err)
+		}
+	} else {
+		for i, line := range bytes.Split(data, []byte("\n")) {
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+			doc, err := decodeDocument(line)
+			if err != nil {
+				return nil, dataErrorf("line %d: %w", i+1, err)
+			}
+			docs = append(docs, doc)
+		}
+	}
+	if len(docs) > maxPreviewDocuments {
+		return nil, dataErrorf("%d documents given; a preview converts at most %d", len(docs), maxPreviewDocuments)
+	}
+	return docs, nil
+}
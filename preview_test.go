@@ -0,0 +1,26 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParsePreviewDocuments(t *testing.T) {
+	for _, input := range []string{
+		`[{"n": 1}, {"n": 2.5}]`,
+		"{\"n\": 1}\n\n{\"n\": 2.5}\n",
+	} {
+		docs, err := parsePreviewDocuments([]byte(input))
+		if err != nil {
+			t.Fatalf("%q: %v", input, err)
+		}
+		if len(docs) != 2 || docs[1]["n"] != json.Number("2.5") {
+			t.Errorf("%q: docs = %v", input, docs)
+		}
+	}
+	tooMany := strings.Repeat("{}\n", maxPreviewDocuments+1)
+	if _, err := parsePreviewDocuments([]byte(tooMany)); err == nil {
+		t.Errorf("%d documents accepted", maxPreviewDocuments+1)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// profileConfig는 ~/.es-schema/config의 내용입니다. 프로필은 이름별로 플래그 이름과 값을
+// 담아, 명령행에 주지 않은 플래그의 기본값이 됩니다. 클러스터 주소, 자격 증명, 출력
+// 디렉터리처럼 실행마다 같은 값을 프로필에 두고 --profile prod로 고릅니다.
+//
+//	profiles:
+//	  prod:
+//	    url: https://es-prod:9200
+//	    api-key: ${vault:secret/data/es-prod#api_key}
+//	    out-dir: /data/exports
+//
+// projections에는 --projection으로 고르는 출력별 컬럼 집합과 가림 규칙을 둡니다
+// (projectionSpec).
+type profileConfig struct {
+	Profiles    map[string]map[string]interface{} `json:"profiles"`
+	Projections map[string]*projectionSpec        `json:"projections"`
+	Encryption  *encryptionConfig                 `json:"encryption"`
+}
+
+// profilePath 함수는 설정 파일 경로를 반환합니다. $ES_SCHEMA_CONFIG가 있으면 그 경로입니다.
+func profilePath() (string, error) {
+	if p := os.Getenv("ES_SCHEMA_CONFIG"); p != "" {
+		return p, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", configErrorf("locating the config file: %w", err)
+	}
+	return filepath.Join(home, ".es-schema", "config"), nil
+}
+
+// loadProfile 함수는 path의 설정 파일에서 name 프로필을 읽습니다.
+func loadProfile(path, name string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, configErrorf("reading profile %q: %w", name, err)
+	}
+	var cfg profileConfig
+	if err := unmarshalYAML(data, &cfg); err != nil {
+		return nil, configErrorf("parsing %s: %w", path, err)
+	}
+	settings, ok := cfg.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(cfg.Profiles))
+		for n := range cfg.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, configErrorf("%s has no profile %q (profiles: %v)", path, name, names)
+	}
+	return settings, nil
+}
+
+// applySettings 함수는 명령행에 주지 않은 플래그에 프로필이나 파이프라인의 설정 값을
+// 넣습니다. source는 오류 메시지에 쓰는 설정의 출처입니다. 한 프로필을 모든 명령이 함께
+// 쓰므로 이 명령에 없는 플래그는 건너뛰지만, 어느 명령에도 없는 이름은 오타이므로
+// 오류입니다. 값이 배열이면 원소마다 플래그를 한 번씩 지정한 것과 같습니다. 문자열 값의
+// ${env:...}, ${file:...}, ${vault:...} 참조는 실제로 쓰는 설정만 풉니다.
+func applySettings(ctx context.Context, fs *flag.FlagSet, source string, settings map[string]interface{}) error {
+	var secrets secretResolver
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	known := allFlagNames()
+	keys := make([]string, 0, len(settings))
+	for k := range settings {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if key == "profile" || !known[key] {
+			return configErrorf("%s: unknown setting %q (settings are flag names such as url or out-dir)", source, key)
+		}
+		if explicit[key] || fs.Lookup(key) == nil {
+			continue
+		}
+		values, ok := settings[key].([]interface{})
+		if !ok {
+			values = []interface{}{settings[key]}
+		}
+		for _, v := range values {
+			s, err := profileValue(v)
+			if err != nil {
+				return configErrorf("%s: %s: %w", source, key, err)
+			}
+			if s, err = secrets.expand(ctx, s); err != nil {
+				return fmt.Errorf("%s: %s: %w", source, key, err)
+			}
+			if err := fs.Set(key, s); err != nil {
+				return configErrorf("%s: %s: %w", source, key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// profileValue 함수는 YAML 값을 플래그 값 문자열로 바꿉니다.
+func profileValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case nil:
+		return "", nil
+	}
+	return "", fmt.Errorf("want a string, number, boolean or list of them, got %T", v)
+}
+
+// profileSecret 함수는 settings에서 비밀 값을 그대로 담은 설정 하나의 이름을 반환합니다.
+// 비밀 참조로 적은 값은 세지 않습니다.
+func profileSecret(settings map[string]interface{}) string {
+	keys := make([]string, 0, len(settings))
+	for k, v := range settings {
+		if s, ok := v.(string); ok && hasSecretRef(s) {
+			continue
+		}
+		if isSecretSetting(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	if len(keys) == 0 {
+		return ""
+	}
+	return keys[0]
+}
+
+// isSecretSetting 함수는 key가 비밀 값을 담는 플래그인지 알려 줍니다.
+func isSecretSetting(key string) bool {
+	return strings.HasSuffix(key, "password") || strings.HasSuffix(key, "api-key") || strings.HasSuffix(key, "token") || strings.HasSuffix(key, "vault-key")
+}
+
+// redactSettings 함수는 비밀 값을 그대로 담은 설정을 가린 사본을 반환합니다. 비밀 참조는
+// 값이 아니므로 그대로 둡니다.
+func redactSettings(settings map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(settings))
+	for k, v := range settings {
+		if s, ok := v.(string); isSecretSetting(k) && !(ok && hasSecretRef(s)) {
+			v = "<redacted>"
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// allFlagNames 함수는 모든 명령과 전역 옵션의 플래그 이름을 반환합니다.
+func allFlagNames() map[string]bool {
+	names := make(map[string]bool)
+	for _, cmd := range commands {
+		fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+		var opts globalOptions
+		opts.bind(fs)
+		cmd.setup(fs)
+		fs.VisitAll(func(f *flag.Flag) { names[f.Name] = true })
+	}
+	return names
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	os.WriteFile(path, []byte(`
+profiles:
+  prod:
+    url: https://es-prod:9200
+    api-key: c2VjcmV0
+    password: ${env:ES_TEST_PROFILE_PASSWORD}
+    scroll-size: 5000
+    index: [logs-a, logs-b]
+    pit: true
+    out-dir: /data/exports
+  dev:
+    url: http://localhost:9200
+`), 0o600)
+
+	t.Setenv("ES_TEST_PROFILE_PASSWORD", "from-env")
+	settings, err := loadProfile(path, "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	setupExport(fs)
+	if err := fs.Parse([]string{"--out-dir", "/tmp/here"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applySettings(context.Background(), fs, `profile "prod"`, settings); err != nil {
+		t.Fatal(err)
+	}
+	get := func(name string) string { return fs.Lookup(name).Value.String() }
+	if get("url") != "https://es-prod:9200" || get("scroll-size") != "5000" || get("index") != "logs-a,logs-b" || get("pit") != "true" {
+		t.Errorf("profile flags: url=%s scroll-size=%s index=%s pit=%s", get("url"), get("scroll-size"), get("index"), get("pit"))
+	}
+	if get("password") != "from-env" {
+		t.Errorf("password = %s, want the resolved ${env:...} reference", get("password"))
+	}
+	if get("out-dir") != "/tmp/here" {
+		t.Errorf("out-dir = %s, want the command-line value", get("out-dir"))
+	}
+	if profileSecret(settings) != "api-key" {
+		t.Errorf("profileSecret = %q", profileSecret(settings))
+	}
+
+	// 이 명령에 없는 플래그는 건너뛰고, 어느 명령에도 없는 이름은 오류입니다.
+	inspect := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	setupInspect(inspect)
+	if err := applySettings(context.Background(), inspect, `profile "prod"`, settings); err != nil {
+		t.Errorf("profile with export flags for inspect = %v", err)
+	}
+	err = applySettings(context.Background(), fs, `profile "prod"`, map[string]interface{}{"scrol-size": 10.0})
+	if err == nil || !strings.Contains(err.Error(), `unknown setting "scrol-size"`) {
+		t.Errorf("misspelled setting = %v", err)
+	}
+	if _, err := loadProfile(path, "staging"); err == nil || !strings.Contains(err.Error(), "[dev prod]") {
+		t.Errorf("missing profile = %v", err)
+	}
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+const (
+	maskNullify  = "nullify"
+	maskHash     = "hash"
+	maskRedact   = "redact"
+	maskTokenize = "tokenize"
+)
+
+// redactedValue는 redact 규칙이 값 대신 쓰는 문자열입니다.
+const redactedValue = "REDACTED"
+
+// projectionNamePattern은 프로젝션 이름입니다. 출력 디렉터리 이름이 되므로 경로 구분자를
+// 허용하지 않습니다.
+var projectionNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// projectionSpec은 설정 파일의 projections에 이름으로 둔 출력 하나의 컬럼 집합과 가림
+// 규칙입니다. 경로는 점으로 이은 필드 경로이고, 객체 필드의 경로는 그 아래 필드를 모두
+// 가리킵니다. Include가 있으면 그 필드만, 그 중 Exclude의 필드는 빼고 씁니다. Mask는
+// 경로별로 값을 nullify(null로 씀), hash(SHA-256, Salt가 있으면 HMAC-SHA256의 16진수),
+// redact(REDACTED로 씀) 또는 tokenize(--token-vault의 형식을 유지하는 토큰으로 씀)합니다.
+// nullify가 아닌 규칙은 그 컬럼을 문자열 컬럼으로 바꿉니다.
+//
+//	projections:
+//	  analyst:
+//	    exclude: [user.email, user.phone]
+//	    mask:
+//	      user.id: hash
+//	      client_ip: nullify
+//	      account_no: tokenize
+//	    salt: ${vault:secret/data/es-schema#pii_salt}
+//	  ml:
+//	    include: [label, embedding, features]
+type projectionSpec struct {
+	Include []string          `json:"include,omitempty"`
+	Exclude []string          `json:"exclude,omitempty"`
+	Mask    map[string]string `json:"mask,omitempty"`
+	Salt    string            `json:"salt,omitempty"`
+
+	name string
+	// vault는 tokenize 규칙이 쓰는 토큰 금고입니다.
+	vault *tokenVault
+}
+
+// projectionOptions는 --projection 플래그입니다.
+type projectionOptions struct {
+	names     stringListFlag
+	specs     []*projectionSpec
+	vaultOpts tokenVaultOptions
+	// vault는 tokenize 규칙이 있을 때 load가 잠가 연 토큰 금고로, 실행이 끝나면 close로
+	// 저장합니다.
+	vault *tokenVault
+}
+
+func (o *projectionOptions) bind(fs *flag.FlagSet) {
+	o.vaultOpts.bind(fs)
+	fs.Var(&o.names, "projection", "write every index once per named projection from the projections section of the config file (columns to include or exclude and masking rules), into <out-dir>/<projection>/ (comma-separated or repeated)")
+}
+
+// load 함수는 --projection의 프로젝션을 설정 파일에서 읽고 검사합니다. salt의 비밀
+// 참조도 여기서 풉니다.
+func (o *projectionOptions) load(ctx context.Context) error {
+	if len(o.names) == 0 {
+		return nil
+	}
+	path, err := profilePath()
+	if err != nil {
+		return err
+	}
+	specs, err := loadProjections(path)
+	if err != nil {
+		return err
+	}
+	var secrets secretResolver
+	seen := make(map[string]bool)
+	for _, name := range o.names {
+		if seen[name] {
+			return configErrorf("--projection %s is given twice", name)
+		}
+		seen[name] = true
+		spec, ok := specs[name]
+		if !ok {
+			names := make([]string, 0, len(specs))
+			for n := range specs {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			return configErrorf("%s has no projection %q (projections: %v)", path, name, names)
+		}
+		if spec.Salt, err = secrets.expand(ctx, spec.Salt); err != nil {
+			return fmt.Errorf("projection %s: salt: %w", name, err)
+		}
+		o.specs = append(o.specs, spec)
+	}
+	for _, spec := range o.specs {
+		if !spec.tokenizes() {
+			continue
+		}
+		if o.vault == nil {
+			if o.vault, err = o.vaultOpts.open(true); err != nil {
+				return fmt.Errorf("projection %s: %w", spec.name, err)
+			}
+		}
+		spec.vault = o.vault
+	}
+	return nil
+}
+
+// close 함수는 토큰 금고가 열려 있으면 새 토큰을 저장하고 잠금을 풉니다. 토큰이 이미 파일에
+// 쓰였을 수 있으므로 실행이 실패해도 부릅니다.
+func (o *projectionOptions) close() error {
+	if o.vault == nil {
+		return nil
+	}
+	err := o.vault.close()
+	o.vault = nil
+	return err
+}
+
+// loadProjections 함수는 path의 설정 파일에서 프로젝션을 모두 읽어 검사합니다.
+func loadProjections(path string) (map[string]*projectionSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, configErrorf("reading projections: %w", err)
+	}
+	var cfg profileConfig
+	if err := unmarshalYAML(data, &cfg); err != nil {
+		return nil, configErrorf("parsing %s: %w", path, err)
+	}
+	for name, spec := range cfg.Projections {
+		if spec == nil {
+			spec = &projectionSpec{}
+			cfg.Projections[name] = spec
+		}
+		spec.name = name
+		if err := spec.validate(); err != nil {
+			return nil, configErrorf("%s: projection %q: %w", path, name, err)
+		}
+	}
+	return cfg.Projections, nil
+}
+
+func (p *projectionSpec) validate() error {
+	if !projectionNamePattern.MatchString(p.name) {
+		return fmt.Errorf("name must be letters, digits, - and _")
+	}
+	for _, path := range append(append([]string(nil), p.Include...), p.Exclude...) {
+		if path == "" || strings.HasPrefix(path, ".") || strings.HasSuffix(path, ".") {
+			return fmt.Errorf("invalid field path %q", path)
+		}
+	}
+	for path, rule := range p.Mask {
+		switch rule {
+		case maskNullify, maskHash, maskRedact, maskTokenize:
+		default:
+			return fmt.Errorf("mask %s: unknown rule %q (want nullify, hash, redact or tokenize)", path, rule)
+		}
+	}
+	return nil
+}
+
+// tokenizes 함수는 tokenize 규칙이 있는지 알려 줍니다.
+func (p *projectionSpec) tokenizes() bool {
+	for _, rule := range p.Mask {
+		if rule == maskTokenize {
+			return true
+		}
+	}
+	return false
+}
+
+// maskPaths 함수는 가리는 경로를 정렬해 반환합니다.
+func (p *projectionSpec) maskPaths() []string {
+	paths := make([]string, 0, len(p.Mask))
+	for path := range p.Mask {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// covers 함수는 leaf 경로가 paths 중 하나이거나 그 아래에 있는지 알려 줍니다.
+func covers(paths []string, leaf string) bool {
+	for _, p := range paths {
+		if leaf == p || strings.HasPrefix(leaf, p+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// apply 함수는 매핑 JSON에서 프로젝션에 들지 않는 필드를 지운 매핑과, 매핑에 없는 Include,
+// Exclude, Mask 경로를 반환합니다. 파일에 남기는 매핑에서도 지워지므로 빠진 필드는 이름도
+// 남지 않습니다.
+func (p *projectionSpec) apply(mapping []byte) ([]byte, []string, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal(mapping, &m); err != nil {
+		return nil, nil, err
+	}
+	props, _ := m["properties"].(map[string]interface{})
+	leaves := mappingLeafPaths(props, "")
+	var missing []string
+	for _, path := range append(append(append([]string(nil), p.Include...), p.Exclude...), p.maskPaths()...) {
+		found := false
+		for _, leaf := range leaves {
+			if covers([]string{path}, leaf) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, path)
+		}
+	}
+	if len(p.Include) > 0 || len(p.Exclude) > 0 {
+		kept := make(map[string]bool, len(leaves))
+		for _, leaf := range leaves {
+			kept[leaf] = (len(p.Include) == 0 || covers(p.Include, leaf)) && !covers(p.Exclude, leaf)
+		}
+		pruneMapping(props, "", kept)
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, missing, nil
+}
+
+// maskFields 함수는 nullify가 아닌 규칙으로 가리는 컬럼을 문자열 컬럼으로 바꿉니다. 리스트 컬럼은
+// 문자열 리스트가 됩니다. 프로젝션이 뺀 필드는 건너뜁니다.
+func (p *projectionSpec) maskFields(fields []arrow.Field) []arrow.Field {
+	for _, path := range p.maskPaths() {
+		if p.Mask[path] == maskNullify {
+			continue
+		}
+		updated, ok := updateField(fields, strings.Split(path, "."), func(f arrow.Field) (arrow.Field, bool) {
+			if _, isList := f.Type.(*arrow.ListType); isList {
+				f.Type = arrow.ListOf(arrow.BinaryTypes.String)
+			} else {
+				f.Type = arrow.BinaryTypes.String
+			}
+			f.Nullable = true
+			return f, true
+		})
+		if ok {
+			fields = updated
+		}
+	}
+	return fields
+}
+
+// transform 함수는 Mask의 규칙을 적용하는 변환을 만듭니다. 객체 배열 안의 값과 여러 값을
+// 가진 필드는 원소마다 가립니다.
+func (p *projectionSpec) transform() docTransform {
+	paths := p.maskPaths()
+	split := make([][]string, len(paths))
+	masks := make([]maskFunc, len(paths))
+	for i, path := range paths {
+		split[i], masks[i] = strings.Split(path, "."), p.maskValue(p.Mask[path])
+	}
+	return func(doc map[string]interface{}) (map[string]interface{}, error) {
+		for i, path := range paths {
+			if err := maskAtPath(doc, split[i], masks[i]); err != nil {
+				return nil, fmt.Errorf("masking %s: %w", path, err)
+			}
+		}
+		return doc, nil
+	}
+}
+
+// maskFunc는 값 하나를 가립니다. nil을 반환하면 값을 지웁니다.
+type maskFunc func(v interface{}) (interface{}, error)
+
+// maskValue 함수는 rule에 따라 값 하나를 가리는 함수를 반환합니다.
+func (p *projectionSpec) maskValue(rule string) maskFunc {
+	switch rule {
+	case maskHash:
+		return func(v interface{}) (interface{}, error) {
+			s := maskString(v)
+			if p.Salt == "" {
+				sum := sha256.Sum256([]byte(s))
+				return hex.EncodeToString(sum[:]), nil
+			}
+			mac := hmac.New(sha256.New, []byte(p.Salt))
+			mac.Write([]byte(s))
+			return hex.EncodeToString(mac.Sum(nil)), nil
+		}
+	case maskRedact:
+		return func(interface{}) (interface{}, error) { return redactedValue, nil }
+	case maskTokenize:
+		return func(v interface{}) (interface{}, error) {
+			return p.vault.tokenize(maskString(v))
+		}
+	}
+	return func(interface{}) (interface{}, error) { return nil, nil }
+}
+
+// maskString 함수는 가릴 값을 문자열로 바꿉니다. 문자열이 아닌 값은 JSON으로 씁니다.
+func maskString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func maskAtPath(v interface{}, path []string, mask maskFunc) error {
+	switch x := v.(type) {
+	case []interface{}:
+		for _, item := range x {
+			if err := maskAtPath(item, path, mask); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		child, ok := x[path[0]]
+		if !ok || child == nil {
+			return nil
+		}
+		if len(path) > 1 {
+			return maskAtPath(child, path[1:], mask)
+		}
+		items, isList := child.([]interface{})
+		if !isList {
+			items = []interface{}{child}
+		}
+		masked := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			if item == nil {
+				continue
+			}
+			m, err := mask(item)
+			if err != nil {
+				return err
+			}
+			if m != nil {
+				masked = append(masked, m)
+			}
+		}
+		switch {
+		case len(masked) == 0:
+			delete(x, path[0])
+		case isList:
+			x[path[0]] = masked
+		default:
+			x[path[0]] = masked[0]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadProjections(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	os.WriteFile(path, []byte(`
+profiles:
+  prod:
+    url: https://es-prod:9200
+projections:
+  analyst:
+    exclude: [user.email]
+    mask:
+      user.id: hash
+      client_ip: nullify
+    salt: ${env:ES_TEST_PROJECTION_SALT}
+  ml:
+    include: [label, embedding]
+`), 0o600)
+	t.Setenv("ES_SCHEMA_CONFIG", path)
+	t.Setenv("ES_TEST_PROJECTION_SALT", "pepper")
+
+	o := projectionOptions{names: stringListFlag{"analyst", "ml"}}
+	if err := o.load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(o.specs) != 2 || o.specs[0].name != "analyst" || o.specs[0].Salt != "pepper" || o.specs[1].Include[1] != "embedding" {
+		t.Errorf("specs = %+v", o.specs)
+	}
+
+	o = projectionOptions{names: stringListFlag{"finance"}}
+	err := o.load(context.Background())
+	if exitCodeFor(err) != exitConfigError || !strings.Contains(err.Error(), "no projection \"finance\" (projections: [analyst ml])") {
+		t.Errorf("unknown projection = %v", err)
+	}
+
+	os.WriteFile(path, []byte("projections:\n  analyst:\n    mask:\n      user.id: scramble\n"), 0o600)
+	if _, err := loadProjections(path); err == nil || !strings.Contains(err.Error(), `unknown rule "scramble"`) {
+		t.Errorf("bad mask rule = %v", err)
+	}
+}
+
+func TestProjectionMapping(t *testing.T) {
+	mapping := []byte(`{"properties": {
+		"label": {"type": "keyword"},
+		"embedding": {"type": "dense_vector", "dims": 3},
+		"user": {"properties": {"id": {"type": "keyword"}, "email": {"type": "keyword"}, "name": {"type": "text"}}},
+		"message": {"type": "text"}
+	}}`)
+	cases := []struct {
+		spec    projectionSpec
+		leaves  []string
+		missing []string
+	}{
+		{projectionSpec{Exclude: []string{"user.email"}}, []string{"embedding", "label", "message", "user.id", "user.name"}, nil},
+		{projectionSpec{Include: []string{"label", "embedding", "vector"}}, []string{"embedding", "label"}, []string{"vector"}},
+		{projectionSpec{Include: []string{"user"}, Exclude: []string{"user.email"}, Mask: map[string]string{"user.id": maskHash}}, []string{"user.id", "user.name"}, nil},
+	}
+	for _, c := range cases {
+		data, missing, err := c.spec.apply(mapping)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var m map[string]interface{}
+		json.Unmarshal(data, &m)
+		leaves := mappingLeafPaths(m["properties"].(map[string]interface{}), "")
+		if !reflect.DeepEqual(leaves, c.leaves) || !reflect.DeepEqual(missing, c.missing) {
+			t.Errorf("%+v: leaves = %v, missing = %v; want %v, %v", c.spec, leaves, missing, c.leaves, c.missing)
+		}
+	}
+}
+
+func TestProjectionMask(t *testing.T) {
+	spec := projectionSpec{Mask: map[string]string{"user.id": maskHash, "tags": maskRedact, "orders.card": maskNullify, "ip": maskNullify}}
+	var doc map[string]interface{}
+	json.Unmarshal([]byte(`{"user": {"id": "u-1", "name": "Kim"}, "tags": ["a", null, "b"], "orders": [{"card": "4111", "total": 3}, {"total": 5}], "ip": "10.0.0.1"}`), &doc)
+	doc, err := spec.transform()(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := json.Marshal(doc)
+	want := `{"orders":[{"total":3},{"total":5}],"tags":["REDACTED","REDACTED"],"user":{"id":"a24a7f55f278dd49fb1f99c5507800cb198a5bfe10fe2126cd0b25672152b0da","name":"Kim"}}`
+	if string(got) != want {
+		t.Errorf("masked = %s\nwant %s", got, want)
+	}
+
+	salted := projectionSpec{Mask: spec.Mask, Salt: "pepper"}
+	hash := func(p projectionSpec, v interface{}) interface{} {
+		h, _ := p.maskValue(maskHash)(v)
+		return h
+	}
+	if hash(salted, "u-1") == hash(spec, "u-1") || hash(spec, float64(7)) == hash(spec, "8") {
+		t.Error("hash ignores the salt or the value")
+	}
+}
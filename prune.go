@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	pruneByMtime     = "mtime"
+	pruneByPartition = "partition"
+)
+
+// partitionLayouts는 --by partition이 파티션 값으로 받아들이는 날짜 형식입니다.
+var partitionLayouts = []string{"2006-01-02T15:04:05Z07:00", "2006-01-02T15", "2006-01-02", "20060102", "2006-01", "2006"}
+
+// pruneOptions는 prune 명령의 설정입니다. 내보낸 데이터셋에서 보존 기간이 지난 파일이나
+// 파티션 디렉터리를 지웁니다.
+type pruneOptions struct {
+	olderThan    time.Duration
+	by           string
+	partitionKey string
+	dryRun       bool
+	now          time.Time
+}
+
+// ageFlag는 time.ParseDuration 형식에 더해 일(d)과 주(w) 단위를 받는 기간 플래그입니다.
+type ageFlag struct{ d *time.Duration }
+
+func (f ageFlag) String() string {
+	if f.d == nil {
+		return ""
+	}
+	return f.d.String()
+}
+
+func (f ageFlag) Set(s string) error {
+	d, err := parseAge(s)
+	if err != nil {
+		return err
+	}
+	*f.d = d
+	return nil
+}
+
+// parseAge 함수는 "90d", "2w", "36h" 같은 기간을 읽습니다.
+func parseAge(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.Atoi(n)
+			if err != nil || v < 0 {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			return time.Duration(v) * unit, nil
+		}
+	}
+	return time.ParseDuration(s)
+}
+
+func setupPrune(fs *flag.FlagSet) func(ctx context.Context, report *runReport, args []string) error {
+	var o pruneOptions
+	fs.Var(ageFlag{&o.olderThan}, "older-than", "delete data older than this, e.g. 90d, 2w or 36h (required)")
+	fs.StringVar(&o.by, "by", pruneByMtime, "how to date data: mtime (each Parquet file's modification time) or partition (the date in <--partition-key>=<value> directory names; whole partitions are deleted)")
+	fs.StringVar(&o.partitionKey, "partition-key", "date", "partition directory key holding the date for --by partition")
+	fs.BoolVar(&o.dryRun, "dry-run", false, "print what would be deleted without deleting it")
+
+	return func(ctx context.Context, report *runReport, args []string) error {
+		if len(args) == 0 {
+			return configErrorf("prune: no directories given")
+		}
+		if o.olderThan <= 0 {
+			return configErrorf("prune: --older-than is required")
+		}
+		switch o.by {
+		case pruneByMtime, pruneByPartition:
+		default:
+			return configErrorf("prune: unknown --by %q (want mtime or partition)", o.by)
+		}
+		for _, dir := range args {
+			if strings.Contains(dir, "://") {
+				return configErrorf("prune: %s: only local directories are supported", dir)
+			}
+		}
+		o.now = time.Now()
+		for _, dir := range args {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := o.prune(dir); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// prune 함수는 dir 아래에서 만료된 대상을 찾아 지웁니다.
+func (o *pruneOptions) prune(dir string) error {
+	expired, err := o.expired(dir)
+	if err != nil {
+		return configErrorf("prune: %w", err)
+	}
+	verb := "deleted"
+	if o.dryRun {
+		verb = "would delete"
+	}
+	for _, path := range expired {
+		if !o.dryRun {
+			if err := os.RemoveAll(path); err != nil {
+				return configErrorf("prune: %w", err)
+			}
+		}
+		fmt.Printf("%s %s\n", verb, path)
+	}
+	if len(expired) == 0 {
+		fmt.Printf("%s: nothing older than %s\n", dir, o.olderThan)
+	}
+	return nil
+}
+
+// expired 함수는 지울 파일이나 파티션 디렉터리의 경로를 정렬해 반환합니다. 만료된
+// 파티션 디렉터리는 통째로 지우므로 그 아래는 더 보지 않습니다.
+func (o *pruneOptions) expired(dir string) ([]string, error) {
+	cutoff := o.now.Add(-o.olderThan)
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		switch o.by {
+		case pruneByPartition:
+			if !d.IsDir() || path == dir {
+				return nil
+			}
+			if t, ok := partitionTime(d.Name(), o.partitionKey); ok && partitionEnd(t, d.Name()).Before(cutoff) {
+				paths = append(paths, path)
+				return filepath.SkipDir
+			}
+		case pruneByMtime:
+			if d.IsDir() || !strings.HasSuffix(d.Name(), ".parquet") {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if info.ModTime().Before(cutoff) {
+				paths = append(paths, path)
+			}
+		}
+		return nil
+	})
+	sort.Strings(paths)
+	return paths, err
+}
+
+// partitionTime 함수는 key=value 형식의 디렉터리 이름에서 날짜를 읽습니다.
+func partitionTime(name, key string) (time.Time, bool) {
+	value, ok := strings.CutPrefix(name, key+"=")
+	if !ok {
+		return time.Time{}, false
+	}
+	for _, layout := range partitionLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// partitionEnd 함수는 날짜 t로 시작하는 파티션이 끝나는 시각을 반환합니다. 월 파티션은
+// 그 달의 마지막 데이터도 보존 기간을 채운 뒤에 지웁니다.
+func partitionEnd(t time.Time, name string) time.Time {
+	value := name[strings.IndexByte(name, '=')+1:]
+	switch len(value) {
+	case len("2006"):
+		return t.AddDate(1, 0, 0)
+	case len("2006-01"):
+		return t.AddDate(0, 1, 0)
+	case len("2006-01-02T15"):
+		return t.Add(time.Hour)
+	case len("2006-01-02"), len("20060102"):
+		return t.AddDate(0, 0, 1)
+	}
+	return t
+}
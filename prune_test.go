@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	for in, want := range map[string]time.Duration{"90d": 90 * 24 * time.Hour, "2w": 14 * 24 * time.Hour, "36h": 36 * time.Hour} {
+		if got, err := parseAge(in); err != nil || got != want {
+			t.Errorf("parseAge(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := parseAge("xd"); err == nil {
+		t.Error("parseAge accepted xd")
+	}
+}
+
+func TestPruneExpiredPartitions(t *testing.T) {
+	dir := t.TempDir()
+	for _, p := range []string{"index=logs/date=2024-01-30", "index=logs/date=2024-02-01", "index=logs/date=2024-01", "index=logs/other"} {
+		if err := os.MkdirAll(filepath.Join(dir, p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	o := &pruneOptions{
+		olderThan:    30 * 24 * time.Hour,
+		by:           pruneByPartition,
+		partitionKey: "date",
+		now:          time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	got, err := o.expired(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2024-01 파티션은 1월 31일까지의 데이터를 담으므로 30일이 지나지 않았습니다.
+	want := []string{filepath.Join(dir, "index=logs/date=2024-01-30")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expired = %v, want %v", got, want)
+	}
+}
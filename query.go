@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// queryFormats는 query --format 값과 DuckDB CLI의 출력 옵션입니다.
+var queryFormats = map[string]string{
+	"table":  "-box",
+	"csv":    "-csv",
+	"json":   "-json",
+	"ndjson": "-jsonlines",
+}
+
+// queryOptions는 query 명령의 설정입니다. Go용 DuckDB 바인딩은 cgo와 네이티브 라이브러리가
+// 필요해 배포 바이너리를 무겁게 하므로, 설치된 DuckDB CLI를 불러 SQL을 실행합니다.
+type queryOptions struct {
+	duckdb string
+	format string
+	file   string
+}
+
+func setupQuery(fs *flag.FlagSet) func(ctx context.Context, report *runReport, args []string) error {
+	var o queryOptions
+	fs.StringVar(&o.duckdb, "duckdb", "duckdb", "DuckDB CLI to run the query with")
+	fs.StringVar(&o.format, "format", "table", "output format: table, csv, json or ndjson")
+	fs.StringVar(&o.file, "file", "", "read the SQL from this file instead of the argument")
+
+	return func(ctx context.Context, report *runReport, args []string) error {
+		sql, err := o.sql(args)
+		if err != nil {
+			return err
+		}
+		mode, ok := queryFormats[o.format]
+		if !ok {
+			return configErrorf("query: unknown --format %q (want table, csv, json or ndjson)", o.format)
+		}
+		bin, err := exec.LookPath(o.duckdb)
+		if err != nil {
+			return configErrorf("query: DuckDB CLI %q not found; install it from duckdb.org or point --duckdb at it", o.duckdb)
+		}
+		return runDuckDB(ctx, bin, mode, sql)
+	}
+}
+
+// sql 함수는 인자나 --file에서 실행할 SQL을 읽습니다.
+func (o *queryOptions) sql(args []string) (string, error) {
+	switch {
+	case o.file != "" && len(args) > 0:
+		return "", configErrorf("query: give the SQL as an argument or with --file, not both")
+	case o.file != "":
+		data, err := os.ReadFile(o.file)
+		if err != nil {
+			return "", configErrorf("reading --file: %w", err)
+		}
+		return string(data), nil
+	case len(args) == 1 && strings.TrimSpace(args[0]) != "":
+		return args[0], nil
+	}
+	return "", configErrorf("query: want one SQL statement, e.g. es-schema query \"SELECT count(*) FROM 'out/*.parquet'\"")
+}
+
+// runDuckDB 함수는 메모리 데이터베이스로 DuckDB CLI를 실행해 sql의 결과를 표준 출력에
+// 씁니다. SQL은 인자 길이 제한을 피하려고 표준 입력으로 넘깁니다. DuckDB가 실패하면 그
+// 메시지는 표준 오류에 나오고, 잘못된 쿼리로 보아 설정 오류를 반환합니다.
+func runDuckDB(ctx context.Context, bin, mode, sql string) error {
+	cmd := exec.CommandContext(ctx, bin, "-batch", "-bail", mode, ":memory:")
+	cmd.Stdin = strings.NewReader(sql)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		return configErrorf("query: duckdb exited with status %d", exit.ExitCode())
+	}
+	if err != nil {
+		return configErrorf("query: running duckdb: %w", err)
+	}
+	return nil
+}
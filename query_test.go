@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunDuckDBPassesSQLOnStdin(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "calls")
+	fake := filepath.Join(dir, "duckdb")
+	script := "#!/bin/sh\necho \"$@\" > " + out + "\ncat >> " + out + "\n"
+	if err := os.WriteFile(fake, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := runDuckDB(context.Background(), fake, queryFormats["csv"], "SELECT 1"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(out)
+	if want := "-batch -bail -csv :memory:\nSELECT 1"; string(data) != want {
+		t.Errorf("duckdb saw %q, want %q", data, want)
+	}
+
+	if err := os.WriteFile(fake, []byte("#!/bin/sh\nexit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := runDuckDB(context.Background(), fake, "-box", "SELECT nope"); kindOf(err) != kindConfig {
+		t.Errorf("failed query error = %v, want a config error", err)
+	}
+}
+
+func TestQuerySQLSource(t *testing.T) {
+	o := &queryOptions{}
+	if _, err := o.sql(nil); err == nil {
+		t.Error("sql accepted no statement")
+	}
+	if sql, err := o.sql([]string{"SELECT 1"}); err != nil || sql != "SELECT 1" {
+		t.Errorf("sql = %q, %v", sql, err)
+	}
+	o.file = "q.sql"
+	if _, err := o.sql([]string{"SELECT 1"}); err == nil {
+		t.Error("sql accepted both an argument and --file")
+	}
+}
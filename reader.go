@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/memory"
+	"github.com/apache/arrow/go/v10/parquet/file"
+	"github.com/apache/arrow/go/v10/parquet/pqarrow"
+)
+
+// readBatchSize는 Parquet를 읽을 때 한 레코드에 담는 최대 행 수입니다.
+const readBatchSize = 64 * 1024
+
+// parquetFile은 Arrow 레코드 단위로 읽기 위해 연 Parquet 파일입니다.
+type parquetFile struct {
+	path   string
+	pf     *file.Reader
+	reader *pqarrow.FileReader
+}
+
+func openParquetFile(path string) (*parquetFile, error) {
+	pf, err := file.OpenParquetFile(path, false)
+	if err != nil {
+		return nil, dataErrorf("opening %s: %w", path, err)
+	}
+	reader, err := pqarrow.NewFileReader(pf, pqarrow.ArrowReadProperties{BatchSize: readBatchSize}, memory.DefaultAllocator)
+	if err != nil {
+		pf.Close()
+		return nil, dataErrorf("reading Arrow schema of %s: %w", path, err)
+	}
+	return &parquetFile{path: path, pf: pf, reader: reader}, nil
+}
+
+func (f *parquetFile) Close() error {
+	return f.pf.Close()
+}
+
+// NumRows 함수는 파일 메타데이터에 기록된 전체 행 수를 반환합니다.
+func (f *parquetFile) NumRows() int64 {
+	return f.pf.NumRows()
+}
+
+// records 함수는 columns로 지정한 컬럼만 읽어 레코드마다 fn을 호출합니다.
+// columns가 비어 있으면 모든 컬럼을 읽습니다. fn에 전달된 레코드는 다음 호출 전에
+// 해제되므로, 보관하려면 Retain해야 합니다. 읽은 컬럼의 스키마를 반환합니다.
+func (f *parquetFile) records(ctx context.Context, columns []string, fn func(arrow.Record) error) (*arrow.Schema, error) {
+	indices, err := parquetColumnIndices(f.reader.Manifest, columns)
+	if err != nil {
+		return nil, err
+	}
+	rr, err := f.reader.GetRecordReader(ctx, indices, nil)
+	if err != nil {
+		return nil, dataErrorf("reading %s: %w", f.path, err)
+	}
+	defer rr.Release()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		rec, err := rr.Read()
+		if err == io.EOF || (err == nil && rec == nil) {
+			break
+		}
+		if err != nil {
+			return nil, dataErrorf("reading %s: %w", f.path, err)
+		}
+		if err := fn(rec); err != nil {
+			return nil, err
+		}
+	}
+	return rr.Schema(), nil
+}
+
+// parquetColumnIndices 함수는 컬럼 이름 목록을 Parquet leaf 컬럼 인덱스로 바꿉니다.
+// 이름은 최상위 컬럼("user")이거나 점으로 구분한 하위 경로("user.address.city")일 수 있으며,
+// 해당 경로 아래의 모든 leaf 컬럼이 선택됩니다. columns가 비어 있으면 nil(전체)을 반환합니다.
+func parquetColumnIndices(manifest *pqarrow.SchemaManifest, columns []string) ([]int, error) {
+	if len(columns) == 0 {
+		return nil, nil
+	}
+
+	var indices []int
+	seen := make(map[int]bool)
+	for _, col := range columns {
+		field := findManifestField(manifest.Fields, strings.Split(col, "."))
+		if field == nil {
+			return nil, configErrorf("column %q not found in Parquet schema", col)
+		}
+		for _, idx := range manifestLeaves(field) {
+			if !seen[idx] {
+				seen[idx] = true
+				indices = append(indices, idx)
+			}
+		}
+	}
+	sort.Ints(indices)
+	return indices, nil
+}
+
+// findManifestField 함수는 경로에 해당하는 스키마 필드를 찾습니다. 리스트의 요소
+// 단계는 이름 없이 통과하므로 "user.tags"처럼 리스트 안의 필드도 같은 방식으로 지정합니다.
+func findManifestField(fields []pqarrow.SchemaField, path []string) *pqarrow.SchemaField {
+	for i := range fields {
+		f := &fields[i]
+		if f.Field.Name != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			return f
+		}
+		// 리스트는 요소 필드 하나만 자식으로 가지므로 구조체가 나올 때까지 내려갑니다.
+		for isListType(f.Field.Type) && len(f.Children) == 1 {
+			f = &f.Children[0]
+		}
+		return findManifestField(f.Children, path[1:])
+	}
+	return nil
+}
+
+func isListType(dt arrow.DataType) bool {
+	switch dt.ID() {
+	case arrow.LIST, arrow.FIXED_SIZE_LIST, arrow.LARGE_LIST:
+		return true
+	}
+	return false
+}
+
+// manifestLeaves 함수는 필드 아래의 모든 leaf 컬럼 인덱스를 모읍니다.
+func manifestLeaves(field *pqarrow.SchemaField) []int {
+	if field.IsLeaf() {
+		return []int{field.ColIndex}
+	}
+	var leaves []int
+	for i := range field.Children {
+		leaves = append(leaves, manifestLeaves(&field.Children[i])...)
+	}
+	return leaves
+}
+
+// describeColumns 함수는 오류 메시지에 쓸 최상위 컬럼 이름 목록을 만듭니다.
+func describeColumns(sc *arrow.Schema) string {
+	names := make([]string, 0, len(sc.Fields()))
+	for _, f := range sc.Fields() {
+		names = append(names, f.Name)
+	}
+	return fmt.Sprintf("[%s]", strings.Join(names, ", "))
+}
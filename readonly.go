@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// readOnlyEndpoints는 --read-only에서도 POST로 보낼 수 있는, 클러스터를 바꾸지 않는
+// API입니다. 경로에서 _로 시작하는 첫 부분부터 끝까지로 찾으므로 /logs/_doc/_search처럼
+// 문서 ID가 API 이름과 같은 색인 요청은 걸리지 않습니다. _pit은 검색 컨텍스트를 열 뿐
+// 문서나 설정을 바꾸지 않습니다.
+var readOnlyEndpoints = map[string]bool{
+	"_search":          true,
+	"_msearch":         true,
+	"_count":           true,
+	"_field_caps":      true,
+	"_analyze":         true,
+	"_mget":            true,
+	"_pit":             true,
+	"_validate/query":  true,
+	"_search/scroll":   true,
+	"_search/template": true,
+
+	"_security/user/_has_privileges": true,
+}
+
+// readOnlyRequest 함수는 요청이 클러스터의 데이터, 매핑, 설정을 바꿀 수 없는지 판단합니다.
+// 모르는 API는 바꿀 수 있다고 봅니다. DELETE는 읽기가 남긴 scroll과 point in time을
+// 정리할 때만 허용합니다.
+func readOnlyRequest(method, path string) bool {
+	path = strings.TrimRight(path, "/")
+	switch method {
+	case http.MethodGet, http.MethodHead:
+		return true
+	case http.MethodDelete:
+		return path == "/_search/scroll" || path == "/_pit"
+	case http.MethodPost:
+		parts := strings.Split(path, "/")
+		for i, part := range parts {
+			if strings.HasPrefix(part, "_") {
+				return readOnlyEndpoints[strings.Join(parts[i:], "/")]
+			}
+		}
+	}
+	return false
+}
+
+// readOnlyError 함수는 --read-only 클라이언트가 거부한 요청의 오류를 만듭니다.
+func readOnlyError(prefix, method, path string) error {
+	return configErrorf("--%sread-only: refusing %s %s, which can change the cluster", prefix, method, path)
+}
+
+// hasPrivilegesResponse는 _security/user/_has_privileges의 응답입니다.
+type hasPrivilegesResponse struct {
+	Username        string                     `json:"username"`
+	HasAllRequested bool                       `json:"has_all_requested"`
+	Cluster         map[string]bool            `json:"cluster"`
+	Index           map[string]map[string]bool `json:"index"`
+}
+
+// missing 함수는 없는 권한을 "권한 on 인덱스" 형식으로 정렬해 반환합니다.
+func (r *hasPrivilegesResponse) missing() []string {
+	var out []string
+	for name, ok := range r.Cluster {
+		if !ok {
+			out = append(out, "cluster "+name)
+		}
+	}
+	for index, privileges := range r.Index {
+		for name, ok := range privileges {
+			if !ok {
+				out = append(out, name+" on "+index)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// hasPrivileges 함수는 현재 자격 증명이 indices에 privileges를 모두 가졌는지 묻습니다.
+func (c *esClient) hasPrivileges(ctx context.Context, indices, privileges []string) (*hasPrivilegesResponse, error) {
+	body := map[string]interface{}{
+		"index": []map[string]interface{}{{"names": indices, "privileges": privileges}},
+	}
+	var resp hasPrivilegesResponse
+	if err := c.sendJSON(ctx, http.MethodPost, "/_security/user/_has_privileges", nil, body, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// checkReadOnly 함수는 --read-only일 때 자격 증명이 indices를 읽을 권한을 모두 가졌는지
+// 실행 전에 확인해, 몇 시간 뒤 중간에 권한 오류로 멈추지 않게 합니다. 권한이 모자라면
+// 빠진 권한을 모두 담은 설정 오류를 반환합니다. 보안 기능이 꺼진 클러스터처럼 권한을
+// 물을 수 없으면 경고만 남깁니다. 요청 거부는 클라이언트가 하므로 그래도 읽기 전용입니다.
+func (o *esOptions) checkReadOnly(ctx context.Context, c *esClient, report *runReport, indices []string, privileges ...string) error {
+	if !o.readOnly {
+		return nil
+	}
+	resp, err := c.hasPrivileges(ctx, indices, privileges)
+	var ee *esError
+	if errors.As(err, &ee) && ee.Status != http.StatusUnauthorized {
+		report.warnf("--%sread-only: cannot check privileges on %s (%v); continuing with mutating requests refused", o.prefix, o.url, err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if resp.HasAllRequested {
+		return nil
+	}
+	user := resp.Username
+	if user == "" {
+		user = "the current user"
+	}
+	return configErrorf("--%sread-only: %s lacks %s on %s", o.prefix, user, strings.Join(resp.missing(), ", "), o.url)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReadOnlyRequest(t *testing.T) {
+	cases := []struct {
+		method, path string
+		want         bool
+	}{
+		{http.MethodGet, "/logs/_mapping", true},
+		{http.MethodHead, "/logs", true},
+		{http.MethodPost, "/logs/_search", true},
+		{http.MethodPost, "/_search/scroll", true},
+		{http.MethodPost, "/logs-*,metrics/_pit", true},
+		{http.MethodPost, "/_security/user/_has_privileges", true},
+		{http.MethodDelete, "/_search/scroll", true},
+		{http.MethodDelete, "/_pit", true},
+		{http.MethodPost, "/logs/_bulk", false},
+		{http.MethodPost, "/logs/_doc/_search", false},
+		{http.MethodPost, "/logs/_update_by_query", false},
+		{http.MethodPost, "/logs", false},
+		{http.MethodPut, "/logs", false},
+		{http.MethodPut, "/logs/_search", false},
+		{http.MethodDelete, "/logs", false},
+	}
+	for _, c := range cases {
+		if got := readOnlyRequest(c.method, c.path); got != c.want {
+			t.Errorf("readOnlyRequest(%s %s) = %v, want %v", c.method, c.path, got, c.want)
+		}
+	}
+}
+
+func TestReadOnlyClient(t *testing.T) {
+	var requests []string
+	var missing bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		var body struct {
+			Index []struct {
+				Names      []string `json:"names"`
+				Privileges []string `json:"privileges"`
+			} `json:"index"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if len(body.Index) != 1 || strings.Join(body.Index[0].Privileges, ",") != "read,view_index_metadata" {
+			t.Errorf("privileges request = %+v", body)
+		}
+		fmt.Fprintf(w, `{"username": "exporter", "has_all_requested": %v, "cluster": {}, "index": {"logs-*": {"read": true, "view_index_metadata": %v}}}`, !missing, !missing)
+	}))
+	defer srv.Close()
+	o := esOptions{url: srv.URL, readOnly: true}
+	client, err := o.client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.http = srv.Client()
+	ctx := context.Background()
+	report := newRunReport("export")
+
+	if err := o.checkReadOnly(ctx, client, report, []string{"logs-*"}, "read", "view_index_metadata"); err != nil {
+		t.Errorf("check with all privileges: %v", err)
+	}
+	missing = true
+	err = o.checkReadOnly(ctx, client, report, []string{"logs-*"}, "read", "view_index_metadata")
+	if exitCodeFor(err) != exitConfigError || !strings.Contains(err.Error(), "exporter lacks view_index_metadata on logs-*") {
+		t.Errorf("check with a missing privilege = %v", err)
+	}
+
+	// 바꾸는 요청은 서버에 닿기 전에 거부됩니다.
+	err = client.createIndex(ctx, "logs-copy", map[string]interface{}{})
+	if exitCodeFor(err) != exitConfigError || !strings.Contains(err.Error(), "--read-only: refusing PUT /logs-copy") {
+		t.Errorf("createIndex = %v", err)
+	}
+	if _, err := client.bulk(ctx, "logs", []byte("{}\n"), nil); err == nil {
+		t.Error("bulk was sent by a read-only client")
+	}
+	if len(requests) != 2 {
+		t.Errorf("requests = %v, want only the two privilege checks", requests)
+	}
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	conflictWiden   = "widen"
+	conflictKeyword = "keyword"
+	conflictFail    = "fail"
+)
+
+// typeConflictOptions는 여러 인덱스에서 같은 필드의 타입이 다를 때 통합 스키마에 쓸
+// 타입을 정하는 규칙입니다. overrides는 --resolve-type으로 필드마다 정한 타입입니다.
+type typeConflictOptions struct {
+	onConflict string
+	resolve    stringListFlag
+	overrides  map[string]string
+}
+
+func (o *typeConflictOptions) bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.onConflict, "on-type-conflict", conflictWiden, "when a field has different types across indices: widen (the widest number type, date over date_nanos, nested over object, keyword otherwise), keyword or fail")
+	fs.Var(&o.resolve, "resolve-type", "use this type for a field whatever the indices say, as path=type (comma-separated or repeated)")
+}
+
+func (o *typeConflictOptions) validate() error {
+	switch o.onConflict {
+	case conflictWiden, conflictKeyword, conflictFail:
+	default:
+		return configErrorf("unknown --on-type-conflict %q (want widen, keyword or fail)", o.onConflict)
+	}
+	o.overrides = make(map[string]string, len(o.resolve))
+	for _, spec := range o.resolve {
+		path, typ, ok := strings.Cut(spec, "=")
+		if !ok || path == "" || typ == "" {
+			return configErrorf("--resolve-type %q: want path=type", spec)
+		}
+		o.overrides[path] = typ
+	}
+	return nil
+}
+
+// typeConflict는 인덱스마다 타입이 다른 필드 하나와 그 해결 결과입니다. Types는 타입별로
+// 그 타입을 쓰는 인덱스입니다.
+type typeConflict struct {
+	Field    string              `json:"field"`
+	Types    map[string][]string `json:"types"`
+	Resolved string              `json:"resolved"`
+	Rule     string              `json:"rule"`
+}
+
+// metadataFields는 _field_caps가 돌려주지만 문서의 필드가 아닌 메타데이터 필드입니다.
+// metadata_field 표시가 없는 오래된 버전을 위해 이름으로도 거릅니다.
+var metadataFields = map[string]bool{
+	"_id": true, "_index": true, "_source": true, "_routing": true, "_type": true, "_uid": true,
+	"_seq_no": true, "_primary_term": true, "_version": true, "_field_names": true, "_ignored": true,
+	"_size": true, "_doc_count": true, "_tier": true, "_nested_path": true, "_feature": true,
+	"_data_stream_timestamp": true, "_parent": true, "_all": true,
+}
+
+var integerRanks = map[string]int{"byte": 1, "short": 2, "integer": 3, "long": 4}
+
+var floatRanks = map[string]int{"half_float": 1, "float": 2, "scaled_float": 3, "double": 4}
+
+// widenESTypes 함수는 타입 목록의 값을 모두 담을 수 있는 타입을 고릅니다. 정수끼리는 가장
+// 넓은 정수, 숫자가 섞이면 double, date와 date_nanos는 date, object와 nested는 nested,
+// 모두 text 계열이면 text이고, 나머지는 keyword입니다.
+func widenESTypes(types []string) string {
+	widest, allInt, allFloat, allNumber := "", true, true, true
+	allDate, allObject, allText := true, true, true
+	for _, t := range types {
+		_, isInt := integerRanks[t]
+		_, isFloat := floatRanks[t]
+		allInt = allInt && isInt
+		allFloat = allFloat && isFloat
+		allNumber = allNumber && (isInt || isFloat)
+		allDate = allDate && (t == "date" || t == "date_nanos")
+		allObject = allObject && (t == "object" || t == "nested")
+		allText = allText && (t == "text" || t == "match_only_text")
+		if widest == "" || integerRanks[t] > integerRanks[widest] || floatRanks[t] > floatRanks[widest] {
+			widest = t
+		}
+	}
+	switch {
+	case allInt:
+		return widest
+	case allFloat && widest != "scaled_float":
+		return widest
+	case allNumber:
+		return "double"
+	case allDate:
+		return "date"
+	case allObject:
+		return "nested"
+	case allText:
+		return "text"
+	}
+	return "keyword"
+}
+
+// unify 함수는 _field_caps 결과로 모든 인덱스의 필드를 담는 하나의 매핑을 만듭니다.
+// 타입이 다른 필드는 규칙대로 해결하고 conflicts로 알립니다. 규칙이 fail이면 충돌을 모두
+// 모은 뒤 스키마 오류를 반환합니다. 잎 필드의 매개변수(dims, format 등)는 같은 타입으로
+// 매핑한 인덱스의 매핑에서 가져옵니다.
+func (o *typeConflictOptions) unify(caps map[string]map[string]fieldCapability, indices []string, mappings map[string]json.RawMessage) (map[string]interface{}, []typeConflict, error) {
+	paths := make([]string, 0, len(caps))
+	for path := range caps {
+		paths = append(paths, path)
+	}
+	// 부모 필드가 자식보다 먼저 오도록 정렬합니다.
+	sort.Strings(paths)
+
+	sources := make([]map[string]interface{}, 0, len(mappings))
+	for _, index := range sortedRawKeys(mappings) {
+		data, err := typelessMapping(mappings[index])
+		if err != nil {
+			return nil, nil, err
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal(data, &m); err == nil {
+			sources = append(sources, m)
+		}
+	}
+
+	root := map[string]interface{}{"properties": map[string]interface{}{}}
+	resolved := make(map[string]string, len(paths))
+	var conflicts []typeConflict
+	for _, path := range paths {
+		byType := caps[path]
+		if isMetadataField(path, byType) {
+			continue
+		}
+		if i := strings.LastIndex(path, "."); i >= 0 {
+			// text 필드 아래의 keyword처럼 객체가 아닌 필드 아래의 경로는 다중 필드입니다.
+			if parent, ok := resolved[path[:i]]; !ok || (parent != "object" && parent != "nested") {
+				continue
+			}
+		}
+		types := make([]string, 0, len(byType))
+		for t := range byType {
+			if t != "unmapped" {
+				types = append(types, t)
+			}
+		}
+		if len(types) == 0 {
+			continue
+		}
+		sort.Strings(types)
+		typ, rule := types[0], ""
+		switch override, ok := o.overrides[path]; {
+		case ok:
+			typ, rule = override, "resolve-type"
+		case len(types) == 1:
+		case o.onConflict == conflictKeyword:
+			typ, rule = "keyword", conflictKeyword
+		default:
+			typ, rule = widenESTypes(types), o.onConflict
+		}
+		if len(types) > 1 {
+			c := typeConflict{Field: path, Types: make(map[string][]string, len(types)), Resolved: typ, Rule: rule}
+			for _, t := range types {
+				c.Types[t] = byType[t].Indices
+				if c.Types[t] == nil {
+					c.Types[t] = indices
+				}
+			}
+			conflicts = append(conflicts, c)
+		}
+		resolved[path] = typ
+		setMappingField(root, path, typ, sources)
+	}
+	if o.onConflict == conflictFail {
+		var unresolved []string
+		for _, c := range conflicts {
+			if c.Rule != "resolve-type" {
+				unresolved = append(unresolved, fmt.Sprintf("%s (%s)", c.Field, strings.Join(sortedTypes(c.Types), ", ")))
+			}
+		}
+		if len(unresolved) > 0 {
+			return nil, conflicts, schemaErrorf("fields with different types across indices: %s; choose one with --resolve-type path=type", strings.Join(unresolved, "; "))
+		}
+	}
+	return root, conflicts, nil
+}
+
+func isMetadataField(path string, byType map[string]fieldCapability) bool {
+	if metadataFields[path] {
+		return true
+	}
+	for _, c := range byType {
+		if c.MetadataField {
+			return true
+		}
+	}
+	return false
+}
+
+// setMappingField 함수는 root 매핑의 path 자리에 typ 필드를 만듭니다. 부모 객체는 이미
+// 만들어져 있어야 합니다.
+func setMappingField(root map[string]interface{}, path, typ string, sources []map[string]interface{}) {
+	names := strings.Split(path, ".")
+	parent := root
+	for _, name := range names[:len(names)-1] {
+		props, _ := parent["properties"].(map[string]interface{})
+		parent, _ = props[name].(map[string]interface{})
+	}
+	field := map[string]interface{}{"type": typ}
+	switch typ {
+	case "object":
+		field = map[string]interface{}{"properties": map[string]interface{}{}}
+	case "nested":
+		field["properties"] = map[string]interface{}{}
+	default:
+		for _, src := range sources {
+			orig := lookupMappingField(src, names)
+			if orig == nil || orig["type"] != typ {
+				continue
+			}
+			for k, v := range orig {
+				if k != "properties" {
+					field[k] = v
+				}
+			}
+			break
+		}
+	}
+	props, ok := parent["properties"].(map[string]interface{})
+	if !ok {
+		props = map[string]interface{}{}
+		parent["properties"] = props
+	}
+	props[names[len(names)-1]] = field
+}
+
+func lookupMappingField(mapping map[string]interface{}, names []string) map[string]interface{} {
+	field := mapping
+	for _, name := range names {
+		props, _ := field["properties"].(map[string]interface{})
+		next, ok := props[name].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		field = next
+	}
+	return field
+}
+
+func sortedTypes(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedRawKeys(m map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeTypeConflicts 함수는 타입 충돌과 해결 결과를 표로 씁니다.
+func writeTypeConflicts(w io.Writer, indices int, conflicts []typeConflict) {
+	fmt.Fprintf(w, "%d indices, %d field(s) with conflicting types\n", indices, len(conflicts))
+	for _, c := range conflicts {
+		var parts []string
+		for _, t := range sortedTypes(c.Types) {
+			parts = append(parts, fmt.Sprintf("%s in %s", t, strings.Join(c.Types[t], ",")))
+		}
+		fmt.Fprintf(w, "  %-30s -> %-10s (%s) %s\n", c.Field, c.Resolved, c.Rule, strings.Join(parts, "; "))
+	}
+}
+
+// reconcileMapping 함수는 pattern이 가리키는 인덱스들의 통합 매핑을 만듭니다.
+func (o *typeConflictOptions) reconcileMapping(ctx context.Context, client *esClient, pattern string) (map[string]interface{}, []typeConflict, int, error) {
+	indices, caps, err := client.fieldCaps(ctx, pattern)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if len(indices) == 0 {
+		return nil, nil, 0, configErrorf("%s matches no indices", pattern)
+	}
+	mappings, err := client.getMappings(ctx, pattern)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	mapping, conflicts, err := o.unify(caps, indices, mappings)
+	return mapping, conflicts, len(indices), err
+}
+
+// reconcileOptions는 reconcile 명령의 설정입니다. reconcile은 여러 인덱스의 필드 타입을
+// _field_caps로 비교하고, 충돌을 해결한 통합 매핑을 씁니다.
+type reconcileOptions struct {
+	source     esOptions
+	indices    stringListFlag
+	mappingOut string
+	conflicts  typeConflictOptions
+}
+
+func setupReconcile(fs *flag.FlagSet) func(ctx context.Context, report *runReport, args []string) error {
+	var o reconcileOptions
+	o.source.bind(fs, "", "source")
+	fs.Var(&o.indices, "index", "indices, aliases or patterns such as logs-* to reconcile (comma-separated or repeated; required)")
+	fs.StringVar(&o.mappingOut, "mapping-out", "", "write the unified mapping to this file (default: stdout)")
+	o.conflicts.bind(fs)
+
+	return func(ctx context.Context, report *runReport, args []string) error {
+		if len(args) > 0 {
+			return configErrorf("reconcile: unexpected arguments %v", args)
+		}
+		if len(o.indices) == 0 {
+			return configErrorf("reconcile: --index is required")
+		}
+		if err := o.conflicts.validate(); err != nil {
+			return err
+		}
+		client, err := o.source.client()
+		if err != nil {
+			return err
+		}
+		if err := o.source.checkReadOnly(ctx, client, report, o.indices, "view_index_metadata"); err != nil {
+			return err
+		}
+		mapping, conflicts, n, err := o.conflicts.reconcileMapping(ctx, client, strings.Join(o.indices, ","))
+		if err != nil && conflicts == nil {
+			return err
+		}
+		report.TypeConflicts = conflicts
+		// 매핑 JSON을 표준 출력으로 파이프할 수 있도록, 매핑을 파일로 쓰지 않으면 충돌 표는
+		// 표준 에러로 보냅니다.
+		w := os.Stderr
+		if o.mappingOut != "" {
+			w = os.Stdout
+		}
+		writeTypeConflicts(w, n, conflicts)
+		if err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(mapping, "", "  ")
+		if err != nil {
+			return schemaErrorf("encoding unified mapping: %w", err)
+		}
+		data = append(data, '\n')
+		if o.mappingOut == "" {
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(o.mappingOut, data, 0o644); err != nil {
+			return configErrorf("writing --mapping-out: %w", err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestUnifyFieldCaps(t *testing.T) {
+	var caps map[string]map[string]fieldCapability
+	err := json.Unmarshal([]byte(`{
+		"_id": {"_id": {"type": "_id", "metadata_field": true}},
+		"status": {"keyword": {"type": "keyword", "indices": ["logs-a"]}, "long": {"type": "long", "indices": ["logs-b"]}},
+		"count": {"integer": {"type": "integer", "indices": ["logs-a"]}, "long": {"type": "long", "indices": ["logs-b"]}},
+		"message": {"text": {"type": "text"}},
+		"message.keyword": {"keyword": {"type": "keyword"}},
+		"user": {"object": {"type": "object"}},
+		"user.name": {"keyword": {"type": "keyword"}},
+		"vec": {"dense_vector": {"type": "dense_vector"}}
+	}`), &caps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mappings := map[string]json.RawMessage{
+		"logs-a": json.RawMessage(`{"properties": {"vec": {"type": "dense_vector", "dims": 3}}}`),
+	}
+	indices := []string{"logs-a", "logs-b"}
+
+	o := &typeConflictOptions{onConflict: conflictWiden, resolve: stringListFlag{"status=keyword"}}
+	if err := o.validate(); err != nil {
+		t.Fatal(err)
+	}
+	mapping, conflicts, err := o.unify(caps, indices, mappings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"properties": map[string]interface{}{
+		"status":  map[string]interface{}{"type": "keyword"},
+		"count":   map[string]interface{}{"type": "long"},
+		"message": map[string]interface{}{"type": "text"},
+		"user": map[string]interface{}{"properties": map[string]interface{}{
+			"name": map[string]interface{}{"type": "keyword"},
+		}},
+		"vec": map[string]interface{}{"type": "dense_vector", "dims": 3.0},
+	}}
+	if !reflect.DeepEqual(mapping, want) {
+		got, _ := json.Marshal(mapping)
+		t.Errorf("unified mapping = %s", got)
+	}
+	if len(conflicts) != 2 || conflicts[0].Field != "count" || conflicts[0].Rule != conflictWiden ||
+		conflicts[1].Field != "status" || conflicts[1].Rule != "resolve-type" {
+		t.Errorf("conflicts = %+v", conflicts)
+	}
+
+	o = &typeConflictOptions{onConflict: conflictFail}
+	o.validate()
+	if _, conflicts, err := o.unify(caps, indices, mappings); err == nil || len(conflicts) != 2 {
+		t.Errorf("fail rule: conflicts = %v, err = %v", conflicts, err)
+	}
+}
+
+func TestWidenESTypes(t *testing.T) {
+	for _, c := range []struct {
+		types []string
+		want  string
+	}{
+		{[]string{"integer", "short"}, "integer"},
+		{[]string{"float", "half_float"}, "float"},
+		{[]string{"long", "float"}, "double"},
+		{[]string{"date", "date_nanos"}, "date"},
+		{[]string{"nested", "object"}, "nested"},
+		{[]string{"match_only_text", "text"}, "text"},
+		{[]string{"keyword", "text"}, "keyword"},
+		{[]string{"long", "keyword"}, "keyword"},
+	} {
+		if got := widenESTypes(c.types); got != c.want {
+			t.Errorf("widenESTypes(%v) = %s, want %s", c.types, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+
+	"es-schema/esschema"
+)
+
+// recordDocuments 함수는 레코드의 각 행을 Elasticsearch _source 형태의 문서로 되돌립니다.
+// null 값인 필드는 문서에서 생략하고, 이름이 바뀐 필드는 원래 이름으로 되돌립니다.
+// overflow 컬럼의 JSON 객체는 문서에 다시 합치고, --analyze의 토큰 컬럼과 --geo-lat-lon
+// 컬럼은 버립니다. --non-data-fields json과 --geo-shape json의 문자열은 JSON 값으로,
+// GeoParquet 지오메트리 컬럼과 구조체 안 geo_shape 컬럼의 WKB는 Elasticsearch가 받는 좌표나
+// GeoJSON으로 되돌립니다.
+func recordDocuments(rec arrow.Record) []map[string]interface{} {
+	docs := make([]map[string]interface{}, rec.NumRows())
+	geo := geoValues(rec.Schema())
+	for row := range docs {
+		doc := make(map[string]interface{}, rec.NumCols())
+		var overflow []string
+		for col, arr := range rec.Columns() {
+			v := arrayValue(arr, row)
+			if v == nil {
+				continue
+			}
+			f := rec.Schema().Field(col)
+			if isTokenColumn(f) || isDerivedColumn(f) {
+				continue
+			}
+			if s, ok := v.(string); ok && isOverflowColumn(f) {
+				overflow = append(overflow, s)
+				continue
+			}
+			if isJSONColumn(f) {
+				v = unmarshalJSONValue(v)
+			}
+			if b, ok := v.([]byte); ok && geo[f.Name] != nil {
+				if g, ok := geo[f.Name](b); ok {
+					v = g
+				}
+			}
+			doc[originalName(f)] = v
+		}
+		// --max-fields로 컬럼이 되지 못한 필드는 다른 컬럼을 모두 채운 뒤 합칩니다.
+		for _, raw := range overflow {
+			mergeOverflow(doc, raw)
+		}
+		docs[row] = doc
+	}
+	return docs
+}
+
+// arrayValue 함수는 배열의 i번째 값을 JSON으로 직렬화할 수 있는 Go 값으로 바꿉니다.
+// 타임스탬프는 RFC 3339 문자열, 바이너리는 (encoding/json에 의해) base64 문자열이 됩니다.
+func arrayValue(arr arrow.Array, i int) interface{} {
+	if arr.IsNull(i) {
+		return nil
+	}
+	switch a := arr.(type) {
+	case *array.Boolean:
+		return a.Value(i)
+	case *array.Int8:
+		return a.Value(i)
+	case *array.Int16:
+		return a.Value(i)
+	case *array.Int32:
+		return a.Value(i)
+	case *array.Int64:
+		return a.Value(i)
+	case *array.Uint8:
+		return a.Value(i)
+	case *array.Uint16:
+		return a.Value(i)
+	case *array.Uint32:
+		return a.Value(i)
+	case *array.Uint64:
+		return a.Value(i)
+	case *array.Float16:
+		return a.Value(i).Float32()
+	case *array.Float32:
+		return a.Value(i)
+	case *array.Float64:
+		return a.Value(i)
+	case *array.Decimal128:
+		return a.Value(i).ToFloat64(a.DataType().(*arrow.Decimal128Type).Scale)
+	case *array.String:
+		return a.Value(i)
+	case *array.Binary:
+		return a.Value(i)
+	case *array.FixedSizeBinary:
+		if esschema.IsIP(a.DataType()) {
+			return esschema.IPText(a.Value(i))
+		}
+		return a.Value(i)
+	case *array.Dictionary:
+		return arrayValue(a.Dictionary(), a.GetValueIndex(i))
+	case *array.Timestamp:
+		unit := a.DataType().(*arrow.TimestampType).Unit
+		return timestampToTime(a.Value(i), unit).Format(time.RFC3339Nano)
+	case *array.Struct:
+		st := a.DataType().(*arrow.StructType)
+		obj := make(map[string]interface{}, len(st.Fields()))
+		for j, f := range st.Fields() {
+			if v := arrayValue(a.Field(j), i); v != nil {
+				if isJSONColumn(f) {
+					v = unmarshalJSONValue(v)
+				}
+				if b, ok := v.([]byte); ok {
+					v = geoShapeValue(f, b)
+				}
+				obj[originalName(f)] = v
+			}
+		}
+		return obj
+	case *array.Map:
+		// flattened 맵은 점으로 이은 경로를 키로 하는 객체로 되돌립니다. Elasticsearch는
+		// flattened 필드에서 {"a.b": v}와 {"a": {"b": v}}를 같게 색인합니다.
+		offsets := a.Offsets()
+		keys, items := a.Keys(), a.Items()
+		obj := make(map[string]interface{}, offsets[i+1]-offsets[i])
+		for j := offsets[i]; j < offsets[i+1]; j++ {
+			if k, ok := arrayValue(keys, int(j)).(string); ok {
+				obj[k] = arrayValue(items, int(j))
+			}
+		}
+		return obj
+	case *array.List:
+		offsets := a.Offsets()
+		values := a.ListValues()
+		items := make([]interface{}, 0, offsets[i+1]-offsets[i])
+		for j := offsets[i]; j < offsets[i+1]; j++ {
+			items = append(items, arrayValue(values, int(j)))
+		}
+		return items
+	case *array.FixedSizeList:
+		n := int(a.DataType().(*arrow.FixedSizeListType).Len())
+		values := a.ListValues()
+		start := (a.Data().Offset() + i) * n
+		items := make([]interface{}, 0, n)
+		for j := start; j < start+n; j++ {
+			items = append(items, arrayValue(values, j))
+		}
+		return items
+	}
+	return nil
+}
+
+// timestampToTime 함수는 Arrow 타임스탬프 값을 단위에 맞춰 UTC 시각으로 바꿉니다.
+func timestampToTime(v arrow.Timestamp, unit arrow.TimeUnit) time.Time {
+	switch unit {
+	case arrow.Second:
+		return time.Unix(int64(v), 0).UTC()
+	case arrow.Millisecond:
+		return time.UnixMilli(int64(v)).UTC()
+	case arrow.Microsecond:
+		return time.UnixMicro(int64(v)).UTC()
+	default:
+		return time.Unix(0, int64(v)).UTC()
+	}
+}
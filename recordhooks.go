@@ -0,0 +1,160 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+	"github.com/apache/arrow/go/v10/arrow/memory"
+
+	"es-schema/esschema"
+)
+
+// recordHook은 완성된 Arrow 레코드 배치를 출력(Parquet 파일, bulk 색인)에 넘기기 전에
+// 처리하는 함수입니다. 배치 단위 컬럼 추가, 임베딩 계산, 검증 같은 일을 합니다. 반환하는
+// 레코드는 호출한 쪽이 Release하므로, rec을 그대로 돌려줄 때는 Retain해야 합니다.
+// 파일 하나의 스키마는 첫 배치로 정해지므로 훅은 배치마다 같은 스키마를 만들어야 합니다.
+type recordHook func(rec arrow.Record) (arrow.Record, error)
+
+// recordHookFactory는 --record-hook name:arg의 arg로 훅 하나를 만듭니다.
+type recordHookFactory func(arg string) (recordHook, error)
+
+// recordHookFactories는 --record-hook으로 쓸 수 있는 훅입니다. 빌드에 포함된 다른 파일의
+// init에서 registerRecordHook으로 훅을 더할 수 있습니다.
+var recordHookFactories = map[string]recordHookFactory{
+	"add-column":       addColumnHook,
+	"exported-at":      exportedAtHook,
+	"require-non-null": requireNonNullHook,
+}
+
+// registerRecordHook 함수는 이름 name으로 훅을 등록합니다. 이미 있는 이름이면 패닉합니다.
+func registerRecordHook(name string, factory recordHookFactory) {
+	if _, ok := recordHookFactories[name]; ok {
+		panic("es-schema: record hook " + name + " registered twice")
+	}
+	recordHookFactories[name] = factory
+}
+
+// recordHookChain은 차례로 적용할 레코드 훅입니다.
+type recordHookChain []recordHook
+
+// apply 함수는 rec에 훅을 차례로 적용한 레코드를 반환합니다. rec의 소유권은 그대로
+// 호출한 쪽에 있고, 반환한 레코드는 따로 Release해야 합니다.
+func (c recordHookChain) apply(rec arrow.Record) (arrow.Record, error) {
+	rec.Retain()
+	for _, h := range c {
+		out, err := h(rec)
+		rec.Release()
+		if err != nil {
+			return nil, err
+		}
+		rec = out
+	}
+	return rec, nil
+}
+
+// recordHookOptions는 --record-hook 플래그입니다.
+type recordHookOptions struct {
+	specs stringListFlag
+}
+
+func (o *recordHookOptions) bind(fs *flag.FlagSet) {
+	fs.Var(&o.specs, "record-hook", "apply name:arg to each converted record batch before it is written, in order: add-column:name=value, exported-at:column or require-non-null:column (repeated)")
+}
+
+// build 함수는 --record-hook 목록으로 훅 체인을 만듭니다.
+func (o *recordHookOptions) build() (recordHookChain, error) {
+	var chain recordHookChain
+	for _, spec := range o.specs {
+		name, arg, _ := strings.Cut(spec, ":")
+		factory, ok := recordHookFactories[name]
+		if !ok {
+			names := make([]string, 0, len(recordHookFactories))
+			for n := range recordHookFactories {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			return nil, configErrorf("unknown --record-hook %q (want one of %s)", name, strings.Join(names, ", "))
+		}
+		h, err := factory(arg)
+		if err != nil {
+			return nil, configErrorf("--record-hook %s: %w", spec, err)
+		}
+		chain = append(chain, h)
+	}
+	return chain, nil
+}
+
+// addColumnHook 함수는 모든 행에 같은 문자열 값을 넣은 컬럼을 덧붙이는 훅을 만듭니다.
+// 예를 들어 add-column:source_cluster=prod-eu로 출처를 남길 수 있습니다.
+func addColumnHook(arg string) (recordHook, error) {
+	name, value, ok := strings.Cut(arg, "=")
+	if !ok || name == "" {
+		return nil, fmt.Errorf("want name=value")
+	}
+	return func(rec arrow.Record) (arrow.Record, error) {
+		b := array.NewBuilder(memory.DefaultAllocator, arrow.BinaryTypes.String).(*array.StringBuilder)
+		defer b.Release()
+		for i := int64(0); i < rec.NumRows(); i++ {
+			b.Append(value)
+		}
+		return appendColumn(rec, arrow.Field{Name: name, Type: arrow.BinaryTypes.String}, b)
+	}, nil
+}
+
+// exportedAtHook 함수는 배치를 처리한 시각(UTC, 밀리초)을 컬럼으로 덧붙이는 훅을 만듭니다.
+func exportedAtHook(arg string) (recordHook, error) {
+	if arg == "" {
+		return nil, fmt.Errorf("want a column name")
+	}
+	dt := &arrow.TimestampType{Unit: arrow.Millisecond, TimeZone: "UTC"}
+	return func(rec arrow.Record) (arrow.Record, error) {
+		b := array.NewBuilder(memory.DefaultAllocator, dt).(*array.TimestampBuilder)
+		defer b.Release()
+		now := esschema.Timestamp(time.Now(), arrow.Millisecond)
+		for i := int64(0); i < rec.NumRows(); i++ {
+			b.Append(now)
+		}
+		return appendColumn(rec, arrow.Field{Name: arg, Type: dt}, b)
+	}, nil
+}
+
+// requireNonNullHook 함수는 최상위 컬럼에 null이 있으면 실행을 멈추는 검증 훅을 만듭니다.
+func requireNonNullHook(arg string) (recordHook, error) {
+	if arg == "" {
+		return nil, fmt.Errorf("want a column name")
+	}
+	return func(rec arrow.Record) (arrow.Record, error) {
+		for i, f := range rec.Schema().Fields() {
+			if f.Name != arg {
+				continue
+			}
+			if n := rec.Column(i).NullN(); n > 0 {
+				return nil, dataErrorf("record hook require-non-null: column %s has %d null values in a batch of %d rows", arg, n, rec.NumRows())
+			}
+			rec.Retain()
+			return rec, nil
+		}
+		return nil, schemaErrorf("record hook require-non-null: no column %s", arg)
+	}, nil
+}
+
+// appendColumn 함수는 rec 뒤에 b로 만든 컬럼을 붙인 새 레코드를 반환합니다. 같은 이름의
+// 컬럼이 이미 있으면 오류입니다.
+func appendColumn(rec arrow.Record, field arrow.Field, b array.Builder) (arrow.Record, error) {
+	for _, f := range rec.Schema().Fields() {
+		if f.Name == field.Name {
+			return nil, schemaErrorf("record hook: column %s already exists", field.Name)
+		}
+	}
+	col := b.NewArray()
+	defer col.Release()
+	fields := append(append([]arrow.Field(nil), rec.Schema().Fields()...), field)
+	cols := append(append([]arrow.Array(nil), rec.Columns()...), col)
+	md := rec.Schema().Metadata()
+	return array.NewRecord(arrow.NewSchema(fields, &md), cols, rec.NumRows()), nil
+}
@@ -0,0 +1,17 @@
+package main
+
+import "testing"
+
+func TestRecordHookOptionsBuild(t *testing.T) {
+	o := recordHookOptions{specs: stringListFlag{"add-column:cluster=prod", "exported-at:exported_at", "require-non-null:_id"}}
+	chain, err := o.build()
+	if err != nil || len(chain) != 3 {
+		t.Fatalf("build = %d hooks, %v", len(chain), err)
+	}
+	for _, spec := range []string{"embed:vector", "add-column:cluster", "exported-at:", "require-non-null"} {
+		o := recordHookOptions{specs: stringListFlag{spec}}
+		if _, err := o.build(); kindOf(err) != kindConfig {
+			t.Errorf("build(%q) = %v, want a config error", spec, err)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sync"
+)
+
+const (
+	badDocumentNull = "null"
+	badDocumentSkip = "skip"
+)
+
+// badDocumentOptions는 매핑된 타입으로 바꿀 수 없는 값이 있는 문서를 어떻게 다룰지
+// 정합니다. 디코딩이나 변환 자체가 실패한 문서는 이 설정과 관계없이 빠지고 기록됩니다.
+type badDocumentOptions struct {
+	mode string
+}
+
+func (o *badDocumentOptions) bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.mode, "on-bad-document", badDocumentNull, "documents with values that do not fit their mapped type: null (write those values as null) or skip (leave the whole document out and report it)")
+}
+
+func (o *badDocumentOptions) validate() error {
+	switch o.mode {
+	case badDocumentNull, badDocumentSkip:
+		return nil
+	}
+	return configErrorf("unknown --on-bad-document %q (want null or skip)", o.mode)
+}
+
+func (o *badDocumentOptions) skip() bool {
+	return o.mode == badDocumentSkip
+}
+
+// docRejects는 정규화에서 빠진 문서를 모읍니다. 여러 scroll이 함께 쓸 수 있도록 mu로
+// 보호하고, deadLetters가 있으면 원본 문서를 사유와 함께 남깁니다.
+type docRejects struct {
+	mu          sync.Mutex
+	count       int64
+	reasons     map[string]int64
+	examples    []string
+	deadLetters *deadLetterWriter
+}
+
+// decodeHits 함수는 hits의 _source를 디코딩합니다. 디코딩할 수 없는 문서는 빼고
+// 기록하며, 반환하는 hits는 docs와 짝이 맞습니다. 페이지 안에서 되풀이되는 문자열은
+// stringPool로 한 번만 할당합니다.
+func (r *docRejects) decodeHits(hits []searchHit) ([]map[string]interface{}, []searchHit, error) {
+	docs := make([]map[string]interface{}, 0, len(hits))
+	kept := make([]searchHit, 0, len(hits))
+	pool := newStringPool()
+	for _, hit := range hits {
+		doc, err := pool.decodeDocument(hit.Source)
+		if err != nil {
+			if err := r.add(hit, "decode", "decoding _source: "+err.Error()); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		docs = append(docs, doc)
+		kept = append(kept, hit)
+	}
+	return docs, kept, nil
+}
+
+// keep 함수는 normalizer.record에서 빠진 문서를 기록하고, 레코드의 행과 짝이 맞도록
+// 나머지 hits와 docs를 반환합니다.
+func (r *docRejects) keep(hits []searchHit, docs []map[string]interface{}, rejected []rejection) ([]searchHit, []map[string]interface{}, error) {
+	if len(rejected) == 0 {
+		return hits, docs, nil
+	}
+	keptHits := make([]searchHit, 0, len(hits)-len(rejected))
+	keptDocs := make([]map[string]interface{}, 0, len(docs)-len(rejected))
+	next := 0
+	for i := range docs {
+		if next < len(rejected) && rejected[next].index == i {
+			category := "conversion:error"
+			if fields := rejected[next].fields; len(fields) > 0 {
+				category = "conversion:" + fields[0]
+				if rejected[next].overflow {
+					category = "overflow:" + fields[0]
+				}
+			}
+			if err := r.add(hits[i], category, rejected[next].reason); err != nil {
+				return nil, nil, err
+			}
+			next++
+			continue
+		}
+		keptHits = append(keptHits, hits[i])
+		keptDocs = append(keptDocs, docs[i])
+	}
+	return keptHits, keptDocs, nil
+}
+
+func (r *docRejects) add(hit searchHit, category, reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.count++
+	if r.reasons == nil {
+		r.reasons = make(map[string]int64)
+	}
+	r.reasons[category]++
+	if len(r.examples) < maxReportedFailures {
+		r.examples = append(r.examples, fmt.Sprintf("document %q in %s: %s", hit.ID, hit.Index, reason))
+	}
+	if r.deadLetters != nil {
+		dl := deadLetter{Index: hit.Index, ID: hit.ID, Category: category, Reason: reason, Document: hit.Source}
+		if err := r.deadLetters.write(dl); err != nil {
+			return configErrorf("writing dead-letter file: %w", err)
+		}
+	}
+	return nil
+}
+
+// merge 함수는 other에 모인 기록을 r에 더합니다.
+func (r *docRejects) merge(other *docRejects) {
+	r.count += other.count
+	for reason, n := range other.reasons {
+		if r.reasons == nil {
+			r.reasons = make(map[string]int64)
+		}
+		r.reasons[reason] += n
+	}
+	for _, e := range other.examples {
+		if len(r.examples) < maxReportedFailures {
+			r.examples = append(r.examples, e)
+		}
+	}
+}
+
+// finish 함수는 빠진 문서를 보고서에 반영합니다. 빠진 문서가 있으면 부분 성공 오류를
+// 반환합니다.
+func (r *docRejects) finish(report *runReport) error {
+	if r.count == 0 {
+		return nil
+	}
+	report.DocumentsFailed += r.count
+	for reason, n := range r.reasons {
+		report.addFailures(reason, n)
+	}
+	for _, e := range r.examples {
+		report.warnf("%s", e)
+	}
+	fmt.Printf("left out %d documents that could not be converted\n", r.count)
+	for _, reason := range sortedKeys(r.reasons) {
+		fmt.Printf("  %s: %d\n", reason, r.reasons[reason])
+	}
+	return withKind(kindPartial, fmt.Errorf("%d documents could not be converted and were left out", r.count))
+}
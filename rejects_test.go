@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestDocRejectsKeepDropsRejectedRows(t *testing.T) {
+	hits := []searchHit{{ID: "1"}, {ID: "2"}, {ID: "3"}}
+	docs := []map[string]interface{}{{"n": 1}, {"n": 2}, {"n": 3}}
+	var r docRejects
+	keptHits, keptDocs, err := r.keep(hits, docs, []rejection{{index: 1, fields: []string{"n"}, reason: "bad n"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keptHits) != 2 || keptHits[0].ID != "1" || keptHits[1].ID != "3" || keptDocs[1]["n"] != 3 {
+		t.Errorf("kept %v / %v, want documents 1 and 3", keptHits, keptDocs)
+	}
+	report := newRunReport("export")
+	if err := r.finish(report); kindOf(err) != kindPartial {
+		t.Errorf("finish = %v, want a partial success", err)
+	}
+	if report.DocumentsFailed != 1 || report.FailureReasons["conversion:n"] != 1 {
+		t.Errorf("report failures = %d %v", report.DocumentsFailed, report.FailureReasons)
+	}
+}
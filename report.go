@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	statusSuccess        = "success"
+	statusFailed         = "failed"
+	statusPartialSuccess = "partial_success"
+)
+
+// runReport는 한 번의 실행 결과 요약입니다. 워크플로 엔진이 로그를 파싱하지 않고
+// 결과를 읽을 수 있도록 상태 파일에 JSON으로 기록됩니다.
+type runReport struct {
+	Command          string            `json:"command"`
+	Status           string            `json:"status"`
+	StartedAt        time.Time         `json:"started_at"`
+	FinishedAt       time.Time         `json:"finished_at"`
+	DurationSeconds  float64           `json:"duration_seconds"`
+	RowsExported     int64             `json:"rows_exported"`
+	RowsRead         int64             `json:"rows_read,omitempty"`
+	DocumentsFailed  int64             `json:"documents_failed,omitempty"`
+	DocumentsDropped int64             `json:"documents_dropped,omitempty"`
+	FailureReasons   map[string]int64  `json:"failure_reasons,omitempty"`
+	NumericOverflows map[string]int64  `json:"numeric_overflows,omitempty"`
+	SanitizedStrings map[string]int64  `json:"sanitized_strings,omitempty"`
+	DeadLetterFile   string            `json:"dead_letter_file,omitempty"`
+	RenamedFields    map[string]string `json:"renamed_fields,omitempty"`
+	Estimate         *outputEstimate   `json:"estimate,omitempty"`
+	Files            []fileReport      `json:"files"`
+	Indices          []indexReport     `json:"indices,omitempty"`
+	TypeConflicts    []typeConflict    `json:"type_conflicts,omitempty"`
+	Anomalies        []runAnomaly      `json:"anomalies,omitempty"`
+	Warnings         []string          `json:"warnings"`
+	Error            *errorReport      `json:"error,omitempty"`
+
+	// progress는 daemon이 작업의 진행 상황을 받으려고 넣는 함수로, export가 인덱스별로
+	// 부릅니다.
+	progress func(index string, done, total int64)
+}
+
+// fileReport는 실행 중에 생성된 출력 파일 하나의 정보입니다.
+type fileReport struct {
+	Path  string `json:"path"`
+	Rows  int64  `json:"rows"`
+	Bytes int64  `json:"bytes"`
+}
+
+func newRunReport(command string) *runReport {
+	return &runReport{
+		Command:   command,
+		StartedAt: time.Now().UTC(),
+		Files:     []fileReport{},
+		Warnings:  []string{},
+	}
+}
+
+// addFile 함수는 완성된 출력 파일을 보고서에 추가합니다.
+func (r *runReport) addFile(path string, rows int64) {
+	r.addCopyFile(path, rows)
+	r.RowsExported += rows
+}
+
+// addCopyFile 함수는 주 결과가 아닌 사본 파일(예: migrate의 --parquet)을 추가합니다.
+// 같은 행이 이미 다른 곳으로 내보내졌으므로 rows_exported에는 더하지 않습니다.
+func (r *runReport) addCopyFile(path string, rows int64) {
+	var size int64
+	if info, err := os.Stat(path); err == nil {
+		size = info.Size()
+	}
+	r.Files = append(r.Files, fileReport{Path: path, Rows: rows, Bytes: size})
+}
+
+// addFailures 함수는 실패한 문서 수를 사유별로 누적합니다. 사유 키는
+// "pipeline:<프로세서>:<오류 종류>" 또는 "indexing:<오류 종류>" 형식입니다.
+func (r *runReport) addFailures(reason string, n int64) {
+	if r.FailureReasons == nil {
+		r.FailureReasons = make(map[string]int64)
+	}
+	r.FailureReasons[reason] += n
+}
+
+// warnf 함수는 실행을 중단시키지 않는 경고를 기록합니다.
+func (r *runReport) warnf(format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// finish 함수는 실행 결과 err를 반영해 보고서를 마무리합니다.
+func (r *runReport) finish(err error) {
+	r.FinishedAt = time.Now().UTC()
+	r.DurationSeconds = r.FinishedAt.Sub(r.StartedAt).Seconds()
+	switch {
+	case err == nil:
+		r.Status = statusSuccess
+	case kindOf(err) == kindPartial:
+		r.Status = statusPartialSuccess
+	default:
+		r.Status = statusFailed
+	}
+	if err != nil {
+		r.Error = &errorReport{Error: err.Error(), Kind: kindOf(err), ExitCode: exitCodeFor(err)}
+	}
+}
+
+// writeJSONFileAtomic 함수는 v를 같은 디렉터리의 임시 파일에 쓴 뒤 rename하여,
+// 읽는 쪽이 절반만 쓰인 파일을 보지 않도록 합니다.
+func writeJSONFileAtomic(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'))
+}
+
+// writeFileAtomic 함수는 writeJSONFileAtomic처럼 data를 임시 파일에 쓴 뒤 rename합니다.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// sortedKeys 함수는 출력 순서를 고정하기 위해 맵의 키를 정렬해 반환합니다.
+func sortedKeys(m map[string]int64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStatusFileIsWrittenAtomically(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "status.json")
+
+	report := newRunReport("demo")
+	report.warnf("field %s promoted to list", "tags")
+	report.finish(withKind(kindPartial, errors.New("2 documents failed")))
+	if err := writeJSONFileAtomic(path, report); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected only the status file in %s, found %d entries", dir, len(entries))
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got runReport
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != statusPartialSuccess {
+		t.Errorf("status = %q, want %q", got.Status, statusPartialSuccess)
+	}
+	if got.Error == nil || got.Error.ExitCode != exitPartialSuccess {
+		t.Errorf("error = %+v, want exit code %d", got.Error, exitPartialSuccess)
+	}
+	if len(got.Warnings) != 1 {
+		t.Errorf("warnings = %v, want one entry", got.Warnings)
+	}
+}
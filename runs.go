@@ -0,0 +1,420 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	runsFormatText = "text"
+	runsFormatJSON = "json"
+)
+
+// runStoreOptions는 끝난 실행마다 전체 보고서를 남기는 실행 이력 저장소의 설정입니다.
+// 파일에는 한 줄에 한 실행씩 덧붙이고, 인덱스에는 실행 ID를 문서 ID로 _create합니다.
+// runs 명령이 같은 플래그로 이력을 읽습니다.
+type runStoreOptions struct {
+	path  string
+	index string
+	es    esOptions
+}
+
+func (o *runStoreOptions) bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.path, "runs-store", os.Getenv("ES_SCHEMA_RUNS"), "append the full report of every run to this JSON lines file, read back by es-schema runs list and show (default $ES_SCHEMA_RUNS)")
+	fs.StringVar(&o.index, "runs-index", "", "also index the report of every run into this Elasticsearch index on --runs-url; es-schema runs reads it instead of --runs-store when given")
+	o.es.bind(fs, "runs-", "run history")
+}
+
+func (o *runStoreOptions) validate() error {
+	if o.index != "" && o.es.url == "" {
+		return configErrorf("--runs-index requires --runs-url")
+	}
+	return nil
+}
+
+// storedRun은 실행 이력의 한 건입니다. Pipeline은 --anomaly-key와 같은 이름(daemon에서는
+// 파이프라인 이름)입니다.
+type storedRun struct {
+	ID       string `json:"id"`
+	Pipeline string `json:"pipeline,omitempty"`
+	runReport
+}
+
+// runIndexMapping은 --runs-index를 처음 만들 때 쓰는 매핑입니다. 실행마다 키가 다른
+// failure_reasons 같은 객체가 필드를 늘리지 않도록 걸러 찾는 필드만 매핑하고 나머지는
+// _source에만 둡니다.
+var runIndexMapping = map[string]interface{}{
+	"mappings": map[string]interface{}{
+		"dynamic": false,
+		"properties": map[string]interface{}{
+			"id":               map[string]string{"type": "keyword"},
+			"pipeline":         map[string]string{"type": "keyword"},
+			"command":          map[string]string{"type": "keyword"},
+			"status":           map[string]string{"type": "keyword"},
+			"started_at":       map[string]string{"type": "date"},
+			"finished_at":      map[string]string{"type": "date"},
+			"duration_seconds": map[string]string{"type": "double"},
+			"rows_exported":    map[string]string{"type": "long"},
+		},
+	},
+}
+
+// record 함수는 끝난 실행의 보고서를 이력에 남깁니다. 이력을 읽는 runs 명령 자신은 남기지
+// 않습니다.
+func (o *runStoreOptions) record(report *runReport, pipeline string) error {
+	if (o.path == "" && o.index == "") || report.Command == "runs" {
+		return nil
+	}
+	run := storedRun{ID: newRunID(report.StartedAt), Pipeline: pipeline, runReport: *report}
+	if o.path != "" {
+		if err := appendJSONLine(o.path, run); err != nil {
+			return err
+		}
+	}
+	if o.index == "" {
+		return nil
+	}
+	client, err := o.es.client()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), auditTimeout)
+	defer cancel()
+	exists, err := client.indexExists(ctx, o.index)
+	if err != nil {
+		return err
+	}
+	// 다른 실행이 먼저 만들었으면 그 인덱스를 씁니다.
+	if !exists {
+		if err := client.createIndex(ctx, o.index, runIndexMapping); err != nil && !isESStatus(err, http.StatusBadRequest) {
+			return err
+		}
+	}
+	return client.sendJSON(ctx, http.MethodPut, "/"+url.PathEscape(o.index)+"/_create/"+url.PathEscape(run.ID), nil, run, nil)
+}
+
+// newRunID 함수는 시작 시각과 임의의 값으로 정렬되는 실행 ID를 만듭니다.
+func newRunID(started time.Time) string {
+	b := make([]byte, 3)
+	rand.Read(b)
+	return started.UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(b)
+}
+
+// runFilter는 runs list가 고르는 실행의 조건입니다. 빈 값은 모두를 고릅니다.
+type runFilter struct {
+	pipeline string
+	command  string
+	status   string
+	since    time.Duration
+}
+
+func (f *runFilter) match(r *storedRun, now time.Time) bool {
+	switch {
+	case f.pipeline != "" && r.Pipeline != f.pipeline,
+		f.command != "" && r.Command != f.command,
+		f.status != "" && r.Status != f.status,
+		f.since > 0 && r.StartedAt.Before(now.Add(-f.since)):
+		return false
+	}
+	return true
+}
+
+// esQuery 함수는 f를 --runs-index 검색의 bool 쿼리로 바꿉니다.
+func (f *runFilter) esQuery(now time.Time) map[string]interface{} {
+	filters := []interface{}{}
+	for field, v := range map[string]string{"pipeline": f.pipeline, "command": f.command, "status": f.status} {
+		if v != "" {
+			filters = append(filters, map[string]interface{}{"term": map[string]string{field: v}})
+		}
+	}
+	if f.since > 0 {
+		filters = append(filters, map[string]interface{}{"range": map[string]interface{}{"started_at": map[string]string{"gte": now.Add(-f.since).Format(time.RFC3339)}}})
+	}
+	return map[string]interface{}{"bool": map[string]interface{}{"filter": filters}}
+}
+
+func setupRuns(fs *flag.FlagSet) func(ctx context.Context, report *runReport, args []string) error {
+	var filter runFilter
+	fs.StringVar(&filter.pipeline, "pipeline", "", "runs list: only runs of this pipeline (the daemon pipeline name, or the --anomaly-key of the run)")
+	fs.StringVar(&filter.command, "command", "", "runs list: only runs of this command")
+	fs.StringVar(&filter.status, "status", "", "runs list: only runs with this status: success, partial_success or failed")
+	fs.Var(ageFlag{&filter.since}, "since", "runs list: only runs started within this period, e.g. 7d or 12h")
+	limit := fs.Int("limit", 20, "runs list: number of most recent runs to print")
+	format := fs.String("format", runsFormatText, "output format: text or json")
+
+	return func(ctx context.Context, report *runReport, args []string) error {
+		// 하위 명령 뒤의 플래그(runs list --status failed)는 flag 패키지가 읽지 않고 남기므로
+		// 다시 읽습니다.
+		if len(args) == 0 {
+			return configErrorf("runs: missing subcommand (want list or show)")
+		}
+		sub := args[0]
+		if err := fs.Parse(args[1:]); err != nil {
+			return configErrorf("runs: %w", err)
+		}
+		args = fs.Args()
+		switch *format {
+		case runsFormatText, runsFormatJSON:
+		default:
+			return configErrorf("runs: unknown --format %q (want text or json)", *format)
+		}
+		store, err := runStoreFromFlags(fs)
+		if err != nil {
+			return err
+		}
+		switch sub {
+		case "list":
+			if len(args) > 0 {
+				return configErrorf("runs list: unexpected arguments %v", args)
+			}
+			if *limit <= 0 {
+				return configErrorf("runs list: --limit must be positive")
+			}
+			runs, err := store.list(ctx, &filter, *limit)
+			if err != nil {
+				return err
+			}
+			report.RowsRead = int64(len(runs))
+			if *format == runsFormatJSON {
+				return writeIndentedJSON(os.Stdout, runs)
+			}
+			printRuns(os.Stdout, runs)
+			return nil
+		case "show":
+			if len(args) != 1 {
+				return configErrorf("runs show: want one run ID, got %d arguments", len(args))
+			}
+			run, err := store.show(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			if *format == runsFormatJSON {
+				return writeIndentedJSON(os.Stdout, run)
+			}
+			printRun(os.Stdout, run)
+			return nil
+		}
+		return configErrorf("runs: unknown subcommand %q (want list or show)", sub)
+	}
+}
+
+// runStoreFromFlags 함수는 fs의 전역 저장소 플래그(--runs-store, --runs-index, --runs-url, ...)
+// 값으로 읽을 저장소를 만듭니다. 같은 이름을 runs 명령에 다시 등록할 수 없으므로 따로 만든
+// 플래그 집합에 값을 옮깁니다.
+func runStoreFromFlags(fs *flag.FlagSet) (*runStoreOptions, error) {
+	var o runStoreOptions
+	own := flag.NewFlagSet("runs", flag.ContinueOnError)
+	o.bind(own)
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if own.Lookup(f.Name) != nil && err == nil {
+			err = own.Set(f.Name, f.Value.String())
+		}
+	})
+	if err != nil {
+		return nil, configErrorf("runs: %w", err)
+	}
+	if o.path == "" && o.index == "" {
+		return nil, configErrorf("runs: --runs-store or --runs-index is required")
+	}
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// list 함수는 filter에 맞는 최근 실행을 최대 limit개까지 새로운 순서로 반환합니다.
+func (o *runStoreOptions) list(ctx context.Context, filter *runFilter, limit int) ([]storedRun, error) {
+	now := time.Now()
+	if o.index != "" {
+		return o.search(ctx, filter.esQuery(now), limit)
+	}
+	all, err := readRuns(o.path)
+	if err != nil {
+		return nil, err
+	}
+	var runs []storedRun
+	for i := len(all) - 1; i >= 0; i-- {
+		if filter.match(&all[i], now) {
+			runs = append(runs, all[i])
+		}
+	}
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].StartedAt.After(runs[j].StartedAt) })
+	if len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs, nil
+}
+
+// show 함수는 ID가 id이거나 id로 시작하는 실행 하나를 반환합니다. 여러 실행이 맞으면
+// 오류입니다.
+func (o *runStoreOptions) show(ctx context.Context, id string) (*storedRun, error) {
+	var matches []storedRun
+	if o.index != "" {
+		var err error
+		if matches, err = o.search(ctx, map[string]interface{}{"prefix": map[string]string{"id": id}}, 2); err != nil {
+			return nil, err
+		}
+	} else {
+		all, err := readRuns(o.path)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range all {
+			if r.ID == id {
+				return &r, nil
+			}
+			if strings.HasPrefix(r.ID, id) {
+				matches = append(matches, r)
+			}
+		}
+	}
+	for _, r := range matches {
+		if r.ID == id {
+			return &r, nil
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, configErrorf("runs show: no run %q", id)
+	case 1:
+		return &matches[0], nil
+	}
+	return nil, configErrorf("runs show: %q matches more than one run; give more of the ID", id)
+}
+
+// search 함수는 --runs-index에서 query에 맞는 실행을 새로운 순서로 size개까지 찾습니다.
+func (o *runStoreOptions) search(ctx context.Context, query map[string]interface{}, size int) ([]storedRun, error) {
+	client, err := o.es.client()
+	if err != nil {
+		return nil, err
+	}
+	body := map[string]interface{}{
+		"size":  size,
+		"query": query,
+		"sort":  []interface{}{map[string]string{"started_at": "desc"}},
+	}
+	var resp struct {
+		Hits struct {
+			Hits []struct {
+				Source storedRun `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := client.sendJSON(ctx, http.MethodPost, "/"+url.PathEscape(o.index)+"/_search", nil, body, &resp); err != nil {
+		return nil, err
+	}
+	runs := make([]storedRun, len(resp.Hits.Hits))
+	for i, h := range resp.Hits.Hits {
+		runs[i] = h.Source
+	}
+	return runs, nil
+}
+
+// readRuns 함수는 --runs-store 파일의 실행을 기록된 순서로 읽습니다. 파일이 없으면 이력이
+// 없는 것입니다.
+func readRuns(path string) ([]storedRun, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, configErrorf("reading --runs-store: %w", err)
+	}
+	defer f.Close()
+	var runs []storedRun
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		if len(strings.TrimSpace(sc.Text())) == 0 {
+			continue
+		}
+		var r storedRun
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			return nil, configErrorf("%s:%d: %w", path, line, err)
+		}
+		runs = append(runs, r)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, configErrorf("reading --runs-store: %w", err)
+	}
+	return runs, nil
+}
+
+func writeIndentedJSON(w io.Writer, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// printRuns 함수는 실행마다 한 줄로 ID, 시작 시각, 명령, 파이프라인, 상태, 걸린 시간, 행 수와
+// 출력 크기를 씁니다.
+func printRuns(w io.Writer, runs []storedRun) {
+	fmt.Fprintf(w, "%-22s %-20s %-10s %-24s %-15s %9s %12s %10s\n", "ID", "STARTED", "COMMAND", "PIPELINE", "STATUS", "DURATION", "ROWS", "OUTPUT")
+	for _, r := range runs {
+		fmt.Fprintf(w, "%-22s %-20s %-10s %-24s %-15s %9s %12d %10s\n", r.ID, r.StartedAt.Format("2006-01-02 15:04:05"), r.Command, r.Pipeline, r.Status, runDuration(r.DurationSeconds), r.RowsExported, formatBytes(r.outputBytes()))
+	}
+}
+
+// printRun 함수는 실행 하나를 사람이 읽는 형식으로 씁니다. 전체 보고서는 --format json으로
+// 봅니다.
+func printRun(w io.Writer, r *storedRun) {
+	fmt.Fprintf(w, "run %s\n", r.ID)
+	fmt.Fprintf(w, "  command:   %s\n", r.Command)
+	if r.Pipeline != "" {
+		fmt.Fprintf(w, "  pipeline:  %s\n", r.Pipeline)
+	}
+	fmt.Fprintf(w, "  status:    %s\n", r.Status)
+	fmt.Fprintf(w, "  started:   %s\n", r.StartedAt.Format(time.RFC3339))
+	fmt.Fprintf(w, "  duration:  %s\n", runDuration(r.DurationSeconds))
+	fmt.Fprintf(w, "  rows:      %d exported, %d read, %d failed\n", r.RowsExported, r.RowsRead, r.DocumentsFailed)
+	if r.Error != nil {
+		fmt.Fprintf(w, "  error:     %s (%s, exit code %d)\n", r.Error.Error, r.Error.Kind, r.Error.ExitCode)
+	}
+	if len(r.Files) > 0 {
+		fmt.Fprintf(w, "\n  files:\n")
+		for _, f := range r.Files {
+			fmt.Fprintf(w, "    %s: %d rows, %s\n", f.Path, f.Rows, formatBytes(f.Bytes))
+		}
+	}
+	if len(r.Anomalies) > 0 {
+		fmt.Fprintf(w, "\n  anomalies:\n")
+		for _, a := range r.Anomalies {
+			fmt.Fprintf(w, "    %s: %s (baseline %s)\n", a.Metric, formatMetric(a.Value), formatMetric(a.Baseline))
+		}
+	}
+	if len(r.Warnings) > 0 {
+		fmt.Fprintf(w, "\n  warnings:\n")
+		for _, warning := range r.Warnings {
+			fmt.Fprintf(w, "    %s\n", warning)
+		}
+	}
+}
+
+func (r *runReport) outputBytes() int64 {
+	var n int64
+	for _, f := range r.Files {
+		n += f.Bytes
+	}
+	return n
+}
+
+func runDuration(seconds float64) string {
+	return time.Duration(seconds * float64(time.Second)).Round(time.Second).String()
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunStore(t *testing.T) {
+	store := runStoreOptions{path: filepath.Join(t.TempDir(), "runs.jsonl")}
+	ctx := context.Background()
+	record := func(command, pipeline string, started time.Time, err error) {
+		report := newRunReport(command)
+		report.StartedAt = started
+		report.RowsExported = 42
+		report.finish(err)
+		if err := store.record(report, pipeline); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now().UTC()
+	record("export", "logs", now.Add(-48*time.Hour), nil)
+	record("convert", "orders", now.Add(-2*time.Hour), dataErrorf("bad documents"))
+	record("export", "logs", now.Add(-time.Hour), nil)
+	record("runs", "", now, nil)
+
+	runs, err := store.list(ctx, &runFilter{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 3 || runs[0].Pipeline != "logs" || runs[1].Command != "convert" || !runs[2].StartedAt.Equal(now.Add(-48*time.Hour)) {
+		t.Fatalf("list = %+v", runs)
+	}
+	if runs[1].Status != statusFailed || runs[1].Error == nil || runs[0].RowsExported != 42 {
+		t.Errorf("stored reports lost fields: %+v", runs[:2])
+	}
+
+	for _, tc := range []struct {
+		filter runFilter
+		limit  int
+		want   int
+	}{
+		{runFilter{pipeline: "logs"}, 10, 2},
+		{runFilter{status: statusFailed}, 10, 1},
+		{runFilter{command: "export", since: 24 * time.Hour}, 10, 1},
+		{runFilter{}, 2, 2},
+	} {
+		got, err := store.list(ctx, &tc.filter, tc.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != tc.want {
+			t.Errorf("list %+v = %d runs, want %d", tc.filter, len(got), tc.want)
+		}
+	}
+
+	run, err := store.show(ctx, runs[1].ID[:len(runs[1].ID)-2])
+	if err != nil || run.ID != runs[1].ID {
+		t.Errorf("show by prefix = %v, %v", run, err)
+	}
+	if _, err := store.show(ctx, "2"); err == nil || !strings.Contains(err.Error(), "more than one") {
+		t.Errorf("ambiguous prefix: %v", err)
+	}
+	if _, err := store.show(ctx, "nope"); err == nil {
+		t.Error("unknown ID: no error")
+	}
+
+	var buf bytes.Buffer
+	printRuns(&buf, runs)
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 4 || !strings.Contains(lines[2], "orders") {
+		t.Errorf("printRuns:\n%s", buf.String())
+	}
+}
+
+func TestRunStoreFromFlags(t *testing.T) {
+	var g globalOptions
+	fs := flag.NewFlagSet("runs", flag.ContinueOnError)
+	g.bind(fs)
+	if err := fs.Parse([]string{"--runs-index", "es-schema-runs", "--runs-url", "http://localhost:9200"}); err != nil {
+		t.Fatal(err)
+	}
+	store, err := runStoreFromFlags(fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.index != "es-schema-runs" || store.es.url != "http://localhost:9200" {
+		t.Errorf("store = %+v", store)
+	}
+
+	t.Setenv("ES_SCHEMA_RUNS", "")
+	fs = flag.NewFlagSet("runs", flag.ContinueOnError)
+	g = globalOptions{}
+	g.bind(fs)
+	if _, err := runStoreFromFlags(fs); err == nil {
+		t.Error("no store: no error")
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"es-schema/esschema"
+)
+
+// sanitizeOptions는 --sanitize-strings 플래그입니다. 로그에서 온 문자열의 잘못된 UTF-8,
+// 제어 문자, 분해된 유니코드를 쓰기 전에 고치고, 고친 값을 필드별로 세어 보고서의
+// sanitized_strings에 남깁니다.
+type sanitizeOptions struct {
+	methods  stringListFlag
+	sanitize esschema.Sanitize
+}
+
+func (o *sanitizeOptions) bind(fs *flag.FlagSet) {
+	fs.Var(&o.methods, "sanitize-strings", "fix string values before writing them, as a comma-separated list or repeated: utf8 (drop bytes that are not UTF-8), control (drop control characters other than tab, newline and carriage return), nfc (normalize to Unicode NFC) or all; some Parquet readers reject files with such values (default: write strings as they are)")
+}
+
+func (o *sanitizeOptions) validate() error {
+	s, err := esschema.ParseSanitize(o.methods)
+	if err != nil {
+		return configErrorf("--sanitize-strings: %v", err)
+	}
+	o.sanitize = s
+	return nil
+}
+
+// apply 함수는 n이 문자열 값을 고쳐 쓰게 합니다. validate 뒤에 부릅니다.
+func (o *sanitizeOptions) apply(n *normalizer) {
+	n.sanitize = o.sanitize
+}
+
+// report 함수는 counts를 보고서에 더하고, 고친 값이 있으면 경고 하나를 남깁니다. 여러
+// 인덱스의 수는 모두 더한 뒤에 한 번 부릅니다.
+func (o *sanitizeOptions) report(report *runReport, counts map[string]int64) {
+	for path, n := range counts {
+		if report.SanitizedStrings == nil {
+			report.SanitizedStrings = make(map[string]int64)
+		}
+		report.SanitizedStrings[path] += n
+	}
+	if len(report.SanitizedStrings) == 0 {
+		return
+	}
+	var total int64
+	paths := sortedKeys(report.SanitizedStrings)
+	sort.SliceStable(paths, func(i, j int) bool {
+		return report.SanitizedStrings[paths[i]] > report.SanitizedStrings[paths[j]]
+	})
+	parts := make([]string, 0, len(paths))
+	for _, path := range paths {
+		total += report.SanitizedStrings[path]
+		parts = append(parts, fmt.Sprintf("%s (%d)", path, report.SanitizedStrings[path]))
+	}
+	report.warnf("%d string values were changed by --sanitize-strings %s: %s", total, o.methods.String(), strings.Join(parts, ", "))
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"es-schema/esschema"
+)
+
+func TestSanitizeReport(t *testing.T) {
+	o := sanitizeOptions{methods: stringListFlag{"utf8", "control"}}
+	if err := o.validate(); err != nil || o.sanitize != esschema.SanitizeUTF8|esschema.SanitizeControl {
+		t.Fatalf("validate = %d, %v", o.sanitize, err)
+	}
+	if err := (&sanitizeOptions{methods: stringListFlag{"ascii"}}).validate(); exitCodeFor(err) != exitConfigError {
+		t.Errorf("validate(ascii) = %v, want a config error", err)
+	}
+
+	report := newRunReport("convert")
+	o.report(report, map[string]int64{})
+	if len(report.Warnings) != 0 || report.SanitizedStrings != nil {
+		t.Errorf("report without changes = %v, %v", report.Warnings, report.SanitizedStrings)
+	}
+	o.report(report, map[string]int64{"message": 4, "user.name": 1})
+	if want := map[string]int64{"message": 4, "user.name": 1}; !reflect.DeepEqual(report.SanitizedStrings, want) {
+		t.Errorf("sanitized_strings = %v, want %v", report.SanitizedStrings, want)
+	}
+	if len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0], "5 string values were changed by --sanitize-strings utf8,control: message (4), user.name (1)") {
+		t.Errorf("warnings = %v", report.Warnings)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/apache/arrow/go/v10/arrow"
+
+	"es-schema/esschema"
+)
+
+// scaledFloatOptions는 scaled_float 필드의 컬럼 형식을 고르는 --scaled-float 플래그입니다.
+// decimal은 scaling_factor의 자릿수를 소수 자릿수로 하는 decimal128로, float64는 double처럼
+// 씁니다.
+type scaledFloatOptions struct {
+	as string
+}
+
+func (o *scaledFloatOptions) bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.as, "scaled-float", "decimal", "column type of scaled_float fields: decimal (decimal128 with as many fraction digits as the scaling_factor needs, rounded like Elasticsearch stores them) or float64 (for readers without decimal support)")
+}
+
+func (o *scaledFloatOptions) validate() error {
+	switch o.as {
+	case "", "decimal", "float64":
+		return nil
+	}
+	return configErrorf("unknown --scaled-float %q (want decimal or float64)", o.as)
+}
+
+// apply 함수는 float64이면 fields의 scaled_float 필드를 float64 컬럼으로 바꿉니다.
+func (o *scaledFloatOptions) apply(fields []arrow.Field) []arrow.Field {
+	if o.as != "float64" {
+		return fields
+	}
+	return esschema.ScaledFloatsAsFloat64(fields)
+}
@@ -0,0 +1,14 @@
+package main
+
+import "testing"
+
+func TestScaledFloatValidate(t *testing.T) {
+	for _, as := range []string{"decimal", "float64"} {
+		if err := (&scaledFloatOptions{as: as}).validate(); err != nil {
+			t.Errorf("--scaled-float %s: %v", as, err)
+		}
+	}
+	if err := (&scaledFloatOptions{as: "double"}).validate(); exitCodeFor(err) != exitConfigError {
+		t.Errorf("--scaled-float double = %v, want a config error", err)
+	}
+}
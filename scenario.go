@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scenario는 벤치마크용 데이터셋의 모양을 적은 YAML 파일입니다. 같은 파일과 시드는 언제나
+// 같은 매핑과 문서를 만듭니다.
+//
+//	name: vectors
+//	docs: 100000
+//	seed: 7
+//	fields:
+//	  - type: keyword
+//	    count: 5
+//	    cardinality: 1000
+//	    skew: 1.2
+//	  - type: dense_vector
+//	    dims: 384
+type scenario struct {
+	Name   string          `json:"name"`
+	Docs   int             `json:"docs"`
+	Seed   int64           `json:"seed"`
+	Batch  int             `json:"batch"`
+	Fields []scenarioField `json:"fields"`
+}
+
+// scenarioField는 같은 모양의 필드 Count개(없으면 1개)입니다. 필드 이름은 타입과 번호로
+// 정합니다(keyword_0, keyword_1, ...). Cardinality가 있으면 값은 그만큼의 서로 다른 값
+// 중에서 고르고, Skew가 있으면 그 지수의 Zipf 분포로 앞쪽 값을 더 자주 고릅니다.
+type scenarioField struct {
+	Type        string  `json:"type"`
+	Count       int     `json:"count"`
+	Dims        int     `json:"dims"`
+	Cardinality int     `json:"cardinality"`
+	Skew        float64 `json:"skew"`
+	NullRate    float64 `json:"null_rate"`
+}
+
+// scenarioTypes는 시나리오에 쓸 수 있는 필드 타입입니다. Parquet 컬럼이 원래 타입을
+// 유지하는 타입만 받아 두 쪽의 쿼리가 같은 값을 보게 합니다.
+var scenarioTypes = map[string]bool{
+	"keyword": true, "text": true, "integer": true, "long": true, "float": true,
+	"double": true, "boolean": true, "date": true, "dense_vector": true,
+}
+
+// defaultScenarioBatch는 batch가 없을 때 한 번에 Parquet 레코드로 바꾸는 문서 수입니다.
+const defaultScenarioBatch = 10000
+
+// readScenario 함수는 시나리오 파일을 읽고 검사합니다.
+func readScenario(path string) (*scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, configErrorf("reading scenario: %w", err)
+	}
+	var s scenario
+	if err := unmarshalYAML(data, &s); err != nil {
+		return nil, configErrorf("decoding scenario %s: %w", path, err)
+	}
+	if err := s.validate(); err != nil {
+		return nil, configErrorf("scenario %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+func (s *scenario) validate() error {
+	if s.Docs < 0 {
+		return fmt.Errorf("docs must not be negative")
+	}
+	if s.Batch < 0 {
+		return fmt.Errorf("batch must not be negative")
+	}
+	if len(s.Fields) == 0 {
+		return fmt.Errorf("no fields")
+	}
+	for i, f := range s.Fields {
+		switch {
+		case !scenarioTypes[f.Type]:
+			return fmt.Errorf("fields[%d]: unsupported type %q", i, f.Type)
+		case f.Count < 0:
+			return fmt.Errorf("fields[%d]: count must not be negative", i)
+		case f.Type == "dense_vector" && f.Dims < 1:
+			return fmt.Errorf("fields[%d]: dense_vector needs dims", i)
+		case f.Type != "dense_vector" && f.Dims != 0:
+			return fmt.Errorf("fields[%d]: dims only applies to dense_vector", i)
+		case f.Cardinality < 0:
+			return fmt.Errorf("fields[%d]: cardinality must not be negative", i)
+		case f.Skew != 0 && f.Skew <= 1:
+			return fmt.Errorf("fields[%d]: skew must be 0 (uniform) or greater than 1", i)
+		case f.Skew != 0 && f.Cardinality == 0:
+			return fmt.Errorf("fields[%d]: skew needs a cardinality", i)
+		case f.Cardinality > 0 && (f.Type == "boolean" || f.Type == "dense_vector"):
+			return fmt.Errorf("fields[%d]: cardinality does not apply to %s", i, f.Type)
+		case f.NullRate < 0 || f.NullRate > 1:
+			return fmt.Errorf("fields[%d]: null_rate must be between 0 and 1", i)
+		}
+	}
+	return nil
+}
+
+// scenarioColumn은 시나리오가 만든 필드 하나와 그 값을 고르는 분포입니다.
+type scenarioColumn struct {
+	name  string
+	field scenarioField
+	props map[string]interface{}
+	zipf  *rand.Zipf
+}
+
+// scenarioGenerator는 시나리오의 매핑과 문서를 만듭니다.
+type scenarioGenerator struct {
+	gen     *generator
+	columns []scenarioColumn
+}
+
+func newScenarioGenerator(s *scenario) *scenarioGenerator {
+	sg := &scenarioGenerator{gen: newGenerator(s.Seed, 1, 1)}
+	seen := make(map[string]int)
+	for _, f := range s.Fields {
+		count := f.Count
+		if count == 0 {
+			count = 1
+		}
+		for i := 0; i < count; i++ {
+			c := scenarioColumn{
+				name:  f.Type + "_" + strconv.Itoa(seen[f.Type]),
+				field: f,
+				props: map[string]interface{}{"type": f.Type},
+			}
+			seen[f.Type]++
+			if f.Type == "dense_vector" {
+				c.props["dims"] = float64(f.Dims)
+			}
+			if f.Skew > 0 {
+				c.zipf = rand.NewZipf(sg.gen.rng, f.Skew, 1, uint64(f.Cardinality-1))
+			}
+			sg.columns = append(sg.columns, c)
+		}
+	}
+	return sg
+}
+
+// mapping 함수는 시나리오의 매핑 JSON을 만듭니다.
+func (sg *scenarioGenerator) mapping() ([]byte, error) {
+	props := make(map[string]interface{}, len(sg.columns))
+	for _, c := range sg.columns {
+		props[c.name] = c.props
+	}
+	return json.MarshalIndent(map[string]interface{}{"properties": props}, "", "  ")
+}
+
+// document 함수는 문서 하나를 만듭니다. 배열 값은 만들지 않으므로 Parquet 스키마는 매핑
+// 그대로이고 배치마다 바뀌지 않습니다.
+func (sg *scenarioGenerator) document() map[string]interface{} {
+	doc := make(map[string]interface{}, len(sg.columns))
+	for _, c := range sg.columns {
+		if c.field.NullRate > 0 && sg.gen.rng.Float64() < c.field.NullRate {
+			continue
+		}
+		if c.field.Cardinality == 0 {
+			doc[c.name] = sg.gen.value(c.field.Type, c.props)
+			continue
+		}
+		doc[c.name] = c.value(sg.gen.rng)
+	}
+	return doc
+}
+
+// rank 함수는 0부터 Cardinality-1 사이의 값 번호를 고릅니다. 번호가 작을수록 Zipf
+// 분포에서 자주 나옵니다.
+func (c *scenarioColumn) rank(rng *rand.Rand) int {
+	if c.zipf != nil {
+		return int(c.zipf.Uint64())
+	}
+	return rng.Intn(c.field.Cardinality)
+}
+
+// value 함수는 값 번호를 타입에 맞는 값으로 바꿉니다. text는 번호를 단어로 삼아 3~8개
+// 단어의 문장을 만들어, cardinality가 어휘 크기가 되게 합니다.
+func (c *scenarioColumn) value(rng *rand.Rand) interface{} {
+	switch c.field.Type {
+	case "keyword":
+		return c.name + "_" + strconv.Itoa(c.rank(rng))
+	case "text":
+		words := make([]string, 3+rng.Intn(6))
+		for i := range words {
+			words[i] = "w" + strconv.Itoa(c.rank(rng))
+		}
+		return strings.Join(words, " ")
+	case "integer", "long":
+		return int64(c.rank(rng))
+	case "float", "double":
+		return float64(c.rank(rng)) + 0.5
+	case "date":
+		return generateEpoch.Add(time.Duration(c.rank(rng)) * time.Hour).Format(time.RFC3339)
+	}
+	return nil
+}
+
+// scenarioOptions는 scenario 명령의 설정입니다.
+type scenarioOptions struct {
+	config  string
+	outDir  string
+	parquet parquetOptions
+}
+
+func setupScenario(fs *flag.FlagSet) func(ctx context.Context, report *runReport, args []string) error {
+	var o scenarioOptions
+	fs.StringVar(&o.config, "config", "", "scenario YAML file describing the dataset (required)")
+	fs.StringVar(&o.outDir, "out-dir", ".", "directory for mapping.json, data.ndjson (a bulk file) and data.parquet")
+	o.parquet.bind(fs)
+
+	return func(ctx context.Context, report *runReport, args []string) error {
+		if len(args) > 0 {
+			return configErrorf("scenario: unexpected arguments %v", args)
+		}
+		if o.config == "" {
+			return configErrorf("scenario: --config is required")
+		}
+		if err := o.parquet.validate(); err != nil {
+			return err
+		}
+		s, err := readScenario(o.config)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(o.outDir, 0o755); err != nil {
+			return configErrorf("creating %s: %w", o.outDir, err)
+		}
+		return runScenario(ctx, report, s, o.outDir, &o.parquet)
+	}
+}
+
+// runScenario 함수는 시나리오의 매핑, bulk 파일, Parquet 파일을 dir에 씁니다. bulk 파일의
+// 문서와 Parquet 행은 같은 _id를 가지므로 두 쪽의 쿼리 결과를 서로 비교할 수 있습니다.
+func runScenario(ctx context.Context, report *runReport, s *scenario, dir string, opts *parquetOptions) error {
+	sg := newScenarioGenerator(s)
+	mapping, err := sg.mapping()
+	if err != nil {
+		return err
+	}
+	mappingPath := filepath.Join(dir, "mapping.json")
+	if err := os.WriteFile(mappingPath, append(mapping, '\n'), 0o644); err != nil {
+		return configErrorf("writing %s: %w", mappingPath, err)
+	}
+	schema, err := schemaFromMapping(mapping)
+	if err != nil {
+		return schemaErrorf("parsing scenario mapping: %w", err)
+	}
+
+	bulkPath := filepath.Join(dir, "data.ndjson")
+	f, err := os.Create(bulkPath)
+	if err != nil {
+		return configErrorf("creating %s: %w", bulkPath, err)
+	}
+	defer f.Close()
+	bw := bufio.NewWriter(f)
+
+	batch := s.Batch
+	if batch == 0 {
+		batch = defaultScenarioBatch
+	}
+	parquetPath := filepath.Join(dir, "data.parquet")
+	sink, err := newParquetSink(parquetPath, schema, mapping, sinkColumns{}, &nameOptions{}, opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if sink != nil {
+			sink.abort()
+		}
+	}()
+	norm := newNormalizer(schema)
+
+	for start := 0; start < s.Docs; start += batch {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n := batch
+		if s.Docs-start < n {
+			n = s.Docs - start
+		}
+		docs := make([]map[string]interface{}, n)
+		hits := make([]searchHit, n)
+		for i := range docs {
+			id := strconv.Itoa(start + i)
+			data, err := json.Marshal(sg.document())
+			if err != nil {
+				return dataErrorf("encoding document %s: %w", id, err)
+			}
+			fmt.Fprintf(bw, "{\"index\":{\"_id\":%q}}\n%s\n", id, data)
+			// Parquet에는 bulk 파일을 색인했을 때의 _source와 같은 값을 씁니다.
+			if docs[i], err = decodeDocument(data); err != nil {
+				return dataErrorf("decoding document %s: %w", id, err)
+			}
+			hits[i] = searchHit{ID: id, Source: data}
+		}
+		norm.widen(docs)
+		rec, rejected := norm.record(docs)
+		if len(rejected) > 0 {
+			rec.Release()
+			return dataErrorf("generated document %s could not be converted: %s", hits[rejected[0].index].ID, rejected[0].reason)
+		}
+		err := sink.writeHits(rec, hits)
+		rec.Release()
+		if err != nil {
+			return err
+		}
+		report.RowsRead += int64(n)
+	}
+	if err := bw.Flush(); err != nil {
+		return dataErrorf("writing %s: %w", bulkPath, err)
+	}
+	if err := f.Close(); err != nil {
+		return dataErrorf("writing %s: %w", bulkPath, err)
+	}
+	w := sink
+	sink = nil
+	if err := w.close(); err != nil {
+		return err
+	}
+	report.addFile(parquetPath, w.rows)
+	report.addCopyFile(bulkPath, int64(s.Docs))
+	if norm.dropped > 0 {
+		report.warnf("%d generated values could not be converted to their mapped type and were written as null", norm.dropped)
+	}
+	fmt.Fprintf(os.Stderr, "wrote scenario %q to %s: %d documents, %d fields (seed %d)\n", s.Name, dir, s.Docs, len(sg.columns), s.Seed)
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testScenario = `name: skewed
+docs: 25
+seed: 3
+batch: 10
+fields:
+  - type: keyword
+    count: 2
+    cardinality: 50
+    skew: 1.5
+  - type: integer
+    null_rate: 0.5
+  - type: dense_vector
+    dims: 4
+`
+
+func writeScenario(t *testing.T, src string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadScenario(t *testing.T) {
+	s, err := readScenario(writeScenario(t, testScenario))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Docs != 25 || s.Batch != 10 || len(s.Fields) != 3 || s.Fields[0].Skew != 1.5 || s.Fields[2].Dims != 4 {
+		t.Errorf("scenario = %+v", s)
+	}
+
+	for _, bad := range []string{
+		"docs: 1\nfields:\n  - type: geo_shape\n",
+		"docs: 1\nfields:\n  - type: dense_vector\n",
+		"docs: 1\nfields:\n  - type: keyword\n    skew: 0.5\n    cardinality: 10\n",
+		"docs: 1\nfields:\n  - type: keyword\n    skew: 2\n",
+		"docs: 1\nfields:\n  - type: keyword\n    null_rate: 2\n",
+		"docs: 1\n",
+	} {
+		if _, err := readScenario(writeScenario(t, bad)); err == nil {
+			t.Errorf("readScenario accepted %q", bad)
+		}
+	}
+}
+
+func TestScenarioSkew(t *testing.T) {
+	s := &scenario{Seed: 1, Fields: []scenarioField{
+		{Type: "keyword", Cardinality: 100, Skew: 2},
+		{Type: "keyword", Cardinality: 100},
+	}}
+	sg := newScenarioGenerator(s)
+	counts := []map[interface{}]int{{}, {}}
+	for i := 0; i < 2000; i++ {
+		doc := sg.document()
+		counts[0][doc["keyword_0"]]++
+		counts[1][doc["keyword_1"]]++
+	}
+	if n := counts[0]["keyword_0_0"]; n < 1000 {
+		t.Errorf("skewed field has its top value %d times in 2000, want most of them", n)
+	}
+	if n := counts[1]["keyword_1_0"]; n > 100 {
+		t.Errorf("uniform field has one value %d times in 2000", n)
+	}
+	if len(counts[1]) > 100 {
+		t.Errorf("uniform field has %d values, more than cardinality 100", len(counts[1]))
+	}
+}
+
+func TestScenarioMapping(t *testing.T) {
+	s, err := readScenario(writeScenario(t, testScenario))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := newScenarioGenerator(s).mapping()
+	b, _ := newScenarioGenerator(s).mapping()
+	if !reflect.DeepEqual(a, b) {
+		t.Error("the same scenario gave different mappings")
+	}
+	for _, name := range []string{`"keyword_0"`, `"keyword_1"`, `"integer_0"`, `"dense_vector_0"`, `"dims": 4`} {
+		if !strings.Contains(string(a), name) {
+			t.Errorf("mapping %s has no %s", a, name)
+		}
+	}
+}
+
+func TestRunScenario(t *testing.T) {
+	s, err := readScenario(writeScenario(t, testScenario))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	report := newRunReport("scenario")
+	opts := &parquetOptions{rowGroups: rowGroupsPerRecord, rowGroupRows: 1 << 20, storeSchema: true, dictionary: true, compression: "snappy"}
+	if err := runScenario(context.Background(), report, s, dir, opts); err != nil {
+		t.Fatal(err)
+	}
+	if report.RowsExported != 25 {
+		t.Errorf("rows exported = %d, want 25", report.RowsExported)
+	}
+	bulk, err := os.ReadFile(filepath.Join(dir, "data.ndjson"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(bulk), "\n"); lines != 50 {
+		t.Errorf("bulk file has %d lines, want 50", lines)
+	}
+	if !strings.HasPrefix(string(bulk), `{"index":{"_id":"0"}}`) {
+		t.Errorf("bulk file starts with %.40q", bulk)
+	}
+}
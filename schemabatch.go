@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// schemaIndexFile은 schema가 --out-dir에 쓰는 묶음 색인 파일의 이름입니다.
+const schemaIndexFile = "index.json"
+
+// schemaBatchOptions는 schema가 여러 매핑 파일을 한 번에 바꾸는 설정입니다. 인자로 받은
+// 매핑 파일(디렉터리는 그 안의 *.json)마다 --out-dir에 <이름>.schema.txt나
+// <이름>.schema.json을 쓰고, 모든 결과를 index.json에 모읍니다. 매핑을 git으로 관리하는
+// 팀이 스키마 산출물도 함께 커밋할 수 있도록 출력은 실행마다 같습니다.
+type schemaBatchOptions struct {
+	outDir  string
+	workers int
+}
+
+func (o *schemaBatchOptions) bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.outDir, "out-dir", "", "convert the mapping files given as arguments (directories stand for the *.json files in them) and write one schema file per mapping plus "+schemaIndexFile+" to this directory")
+	fs.IntVar(&o.workers, "workers", 4, "number of mapping files converted at once with --out-dir")
+}
+
+// schemaIndex는 --out-dir의 index.json입니다.
+type schemaIndex struct {
+	Format  string             `json:"format"`
+	Schemas []schemaIndexEntry `json:"schemas"`
+}
+
+// schemaIndexEntry는 매핑 파일 하나의 결과입니다. 실패한 매핑은 Error만 있고 스키마 파일이
+// 없습니다.
+type schemaIndexEntry struct {
+	Name          string `json:"name"`
+	Mapping       string `json:"mapping"`
+	MappingSHA256 string `json:"mapping_sha256"`
+	File          string `json:"file,omitempty"`
+	Columns       int    `json:"columns,omitempty"`
+	Error         string `json:"error,omitempty"`
+
+	renames []fieldRename
+}
+
+// run 함수는 args의 매핑 파일을 o.workers개씩 동시에 바꿉니다. 어떤 매핑이 실패해도 나머지는
+// 끝까지 바꾸고 index.json에 실패를 남긴 뒤 오류를 반환합니다.
+func (o *schemaBatchOptions) run(ctx context.Context, report *runReport, in *mappingInputOptions, names *nameOptions, format string, args []string) error {
+	switch {
+	case o.outDir == "":
+		return configErrorf("schema: mapping file arguments need --out-dir")
+	case len(args) == 0:
+		return configErrorf("schema: --out-dir needs mapping files as arguments")
+	case in.mapping != "" || in.index != "":
+		return configErrorf("schema: --mapping and --index cannot be combined with mapping file arguments")
+	case o.workers <= 0:
+		return configErrorf("schema: --workers must be positive")
+	}
+	for _, v := range []interface{ validate() error }{&in.ipFormat, &in.scaled, &in.tsUnit, &in.flattened, &in.geoShape, &in.vectorDims} {
+		if err := v.validate(); err != nil {
+			return err
+		}
+	}
+	paths, err := expandMappingArgs(args)
+	if err != nil {
+		return err
+	}
+	kept := paths[:0]
+	for _, path := range paths {
+		if !isSchemaArtifact(path, o.outDir) {
+			kept = append(kept, path)
+		}
+	}
+	if paths = kept; len(paths) == 0 {
+		return configErrorf("schema: no mapping files besides the output of --out-dir %s", o.outDir)
+	}
+	entries := make([]schemaIndexEntry, len(paths))
+	seen := make(map[string]string, len(paths))
+	for i, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		if other, ok := seen[name]; ok {
+			return configErrorf("schema: %s and %s would both write %s; rename one of them", other, path, schemaFileName(name, format))
+		}
+		seen[name] = path
+		entries[i] = schemaIndexEntry{Name: name, Mapping: filepath.ToSlash(path)}
+	}
+	if err := os.MkdirAll(o.outDir, 0o755); err != nil {
+		return configErrorf("schema: creating --out-dir: %w", err)
+	}
+
+	pool := newWorkerPool(o.workers)
+	var wg sync.WaitGroup
+	for i := range entries {
+		if err := pool.acquire(ctx); err != nil {
+			break
+		}
+		wg.Add(1)
+		go func(e *schemaIndexEntry) {
+			defer wg.Done()
+			defer pool.release()
+			o.convert(e, in, names, format)
+		}(&entries[i])
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, e := range entries {
+		if e.Error != "" {
+			failed = append(failed, e.Name)
+			report.warnf("schema: %s: %s", e.Mapping, e.Error)
+			fmt.Printf("%s: %s\n", e.Mapping, e.Error)
+			continue
+		}
+		for i := range e.renames {
+			e.renames[i].From = e.Name + ":" + e.renames[i].From
+		}
+		recordRenames(report, e.renames)
+		file := filepath.Join(o.outDir, e.File)
+		report.addCopyFile(file, 0)
+		fmt.Printf("%s -> %s (%d columns)\n", e.Mapping, file, e.Columns)
+	}
+	indexPath := filepath.Join(o.outDir, schemaIndexFile)
+	if err := writeJSONFileAtomic(indexPath, schemaIndex{Format: format, Schemas: entries}); err != nil {
+		return fmt.Errorf("schema: writing %s: %w", indexPath, err)
+	}
+	report.addCopyFile(indexPath, 0)
+	if len(failed) > 0 {
+		return schemaErrorf("schema: %d of %d mappings failed: %s", len(failed), len(entries), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// convert 함수는 매핑 파일 하나를 바꿔 스키마 파일을 쓰고 결과를 e에 채웁니다.
+func (o *schemaBatchOptions) convert(e *schemaIndexEntry, in *mappingInputOptions, names *nameOptions, format string) {
+	data, err := os.ReadFile(e.Mapping)
+	if err != nil {
+		e.Error = err.Error()
+		return
+	}
+	sum := sha256.Sum256(data)
+	e.MappingSHA256 = hex.EncodeToString(sum[:])
+	schema, err := in.schemaOf(data, e.Mapping)
+	if err == nil && names.enabled() {
+		schema, e.renames, err = names.apply(schema)
+	}
+	var out []byte
+	if err == nil {
+		out, err = renderSchema(schema, format)
+	}
+	if err == nil {
+		err = writeFileAtomic(filepath.Join(o.outDir, schemaFileName(e.Name, format)), out)
+	}
+	if err != nil {
+		e.Error = err.Error()
+		return
+	}
+	e.File = schemaFileName(e.Name, format)
+	e.Columns = len(schema.Fields())
+}
+
+// isSchemaArtifact 함수는 path가 outDir에 schema가 쓴 산출물(index.json이나
+// <이름>.schema.json)인지 알려 줍니다. 결과 디렉터리가 매핑 디렉터리와 같아도 이전 실행의
+// 산출물을 매핑으로 읽지 않게 합니다.
+func isSchemaArtifact(path, outDir string) bool {
+	abs, _ := filepath.Abs(path)
+	dir, _ := filepath.Abs(outDir)
+	base := filepath.Base(path)
+	return filepath.Dir(abs) == dir && (base == schemaIndexFile || strings.HasSuffix(base, ".schema.json"))
+}
+
+// schemaFileName 함수는 매핑 name의 스키마 파일 이름을 반환합니다.
+func schemaFileName(name, format string) string {
+	if format == "json" {
+		return name + ".schema.json"
+	}
+	return name + ".schema.txt"
+}
+
+// expandMappingArgs 함수는 schema의 인자를 매핑 파일 경로로 펼칩니다. 디렉터리는 그 안의
+// *.json 파일이 되고, 셸이 펼치지 않은 글롭 패턴(따옴표로 감싼 './mappings/*.json')도
+// 펼칩니다. 결과는 정렬하고 중복을 뺍니다.
+func expandMappingArgs(args []string) ([]string, error) {
+	var paths []string
+	for _, arg := range args {
+		matches := []string{arg}
+		if strings.ContainsAny(arg, "*?[") {
+			var err error
+			if matches, err = filepath.Glob(arg); err != nil {
+				return nil, configErrorf("schema: bad pattern %s: %w", arg, err)
+			}
+			if len(matches) == 0 {
+				return nil, configErrorf("schema: no mapping files match %s", arg)
+			}
+		}
+		for _, m := range matches {
+			info, err := os.Stat(m)
+			if err != nil {
+				return nil, configErrorf("schema: %w", err)
+			}
+			if !info.IsDir() {
+				paths = append(paths, m)
+				continue
+			}
+			inDir, err := filepath.Glob(filepath.Join(m, "*.json"))
+			if err != nil {
+				return nil, configErrorf("schema: %w", err)
+			}
+			if len(inDir) == 0 {
+				return nil, configErrorf("schema: directory %s has no *.json mapping files", m)
+			}
+			paths = append(paths, inDir...)
+		}
+	}
+	sort.Strings(paths)
+	var out []string
+	for _, p := range paths {
+		if len(out) == 0 || p != out[len(out)-1] {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
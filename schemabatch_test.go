@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandMappingArgs(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.json", "b.json", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a, b := filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")
+	for _, tc := range []struct {
+		args []string
+		want []string
+	}{
+		{[]string{dir}, []string{a, b}},
+		{[]string{filepath.Join(dir, "*.json")}, []string{a, b}},
+		{[]string{b, a, dir}, []string{a, b}},
+	} {
+		got, err := expandMappingArgs(tc.args)
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("expandMappingArgs(%v) = %v, %v; want %v", tc.args, got, err, tc.want)
+		}
+	}
+	for _, args := range [][]string{
+		{filepath.Join(dir, "*.yaml")},
+		{filepath.Join(dir, "missing.json")},
+		{t.TempDir()},
+	} {
+		if _, err := expandMappingArgs(args); exitCodeFor(err) != exitConfigError {
+			t.Errorf("expandMappingArgs(%v) = %v, want a config error", args, err)
+		}
+	}
+}
+
+func TestSchemaBatch(t *testing.T) {
+	dir := t.TempDir()
+	mappings := map[string]string{
+		"logs.json":   `{"properties": {"message": {"type": "text"}, "at": {"type": "date"}}}`,
+		"orders.json": `{"mappings": {"properties": {"total": {"type": "scaled_float", "scaling_factor": 100}}}}`,
+		"broken.json": `{"properties": `,
+	}
+	for name, body := range mappings {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := filepath.Join(dir, "schemas")
+	o := schemaBatchOptions{outDir: out, workers: 2}
+	report := newRunReport("schema")
+	err := o.run(context.Background(), report, &mappingInputOptions{}, &nameOptions{}, "text", []string{dir})
+	if exitCodeFor(err) != exitSchemaError || !strings.Contains(err.Error(), "1 of 3") {
+		t.Errorf("run = %v, want a schema error for broken.json", err)
+	}
+	logs, err := os.ReadFile(filepath.Join(out, "logs.schema.txt"))
+	if err != nil || string(logs) != "at: timestamp[ns, tz=UTC]\nmessage: utf8\n" {
+		t.Errorf("logs.schema.txt = %q, %v", logs, err)
+	}
+	data, err := os.ReadFile(filepath.Join(out, schemaIndexFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index schemaIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Schemas) != 3 || index.Schemas[0].Name != "broken" || index.Schemas[0].Error == "" || index.Schemas[2].File != "orders.schema.txt" || index.Schemas[2].Columns != 1 || len(index.Schemas[1].MappingSHA256) != 64 {
+		t.Errorf("index = %+v", index)
+	}
+	if len(report.Files) != 3 {
+		t.Errorf("report files = %+v", report.Files)
+	}
+
+	// 결과 디렉터리가 매핑 디렉터리와 같아도 이전 산출물을 매핑으로 읽지 않습니다.
+	os.Remove(filepath.Join(dir, "broken.json"))
+	o.outDir = dir
+	var first []byte
+	for run := 0; run < 2; run++ {
+		if err := o.run(context.Background(), newRunReport("schema"), &mappingInputOptions{}, &nameOptions{}, "json", []string{dir}); err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, schemaIndexFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if run == 1 && string(data) != string(first) {
+			t.Errorf("index changed between runs:\n%s\n%s", first, data)
+		}
+		first = data
+	}
+}
+
+func TestSchemaBatchFlagsAfterMappings(t *testing.T) {
+	dir := t.TempDir()
+	var mappings []string
+	for _, name := range []string{"logs.json", "orders.json"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(`{"properties": {"id": {"type": "keyword"}}}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		mappings = append(mappings, path)
+	}
+	out := filepath.Join(dir, "schemas")
+
+	// 셸이 ./mappings/*.json을 펼친 모양 그대로 플래그가 파일 뒤에 옵니다.
+	fs := flag.NewFlagSet("schema", flag.ContinueOnError)
+	run := setupSchema(fs)
+	if err := fs.Parse(append(mappings, "--out-dir", out)); err != nil {
+		t.Fatal(err)
+	}
+	if err := run(context.Background(), newRunReport("schema"), fs.Args()); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"logs.schema.txt", "orders.schema.txt", schemaIndexFile} {
+		if _, err := os.Stat(filepath.Join(out, name)); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+const (
+	schemaLockAuto    = "auto"
+	schemaLockWrite   = "write"
+	schemaLockConform = "conform"
+)
+
+// schemaLockVersion은 잠금 파일 형식의 버전입니다.
+const schemaLockVersion = 1
+
+// lockedNumbers는 잠금의 types가 가질 수 있는 타입입니다. --on-numeric-overflow widen이
+// 넓힌 숫자 타입은 이 셋 중 하나입니다.
+var lockedNumbers = map[string]arrow.DataType{
+	"int64":   arrow.PrimitiveTypes.Int64,
+	"float32": arrow.PrimitiveTypes.Float32,
+	"float64": arrow.PrimitiveTypes.Float64,
+}
+
+// schemaLock은 --schema-lock 파일의 내용입니다. 매핑만으로는 정해지지 않고 문서를 보고 내린
+// 결정(Lists는 배열 값 때문에 리스트로 넓힌 필드의 경로, Types는 범위를 넘는 값 때문에 넓힌
+// 숫자 필드의 타입, Renames는 --sanitize-names 같은 플래그가 바꾼 이름)과 그 결과인 최상위
+// 컬럼의 타입을 담습니다. 경로와 컬럼 이름은 이름을 바꾸기 전의 것입니다.
+type schemaLock struct {
+	Version int               `json:"version"`
+	Lists   []string          `json:"lists,omitempty"`
+	Types   map[string]string `json:"types,omitempty"`
+	Renames map[string]string `json:"renames,omitempty"`
+	Columns []lockedColumn    `json:"columns"`
+}
+
+// lockedColumn은 잠금에 남긴 최상위 컬럼 하나입니다. Type은 Arrow 타입의 문자열입니다.
+type lockedColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// schemaLockOptions는 export와 convert의 --schema-lock 설정입니다. write는 이번 실행의
+// 결정을 잠금 파일에 쓰고, conform은 잠금의 결정을 처음부터 적용한 뒤 스키마가 잠금과 다르면
+// 파일을 쓰기 전에 실패하므로, 매일 내보내는 파일의 스키마가 문서에 따라 모르게 바뀌지
+// 않습니다.
+type schemaLockOptions struct {
+	path string
+	mode string
+	// lock은 conform일 때 읽은 잠금이고, write일 때 이번 실행에서 모은 결정입니다.
+	lock *schemaLock
+}
+
+func (o *schemaLockOptions) bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.path, "schema-lock", "", "schema lock file recording the decisions made from the documents (fields promoted to lists, numeric types widened by --on-numeric-overflow widen, renamed fields) and the resulting columns; see --schema-lock-mode")
+	fs.StringVar(&o.mode, "schema-lock-mode", schemaLockAuto, "auto (conform when the --schema-lock file exists, otherwise write it), write (record this run's decisions, replacing the file) or conform (apply the locked decisions from the start and fail before writing a file whose schema differs from the lock)")
+}
+
+func (o *schemaLockOptions) validate() error {
+	switch o.mode {
+	case schemaLockAuto, schemaLockWrite, schemaLockConform:
+	default:
+		return configErrorf("unknown --schema-lock-mode %q (want auto, write or conform)", o.mode)
+	}
+	if o.path == "" {
+		if o.mode != schemaLockAuto {
+			return configErrorf("--schema-lock-mode %s needs --schema-lock", o.mode)
+		}
+		return nil
+	}
+	if o.mode == schemaLockAuto {
+		o.mode = schemaLockWrite
+		if _, err := os.Stat(o.path); err == nil {
+			o.mode = schemaLockConform
+		}
+	}
+	if o.mode == schemaLockWrite {
+		o.lock = &schemaLock{Version: schemaLockVersion}
+		return nil
+	}
+	lock, err := readSchemaLock(o.path)
+	if err != nil {
+		return configErrorf("--schema-lock: %w", err)
+	}
+	o.lock = lock
+	return nil
+}
+
+// readSchemaLock 함수는 path의 잠금 파일을 읽습니다.
+func readSchemaLock(path string) (*schemaLock, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var lock schemaLock
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if lock.Version != schemaLockVersion {
+		return nil, fmt.Errorf("%s has version %d, want %d", path, lock.Version, schemaLockVersion)
+	}
+	for path, t := range lock.Types {
+		if _, ok := lockedNumbers[t]; !ok {
+			return nil, fmt.Errorf("field %s is locked as %q, which is not a widened numeric type", path, t)
+		}
+	}
+	return &lock, nil
+}
+
+// conforming 함수는 잠금에 맞춰야 하는 실행인지 알려 줍니다.
+func (o *schemaLockOptions) conforming() bool {
+	return o.mode == schemaLockConform && o.lock != nil
+}
+
+// apply 함수는 conform이면 잠금의 결정을 fields에 적용합니다. 여러 인덱스를 내보낼 때
+// 잠금의 필드가 모든 인덱스에 있지는 않으므로 없는 경로는 건너뜁니다.
+func (o *schemaLockOptions) apply(fields []arrow.Field) []arrow.Field {
+	if !o.conforming() {
+		return fields
+	}
+	return o.lock.apply(fields)
+}
+
+// apply 함수는 넓힌 숫자 타입을 먼저 바꾼 뒤 리스트로 넓힌 필드를 리스트로 바꿉니다. 매핑이
+// 바뀌어 더는 숫자가 아닌 필드는 그대로 두어 check가 차이를 알리게 합니다.
+func (l *schemaLock) apply(fields []arrow.Field) []arrow.Field {
+	paths := make([]string, 0, len(l.Types))
+	for path := range l.Types {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		t := lockedNumbers[l.Types[path]]
+		updated, ok := updateField(fields, strings.Split(path, "."), func(f arrow.Field) (arrow.Field, bool) {
+			if lt, isList := f.Type.(*arrow.ListType); isList {
+				if !isNumber(lt.Elem()) {
+					return f, false
+				}
+				f.Type = arrow.ListOf(t)
+			} else if isNumber(f.Type) {
+				f.Type = t
+			} else {
+				return f, false
+			}
+			f.Nullable = true
+			return f, true
+		})
+		if ok {
+			fields = updated
+		}
+	}
+	for _, path := range l.Lists {
+		if forced, ok := forceList(fields, strings.Split(path, ".")); ok {
+			fields = forced
+		}
+	}
+	return fields
+}
+
+// isNumber 함수는 t가 --on-numeric-overflow widen이 넓힐 수 있는 숫자 타입인지 알려 줍니다.
+func isNumber(t arrow.DataType) bool {
+	switch t.ID() {
+	case arrow.INT8, arrow.INT16, arrow.INT32, arrow.INT64, arrow.UINT64,
+		arrow.FLOAT16, arrow.FLOAT32, arrow.FLOAT64, arrow.DECIMAL128:
+		return true
+	}
+	return false
+}
+
+// check 함수는 conform이면 파일을 열기 전의 스키마 schema가 잠금과 같은지 확인합니다. 이름은
+// names로 바꿔 보고 잠금의 이름과 비교합니다.
+func (o *schemaLockOptions) check(label string, schema *arrow.Schema, names *nameOptions) error {
+	if !o.conforming() {
+		return nil
+	}
+	var renames []fieldRename
+	if names.enabled() {
+		var err error
+		if _, renames, err = names.apply(schema); err != nil {
+			return err
+		}
+	}
+	if drift := o.lock.drift(schema.Fields(), renames); len(drift) > 0 {
+		return schemaErrorf("the schema of %s differs from --schema-lock %s: %s; rerun with --schema-lock-mode write to accept the new schema", label, o.path, strings.Join(drift, "; "))
+	}
+	return nil
+}
+
+// drift 함수는 fields와 renames가 잠금과 다른 점을 반환합니다. 한 잠금이 매핑이 서로 다른
+// 여러 인덱스를 함께 다룰 수 있으므로 잠금에만 있는 컬럼은 차이로 보지 않습니다.
+func (l *schemaLock) drift(fields []arrow.Field, renames []fieldRename) []string {
+	locked := make(map[string]string, len(l.Columns))
+	for _, c := range l.Columns {
+		locked[c.Name] = c.Type
+	}
+	var drift []string
+	for _, f := range fields {
+		want, ok := locked[f.Name]
+		switch {
+		case !ok:
+			drift = append(drift, fmt.Sprintf("new column %s %s", f.Name, f.Type))
+		case want != fmt.Sprint(f.Type):
+			drift = append(drift, fmt.Sprintf("column %s is %s, locked as %s", f.Name, f.Type, want))
+		}
+	}
+	for _, r := range renames {
+		if want, ok := l.Renames[r.From]; !ok {
+			drift = append(drift, fmt.Sprintf("%s is renamed to %s, which the lock does not rename", r.From, r.To))
+		} else if want != r.To {
+			drift = append(drift, fmt.Sprintf("%s is renamed to %s, locked as %s", r.From, r.To, want))
+		}
+	}
+	return drift
+}
+
+// record 함수는 write이면 매핑으로 만든 base 필드와 문서를 보고 넓힌 schema를 비교한 결정과
+// 컬럼, 이름 바꾸기를 잠금에 더합니다. 여러 인덱스의 결정은 합치고, 같은 이름의 컬럼은
+// 처음 것을 남깁니다.
+func (o *schemaLockOptions) record(base []arrow.Field, schema *arrow.Schema, renames []fieldRename) {
+	if o.mode != schemaLockWrite || o.lock == nil {
+		return
+	}
+	l := o.lock
+	l.decide(base, schema.Fields(), "")
+	for _, f := range schema.Fields() {
+		if !l.hasColumn(f.Name) {
+			l.Columns = append(l.Columns, lockedColumn{Name: f.Name, Type: fmt.Sprint(f.Type)})
+		}
+	}
+	for _, r := range renames {
+		if l.Renames == nil {
+			l.Renames = make(map[string]string)
+		}
+		l.Renames[r.From] = r.To
+	}
+}
+
+func (l *schemaLock) hasColumn(name string) bool {
+	for _, c := range l.Columns {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *schemaLock) hasList(path string) bool {
+	for _, p := range l.Lists {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// decide 함수는 final 필드 가운데 base와 달리 리스트가 된 필드와 숫자 타입이 넓어진 필드를
+// 점으로 구분된 경로로 잠금에 더합니다. 구조체와 구조체 리스트 안으로 들어갑니다.
+func (l *schemaLock) decide(base, final []arrow.Field, prefix string) {
+	for _, f := range final {
+		b, ok := fieldByName(base, f.Name)
+		if !ok {
+			continue
+		}
+		path := prefix + f.Name
+		ft, bt := f.Type, b.Type
+		if lt, isList := ft.(*arrow.ListType); isList {
+			if blt, wasList := bt.(*arrow.ListType); wasList {
+				bt = blt.Elem()
+			} else if !l.hasList(path) {
+				l.Lists = append(l.Lists, path)
+			}
+			ft = lt.Elem()
+		}
+		fst, ok1 := ft.(*arrow.StructType)
+		bst, ok2 := bt.(*arrow.StructType)
+		if ok1 && ok2 {
+			l.decide(bst.Fields(), fst.Fields(), path+".")
+			continue
+		}
+		if _, widened := lockedNumbers[fmt.Sprint(ft)]; widened && !arrow.TypeEqual(ft, bt) {
+			if l.Types == nil {
+				l.Types = make(map[string]string)
+			}
+			l.Types[path] = fmt.Sprint(ft)
+		}
+	}
+}
+
+// fieldByName 함수는 fields에서 이름이 name인 필드를 찾습니다.
+func fieldByName(fields []arrow.Field, name string) (arrow.Field, bool) {
+	for _, f := range fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return arrow.Field{}, false
+}
+
+// save 함수는 write이면 모은 잠금을 파일에 쓰고 보고서에 남깁니다.
+func (o *schemaLockOptions) save(report *runReport) error {
+	if o.mode != schemaLockWrite || o.lock == nil {
+		return nil
+	}
+	sort.Strings(o.lock.Lists)
+	if err := writeJSONFileAtomic(o.path, o.lock); err != nil {
+		return fmt.Errorf("writing --schema-lock: %w", err)
+	}
+	report.addCopyFile(o.path, 0)
+	fmt.Printf("wrote schema lock %s\n", o.path)
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+func TestSchemaLockValidate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logs.lock.json")
+
+	if err := (&schemaLockOptions{mode: schemaLockAuto}).validate(); err != nil {
+		t.Errorf("no --schema-lock: %v", err)
+	}
+	for _, o := range []schemaLockOptions{
+		{mode: "strict", path: path},
+		{mode: schemaLockConform},
+		{mode: schemaLockConform, path: path},
+	} {
+		if err := o.validate(); exitCodeFor(err) != exitConfigError {
+			t.Errorf("validate(%+v) = %v, want a config error", o, err)
+		}
+	}
+
+	o := schemaLockOptions{mode: schemaLockAuto, path: path}
+	if err := o.validate(); err != nil || o.mode != schemaLockWrite || o.conforming() {
+		t.Fatalf("auto without a lock file = %q, %v, want write", o.mode, err)
+	}
+	o.lock.Lists = []string{"tags"}
+	o.lock.Columns = []lockedColumn{{Name: "tags", Type: "list<item: utf8, nullable>"}}
+	if err := o.save(newRunReport("convert")); err != nil {
+		t.Fatal(err)
+	}
+
+	o = schemaLockOptions{mode: schemaLockAuto, path: path}
+	if err := o.validate(); err != nil || !o.conforming() {
+		t.Fatalf("auto with a lock file = %q, %v, want conform", o.mode, err)
+	}
+	if !reflect.DeepEqual(o.lock.Lists, []string{"tags"}) || len(o.lock.Columns) != 1 {
+		t.Errorf("read lock = %+v", o.lock)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"version": 1, "types": {"size": "utf8"}, "columns": []}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	o = schemaLockOptions{mode: schemaLockConform, path: path}
+	if err := o.validate(); exitCodeFor(err) != exitConfigError || !strings.Contains(err.Error(), "size") {
+		t.Errorf("lock with a non-numeric type = %v, want a config error", err)
+	}
+}
+
+func TestSchemaLockDecide(t *testing.T) {
+	user := arrow.StructOf(arrow.Field{Name: "age", Type: arrow.PrimitiveTypes.Int32, Nullable: true})
+	base := []arrow.Field{
+		{Name: "tags", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "size", Type: arrow.PrimitiveTypes.Int32, Nullable: true},
+		{Name: "user", Type: user, Nullable: true},
+	}
+	final := arrow.NewSchema([]arrow.Field{
+		{Name: "tags", Type: arrow.ListOf(arrow.BinaryTypes.String), Nullable: true},
+		{Name: "size", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+		{Name: "user", Type: arrow.ListOf(arrow.StructOf(arrow.Field{Name: "age", Type: arrow.PrimitiveTypes.Int64, Nullable: true})), Nullable: true},
+	}, nil)
+
+	o := schemaLockOptions{mode: schemaLockWrite, lock: &schemaLock{Version: schemaLockVersion}}
+	o.record(base, final, []fieldRename{{From: "Size", To: "size"}})
+	if want := []string{"tags", "user"}; !reflect.DeepEqual(o.lock.Lists, want) {
+		t.Errorf("lists = %v, want %v", o.lock.Lists, want)
+	}
+	if want := map[string]string{"size": "int64", "user.age": "int64"}; !reflect.DeepEqual(o.lock.Types, want) {
+		t.Errorf("types = %v, want %v", o.lock.Types, want)
+	}
+	if len(o.lock.Columns) != 3 || o.lock.Renames["Size"] != "size" {
+		t.Errorf("lock = %+v", o.lock)
+	}
+
+	// 잠금의 결정을 처음부터 적용하면 같은 스키마가 되어 차이가 없습니다.
+	applied := o.lock.apply(base)
+	if got := arrow.NewSchema(applied, nil); !got.Equal(final) {
+		t.Errorf("applied = %s, want %s", got, final)
+	}
+	if drift := o.lock.drift(applied, []fieldRename{{From: "Size", To: "size"}}); len(drift) != 0 {
+		t.Errorf("drift after apply = %v", drift)
+	}
+}
+
+func TestSchemaLockDrift(t *testing.T) {
+	lock := &schemaLock{
+		Version: schemaLockVersion,
+		Renames: map[string]string{"Host": "host"},
+		Columns: []lockedColumn{{Name: "size", Type: "int64"}, {Name: "Host", Type: "utf8"}, {Name: "gone", Type: "utf8"}},
+	}
+	fields := []arrow.Field{
+		{Name: "size", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "Host", Type: arrow.BinaryTypes.String},
+		{Name: "extra", Type: arrow.BinaryTypes.String},
+	}
+	drift := lock.drift(fields, []fieldRename{{From: "Host", To: "host_2"}})
+	want := []string{
+		"column size is float64, locked as int64",
+		"new column extra utf8",
+		"Host is renamed to host_2, locked as host",
+	}
+	if !reflect.DeepEqual(drift, want) {
+		t.Errorf("drift = %q, want %q", drift, want)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// secretRefPattern은 설정 값 안의 비밀 참조입니다. ${env:VAR}는 환경 변수, ${file:/path}는
+// 파일 내용, ${vault:path#key}는 HashiCorp Vault의 KV 비밀에 있는 key의 값으로 바뀝니다.
+var secretRefPattern = regexp.MustCompile(`\$\{(env|file|vault):([^}]*)\}`)
+
+// secretResolver는 설정 값의 비밀 참조를 풉니다. Vault는 VAULT_ADDR과 VAULT_TOKEN(없으면
+// ~/.vault-token), VAULT_NAMESPACE 환경 변수로 접속하며, 같은 경로는 한 번만 읽습니다.
+type secretResolver struct {
+	http  *http.Client
+	vault map[string]map[string]interface{}
+}
+
+// hasSecretRef 함수는 s에 비밀 참조가 있는지 알려 줍니다.
+func hasSecretRef(s string) bool {
+	return secretRefPattern.MatchString(s)
+}
+
+// expand 함수는 s의 비밀 참조를 모두 값으로 바꿉니다.
+func (r *secretResolver) expand(ctx context.Context, s string) (string, error) {
+	var firstErr error
+	out := secretRefPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		m := secretRefPattern.FindStringSubmatch(ref)
+		v, err := r.lookup(ctx, m[1], m[2])
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		return v
+	})
+	if firstErr != nil {
+		return "", firstErr
+	}
+	return out, nil
+}
+
+func (r *secretResolver) lookup(ctx context.Context, kind, ref string) (string, error) {
+	switch kind {
+	case "env":
+		v, ok := os.LookupEnv(ref)
+		if !ok {
+			return "", configErrorf("${env:%s}: environment variable is not set", ref)
+		}
+		return v, nil
+	case "file":
+		data, err := os.ReadFile(ref)
+		if err != nil {
+			return "", configErrorf("${file:%s}: %w", ref, err)
+		}
+		// 비밀 파일은 보통 끝에 줄바꿈이 있습니다.
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	path, key, ok := strings.Cut(ref, "#")
+	if !ok || path == "" || key == "" {
+		return "", configErrorf("${vault:%s}: want ${vault:<path>#<key>}, such as ${vault:secret/data/es#password}", ref)
+	}
+	data, err := r.vaultSecret(ctx, strings.Trim(path, "/"))
+	if err != nil {
+		return "", err
+	}
+	switch v := data[key].(type) {
+	case string:
+		return v, nil
+	case nil:
+		return "", configErrorf("${vault:%s}: the secret has no key %q", ref, key)
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// vaultSecret 함수는 Vault의 path를 읽어 비밀의 키와 값을 반환합니다. KV 버전 2 엔진은
+// 값을 data.data에, 버전 1은 data에 담습니다.
+func (r *secretResolver) vaultSecret(ctx context.Context, path string) (map[string]interface{}, error) {
+	if data, ok := r.vault[path]; ok {
+		return data, nil
+	}
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		return nil, configErrorf("${vault:%s#...}: VAULT_ADDR is not set", path)
+	}
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			if data, err := os.ReadFile(filepath.Join(home, ".vault-token")); err == nil {
+				token = strings.TrimSpace(string(data))
+			}
+		}
+	}
+	if token == "" {
+		return nil, configErrorf("${vault:%s#...}: set VAULT_TOKEN or log in with vault login", path)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(addr, "/")+"/v1/"+path, nil)
+	if err != nil {
+		return nil, configErrorf("invalid VAULT_ADDR: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if ns := os.Getenv("VAULT_NAMESPACE"); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+	if r.http == nil {
+		r.http = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return nil, connectionErrorf("reading %s from Vault: %w", path, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, connectionErrorf("reading %s from Vault: %w", path, err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusNotFound:
+		return nil, configErrorf("reading %s from Vault: HTTP %d: %s", path, resp.StatusCode, strings.TrimSpace(string(body)))
+	case resp.StatusCode >= 300:
+		return nil, connectionErrorf("reading %s from Vault: HTTP %d: %s", path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var secret struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return nil, dataErrorf("reading %s from Vault: %w", path, err)
+	}
+	data := secret.Data
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		if _, v2 := data["metadata"]; v2 {
+			data = inner
+		}
+	}
+	if r.vault == nil {
+		r.vault = make(map[string]map[string]interface{})
+	}
+	r.vault[path] = data
+	return data, nil
+}
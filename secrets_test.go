@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandSecrets(t *testing.T) {
+	var requests int
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("X-Vault-Token") != "root" {
+			http.Error(w, `{"errors": ["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/es":
+			w.Write([]byte(`{"data": {"data": {"user": "elastic", "password": "s3cret"}, "metadata": {"version": 2}}}`))
+		case "/v1/kv/es":
+			w.Write([]byte(`{"data": {"api_key": "a2V5"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer vault.Close()
+	t.Setenv("VAULT_ADDR", vault.URL)
+	t.Setenv("VAULT_TOKEN", "root")
+	t.Setenv("ES_TEST_HOST", "es-prod")
+	secretFile := filepath.Join(t.TempDir(), "password")
+	os.WriteFile(secretFile, []byte("from-file\n"), 0o600)
+
+	var r secretResolver
+	ctx := context.Background()
+	for in, want := range map[string]string{
+		"https://${env:ES_TEST_HOST}:9200":                              "https://es-prod:9200",
+		"${file:" + secretFile + "}":                                    "from-file",
+		"${vault:secret/data/es#user}:${vault:secret/data/es#password}": "elastic:s3cret",
+		"${vault:/kv/es#api_key}":                                       "a2V5",
+		"plain":                                                         "plain",
+	} {
+		if got, err := r.expand(ctx, in); err != nil || got != want {
+			t.Errorf("expand(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if requests != 2 {
+		t.Errorf("Vault was read %d times, want once per path", requests)
+	}
+
+	for in, want := range map[string]string{
+		"${env:ES_TEST_MISSING}":      "not set",
+		"${file:/nonexistent/x}":      "no such file",
+		"${vault:secret/data/es}":     "want ${vault:<path>#<key>}",
+		"${vault:secret/data/es#pw}":  `no key "pw"`,
+		"${vault:secret/data/gone#x}": "HTTP 404",
+	} {
+		if _, err := r.expand(ctx, in); err == nil || !strings.Contains(err.Error(), want) || kindOf(err) != kindConfig {
+			t.Errorf("expand(%q) = %v, want a config error containing %q", in, err, want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+const (
+	sliceOutputMerge = "merge"
+	sliceOutputFiles = "files"
+)
+
+// sliceOptions는 인덱스 하나를 sliced scroll(--pit이면 sliced point in time 검색)로 나눠
+// 동시에 읽는 설정입니다. 조각마다 자기 레코드 빌더로 변환하고, 파일은 하나로 모으거나
+// 조각마다 따로 씁니다.
+type sliceOptions struct {
+	n      int
+	output string
+}
+
+func (o *sliceOptions) bind(fs *flag.FlagSet) {
+	fs.IntVar(&o.n, "slices", 1, "read every index with this many concurrent sliced scrolls (sliced point in time searches with --pit), each converting its own pages, sharing the --workers budget")
+	fs.StringVar(&o.output, "slice-output", sliceOutputMerge, "where --slices writes: merge (every slice into <index>.parquet) or files (one <index>/part-NNNNN.parquet per slice, encoded concurrently)")
+}
+
+func (o *sliceOptions) validate() error {
+	if o.n <= 0 {
+		return configErrorf("export: --slices must be positive")
+	}
+	switch o.output {
+	case sliceOutputMerge:
+	case sliceOutputFiles:
+		if o.n == 1 {
+			return configErrorf("export: --slice-output files requires --slices greater than 1")
+		}
+	default:
+		return configErrorf("export: unknown --slice-output %q (want merge or files)", o.output)
+	}
+	return nil
+}
+
+func (o *sliceOptions) enabled() bool {
+	return o.n > 1
+}
+
+// files 함수는 조각마다 따로 파일을 쓰는지 알려 줍니다.
+func (o *sliceOptions) files() bool {
+	return o.enabled() && o.output == sliceOutputFiles
+}
+
+// slicePath 함수는 --slice-output files에서 slice번째 조각의 파일 경로입니다.
+func slicePath(dir string, slice int) string {
+	return filepath.Join(dir, fmt.Sprintf("part-%05d.parquet", slice))
+}
+
+// sliceSink 함수는 slice번째 조각의 파일을 반환합니다. 없으면 schema로 엽니다. mu를 잡고
+// 부릅니다.
+func (j *exportJob) sliceSink(slice int, schema *arrow.Schema) (*parquetSink, error) {
+	if s := j.slices[slice]; s != nil {
+		return s, nil
+	}
+	if err := os.MkdirAll(j.path, 0o755); err != nil {
+		return nil, configErrorf("creating slice directory: %w", err)
+	}
+	s, err := newParquetSink(slicePath(j.path, slice), schema, j.mappingJSON, j.sinkColumns(), &j.opts.names, &j.opts.parquet)
+	if err != nil {
+		return nil, err
+	}
+	j.slices[slice] = s
+	j.sliceSchema = schema
+	j.renames = s.renames
+	j.lineage = s.lineage
+	return s, nil
+}
+
+// writeSlice 함수는 slice번째 조각의 레코드를 그 조각의 파일에 씁니다. 파일마다 그 조각의
+// scroll만 쓰므로 인코딩과 압축은 잠금 밖에서 조각마다 동시에 합니다.
+func (j *exportJob) writeSlice(slice int, rec arrow.Record, hits []searchHit) error {
+	j.mu.Lock()
+	if j.opts.memoryStats {
+		j.memory.add(rec)
+	}
+	sink, err := j.sliceSink(slice, rec.Schema())
+	j.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := sink.writeHits(rec, hits); err != nil {
+		return err
+	}
+	written := sink.bytesWritten()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.sliceBytes[slice] = written
+	j.rows += int64(len(hits))
+	j.progress.report(j.label(), j.rows, j.total, false)
+	return j.spend()
+}
+
+// sliceBytesWritten 함수는 조각 파일들에 지금까지 쓴 바이트 수입니다. 각 조각이 쓴 뒤에
+// 남긴 값을 더하므로, 다른 조각이 쓰는 중인 파일을 읽지 않습니다.
+func (j *exportJob) sliceBytesWritten() int64 {
+	var n int64
+	for _, b := range j.sliceBytes {
+		n += b
+	}
+	return n
+}
+
+// finishSlices 함수는 조각 파일을 모두 닫습니다. 문서가 없던 조각도 스키마만 있는 파일을
+// 만들어, 조각 수만큼의 파일이 한 테이블로 읽히게 합니다. 하나라도 닫지 못하면 abort가
+// 닫은 파일까지 모두 지웁니다.
+func (j *exportJob) finishSlices() error {
+	schema := j.sliceSchema
+	if schema == nil {
+		var err error
+		if schema, err = j.emptySchema(); err != nil {
+			return err
+		}
+	}
+	for i := range j.slices {
+		if _, err := j.sliceSink(i, schema); err != nil {
+			return err
+		}
+	}
+	var written int64
+	files := make([]partitionFile, len(j.slices))
+	for i, s := range j.slices {
+		if err := s.close(); err != nil {
+			return err
+		}
+		written += s.bytesWritten()
+		files[i] = partitionFile{path: s.path, rows: s.rows}
+	}
+	j.slices, j.sliceFiles = nil, files
+	j.account(written)
+	return nil
+}
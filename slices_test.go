@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSlicedScroll(t *testing.T) {
+	var mu sync.Mutex
+	var slices []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Slice    *searchSlice `json:"slice"`
+			ScrollID string       `json:"scroll_id"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/logs/_search":
+			mu.Lock()
+			slices = append(slices, fmt.Sprintf("%d/%d", body.Slice.ID, body.Slice.Max))
+			mu.Unlock()
+			fmt.Fprintf(w, `{"_scroll_id": "s%d", "hits": {"hits": [{"_id": "doc-%d"}]}}`, body.Slice.ID, body.Slice.ID)
+		case r.Method == http.MethodPost && r.URL.Path == "/_search/scroll":
+			fmt.Fprintf(w, `{"_scroll_id": %q, "hits": {"hits": []}}`, body.ScrollID)
+		case r.Method == http.MethodDelete:
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer srv.Close()
+
+	client := &esClient{baseURL: srv.URL, http: srv.Client()}
+	var ids []string
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := client.scroll(context.Background(), "logs", nil, 10, time.Minute, &searchSlice{ID: i, Max: 3}, nil, func(hits []searchHit) error {
+				mu.Lock()
+				defer mu.Unlock()
+				for _, h := range hits {
+					ids = append(ids, h.ID)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	sort.Strings(slices)
+	sort.Strings(ids)
+	if strings.Join(slices, ",") != "0/3,1/3,2/3" || strings.Join(ids, ",") != "doc-0,doc-1,doc-2" {
+		t.Errorf("slices = %v, ids = %v", slices, ids)
+	}
+}
+
+func TestSliceOptions(t *testing.T) {
+	cases := []struct {
+		opts sliceOptions
+		ok   bool
+	}{
+		{sliceOptions{n: 1, output: sliceOutputMerge}, true},
+		{sliceOptions{n: 4, output: sliceOutputFiles}, true},
+		{sliceOptions{n: 0, output: sliceOutputMerge}, false},
+		{sliceOptions{n: 1, output: sliceOutputFiles}, false},
+		{sliceOptions{n: 4, output: "shards"}, false},
+	}
+	for _, c := range cases {
+		err := c.opts.validate()
+		if (err == nil) != c.ok {
+			t.Errorf("validate(%+v) = %v", c.opts, err)
+		}
+		if err != nil && exitCodeFor(err) != exitConfigError {
+			t.Errorf("validate(%+v) = %v, want a config error", c.opts, err)
+		}
+	}
+	if p := slicePath("out/logs", 3); p != "out/logs/part-00003.parquet" {
+		t.Errorf("slicePath = %s", p)
+	}
+}
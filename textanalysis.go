@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+// analyzerKey는 --analyze로 만든 토큰 컬럼에 분석기 이름을 남기는 필드 메타데이터
+// 키입니다. 토큰 컬럼은 원래 문서에 없던 값이므로 가져오기 시 버립니다.
+const analyzerKey = "es_schema.analyzer"
+
+// tokensSuffix는 --analyze 필드의 토큰 컬럼 이름에 붙는 접미사입니다.
+const tokensSuffix = "_tokens"
+
+// localAnalyzers는 --analyze-local이 흉내 내는 Elasticsearch 분석기입니다. standard는
+// 글자와 숫자가 아닌 문자에서 나누고 소문자로 바꾸는 근사로, 유니코드 단어 분할 규칙의
+// 세부(예: 숫자 사이의 점)는 따르지 않습니다.
+var localAnalyzers = map[string]func(string) []string{
+	"standard": func(s string) []string {
+		return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+		})
+	},
+	"simple": func(s string) []string {
+		return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool { return !unicode.IsLetter(r) })
+	},
+	"whitespace": strings.Fields,
+	"keyword":    func(s string) []string { return []string{s} },
+}
+
+// analyzeSpec은 --analyze 항목 하나입니다. analyzer가 비어 있으면 필드 매핑의 분석기를
+// 씁니다.
+type analyzeSpec struct {
+	path     string
+	analyzer string
+}
+
+// column 함수는 토큰 컬럼의 이름을 반환합니다.
+func (s analyzeSpec) column() string {
+	return s.path + tokensSuffix
+}
+
+// analyzeOptions는 text 필드를 분석한 토큰 목록을 컬럼으로 더하는 설정입니다. 검색
+// 적합성 분석처럼 인덱스가 실제로 본 토큰이 필요한 오프라인 작업을 위한 것입니다.
+type analyzeOptions struct {
+	fields stringListFlag
+	local  bool
+	specs  []analyzeSpec
+}
+
+func (o *analyzeOptions) bind(fs *flag.FlagSet) {
+	fs.Var(&o.fields, "analyze", "also export the tokens of these text fields as list<utf8> columns named <field>"+tokensSuffix+", as path or path=analyzer (default: the field's own analyzer; comma-separated or repeated)")
+	fs.BoolVar(&o.local, "analyze-local", false, "tokenize --analyze fields locally with an approximation of the standard, simple, whitespace or keyword analyzer instead of calling _analyze for every document")
+}
+
+func (o *analyzeOptions) validate() error {
+	o.specs = nil
+	for _, f := range o.fields {
+		path, analyzer, _ := strings.Cut(f, "=")
+		if path == "" {
+			return configErrorf("--analyze %q: want path or path=analyzer", f)
+		}
+		if o.local {
+			if analyzer == "" {
+				analyzer = "standard"
+			}
+			if _, ok := localAnalyzers[analyzer]; !ok {
+				return configErrorf("--analyze %s: --analyze-local only knows the standard, simple, whitespace and keyword analyzers", f)
+			}
+		}
+		o.specs = append(o.specs, analyzeSpec{path: path, analyzer: analyzer})
+	}
+	return nil
+}
+
+// tokenField 함수는 spec의 토큰 컬럼 필드를 만듭니다.
+func tokenField(spec analyzeSpec) arrow.Field {
+	analyzer := spec.analyzer
+	if analyzer == "" {
+		analyzer = "field"
+	}
+	return arrow.Field{
+		Name:     spec.column(),
+		Type:     arrow.ListOf(arrow.BinaryTypes.String),
+		Nullable: true,
+		Metadata: arrow.NewMetadata([]string{analyzerKey, derivedFromKey}, []string{analyzer, spec.path}),
+	}
+}
+
+// isTokenColumn 함수는 field가 --analyze로 만든 토큰 컬럼인지 알려 줍니다.
+func isTokenColumn(f arrow.Field) bool {
+	return f.Metadata.FindKey(analyzerKey) >= 0
+}
+
+// analyzeFunc는 한 필드의 문자열 값들을 토큰 목록 하나로 바꿉니다.
+type analyzeFunc func(spec analyzeSpec, texts []string) ([]string, error)
+
+// localAnalyze 함수는 --analyze-local의 analyzeFunc입니다.
+func localAnalyze(spec analyzeSpec, texts []string) ([]string, error) {
+	analyze := localAnalyzers[spec.analyzer]
+	var tokens []string
+	for _, t := range texts {
+		tokens = append(tokens, analyze(t)...)
+	}
+	return tokens, nil
+}
+
+// remoteAnalyze 함수는 index의 _analyze API를 부르는 analyzeFunc를 만듭니다.
+func remoteAnalyze(ctx context.Context, client *esClient, index string) analyzeFunc {
+	return func(spec analyzeSpec, texts []string) ([]string, error) {
+		return client.analyze(ctx, index, spec.path, spec.analyzer, texts)
+	}
+}
+
+// tokensTransform 함수는 specs의 필드 값을 분석해 토큰 컬럼 값을 문서 최상위에 넣는
+// 변환을 만듭니다. 필드에 문자열 값이 없으면 토큰 컬럼은 null입니다. 분석이 실패한
+// 문서는 변환 실패로 기록됩니다.
+func tokensTransform(specs []analyzeSpec, analyze analyzeFunc) docTransform {
+	return func(doc map[string]interface{}) (map[string]interface{}, error) {
+		for _, spec := range specs {
+			var texts []string
+			collectStrings(doc, strings.Split(spec.path, "."), &texts)
+			if len(texts) == 0 {
+				continue
+			}
+			tokens, err := analyze(spec, texts)
+			if err != nil {
+				return nil, fmt.Errorf("analyzing %s: %w", spec.path, err)
+			}
+			values := make([]interface{}, len(tokens))
+			for i, t := range tokens {
+				values[i] = t
+			}
+			doc[spec.column()] = values
+		}
+		return doc, nil
+	}
+}
+
+// collectStrings 함수는 v에서 path의 문자열 값을 모두 모읍니다. 배열과 객체 배열 안의
+// 값도 모읍니다.
+func collectStrings(v interface{}, path []string, out *[]string) {
+	switch x := v.(type) {
+	case []interface{}:
+		for _, item := range x {
+			collectStrings(item, path, out)
+		}
+	case map[string]interface{}:
+		if len(path) > 0 {
+			collectStrings(x[path[0]], path[1:], out)
+		}
+	case string:
+		if len(path) == 0 {
+			*out = append(*out, x)
+		}
+	}
+}
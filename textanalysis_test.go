@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestLocalAnalyzers(t *testing.T) {
+	for _, c := range []struct {
+		analyzer, text string
+		want           []string
+	}{
+		{"standard", "The Quick-Brown fox's 2 dogs!", []string{"the", "quick", "brown", "fox's", "2", "dogs"}},
+		{"simple", "R2-D2 Droid", []string{"r", "d", "droid"}},
+		{"whitespace", " Keep  Case ", []string{"Keep", "Case"}},
+		{"keyword", "New York", []string{"New York"}},
+	} {
+		got, _ := localAnalyze(analyzeSpec{path: "f", analyzer: c.analyzer}, []string{c.text})
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s(%q) = %q, want %q", c.analyzer, c.text, got, c.want)
+		}
+	}
+
+	o := &analyzeOptions{fields: stringListFlag{"body", "title=english"}, local: true}
+	if err := o.validate(); err == nil {
+		t.Error("--analyze-local accepted the english analyzer")
+	}
+	o.local = false
+	if err := o.validate(); err != nil || o.specs[0].analyzer != "" || o.specs[1].analyzer != "english" {
+		t.Errorf("specs = %+v, %v", o.specs, err)
+	}
+}
+
+func TestTokensTransform(t *testing.T) {
+	var calls [][]string
+	analyze := func(spec analyzeSpec, texts []string) ([]string, error) {
+		calls = append(calls, texts)
+		if texts[0] == "fail" {
+			return nil, errors.New("boom")
+		}
+		return localAnalyze(analyzeSpec{analyzer: "whitespace"}, texts)
+	}
+	transform := tokensTransform([]analyzeSpec{{path: "comments.text"}, {path: "missing"}}, analyze)
+
+	doc, _ := decodeDocument([]byte(`{"comments": [{"text": "a b"}, {"text": ["c", 1]}]}`))
+	out, err := transform(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out["comments.text_tokens"], []interface{}{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tokens = %v, want %v", got, want)
+	}
+	if _, ok := out["missing_tokens"]; ok || len(calls) != 1 {
+		t.Errorf("a field without values was analyzed: %v", calls)
+	}
+
+	doc, _ = decodeDocument([]byte(`{"comments": {"text": "fail"}}`))
+	if _, err := transform(doc); err == nil {
+		t.Error("a failed analysis did not fail the document")
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/apache/arrow/go/v10/arrow"
+
+	"es-schema/esschema"
+)
+
+// timestampUnitOptions는 date와 date_nanos 필드의 timestamp 단위를 고르는 --timestamp-unit
+// 플래그입니다. 나노초만 읽지 못하는 소비자(예전 Spark 등)를 위해 ms나 us로 씁니다.
+type timestampUnitOptions struct {
+	unit string
+}
+
+func (o *timestampUnitOptions) bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.unit, "timestamp-unit", "ns", "Arrow timestamp unit of date and date_nanos fields: s, ms, us or ns; coarser units drop the finer digits of each value but hold dates before 1677 and after 2262")
+}
+
+func (o *timestampUnitOptions) validate() error {
+	if o.unit == "" {
+		return nil
+	}
+	if _, err := esschema.ParseTimeUnit(o.unit); err != nil {
+		return configErrorf("--timestamp-unit: %w", err)
+	}
+	return nil
+}
+
+// apply 함수는 ns가 아니면 fields의 timestamp 컬럼을 그 단위로 바꿉니다.
+func (o *timestampUnitOptions) apply(fields []arrow.Field) []arrow.Field {
+	unit, err := esschema.ParseTimeUnit(o.unit)
+	if err != nil || unit == arrow.Nanosecond {
+		return fields
+	}
+	return esschema.TimestampsIn(fields, unit)
+}
@@ -0,0 +1,14 @@
+package main
+
+import "testing"
+
+func TestTimestampUnitValidate(t *testing.T) {
+	for _, unit := range []string{"s", "ms", "us", "ns"} {
+		if err := (&timestampUnitOptions{unit: unit}).validate(); err != nil {
+			t.Errorf("--timestamp-unit %s: %v", unit, err)
+		}
+	}
+	if err := (&timestampUnitOptions{unit: "micros"}).validate(); exitCodeFor(err) != exitConfigError {
+		t.Errorf("--timestamp-unit micros = %v, want a config error", err)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+	"github.com/apache/arrow/go/v10/arrow/memory"
+)
+
+// deletedColumn은 --tombstones rows에서 tombstone 행을 표시하는 불리언 컬럼입니다.
+// 가져오기는 이 값이 true인 행을 삭제 요청으로 보냅니다.
+const deletedColumn = "_deleted"
+
+const (
+	tombstonesRows = "rows"
+	tombstonesFile = "file"
+)
+
+// tombstoneOptions는 반복해서 내보내는 데이터셋에서 삭제된 문서를 표시하는 설정입니다.
+// 삭제는 지난 실행의 _id 목록(idSnapshot)에 있었는데 이번에 없는 문서, 또는 소프트 삭제
+// 쿼리(deletedQuery)에 맞는 문서로 찾습니다. 찾은 _id는 mode에 따라 데이터 파일의 tombstone
+// 행이나, _id 컬럼 하나로 된 <index>.deletes.parquet 파일(equality delete)로 씁니다.
+type tombstoneOptions struct {
+	idSnapshot   string
+	deletedQuery string
+	mode         string
+}
+
+func (o *tombstoneOptions) bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.idSnapshot, "id-snapshot", "", "directory keeping each index's exported _ids as <index>.ids; documents listed by the previous run but no longer found become tombstones")
+	fs.StringVar(&o.deletedQuery, "deleted-query", "", "query DSL JSON matching soft-deleted documents; they are left out of the data and written as tombstones")
+	fs.StringVar(&o.mode, "tombstones", tombstonesRows, "how to write tombstones: rows (rows with "+deletedColumn+"=true and null fields in the data file) or file (an <index>.deletes.parquet file with only _id)")
+}
+
+func (o *tombstoneOptions) validate() error {
+	switch o.mode {
+	case tombstonesRows, tombstonesFile:
+	default:
+		return configErrorf("unknown --tombstones %q (want rows or file)", o.mode)
+	}
+	if o.deletedQuery != "" && !json.Valid([]byte(o.deletedQuery)) {
+		return configErrorf("--deleted-query is not valid JSON")
+	}
+	if o.idSnapshot != "" {
+		if err := os.MkdirAll(o.idSnapshot, 0o755); err != nil {
+			return configErrorf("creating --id-snapshot: %w", err)
+		}
+	}
+	return nil
+}
+
+func (o *tombstoneOptions) enabled() bool {
+	return o.idSnapshot != "" || o.deletedQuery != ""
+}
+
+// rows 함수는 tombstone을 데이터 파일에 행으로 쓰는지 알려 줍니다.
+func (o *tombstoneOptions) rows() bool {
+	return o.enabled() && o.mode == tombstonesRows
+}
+
+// dataQuery 함수는 query에서 --deleted-query에 맞는 문서를 뺀 검색 조건을 만듭니다.
+func (o *tombstoneOptions) dataQuery(query string) string {
+	if o.deletedQuery == "" {
+		return query
+	}
+	b := map[string]interface{}{"must_not": []json.RawMessage{json.RawMessage(o.deletedQuery)}}
+	if query != "" {
+		b["filter"] = []json.RawMessage{json.RawMessage(query)}
+	}
+	data, _ := json.Marshal(map[string]interface{}{"bool": b})
+	return string(data)
+}
+
+// deletedSearch 함수는 query에 맞는 문서 중 --deleted-query에도 맞는 문서의 검색 조건을
+// 만듭니다.
+func (o *tombstoneOptions) deletedSearch(query string) string {
+	filter := []json.RawMessage{json.RawMessage(o.deletedQuery)}
+	if query != "" {
+		filter = append(filter, json.RawMessage(query))
+	}
+	data, _ := json.Marshal(map[string]interface{}{"bool": map[string]interface{}{"filter": filter}})
+	return string(data)
+}
+
+func (o *tombstoneOptions) snapshotPath(index string) string {
+	return filepath.Join(o.idSnapshot, index+".ids")
+}
+
+// readIDSnapshot 함수는 한 줄에 _id 하나씩 쓴 스냅샷 파일을 읽습니다. 파일이 없으면(첫
+// 실행) nil을 반환합니다.
+func readIDSnapshot(path string) (map[string]bool, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, configErrorf("reading id snapshot: %w", err)
+	}
+	defer f.Close()
+	ids := make(map[string]bool)
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		ids[sc.Text()] = true
+	}
+	if err := sc.Err(); err != nil {
+		return nil, configErrorf("reading id snapshot %s: %w", path, err)
+	}
+	return ids, nil
+}
+
+// writeIDSnapshot 함수는 ids를 정렬해 path에 씁니다. 중간에 실패해도 이전 스냅샷이 남도록
+// 임시 파일에 쓴 뒤 이름을 바꿉니다.
+func writeIDSnapshot(path string, ids map[string]bool) error {
+	sorted := make([]string, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Strings(sorted)
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return configErrorf("writing id snapshot: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	for _, id := range sorted {
+		fmt.Fprintln(w, id)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return configErrorf("writing id snapshot %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return configErrorf("writing id snapshot %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return configErrorf("writing id snapshot %s: %w", path, err)
+	}
+	return nil
+}
+
+// missingIDs 함수는 previous에 있고 current에 없는 _id를 정렬해 반환합니다.
+func missingIDs(previous, current map[string]bool) []string {
+	var missing []string
+	for id := range previous {
+		if !current[id] {
+			missing = append(missing, id)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// writeDeletesFile 함수는 ids를 _id 컬럼 하나로 된 Parquet 파일로 씁니다. 삭제가 없어도
+// 파일을 만들어, 지난 실행의 삭제 파일이 남아 잘못 읽히지 않게 합니다.
+func writeDeletesFile(path string, ids []string, opts *parquetOptions) error {
+	schema := arrow.NewSchema([]arrow.Field{{Name: "_id", Type: arrow.BinaryTypes.String}}, nil)
+	w, err := createParquetFile(path, schema, opts)
+	if err != nil {
+		return err
+	}
+	b := array.NewBuilder(memory.DefaultAllocator, arrow.BinaryTypes.String).(*array.StringBuilder)
+	defer b.Release()
+	b.AppendValues(ids, nil)
+	col := b.NewArray()
+	defer col.Release()
+	rec := array.NewRecord(schema, []arrow.Array{col}, int64(len(ids)))
+	defer rec.Release()
+	if err := w.write(rec); err != nil {
+		w.abort()
+		return err
+	}
+	return w.close()
+}
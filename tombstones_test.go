@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTombstoneQueries(t *testing.T) {
+	o := &tombstoneOptions{deletedQuery: `{"term":{"deleted":true}}`, mode: tombstonesRows}
+	if got, want := o.dataQuery(`{"match_all":{}}`), `{"bool":{"filter":[{"match_all":{}}],"must_not":[{"term":{"deleted":true}}]}}`; got != want {
+		t.Errorf("dataQuery = %s, want %s", got, want)
+	}
+	if got, want := o.deletedSearch(""), `{"bool":{"filter":[{"term":{"deleted":true}}]}}`; got != want {
+		t.Errorf("deletedSearch = %s, want %s", got, want)
+	}
+	if got := (&tombstoneOptions{}).dataQuery(`{"match_all":{}}`); got != `{"match_all":{}}` {
+		t.Errorf("dataQuery without --deleted-query = %s", got)
+	}
+	o.mode = "delta"
+	if err := o.validate(); err == nil {
+		t.Error("validate accepted --tombstones delta")
+	}
+}
+
+func TestIDSnapshotFindsMissingIDs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.ids")
+	previous, err := readIDSnapshot(path)
+	if err != nil || previous != nil {
+		t.Fatalf("first run snapshot = %v, %v; want none", previous, err)
+	}
+	if err := writeIDSnapshot(path, map[string]bool{"c": true, "a": true, "b": true}); err != nil {
+		t.Fatal(err)
+	}
+	previous, err = readIDSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := missingIDs(previous, map[string]bool{"b": true, "d": true}); !reflect.DeepEqual(got, []string{"a", "c"}) {
+		t.Errorf("missingIDs = %v, want [a c]", got)
+	}
+}
+
+func TestBulkIndexerDeleteSendsActionOnly(t *testing.T) {
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		w.Write([]byte(`{"errors": false, "items": [{"delete": {"_id": "7", "status": 404}}, {"index": {"_id": "8", "status": 201}}]}`))
+	}))
+	defer srv.Close()
+
+	indexer := newBulkIndexer(&esClient{baseURL: srv.URL, http: srv.Client()}, "logs", actionIndex, 10, 1<<20)
+	ctx := context.Background()
+	if err := indexer.delete(ctx, "7"); err != nil {
+		t.Fatal(err)
+	}
+	if err := indexer.add(ctx, "8", map[string]interface{}{"n": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := indexer.flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\"delete\":{\"_id\":\"7\"}}\n{\"index\":{\"_id\":\"8\"}}\n{\"n\":1}\n"; body != want {
+		t.Errorf("bulk body =\n%s\nwant\n%s", body, want)
+	}
+	if indexer.succeeded != 2 {
+		t.Errorf("succeeded = %d, want 2 (not_found deletes are not failures)", indexer.succeeded)
+	}
+}
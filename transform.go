@@ -0,0 +1,215 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// docTransform은 변환 전에 _source 문서 하나를 고치는 미들웨어입니다. 문서를 바꿔
+// 반환하거나(제자리에서 고쳐도 됩니다), nil을 반환해 문서를 버리거나, 오류를 반환해
+// 문서를 실패로 기록할 수 있습니다. 인제스트 파이프라인의 프로세서에 해당합니다.
+type docTransform func(doc map[string]interface{}) (map[string]interface{}, error)
+
+// transformFactory는 --transform name:arg의 arg로 변환 하나를 만듭니다.
+type transformFactory func(arg string) (docTransform, error)
+
+// transformFactories는 --transform으로 쓸 수 있는 변환입니다. 빌드에 포함된 다른 파일의
+// init에서 registerTransform으로 변환을 더할 수 있습니다.
+var transformFactories = map[string]transformFactory{
+	"rename":     renameTransform,
+	"drop-field": dropFieldTransform,
+	"require":    requireTransform,
+	"set":        setTransform,
+}
+
+// registerTransform 함수는 이름 name으로 변환을 등록합니다. 이미 있는 이름이면 패닉합니다.
+func registerTransform(name string, factory transformFactory) {
+	if _, ok := transformFactories[name]; ok {
+		panic("es-schema: transform " + name + " registered twice")
+	}
+	transformFactories[name] = factory
+}
+
+// transformChain은 차례로 적용할 변환입니다.
+type transformChain []docTransform
+
+// apply 함수는 doc에 변환을 차례로 적용합니다. 어느 변환이든 nil을 반환하면 거기서
+// 멈추고 nil을 반환합니다.
+func (c transformChain) apply(doc map[string]interface{}) (map[string]interface{}, error) {
+	for _, t := range c {
+		var err error
+		if doc, err = t(doc); err != nil || doc == nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// transformOptions는 --transform 플래그입니다.
+type transformOptions struct {
+	specs stringListFlag
+}
+
+func (o *transformOptions) bind(fs *flag.FlagSet) {
+	fs.Var(&o.specs, "transform", "apply name:arg to each document before conversion, in order: rename:old=new, drop-field:path, require:path (drop documents without it) or set:path=json (repeated)")
+}
+
+// build 함수는 --transform 목록으로 변환 체인을 만듭니다.
+func (o *transformOptions) build() (transformChain, error) {
+	var chain transformChain
+	for _, spec := range o.specs {
+		name, arg, _ := strings.Cut(spec, ":")
+		factory, ok := transformFactories[name]
+		if !ok {
+			return nil, configErrorf("unknown --transform %q (want one of %s)", name, strings.Join(transformNames(), ", "))
+		}
+		t, err := factory(arg)
+		if err != nil {
+			return nil, configErrorf("--transform %s: %w", spec, err)
+		}
+		chain = append(chain, t)
+	}
+	return chain, nil
+}
+
+func transformNames() []string {
+	names := make([]string, 0, len(transformFactories))
+	for name := range transformFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// renameTransform 함수는 rename:old.path=new.path 변환을 만듭니다. 원래 필드가 없으면
+// 문서를 그대로 둡니다.
+func renameTransform(arg string) (docTransform, error) {
+	from, to, ok := strings.Cut(arg, "=")
+	if !ok || from == "" || to == "" {
+		return nil, fmt.Errorf("want old=new")
+	}
+	fromPath, toPath := strings.Split(from, "."), strings.Split(to, ".")
+	return func(doc map[string]interface{}) (map[string]interface{}, error) {
+		if v, ok := deletePath(doc, fromPath); ok {
+			if err := setPath(doc, toPath, v); err != nil {
+				return nil, err
+			}
+		}
+		return doc, nil
+	}, nil
+}
+
+func dropFieldTransform(arg string) (docTransform, error) {
+	if arg == "" {
+		return nil, fmt.Errorf("want a field path")
+	}
+	path := strings.Split(arg, ".")
+	return func(doc map[string]interface{}) (map[string]interface{}, error) {
+		deletePath(doc, path)
+		return doc, nil
+	}, nil
+}
+
+// requireTransform 함수는 필드가 없거나 null인 문서를 버리는 변환을 만듭니다.
+func requireTransform(arg string) (docTransform, error) {
+	if arg == "" {
+		return nil, fmt.Errorf("want a field path")
+	}
+	path := strings.Split(arg, ".")
+	return func(doc map[string]interface{}) (map[string]interface{}, error) {
+		if v, ok := getPath(doc, path); !ok || v == nil {
+			return nil, nil
+		}
+		return doc, nil
+	}, nil
+}
+
+// setTransform 함수는 set:path=json 변환을 만듭니다. 값은 JSON으로 읽습니다.
+func setTransform(arg string) (docTransform, error) {
+	field, raw, ok := strings.Cut(arg, "=")
+	if !ok || field == "" {
+		return nil, fmt.Errorf("want path=json")
+	}
+	value, err := decodeJSONValue([]byte(raw))
+	if err != nil {
+		return nil, fmt.Errorf("value %s: %w", raw, err)
+	}
+	path := strings.Split(field, ".")
+	return func(doc map[string]interface{}) (map[string]interface{}, error) {
+		return doc, setPath(doc, path, value)
+	}, nil
+}
+
+func getPath(doc map[string]interface{}, path []string) (interface{}, bool) {
+	for _, key := range path[:len(path)-1] {
+		next, ok := doc[key].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		doc = next
+	}
+	v, ok := doc[path[len(path)-1]]
+	return v, ok
+}
+
+func deletePath(doc map[string]interface{}, path []string) (interface{}, bool) {
+	for _, key := range path[:len(path)-1] {
+		next, ok := doc[key].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		doc = next
+	}
+	last := path[len(path)-1]
+	v, ok := doc[last]
+	delete(doc, last)
+	return v, ok
+}
+
+// setPath 함수는 중간 객체를 만들어 가며 값을 넣습니다. 중간 경로에 객체가 아닌 값이
+// 있으면 오류를 반환합니다.
+func setPath(doc map[string]interface{}, path []string, v interface{}) error {
+	for i, key := range path[:len(path)-1] {
+		switch next := doc[key].(type) {
+		case map[string]interface{}:
+			doc = next
+		case nil:
+			obj := map[string]interface{}{}
+			doc[key] = obj
+			doc = obj
+		default:
+			return fmt.Errorf("%s is not an object", strings.Join(path[:i+1], "."))
+		}
+	}
+	doc[path[len(path)-1]] = v
+	return nil
+}
+
+// transformHits 함수는 chain을 문서마다 적용합니다. 오류를 낸 문서는 r에 실패로 기록하고,
+// 버려진 문서는 dropped에 셉니다. 반환하는 hits는 docs와 짝이 맞습니다.
+func (r *docRejects) transformHits(chain transformChain, hits []searchHit, docs []map[string]interface{}, dropped *int64) ([]searchHit, []map[string]interface{}, error) {
+	if len(chain) == 0 {
+		return hits, docs, nil
+	}
+	keptHits := hits[:0:0]
+	keptDocs := docs[:0:0]
+	for i, doc := range docs {
+		out, err := chain.apply(doc)
+		if err != nil {
+			if err := r.add(hits[i], "transform", err.Error()); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		if out == nil {
+			atomic.AddInt64(dropped, 1)
+			continue
+		}
+		keptHits = append(keptHits, hits[i])
+		keptDocs = append(keptDocs, out)
+	}
+	return keptHits, keptDocs, nil
+}
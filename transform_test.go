@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTransformChain(t *testing.T) {
+	o := transformOptions{specs: stringListFlag{
+		"rename:msg=message.text",
+		"drop-field:debug",
+		"set:source.kind=\"legacy\"",
+		"require:message.text",
+	}}
+	chain, err := o.build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := chain.apply(map[string]interface{}{"msg": "hello", "debug": true, "level": json.Number("3")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"message": map[string]interface{}{"text": "hello"},
+		"source":  map[string]interface{}{"kind": "legacy"},
+		"level":   json.Number("3"),
+	}
+	if !reflect.DeepEqual(doc, want) {
+		t.Errorf("doc = %v, want %v", doc, want)
+	}
+
+	if doc, err := chain.apply(map[string]interface{}{"other": 1}); doc != nil || err != nil {
+		t.Errorf("document without message.text = %v, %v; want it dropped", doc, err)
+	}
+	if _, err := (&transformOptions{specs: stringListFlag{"upper:x"}}).build(); err == nil {
+		t.Error("unknown transform accepted")
+	}
+}
+
+func TestTransformHitsRecordsErrorsAndDrops(t *testing.T) {
+	chain := transformChain{func(doc map[string]interface{}) (map[string]interface{}, error) {
+		switch doc["n"] {
+		case 1:
+			return nil, nil
+		case 2:
+			return nil, errors.New("legacy format")
+		}
+		return doc, nil
+	}}
+	hits := []searchHit{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	docs := []map[string]interface{}{{"n": 1}, {"n": 2}, {"n": 3}}
+	var r docRejects
+	var dropped int64
+	hits, docs, err := r.transformHits(chain, hits, docs, &dropped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || hits[0].ID != "c" || docs[0]["n"] != 3 {
+		t.Errorf("kept %v, want only c", hits)
+	}
+	if dropped != 1 || r.count != 1 || r.reasons["transform"] != 1 {
+		t.Errorf("dropped %d, failed %d %v", dropped, r.count, r.reasons)
+	}
+}
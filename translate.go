@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// mappingRootParams는 매핑 최상위에 올 수 있는 매개변수입니다. 이 밖의 키 하나만 있는
+// 매핑은 Elasticsearch 6 이전의 매핑 타입(예: "_doc")으로 감싼 것으로 봅니다.
+var mappingRootParams = map[string]bool{
+	"properties": true, "dynamic": true, "dynamic_templates": true, "date_detection": true,
+	"dynamic_date_formats": true, "numeric_detection": true, "enabled": true, "runtime": true,
+	"_source": true, "_routing": true, "_meta": true, "_all": true, "_field_names": true, "_size": true,
+	"_timestamp": true, "_ttl": true,
+}
+
+// typeFallbacks는 대상 클러스터의 주 버전이 minMajor보다 낮을 때 쓸 수 없는 필드 타입과
+// 그 대신 쓸 타입입니다.
+var typeFallbacks = map[string]struct {
+	minMajor int
+	fallback string
+}{
+	"dense_vector":       {7, "float"},
+	"flattened":          {7, "object"},
+	"wildcard":           {7, "keyword"},
+	"match_only_text":    {7, "text"},
+	"search_as_you_type": {7, "text"},
+	"rank_feature":       {7, "float"},
+	"constant_keyword":   {7, "keyword"},
+}
+
+// renamedParams는 이름이 바뀐 필드 매개변수와 새 이름이 쓰이기 시작한 주 버전입니다.
+var renamedParams = map[string]struct {
+	minMajor int
+	newName  string
+}{
+	"index_analyzer":      {2, "analyzer"},
+	"position_offset_gap": {2, "position_increment_gap"},
+}
+
+// removedParams는 대상 주 버전부터 받아들이지 않아 지우는 필드 매개변수입니다.
+var removedParams = map[string]int{
+	"include_in_all": 6,
+	"precision_step": 5,
+	"index_name":     2,
+}
+
+// removedRootParams는 대상 주 버전부터 지원하지 않는 최상위 매핑 매개변수입니다.
+var removedRootParams = map[string]int{
+	"_all":       6,
+	"_timestamp": 5,
+	"_ttl":       5,
+}
+
+const (
+	changeRemoved = "removed"
+	changeChanged = "changed"
+	changeReview  = "review"
+)
+
+// mappingChange는 매핑 번역에서 바뀌었거나 사람이 확인해야 하는 구성 하나입니다.
+type mappingChange struct {
+	Path   string `json:"path"`
+	Action string `json:"action"`
+	Detail string `json:"detail"`
+}
+
+func (c mappingChange) String() string {
+	return fmt.Sprintf("%s: %s %s", c.Path, c.Action, c.Detail)
+}
+
+// mappingTranslator는 한 번의 번역 동안 대상 버전과 모은 변경 사항을 들고 다닙니다.
+type mappingTranslator struct {
+	dstMajor int
+	changes  []mappingChange
+}
+
+func (t *mappingTranslator) note(path, action, format string, args ...interface{}) {
+	t.changes = append(t.changes, mappingChange{Path: path, Action: action, Detail: fmt.Sprintf(format, args...)})
+}
+
+// translateMapping 함수는 다른 버전 클러스터에서 가져온 매핑을 dstMajor 버전 클러스터가
+// 받아들이는 타입 없는(typeless) 매핑으로 바꿉니다. 바꾼 내용과 직접 확인해야 할 구성은
+// changes로 돌려줍니다. 대상이 Elasticsearch 6 이하이면 인덱스를 만들 때
+// wrapMappingType으로 다시 감싸야 합니다.
+func translateMapping(raw json.RawMessage, dstMajor int) (map[string]interface{}, []mappingChange, error) {
+	var mapping map[string]interface{}
+	if err := json.Unmarshal(raw, &mapping); err != nil {
+		return nil, nil, schemaErrorf("decoding source mapping: %w", err)
+	}
+	t := &mappingTranslator{dstMajor: dstMajor}
+	mapping, err := t.unwrapMappingType(mapping)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, param := range sortedParams(mapping) {
+		if minMajor, ok := removedRootParams[param]; ok && dstMajor >= minMajor {
+			delete(mapping, param)
+			t.note(param, changeRemoved, "(not supported since Elasticsearch %d)", minMajor)
+		}
+	}
+	if props, ok := mapping["properties"].(map[string]interface{}); ok {
+		t.translateProperties(props, "")
+	}
+	return mapping, t.changes, nil
+}
+
+// unwrapMappingType 함수는 매핑 타입으로 감싼 매핑에서 타입 단계를 걷어냅니다.
+// 타입이 여러 개인 인덱스는 타입 없는 인덱스 하나로 옮길 수 없습니다.
+func (t *mappingTranslator) unwrapMappingType(mapping map[string]interface{}) (map[string]interface{}, error) {
+	var types []string
+	for k := range mapping {
+		if !mappingRootParams[k] {
+			types = append(types, k)
+		}
+	}
+	_, typeless := mapping["properties"]
+	switch {
+	case typeless || len(types) == 0:
+		return mapping, nil
+	case len(types) > 1:
+		sort.Strings(types)
+		return nil, schemaErrorf("source mapping has several mapping types %v; migrate them one at a time", types)
+	}
+	inner, ok := mapping[types[0]].(map[string]interface{})
+	if !ok {
+		return nil, schemaErrorf("source mapping type %q is not an object", types[0])
+	}
+	t.note(types[0], changeRemoved, "mapping type (indices are typeless since Elasticsearch 7)")
+	return inner, nil
+}
+
+// typelessMapping 함수는 원본 클러스터의 매핑에서 매핑 타입 단계만 걷어내고 나머지는
+// 그대로 둔 JSON을 반환합니다. 같은 버전으로 내보낼 때처럼 번역이 필요 없을 때 씁니다.
+func typelessMapping(raw json.RawMessage) ([]byte, error) {
+	var mapping map[string]interface{}
+	if err := json.Unmarshal(raw, &mapping); err != nil {
+		return nil, schemaErrorf("decoding mapping: %w", err)
+	}
+	mapping, err := (&mappingTranslator{}).unwrapMappingType(mapping)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(mapping)
+	if err != nil {
+		return nil, schemaErrorf("encoding mapping: %w", err)
+	}
+	return data, nil
+}
+
+// wrapMappingType 함수는 Elasticsearch 6 이하의 인덱스 생성 요청에 필요한 "_doc" 타입으로
+// 매핑을 감쌉니다.
+func wrapMappingType(mapping map[string]interface{}, dstMajor int) map[string]interface{} {
+	if dstMajor >= 7 {
+		return mapping
+	}
+	return map[string]interface{}{"_doc": mapping}
+}
+
+// translateProperties 함수는 properties의 각 필드를 제자리에서 바꿉니다.
+func (t *mappingTranslator) translateProperties(props map[string]interface{}, prefix string) {
+	for _, name := range sortedParams(props) {
+		field, ok := props[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		path := prefix + name
+		t.translateField(field, path)
+		if sub, ok := field["properties"].(map[string]interface{}); ok {
+			t.translateProperties(sub, path+".")
+		}
+		if sub, ok := field["fields"].(map[string]interface{}); ok {
+			t.translateProperties(sub, path+".")
+		}
+	}
+}
+
+func (t *mappingTranslator) translateField(field map[string]interface{}, path string) {
+	fieldType, _ := field["type"].(string)
+
+	if t.dstMajor >= 5 {
+		// Elasticsearch 2의 string 타입은 5부터 text와 keyword로 나뉘었습니다.
+		if fieldType == "string" {
+			fieldType = "text"
+			if idx, _ := field["index"].(string); idx == "not_analyzed" || idx == "no" {
+				fieldType = "keyword"
+			}
+			field["type"] = fieldType
+			t.note(path, changeChanged, "type string -> %s", fieldType)
+		}
+		if idx, ok := field["index"].(string); ok {
+			field["index"] = idx != "no"
+		}
+		if norms, ok := field["norms"].(map[string]interface{}); ok {
+			enabled, _ := norms["enabled"].(bool)
+			field["norms"] = enabled
+			t.note(path, changeChanged, "norms {\"enabled\": %t} -> %t", enabled, enabled)
+		}
+	}
+	for _, param := range sortedParams(field) {
+		if r, ok := renamedParams[param]; ok && t.dstMajor >= r.minMajor {
+			field[r.newName] = field[param]
+			delete(field, param)
+			t.note(path, changeChanged, "parameter %s renamed to %s", param, r.newName)
+		}
+		if minMajor, ok := removedParams[param]; ok && t.dstMajor >= minMajor {
+			delete(field, param)
+			t.note(path, changeRemoved, "parameter %s (not supported since Elasticsearch %d)", param, minMajor)
+		}
+	}
+	if fb, ok := typeFallbacks[fieldType]; ok && t.dstMajor < fb.minMajor {
+		field["type"] = fb.fallback
+		for _, param := range []string{"dims", "index", "similarity", "depth_limit", "value", "positive_score_impact"} {
+			delete(field, param)
+		}
+		if fb.fallback == "object" {
+			field["enabled"] = false
+		}
+		t.note(path, changeChanged, "type %s -> %s (not supported by Elasticsearch %d)", fieldType, fb.fallback, t.dstMajor)
+	}
+	// Elasticsearch 7부터 날짜 형식은 Joda 대신 java.time 패턴으로 해석되어, Y가 연도가
+	// 아니라 주 기준 연도가 됩니다. 자동으로 바꿀 수 없으므로 확인 대상으로만 알립니다.
+	if format, ok := field["format"].(string); ok && t.dstMajor >= 7 {
+		for _, pattern := range strings.Split(format, "||") {
+			if strings.ContainsAny(pattern, "-/:. ") && strings.Contains(pattern, "Y") {
+				t.note(path, changeReview, "date format %q: Y means week-based year in java.time; use y or u", pattern)
+			}
+		}
+	}
+	// migrate는 날짜를 RFC 3339 문자열로 다시 쓰므로, 그 문자열을 읽지 못하는 형식(예:
+	// epoch_millis)에는 strict_date_optional_time을 덧붙입니다.
+	if format, ok := field["format"].(string); ok && dateFieldTypes[fieldType] && !acceptsISODates(format) {
+		field["format"] = format + "||strict_date_optional_time"
+		t.note(path, changeChanged, "format %q -> %q (migrated dates are written as RFC 3339)", format, field["format"])
+	}
+}
+
+// dateFieldTypes는 format 매개변수로 날짜를 읽는 필드 타입입니다.
+var dateFieldTypes = map[string]bool{"date": true, "date_nanos": true, "date_range": true}
+
+// isoDateFormats는 RFC 3339 문자열을 읽는 내장 날짜 형식입니다.
+var isoDateFormats = map[string]bool{
+	"strict_date_optional_time": true, "date_optional_time": true,
+	"strict_date_optional_time_nanos": true, "iso8601": true,
+}
+
+// acceptsISODates 함수는 format의 형식 중 하나라도 RFC 3339 문자열을 읽는지 알려 줍니다.
+func acceptsISODates(format string) bool {
+	for _, pattern := range strings.Split(format, "||") {
+		if isoDateFormats[strings.TrimSpace(pattern)] {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedParams(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeMappingChanges 함수는 dry-run 보고서의 변경 사항 표를 씁니다.
+func writeMappingChanges(w io.Writer, srcMajor, dstMajor int, changes []mappingChange) {
+	fmt.Fprintf(w, "mapping translation Elasticsearch %d -> %d: %d change(s)\n", srcMajor, dstMajor, len(changes))
+	for _, c := range changes {
+		fmt.Fprintf(w, "  %-8s %-30s %s\n", c.Action, c.Path, c.Detail)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestTranslateMappingFromTypedStringMapping(t *testing.T) {
+	raw := json.RawMessage(`{"doc": {
+		"_all": {"enabled": false},
+		"properties": {
+			"title": {"type": "string", "include_in_all": true},
+			"tag":   {"type": "string", "index": "not_analyzed"},
+			"user":  {"properties": {"id": {"type": "string", "index": "no"}}},
+			"vec":   {"type": "dense_vector", "dims": 3}
+		}
+	}}`)
+	mapping, changes, err := translateMapping(raw, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"properties": map[string]interface{}{
+		"title": map[string]interface{}{"type": "text"},
+		"tag":   map[string]interface{}{"type": "keyword", "index": true},
+		"user": map[string]interface{}{"properties": map[string]interface{}{
+			"id": map[string]interface{}{"type": "keyword", "index": false},
+		}},
+		"vec": map[string]interface{}{"type": "float"},
+	}}
+	if !reflect.DeepEqual(mapping, want) {
+		t.Errorf("translated mapping = %v\nwant %v", mapping, want)
+	}
+	if len(changes) != 7 {
+		t.Errorf("changes = %v, want 7 (type, _all, include_in_all, 3 strings, dense_vector)", changes)
+	}
+	if _, ok := wrapMappingType(mapping, 6)["_doc"]; !ok {
+		t.Errorf("mapping for Elasticsearch 6 should be wrapped in _doc")
+	}
+}
+
+func TestTranslateMappingRejectsSeveralTypes(t *testing.T) {
+	raw := json.RawMessage(`{"a": {"properties": {}}, "b": {"properties": {}}}`)
+	if _, _, err := translateMapping(raw, 7); kindOf(err) != kindSchema {
+		t.Errorf("err = %v, want schema error", err)
+	}
+}
+
+func TestTranslateMappingFlagsWeekBasedYearFormats(t *testing.T) {
+	raw := json.RawMessage(`{"properties": {"day": {"type": "date", "format": "YYYY-MM-dd||epoch_millis"}}}`)
+	_, changes, err := translateMapping(raw, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var review []mappingChange
+	for _, c := range changes {
+		if c.Action == changeReview {
+			review = append(review, c)
+		}
+	}
+	if len(review) != 1 || review[0].Path != "day" {
+		t.Errorf("changes = %v, want one review item for day", changes)
+	}
+}
+
+func TestTranslateMappingAddsISODateFormat(t *testing.T) {
+	raw := json.RawMessage(`{"properties": {"at": {"type": "date", "format": "epoch_millis"}, "day": {"type": "date", "format": "yyyy-MM-dd||strict_date_optional_time"}, "seen": {"type": "date"}}}`)
+	mapping, _, err := translateMapping(raw, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	props := mapping["properties"].(map[string]interface{})
+	want := map[string]interface{}{"at": "epoch_millis||strict_date_optional_time", "day": "yyyy-MM-dd||strict_date_optional_time", "seen": nil}
+	for name, format := range want {
+		if got := props[name].(map[string]interface{})["format"]; got != format {
+			t.Errorf("%s format = %v, want %v", name, got, format)
+		}
+	}
+}
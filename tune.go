@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+// maxSampleLine은 표본 NDJSON 한 줄의 최대 크기입니다.
+const maxSampleLine = 64 << 20
+
+// tuneOptions는 tune 명령의 설정입니다. tune은 표본 문서로 여러 압축 설정의 시험 파일을
+// 써 보고, 전체 내보내기에 쓸 설정을 추천합니다.
+type tuneOptions struct {
+	input        string
+	mappingPath  string
+	codecs       stringListFlag
+	rowGroupRows stringListFlag
+	// maxSlowdown은 추천 대상이 될 수 있는, 가장 빠른 시험 대비 쓰기 시간의 배수입니다.
+	maxSlowdown float64
+	fields      fieldSelectionOptions
+}
+
+// codecTrial은 시험할 압축 코덱과 수준 하나입니다. level 0은 코덱 기본값입니다.
+type codecTrial struct {
+	codec string
+	level int
+}
+
+func (c codecTrial) String() string {
+	if c.level == 0 {
+		return c.codec
+	}
+	return c.codec + ":" + strconv.Itoa(c.level)
+}
+
+// tuneResult는 시험 파일 하나의 결과입니다.
+type tuneResult struct {
+	codec        codecTrial
+	rowGroupRows int64
+	size         int64
+	elapsed      time.Duration
+}
+
+// flags 함수는 이 결과를 재현하는 명령행 플래그를 반환합니다.
+func (r tuneResult) flags() string {
+	s := "--compression " + r.codec.codec
+	if r.codec.level != 0 {
+		s += " --compression-level " + strconv.Itoa(r.codec.level)
+	}
+	return s + " --row-group-rows " + strconv.FormatInt(r.rowGroupRows, 10)
+}
+
+func setupTune(fs *flag.FlagSet) func(ctx context.Context, report *runReport, args []string) error {
+	var o tuneOptions
+	for _, name := range []string{"input", "i"} {
+		fs.StringVar(&o.input, name, "", "sample documents, one JSON object per line (required)")
+	}
+	for _, name := range []string{"mapping", "m"} {
+		fs.StringVar(&o.mappingPath, name, "", "mapping JSON file describing the documents (required)")
+	}
+	fs.Var(&o.codecs, "codecs", "codec[:level] combinations to try (comma-separated or repeated; default snappy,lz4,gzip,zstd:1,zstd:3,zstd:9,zstd:19)")
+	fs.Var(&o.rowGroupRows, "row-group-rows", "row group sizes to try (comma-separated or repeated; default 65536,1048576)")
+	fs.Float64Var(&o.maxSlowdown, "max-slowdown", 3, "only recommend settings that write at most this many times slower than the fastest trial")
+	o.fields.bind(fs)
+
+	return func(ctx context.Context, report *runReport, args []string) error {
+		trials, rowGroups, err := o.validate(args)
+		if err != nil {
+			return err
+		}
+		schema, err := o.fields.loadMapping(o.mappingPath)
+		if err != nil {
+			return err
+		}
+		docs, err := readSampleDocuments(o.input)
+		if err != nil {
+			return err
+		}
+		report.RowsRead = int64(len(docs))
+
+		norm := newNormalizer(schema)
+		norm.widen(docs)
+		rec, rejected := norm.record(docs)
+		defer rec.Release()
+		if len(rejected) > 0 {
+			report.warnf("%d sample documents could not be converted and were left out of the trials", len(rejected))
+		}
+		if norm.dropped > 0 {
+			report.warnf("%d values could not be converted to their mapped type and were written as null", norm.dropped)
+		}
+
+		dir, err := os.MkdirTemp("", "es-schema-tune-")
+		if err != nil {
+			return configErrorf("creating trial directory: %w", err)
+		}
+		defer os.RemoveAll(dir)
+
+		var results []tuneResult
+		for _, rows := range rowGroups {
+			for _, c := range trials {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				r, err := runTrial(filepath.Join(dir, "trial.parquet"), rec, c, rows)
+				if err != nil {
+					return err
+				}
+				results = append(results, r)
+			}
+		}
+		writeTuneResults(os.Stdout, results, int64(len(docs)))
+		if best, ok := recommendTrial(results, o.maxSlowdown); ok {
+			fmt.Printf("\nrecommended: %s\n", best.flags())
+		}
+		return nil
+	}
+}
+
+func (o *tuneOptions) validate(args []string) ([]codecTrial, []int64, error) {
+	if len(args) > 0 {
+		return nil, nil, configErrorf("tune: unexpected arguments %v", args)
+	}
+	if o.input == "" || o.mappingPath == "" {
+		return nil, nil, configErrorf("tune: --input and --mapping are required")
+	}
+	if o.maxSlowdown < 1 {
+		return nil, nil, configErrorf("tune: --max-slowdown must be at least 1")
+	}
+	specs := []string(o.codecs)
+	if len(specs) == 0 {
+		specs = []string{"snappy", "lz4", "gzip", "zstd:1", "zstd:3", "zstd:9", "zstd:19"}
+	}
+	trials := make([]codecTrial, len(specs))
+	for i, spec := range specs {
+		c, err := parseCodecTrial(spec)
+		if err != nil {
+			return nil, nil, err
+		}
+		trials[i] = c
+	}
+	sizes := []string(o.rowGroupRows)
+	if len(sizes) == 0 {
+		sizes = []string{"65536", "1048576"}
+	}
+	rowGroups := make([]int64, len(sizes))
+	for i, s := range sizes {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n <= 0 {
+			return nil, nil, configErrorf("tune: --row-group-rows %q is not a positive number", s)
+		}
+		rowGroups[i] = n
+	}
+	if err := o.fields.load(); err != nil {
+		return nil, nil, err
+	}
+	return trials, rowGroups, nil
+}
+
+// parseCodecTrial 함수는 "zstd:9" 같은 codec[:level] 표기를 읽습니다.
+func parseCodecTrial(spec string) (codecTrial, error) {
+	name, levelStr, hasLevel := strings.Cut(spec, ":")
+	c := codecTrial{codec: strings.ToLower(name)}
+	if _, ok := codecs[c.codec]; !ok {
+		return c, configErrorf("tune: unknown codec %q (want uncompressed, snappy, gzip, brotli, lz4 or zstd)", name)
+	}
+	if hasLevel {
+		level, err := strconv.Atoi(levelStr)
+		if err != nil || level == 0 {
+			return c, configErrorf("tune: invalid compression level in %q", spec)
+		}
+		c.level = level
+	}
+	return c, nil
+}
+
+// readSampleDocuments 함수는 NDJSON 파일에서 문서를 읽습니다. 검색 결과를 그대로 저장한
+// 줄처럼 "_source"가 있으면 그 안의 문서를 씁니다.
+func readSampleDocuments(path string) ([]map[string]interface{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, configErrorf("opening sample: %w", err)
+	}
+	defer f.Close()
+
+	var docs []map[string]interface{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 1<<20), maxSampleLine)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		doc, err := decodeDocument(scanner.Bytes())
+		if err != nil {
+			return nil, dataErrorf("%s:%d: %w", path, line, err)
+		}
+		if source, ok := doc["_source"].(map[string]interface{}); ok {
+			doc = source
+		}
+		docs = append(docs, doc)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, dataErrorf("reading %s: %w", path, err)
+	}
+	if len(docs) == 0 {
+		return nil, dataErrorf("%s has no documents", path)
+	}
+	return docs, nil
+}
+
+// runTrial 함수는 rec을 주어진 설정으로 path에 써 보고 파일 크기와 걸린 시간을 잽니다.
+func runTrial(path string, rec arrow.Record, c codecTrial, rowGroupRows int64) (tuneResult, error) {
+	opts := &parquetOptions{
+		rowGroups:        rowGroupsPerRecord,
+		rowGroupRows:     rowGroupRows,
+		storeSchema:      true,
+		dictionary:       true,
+		compression:      c.codec,
+		compressionLevel: c.level,
+	}
+	start := time.Now()
+	w, err := createParquetFile(path, rec.Schema(), opts)
+	if err != nil {
+		return tuneResult{}, err
+	}
+	if err := w.write(rec); err != nil {
+		w.abort()
+		return tuneResult{}, err
+	}
+	if err := w.close(); err != nil {
+		return tuneResult{}, err
+	}
+	elapsed := time.Since(start)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return tuneResult{}, dataErrorf("checking trial file: %w", err)
+	}
+	os.Remove(path)
+	return tuneResult{codec: c, rowGroupRows: rowGroupRows, size: info.Size(), elapsed: elapsed}, nil
+}
+
+// recommendTrial 함수는 가장 빠른 시험보다 maxSlowdown배 넘게 느리지 않은 시험 중 가장
+// 작은 파일을 만든 설정을 고릅니다. 크기가 같으면 더 빠른 쪽을 고릅니다.
+func recommendTrial(results []tuneResult, maxSlowdown float64) (tuneResult, bool) {
+	if len(results) == 0 {
+		return tuneResult{}, false
+	}
+	fastest := results[0].elapsed
+	for _, r := range results[1:] {
+		if r.elapsed < fastest {
+			fastest = r.elapsed
+		}
+	}
+	limit := time.Duration(float64(fastest) * maxSlowdown)
+	var best tuneResult
+	found := false
+	for _, r := range results {
+		if r.elapsed > limit {
+			continue
+		}
+		if !found || r.size < best.size || (r.size == best.size && r.elapsed < best.elapsed) {
+			best, found = r, true
+		}
+	}
+	return best, found
+}
+
+// writeTuneResults 함수는 시험 결과를 파일 크기 순으로 표로 씁니다.
+func writeTuneResults(w io.Writer, results []tuneResult, docs int64) {
+	sorted := append([]tuneResult(nil), results...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].size < sorted[j].size })
+	fmt.Fprintf(w, "%d sample documents, %d trials\n", docs, len(sorted))
+	fmt.Fprintf(w, "  %-10s %14s %12s %10s %12s\n", "codec", "row-group-rows", "bytes", "bytes/doc", "write time")
+	for _, r := range sorted {
+		fmt.Fprintf(w, "  %-10s %14d %12d %10.1f %12s\n", r.codec, r.rowGroupRows, r.size, float64(r.size)/float64(docs), r.elapsed.Round(time.Microsecond))
+	}
+}
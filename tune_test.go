@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCodecTrial(t *testing.T) {
+	c, err := parseCodecTrial("ZSTD:9")
+	if err != nil || c != (codecTrial{codec: "zstd", level: 9}) {
+		t.Errorf("parseCodecTrial(ZSTD:9) = %v, %v", c, err)
+	}
+	for _, spec := range []string{"zip", "gzip:x", "gzip:0"} {
+		if _, err := parseCodecTrial(spec); err == nil {
+			t.Errorf("parseCodecTrial(%q) succeeded, want error", spec)
+		}
+	}
+}
+
+func TestRecommendTrialSkipsSlowSettings(t *testing.T) {
+	results := []tuneResult{
+		{codec: codecTrial{codec: "snappy"}, rowGroupRows: 65536, size: 1000, elapsed: 10 * time.Millisecond},
+		{codec: codecTrial{codec: "zstd", level: 3}, rowGroupRows: 65536, size: 700, elapsed: 20 * time.Millisecond},
+		{codec: codecTrial{codec: "zstd", level: 19}, rowGroupRows: 65536, size: 600, elapsed: 200 * time.Millisecond},
+	}
+	best, ok := recommendTrial(results, 3)
+	if !ok || best.codec.String() != "zstd:3" {
+		t.Fatalf("recommendTrial = %v, %v; want zstd:3", best.codec, ok)
+	}
+	if got, want := best.flags(), "--compression zstd --compression-level 3 --row-group-rows 65536"; got != want {
+		t.Errorf("flags = %q, want %q", got, want)
+	}
+	if best, _ := recommendTrial(results, 100); best.codec.level != 19 {
+		t.Errorf("with --max-slowdown 100, recommended %v, want zstd:19", best.codec)
+	}
+}
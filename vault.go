@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+)
+
+// maxTokenAttempts는 아직 쓰지 않은 토큰을 찾을 때까지 다시 뽑는 최대 횟수입니다.
+const maxTokenAttempts = 64
+
+// tokenVaultOptions는 tokenize 가림 규칙과 detokenize 명령이 쓰는 토큰 금고의 설정입니다.
+type tokenVaultOptions struct {
+	path string
+	key  string
+}
+
+func (o *tokenVaultOptions) bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.path, "token-vault", os.Getenv("ES_SCHEMA_TOKEN_VAULT"), "encrypted token vault file mapping the tokens of the tokenize masking rule to their original values; share it between runs so the same value always gets the same token (default $ES_SCHEMA_TOKEN_VAULT)")
+	fs.StringVar(&o.key, "token-vault-key", "", "AES-256 key of --token-vault as 32 random bytes in base64, such as the output of openssl rand -base64 32 (default $ES_SCHEMA_TOKEN_VAULT_KEY)")
+}
+
+// open 함수는 금고를 엽니다. lock이면 실행이 끝날 때까지 다른 실행이 금고를 고치지
+// 못하게 잠급니다.
+func (o *tokenVaultOptions) open(lock bool) (*tokenVault, error) {
+	if o.path == "" {
+		return nil, configErrorf("the tokenize masking rule and detokenize need --token-vault")
+	}
+	// 키는 -h에 드러나지 않도록 플래그 기본값이 아니라 여기서 환경 변수로 채웁니다.
+	key, err := base64.StdEncoding.DecodeString(firstNonEmpty(o.key, os.Getenv("ES_SCHEMA_TOKEN_VAULT_KEY")))
+	if err != nil || len(key) != 32 {
+		return nil, configErrorf("--token-vault-key must be 32 random bytes in base64 (openssl rand -base64 32)")
+	}
+	return openTokenVault(o.path, key, lock)
+}
+
+// tokenVaultFile은 금고 파일의 내용입니다. Ciphertext는 토큰에서 원래 값으로의 맵을 담은
+// JSON을 AES-256-GCM으로 암호화한 것입니다.
+type tokenVaultFile struct {
+	Version    int    `json:"version"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// tokenVault는 형식을 유지하는 토큰과 원래 값의 암호화된 대응표입니다. 값은 처음 볼 때
+// 숫자는 숫자로, 글자는 같은 대소문자의 글자로 바꾸고 나머지 문자는 그대로 둔 임의의
+// 토큰을 받습니다. 같은 금고를 쓰는 실행은 같은 값에 같은 토큰을 쓰므로 내보낸 파일끼리
+// 토큰으로 조인할 수 있고, 원래 값은 키를 가진 사람만 detokenize로 되찾습니다. 여러
+// 작업자가 함께 쓰므로 mu로 보호합니다.
+type tokenVault struct {
+	path string
+	aead cipher.AEAD
+	// lockPath는 lock으로 연 금고의 잠금 파일입니다.
+	lockPath string
+
+	mu     sync.Mutex
+	tokens map[string]string
+	values map[string]string
+	added  int
+}
+
+func openTokenVault(path string, key []byte, lock bool) (*tokenVault, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, configErrorf("token vault key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, configErrorf("token vault key: %w", err)
+	}
+	v := &tokenVault{path: path, aead: aead, tokens: make(map[string]string), values: make(map[string]string)}
+	if lock {
+		lockPath := path + ".lock"
+		f, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if errors.Is(err, os.ErrExist) {
+			return nil, configErrorf("token vault %s is in use by another run; remove %s if that run is gone", path, lockPath)
+		}
+		if err != nil {
+			return nil, configErrorf("locking token vault: %w", err)
+		}
+		fmt.Fprintf(f, "%d\n", os.Getpid())
+		f.Close()
+		v.lockPath = lockPath
+	}
+	if err := v.load(); err != nil {
+		v.unlock()
+		return nil, err
+	}
+	return v, nil
+}
+
+// load 함수는 금고 파일을 읽어 풉니다. 파일이 없으면 빈 금고입니다.
+func (v *tokenVault) load() error {
+	data, err := os.ReadFile(v.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return configErrorf("reading token vault: %w", err)
+	}
+	var f tokenVaultFile
+	if err := json.Unmarshal(data, &f); err != nil || f.Version != 1 {
+		return configErrorf("token vault %s is not a version 1 vault file", v.path)
+	}
+	plain, err := v.aead.Open(nil, f.Nonce, f.Ciphertext, nil)
+	if err != nil {
+		return configErrorf("token vault %s cannot be decrypted with --token-vault-key", v.path)
+	}
+	if err := json.Unmarshal(plain, &v.tokens); err != nil {
+		return configErrorf("decoding token vault %s: %w", v.path, err)
+	}
+	for token, value := range v.tokens {
+		v.values[value] = token
+	}
+	return nil
+}
+
+// tokenize 함수는 value의 토큰을 반환합니다. 처음 보는 값이면 쓰지 않은 토큰을 새로
+// 뽑습니다. 글자와 숫자가 짧아 쓰지 않은 토큰이 남지 않으면 오류입니다.
+func (v *tokenVault) tokenize(value string) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if token, ok := v.values[value]; ok {
+		return token, nil
+	}
+	for attempt := 0; attempt < maxTokenAttempts; attempt++ {
+		token, err := randomToken(value)
+		if err != nil {
+			return "", err
+		}
+		if _, taken := v.tokens[token]; taken || (token == value && hasAlphanumeric(value)) {
+			continue
+		}
+		v.tokens[token], v.values[value] = value, token
+		v.added++
+		return token, nil
+	}
+	return "", dataErrorf("token vault: no unused token left for a value of %d characters", len([]rune(value)))
+}
+
+// detokenize 함수는 토큰의 원래 값을 반환합니다.
+func (v *tokenVault) detokenize(token string) (string, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	value, ok := v.tokens[token]
+	return value, ok
+}
+
+// close 함수는 새 토큰이 있으면 금고를 새 nonce로 다시 암호화해 쓰고 잠금을 풉니다. 중간에
+// 실패해도 이전 금고가 남도록 임시 파일에 쓴 뒤 이름을 바꿉니다.
+func (v *tokenVault) close() error {
+	defer v.unlock()
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.added == 0 {
+		return nil
+	}
+	plain, err := json.Marshal(v.tokens)
+	if err != nil {
+		return err
+	}
+	f := tokenVaultFile{Version: 1, Nonce: make([]byte, v.aead.NonceSize())}
+	if _, err := rand.Read(f.Nonce); err != nil {
+		return err
+	}
+	f.Ciphertext = v.aead.Seal(nil, f.Nonce, plain, nil)
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	tmp := v.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return configErrorf("writing token vault: %w", err)
+	}
+	if err := os.Rename(tmp, v.path); err != nil {
+		os.Remove(tmp)
+		return configErrorf("writing token vault: %w", err)
+	}
+	v.added = 0
+	return nil
+}
+
+func (v *tokenVault) unlock() {
+	if v.lockPath != "" {
+		os.Remove(v.lockPath)
+		v.lockPath = ""
+	}
+}
+
+// randomToken 함수는 value와 형식이 같은 임의의 토큰을 만듭니다. 숫자는 숫자로, ASCII
+// 소문자와 대문자는 같은 대소문자의 글자로 바꾸고, 나머지 문자(구분자, ASCII가 아닌 글자)는
+// 그대로 둡니다.
+func randomToken(value string) (string, error) {
+	var sb strings.Builder
+	for _, r := range value {
+		var base rune
+		var n int64
+		switch {
+		case r >= '0' && r <= '9':
+			base, n = '0', 10
+		case r >= 'a' && r <= 'z':
+			base, n = 'a', 26
+		case r >= 'A' && r <= 'Z':
+			base, n = 'A', 26
+		default:
+			sb.WriteRune(r)
+			continue
+		}
+		i, err := rand.Int(rand.Reader, big.NewInt(n))
+		if err != nil {
+			return "", err
+		}
+		sb.WriteRune(base + rune(i.Int64()))
+	}
+	return sb.String(), nil
+}
+
+// hasAlphanumeric 함수는 s에 randomToken이 바꾸는 문자가 있는지 알려 줍니다.
+func hasAlphanumeric(s string) bool {
+	for _, r := range s {
+		if r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+			return true
+		}
+	}
+	return false
+}
+
+func setupDetokenize(fs *flag.FlagSet) func(ctx context.Context, report *runReport, args []string) error {
+	var o tokenVaultOptions
+	o.bind(fs)
+	return func(ctx context.Context, report *runReport, args []string) error {
+		vault, err := o.open(false)
+		if err != nil {
+			return err
+		}
+		tokens := args
+		if len(tokens) == 0 {
+			sc := bufio.NewScanner(os.Stdin)
+			for sc.Scan() {
+				if t := strings.TrimSpace(sc.Text()); t != "" {
+					tokens = append(tokens, t)
+				}
+			}
+			if err := sc.Err(); err != nil {
+				return configErrorf("reading tokens: %w", err)
+			}
+		}
+		unknown := 0
+		for _, token := range tokens {
+			value, ok := vault.detokenize(token)
+			if !ok {
+				unknown++
+				report.warnf("%s is not a token of %s", token, o.path)
+				continue
+			}
+			fmt.Printf("%s\t%s\n", token, value)
+		}
+		report.RowsRead = int64(len(tokens))
+		if unknown > 0 {
+			return dataErrorf("%d of %d tokens are not in %s", unknown, len(tokens), o.path)
+		}
+		return nil
+	}
+}
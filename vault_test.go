@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestTokenVault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.vault")
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))
+	o := tokenVaultOptions{path: path, key: key}
+
+	v, err := o.open(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.open(true); exitCodeFor(err) != exitConfigError || !strings.Contains(err.Error(), "in use by another run") {
+		t.Errorf("second lock = %v", err)
+	}
+	card, err := v.tokenize("4111-1111-1111-1111")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^\d{4}-\d{4}-\d{4}-\d{4}$`).MatchString(card) || card == "4111-1111-1111-1111" {
+		t.Errorf("card token = %q, want the same format", card)
+	}
+	name, _ := v.tokenize("Kim Ji-ho")
+	if !regexp.MustCompile(`^[A-Z][a-z]{2} [A-Z][a-z]-[a-z]{2}$`).MatchString(name) {
+		t.Errorf("name token = %q, want the same format", name)
+	}
+	if again, _ := v.tokenize("4111-1111-1111-1111"); again != card {
+		t.Errorf("second token = %q, want %q", again, card)
+	}
+	if err := v.close(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); bytes.Contains(data, []byte("4111")) {
+		t.Error("the vault file holds a value in plain text")
+	}
+
+	// 다시 연 금고는 같은 토큰을 쓰고 원래 값을 돌려줍니다.
+	v, err = o.open(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer v.close()
+	if again, _ := v.tokenize("4111-1111-1111-1111"); again != card {
+		t.Errorf("token after reopening = %q, want %q", again, card)
+	}
+	if value, ok := v.detokenize(name); !ok || value != "Kim Ji-ho" {
+		t.Errorf("detokenize = %q, %v", value, ok)
+	}
+
+	wrong := tokenVaultOptions{path: path, key: base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{8}, 32))}
+	if _, err := wrong.open(false); err == nil || !strings.Contains(err.Error(), "cannot be decrypted") {
+		t.Errorf("wrong key = %v", err)
+	}
+	if _, err := (&tokenVaultOptions{path: path, key: "short"}).open(false); exitCodeFor(err) != exitConfigError {
+		t.Errorf("short key = %v", err)
+	}
+
+	t.Setenv("ES_SCHEMA_TOKEN_VAULT_KEY", key)
+	var fromEnv tokenVaultOptions
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fromEnv.bind(fs)
+	if f := fs.Lookup("token-vault-key"); f.DefValue != "" {
+		t.Errorf("--token-vault-key default = %q, want the key kept out of -h", f.DefValue)
+	}
+	fromEnv.path = path
+	v, err = fromEnv.open(false)
+	if err != nil {
+		t.Fatalf("key from $ES_SCHEMA_TOKEN_VAULT_KEY: %v", err)
+	}
+	defer v.close()
+}
+
+func TestTokenVaultRunsOutOfTokens(t *testing.T) {
+	v, err := openTokenVault(filepath.Join(t.TempDir(), "v"), bytes.Repeat([]byte{1}, 32), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 다른 값들이 0부터 8까지의 토큰을 쓰고 있으면 9는 자기 자신밖에 남지 않습니다.
+	for d := '0'; d < '9'; d++ {
+		v.tokens[string(d)] = "value-" + string(d)
+	}
+	if _, err := v.tokenize("9"); exitCodeFor(err) != exitDataError {
+		t.Errorf("tokenize with no token left = %v, want a data error", err)
+	}
+	if token, _ := v.tokenize("--"); token != "--" {
+		t.Errorf("token of a value without letters or digits = %q", token)
+	}
+}
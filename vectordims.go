@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/apache/arrow/go/v10/arrow"
+
+	"es-schema/esschema"
+)
+
+// vectorDimsOptions는 매핑에 dims가 없는 dense_vector 필드를 다루는 --vector-dims
+// 플래그입니다. error는 스키마를 만들 때 실패하고, infer는 Elasticsearch처럼 첫 배치에서 처음
+// 본 벡터의 길이로 fixed-size list를 정합니다.
+type vectorDimsOptions struct {
+	mode string
+}
+
+func (o *vectorDimsOptions) bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.mode, "vector-dims", "error", "dense_vector fields without dims in the mapping: error (fail, since the column length is unknown) or infer (take the length from the first vector in the first batch of documents; a field with no vector there stays a variable-length list)")
+}
+
+func (o *vectorDimsOptions) validate() error {
+	switch o.mode {
+	case "", "error", "infer":
+		return nil
+	}
+	return configErrorf("unknown --vector-dims %q (want error or infer)", o.mode)
+}
+
+// check 함수는 infer가 아닐 때 source의 fields에 dims가 없는 dense_vector 필드가 있으면
+// 실패합니다.
+func (o *vectorDimsOptions) check(fields []arrow.Field, source string) error {
+	if o.mode == "infer" {
+		return nil
+	}
+	if paths := esschema.UnsizedVectors(fields); len(paths) > 0 {
+		return schemaErrorf("dense_vector fields of %s have no dims: %s; add dims to the mapping or use --vector-dims infer to take them from the documents", source, strings.Join(paths, ", "))
+	}
+	return nil
+}
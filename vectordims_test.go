@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+func TestVectorDimsValidate(t *testing.T) {
+	for _, mode := range []string{"error", "infer"} {
+		if err := (&vectorDimsOptions{mode: mode}).validate(); err != nil {
+			t.Errorf("--vector-dims %s: %v", mode, err)
+		}
+	}
+	if err := (&vectorDimsOptions{mode: "zero"}).validate(); exitCodeFor(err) != exitConfigError {
+		t.Errorf("--vector-dims zero = %v, want a config error", err)
+	}
+}
+
+func TestVectorDimsInfer(t *testing.T) {
+	mapping := []byte(`{"properties": {
+		"embedding": {"type": "dense_vector"},
+		"late": {"type": "dense_vector", "element_type": "byte"}
+	}}`)
+	in := mappingInputOptions{vectorDims: vectorDimsOptions{mode: "error"}}
+	if _, err := in.schemaOf(mapping, "vectors.json"); err == nil || !strings.Contains(err.Error(), "embedding, late") {
+		t.Fatalf("--vector-dims error = %v, want an error naming both fields", err)
+	}
+	in.vectorDims.mode = "infer"
+	schema, err := in.schemaOf(mapping, "vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := newNormalizer(schema)
+	first := []map[string]interface{}{
+		{"embedding": []interface{}{0.5, 1.5, 2.5}},
+		{"embedding": []interface{}{1.0, 2.0}},
+	}
+	if changed := n.widen(first); changed != "embedding" {
+		t.Errorf("first widen changed %q, want embedding", changed)
+	}
+	// 첫 배치에 벡터가 없던 필드는 길이 없는 리스트로 남고, 스키마는 더 바뀌지 않습니다.
+	if changed := n.widen([]map[string]interface{}{{"late": []interface{}{1.0, 2.0}}}); changed != "" {
+		t.Errorf("later widen changed %q", changed)
+	}
+	want := []arrow.DataType{
+		arrow.FixedSizeListOf(3, arrow.PrimitiveTypes.Float32),
+		arrow.ListOf(arrow.PrimitiveTypes.Int8),
+	}
+	for i, f := range n.schema.Fields() {
+		if !arrow.TypeEqual(f.Type, want[i]) {
+			t.Errorf("%s = %s, want %s", f.Name, f.Type, want[i])
+		}
+	}
+	rec, rejected := n.record(first)
+	defer rec.Release()
+	if rec.NumRows() != 2 || len(rejected) != 0 || n.dropped != 1 {
+		t.Errorf("rows = %d, rejected = %d, dropped = %d; want 2, 0, 1 (the short vector)", rec.NumRows(), len(rejected), n.dropped)
+	}
+}
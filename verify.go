@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+func setupVerify(fs *flag.FlagSet) func(ctx context.Context, report *runReport, args []string) error {
+	mappingPath := fs.String("mapping", "", "mapping JSON file to check column types against")
+	var columns stringListFlag
+	fs.Var(&columns, "columns", "only read these columns; dotted paths select nested fields (comma-separated or repeated)")
+
+	return func(ctx context.Context, report *runReport, args []string) error {
+		if len(args) == 0 {
+			return configErrorf("verify: no Parquet files given")
+		}
+		var expected *arrow.Schema
+		if *mappingPath != "" {
+			var err error
+			if expected, err = loadMappingFile(*mappingPath); err != nil {
+				return err
+			}
+		}
+		for _, path := range args {
+			if err := verifyParquetFile(ctx, path, columns, expected, report); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// verifyParquetFile 함수는 파일의 선택된 컬럼을 끝까지 읽어 행 수가 메타데이터와 일치하는지,
+// 그리고 expected가 주어진 경우 컬럼 타입이 매핑과 호환되는지 확인합니다.
+func verifyParquetFile(ctx context.Context, path string, columns []string, expected *arrow.Schema, report *runReport) error {
+	pf, err := openParquetFile(path)
+	if err != nil {
+		return err
+	}
+	defer pf.Close()
+
+	var rows int64
+	var nulls []int64
+	sc, err := pf.records(ctx, columns, func(rec arrow.Record) error {
+		if nulls == nil {
+			nulls = make([]int64, rec.NumCols())
+		}
+		for i, col := range rec.Columns() {
+			nulls[i] += int64(col.NullN())
+		}
+		rows += rec.NumRows()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	report.RowsRead += rows
+
+	if rows != pf.NumRows() {
+		return dataErrorf("%s: read %d rows but file metadata declares %d", path, rows, pf.NumRows())
+	}
+
+	fmt.Printf("%s: %d rows\n", path, rows)
+	for i, field := range sc.Fields() {
+		var n int64
+		if nulls != nil {
+			n = nulls[i]
+		}
+		fmt.Printf("  %s: %s (nulls %d)\n", field.Name, field.Type, n)
+	}
+
+	if expected == nil {
+		return nil
+	}
+	var problems []string
+	// --geo-wkb의 지오메트리 컬럼은 매핑의 geo 타입 대신 WKB 바이너리입니다.
+	geo := geoValues(sc)
+	for _, field := range sc.Fields() {
+		if isOverflowColumn(field) || isTokenColumn(field) || isDerivedColumn(field) || isMetadataColumn(field.Name) || field.Name == deletedColumn || field.Name == docHashColumn {
+			continue
+		}
+		if geo[field.Name] != nil && field.Type.ID() == arrow.BINARY {
+			continue
+		}
+		want, ok := expected.FieldsByName(field.Name)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("column %q is not in the mapping (mapping has %s)", field.Name, describeColumns(expected)))
+			continue
+		}
+		if !compatibleType(want[0].Type, field.Type) {
+			problems = append(problems, fmt.Sprintf("column %q has type %s, mapping expects %s", field.Name, field.Type, want[0].Type))
+		}
+	}
+	if len(problems) > 0 {
+		return schemaErrorf("%s does not match the mapping:\n  %s", path, strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// compatibleType 함수는 파일의 타입 got이 매핑에서 만든 타입 want와 호환되는지 확인합니다.
+// 샘플 데이터에 따라 리스트로 승격된 필드(adjustSchemaForLists)도 호환되는 것으로 봅니다.
+func compatibleType(want, got arrow.DataType) bool {
+	if arrow.TypeEqual(want, got) {
+		return true
+	}
+	if want.ID() == arrow.FLOAT16 && got.ID() == arrow.FLOAT32 {
+		// half_float 컬럼은 float32로 씁니다(parquetFileSchema).
+		return true
+	}
+	if got.ID() == arrow.LIST && want.ID() != arrow.LIST {
+		return compatibleType(want, got.(*arrow.ListType).Elem())
+	}
+	if got.ID() == arrow.LIST && want.ID() == arrow.LIST {
+		// nested 필드의 구조체 리스트는 원소 구조체 안의 필드가 승격될 수 있습니다.
+		return compatibleType(want.(*arrow.ListType).Elem(), got.(*arrow.ListType).Elem())
+	}
+	if want.ID() == arrow.STRUCT && got.ID() == arrow.STRUCT {
+		wantStruct := want.(*arrow.StructType)
+		for _, gf := range got.(*arrow.StructType).Fields() {
+			wf, ok := wantStruct.FieldByName(gf.Name)
+			if !ok || !compatibleType(wf.Type, gf.Type) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
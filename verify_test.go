@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/parquet"
+	"github.com/apache/arrow/go/v10/parquet/pqarrow"
+)
+
+// writeMappingParquet 함수는 mapping의 스키마로 docs를 쓴 Parquet 파일의 경로를 반환합니다.
+// 내보낸 파일처럼 매핑을 스키마 메타데이터에 넣습니다.
+func writeMappingParquet(t *testing.T, mapping string, docs []map[string]interface{}) string {
+	t.Helper()
+	schema, err := schemaFromMapping([]byte(mapping))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schema, err = withMappingMetadata(schema, []byte(mapping)); err != nil {
+		t.Fatal(err)
+	}
+	return writeSchemaParquet(t, schema, docs)
+}
+
+// writeSchemaParquet 함수는 schema로 docs를 쓴 Parquet 파일의 경로를 반환합니다.
+func writeSchemaParquet(t *testing.T, schema *arrow.Schema, docs []map[string]interface{}) string {
+	t.Helper()
+	rec, _, err := createArrowRecord(context.Background(), schema, docs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Release()
+
+	path := filepath.Join(t.TempDir(), "docs.parquet")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w, err := pqarrow.NewFileWriter(schema, f, parquet.NewWriterProperties(), pqarrow.NewArrowWriterProperties(pqarrow.WithStoreSchema()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(rec); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestVerifyColumns(t *testing.T) {
+	path := writeMappingParquet(t, `{"properties": {"count": {"type": "long"}, "user": {"properties": {"name": {"type": "keyword"}, "age": {"type": "integer"}}}}}`,
+		[]map[string]interface{}{
+			{"count": 1, "user": map[string]interface{}{"name": "kim", "age": 30}},
+			{"count": 2, "user": map[string]interface{}{"name": "lee", "age": 41}},
+		})
+
+	f, err := openParquetFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	sc, err := f.records(context.Background(), []string{"user.name"}, func(arrow.Record) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, ok := sc.FieldsByName("user")
+	if len(sc.Fields()) != 1 || !ok || len(user[0].Type.(*arrow.StructType).Fields()) != 1 {
+		t.Errorf("--columns user.name read %s", sc)
+	}
+
+	// count의 타입이 매핑과 다르지만 --columns로 읽지 않으면 확인하지 않습니다.
+	expected, err := schemaFromMapping([]byte(`{"properties": {"count": {"type": "keyword"}, "user": {"properties": {"name": {"type": "keyword"}, "age": {"type": "integer"}}}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := newRunReport("verify")
+	if err := verifyParquetFile(context.Background(), path, []string{"user.name"}, expected, report); err != nil || report.RowsRead != 2 {
+		t.Errorf("verify --columns user.name = %v, rows = %d", err, report.RowsRead)
+	}
+	if err := verifyParquetFile(context.Background(), path, nil, expected, newRunReport("verify")); exitCodeFor(err) != exitSchemaError {
+		t.Errorf("verify of every column = %v, want a schema error for count", err)
+	}
+	if err := verifyParquetFile(context.Background(), path, []string{"user.email"}, expected, newRunReport("verify")); exitCodeFor(err) != exitConfigError {
+		t.Errorf("verify --columns user.email = %v, want a config error", err)
+	}
+}
@@ -0,0 +1,55 @@
+// Thin JavaScript wrapper around the es-schema WebAssembly build.
+//
+// Build the module and copy Go's loader next to this file:
+//
+//   GOOS=js GOARCH=wasm go build -tags noasm -o wasm/es-schema.wasm .
+//   cp "$(go env GOROOT)/lib/wasm/wasm_exec.js" wasm/   # misc/wasm before Go 1.24
+//
+// The noasm tag is required: Arrow's assembly kernels have no js/wasm version.
+//
+// Then, after loading wasm_exec.js:
+//
+//   import { load } from './es-schema.js';
+//   const esSchema = await load();
+//   const fields = await esSchema.previewMapping(mapping);
+//   const { schema, rows, rejected } = await esSchema.previewConversion(mapping, docs);
+//
+// mapping may be a JSON string or an object; docs may be an array of objects,
+// a JSON array string or NDJSON.
+
+function call(fn, ...args) {
+  const out = JSON.parse(fn(...args));
+  if (out.error !== undefined) {
+    const err = new Error(out.error);
+    err.kind = out.kind;
+    throw err;
+  }
+  return out.result;
+}
+
+function toJSON(v) {
+  return typeof v === 'string' ? v : JSON.stringify(v);
+}
+
+export async function load(url = new URL('./es-schema.wasm', import.meta.url)) {
+  if (typeof globalThis.Go !== 'function') {
+    throw new Error('load wasm_exec.js before es-schema.js');
+  }
+  const go = new globalThis.Go();
+  const ready = new Promise((resolve) => {
+    globalThis.addEventListener('es-schema-ready', resolve, { once: true });
+  });
+  const { instance } = await WebAssembly.instantiateStreaming(fetch(url), go.importObject);
+  go.run(instance);
+  await ready;
+
+  const api = globalThis.esSchemaGo;
+  return {
+    async previewMapping(mapping) {
+      return call(api.previewMapping, toJSON(mapping));
+    },
+    async previewConversion(mapping, docs) {
+      return call(api.previewConversion, toJSON(mapping), toJSON(docs));
+    },
+  };
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+)
+
+// watchOptions는 watch 명령의 설정입니다. watch는 매핑 디렉터리의 *.json 파일을 살피다가
+// 바뀐 매핑의 산출물(--artifacts)을 --out-dir에 다시 만듭니다. 매핑을 고치면서 스키마와
+// DDL을 바로 확인하는 로컬 개발용으로, 잘못된 매핑은 알리기만 하고 계속 살핍니다.
+type watchOptions struct {
+	in        mappingInputOptions
+	dir       string
+	outDir    string
+	artifacts stringListFlag
+	interval  time.Duration
+	once      bool
+}
+
+// watchArtifactHelp는 --artifacts가 받는 산출물입니다.
+const watchArtifactHelp = "schema (<name>.schema.txt), schema-json (<name>.schema.json), avro (<name>.avsc) or ddl:bigquery, ddl:snowflake, ddl:trino (<name>.<dialect>.sql with the mapping name as the table)"
+
+func setupWatch(fs *flag.FlagSet) func(ctx context.Context, report *runReport, args []string) error {
+	var o watchOptions
+	fs.StringVar(&o.dir, "dir", "", "directory of mapping files (*.json) to watch (required)")
+	fs.StringVar(&o.outDir, "out-dir", "", "directory to write the artifacts to (required)")
+	fs.Var(&o.artifacts, "artifacts", "artifacts to regenerate for each changed mapping (comma-separated or repeated; default schema): "+watchArtifactHelp)
+	fs.DurationVar(&o.interval, "interval", time.Second, "check the mapping files for changes this often")
+	fs.BoolVar(&o.once, "once", false, "generate the artifacts of every mapping once and exit")
+	o.in.ipFormat.bind(fs)
+	o.in.scaled.bind(fs)
+	o.in.tsUnit.bind(fs)
+	o.in.flattened.bind(fs)
+	o.in.geoShape.bind(fs)
+	o.in.vectorDims.bind(fs)
+
+	return func(ctx context.Context, report *runReport, args []string) error {
+		if err := o.validate(args); err != nil {
+			return err
+		}
+		w := &mappingWatcher{opts: &o, seen: make(map[string]fileStamp), written: make(map[string]bool)}
+		defer w.record(report)
+		failed := w.cycle()
+		if o.once {
+			if failed > 0 {
+				return schemaErrorf("watch: %d mappings failed", failed)
+			}
+			return nil
+		}
+		fmt.Printf("watch: watching %s every %s, writing %s to %s\n", o.dir, o.interval, o.artifacts.String(), o.outDir)
+		t := time.NewTicker(o.interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-t.C:
+				w.cycle()
+			}
+		}
+	}
+}
+
+func (o *watchOptions) validate(args []string) error {
+	if len(args) > 0 {
+		return configErrorf("watch: unexpected arguments %v", args)
+	}
+	if o.dir == "" || o.outDir == "" {
+		return configErrorf("watch: --dir and --out-dir are required")
+	}
+	if info, err := os.Stat(o.dir); err != nil || !info.IsDir() {
+		return configErrorf("watch: --dir %s is not a directory", o.dir)
+	}
+	if o.interval <= 0 {
+		return configErrorf("watch: --interval must be positive")
+	}
+	if len(o.artifacts) == 0 {
+		o.artifacts = stringListFlag{"schema"}
+	}
+	for _, a := range o.artifacts {
+		if _, err := watchArtifactFile("mapping", a); err != nil {
+			return err
+		}
+	}
+	for _, v := range []interface{ validate() error }{&o.in.ipFormat, &o.in.scaled, &o.in.tsUnit, &o.in.flattened, &o.in.geoShape, &o.in.vectorDims} {
+		if err := v.validate(); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(o.outDir, 0o755); err != nil {
+		return configErrorf("watch: creating --out-dir: %w", err)
+	}
+	return nil
+}
+
+// watchArtifactFile 함수는 매핑 name의 산출물 artifact를 쓸 파일 이름을 반환합니다.
+func watchArtifactFile(name, artifact string) (string, error) {
+	switch {
+	case artifact == "schema":
+		return schemaFileName(name, "text"), nil
+	case artifact == "schema-json":
+		return schemaFileName(name, "json"), nil
+	case artifact == "avro":
+		return name + ".avsc", nil
+	case strings.HasPrefix(artifact, "ddl:"):
+		dialect := strings.TrimPrefix(artifact, "ddl:")
+		if _, ok := sqlDialects[dialect]; ok {
+			return name + "." + dialect + ".sql", nil
+		}
+	}
+	return "", configErrorf("watch: unknown artifact %q (want %s)", artifact, watchArtifactHelp)
+}
+
+// fileStamp는 매핑 파일이 바뀌었는지 알아보는 수정 시각과 크기입니다.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// mappingWatcher는 watch가 마지막으로 본 매핑 파일들의 상태입니다.
+type mappingWatcher struct {
+	opts *watchOptions
+	seen map[string]fileStamp
+	// written은 지금까지 쓴 산출물 경로로, 끝날 때 보고서에 한 번씩 남깁니다.
+	written map[string]bool
+}
+
+// cycle 함수는 매핑 디렉터리를 한 번 살펴 새로 생기거나 바뀐 매핑의 산출물을 만들고, 사라진
+// 매핑의 산출물을 지웁니다. 실패한 매핑의 수를 반환합니다.
+func (w *mappingWatcher) cycle() int {
+	paths, err := filepath.Glob(filepath.Join(w.opts.dir, "*.json"))
+	if err != nil {
+		fmt.Printf("watch: %v\n", err)
+		return 1
+	}
+	sort.Strings(paths)
+	failed := 0
+	present := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		if isSchemaArtifact(path, w.opts.outDir) {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		present[path] = true
+		stamp := fileStamp{modTime: info.ModTime(), size: info.Size()}
+		if old, ok := w.seen[path]; ok && old == stamp {
+			continue
+		}
+		w.seen[path] = stamp
+		files, err := w.generate(path)
+		switch {
+		case err != nil:
+			failed++
+			fmt.Printf("watch: %s: %v\n", path, err)
+		case len(files) > 0:
+			fmt.Printf("watch: %s: wrote %s\n", path, strings.Join(files, ", "))
+		}
+	}
+	for path := range w.seen {
+		if !present[path] {
+			delete(w.seen, path)
+			w.remove(path)
+		}
+	}
+	return failed
+}
+
+// generate 함수는 매핑 파일 하나의 산출물을 모두 만들고, 내용이 바뀌어 새로 쓴 파일의 이름을
+// 반환합니다. 내용이 같은 파일은 다시 쓰지 않으므로 산출물을 살피는 다른 도구가 불필요하게
+// 다시 돌지 않습니다.
+func (w *mappingWatcher) generate(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := w.opts.in.schemaOf(data, path)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	var files []string
+	for _, artifact := range w.opts.artifacts {
+		file, _ := watchArtifactFile(name, artifact)
+		var out []byte
+		switch {
+		case artifact == "schema":
+			out, err = renderSchema(schema, "text")
+		case artifact == "schema-json":
+			out, err = renderSchema(schema, "json")
+		case artifact == "avro":
+			names := nameOptions{sanitize: true, collision: collisionSuffix}
+			sanitized, _, nameErr := names.apply(schema)
+			if nameErr != nil {
+				return nil, nameErr
+			}
+			out, err = avroSchema(names.clean(name), sanitized.Fields())
+		default:
+			out, err = watchDDL(sqlDialects[strings.TrimPrefix(artifact, "ddl:")], name, schema.Fields())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", artifact, err)
+		}
+		target := filepath.Join(w.opts.outDir, file)
+		w.written[target] = true
+		if old, err := os.ReadFile(target); err == nil && bytes.Equal(old, out) {
+			continue
+		}
+		if err := writeFileAtomic(target, out); err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// watchDDL 함수는 ddl 명령의 기본 설정(예약어는 따옴표, 대소문자 충돌은 _2 접미사)으로
+// table의 CREATE TABLE 문을 만듭니다.
+func watchDDL(d *sqlDialect, table string, fields []arrow.Field) ([]byte, error) {
+	o := ddlOptions{reserved: reservedQuote, caseCollision: collisionSuffix}
+	var renames []fieldRename
+	resolved, err := o.resolveNames(d, fields, "", "", &renames, newRunReport("ddl"))
+	if err != nil {
+		return nil, err
+	}
+	ddl, err := createTableDDL(d, table, resolved)
+	return []byte(ddl), err
+}
+
+// remove 함수는 사라진 매핑 파일의 산출물을 지웁니다.
+func (w *mappingWatcher) remove(path string) {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	var removed []string
+	for _, artifact := range w.opts.artifacts {
+		file, _ := watchArtifactFile(name, artifact)
+		target := filepath.Join(w.opts.outDir, file)
+		delete(w.written, target)
+		if err := os.Remove(target); err == nil {
+			removed = append(removed, file)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			fmt.Printf("watch: %v\n", err)
+		}
+	}
+	if len(removed) > 0 {
+		fmt.Printf("watch: %s was removed: deleted %s\n", path, strings.Join(removed, ", "))
+	}
+}
+
+// record 함수는 쓴 산출물을 보고서에 남깁니다.
+func (w *mappingWatcher) record(report *runReport) {
+	paths := make([]string, 0, len(w.written))
+	for path := range w.written {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		report.addCopyFile(path, 0)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchArtifactFile(t *testing.T) {
+	for artifact, want := range map[string]string{
+		"schema":       "logs.schema.txt",
+		"schema-json":  "logs.schema.json",
+		"avro":         "logs.avsc",
+		"ddl:bigquery": "logs.bigquery.sql",
+		"ddl:trino":    "logs.trino.sql",
+	} {
+		if got, err := watchArtifactFile("logs", artifact); err != nil || got != want {
+			t.Errorf("watchArtifactFile(%q) = %q, %v; want %q", artifact, got, err, want)
+		}
+	}
+	for _, artifact := range []string{"ddl:postgres", "ddl", "parquet"} {
+		if _, err := watchArtifactFile("logs", artifact); exitCodeFor(err) != exitConfigError {
+			t.Errorf("watchArtifactFile(%q) = %v, want a config error", artifact, err)
+		}
+	}
+}
+
+func TestWatchValidate(t *testing.T) {
+	dir := t.TempDir()
+	for _, o := range []watchOptions{
+		{outDir: dir, interval: time.Second},
+		{dir: filepath.Join(dir, "missing"), outDir: dir, interval: time.Second},
+		{dir: dir, outDir: dir},
+		{dir: dir, outDir: dir, interval: time.Second, artifacts: stringListFlag{"ddl:postgres"}},
+	} {
+		if err := o.validate(nil); exitCodeFor(err) != exitConfigError {
+			t.Errorf("validate(%+v) = %v, want a config error", o, err)
+		}
+	}
+}
+
+func TestWatchCycle(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	write := func(name, body string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("logs.json", `{"properties": {"message": {"type": "text"}}}`)
+	write("broken.json", `{"properties": `)
+	o := watchOptions{dir: dir, outDir: out, interval: time.Second, artifacts: stringListFlag{"schema", "ddl:trino"}}
+	if err := o.validate(nil); err != nil {
+		t.Fatal(err)
+	}
+	w := &mappingWatcher{opts: &o, seen: make(map[string]fileStamp), written: make(map[string]bool)}
+	if failed := w.cycle(); failed != 1 {
+		t.Errorf("first cycle: %d failed, want 1", failed)
+	}
+	ddl, err := os.ReadFile(filepath.Join(out, "logs.trino.sql"))
+	if err != nil || !strings.Contains(string(ddl), "CREATE TABLE") {
+		t.Fatalf("logs.trino.sql = %q, %v", ddl, err)
+	}
+
+	// 고친 매핑만 다시 만들고, 사라진 매핑의 산출물은 지웁니다.
+	write("broken.json", `{"properties": {"id": {"type": "keyword"}}}`)
+	if err := os.Remove(filepath.Join(dir, "logs.json")); err != nil {
+		t.Fatal(err)
+	}
+	if failed := w.cycle(); failed != 0 {
+		t.Errorf("second cycle: %d failed", failed)
+	}
+	if _, err := os.Stat(filepath.Join(out, "broken.schema.txt")); err != nil {
+		t.Error(err)
+	}
+	for _, gone := range []string{"logs.schema.txt", "logs.trino.sql"} {
+		if _, err := os.Stat(filepath.Join(out, gone)); !os.IsNotExist(err) {
+			t.Errorf("%s was not removed: %v", gone, err)
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/parquet"
+	"github.com/apache/arrow/go/v10/parquet/compress"
+	"github.com/apache/arrow/go/v10/parquet/pqarrow"
+)
+
+const (
+	rowGroupsPerRecord = "record"
+	rowGroupsBuffered  = "buffered"
+)
+
+// parquetOptions는 Parquet 파일을 쓰는 명령이 공유하는 설정입니다.
+type parquetOptions struct {
+	// rowGroups는 레코드와 row group의 대응 방식입니다. record이면 Arrow 레코드 하나가
+	// 곧 row group 하나(rowGroupRows보다 크면 여러 개)가 되고, buffered이면 레코드를
+	// 모아 rowGroupRows 행짜리 row group을 만듭니다. 후자는 메모리를 더 쓰는 대신
+	// 작은 배치가 많을 때 row group 수를 줄입니다.
+	rowGroups    string
+	rowGroupRows int64
+
+	// 아래는 pqarrow.ArrowWriterProperties와 사전 인코딩 설정입니다.
+	storeSchema      bool
+	coerceTimestamps string
+	truncateTimes    bool
+	int96Timestamps  bool
+	// localTimestamps이면 timestamp 컬럼을 시간대 없는(isAdjustedToUTC=false) 타입으로
+	// 씁니다. 값은 그대로 UTC 시각입니다.
+	localTimestamps bool
+	dictionary      bool
+	noDictionary    stringListFlag
+
+	compression      string
+	compressionLevel int
+	// stringZstdLevel이 0이 아니면 문자열(BYTE_ARRAY) 컬럼만 이 수준의 zstd로 압축합니다.
+	stringZstdLevel int
+
+	// encryption은 --encrypt-columns일 때 encryptionOptions.load가 설정합니다.
+	encryption *columnEncryption
+}
+
+// codecs는 --compression 값과 Parquet 압축 코덱의 대응입니다.
+var codecs = map[string]compress.Compression{
+	"uncompressed": compress.Codecs.Uncompressed,
+	"snappy":       compress.Codecs.Snappy,
+	"gzip":         compress.Codecs.Gzip,
+	"brotli":       compress.Codecs.Brotli,
+	"lz4":          compress.Codecs.Lz4,
+	"zstd":         compress.Codecs.Zstd,
+}
+
+// timeUnits는 --coerce-timestamps 값과 Arrow 시간 단위의 대응입니다.
+var timeUnits = map[string]arrow.TimeUnit{
+	"s":  arrow.Second,
+	"ms": arrow.Millisecond,
+	"us": arrow.Microsecond,
+	"ns": arrow.Nanosecond,
+}
+
+func (o *parquetOptions) bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.rowGroups, "row-groups", rowGroupsPerRecord, "row group layout: record (one row group per record batch) or buffered (fill row groups of --row-group-rows across batches)")
+	fs.Int64Var(&o.rowGroupRows, "row-group-rows", 1<<20, "maximum rows per Parquet row group")
+	fs.BoolVar(&o.storeSchema, "store-schema", true, "store the Arrow schema in the file so readers get back exact types and field metadata (e.g. original field names)")
+	fs.StringVar(&o.coerceTimestamps, "coerce-timestamps", "", "write timestamps in this unit: s, ms, us or ns (default: keep the Arrow unit)")
+	fs.BoolVar(&o.truncateTimes, "truncate-timestamps", false, "allow --coerce-timestamps to drop sub-unit precision instead of failing")
+	fs.BoolVar(&o.int96Timestamps, "int96-timestamps", false, "write timestamps as deprecated INT96 for older readers (e.g. old Hive or Impala)")
+	fs.BoolVar(&o.localTimestamps, "local-timestamps", false, "write timestamps without a time zone (isAdjustedToUTC=false) holding the UTC wall-clock time, which Hive and Impala read without shifting them by the server's zone")
+	fs.BoolVar(&o.dictionary, "dictionary", true, "dictionary-encode columns")
+	fs.Var(&o.noDictionary, "no-dictionary", "disable dictionary encoding for these Parquet column paths, e.g. message or user.name (comma-separated or repeated)")
+	fs.StringVar(&o.compression, "compression", "snappy", "compression codec: uncompressed, snappy, gzip, brotli, lz4 or zstd")
+	fs.IntVar(&o.compressionLevel, "compression-level", 0, "codec-specific compression level (0: codec default)")
+	fs.IntVar(&o.stringZstdLevel, "string-zstd-level", 0, "compress string columns with zstd at this level (e.g. 19) regardless of --compression; helps repetitive log text (0: off)")
+}
+
+func (o *parquetOptions) validate() error {
+	switch o.rowGroups {
+	case rowGroupsPerRecord, rowGroupsBuffered:
+	default:
+		return configErrorf("unknown --row-groups %q (want record or buffered)", o.rowGroups)
+	}
+	if o.rowGroupRows <= 0 {
+		return configErrorf("--row-group-rows must be positive")
+	}
+	if _, ok := timeUnits[o.coerceTimestamps]; o.coerceTimestamps != "" && !ok {
+		return configErrorf("unknown --coerce-timestamps %q (want s, ms, us or ns)", o.coerceTimestamps)
+	}
+	if o.int96Timestamps && o.coerceTimestamps != "" {
+		return configErrorf("--int96-timestamps and --coerce-timestamps are mutually exclusive")
+	}
+	if o.int96Timestamps && o.localTimestamps {
+		return configErrorf("--local-timestamps has no effect with --int96-timestamps, which carry no time zone")
+	}
+	if o.truncateTimes && o.coerceTimestamps == "" {
+		return configErrorf("--truncate-timestamps is only used together with --coerce-timestamps")
+	}
+	if _, ok := codecs[o.compression]; !ok {
+		return configErrorf("unknown --compression %q (want uncompressed, snappy, gzip, brotli, lz4 or zstd)", o.compression)
+	}
+	if o.stringZstdLevel < 0 || o.stringZstdLevel > 22 {
+		return configErrorf("--string-zstd-level must be between 1 and 22 (0: off)")
+	}
+	return nil
+}
+
+// writerProperties 함수는 schema를 쓰기 위한 Parquet 쓰기 설정을 만듭니다. 문자열 컬럼에
+// 따로 압축을 지정하거나 컬럼을 암호화하려면 Parquet 컬럼 경로가 필요하므로 스키마를 먼저
+// 변환해 봅니다.
+func (o *parquetOptions) writerProperties(schema *arrow.Schema) (*parquet.WriterProperties, error) {
+	props := []parquet.WriterProperty{
+		parquet.WithCompression(codecs[o.compression]),
+		parquet.WithMaxRowGroupLength(o.rowGroupRows),
+		parquet.WithDictionaryDefault(o.dictionary),
+	}
+	if o.compressionLevel != 0 {
+		props = append(props, parquet.WithCompressionLevel(o.compressionLevel))
+	}
+	for _, col := range o.noDictionary {
+		props = append(props, parquet.WithDictionaryFor(col, false))
+	}
+	if o.stringZstdLevel == 0 && o.encryption == nil {
+		return parquet.NewWriterProperties(props...), nil
+	}
+	pqSchema, err := pqarrow.ToParquet(schema, parquet.NewWriterProperties(props...), o.arrowWriterProperties())
+	if err != nil {
+		return nil, schemaErrorf("converting schema to Parquet: %w", err)
+	}
+	if o.stringZstdLevel != 0 {
+		for i := 0; i < pqSchema.NumColumns(); i++ {
+			col := pqSchema.Column(i)
+			if col.PhysicalType() == parquet.Types.ByteArray {
+				props = append(props,
+					parquet.WithCompressionFor(col.Path(), compress.Codecs.Zstd),
+					parquet.WithCompressionLevelFor(col.Path(), o.stringZstdLevel))
+			}
+		}
+	}
+	if o.encryption != nil {
+		enc, err := o.encryption.properties(schema, pqSchema)
+		if err != nil {
+			return nil, err
+		}
+		if enc != nil {
+			props = append(props, parquet.WithEncryptionProperties(enc))
+		}
+	}
+	return parquet.NewWriterProperties(props...), nil
+}
+
+func (o *parquetOptions) arrowWriterProperties() pqarrow.ArrowWriterProperties {
+	var props []pqarrow.WriterOption
+	if o.storeSchema {
+		props = append(props, pqarrow.WithStoreSchema())
+	}
+	if unit, ok := timeUnits[o.coerceTimestamps]; ok {
+		props = append(props, pqarrow.WithCoerceTimestamps(unit), pqarrow.WithTruncatedTimestamps(o.truncateTimes))
+	}
+	if o.int96Timestamps {
+		props = append(props, pqarrow.WithDeprecatedInt96Timestamps(true))
+	}
+	return pqarrow.NewArrowWriterProperties(props...)
+}
+
+// parquetWriter는 Arrow 레코드를 parquetOptions에 따라 Parquet 파일에 씁니다.
+type parquetWriter struct {
+	path string
+	opts *parquetOptions
+	w    *pqarrow.FileWriter
+	file *countingFile
+	rows int64
+	// groupRows는 buffered 모드에서 아직 닫히지 않은 row group의 행 수입니다.
+	groupRows int64
+	// stored는 레코드와 타입이 다른 파일 스키마입니다(parquetFileSchema). nil이 아니면 쓰는
+	// 레코드마다 이 스키마로 바꿉니다.
+	stored *arrow.Schema
+}
+
+func createParquetFile(path string, schema *arrow.Schema, opts *parquetOptions) (*parquetWriter, error) {
+	stored := parquetFileSchema(schema, opts.localTimestamps)
+	if stored != nil {
+		schema = stored
+	}
+	props, err := opts.writerProperties(schema)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, configErrorf("creating %s: %w", path, err)
+	}
+	cf := &countingFile{File: f}
+	w, err := pqarrow.NewFileWriter(schema, cf, props, opts.arrowWriterProperties())
+	if err != nil {
+		f.Close()
+		os.Remove(path)
+		return nil, schemaErrorf("creating Parquet writer for %s: %w", path, err)
+	}
+	return &parquetWriter{path: path, opts: opts, w: w, file: cf, stored: stored}, nil
+}
+
+// parquetFileSchema 함수는 schema를 Parquet 파일에 쓸 스키마로 바꿉니다. 이 버전의 Parquet
+// 기록기는 float16을 쓰지 못하므로 half_float 컬럼은 같은 값의 float32가 되고, local이면
+// timestamp의 시간대를 뺍니다. 바꿀 것이 없으면 nil입니다.
+func parquetFileSchema(schema *arrow.Schema, local bool) *arrow.Schema {
+	if !local {
+		return mapSchemaTypes(schema, halfFloatAsFloat32)
+	}
+	return mapSchemaTypes(schema, func(dt arrow.DataType) arrow.DataType {
+		return localTimestampType(halfFloatAsFloat32(dt))
+	})
+}
+
+// withoutTimeZones 함수는 schema의 timestamp 타입(구조체와 리스트 안 포함)에서 시간대를 뺀
+// 스키마를 반환합니다. Parquet에서는 isAdjustedToUTC=false가 됩니다. 시간대가 있는
+// timestamp가 없으면 nil입니다.
+func withoutTimeZones(schema *arrow.Schema) *arrow.Schema {
+	return mapSchemaTypes(schema, localTimestampType)
+}
+
+// mapSchemaTypes 함수는 schema의 원시 타입(구조체와 리스트 안 포함)을 leaf로 바꾼 스키마를
+// 반환합니다. 바뀐 타입이 없으면 nil입니다.
+func mapSchemaTypes(schema *arrow.Schema, leaf func(arrow.DataType) arrow.DataType) *arrow.Schema {
+	fields := append([]arrow.Field(nil), schema.Fields()...)
+	changed := false
+	for i, f := range fields {
+		fields[i].Type = mapType(f.Type, leaf)
+		changed = changed || !arrow.TypeEqual(fields[i].Type, f.Type)
+	}
+	if !changed {
+		return nil
+	}
+	md := schema.Metadata()
+	return arrow.NewSchema(fields, &md)
+}
+
+func mapType(dt arrow.DataType, leaf func(arrow.DataType) arrow.DataType) arrow.DataType {
+	switch t := dt.(type) {
+	case *arrow.ListType:
+		return arrow.ListOf(mapType(t.Elem(), leaf))
+	case *arrow.FixedSizeListType:
+		return arrow.FixedSizeListOf(t.Len(), mapType(t.Elem(), leaf))
+	case *arrow.StructType:
+		fields := append([]arrow.Field(nil), t.Fields()...)
+		for i, f := range fields {
+			fields[i].Type = mapType(f.Type, leaf)
+		}
+		return arrow.StructOf(fields...)
+	}
+	return leaf(dt)
+}
+
+func localTimestampType(dt arrow.DataType) arrow.DataType {
+	if t, ok := dt.(*arrow.TimestampType); ok && t.TimeZone != "" {
+		return &arrow.TimestampType{Unit: t.Unit}
+	}
+	return dt
+}
+
+func halfFloatAsFloat32(dt arrow.DataType) arrow.DataType {
+	if dt.ID() == arrow.FLOAT16 {
+		return arrow.PrimitiveTypes.Float32
+	}
+	return dt
+}
+
+// countingFile은 쓴 바이트 수를 세는 파일입니다.
+type countingFile struct {
+	*os.File
+	n int64
+}
+
+func (f *countingFile) Write(p []byte) (int, error) {
+	n, err := f.File.Write(p)
+	f.n += int64(n)
+	return n, err
+}
+
+// bytesWritten 함수는 지금까지 파일에 쓴 바이트 수를 반환합니다. buffered 모드에서 아직
+// 닫히지 않은 row group은 들어가지 않습니다.
+func (w *parquetWriter) bytesWritten() int64 {
+	return w.file.n
+}
+
+// write 함수는 레코드를 씁니다. buffered 모드에서는 현재 row group을 rowGroupRows까지
+// 채우고, 넘치는 부분은 잘라 다음 row group으로 넘깁니다.
+func (w *parquetWriter) write(rec arrow.Record) error {
+	if w.stored != nil {
+		rec = renameRecord(rec, w.stored)
+		defer rec.Release()
+	}
+	if w.opts.rowGroups == rowGroupsPerRecord {
+		if err := w.w.Write(rec); err != nil {
+			return dataErrorf("writing %s: %w", w.path, err)
+		}
+		w.rows += rec.NumRows()
+		return nil
+	}
+
+	n := rec.NumRows()
+	for off := int64(0); off < n; {
+		if w.groupRows == w.opts.rowGroupRows {
+			w.w.NewBufferedRowGroup()
+			w.groupRows = 0
+		}
+		take := n - off
+		if room := w.opts.rowGroupRows - w.groupRows; take > room {
+			take = room
+		}
+		part := rec
+		if take != n {
+			part = rec.NewSlice(off, off+take)
+		}
+		err := w.w.WriteBuffered(part)
+		if take != n {
+			part.Release()
+		}
+		if err != nil {
+			return dataErrorf("writing %s: %w", w.path, err)
+		}
+		w.groupRows += take
+		w.rows += take
+		off += take
+	}
+	return nil
+}
+
+// close 함수는 남은 row group과 footer를 쓰고 파일을 닫습니다.
+func (w *parquetWriter) close() error {
+	if err := w.w.Close(); err != nil {
+		return dataErrorf("closing %s: %w", w.path, err)
+	}
+	return nil
+}
+
+// abort 함수는 실패한 실행의 반쪽짜리 파일을 지웁니다.
+func (w *parquetWriter) abort() {
+	w.w.Close()
+	os.Remove(w.path)
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"flag"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+	"github.com/apache/arrow/go/v10/arrow/float16"
+	"github.com/apache/arrow/go/v10/arrow/memory"
+	"github.com/apache/arrow/go/v10/parquet"
+	"github.com/apache/arrow/go/v10/parquet/compress"
+)
+
+func TestWithoutTimeZones(t *testing.T) {
+	md := arrow.NewMetadata([]string{"k"}, []string{"v"})
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "@timestamp", Type: arrow.FixedWidthTypes.Timestamp_ns},
+		{Name: "events", Type: arrow.ListOf(arrow.StructOf(arrow.Field{Name: "at", Type: arrow.FixedWidthTypes.Timestamp_ms}))},
+		{Name: "message", Type: arrow.BinaryTypes.String},
+	}, &md)
+	local := withoutTimeZones(schema)
+	if local == nil {
+		t.Fatal("withoutTimeZones found no time zone")
+	}
+	want := []arrow.DataType{
+		&arrow.TimestampType{Unit: arrow.Nanosecond},
+		arrow.ListOf(arrow.StructOf(arrow.Field{Name: "at", Type: &arrow.TimestampType{Unit: arrow.Millisecond}})),
+		arrow.BinaryTypes.String,
+	}
+	for i, f := range local.Fields() {
+		if !arrow.TypeEqual(f.Type, want[i]) {
+			t.Errorf("%s = %s, want %s", f.Name, f.Type, want[i])
+		}
+	}
+	if local.Metadata().FindKey("k") < 0 {
+		t.Error("schema metadata was dropped")
+	}
+	if withoutTimeZones(local) != nil {
+		t.Error("withoutTimeZones changed a schema without time zones")
+	}
+}
+
+func TestParquetFileSchema(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "rating", Type: arrow.FixedWidthTypes.Float16},
+		{Name: "at", Type: arrow.FixedWidthTypes.Timestamp_ns},
+	}, nil)
+	stored := parquetFileSchema(schema, false)
+	if stored == nil || stored.Field(0).Type.ID() != arrow.FLOAT32 || !arrow.TypeEqual(stored.Field(1).Type, arrow.FixedWidthTypes.Timestamp_ns) {
+		t.Fatalf("stored = %v", stored)
+	}
+	if local := parquetFileSchema(schema, true); local.Field(1).Type.(*arrow.TimestampType).TimeZone != "" {
+		t.Errorf("local = %v", local)
+	}
+	if parquetFileSchema(stored, false) != nil {
+		t.Error("parquetFileSchema changed a schema without half floats")
+	}
+
+	b := array.NewFloat16Builder(memory.DefaultAllocator)
+	defer b.Release()
+	b.Append(float16.New(1.5))
+	b.AppendNull()
+	col := b.NewArray()
+	defer col.Release()
+	rec := array.NewRecord(arrow.NewSchema(schema.Fields()[:1], nil), []arrow.Array{col}, 2)
+	defer rec.Release()
+	out := renameRecord(rec, arrow.NewSchema(stored.Fields()[:1], nil))
+	defer out.Release()
+	f32 := out.Column(0).(*array.Float32)
+	if f32.Value(0) != 1.5 || !f32.IsNull(1) {
+		t.Errorf("float32 column = %v", f32)
+	}
+}
+
+func TestLocalTimestampsValidate(t *testing.T) {
+	o := parquetOptions{rowGroups: rowGroupsPerRecord, rowGroupRows: 1, compression: "snappy", localTimestamps: true}
+	if err := o.validate(); err != nil {
+		t.Fatal(err)
+	}
+	o.int96Timestamps = true
+	if err := o.validate(); exitCodeFor(err) != exitConfigError {
+		t.Errorf("--local-timestamps with --int96-timestamps = %v, want a config error", err)
+	}
+}
+
+// testParquetOptions 함수는 플래그 args로 만든 parquetOptions를 반환합니다.
+func testParquetOptions(t *testing.T, args ...string) *parquetOptions {
+	t.Helper()
+	var o parquetOptions
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	o.bind(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.validate(); err != nil {
+		t.Fatal(err)
+	}
+	return &o
+}
+
+// testBatch 함수는 id, message, at 컬럼의 rows행 레코드를 만듭니다.
+func testBatch(rows int) arrow.Record {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+		{Name: "message", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "at", Type: arrow.FixedWidthTypes.Timestamp_ns, Nullable: true},
+	}, nil)
+	b := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer b.Release()
+	for i := 0; i < rows; i++ {
+		b.Field(0).(*array.Int64Builder).Append(int64(i))
+		b.Field(1).(*array.StringBuilder).Append("GET /index.html 200")
+		b.Field(2).(*array.TimestampBuilder).Append(arrow.Timestamp(int64(i) * 1e9))
+	}
+	return b.NewRecord()
+}
+
+// writeTestParquet 함수는 batches개의 rows행 레코드를 opts로 쓴 파일의 경로를 반환합니다.
+func writeTestParquet(t *testing.T, opts *parquetOptions, batches, rows int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "out.parquet")
+	rec := testBatch(rows)
+	defer rec.Release()
+	w, err := createParquetFile(path, rec.Schema(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < batches; i++ {
+		if err := w.write(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// rowGroupRows 함수는 path의 row group마다 행 수를 반환합니다.
+func rowGroupRows(t *testing.T, path string) []int64 {
+	t.Helper()
+	f, err := openParquetFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var rows []int64
+	for i := 0; i < f.pf.NumRowGroups(); i++ {
+		rows = append(rows, f.pf.RowGroup(i).NumRows())
+	}
+	return rows
+}
+
+func TestRowGroups(t *testing.T) {
+	cases := []struct {
+		args []string
+		want []int64
+	}{
+		{[]string{"--row-groups", "record"}, []int64{3, 3, 3}},
+		{[]string{"--row-groups", "record", "--row-group-rows", "2"}, []int64{2, 1, 2, 1, 2, 1}},
+		{[]string{"--row-groups", "buffered", "--row-group-rows", "4"}, []int64{4, 4, 1}},
+	}
+	for _, c := range cases {
+		got := rowGroupRows(t, writeTestParquet(t, testParquetOptions(t, c.args...), 3, 3))
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%v: row groups = %v, want %v", c.args, got, c.want)
+		}
+	}
+	if err := (&parquetOptions{rowGroups: "page", rowGroupRows: 1, compression: "snappy"}).validate(); exitCodeFor(err) != exitConfigError {
+		t.Errorf("--row-groups page = %v, want a config error", err)
+	}
+}
+
+func TestWriterProperties(t *testing.T) {
+	path := writeTestParquet(t, testParquetOptions(t, "--coerce-timestamps", "ms", "--no-dictionary", "message"), 1, 3)
+	pf, err := openParquetFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pf.Close()
+	sc, err := pf.reader.Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if at := sc.Field(2).Type.(*arrow.TimestampType); at.Unit != arrow.Millisecond {
+		t.Errorf("at = %s, want milliseconds", at)
+	}
+	if pf.pf.MetaData().KeyValueMetadata().FindValue("ARROW:schema") == nil {
+		t.Error("the Arrow schema was not stored")
+	}
+	rg := pf.pf.MetaData().RowGroup(0)
+	for i, wantDict := range []bool{true, false} {
+		chunk, err := rg.ColumnChunk(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if chunk.HasDictionaryPage() != wantDict {
+			t.Errorf("%s: dictionary = %t, want %t", chunk.PathInSchema(), chunk.HasDictionaryPage(), wantDict)
+		}
+	}
+
+	int96, err := openParquetFile(writeTestParquet(t, testParquetOptions(t, "--int96-timestamps", "--store-schema=false"), 1, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer int96.Close()
+	if at := int96.pf.MetaData().Schema.Column(2); at.PhysicalType() != parquet.Types.Int96 {
+		t.Errorf("at with --int96-timestamps is %s, want INT96", at.PhysicalType())
+	}
+
+	for _, args := range [][]string{
+		{"--coerce-timestamps", "h"},
+		{"--int96-timestamps", "--coerce-timestamps", "ms"},
+		{"--truncate-timestamps"},
+	} {
+		var o parquetOptions
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		o.bind(fs)
+		fs.Parse(args)
+		if err := o.validate(); exitCodeFor(err) != exitConfigError {
+			t.Errorf("%v = %v, want a config error", args, err)
+		}
+	}
+}
+
+func TestCompression(t *testing.T) {
+	cases := []struct {
+		args            []string
+		id, message, at compress.Compression
+	}{
+		{nil, compress.Codecs.Snappy, compress.Codecs.Snappy, compress.Codecs.Snappy},
+		{[]string{"--compression", "zstd", "--compression-level", "9"}, compress.Codecs.Zstd, compress.Codecs.Zstd, compress.Codecs.Zstd},
+		{[]string{"--compression", "gzip", "--string-zstd-level", "19"}, compress.Codecs.Gzip, compress.Codecs.Zstd, compress.Codecs.Gzip},
+	}
+	for _, c := range cases {
+		pf, err := openParquetFile(writeTestParquet(t, testParquetOptions(t, c.args...), 1, 100))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rg := pf.pf.MetaData().RowGroup(0)
+		for i, want := range []compress.Compression{c.id, c.message, c.at} {
+			chunk, err := rg.ColumnChunk(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := chunk.Compression(); got != want {
+				t.Errorf("%v: %s codec = %s, want %s", c.args, chunk.PathInSchema(), got, want)
+			}
+		}
+		pf.Close()
+	}
+	for _, o := range []parquetOptions{
+		{rowGroups: rowGroupsPerRecord, rowGroupRows: 1, compression: "lzo"},
+		{rowGroups: rowGroupsPerRecord, rowGroupRows: 1, compression: "snappy", stringZstdLevel: 23},
+	} {
+		if err := o.validate(); exitCodeFor(err) != exitConfigError {
+			t.Errorf("validate(%+v) = %v, want a config error", o, err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	v, err := parseYAML([]byte(`# dataset
+name: "logs # not a comment"
+docs: 1000   # trailing comment
+ratio: 0.5
+enabled: true
+empty: ~
+tags: [a, 'b c', 3]
+fields:
+  - type: keyword
+    count: 2
+  - type: dense_vector
+    dims: 8
+nested:
+  list:
+  - x
+  -
+  - - y
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"name":    "logs # not a comment",
+		"docs":    1000.0,
+		"ratio":   0.5,
+		"enabled": true,
+		"empty":   nil,
+		"tags":    []interface{}{"a", "b c", 3.0},
+		"fields": []interface{}{
+			map[string]interface{}{"type": "keyword", "count": 2.0},
+			map[string]interface{}{"type": "dense_vector", "dims": 8.0},
+		},
+		"nested": map[string]interface{}{"list": []interface{}{"x", nil, []interface{}{"y"}}},
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("parseYAML = %#v, want %#v", v, want)
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	for _, src := range []string{
+		"a: 1\n  b: 2\n",
+		"a: 1\na: 2\n",
+		"just text\n",
+		"a: \"open\n",
+		"a: &anchor 1\n",
+		"a:\n\t- 1\n",
+	} {
+		if _, err := parseYAML([]byte(src)); err == nil {
+			t.Errorf("parseYAML(%q) succeeded", src)
+		}
+	}
+}